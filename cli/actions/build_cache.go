@@ -0,0 +1,91 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/opentable/sous/ext/docker"
+)
+
+type (
+	// BuildCacheList is an Action that lists the runmount build caches on
+	// this machine.
+	BuildCacheList struct {
+		Caches    *docker.BuildCaches
+		OutWriter io.Writer
+	}
+
+	// BuildCachePrune is an Action that removes runmount build caches from
+	// this machine.
+	BuildCachePrune struct {
+		Caches *docker.BuildCaches
+		// MaxSize is the total size in bytes the caches are pruned to, least
+		// recently used first. Negative means no cap.
+		MaxSize int64
+		// Cutoff is when caches must have been used since to be kept. Zero
+		// means no limit on age.
+		Cutoff    time.Time
+		OutWriter io.Writer
+	}
+)
+
+// Do implements Action on BuildCacheList.
+func (l *BuildCacheList) Do() error {
+	caches, err := l.Caches.List()
+	if err != nil {
+		return err
+	}
+	if len(caches) == 0 {
+		fmt.Fprintln(l.OutWriter, "No build caches.")
+		return nil
+	}
+	w := &tabwriter.Writer{}
+	w.Init(l.OutWriter, 2, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VOLUME\tPROJECT\tSIZE\tLAST USED\tSHARED")
+	total := int64(0)
+	for _, c := range caches {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\n", c.Volume, cacheProject(c), cacheSize(c.Size), cacheLastUsed(c), c.Shared)
+		total += c.Size
+	}
+	fmt.Fprintf(w, "\t\t%s\t\t\n", cacheSize(total))
+	return w.Flush()
+}
+
+// Do implements Action on BuildCachePrune.
+func (p *BuildCachePrune) Do() error {
+	removed, err := p.Caches.Prune(p.MaxSize, p.Cutoff)
+	freed := int64(0)
+	for _, c := range removed {
+		fmt.Fprintf(p.OutWriter, "Removed %s (%s, %s)\n", c.Volume, cacheProject(c), cacheSize(c.Size))
+		freed += c.Size
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(p.OutWriter, "Removed %d build caches, freeing %s.\n", len(removed), cacheSize(freed))
+	return nil
+}
+
+func cacheProject(c docker.BuildCache) string {
+	switch {
+	case c.Repo == "":
+		return "-"
+	case c.Offset == "":
+		return c.Repo
+	default:
+		return c.Repo + "," + c.Offset
+	}
+}
+
+func cacheLastUsed(c docker.BuildCache) string {
+	if c.LastUsed.IsZero() {
+		return "-"
+	}
+	return c.LastUsed.Format(time.RFC3339)
+}
+
+func cacheSize(bytes int64) string {
+	return fmt.Sprintf("%.1fMB", float64(bytes)/(1024*1024))
+}
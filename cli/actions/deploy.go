@@ -3,6 +3,7 @@ package actions
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 	"time"
@@ -29,23 +30,52 @@ type Deploy struct {
 	LogSink            logging.LogSink
 	User               sous.User
 	Force, WaitStable  bool
+	// At, if set, schedules the deploy for that time, instead of deploying
+	// now.
+	At time.Time
 	*config.Config
 }
 
 // Do implements Action on Deploy.
 func (sd *Deploy) Do() error {
+	location, err := sd.submit()
+	if err != nil || location == "" {
+		return err
+	}
+
+	fmt.Printf("Deployment queued: %s\n", location)
+	logging.Deliver(sd.LogSink, logging.Console("\n"))
+
+	var p *mpb.Progress
+	if terminal.IsTerminal(int(os.Stdin.Fd())) {
+		p = mpb.New()
+	}
+	result := sd.await(location, p, "")
+	if p != nil {
+		p.Wait()
+	}
+	return result
+}
+
+// submit updates the target deployment to the requested version. It returns
+// the location of the queued deploy to wait for, or "" if there's nothing to
+// wait for.
+func (sd *Deploy) submit() (string, error) {
 	newVersion, err := sd.ResolveFilter.TagVersion()
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	d := server.SingleDeploymentBody{}
 	q := sd.TargetDeploymentID.QueryMap()
 	q["force"] = strconv.FormatBool(sd.Force)
+	if !sd.At.IsZero() {
+		q["at"] = sd.At.Format(time.RFC3339)
+	}
 
 	updater, err := sd.HTTPClient.Retrieve("./single-deployment", q, &d, sd.User.HTTPHeaders())
 	if err != nil {
-		return errors.Errorf("\nFailed to retrieve current deployment:\n\n\tPlease check your repo, flavor, and offset.  Items are case sensitive.  Use the following command to verify values sous expects.\n\n\tsous query gdm\n\nError returned: %s", err)
+		return "", errors.Errorf("\nFailed to retrieve current deployment:\n\n\tPlease check your repo, flavor, and offset.  Items are case sensitive.  Use the following command to verify values sous expects.\n\n\tsous query gdm\n\nError returned: %s", err)
 	}
 	messages.ReportLogFieldsMessage("SousNewDeploy.Execute Retrieved Deployment",
 		logging.ExtraDebug1Level, sd.LogSink, d)
@@ -62,8 +92,19 @@ func (sd *Deploy) Do() error {
 	}()
 
 	updateResponse, err := updater.Update(d, sd.User.HTTPHeaders())
+	if conflict, is := errors.Cause(err).(*restful.ConflictError); is {
+		return "", errors.Errorf("Deployment of %s refused: %s", sd.TargetDeploymentID, conflict.Reason)
+	}
+	if denied, is := errors.Cause(err).(*restful.DeniedError); is {
+		return "", errors.Errorf("Not allowed to deploy %s: %s", sd.TargetDeploymentID, explainDenial(denied))
+	}
 	if err != nil {
-		return errors.Wrap(err, "Failed to update deployment")
+		return "", errors.Wrap(err, "Failed to update deployment")
+	}
+
+	if !sd.At.IsZero() && updateResponse.Location() != "" {
+		sd.reportScheduled(updateResponse.Location())
+		return "", nil
 	}
 
 	if !sd.WaitStable {
@@ -72,52 +113,62 @@ func (sd *Deploy) Do() error {
 			logging.DebugLevel,
 			sd.LogSink,
 		)
-		return nil
-	}
-
-	if location := updateResponse.Location(); location != "" {
-		fmt.Printf("Deployment queued: %s\n", location)
-		pollTime := sd.Config.PollIntervalForClient
-
-		logging.Deliver(sd.LogSink, logging.Console("\n"))
-
-		var p *mpb.Progress
-		var bar *mpb.Bar
-		if terminal.IsTerminal(int(os.Stdin.Fd())) {
-			p = mpb.New()
-			// initialize bar with dynamic total and initial total guess = 80
-			bar = p.AddBar(100,
-				// indicate that total is dynamic
-				mpb.BarDynamicTotal(),
-				// trigger total auto increment by 1, when 18 % remains till bar completion
-				mpb.BarAutoIncrTotal(18, 1),
-				mpb.PrependDecorators(
-					decor.CountersNoUnit("%d / %d", 12, 0),
-				),
-				mpb.AppendDecorators(
-					decor.Percentage(5, 0),
-				),
-			)
-		}
+		return "", nil
+	}
+
+	location := updateResponse.Location()
+	if location == "" {
+		messages.ReportLogFieldsMessageToConsole(
+			fmt.Sprintf("Desired version for %q already %q", sd.TargetDeploymentID, newVersion),
+			logging.DebugLevel,
+			sd.LogSink,
+		)
+	}
+	return location, nil
+}
 
-		result := sd.pollDeployQueue(location, pollTime, bar)
+// reportScheduled tells the user that their deploy is scheduled, and how to
+// cancel it. location is that of the scheduled deploy.
+func (sd *Deploy) reportScheduled(location string) {
+	id := location
+	if u, err := url.Parse(restful.AbsoluteLocation(location)); err == nil {
+		id = u.Query().Get("id")
+	}
+	version, _ := sd.ResolveFilter.TagVersion()
+	fmt.Printf("Deploy of %s at version %s scheduled for %s.\nCancel it with: sous scheduled -cluster %s -cancel %s\n",
+		sd.TargetDeploymentID, version, sd.At.Format(time.RFC1123), sd.TargetDeploymentID.Cluster, id)
+}
 
-		if terminal.IsTerminal(int(os.Stdin.Fd())) && bar != nil && p != nil {
-			bar.SetTotal(100, true)
-			bar.Incr(100)
-			bar.Complete()
-			p.Wait()
-			p.RemoveBar(bar)
+// await polls the deploy queued at location until it completes, showing its
+// progress as a bar in p, labelled with name, unless p is nil.
+func (sd *Deploy) await(location string, p *mpb.Progress, name string) error {
+	var bar *mpb.Bar
+	if p != nil {
+		decorators := []decor.DecoratorFunc{decor.CountersNoUnit("%d / %d", 12, 0)}
+		if name != "" {
+			decorators = append([]decor.DecoratorFunc{decor.StaticName(name, 0, decor.DSyncSpace|decor.DidentRight)}, decorators...)
 		}
+		// initialize bar with dynamic total and initial total guess = 80
+		bar = p.AddBar(100,
+			// indicate that total is dynamic
+			mpb.BarDynamicTotal(),
+			// trigger total auto increment by 1, when 18 % remains till bar completion
+			mpb.BarAutoIncrTotal(18, 1),
+			mpb.PrependDecorators(decorators...),
+			mpb.AppendDecorators(
+				decor.Percentage(5, 0),
+			),
+		)
+	}
+
+	result := sd.pollDeployQueue(location, sd.Config.PollIntervalForClient, bar)
 
-		return result
+	if bar != nil {
+		bar.SetTotal(100, true)
+		bar.Incr(100)
+		bar.Complete()
 	}
-	messages.ReportLogFieldsMessageToConsole(
-		fmt.Sprintf("Desired version for %q already %q", sd.TargetDeploymentID, newVersion),
-		logging.DebugLevel,
-		sd.LogSink,
-	)
-	return nil
+	return result
 }
 
 func timeTrack(start time.Time) string {
@@ -128,7 +179,8 @@ func timeTrack(start time.Time) string {
 func (sd *Deploy) pollDeployQueue(location string, pollAtempts int, bar *mpb.Bar) error {
 	start := time.Now()
 	response := dto.R11nResponse{}
-	location = "http://" + location
+	location = restful.AbsoluteLocation(location)
+	var rollout sous.RolloutProgress
 
 	for i := 0; i < pollAtempts; i++ {
 		if bar != nil {
@@ -140,7 +192,23 @@ func (sd *Deploy) pollDeployQueue(location string, pollAtempts int, bar *mpb.Bar
 
 		queuePosition := response.QueuePosition
 
+		if response.Rollout != nil && *response.Rollout != rollout {
+			rollout = *response.Rollout
+			messages.ReportLogFieldsMessageToConsole(
+				fmt.Sprintf("Rollout %s", rollout),
+				logging.InformationLevel,
+				sd.LogSink,
+			)
+		}
+
 		if response.Resolution != nil && response.Resolution.Error != nil {
+			if rb := response.Resolution.AutoRollback; rb != nil {
+				messages.ReportLogFieldsMessageToConsole(
+					fmt.Sprintf("Deployment %s", rb),
+					logging.WarningLevel,
+					sd.LogSink,
+				)
+			}
 			return errors.Wrapf(response.Resolution.Error, "Failed to deploy, duration: %s\n", timeTrack(start))
 		}
 
@@ -191,3 +259,12 @@ func checkResolutionSuccess(resolution sous.DiffResolution) bool {
 		return true
 	}
 }
+
+// explainDenial returns the reason the server refused a request, and what
+// the user can do about it.
+func explainDenial(denied *restful.DeniedError) string {
+	if denied.Unauthenticated {
+		return denied.Reason + "\n\tThis server requires you to authenticate: set SOUS_USER_TOKEN to a token from a Sous admin, or configure a client certificate with Auth.ClientCertFile and Auth.ClientKeyFile."
+	}
+	return denied.Reason + "\n\tAsk an owner of the manifest to make this change, or to add you to its Owners."
+}
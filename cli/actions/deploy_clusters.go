@@ -0,0 +1,95 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vbauerster/mpb"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// DeployClusters deploys a version to several clusters at once. Each of its
+// Deploys is submitted and tracked concurrently, with a progress bar per
+// cluster, and the outcome for each cluster is summarized at the end.
+type DeployClusters struct {
+	// Deploys are the deploys to each cluster.
+	Deploys   []*Deploy
+	OutWriter io.Writer
+	// Failed maps the name of each cluster whose deploy failed to its error,
+	// once Do has returned.
+	Failed map[string]error
+}
+
+// clusterDeployResult is the outcome of the deploy to a single cluster.
+type clusterDeployResult struct {
+	cluster  string
+	err      error
+	duration time.Duration
+}
+
+// Do implements Action on DeployClusters.
+func (dc *DeployClusters) Do() error {
+	var p *mpb.Progress
+	if terminal.IsTerminal(int(os.Stdin.Fd())) {
+		p = mpb.New()
+	}
+
+	results := make([]clusterDeployResult, len(dc.Deploys))
+	var wg sync.WaitGroup
+	for i, d := range dc.Deploys {
+		wg.Add(1)
+		go func(i int, d *Deploy) {
+			defer wg.Done()
+			start := time.Now()
+			cluster := d.TargetDeploymentID.Cluster
+			location, err := d.submit()
+			if err == nil && location != "" {
+				err = d.await(location, p, cluster)
+			}
+			results[i] = clusterDeployResult{cluster: cluster, err: err, duration: time.Since(start)}
+		}(i, d)
+	}
+	wg.Wait()
+	if p != nil {
+		p.Wait()
+	}
+
+	return dc.summarize(results)
+}
+
+// summarize writes a line per cluster to dc.OutWriter, and returns an error
+// naming the clusters whose deploys failed, if any did.
+func (dc *DeployClusters) summarize(results []clusterDeployResult) error {
+	out := dc.OutWriter
+	if out == nil {
+		out = os.Stdout
+	}
+	tw := tabwriter.NewWriter(out, 2, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CLUSTER\tRESULT\tDURATION")
+
+	dc.Failed = map[string]error{}
+	var failed []string
+	for _, r := range results {
+		result := "ok"
+		if r.err != nil {
+			result = "FAILED: " + strings.Join(strings.Fields(r.err.Error()), " ")
+			failed = append(failed, r.cluster)
+			dc.Failed[r.cluster] = r.err
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.cluster, result, r.duration.Round(time.Second))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		return errors.Errorf("deploy failed in %d of %d clusters: %v", len(failed), len(results), failed)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package actions
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nyarly/spies"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/server"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful/restfultest"
+	"github.com/pkg/errors"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func clusterDeployFixture(t *testing.T, cluster string, retrieveErr error) (*Deploy, *spies.Spy) {
+	t.Helper()
+	log, _ := logging.NewLogSinkSpy()
+	httpClient, ctrl := restfultest.NewHTTPClientSpy()
+	updater, updaterCtrl := restfultest.NewUpdateSpy()
+	current := server.SingleDeploymentBody{
+		Deployment: &sous.DeploySpec{Version: semv.MustParse("1.0.0")},
+	}
+	ctrl.MatchMethod("Retrieve", spies.AnyArgs, current, updater, retrieveErr)
+
+	rf := &sous.ResolveFilter{
+		Tag:     sous.NewResolveFieldMatcher("2.0.0"),
+		Cluster: sous.NewResolveFieldMatcher(cluster),
+	}
+	return &Deploy{
+		ResolveFilter: rf,
+		HTTPClient:    httpClient,
+		TargetDeploymentID: sous.DeploymentID{
+			ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/opentable/example"}},
+			Cluster:    cluster,
+		},
+		LogSink: log,
+	}, updaterCtrl
+}
+
+func TestDeployClusters_Do(t *testing.T) {
+	ok, okCtrl := clusterDeployFixture(t, "cluster1", nil)
+	failing, _ := clusterDeployFixture(t, "cluster2", errors.New("boom"))
+	out := &bytes.Buffer{}
+	dc := &DeployClusters{Deploys: []*Deploy{ok, failing}, OutWriter: out}
+
+	err := dc.Do()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "deploy failed in 1 of 2 clusters: [cluster2]")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Regexp(t, `^cluster1\s+ok\s`, lines[1])
+	assert.Regexp(t, `^cluster2\s+FAILED: Failed to retrieve current deployment: .* Error returned: boom\s`, lines[2])
+
+	assert.Len(t, dc.Failed, 1)
+	assert.Contains(t, dc.Failed, "cluster2")
+
+	updates := okCtrl.CallsTo("Update")
+	require.Len(t, updates, 1)
+	body := updates[0].PassedArgs().Get(0).(server.SingleDeploymentBody)
+	assert.Equal(t, "2.0.0", body.Deployment.Version.String())
+}
+
+func TestDeployClusters_Do_allSucceed(t *testing.T) {
+	d1, _ := clusterDeployFixture(t, "cluster1", nil)
+	d2, _ := clusterDeployFixture(t, "cluster2", nil)
+	out := &bytes.Buffer{}
+	dc := &DeployClusters{Deploys: []*Deploy{d1, d2}, OutWriter: out}
+
+	assert.NoError(t, dc.Do())
+	assert.Empty(t, dc.Failed)
+	assert.Equal(t, 2, strings.Count(out.String(), " ok "))
+}
@@ -51,6 +51,19 @@ func TestPollDeployQueue_success_updated(t *testing.T) {
 	assert.NoError(t, sd.pollDeployQueue("127.0.0.1:1234/deploy-queue-item", 1, nil))
 }
 
+func TestPollDeployQueue_https(t *testing.T) {
+	log, _ := logging.NewLogSinkSpy()
+	httpClient, ctrl := restfultest.NewHTTPClientSpy()
+	createDeployResult(ctrl, -1, "created", 2)
+	sd := &Deploy{
+		HTTPClient: httpClient,
+		LogSink:    log,
+	}
+
+	assert.NoError(t, sd.pollDeployQueue("https://127.0.0.1:1234/deploy-queue-item", 1, nil))
+	assert.Equal(t, "https://127.0.0.1:1234/deploy-queue-item", ctrl.CallsTo("Retrieve")[0].PassedArgs().String(0))
+}
+
 func TestPollDeployQueue_fail(t *testing.T) {
 	log, _ := logging.NewLogSinkSpy()
 	location := "127.0.0.1:8888/deploy-queue-item?action=bb836990-5ab2-4eab-9f52-ad3fd555539b&cluster=dev-ci-sf&flavor=&offset=&repo=github.com%2Fopentable%2Fsous-demo"
@@ -0,0 +1,86 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+)
+
+// History is an Action that lists the recorded changes to deployments.
+type History struct {
+	ResolveFilter *sous.ResolveFilter
+	HTTPClient    restful.HTTPClient
+	LogSink       logging.LogSink
+	OutWriter     io.Writer
+	// Limit, if not 0, is how many of the most recent changes are listed.
+	Limit int
+}
+
+// Do implements Action on History.
+func (h *History) Do() error {
+	resp := dto.HistoryResponse{}
+	if _, err := h.HTTPClient.Retrieve("./history", h.query(), &resp, nil); err != nil {
+		return errors.Wrap(err, "retrieving history")
+	}
+	messages.ReportLogFieldsMessage("Retrieved history", logging.ExtraDebug1Level, h.LogSink, h.ResolveFilter)
+
+	w := &tabwriter.Writer{}
+	w.Init(h.OutWriter, 2, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "WHEN\tDEPLOYMENT\tCHANGE\tUSER\tDIFFERENCES")
+	for _, e := range resp.Events {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			e.When.Format(time.RFC3339), e.DeploymentID, e.Change, e.User, historySummary(e))
+	}
+	return w.Flush()
+}
+
+// query returns the query for /history.
+func (h *History) query() map[string]string {
+	q := filterQuery(h.ResolveFilter)
+	if h.Limit > 0 {
+		q["limit"] = strconv.Itoa(h.Limit)
+	}
+	return q
+}
+
+// filterQuery returns the query for resources filtered by repo, offset,
+// flavor and cluster: only fields rf restricts are included.
+func filterQuery(rf *sous.ResolveFilter) map[string]string {
+	q := map[string]string{}
+	if rf == nil {
+		return q
+	}
+	for name, m := range map[string]sous.ResolveFieldMatcher{
+		"repo":    rf.Repo,
+		"offset":  rf.Offset,
+		"flavor":  rf.Flavor,
+		"cluster": rf.Cluster,
+	} {
+		if !m.All() {
+			q[name] = *m.Match
+		}
+	}
+	return q
+}
+
+func historySummary(e sous.HistoryEvent) string {
+	switch {
+	case len(e.Diffs) > 0:
+		return strings.Join(e.Diffs, "; ")
+	case e.After != nil:
+		return fmt.Sprintf("version %s", e.After.SourceID.Version)
+	case e.Before != nil:
+		return fmt.Sprintf("was version %s", e.Before.SourceID.Version)
+	}
+	return ""
+}
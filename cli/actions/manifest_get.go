@@ -19,6 +19,9 @@ type ManifestGet struct {
 	LogSink          logging.LogSink
 	OutWriter        io.Writer
 	UpdaterCapture   *restful.Updater
+	// Expanded prints the manifest with its Defaults and Overlays merged into
+	// each of its deployments, instead of as it is stored.
+	Expanded bool
 }
 
 // Do implements Action on ManifestGet.
@@ -33,7 +36,15 @@ func (mg *ManifestGet) Do() error {
 
 	messages.ReportLogFieldsMessage("Sous manifest in Execute", logging.ExtraDebug1Level, mg.LogSink, mani.ID())
 
-	yml, err := yaml.Marshal(mani)
+	out := &mani
+	if mg.Expanded {
+		defs := sous.Defs{}
+		if _, err := mg.HTTPClient.Retrieve("./defs", nil, &defs, nil); err != nil {
+			return errors.Wrap(err, "getting cluster definitions to expand the manifest")
+		}
+		out = mani.Expanded(defs)
+	}
+	yml, err := yaml.Marshal(out)
 	if err != nil {
 		return err
 	}
@@ -11,6 +11,7 @@ import (
 	"github.com/opentable/sous/util/restful/restfultest"
 	"github.com/samsalisbury/semv"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestManifestGet(t *testing.T) {
@@ -51,3 +52,31 @@ func TestManifestGet(t *testing.T) {
 
 	assert.Regexp(t, "github", out.String())
 }
+
+func TestManifestGet_Expanded(t *testing.T) {
+	out := &bytes.Buffer{}
+	cl, control := restfultest.NewHTTPClientSpy()
+	var up restful.Updater
+	smg := &ManifestGet{
+		TargetManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/user/project"}},
+		HTTPClient:       cl,
+		OutWriter:        out,
+		LogSink:          logging.SilentLogSet(),
+		UpdaterCapture:   &up,
+		Expanded:         true,
+	}
+
+	mani := sous.ManifestFixture("simple")
+	mani.Defaults = &sous.DeployConfig{NumInstances: 7}
+	mani.Overlays = sous.ManifestOverlays{{Clusters: []string{"env=prod"}, DeployConfig: sous.DeployConfig{NumInstances: 9}}}
+	defs := sous.Defs{Clusters: sous.Clusters{"ci": {Name: "ci", Labels: map[string]string{"env": "prod"}}}}
+	control.MatchMethod("Retrieve", func(args mock.Arguments) bool {
+		return args.String(0) == "./defs"
+	}, defs, restfultest.DummyUpdater(), nil)
+	control.Any("Retrieve", mani, restfultest.DummyUpdater(), nil)
+
+	assert.NoError(t, smg.Do())
+	assert.NotContains(t, out.String(), "Defaults")
+	assert.NotContains(t, out.String(), "Overlays")
+	assert.Contains(t, out.String(), "NumInstances: 9")
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/opentable/sous/util/logging/messages"
 	"github.com/opentable/sous/util/restful"
 	"github.com/opentable/sous/util/yaml"
+	"github.com/pkg/errors"
 )
 
 // ManifestSet is an Action for setting a manifest.
@@ -41,6 +42,12 @@ func (ms *ManifestSet) Do() error {
 	}
 
 	_, err = (*ms.Updater).Update(&yml, nil)
+	if conflict, is := errors.Cause(err).(*restful.ConflictError); is {
+		return errors.Errorf("Manifest update refused: %s", conflict.Reason)
+	}
+	if denied, is := errors.Cause(err).(*restful.DeniedError); is {
+		return errors.Errorf("Not allowed to update manifest %s: %s", ms.ManifestID, explainDenial(denied))
+	}
 	if err != nil {
 		return err
 	}
@@ -3,6 +3,7 @@ package actions
 import (
 	"bytes"
 	"os"
+	"strings"
 	"testing"
 
 	sous "github.com/opentable/sous/lib"
@@ -87,3 +88,28 @@ func TestManifestSet(t *testing.T) {
 		assert.Equal(t, args.Get(0).(*sous.Manifest).Flavor, "vanilla")
 	}
 }
+
+func TestManifestSet_denied(t *testing.T) {
+	mani := sous.ManifestFixture("simple")
+	yml, err := yaml.Marshal(mani)
+	require.NoError(t, err)
+
+	updater, upctl := restfultest.NewUpdateSpy()
+	upctl.Any(
+		"Update",
+		&restful.DeniedError{Status: "403 Forbidden", Reason: "x is not an owner"},
+	)
+	up := updater.(restful.Updater)
+
+	sms := &ManifestSet{
+		ManifestID: mani.ID(),
+		InReader:   bytes.NewBuffer(yml),
+		LogSink:    logging.SilentLogSet(),
+		Updater:    &up,
+	}
+
+	err = sms.Do()
+	if err == nil || !strings.Contains(err.Error(), "x is not an owner") || !strings.Contains(err.Error(), "Ask an owner") {
+		t.Errorf("got error %v; want an explanation of the denial", err)
+	}
+}
@@ -0,0 +1,88 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+)
+
+type (
+	// Pause is an Action that stops the server resolving a cluster, or a
+	// deployment to it, until it is resumed.
+	Pause struct {
+		// Target is the cluster, and optionally manifest, to pause.
+		Target     sous.Pause
+		Reason     string
+		HTTPClient restful.HTTPClient
+		User       sous.User
+		LogSink    logging.LogSink
+		OutWriter  io.Writer
+	}
+
+	// Resume is an Action that resumes resolution of a cluster, or a
+	// deployment to it, paused by Pause.
+	Resume struct {
+		// Target is the cluster, and optionally manifest, to resume.
+		Target     sous.Pause
+		HTTPClient restful.HTTPClient
+		User       sous.User
+		LogSink    logging.LogSink
+		OutWriter  io.Writer
+	}
+)
+
+// Do implements Action on Pause.
+func (p *Pause) Do() error {
+	if existing, paused, err := findPause(p.HTTPClient, p.Target, p.User); err != nil {
+		return err
+	} else if paused {
+		return errors.Errorf("%s is already paused: %s", p.Target.Target(), existing)
+	}
+
+	body := sous.Pause{Reason: p.Reason}
+	_, err := p.HTTPClient.Create("./pause", p.Target.QueryMap(), &body, p.User.HTTPHeaders())
+	if err != nil {
+		return errors.Wrapf(err, "pausing %s", p.Target.Target())
+	}
+	messages.ReportLogFieldsMessage("Paused", logging.DebugLevel, p.LogSink, p.Target.QueryMap())
+	fmt.Fprintf(p.OutWriter, "Paused %s. Resume it with sous resume.\n", p.Target.Target())
+	return nil
+}
+
+// Do implements Action on Resume.
+func (r *Resume) Do() error {
+	if _, paused, err := findPause(r.HTTPClient, r.Target, r.User); err != nil {
+		return err
+	} else if !paused {
+		return errors.Errorf("%s is not paused", r.Target.Target())
+	}
+
+	pause := sous.Pause{}
+	existing, err := r.HTTPClient.Retrieve("./pause", r.Target.QueryMap(), &pause, r.User.HTTPHeaders())
+	if err != nil {
+		return errors.Wrapf(err, "retrieving pause of %s", r.Target.Target())
+	}
+	if err := existing.Delete(r.User.HTTPHeaders()); err != nil {
+		return errors.Wrapf(err, "resuming %s", r.Target.Target())
+	}
+	messages.ReportLogFieldsMessage("Resumed", logging.DebugLevel, r.LogSink, r.Target.QueryMap())
+	fmt.Fprintf(r.OutWriter, "Resumed %s.\n", r.Target.Target())
+	return nil
+}
+
+// findPause returns the pause with the same target as target, if there is
+// one.
+func findPause(client restful.HTTPClient, target sous.Pause, user sous.User) (sous.Pause, bool, error) {
+	resp := dto.PausesResponse{}
+	if _, err := client.Retrieve("./pauses", target.QueryMap(), &resp, user.HTTPHeaders()); err != nil {
+		return sous.Pause{}, false, errors.Wrap(err, "retrieving pauses")
+	}
+	existing, paused := resp.Pauses.Find(target)
+	return existing, paused, nil
+}
@@ -0,0 +1,104 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+)
+
+// Plan is an Action that shows what the server would change to bring running
+// deployments in line with the GDM, without changing anything.
+type Plan struct {
+	ResolveFilter *sous.ResolveFilter
+	HTTPClient    restful.HTTPClient
+	LogSink       logging.LogSink
+	OutWriter     io.Writer
+}
+
+// planSymbols mark each kind of change, as in a Terraform plan.
+var planSymbols = map[string]string{
+	sous.AddedKind.String():    "+",
+	sous.ModifiedKind.String(): "~",
+	sous.RemovedKind.String():  "-",
+}
+
+// Do implements Action on Plan.
+func (p *Plan) Do() error {
+	resp := dto.PlanResponse{}
+	if _, err := p.HTTPClient.Retrieve("./plan", filterQuery(p.ResolveFilter), &resp, nil); err != nil {
+		return errors.Wrap(err, "retrieving plan")
+	}
+	messages.ReportLogFieldsMessage("Retrieved plan", logging.ExtraDebug1Level, p.LogSink, p.ResolveFilter)
+
+	plan := sous.Plan{Changes: resp.Changes, Errors: resp.Errors}
+	changed := false
+	for _, c := range plan.Changes {
+		symbol, isChange := planSymbols[c.Kind]
+		if !isChange {
+			continue
+		}
+		if !changed {
+			fmt.Fprintf(p.OutWriter, "Sous would perform the following actions:\n\n")
+			changed = true
+		}
+		if c.Error != nil {
+			symbol = "!"
+		}
+		fmt.Fprintf(p.OutWriter, "  %s %s\n", symbol, c.DeploymentID)
+		p.printDetails(c)
+		fmt.Fprintln(p.OutWriter)
+	}
+	for _, e := range plan.Errors {
+		fmt.Fprintf(p.OutWriter, "  ! %s\n      error: %v\n\n", e.DeploymentID, e.Error)
+	}
+	if !changed {
+		fmt.Fprintf(p.OutWriter, "No changes. Running deployments match the GDM.\n\n")
+	}
+
+	fmt.Fprintf(p.OutWriter, "Plan: %d to create, %d to update, %d to delete, %d unchanged",
+		plan.Count(sous.AddedKind), plan.Count(sous.ModifiedKind), plan.Count(sous.RemovedKind), plan.Count(sous.SameKind))
+	if n := plan.ErrorCount(); n > 0 {
+		fmt.Fprintf(p.OutWriter, ", %d %s", n, plural(n, "error", "errors"))
+	}
+	fmt.Fprintln(p.OutWriter, ".")
+	return nil
+}
+
+// printDetails prints what the change c would do, indented under it.
+func (p *Plan) printDetails(c sous.PlannedChange) {
+	detail := func(format string, a ...interface{}) {
+		fmt.Fprintf(p.OutWriter, "      "+format+"\n", a...)
+	}
+	switch c.Kind {
+	case sous.AddedKind.String():
+		detail("create: version %s, %d instances", c.Post.SourceID.Version, c.Post.NumInstances)
+	case sous.RemovedKind.String():
+		detail("delete: version %s", c.Prior.SourceID.Version)
+	case sous.ModifiedKind.String():
+		for _, d := range c.Differences {
+			detail("%s", d)
+		}
+	}
+	if c.Artifact != nil {
+		detail("artifact: %s", c.Artifact.DigestReference)
+	}
+	if c.Skipped != "" {
+		detail("skipped: %s", c.Skipped)
+	}
+	if c.Error != nil {
+		detail("error: %v", c.Error)
+	}
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,76 @@
+package actions
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nyarly/spies"
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful/restfultest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlan_Do(t *testing.T) {
+	did := func(repo string) sous.DeploymentID {
+		return sous.DeploymentID{
+			ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: repo}},
+			Cluster:    "cluster1",
+		}
+	}
+	dep := func(repo, version string) *sous.Deployment {
+		return &sous.Deployment{
+			ClusterName:  "cluster1",
+			SourceID:     sous.MustParseSourceID(repo + "," + version),
+			DeployConfig: sous.DeployConfig{NumInstances: 2},
+		}
+	}
+	resp := dto.PlanResponse{
+		Changes: []sous.PlannedChange{
+			{DeploymentID: did("github.com/ot/added"), Kind: "added", Post: dep("github.com/ot/added", "1.0.0"),
+				Artifact: &sous.BuildArtifact{DigestReference: "docker.example.com/added@sha256:abc"}},
+			{DeploymentID: did("github.com/ot/broken"), Kind: "added", Post: dep("github.com/ot/broken", "1.0.0"),
+				Error: sous.WrapResolveError(errors.New("no artifact"))},
+			{DeploymentID: did("github.com/ot/modified"), Kind: "modified",
+				Prior: dep("github.com/ot/modified", "1.0.0"), Post: dep("github.com/ot/modified", "2.0.0"),
+				Differences: sous.Differences{`source id; this: "1.0.0"; other: "2.0.0"`}},
+			{DeploymentID: did("github.com/ot/removed"), Kind: "removed", Prior: dep("github.com/ot/removed", "1.0.0")},
+			{DeploymentID: did("github.com/ot/same"), Kind: "same",
+				Prior: dep("github.com/ot/same", "1.0.0"), Post: dep("github.com/ot/same", "1.0.0")},
+		},
+	}
+	httpClient, ctrl := restfultest.NewHTTPClientSpy()
+	ctrl.MatchMethod("Retrieve", spies.AnyArgs, resp, restfultest.DummyUpdater(), nil)
+	ls, _ := logging.NewLogSinkSpy()
+	out := &bytes.Buffer{}
+
+	p := &Plan{
+		ResolveFilter: &sous.ResolveFilter{Cluster: sous.NewResolveFieldMatcher("cluster1")},
+		HTTPClient:    httpClient,
+		LogSink:       ls,
+		OutWriter:     out,
+	}
+	require.NoError(t, p.Do())
+
+	calls := ctrl.CallsTo("Retrieve")
+	require.Len(t, calls, 1)
+	assert.Equal(t, "./plan", calls[0].PassedArgs().String(0))
+	assert.Equal(t, map[string]string{"cluster": "cluster1"}, calls[0].PassedArgs().Get(1))
+
+	got := out.String()
+	for _, want := range []string{
+		"  + cluster1:github.com/ot/added\n      create: version 1.0.0, 2 instances\n      artifact: docker.example.com/added@sha256:abc\n",
+		"  ! cluster1:github.com/ot/broken\n",
+		"      error: no artifact\n",
+		"  ~ cluster1:github.com/ot/modified\n      source id; this: \"1.0.0\"; other: \"2.0.0\"\n",
+		"  - cluster1:github.com/ot/removed\n      delete: version 1.0.0\n",
+		"Plan: 1 to create, 1 to update, 1 to delete, 1 unchanged, 1 error.\n",
+	} {
+		assert.Contains(t, got, want)
+	}
+	assert.False(t, strings.Contains(got, "github.com/ot/same"), "unchanged deployments should not be listed")
+}
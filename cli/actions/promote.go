@@ -0,0 +1,76 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+	"github.com/samsalisbury/semv"
+)
+
+// Promote is an Action that starts promoting a version of a manifest through
+// its Promotion stages, and follows it until it finishes.
+type Promote struct {
+	ManifestID   sous.ManifestID
+	Version      semv.Version
+	HTTPClient   restful.HTTPClient
+	User         sous.User
+	LogSink      logging.LogSink
+	OutWriter    io.Writer
+	PollInterval time.Duration
+}
+
+// Do implements Action on Promote.
+func (p *Promote) Do() error {
+	q := p.ManifestID.QueryMap()
+	q["version"] = p.Version.String()
+
+	run := sous.PromotionRun{}
+	if existing, err := p.HTTPClient.Retrieve("./promotion", q, &run, p.User.HTTPHeaders()); err == nil {
+		if run.Status == sous.PromotionRunning {
+			fmt.Fprintf(p.OutWriter, "Promotion of %s to %s already started by %s; following it.\n",
+				p.ManifestID, p.Version, run.User)
+			return p.follow(q)
+		}
+		if err := existing.Delete(p.User.HTTPHeaders()); err != nil {
+			return errors.Wrap(err, "discarding previous promotion")
+		}
+	}
+
+	_, err := p.HTTPClient.Create("./promotion", q, nil, p.User.HTTPHeaders())
+	if conflict, is := errors.Cause(err).(*restful.ConflictError); is {
+		return errors.Errorf("Promotion of %s refused: %s", p.ManifestID, conflict.Reason)
+	}
+	if err != nil {
+		return errors.Wrap(err, "starting promotion")
+	}
+	messages.ReportLogFieldsMessage("Promotion started", logging.DebugLevel, p.LogSink, p.ManifestID)
+	return p.follow(q)
+}
+
+// follow prints the progress of the promotion until it succeeds or fails.
+func (p *Promote) follow(q map[string]string) error {
+	last := ""
+	for {
+		run := sous.PromotionRun{}
+		if _, err := p.HTTPClient.Retrieve("./promotion", q, &run, p.User.HTTPHeaders()); err != nil {
+			return errors.Wrap(err, "retrieving promotion")
+		}
+		if s := run.String(); s != last {
+			fmt.Fprintln(p.OutWriter, s)
+			last = s
+		}
+		switch run.Status {
+		case sous.PromotionSucceeded:
+			return nil
+		case sous.PromotionFailed:
+			return errors.Errorf("promotion failed: %s", run.Error)
+		}
+		time.Sleep(p.PollInterval)
+	}
+}
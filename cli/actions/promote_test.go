@@ -0,0 +1,86 @@
+package actions
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nyarly/spies"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+	"github.com/opentable/sous/util/restful/restfultest"
+	"github.com/samsalisbury/semv"
+)
+
+func promoteFixture(t *testing.T) (*Promote, *spies.Spy, *bytes.Buffer) {
+	t.Helper()
+	log, _ := logging.NewLogSinkSpy()
+	httpClient, ctrl := restfultest.NewHTTPClientSpy()
+	out := &bytes.Buffer{}
+	return &Promote{
+		ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/opentable/example"}},
+		Version:    semv.MustParse("1.2.3"),
+		HTTPClient: httpClient,
+		LogSink:    log,
+		OutWriter:  out,
+	}, ctrl, out
+}
+
+func promotionRun(status sous.PromotionStatus) sous.PromotionRun {
+	return sous.PromotionRun{
+		ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/opentable/example"}},
+		Version:    semv.MustParse("1.2.3"),
+		Stages:     []sous.PromotionStage{{Clusters: []string{"ci"}}, {Clusters: []string{"prod"}}},
+		Stage:      1,
+		Status:     status,
+		Error:      "prod is on fire",
+	}
+}
+
+func TestPromote_restartsFinishedPromotion(t *testing.T) {
+	p, ctrl, out := promoteFixture(t)
+	existing, existingCtrl := restfultest.NewUpdateSpy()
+	existingCtrl.MatchMethod("Delete", spies.AnyArgs, nil)
+	ctrl.MatchMethod("Retrieve", spies.AnyArgs, promotionRun(sous.PromotionSucceeded), existing, nil)
+	ctrl.MatchMethod("Create", spies.AnyArgs, nil, restfultest.DummyUpdater(), nil)
+
+	if err := p.Do(); err != nil {
+		t.Fatal(err)
+	}
+	if len(existingCtrl.CallsTo("Delete")) != 1 {
+		t.Errorf("expected the finished promotion to be discarded")
+	}
+	creates := ctrl.CallsTo("Create")
+	if len(creates) != 1 {
+		t.Fatalf("got %d calls to Create; want 1", len(creates))
+	}
+	if v := creates[0].PassedArgs().Get(1).(map[string]string)["version"]; v != "1.2.3" {
+		t.Errorf("got version %q; want %q", v, "1.2.3")
+	}
+	if !strings.Contains(out.String(), "in all 2 stages") {
+		t.Errorf("expected success reported, got %q", out.String())
+	}
+}
+
+func TestPromote_failed(t *testing.T) {
+	p, ctrl, _ := promoteFixture(t)
+	ctrl.MatchMethod("Retrieve", spies.AnyArgs, promotionRun(sous.PromotionFailed), restfultest.DummyUpdater(), nil)
+	ctrl.MatchMethod("Create", spies.AnyArgs, nil, restfultest.DummyUpdater(), nil)
+
+	err := p.Do()
+	if err == nil || !strings.Contains(err.Error(), "prod is on fire") {
+		t.Errorf("got error %v; want the promotion's failure", err)
+	}
+}
+
+func TestPromote_refused(t *testing.T) {
+	p, ctrl, _ := promoteFixture(t)
+	ctrl.MatchMethod("Create", spies.AnyArgs, nil, restfultest.DummyUpdater(),
+		&restful.ConflictError{Status: "409 Conflict", Reason: "already running"})
+
+	err := p.Do()
+	if err == nil || !strings.Contains(err.Error(), "refused: already running") {
+		t.Errorf("got error %v; want refusal", err)
+	}
+}
@@ -19,7 +19,8 @@ func (sr *Rectify) Do() error {
 		return err
 	}
 
-	if err := sr.Resolver.Begin(gdm, sr.State.Defs.Clusters).Wait(); err != nil {
+	// Honor the freezes, pauses and trusted keys in the Defs, as the server does.
+	if err := sr.Resolver.BeginWithDefs(gdm, sr.State.Defs).Wait(); err != nil {
 		return err
 	}
 
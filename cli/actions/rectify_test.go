@@ -0,0 +1,35 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/nyarly/spies"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRectify_pausedClusters(t *testing.T) {
+	state := sous.DefaultStateFixture()
+	for name := range state.Defs.Clusters {
+		state.Defs.Pauses = state.Defs.Pauses.Set(sous.Pause{Cluster: name, User: "oncall", Reason: "incident"})
+	}
+
+	deployer, dctrl := sous.NewDeployerSpy()
+	dctrl.MatchMethod("RunningDeployments", spies.AnyArgs, sous.NewDeployStates(), nil)
+	rectified := 0
+	qs := sous.NewR11nQueueSet(sous.R11nQueueStartWithHandler(func(qr *sous.QueuedR11n) sous.DiffResolution {
+		rectified++
+		return sous.DiffResolution{DeploymentID: qr.Rectification.Pair.ID(), Desc: sous.CreateDiff}
+	}))
+	ls := logging.SilentLogSet()
+
+	sr := &Rectify{
+		Resolver: sous.NewResolver(deployer, sous.NewDummyRegistry(), &sous.ResolveFilter{}, ls, qs),
+		State:    state,
+		Log:      ls,
+	}
+	require.NoError(t, sr.Do())
+	assert.Zero(t, rectified, "rectified deployments to paused clusters")
+}
@@ -0,0 +1,100 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/server"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+	"github.com/samsalisbury/semv"
+)
+
+// Rollback is an Action that redeploys the version a deployment had before
+// its current one, or a version given explicitly.
+type Rollback struct {
+	// Deploy performs the redeploy once the target version is known.
+	*Deploy
+	// To is the version to roll back to. If empty, the prior version is found
+	// in the deployment's history.
+	To string
+	// DryRun prints the change instead of making it.
+	DryRun    bool
+	OutWriter io.Writer
+}
+
+// Do implements Action on Rollback.
+func (r *Rollback) Do() error {
+	did := r.TargetDeploymentID
+	d := server.SingleDeploymentBody{}
+	if _, err := r.HTTPClient.Retrieve("./single-deployment", did.QueryMap(), &d, r.User.HTTPHeaders()); err != nil {
+		return errors.Wrapf(err, "retrieving current deployment of %s", did)
+	}
+	if d.Deployment == nil {
+		return errors.Errorf("no deployment of %s to roll back", did)
+	}
+	current := d.Deployment.Version
+
+	target, err := r.targetVersion(did, current)
+	if err != nil {
+		return err
+	}
+	if target.String() == current.String() {
+		return errors.Errorf("%s is already at version %s", did, current)
+	}
+
+	if r.DryRun {
+		return r.printPair(did, d.Deployment, target)
+	}
+
+	messages.ReportLogFieldsMessageToConsole(
+		fmt.Sprintf("Rolling back %s from %s to %s", did, current, target),
+		logging.InformationLevel,
+		r.LogSink,
+	)
+	if err := r.ResolveFilter.SetTag(target.String()); err != nil {
+		return err
+	}
+	r.WaitStable = true
+	return r.Deploy.Do()
+}
+
+// targetVersion returns the version to roll back to: To if given, otherwise
+// the version did had before current.
+func (r *Rollback) targetVersion(did sous.DeploymentID, current semv.Version) (semv.Version, error) {
+	if r.To != "" {
+		v, err := semv.Parse(r.To)
+		return v, errors.Wrapf(err, "parsing -to %q", r.To)
+	}
+	resp := dto.HistoryResponse{}
+	if _, err := r.HTTPClient.Retrieve("./history", did.QueryMap(), &resp, nil); err != nil {
+		return semv.Version{}, errors.Wrapf(err, "retrieving history of %s", did)
+	}
+	v, ok := sous.PriorVersion(resp.Events, did, current)
+	if !ok {
+		return semv.Version{}, errors.Errorf("no version prior to %s recorded for %s: use -to to choose one", current, did)
+	}
+	return v, nil
+}
+
+// printPair prints the differences rolling back would make.
+func (r *Rollback) printPair(did sous.DeploymentID, spec *sous.DeploySpec, target semv.Version) error {
+	prior := &sous.Deployment{
+		SourceID:     did.ManifestID.Source.SourceID(spec.Version),
+		Flavor:       did.ManifestID.Flavor,
+		ClusterName:  did.Cluster,
+		DeployConfig: spec.DeployConfig,
+	}
+	post := prior.Clone()
+	post.SourceID.Version = target
+
+	pair := &sous.DeploymentPair{Prior: prior, Post: post}
+	fmt.Fprintf(r.OutWriter, "Would roll back %s from %s to %s:\n", did, spec.Version, target)
+	for _, diff := range pair.Diffs() {
+		fmt.Fprintf(r.OutWriter, "\t%s\n", diff)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package actions
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nyarly/spies"
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/server"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful/restfultest"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/mock"
+)
+
+func rollbackFixture(t *testing.T, to string, events []sous.HistoryEvent) (*Rollback, *spies.Spy, *spies.Spy, *bytes.Buffer) {
+	t.Helper()
+	log, _ := logging.NewLogSinkSpy()
+	httpClient, ctrl := restfultest.NewHTTPClientSpy()
+
+	current := server.SingleDeploymentBody{
+		Deployment: &sous.DeploySpec{
+			Version:      semv.MustParse("2.0.0"),
+			DeployConfig: sous.DeployConfig{NumInstances: 2},
+		},
+	}
+	retrieving := func(path string) func(mock.Arguments) bool {
+		return func(args mock.Arguments) bool { return args.String(0) == path }
+	}
+	updater, updaterCtrl := restfultest.NewUpdateSpy()
+	ctrl.MatchMethod("Retrieve", retrieving("./single-deployment"), current, updater, nil)
+	ctrl.MatchMethod("Retrieve", retrieving("./history"), dto.HistoryResponse{Events: events}, restfultest.DummyUpdater(), nil)
+
+	out := &bytes.Buffer{}
+	return &Rollback{
+		Deploy: &Deploy{
+			ResolveFilter:      &sous.ResolveFilter{},
+			HTTPClient:         httpClient,
+			TargetDeploymentID: rollbackDID(),
+			LogSink:            log,
+		},
+		To:        to,
+		DryRun:    true,
+		OutWriter: out,
+	}, ctrl, updaterCtrl, out
+}
+
+func rollbackDID() sous.DeploymentID {
+	return sous.DeploymentID{
+		ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/opentable/example"}},
+		Cluster:    "cluster1",
+	}
+}
+
+func versionChange(from, to string) sous.HistoryEvent {
+	before := &sous.Deployment{
+		SourceID:    sous.SourceLocation{Repo: "github.com/opentable/example"}.SourceID(semv.MustParse(from)),
+		ClusterName: "cluster1",
+	}
+	after := before.Clone()
+	after.SourceID.Version = semv.MustParse(to)
+	return sous.HistoryEvent{
+		DeploymentID: rollbackDID(),
+		Change:       sous.HistoryModified,
+		Before:       before,
+		After:        after,
+	}
+}
+
+func TestRollback_DryRun_prior(t *testing.T) {
+	rb, _, updaterCtrl, out := rollbackFixture(t, "", []sous.HistoryEvent{
+		versionChange("0.9.0", "1.0.0"),
+		versionChange("1.0.0", "2.0.0"),
+	})
+	if err := rb.Do(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "from 2.0.0 to 1.0.0") {
+		t.Errorf("got output %q; want rollback from 2.0.0 to 1.0.0", out.String())
+	}
+	if len(updaterCtrl.CallsTo("Update")) != 0 {
+		t.Errorf("dry run should not deploy")
+	}
+}
+
+func TestRollback_DryRun_to(t *testing.T) {
+	rb, ctrl, _, out := rollbackFixture(t, "1.5.0", nil)
+	if err := rb.Do(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "from 2.0.0 to 1.5.0") {
+		t.Errorf("got output %q; want rollback from 2.0.0 to 1.5.0", out.String())
+	}
+	for _, c := range ctrl.CallsTo("Retrieve") {
+		if c.PassedArgs().String(0) == "./history" {
+			t.Errorf("history should not be consulted when -to is given")
+		}
+	}
+}
+
+func TestRollback_no_prior(t *testing.T) {
+	rb, _, _, _ := rollbackFixture(t, "", nil)
+	err := rb.Do()
+	if err == nil || !strings.Contains(err.Error(), "-to") {
+		t.Errorf("got error %v; want one suggesting -to", err)
+	}
+}
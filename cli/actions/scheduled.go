@@ -0,0 +1,82 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+)
+
+// Scheduled is an Action that lists the deploys scheduled with sous deploy
+// -at which haven't been made yet, or cancels one of them.
+type Scheduled struct {
+	ResolveFilter *sous.ResolveFilter
+	// Cancel is the ID of a scheduled deploy to cancel, instead of listing
+	// them.
+	Cancel     string
+	HTTPClient restful.HTTPClient
+	User       sous.User
+	LogSink    logging.LogSink
+	OutWriter  io.Writer
+}
+
+// Do implements Action on Scheduled.
+func (s *Scheduled) Do() error {
+	if s.Cancel != "" {
+		return s.cancel()
+	}
+
+	resp := dto.ScheduledDeploysResponse{}
+	if _, err := s.HTTPClient.Retrieve("./scheduled-deploys", filterQuery(s.ResolveFilter), &resp, s.User.HTTPHeaders()); err != nil {
+		return errors.Wrap(err, "retrieving scheduled deploys")
+	}
+	messages.ReportLogFieldsMessage("Retrieved scheduled deploys", logging.ExtraDebug1Level, s.LogSink, s.ResolveFilter)
+
+	if len(resp.Deploys) == 0 {
+		fmt.Fprintln(s.OutWriter, "No scheduled deploys.")
+		return nil
+	}
+	w := &tabwriter.Writer{}
+	w.Init(s.OutWriter, 2, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tAT\tDEPLOYMENT\tVERSION\tUSER")
+	for _, sd := range resp.Deploys {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			sd.ID, sd.At.Format(time.RFC3339), sd.DeploymentID, sd.Deployment.Version, sd.User)
+	}
+	return w.Flush()
+}
+
+// cancel cancels the scheduled deploy s.Cancel.
+func (s *Scheduled) cancel() error {
+	sd := sous.ScheduledDeploy{}
+	q := map[string]string{"id": s.Cancel}
+	existing, err := s.HTTPClient.Retrieve("./scheduled-deploy", q, &sd, s.User.HTTPHeaders())
+	if err != nil {
+		return errors.Wrapf(err, "retrieving scheduled deploy %s", s.Cancel)
+	}
+	if sd.Status != sous.ScheduledPending {
+		return errors.Errorf("scheduled deploy %s of %s is %s, and can't be cancelled", sd.ID, sd.DeploymentID, sd.Status)
+	}
+
+	err = existing.Delete(s.User.HTTPHeaders())
+	if conflict, is := errors.Cause(err).(*restful.ConflictError); is {
+		return errors.Errorf("Scheduled deploy %s not cancelled: %s", sd.ID, conflict.Reason)
+	}
+	if denied, is := errors.Cause(err).(*restful.DeniedError); is {
+		return errors.Errorf("Not allowed to cancel the deploy of %s: %s", sd.DeploymentID, explainDenial(denied))
+	}
+	if err != nil {
+		return errors.Wrapf(err, "cancelling scheduled deploy %s", sd.ID)
+	}
+	messages.ReportLogFieldsMessage("Cancelled scheduled deploy", logging.DebugLevel, s.LogSink, sd.DeploymentID, q)
+	fmt.Fprintf(s.OutWriter, "Cancelled the deploy of %s at version %s scheduled for %s.\n",
+		sd.DeploymentID, sd.Deployment.Version, sd.At.Format(time.RFC1123))
+	return nil
+}
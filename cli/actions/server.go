@@ -27,6 +27,7 @@ type Server struct {
 	*config.Config
 	ServerHandler http.Handler
 	*sous.AutoResolver
+	DeployScheduler *sous.DeployScheduler
 }
 
 // Do runs the server.
@@ -43,8 +44,17 @@ func (ss *Server) Do() error {
 		reportServerMessage("Auto-resolver DISABLED", ss.DeployFilterFlags, ss.ListenAddr, ss.Log)
 	}
 
+	if ss.DeployScheduler != nil {
+		ss.DeployScheduler.Kickoff()
+	}
+
 	reportServerMessage("Sous Server Running", ss.DeployFilterFlags, ss.ListenAddr, ss.Log)
 
+	if auth := ss.Config.Auth; auth.TLSCertFile != "" {
+		fmt.Printf("Listening on https://%s", ss.ListenAddr)
+		return server.RunTLS(ss.ListenAddr, ss.ServerHandler, auth.TLSCertFile, auth.TLSKeyFile)
+	}
+
 	fmt.Printf("Listening on http://%s", ss.ListenAddr)
 
 	return server.Run(ss.ListenAddr, ss.ServerHandler)
@@ -14,7 +14,7 @@ func TestAddFlagsForRectify(t *testing.T) {
   -all
         all deployments should be considered
   -cluster string
-        the deployment environment to consider
+        the deployment environment to consider: a cluster, a group of clusters, or cluster labels like env=prod
   -flavor string
         flavor is a short string used to differentiate alternative deployments
   -offset string
@@ -31,7 +31,7 @@ const (
 
 	clusterFlagHelp = `
 	-cluster CLUSTER
-		the deployment environment to consider`
+		the deployment environment to consider: a cluster, a group of clusters, or cluster labels like env=prod`
 
 	allFlagHelp = `
 	-all
@@ -53,6 +53,12 @@ var (
 	DeployFilterFlagsHelp = repoFlagHelp + offsetFlagHelp + flavorFlagHelp + clusterFlagHelp + allFlagHelp + tagFlagHelp
 	// NewDeployFilterFlagsHelp is the text and config for deploy flags
 	NewDeployFilterFlagsHelp = repoFlagHelp + offsetFlagHelp + flavorFlagHelp + clusterFlagHelp + tagFlagHelp
+	// RollbackFilterFlagsHelp is the text and config for rollback flags
+	RollbackFilterFlagsHelp = repoFlagHelp + offsetFlagHelp + flavorFlagHelp + clusterFlagHelp
+	// PauseFilterFlagsHelp is the text and config for pause and resume flags
+	PauseFilterFlagsHelp = repoFlagHelp + offsetFlagHelp + flavorFlagHelp + clusterFlagHelp
+	// PromoteFilterFlagsHelp is the text and config for promote flags
+	PromoteFilterFlagsHelp = repoFlagHelp + offsetFlagHelp + flavorFlagHelp + tagFlagHelp
 	// AddArtifactFlagsHelp is the text and config for add artifact flags
 	AddArtifactFlagsHelp = repoFlagHelp + offsetFlagHelp + tagFlagHelp
 )
@@ -19,6 +19,9 @@ type (
 	}
 )
 
+// BuildSubcommands collects the subcommands of `sous build` as they're added.
+var BuildSubcommands = cmdr.Commands{}
+
 func init() { TopLevelCommands["build"] = &SousBuild{} }
 
 const sousBuildHelp = `build your project
@@ -27,8 +30,16 @@ build builds the project in your current directory by default. If you pass it a
 path, it will instead build the project at that path.
 
 args: [path]
+
+subcommands:
+  cache  list or prune the caches of runmount builds
 `
 
+// Subcommands implements Subcommander on SousBuild.
+func (*SousBuild) Subcommands() cmdr.Commands {
+	return BuildSubcommands
+}
+
 // AddFlags adds flags to the build command.
 func (sb *SousBuild) AddFlags(fs *flag.FlagSet) {
 	MustAddFlags(fs, &sb.DeployFilterFlags, SourceFlagsHelp)
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousBuildCache is the `sous build cache` command.
+type SousBuildCache struct{}
+
+// BuildCacheSubcommands collects the subcommands of `sous build cache` as
+// they're added.
+var BuildCacheSubcommands = cmdr.Commands{}
+
+func init() { BuildSubcommands["cache"] = &SousBuildCache{} }
+
+const sousBuildCacheHelp = `list or prune the caches of runmount builds
+
+Runmount builds mount a docker volume at /cache, keyed by the project and its
+lockfiles (go.sum, package-lock.json, pom.xml and so on), so that builds of
+the same dependencies share it. Set Docker.BuildCacheMaxMB to cap the total
+size of the caches; the least recently used are removed after each build to
+stay under it. Set Docker.BuildCacheRegistry to a docker repository to share
+caches between machines: new caches are imported from it, and exported to it
+after their first build.`
+
+// Subcommands implements Subcommander on SousBuildCache.
+func (SousBuildCache) Subcommands() cmdr.Commands {
+	return BuildCacheSubcommands
+}
+
+// Help implements Command on SousBuildCache.
+func (*SousBuildCache) Help() string { return sousBuildCacheHelp }
+
+// Execute implements Executor on SousBuildCache.
+func (*SousBuildCache) Execute(args []string) cmdr.Result {
+	err := cmdr.UsageErrorf("usage: sous build cache <command>")
+	err.Tip = "try `sous help build cache` for a list of commands"
+	return err
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousBuildCacheLs is the `sous build cache ls` command.
+type SousBuildCacheLs struct {
+	SousGraph *graph.SousGraph
+}
+
+func init() { BuildCacheSubcommands["ls"] = &SousBuildCacheLs{} }
+
+const sousBuildCacheLsHelp = `list the caches of runmount builds on this machine
+
+usage: sous build cache ls
+
+Lists each cache's volume, the project that last used it, its size, when it
+was last used, and whether it's shared through Docker.BuildCacheRegistry, most
+recently used first.`
+
+// Help implements Command on SousBuildCacheLs.
+func (*SousBuildCacheLs) Help() string { return sousBuildCacheLsHelp }
+
+// Execute lists the build caches.
+func (sc *SousBuildCacheLs) Execute(args []string) cmdr.Result {
+	list, err := sc.SousGraph.GetBuildCacheList(os.Stdout)
+	if err != nil {
+		return EnsureErrorResult(err)
+	}
+	if err := list.Do(); err != nil {
+		return EnsureErrorResult(err)
+	}
+	return cmdr.Success()
+}
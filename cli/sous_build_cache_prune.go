@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"flag"
+	"os"
+	"time"
+
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousBuildCachePrune is the `sous build cache prune` command.
+type SousBuildCachePrune struct {
+	SousGraph *graph.SousGraph
+
+	maxSizeMB int64
+	olderThan time.Duration
+	all       bool
+}
+
+func init() { BuildCacheSubcommands["prune"] = &SousBuildCachePrune{} }
+
+const sousBuildCachePruneHelp = `remove caches of runmount builds from this machine
+
+usage: sous build cache prune [-max-size <MB>] [-older-than <duration>] [-all]
+
+Removes the caches not used within -older-than, e.g. 72h, and then the least
+recently used caches until the rest total no more than -max-size MB. Without
+either, the caches are pruned to Docker.BuildCacheMaxMB. With -all, every
+cache is removed.`
+
+// Help implements Command on SousBuildCachePrune.
+func (*SousBuildCachePrune) Help() string { return sousBuildCachePruneHelp }
+
+// AddFlags adds the flags for sous build cache prune.
+func (sc *SousBuildCachePrune) AddFlags(fs *flag.FlagSet) {
+	fs.Int64Var(&sc.maxSizeMB, "max-size", -1, "the total size in MB to prune the caches to")
+	fs.DurationVar(&sc.olderThan, "older-than", 0, "remove caches not used within this long")
+	fs.BoolVar(&sc.all, "all", false, "remove every cache")
+}
+
+// Execute prunes the build caches.
+func (sc *SousBuildCachePrune) Execute(args []string) cmdr.Result {
+	if sc.olderThan < 0 {
+		return cmdr.UsageErrorf("-older-than must not be negative")
+	}
+	maxSize := int64(-1)
+	if sc.maxSizeMB >= 0 {
+		maxSize = sc.maxSizeMB * 1024 * 1024
+	}
+	cutoff := time.Time{}
+	if sc.olderThan > 0 {
+		cutoff = time.Now().Add(-sc.olderThan)
+	}
+	if sc.all {
+		cutoff = time.Now()
+	}
+
+	prune, err := sc.SousGraph.GetBuildCachePrune(maxSize, cutoff, os.Stdout)
+	if err != nil {
+		return EnsureErrorResult(err)
+	}
+	if err := prune.Do(); err != nil {
+		return EnsureErrorResult(err)
+	}
+	return cmdr.Success()
+}
@@ -2,6 +2,9 @@ package cli
 
 import (
 	"flag"
+	"fmt"
+	"strings"
+	"time"
 
 	slack "github.com/ashwanthkumar/slack-go-webhook"
 	"github.com/opentable/sous/cli/actions"
@@ -15,7 +18,9 @@ import (
 type SousDeploy struct {
 	SousGraph *graph.SousGraph
 
-	opts graph.DeployActionOpts
+	opts     graph.DeployActionOpts
+	clusters string
+	at       string
 }
 
 func init() { TopLevelCommands["deploy"] = &SousDeploy{} }
@@ -26,6 +31,15 @@ usage: sous deploy (options)
 
 sous deploy will deploy the version tag for this application in the named
 cluster.
+
+With -clusters or -all-clusters, it deploys to several clusters at once,
+waits for all of them, and prints the outcome for each cluster. It fails if
+the deploy to any of them fails.
+
+With -at, the deploy is scheduled for a later time instead, e.g. a
+maintenance window, and sous deploy returns straight away. The server makes
+the deploy as you when it's due, unless the deployment is frozen or paused by
+then. List and cancel scheduled deploys with sous scheduled.
 `
 
 // Help returns the help string for this command.
@@ -44,10 +58,27 @@ func (sd *SousDeploy) AddFlags(fs *flag.FlagSet) {
 			"values are none,scheduler,registry,both")
 	fs.StringVar(&sd.opts.InitSingularityRequestID, "init-singularity-request-id", "",
 		"If this is the first deployment to this cluster; set the Singularity request ID to this value.")
+	fs.StringVar(&sd.clusters, "clusters", "",
+		"comma-separated clusters or groups of clusters to deploy to, instead of -cluster")
+	fs.BoolVar(&sd.opts.AllClusters, "all-clusters", false,
+		"deploy to every cluster the application is deployed to")
+	fs.StringVar(&sd.at, "at", "",
+		"schedule the deploy for this time: RFC3339 (2006-01-02T15:04:05Z07:00), "+
+			"local time (2006-01-02 15:04), or a duration from now (2h30m)")
 }
 
 // Execute fulfills the cmdr.Executor interface.
 func (sd *SousDeploy) Execute(args []string) cmdr.Result {
+	if sd.clusters != "" {
+		sd.opts.Clusters = strings.Split(sd.clusters, ",")
+	}
+	if sd.at != "" {
+		at, err := parseDeployTime(sd.at, time.Now())
+		if err != nil {
+			return cmdr.UsageErrorf("-at: %s", err)
+		}
+		sd.opts.At = at
+	}
 	deploy, err := sd.SousGraph.GetDeploy(sd.opts)
 
 	if err != nil {
@@ -57,7 +88,13 @@ func (sd *SousDeploy) Execute(args []string) cmdr.Result {
 
 	err = deploy.Do()
 
-	sd.slackMessage(deploy, err)
+	if dc, ok := deploy.(*actions.DeployClusters); ok {
+		for _, d := range dc.Deploys {
+			sd.slackMessage(d, dc.Failed[d.TargetDeploymentID.Cluster])
+		}
+	} else {
+		sd.slackMessage(deploy, err)
+	}
 
 	if err != nil {
 		return EnsureErrorResult(err)
@@ -66,7 +103,26 @@ func (sd *SousDeploy) Execute(args []string) cmdr.Result {
 	return cmdr.Success("Done.")
 }
 
+// parseDeployTime parses the time given to -at: an RFC3339 time, a local time
+// to the minute, or a duration after now.
+func parseDeployTime(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", s, now.Location()); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return now.Add(d), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a time like 2006-01-02T15:04:05Z07:00 or 2006-01-02 15:04, or a duration like 2h30m", s)
+}
+
 func (sd *SousDeploy) slackMessage(action actions.Action, err error) {
+	if !sd.opts.At.IsZero() {
+		// Nothing has been deployed yet.
+		return
+	}
 
 	var slackURL, slackChannel string
 	var additionalChannels map[string]string
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"testing"
+	"time"
 
 	"github.com/opentable/sous/cli/actions"
 	"github.com/opentable/sous/config"
@@ -88,3 +89,22 @@ func TestSlackMessage_both(t *testing.T) {
 	assert.NotPanics(t, func() { sd.slackMessage(dAction, nil) }, "shouldn't panic")
 
 }
+
+func TestParseDeployTime(t *testing.T) {
+	now := time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)
+	for in, want := range map[string]time.Time{
+		"2018-03-02T01:30:00Z":      time.Date(2018, 3, 2, 1, 30, 0, 0, time.UTC),
+		"2018-03-02T01:30:00-08:00": time.Date(2018, 3, 2, 9, 30, 0, 0, time.UTC),
+		"2018-03-02 01:30":          time.Date(2018, 3, 2, 1, 30, 0, 0, time.UTC),
+		"2h30m":                     time.Date(2018, 3, 1, 14, 30, 0, 0, time.UTC),
+	} {
+		got, err := parseDeployTime(in, now)
+		if assert.NoError(t, err, in) {
+			assert.True(t, want.Equal(got), "%s: got %s, want %s", in, got, want)
+		}
+	}
+	for _, in := range []string{"tomorrow", "-1h", ""} {
+		_, err := parseDeployTime(in, now)
+		assert.Error(t, err, in)
+	}
+}
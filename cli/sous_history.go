@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"flag"
+	"os"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousHistory is the description of the `sous history` command.
+type SousHistory struct {
+	SousGraph *graph.SousGraph
+
+	DeployFilterFlags config.DeployFilterFlags `inject:"optional"`
+	limit             int
+}
+
+func init() { TopLevelCommands["history"] = &SousHistory{} }
+
+const sousHistoryHelp = `show the history of changes to deployments
+
+usage: sous history [(options)]
+
+sous history lists every recorded change to the intended state of deployments,
+oldest first: who made it, when, and what changed. Use the filter flags to
+narrow the list; with -all, any offset and flavor are included. With -limit,
+only that many of the most recent changes are listed.`
+
+// Help returns the help string for this command.
+func (sh *SousHistory) Help() string { return sousHistoryHelp }
+
+// AddFlags adds the flags for sous history.
+func (sh *SousHistory) AddFlags(fs *flag.FlagSet) {
+	MustAddFlags(fs, &sh.DeployFilterFlags, RectifyFilterFlagsHelp)
+	fs.IntVar(&sh.limit, "limit", 0,
+		"list only this many of the most recent changes")
+}
+
+// Execute lists the history of matching deployments.
+func (sh *SousHistory) Execute(args []string) cmdr.Result {
+	history, err := sh.SousGraph.GetHistory(sh.DeployFilterFlags, sh.limit, os.Stdout)
+	if err != nil {
+		return EnsureErrorResult(err)
+	}
+	if err := history.Do(); err != nil {
+		return EnsureErrorResult(err)
+	}
+	return cmdr.Success()
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"os"
 
+	"github.com/opentable/sous/cli/actions"
 	"github.com/opentable/sous/config"
 	"github.com/opentable/sous/graph"
 	"github.com/opentable/sous/util/cmdr"
@@ -14,6 +15,7 @@ import (
 type SousManifestGet struct {
 	config.DeployFilterFlags `inject:"optional"`
 	SousGraph                *graph.SousGraph
+	expanded                 bool
 }
 
 func init() { ManifestSubcommands["get"] = &SousManifestGet{} }
@@ -26,6 +28,7 @@ func (*SousManifestGet) Help() string { return sousManifestHelp }
 // AddFlags implements AddFlagger on SousManifestGet.
 func (smg *SousManifestGet) AddFlags(fs *flag.FlagSet) {
 	MustAddFlags(fs, &smg.DeployFilterFlags, ManifestFilterFlagsHelp)
+	fs.BoolVar(&smg.expanded, "expanded", false, "print the manifest with its Defaults and Overlays merged into each deployment")
 }
 
 // Execute implements Executor on SousManifestGet.
@@ -35,6 +38,9 @@ func (smg *SousManifestGet) Execute(args []string) cmdr.Result {
 	if err != nil {
 		return EnsureErrorResult(err)
 	}
+	if get, ok := mg.(*actions.ManifestGet); ok {
+		get.Expanded = smg.expanded
+	}
 
 	if err := mg.Do(); err != nil {
 		return EnsureErrorResult(err)
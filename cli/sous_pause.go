@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"flag"
+	"os"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousPause is the description of the `sous pause` command.
+type SousPause struct {
+	SousGraph *graph.SousGraph
+
+	DeployFilterFlags config.DeployFilterFlags `inject:"optional"`
+	reason            string
+}
+
+func init() { TopLevelCommands["pause"] = &SousPause{} }
+
+const sousPauseHelp = `stop the server changing a cluster, or a deployment to it
+
+usage: sous pause -cluster <cluster> [-repo <repo> [-offset <offset>] [-flavor <flavor>]] [-reason <reason>]
+
+sous pause stops the server resolving the deployments to a cluster, or just
+the deployment of a manifest to it if a repo is given, e.g. during an
+incident. Paused deployments are left as they are running, and can't be
+deployed to, until they are resumed with sous resume. Only admins may pause a
+whole cluster.`
+
+// Help returns the help string for this command.
+func (sp *SousPause) Help() string { return sousPauseHelp }
+
+// AddFlags adds the flags for sous pause.
+func (sp *SousPause) AddFlags(fs *flag.FlagSet) {
+	MustAddFlags(fs, &sp.DeployFilterFlags, PauseFilterFlagsHelp)
+	fs.StringVar(&sp.reason, "reason", "", "why the cluster or deployment is paused")
+}
+
+// Execute pauses the cluster or deployment.
+func (sp *SousPause) Execute(args []string) cmdr.Result {
+	pause, err := sp.SousGraph.GetPause(sp.DeployFilterFlags, sp.reason, os.Stdout)
+	if err != nil {
+		return EnsureErrorResult(err)
+	}
+	if err := pause.Do(); err != nil {
+		return EnsureErrorResult(err)
+	}
+	return cmdr.Success()
+}
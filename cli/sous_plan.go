@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"flag"
+	"os"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousPlan is the description of the `sous plan` command.
+type SousPlan struct {
+	SousGraph *graph.SousGraph
+
+	DeployFilterFlags config.DeployFilterFlags `inject:"optional"`
+}
+
+func init() { TopLevelCommands["plan"] = &SousPlan{} }
+
+const sousPlanHelp = `show what the server would change to match the GDM
+
+usage: sous plan [(options)]
+
+sous plan asks the server to compare the running deployments with those in
+the GDM, and to resolve the artifact each changed deployment would be
+deployed from, then lists the deployments it would create, update and delete,
+and any that it can't. Nothing is changed. Use the filter flags to narrow the
+plan to some deployments; by default it covers the whole GDM.`
+
+// Help returns the help string for this command.
+func (sp *SousPlan) Help() string { return sousPlanHelp }
+
+// AddFlags adds the flags for sous plan.
+func (sp *SousPlan) AddFlags(fs *flag.FlagSet) {
+	MustAddFlags(fs, &sp.DeployFilterFlags, RectifyFilterFlagsHelp)
+}
+
+// Execute prints the plan.
+func (sp *SousPlan) Execute(args []string) cmdr.Result {
+	plan, err := sp.SousGraph.GetPlan(sp.DeployFilterFlags, os.Stdout)
+	if err != nil {
+		return EnsureErrorResult(err)
+	}
+	if err := plan.Do(); err != nil {
+		return EnsureErrorResult(err)
+	}
+	return cmdr.Success()
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/opentable/sous/ext/storage"
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousPlumbingSecret is the `sous plumbing secret` command.
+type SousPlumbingSecret struct {
+	Config graph.LocalSousConfig
+}
+
+func init() { PlumbingSubcommands["secret"] = &SousPlumbingSecret{} }
+
+// Help implements Command on SousPlumbingSecret.
+func (*SousPlumbingSecret) Help() string {
+	return `stores a secret in the encrypted secrets file
+
+usage: sous plumbing secret <name> < value
+
+The value is read from stdin, encrypted with Secrets.FileKey, and stored in
+secrets.json in StateLocation, to be committed with the next change to the
+state. Deployments refer to it by setting an Env value to ${secret:<name>}.
+`
+}
+
+// Execute implements cmdr.Executor on SousPlumbingSecret.
+func (sps *SousPlumbingSecret) Execute(args []string) cmdr.Result {
+	if len(args) != 1 {
+		return cmdr.UsageErrorf("Please give the name of the secret.")
+	}
+	sc := sps.Config.Secrets
+	if sc.Store != "file" {
+		return cmdr.UsageErrorf("Secrets.Store is %q; only the file store can be written to by Sous.", sc.Store)
+	}
+	key, err := base64.StdEncoding.DecodeString(sc.FileKey)
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	store, err := storage.NewFileSecretStore(filepath.Join(sps.Config.StateLocation, storage.SecretsFileName), key)
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	value, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	if err := store.SetSecret(args[0], strings.TrimSuffix(string(value), "\n")); err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	return cmdr.Successf("Stored secret %s; refer to it as ${secret:%s}.", args[0], args[0])
+}
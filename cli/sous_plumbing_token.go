@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"flag"
+	"strings"
+	"time"
+
+	"github.com/opentable/sous/graph"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousPlumbingToken is the `sous plumbing token` command.
+type SousPlumbingToken struct {
+	Config graph.LocalSousConfig
+	flags  struct {
+		name, email, groups string
+		expires             time.Duration
+	}
+}
+
+func init() { PlumbingSubcommands["token"] = &SousPlumbingToken{} }
+
+// Help implements Command on SousPlumbingToken.
+func (*SousPlumbingToken) Help() string {
+	return `mints a user token for a Sous server which requires authentication
+
+Tokens are signed with Auth.TokenSecret, which must be the same as the
+server's. The user presents the token by setting SOUS_USER_TOKEN, or User.Token
+in their config.
+`
+}
+
+// AddFlags implements cmdr.AddFlags on SousPlumbingToken.
+func (spt *SousPlumbingToken) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&spt.flags.name, "name", "", "the user's name")
+	fs.StringVar(&spt.flags.email, "email", "", "the user's email address, as listed in manifest Owners")
+	fs.StringVar(&spt.flags.groups, "groups", "", "comma-separated groups the user belongs to, e.g. the admin group")
+	fs.DurationVar(&spt.flags.expires, "expires", 90*24*time.Hour, "how long the token is valid for; 0 means forever")
+}
+
+// Execute implements cmdr.Executor on SousPlumbingToken.
+func (spt *SousPlumbingToken) Execute(args []string) cmdr.Result {
+	if spt.flags.email == "" && spt.flags.name == "" {
+		return cmdr.UsageErrorf("Please specify the user with -email, -name, or both.")
+	}
+	claims := sous.UserClaims{Name: spt.flags.name, Email: spt.flags.email}
+	if spt.flags.groups != "" {
+		claims.Groups = strings.Split(spt.flags.groups, ",")
+	}
+	if spt.flags.expires > 0 {
+		claims.Expires = time.Now().Add(spt.flags.expires).UTC()
+	}
+	token, err := sous.SignUserToken([]byte(spt.Config.Auth.TokenSecret), claims)
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	return cmdr.Success(token)
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"flag"
+	"os"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousPromote is the description of the `sous promote` command.
+type SousPromote struct {
+	SousGraph *graph.SousGraph
+
+	DeployFilterFlags config.DeployFilterFlags `inject:"optional"`
+}
+
+func init() { TopLevelCommands["promote"] = &SousPromote{} }
+
+const sousPromoteHelp = `promote a version through a manifest's promotion stages
+
+usage: sous promote -tag <version> [(options)]
+
+sous promote deploys a version to the clusters of each stage of the manifest's
+Promotion in turn, waiting for each stage to be stable for its StableMinutes
+before moving to the next. The promotion runs on the server; sous promote
+prints its progress until it succeeds or fails.`
+
+// Help returns the help string for this command.
+func (sp *SousPromote) Help() string { return sousPromoteHelp }
+
+// AddFlags adds the flags for sous promote.
+func (sp *SousPromote) AddFlags(fs *flag.FlagSet) {
+	MustAddFlags(fs, &sp.DeployFilterFlags, PromoteFilterFlagsHelp)
+}
+
+// Execute starts a promotion and follows it to completion.
+func (sp *SousPromote) Execute(args []string) cmdr.Result {
+	promote, err := sp.SousGraph.GetPromote(sp.DeployFilterFlags, os.Stdout)
+	if err != nil {
+		return EnsureErrorResult(err)
+	}
+	if err := promote.Do(); err != nil {
+		return EnsureErrorResult(err)
+	}
+	return cmdr.Success()
+}
@@ -45,6 +45,6 @@ func (sb *SousQueryAds) Execute(args []string) cmdr.Result {
 	if err != nil {
 		return EnsureErrorResult(err)
 	}
-	sous.DumpDeployStatuses(os.Stdout, ads)
+	sous.DumpDeployStatuses(os.Stdout, ads, state.Defs.Pauses)
 	return cmdr.Success()
 }
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"flag"
+	"os"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousResume is the description of the `sous resume` command.
+type SousResume struct {
+	SousGraph *graph.SousGraph
+
+	DeployFilterFlags config.DeployFilterFlags `inject:"optional"`
+}
+
+func init() { TopLevelCommands["resume"] = &SousResume{} }
+
+const sousResumeHelp = `let the server change a paused cluster or deployment again
+
+usage: sous resume -cluster <cluster> [-repo <repo> [-offset <offset>] [-flavor <flavor>]]
+
+sous resume undoes sous pause, given the same flags. The server resolves the
+cluster or deployment again from its next cycle.`
+
+// Help returns the help string for this command.
+func (sr *SousResume) Help() string { return sousResumeHelp }
+
+// AddFlags adds the flags for sous resume.
+func (sr *SousResume) AddFlags(fs *flag.FlagSet) {
+	MustAddFlags(fs, &sr.DeployFilterFlags, PauseFilterFlagsHelp)
+}
+
+// Execute resumes the cluster or deployment.
+func (sr *SousResume) Execute(args []string) cmdr.Result {
+	resume, err := sr.SousGraph.GetResume(sr.DeployFilterFlags, os.Stdout)
+	if err != nil {
+		return EnsureErrorResult(err)
+	}
+	if err := resume.Do(); err != nil {
+		return EnsureErrorResult(err)
+	}
+	return cmdr.Success()
+}
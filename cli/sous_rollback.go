@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"flag"
+	"os"
+
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousRollback is the command description for `sous rollback`.
+type SousRollback struct {
+	SousGraph *graph.SousGraph
+
+	opts graph.RollbackActionOpts
+}
+
+func init() { TopLevelCommands["rollback"] = &SousRollback{} }
+
+const sousRollbackHelp = `redeploys the previous version into a particular cluster
+
+usage: sous rollback (options)
+
+sous rollback redeploys the version this application had in the named cluster
+before its current one, and waits for the deploy to complete. The previous
+version is taken from the deployment history the server records; use -to to
+choose a version yourself.
+`
+
+// Help returns the help string for this command.
+func (sr *SousRollback) Help() string { return sousRollbackHelp }
+
+// AddFlags adds the flags for sous rollback.
+func (sr *SousRollback) AddFlags(fs *flag.FlagSet) {
+	MustAddFlags(fs, &sr.opts.DFF, RollbackFilterFlagsHelp)
+
+	fs.StringVar(&sr.opts.To, "to", "",
+		"the version to roll back to, instead of the previous one")
+	fs.BoolVar(&sr.opts.DryRun, "dry-run", false,
+		"print the change rolling back would make, without making it")
+}
+
+// Execute fulfills the cmdr.Executor interface.
+func (sr *SousRollback) Execute(args []string) cmdr.Result {
+	rollback, err := sr.SousGraph.GetRollback(sr.opts, os.Stdout)
+	if err != nil {
+		return EnsureErrorResult(err)
+	}
+	if err := rollback.Do(); err != nil {
+		return EnsureErrorResult(err)
+	}
+	return cmdr.Success("Done.")
+}
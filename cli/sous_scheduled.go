@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"flag"
+	"os"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousScheduled is the description of the `sous scheduled` command.
+type SousScheduled struct {
+	SousGraph *graph.SousGraph
+
+	DeployFilterFlags config.DeployFilterFlags `inject:"optional"`
+	cancel            string
+}
+
+func init() { TopLevelCommands["scheduled"] = &SousScheduled{} }
+
+const sousScheduledHelp = `list or cancel scheduled deploys
+
+usage: sous scheduled [(options)] [-cancel <id>]
+
+sous scheduled lists the deploys scheduled with sous deploy -at which haven't
+been made yet, soonest first. Use the filter flags to narrow the list. Each
+scheduled deploy is kept by the server of its cluster, so give -cluster to ask
+that server.
+
+With -cancel, the scheduled deploy with that ID is cancelled instead.`
+
+// Help returns the help string for this command.
+func (ss *SousScheduled) Help() string { return sousScheduledHelp }
+
+// AddFlags adds the flags for sous scheduled.
+func (ss *SousScheduled) AddFlags(fs *flag.FlagSet) {
+	MustAddFlags(fs, &ss.DeployFilterFlags, RectifyFilterFlagsHelp)
+	fs.StringVar(&ss.cancel, "cancel", "", "the ID of a scheduled deploy to cancel")
+}
+
+// Execute lists or cancels scheduled deploys.
+func (ss *SousScheduled) Execute(args []string) cmdr.Result {
+	scheduled, err := ss.SousGraph.GetScheduled(ss.DeployFilterFlags, ss.cancel, os.Stdout)
+	if err != nil {
+		return EnsureErrorResult(err)
+	}
+	if err := scheduled.Do(); err != nil {
+		return EnsureErrorResult(err)
+	}
+	return cmdr.Success()
+}
@@ -8,6 +8,8 @@ import (
 	"path"
 
 	"github.com/opentable/sous/ext/docker"
+	"github.com/opentable/sous/ext/kubernetes"
+	"github.com/opentable/sous/ext/nomad"
 	"github.com/opentable/sous/ext/storage"
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/firsterr"
@@ -34,6 +36,10 @@ type (
 		// idea is to use it to transition to DB only and then change the behavior
 		// to be (unconditionally) DatabasePrimary=true.
 		DatabasePrimary bool `env:"SOUS_DATABASE_IS_PRIMARY"`
+		// R11nRetentionMinutes is how long the server keeps finished
+		// rectifications in the database, so they can still be looked up after
+		// a restart. Zero means 24 hours.
+		R11nRetentionMinutes int `env:"SOUS_R11N_RETENTION_MINUTES"`
 		// SiblingURLs is a temporary measure for setting up a distributed cluster
 		// of sous servers. Each server must be configured with accessible URLs for
 		// all the servers in production, as named by cluster.
@@ -44,8 +50,14 @@ type (
 		BuildStateDir string `env:"SOUS_BUILD_STATE_DIR"`
 		// Docker is the Docker configuration.
 		Docker docker.Config
+		// Kubernetes is the configuration for deploying to clusters of kind
+		// "kubernetes".
+		Kubernetes kubernetes.Config
 		// Logging is the logging configuration.
 		Logging logging.Config
+		// Nomad is the configuration for deploying to clusters of kind
+		// "nomad".
+		Nomad nomad.Config
 		// User identifies the user of this client.
 		User sous.User
 		// MaxHTTPConcurrencySingularity is the maximum number of concurrent
@@ -59,6 +71,71 @@ type (
 		SlackChannel string `env:"SOUS_SLACK_CHANNEL"`
 		// AdditionalSlackChannels that should receive messages
 		AdditionalSlackChannels map[string]string `env:"SOUS_ADDITIONAL_SLACK_CHANNELS"`
+		// Auth configures how a server authenticates and authorizes writes,
+		// and how a client presents a certificate to a server.
+		Auth AuthConfig
+		// Secrets configures where the secrets referred to by SecretRefs in
+		// deployments' Env are kept.
+		Secrets SecretsConfig
+		// Provenance configures how clients sign the provenance of the images
+		// they build, and how servers verify it.
+		Provenance ProvenanceConfig
+	}
+
+	// AuthConfig configures authentication between clients and servers. A
+	// server with neither TokenSecret nor ClientCAFile set trusts the user
+	// named in request headers, and lets anyone change any manifest.
+	AuthConfig struct {
+		// TokenSecret is the key user tokens are signed with. Servers sign
+		// the admin token they present to their siblings with it too, so
+		// siblings must share it.
+		TokenSecret string `env:"SOUS_AUTH_TOKEN_SECRET"`
+		// ClientCAFile is a PEM file of the CA certificates that client
+		// certificates must be issued by.
+		ClientCAFile string `env:"SOUS_AUTH_CLIENT_CA_FILE"`
+		// AdminGroup is the group whose members may change any manifest, and
+		// the Defs. For certificates, groups are the OrganizationalUnits.
+		AdminGroup string `env:"SOUS_AUTH_ADMIN_GROUP"`
+		// TLSCertFile and TLSKeyFile, when set, make the server serve HTTPS,
+		// so that clients can present certificates.
+		TLSCertFile string `env:"SOUS_AUTH_TLS_CERT_FILE"`
+		TLSKeyFile  string `env:"SOUS_AUTH_TLS_KEY_FILE"`
+		// ClientCertFile and ClientKeyFile are the certificate and key a
+		// client presents to servers.
+		ClientCertFile string `env:"SOUS_AUTH_CLIENT_CERT_FILE"`
+		ClientKeyFile  string `env:"SOUS_AUTH_CLIENT_KEY_FILE"`
+	}
+
+	// SecretsConfig configures the store SecretRefs are resolved from, when
+	// deployments are sent to Singularity.
+	SecretsConfig struct {
+		// Store is "file" to keep secrets encrypted in the file "secrets.json"
+		// in StateLocation, or "vault" to look them up in Vault. If it's
+		// empty, deployments can't refer to secrets.
+		Store string `env:"SOUS_SECRETS_STORE"`
+		// FileKey is the base64 encoded 256 bit AES key the secrets file is
+		// encrypted with.
+		FileKey string `env:"SOUS_SECRETS_FILE_KEY"`
+		// VaultAddr is the URL of the Vault server, e.g.
+		// https://vault.example.com:8200.
+		VaultAddr string `env:"SOUS_SECRETS_VAULT_ADDR"`
+		// VaultToken is the token Sous authenticates to Vault with.
+		VaultToken string `env:"SOUS_SECRETS_VAULT_TOKEN"`
+		// VaultMount is the mount point of the KV version 2 secrets engine
+		// secrets are kept in. The default is "secret".
+		VaultMount string `env:"SOUS_SECRETS_VAULT_MOUNT"`
+	}
+
+	// ProvenanceConfig configures build provenance attestations.
+	ProvenanceConfig struct {
+		// SigningKeyFile is a PEM file of the ECDSA private key a client signs
+		// the provenance of the images it builds with. If it's empty, builds
+		// aren't attested to.
+		SigningKeyFile string `env:"SOUS_PROVENANCE_SIGNING_KEY_FILE"`
+		// TrustedKeysFile is a PEM file of the ECDSA public keys a server
+		// trusts. If it's set, the server refuses to rectify deployments of
+		// images without provenance signed by one of them.
+		TrustedKeysFile string `env:"SOUS_PROVENANCE_TRUSTED_KEYS_FILE"`
 	}
 )
 
@@ -88,14 +165,40 @@ func (c Config) Validate() error {
 	if err := c.Logging.Validate(); err != nil {
 		return errors.Wrapf(err, "Config.Logging")
 	}
+	if (c.Auth.TLSCertFile == "") != (c.Auth.TLSKeyFile == "") {
+		return errors.New("Config.Auth: TLSCertFile and TLSKeyFile must be set together")
+	}
+	if (c.Auth.ClientCertFile == "") != (c.Auth.ClientKeyFile == "") {
+		return errors.New("Config.Auth: ClientCertFile and ClientKeyFile must be set together")
+	}
+	switch c.Secrets.Store {
+	default:
+		return errors.Errorf("Config.Secrets.Store: %q is not one of file or vault", c.Secrets.Store)
+	case "":
+	case "file":
+		if c.Secrets.FileKey == "" {
+			return errors.New("Config.Secrets: FileKey is required by the file store")
+		}
+	case "vault":
+		if err := checkURL(c.Secrets.VaultAddr); err != nil {
+			return errors.Wrapf(err, "Config.Secrets.VaultAddr")
+		}
+	}
 	return nil
 }
 
+// Enabled returns true if a server configured with ac authenticates callers.
+func (ac AuthConfig) Enabled() bool {
+	return ac.TokenSecret != "" || ac.ClientCAFile != ""
+}
+
 // DefaultConfig returns the default configuration.
 func DefaultConfig() Config {
 	return Config{
-		Docker: docker.DefaultConfig(),
+		Docker:                        docker.DefaultConfig(),
+		Kubernetes:                    kubernetes.DefaultConfig(),
 		MaxHTTPConcurrencySingularity: 10,
+		Nomad:                         nomad.DefaultConfig(),
 		PollIntervalForClient:         600,
 	}
 }
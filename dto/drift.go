@@ -0,0 +1,10 @@
+package dto
+
+import sous "github.com/opentable/sous/lib"
+
+// DriftResponse is returned by the server for GET /drift.
+type DriftResponse struct {
+	// Drifts are the deployments which have drifted from what Sous last
+	// applied, sorted by DeploymentID.
+	Drifts []sous.DriftRecord
+}
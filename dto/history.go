@@ -0,0 +1,9 @@
+package dto
+
+import sous "github.com/opentable/sous/lib"
+
+// HistoryResponse is returned by the server for GET /history.
+type HistoryResponse struct {
+	// Events are the recorded changes to deployments, oldest first.
+	Events []sous.HistoryEvent
+}
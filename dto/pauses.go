@@ -0,0 +1,9 @@
+package dto
+
+import sous "github.com/opentable/sous/lib"
+
+// PausesResponse is returned by the server for GET /pauses.
+type PausesResponse struct {
+	// Pauses are the pauses matching the request.
+	Pauses sous.Pauses
+}
@@ -0,0 +1,12 @@
+package dto
+
+import sous "github.com/opentable/sous/lib"
+
+// PlanResponse is returned by the server for GET /plan.
+type PlanResponse struct {
+	// Changes has an entry for every deployment, saying what resolving would
+	// do to it, sorted by DeploymentID.
+	Changes []sous.PlannedChange
+	// Errors are errors from diffing not about a particular deployment.
+	Errors []sous.DiffResolution
+}
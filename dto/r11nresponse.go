@@ -8,4 +8,7 @@ type R11nResponse struct {
 	// Pointer here is just to allow nil which is a clearer indication of
 	// "nothing to see here" than a JSON-marshalled zero value would be.
 	Resolution *sous.DiffResolution
+	// Rollout reports the progress of a progressive rollout while it is
+	// underway.
+	Rollout *sous.RolloutProgress `json:",omitempty"`
 }
@@ -0,0 +1,10 @@
+package dto
+
+import sous "github.com/opentable/sous/lib"
+
+// ScheduledDeploysResponse is returned by the server for GET
+// /scheduled-deploys.
+type ScheduledDeploysResponse struct {
+	// Deploys are the scheduled deploys matching the request, soonest first.
+	Deploys []sous.ScheduledDeploy
+}
@@ -0,0 +1,321 @@
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/opentable/sous/util/shell"
+	"github.com/pkg/errors"
+)
+
+type (
+	// A BuildCache is a docker volume mounted at /cache by runmount builds.
+	BuildCache struct {
+		// Volume is the name of the docker volume.
+		Volume string
+		// Key is derived from the project and its lockfiles, so that builds
+		// of the same dependencies share a cache.
+		Key string
+		// Repo and Offset locate the project that last used the cache.
+		Repo, Offset string
+		// Size is the size of the cache in bytes, as of its last use.
+		Size int64
+		// LastUsed is when a build last used the cache.
+		LastUsed time.Time
+		// Shared is true if the cache is in the cache registry.
+		Shared bool
+	}
+
+	// BuildCaches manages the BuildCaches on this machine. It keeps an index
+	// of them in a JSON file, since docker doesn't record when a volume was
+	// last used.
+	BuildCaches struct {
+		Sh shell.Shell
+		// IndexPath is the path of the index file.
+		IndexPath string
+		// MaxSize caps the total size of the caches, in bytes. The least
+		// recently used caches are removed to stay under it. Zero means no
+		// cap.
+		MaxSize int64
+		// Registry is a docker repository that caches are exported to, and
+		// imported from, tagged with their keys, so that they can be shared
+		// between machines. Empty means caches aren't shared.
+		Registry string
+		log      logging.LogSink
+	}
+)
+
+const (
+	// BuildCacheLabel labels the docker volumes of BuildCaches with their keys.
+	BuildCacheLabel = "com.opentable.sous.build_cache"
+
+	buildCacheVolumePrefix = "sous-cache-"
+	// buildCacheImageDir is where the files of a cache are kept in the image
+	// it's exported as.
+	buildCacheImageDir  = "/sous-cache"
+	buildCacheUtilImage = "busybox"
+)
+
+// buildCacheLockfiles are the files that pin a project's dependencies, and so
+// key its build cache.
+var buildCacheLockfiles = []string{
+	"go.sum",
+	"package-lock.json", "yarn.lock",
+	"pom.xml", "build.gradle", "gradle.lockfile",
+	"Gemfile.lock",
+	"Pipfile.lock", "poetry.lock", "requirements.txt",
+	"Cargo.lock",
+	"composer.lock",
+}
+
+// NewBuildCaches returns a BuildCaches that runs docker with sh, and keeps its
+// index at indexPath.
+func NewBuildCaches(sh shell.Shell, indexPath string, maxSize int64, registry string, ls logging.LogSink) *BuildCaches {
+	return &BuildCaches{Sh: sh, IndexPath: indexPath, MaxSize: maxSize, Registry: registry, log: ls}
+}
+
+// BuildCacheKey returns the key of the build cache of the project in sc. It
+// changes when any of the project's lockfiles do.
+func BuildCacheKey(sc sous.SourceContext) (string, error) {
+	h := sha256.New()
+	loc := sc.SourceLocation()
+	fmt.Fprintf(h, "%s\x00%s\x00", loc.Repo, loc.Dir)
+	for _, name := range buildCacheLockfiles {
+		content, err := ioutil.ReadFile(filepath.Join(sc.AbsDir(), name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", errors.Wrapf(err, "reading %s", name)
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", name, len(content))
+		h.Write(content)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// Acquire returns the cache for the project in sc, creating its volume if
+// need be. A new cache is imported from the cache registry if it's there.
+func (bc *BuildCaches) Acquire(sc sous.SourceContext) (*BuildCache, error) {
+	key, err := BuildCacheKey(sc)
+	if err != nil {
+		return nil, err
+	}
+	index, err := bc.readIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	cache, known := index[key]
+	if !known {
+		loc := sc.SourceLocation()
+		cache = &BuildCache{Volume: buildCacheVolumePrefix + key, Key: key, Repo: loc.Repo, Offset: loc.Dir}
+	}
+	if err := bc.Sh.Run("docker", "volume", "inspect", cache.Volume); err != nil {
+		if err := bc.Sh.Run("docker", "volume", "create", "--label", BuildCacheLabel+"="+key, cache.Volume); err != nil {
+			return nil, errors.Wrapf(err, "creating build cache volume %s", cache.Volume)
+		}
+		cache.Size, cache.Shared = 0, false
+		if bc.Registry != "" {
+			if err := bc.importCache(cache); err != nil {
+				messages.ReportLogFieldsMessage("Couldn't import build cache", logging.WarningLevel, bc.log, cache.Volume, err)
+			} else {
+				cache.Shared = true
+			}
+		}
+	}
+	messages.ReportLogFieldsMessage("Using build cache", logging.DebugLevel, bc.log, cache.Volume, key)
+
+	cache.LastUsed = time.Now()
+	index[key] = cache
+	return cache, bc.writeIndex(index)
+}
+
+// Release records the size of cache after a build has used it, exports it to
+// the cache registry if it isn't there yet, and prunes the caches to MaxSize.
+func (bc *BuildCaches) Release(cache *BuildCache) error {
+	size, err := bc.volumeSize(cache.Volume)
+	if err != nil {
+		return err
+	}
+	cache.Size = size
+	if bc.Registry != "" && !cache.Shared {
+		if err := bc.exportCache(cache); err != nil {
+			messages.ReportLogFieldsMessage("Couldn't export build cache", logging.WarningLevel, bc.log, cache.Volume, err)
+		} else {
+			cache.Shared = true
+		}
+	}
+
+	index, err := bc.readIndex()
+	if err != nil {
+		return err
+	}
+	index[cache.Key] = cache
+	if err := bc.writeIndex(index); err != nil {
+		return err
+	}
+
+	if bc.MaxSize > 0 {
+		_, err = bc.Prune(bc.MaxSize, time.Time{})
+	}
+	return err
+}
+
+// List returns the caches on this machine, most recently used first.
+func (bc *BuildCaches) List() ([]BuildCache, error) {
+	out, err := bc.Sh.Stdout("docker", "volume", "ls", "--quiet", "--filter", "label="+BuildCacheLabel)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing build cache volumes")
+	}
+	index, err := bc.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	byVolume := map[string]*BuildCache{}
+	for _, c := range index {
+		byVolume[c.Volume] = c
+	}
+
+	caches := []BuildCache{}
+	for _, volume := range strings.Fields(out) {
+		if c, known := byVolume[volume]; known {
+			caches = append(caches, *c)
+			continue
+		}
+		caches = append(caches, BuildCache{Volume: volume, Key: strings.TrimPrefix(volume, buildCacheVolumePrefix)})
+	}
+	sort.SliceStable(caches, func(i, j int) bool { return caches[i].LastUsed.After(caches[j].LastUsed) })
+	return caches, nil
+}
+
+// Prune removes the caches not used since cutoff, and then the least recently
+// used caches until the rest total no more than maxSize bytes. It returns the
+// caches it removed. A negative maxSize means no cap, and a zero cutoff no
+// limit on age.
+func (bc *BuildCaches) Prune(maxSize int64, cutoff time.Time) ([]BuildCache, error) {
+	caches, err := bc.List()
+	if err != nil {
+		return nil, err
+	}
+
+	total := int64(0)
+	kept := 0
+	for _, c := range caches {
+		if !cutoff.IsZero() && c.LastUsed.Before(cutoff) {
+			break
+		}
+		total += c.Size
+		kept++
+	}
+	for maxSize >= 0 && kept > 0 && total > maxSize {
+		kept--
+		total -= caches[kept].Size
+	}
+
+	removed := caches[kept:]
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	index, err := bc.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range removed {
+		if err := bc.Sh.Run("docker", "volume", "rm", c.Volume); err != nil {
+			bc.writeIndex(index)
+			return removed[:i], errors.Wrapf(err, "removing build cache volume %s", c.Volume)
+		}
+		delete(index, c.Key)
+		messages.ReportLogFieldsMessage("Removed build cache", logging.DebugLevel, bc.log, c.Volume)
+	}
+	return removed, bc.writeIndex(index)
+}
+
+// volumeSize returns the size of the files in volume, in bytes.
+func (bc *BuildCaches) volumeSize(volume string) (int64, error) {
+	out, err := bc.Sh.Stdout("docker", "run", "--rm", "--mount", "source="+volume+",target=/cache",
+		buildCacheUtilImage, "du", "-sk", "/cache")
+	if err != nil {
+		return 0, errors.Wrapf(err, "measuring build cache volume %s", volume)
+	}
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return 0, errors.Errorf("measuring build cache volume %s: no output from du", volume)
+	}
+	kb, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "measuring build cache volume %s", volume)
+	}
+	return kb * 1024, nil
+}
+
+func (bc *BuildCaches) imageName(cache *BuildCache) string {
+	return bc.Registry + ":" + cache.Key
+}
+
+// importCache copies the files of the image of cache in the cache registry
+// into its volume.
+func (bc *BuildCaches) importCache(cache *BuildCache) error {
+	image := bc.imageName(cache)
+	if err := bc.Sh.Run("docker", "pull", image); err != nil {
+		return err
+	}
+	return bc.Sh.Run("docker", "run", "--rm", "--mount", "source="+cache.Volume+",target=/cache",
+		image, "sh", "-c", "cp -a "+buildCacheImageDir+"/. /cache/")
+}
+
+// exportCache pushes an image of the files in the volume of cache to the
+// cache registry.
+func (bc *BuildCaches) exportCache(cache *BuildCache) error {
+	image := bc.imageName(cache)
+	container := cache.Volume + "-export"
+	err := bc.Sh.Run("docker", "run", "--name", container, "--mount", "source="+cache.Volume+",target=/cache",
+		buildCacheUtilImage, "sh", "-c", "mkdir -p "+buildCacheImageDir+" && cp -a /cache/. "+buildCacheImageDir+"/")
+	defer bc.Sh.Run("docker", "rm", container)
+	if err != nil {
+		return err
+	}
+	if err := bc.Sh.Run("docker", "commit", container, image); err != nil {
+		return err
+	}
+	return bc.Sh.Run("docker", "push", image)
+}
+
+func (bc *BuildCaches) readIndex() (map[string]*BuildCache, error) {
+	index := map[string]*BuildCache{}
+	b, err := ioutil.ReadFile(bc.IndexPath)
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &index); err != nil {
+		return nil, errors.Wrapf(err, "parsing build cache index %s", bc.IndexPath)
+	}
+	return index, nil
+}
+
+func (bc *BuildCaches) writeIndex(index map[string]*BuildCache) error {
+	b, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(bc.IndexPath), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(bc.IndexPath, b, 0644)
+}
@@ -0,0 +1,151 @@
+package docker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/shell"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildCacheTestProject(t *testing.T, files map[string]string) (sous.SourceContext, func()) {
+	dir, err := ioutil.TempDir("", "sous-build-cache")
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "svc"), 0755))
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "svc", name), []byte(content), 0644))
+	}
+	sc := sous.SourceContext{RootDir: dir, OffsetDir: "svc", PrimaryRemoteURL: "github.com/opentable/app"}
+	return sc, func() { os.RemoveAll(dir) }
+}
+
+func TestBuildCacheKey(t *testing.T) {
+	sc, cleanup := buildCacheTestProject(t, map[string]string{"go.sum": "a v1.0.0 h1:abc=\n", "main.go": "package main"})
+	defer cleanup()
+
+	key, err := BuildCacheKey(sc)
+	require.NoError(t, err)
+	assert.Len(t, key, 16)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(sc.RootDir, "svc", "main.go"), []byte("package main\n"), 0644))
+	same, err := BuildCacheKey(sc)
+	require.NoError(t, err)
+	assert.Equal(t, key, same, "only lockfiles change the key")
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(sc.RootDir, "svc", "go.sum"), []byte("a v1.0.1 h1:def=\n"), 0644))
+	changed, err := BuildCacheKey(sc)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, changed)
+
+	sc.OffsetDir = ""
+	other, err := BuildCacheKey(sc)
+	require.NoError(t, err)
+	assert.NotEqual(t, changed, other, "projects don't share caches")
+}
+
+func TestBuildCaches_AcquireRelease(t *testing.T) {
+	sc, cleanup := buildCacheTestProject(t, map[string]string{"package-lock.json": "{}"})
+	defer cleanup()
+	key, err := BuildCacheKey(sc)
+	require.NoError(t, err)
+	volume := buildCacheVolumePrefix + key
+
+	sh, ctl := shell.NewTestShell()
+	_, inspect := ctl.CmdFor("docker", "volume", "inspect")
+	inspect.ResultFailure("", "no such volume")
+	_, pull := ctl.CmdFor("docker", "pull")
+	pull.ResultFailure("", "not found")
+	_, du := ctl.CmdFor("docker", "run", "--rm")
+	du.ResultSuccess("2048\t/cache\n", "")
+
+	bc := NewBuildCaches(sh, filepath.Join(sc.RootDir, "index.json"), 0, "docker.example.com/caches", logging.SilentLogSet())
+	cache, err := bc.Acquire(sc)
+	require.NoError(t, err)
+	assert.Equal(t, volume, cache.Volume)
+	assert.Equal(t, "github.com/opentable/app", cache.Repo)
+	assert.Equal(t, "svc", cache.Offset)
+	assert.False(t, cache.Shared, "the cache couldn't be imported")
+
+	creates := ctl.CmdsLike("docker", "volume", "create")
+	require.Len(t, creates, 1)
+	assert.Equal(t, []interface{}{"volume", "create", "--label", BuildCacheLabel + "=" + key, volume},
+		creates[0].PassedArgs().Get(1))
+
+	require.NoError(t, bc.Release(cache))
+	assert.Equal(t, int64(2048*1024), cache.Size)
+	assert.True(t, cache.Shared)
+	assert.Len(t, ctl.CmdsLike("docker", "commit", volume+"-export", "docker.example.com/caches:"+key), 1)
+	assert.Len(t, ctl.CmdsLike("docker", "push", "docker.example.com/caches:"+key), 1)
+
+	index, err := bc.readIndex()
+	require.NoError(t, err)
+	assert.Equal(t, cache.Size, index[key].Size)
+	assert.True(t, index[key].Shared)
+}
+
+// pruneTestCaches returns BuildCaches with caches used now, an hour ago and
+// two days ago, and one docker has but the index doesn't.
+func pruneTestCaches(t *testing.T) (*BuildCaches, *shell.TestShellController, func()) {
+	dir, err := ioutil.TempDir("", "sous-build-cache")
+	require.NoError(t, err)
+
+	sh, ctl := shell.NewTestShell()
+	_, ls := ctl.CmdFor("docker", "volume", "ls")
+	ls.ResultSuccess("sous-cache-new\nsous-cache-mid\nsous-cache-old\nsous-cache-lost\n", "")
+
+	bc := NewBuildCaches(sh, filepath.Join(dir, "index.json"), 0, "", logging.SilentLogSet())
+	now := time.Now()
+	require.NoError(t, bc.writeIndex(map[string]*BuildCache{
+		"new":  {Volume: "sous-cache-new", Key: "new", Size: 300, LastUsed: now},
+		"mid":  {Volume: "sous-cache-mid", Key: "mid", Size: 200, LastUsed: now.Add(-time.Hour)},
+		"old":  {Volume: "sous-cache-old", Key: "old", Size: 100, LastUsed: now.Add(-48 * time.Hour)},
+		"gone": {Volume: "sous-cache-gone", Key: "gone", Size: 100, LastUsed: now},
+	}))
+	return bc, ctl, func() { os.RemoveAll(dir) }
+}
+
+func volumes(caches []BuildCache) []string {
+	vs := []string{}
+	for _, c := range caches {
+		vs = append(vs, c.Volume)
+	}
+	return vs
+}
+
+func TestBuildCaches_List(t *testing.T) {
+	bc, _, cleanup := pruneTestCaches(t)
+	defer cleanup()
+
+	caches, err := bc.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sous-cache-new", "sous-cache-mid", "sous-cache-old", "sous-cache-lost"}, volumes(caches))
+	assert.Equal(t, "lost", caches[3].Key)
+}
+
+func TestBuildCaches_Prune(t *testing.T) {
+	bc, ctl, cleanup := pruneTestCaches(t)
+	defer cleanup()
+
+	removed, err := bc.Prune(-1, time.Now().Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sous-cache-old", "sous-cache-lost"}, volumes(removed))
+	assert.Len(t, ctl.CmdsLike("docker", "volume", "rm", "sous-cache-old"), 1)
+
+	index, err := bc.readIndex()
+	require.NoError(t, err)
+	assert.Contains(t, index, "mid")
+	assert.NotContains(t, index, "old")
+
+	bc, _, cleanup = pruneTestCaches(t)
+	defer cleanup()
+	removed, err = bc.Prune(450, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sous-cache-mid", "sous-cache-old", "sous-cache-lost"}, volumes(removed),
+		"least recently used first, until under the cap")
+}
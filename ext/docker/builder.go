@@ -79,6 +79,7 @@ func (b *Builder) Register(br *sous.BuildResult) error {
 		if err != nil {
 			return err
 		}
+		b.resolveBaseImages(prod)
 
 		err = b.recordName(prod)
 		if err != nil {
@@ -150,6 +151,23 @@ func (b *Builder) pushToRegistry(bp *sous.BuildProduct) error {
 	return nil
 }
 
+// resolveBaseImages replaces the names of bp's base images with their digest
+// names, as pulled for the build. Names that can't be resolved are kept.
+func (b *Builder) resolveBaseImages(bp *sous.BuildProduct) {
+	for i, name := range bp.BaseImages {
+		if strings.Contains(name, "@") {
+			continue
+		}
+		output, err := b.SourceShell.Stdout("docker", "image", "inspect", "--format={{index .RepoDigests 0}}", name)
+		digestName := strings.TrimSpace(output)
+		if err != nil || digestName == "" {
+			messages.ReportLogFieldsMessage("Couldn't resolve base image digest", logging.DebugLevel, b.log, name, err)
+			continue
+		}
+		bp.BaseImages[i] = digestName
+	}
+}
+
 // recordName inserts metadata about the newly built image into our local name cache
 func (b *Builder) recordName(bp *sous.BuildProduct) error {
 	sv := bp.Source
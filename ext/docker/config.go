@@ -2,6 +2,14 @@ package docker
 
 type Config struct {
 	RegistryHost string `env:"SOUS_DOCKER_REGISTRY_HOST"`
+	// Daemonless builds images without a Docker daemon, using OCIBuildpack.
+	Daemonless bool `env:"SOUS_DOCKER_DAEMONLESS"`
+	// BuildCacheMaxMB caps the total size of runmount build caches, in MB.
+	// Zero means no cap.
+	BuildCacheMaxMB int64 `env:"SOUS_DOCKER_BUILD_CACHE_MAX_MB"`
+	// BuildCacheRegistry is a repository that runmount build caches are
+	// shared through, e.g. docker.example.com/sous-build-cache.
+	BuildCacheRegistry string `env:"SOUS_DOCKER_BUILD_CACHE_REGISTRY"`
 }
 
 // DefaultConfig builds a default configuration, which can be then overridden by
@@ -5,4 +5,6 @@ const (
 	DockerPathLabel     = "com.opentable.sous.repo_offset"
 	DockerVersionLabel  = "com.opentable.sous.version"
 	DockerRevisionLabel = "com.opentable.sous.revision"
+
+	DockerAdvisoriesLabel = "com.opentable.sous.advisories"
 )
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/opentable/sous/lib"
@@ -15,7 +16,9 @@ import (
 // their own Dockerfile.
 type DockerfileBuildpack struct {
 	detected *sous.DetectResult
-	log      logging.LogSink
+	// baseImages are the images the detected Dockerfile builds on.
+	baseImages []string
+	log        logging.LogSink
 }
 
 const (
@@ -30,6 +33,7 @@ const (
 var (
 	appVersionPattern  = regexp.MustCompile(`(?m)^ARG ` + AppVersionBuildArg + `\b`)
 	appRevisionPattern = regexp.MustCompile(`(?m)^ARG ` + AppRevisionBuildArg + `\b`)
+	fromPattern        = regexp.MustCompile(`(?mi)^FROM\s+(?:--\S+\s+)*(\S+)(?:\s+AS\s+(\S+))?`)
 )
 
 // datectData is data passed from the detect step to the build step as the
@@ -44,6 +48,26 @@ type detectData struct {
 
 	// RunImageSpecPath is used by the split container buildpack
 	RunImageSpecPath string
+
+	// Dockerfile, if set, is the path of a generated Dockerfile to build with
+	// instead of the one in the offset directory.
+	Dockerfile string
+}
+
+// dockerfileBaseImages returns the images named by the FROM lines of the
+// Dockerfile df, leaving out earlier build stages and scratch.
+func dockerfileBaseImages(df string) []string {
+	stages := map[string]bool{"scratch": true}
+	images := []string{}
+	for _, m := range fromPattern.FindAllStringSubmatch(df, -1) {
+		if !stages[strings.ToLower(m[1])] {
+			images = append(images, m[1])
+		}
+		if m[2] != "" {
+			stages[strings.ToLower(m[2])] = true
+		}
+	}
+	return images
 }
 
 // NewDockerfileBuildpack creates a Dockerfile buildpack
@@ -74,6 +98,7 @@ func (d *DockerfileBuildpack) Detect(c *sous.BuildContext) (*sous.DetectResult,
 		HasAppRevisionArg: hasAppRevision,
 	}}
 	d.detected = result
+	d.baseImages = dockerfileBaseImages(df)
 	return result, nil
 }
 
@@ -110,6 +135,6 @@ func (d *DockerfileBuildpack) Build(c *sous.BuildContext) (*sous.BuildResult, er
 
 	return &sous.BuildResult{
 		Elapsed:  time.Since(start),
-		Products: []*sous.BuildProduct{{ID: itag}},
+		Products: []*sous.BuildProduct{{ID: itag, BaseImages: d.baseImages}},
 	}, nil
 }
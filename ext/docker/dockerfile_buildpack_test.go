@@ -10,6 +10,7 @@ import (
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/shell"
+	"github.com/stretchr/testify/assert"
 )
 
 var detectTests = []struct {
@@ -141,3 +142,14 @@ func assertResult(expected *sous.DetectResult, actual *sous.DetectResult, err er
 	}
 	return nil
 }
+
+func TestDockerfileBaseImages(t *testing.T) {
+	df := `FROM golang:1.11 AS build
+RUN go build
+FROM --platform=linux/amd64 docker.example.com/base@sha256:0123
+FROM build
+from scratch
+COPY --from=build /app /app
+`
+	assert.Equal(t, []string{"golang:1.11", "docker.example.com/base@sha256:0123"}, dockerfileBaseImages(df))
+}
@@ -272,7 +272,7 @@ func (nc *NameCache) getImageNameAfterHarvest(sid sous.SourceID) (string, strpai
 }
 
 func qualitiesFromLabels(lm map[string]string) []sous.Quality {
-	advs, ok := lm[`com.opentable.sous.advisories`]
+	advs, ok := lm[DockerAdvisoriesLabel]
 	if !ok {
 		return []sous.Quality{}
 	}
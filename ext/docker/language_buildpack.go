@@ -0,0 +1,116 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/template"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
+
+type (
+	// A LanguageBuildpack builds projects that have no Dockerfile, in an
+	// ecosystem recognised by its Language. It generates the Dockerfile of a
+	// build container, which builds the project and emits a runspec, and then
+	// builds it like a SplitBuildpack.
+	LanguageBuildpack struct {
+		Language Language
+		build    *LanguageBuild
+		log      logging.LogSink
+	}
+)
+
+// languageRunSpecPath is where a generated build container keeps its runspec.
+const languageRunSpecPath = "/sous/runspec.json"
+
+// NewLanguageBuildpack returns a new LanguageBuildpack for l.
+func NewLanguageBuildpack(l Language, ls logging.LogSink) *LanguageBuildpack {
+	return &LanguageBuildpack{Language: l, log: ls}
+}
+
+// LanguageBuildpacks returns a LanguageBuildpack for each of Languages.
+func LanguageBuildpacks(ls logging.LogSink) []*LanguageBuildpack {
+	lbps := []*LanguageBuildpack{}
+	for _, l := range Languages {
+		lbps = append(lbps, NewLanguageBuildpack(l, ls))
+	}
+	return lbps
+}
+
+// Detect implements Buildpack on LanguageBuildpack. The description of the
+// result names the detected runtime.
+func (lbp *LanguageBuildpack) Detect(ctx *sous.BuildContext) (*sous.DetectResult, error) {
+	build, err := lbp.Language.Detect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lbp.build = build
+	desc := fmt.Sprintf("%s project, built with %s", lbp.Language.Name(), build.Runtime)
+	messages.ReportLogFieldsMessage("Detected language", logging.DebugLevel, lbp.log, desc)
+	return &sous.DetectResult{Compatible: true, Description: desc, Data: build}, nil
+}
+
+// Build implements Buildpack on LanguageBuildpack.
+func (lbp *LanguageBuildpack) Build(ctx *sous.BuildContext) (*sous.BuildResult, error) {
+	if lbp.build == nil {
+		return nil, errors.Errorf("%s build: nothing detected", lbp.Language.Name())
+	}
+
+	df, err := ioutil.TempFile("", "sous-language-build")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(df.Name())
+	err = lbp.build.dockerfile(df, ctx.Source.OffsetDir)
+	df.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return splitBuild(ctx, &sous.DetectResult{Compatible: true, Data: detectData{
+		HasAppVersionArg:  true,
+		HasAppRevisionArg: true,
+		RunImageSpecPath:  languageRunSpecPath,
+		Dockerfile:        df.Name(),
+	}})
+}
+
+var languageDockerfileTmpl = template.Must(template.New("Dockerfile").Parse(`FROM {{.BuildImage}}
+ARG ` + AppVersionBuildArg + `
+ARG ` + AppRevisionBuildArg + `
+WORKDIR /src
+COPY . /src
+{{range .Steps -}}
+RUN {{.}}
+{{end -}}
+RUN mkdir -p /sous && printf '%s\n' '{{.RunSpec}}' > ` + languageRunSpecPath + `
+ENV ` + SOUS_RUN_IMAGE_SPEC + ` ` + languageRunSpecPath + `
+`))
+
+// dockerfile writes the Dockerfile of the build container for the project in
+// offset.
+func (lb *LanguageBuild) dockerfile(w io.Writer, offset string) error {
+	spec, err := json.Marshal(SplitImageRunSpec{
+		Offset: offset,
+		Image:  sbmImage{Type: "docker", From: lb.RunImage},
+		Files:  lb.Files,
+		Exec:   lb.Exec,
+	})
+	if err != nil {
+		return err
+	}
+	return languageDockerfileTmpl.Execute(w, struct {
+		*LanguageBuild
+		RunSpec string
+	}{
+		LanguageBuild: lb,
+		RunSpec:       strings.Replace(string(spec), "'", `'\''`, -1),
+	})
+}
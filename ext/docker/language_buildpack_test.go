@@ -0,0 +1,96 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/shell"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func languageTestProject(t *testing.T, files map[string]string) (*sous.BuildContext, func()) {
+	dir, err := ioutil.TempDir("", "sous-language")
+	require.NoError(t, err)
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	sh, err := shell.DefaultInDir(dir)
+	require.NoError(t, err)
+	return &sous.BuildContext{Sh: sh}, func() { os.RemoveAll(dir) }
+}
+
+func TestLanguageBuildpacks_Detect(t *testing.T) {
+	cases := []struct {
+		files map[string]string
+		desc  string
+	}{
+		{map[string]string{"go.mod": "module github.com/opentable/widget\n\ngo 1.12\n"},
+			"Go modules project, built with go 1.12"},
+		{map[string]string{"go.mod": "module github.com/opentable/widget\n"},
+			"Go modules project, built with go 1.11"},
+		{map[string]string{"package.json": `{"name": "widget", "engines": {"node": ">=8.9"}}`},
+			"Node project, built with node 8"},
+		{map[string]string{"pom.xml": `<project><properties><maven.compiler.source>1.8</maven.compiler.source>` +
+			`<java.version>11</java.version></properties></project>`},
+			"JVM (Maven) project, built with java 11"},
+		{map[string]string{"pom.xml": `<project><artifactId>widget</artifactId></project>`},
+			"JVM (Maven) project, built with java 8"},
+	}
+
+	for _, c := range cases {
+		ctx, cleanup := languageTestProject(t, c.files)
+		defer cleanup()
+		detected := []string{}
+		for _, lbp := range LanguageBuildpacks(logging.SilentLogSet()) {
+			if dr, err := lbp.Detect(ctx); err == nil && dr.Compatible {
+				detected = append(detected, dr.Description)
+			}
+		}
+		assert.Equal(t, []string{c.desc}, detected)
+	}
+
+	ctx, cleanup := languageTestProject(t, map[string]string{"README.md": "nothing to build"})
+	defer cleanup()
+	for _, lbp := range LanguageBuildpacks(logging.SilentLogSet()) {
+		_, err := lbp.Detect(ctx)
+		assert.Error(t, err, lbp.Language.Name())
+	}
+}
+
+func TestLanguageBuild_dockerfile(t *testing.T) {
+	ctx, cleanup := languageTestProject(t, map[string]string{"go.mod": "module github.com/opentable/widget\ngo 1.12\n"})
+	defer cleanup()
+	lb, err := goLanguage{}.Detect(ctx)
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, lb.dockerfile(buf, "svc"))
+	df := buf.String()
+	assert.True(t, strings.HasPrefix(df, "FROM golang:1.12\n"), df)
+	assert.Contains(t, df, "ARG APP_VERSION\n")
+	assert.Contains(t, df, "RUN go test ./...\n")
+	assert.Contains(t, df, "ENV SOUS_RUN_IMAGE_SPEC /sous/runspec.json\n")
+
+	ast, err := parseDocker(buf)
+	require.NoError(t, err)
+	var spec SplitImageRunSpec
+	for _, node := range ast.Children {
+		if node.Value != "run" || !strings.Contains(node.Next.Value, "printf") {
+			continue
+		}
+		quoted := strings.SplitN(node.Next.Value, "' '", 2)[1]
+		require.NoError(t, json.Unmarshal([]byte(strings.SplitN(quoted, "' >", 2)[0]), &spec))
+	}
+	assert.Empty(t, spec.Validate())
+	assert.Equal(t, "svc", spec.Offset)
+	assert.Equal(t, "alpine:3.8", spec.Image.From)
+	assert.Equal(t, []string{"/srv/app/widget"}, spec.Exec)
+}
@@ -0,0 +1,246 @@
+package docker
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+)
+
+type (
+	// A Language recognises projects of one ecosystem, and describes how to
+	// build them, for a LanguageBuildpack.
+	Language interface {
+		// Name names the ecosystem, e.g. "Go modules".
+		Name() string
+		// Detect returns how to build the project in ctx, or an error if it
+		// isn't one of this Language's.
+		Detect(ctx *sous.BuildContext) (*LanguageBuild, error)
+	}
+
+	// A LanguageBuild describes how to build a project, as the build container
+	// of a split container build.
+	LanguageBuild struct {
+		// Runtime is the detected language runtime, e.g. "go 1.11".
+		Runtime string
+		// BuildImage is the image the project is built in.
+		BuildImage string
+		// Steps are the shell commands that build the project, run in its
+		// source, which is copied to /src.
+		Steps []string
+		// Files are the built files, and where in the deploy image they go.
+		Files []sbmInstall
+		// RunImage is the base of the deploy image.
+		RunImage string
+		// Exec is the command that runs the project in the deploy image.
+		Exec []string
+	}
+
+	goLanguage    struct{}
+	nodeLanguage  struct{}
+	mavenLanguage struct{}
+)
+
+// Languages are the ecosystems recognised by LanguageBuildpacks, in the order
+// they are tried.
+var Languages = []Language{goLanguage{}, nodeLanguage{}, mavenLanguage{}}
+
+const (
+	// languageOutDir is where language builds put the files they install.
+	languageOutDir = "/sous/out"
+	// languageAppDir is where those files go in the deploy image.
+	languageAppDir = "/srv/app"
+
+	defaultGoVersion   = "1.11"
+	defaultNodeVersion = "10"
+	defaultJavaVersion = "8"
+)
+
+func installOutDir() []sbmInstall {
+	return []sbmInstall{{Source: sbmFile{Dir: languageOutDir}, Destination: sbmFile{Dir: languageAppDir}}}
+}
+
+// projectFile returns the path of the file name in ctx's offset directory,
+// or an error if there isn't one.
+func projectFile(ctx *sous.BuildContext, name string) (string, error) {
+	p := filepath.Join(ctx.Source.OffsetDir, name)
+	if !ctx.Sh.Exists(p) {
+		return "", errors.Errorf("%s does not exist", p)
+	}
+	return ctx.Sh.Abs(p), nil
+}
+
+var (
+	goModuleRE  = regexp.MustCompile(`^module\s+"?([^"\s]+)"?`)
+	goVersionRE = regexp.MustCompile(`^go\s+(\d+\.\d+)`)
+)
+
+func (goLanguage) Name() string {
+	return "Go modules"
+}
+
+// Detect implements Language on goLanguage. Projects have a go.mod; the
+// binary is named after the module.
+func (goLanguage) Detect(ctx *sous.BuildContext) (*LanguageBuild, error) {
+	p, err := projectFile(ctx, "go.mod")
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	module, version := "", defaultGoVersion
+	lines := bufio.NewScanner(f)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if m := goModuleRE.FindStringSubmatch(line); m != nil {
+			module = m[1]
+		}
+		if m := goVersionRE.FindStringSubmatch(line); m != nil {
+			version = m[1]
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return nil, err
+	}
+	if module == "" {
+		return nil, errors.Errorf("%s declares no module", p)
+	}
+
+	bin := path.Base(module)
+	return &LanguageBuild{
+		Runtime:    "go " + version,
+		BuildImage: "golang:" + version,
+		Steps: []string{
+			"go test ./...",
+			"mkdir -p " + languageOutDir,
+			`CGO_ENABLED=0 go build -ldflags "-X main.version=${APP_VERSION} -X main.revision=${APP_REVISION}" -o ` +
+				path.Join(languageOutDir, bin) + " .",
+		},
+		Files:    installOutDir(),
+		RunImage: "alpine:3.8",
+		Exec:     []string{path.Join(languageAppDir, bin)},
+	}, nil
+}
+
+var majorVersionRE = regexp.MustCompile(`\d+`)
+
+func (nodeLanguage) Name() string {
+	return "Node"
+}
+
+// Detect implements Language on nodeLanguage. Projects have a package.json,
+// whose engines.node picks the version of Node, and are run with npm start.
+func (nodeLanguage) Detect(ctx *sous.BuildContext) (*LanguageBuild, error) {
+	p, err := projectFile(ctx, "package.json")
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	pkg := struct {
+		Engines struct {
+			Node string `json:"node"`
+		} `json:"engines"`
+	}{}
+	if err := json.NewDecoder(f).Decode(&pkg); err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", p)
+	}
+	version := defaultNodeVersion
+	if v := majorVersionRE.FindString(pkg.Engines.Node); v != "" {
+		version = v
+	}
+
+	install := "npm install"
+	if _, err := projectFile(ctx, "package-lock.json"); err == nil {
+		install = "npm ci"
+	}
+	return &LanguageBuild{
+		Runtime:    "node " + version,
+		BuildImage: "node:" + version,
+		Steps: []string{
+			install,
+			"npm run build --if-present",
+			"npm test",
+			"npm prune --production",
+			"mkdir -p " + path.Dir(languageOutDir),
+			"cp -a /src " + languageOutDir,
+		},
+		Files:    installOutDir(),
+		RunImage: "node:" + version + "-slim",
+		Exec:     []string{"npm", "start", "--prefix", languageAppDir},
+	}, nil
+}
+
+// javaVersionProperties are the pom.xml properties that pick the version of
+// Java, most telling first.
+var javaVersionProperties = []string{"maven.compiler.release", "java.version", "maven.compiler.target", "maven.compiler.source"}
+
+func (mavenLanguage) Name() string {
+	return "JVM (Maven)"
+}
+
+// Detect implements Language on mavenLanguage. Projects have a pom.xml, which
+// packages a single runnable jar.
+func (mavenLanguage) Detect(ctx *sous.BuildContext) (*LanguageBuild, error) {
+	p, err := projectFile(ctx, "pom.xml")
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	pom := struct {
+		Properties struct {
+			Entries []struct {
+				XMLName xml.Name
+				Value   string `xml:",chardata"`
+			} `xml:",any"`
+		} `xml:"properties"`
+	}{}
+	if err := xml.NewDecoder(f).Decode(&pom); err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", p)
+	}
+	props := map[string]string{}
+	for _, e := range pom.Properties.Entries {
+		props[e.XMLName.Local] = strings.TrimSpace(e.Value)
+	}
+	version := defaultJavaVersion
+	for _, name := range javaVersionProperties {
+		if v := props[name]; v != "" && !strings.HasPrefix(v, "$") {
+			version = strings.TrimPrefix(v, "1.")
+			break
+		}
+	}
+
+	jar := path.Join(languageOutDir, "app.jar")
+	return &LanguageBuild{
+		Runtime:    "java " + version,
+		BuildImage: "maven:3-jdk-" + version,
+		Steps: []string{
+			"mvn -B package",
+			"mkdir -p " + languageOutDir,
+			`find target -maxdepth 1 -name '*.jar' ! -name 'original-*' ! -name '*-sources.jar' ! -name '*-javadoc.jar' -exec cp {} ` + jar + ` \;`,
+		},
+		Files:    installOutDir(),
+		RunImage: "openjdk:" + version + "-jre-slim",
+		Exec:     []string{"java", "-jar", path.Join(languageAppDir, "app.jar")},
+	}, nil
+}
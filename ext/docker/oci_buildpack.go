@@ -0,0 +1,299 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/schema2"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/docker_registry"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
+
+type (
+	// An OCIBuildpack builds images without a Docker daemon, for build hosts
+	// that can't run one. It reads a MultiImageRunSpec from runspec.json in
+	// the offset directory: the files it lists must already be built, and are
+	// installed, as a single layer assembled in-process, on the image they
+	// name, which is pulled through the registry client. The images are
+	// pushed straight to the registry, so an OCIBuildpack is also the
+	// Labeller and Registrar of what it builds.
+	OCIBuildpack struct {
+		registry     docker_registry.Client
+		registryHost string
+		inserter     sous.Inserter
+		detected     *sous.DetectResult
+		images       map[*sous.BuildProduct]*ociImage
+		log          logging.LogSink
+	}
+
+	// ociImage is an image built by an OCIBuildpack but not yet pushed.
+	ociImage struct {
+		base   docker_registry.Image
+		layer  *ociLayer
+		config docker_registry.ImageConfig
+		// configJSON is config, as pushed, once ApplyMetadata has labelled it.
+		configJSON []byte
+	}
+)
+
+// OCIRunSpecFile is the name of the file, in the offset directory, that
+// describes the images an OCIBuildpack builds.
+const OCIRunSpecFile = "runspec.json"
+
+// NewOCIBuildpack returns a new OCIBuildpack, which pushes images to
+// registryHost and records their names with nc.
+func NewOCIBuildpack(rc docker_registry.Client, registryHost string, nc sous.Inserter, ls logging.LogSink) *OCIBuildpack {
+	return &OCIBuildpack{
+		registry:     rc,
+		registryHost: registryHost,
+		inserter:     nc,
+		images:       map[*sous.BuildProduct]*ociImage{},
+		log:          ls,
+	}
+}
+
+// Detect implements Buildpack on OCIBuildpack.
+func (ob *OCIBuildpack) Detect(ctx *sous.BuildContext) (*sous.DetectResult, error) {
+	specPath := filepath.Join(ctx.Source.OffsetDir, OCIRunSpecFile)
+	if !ctx.Sh.Exists(specPath) {
+		return nil, errors.Errorf("%s does not exist", specPath)
+	}
+
+	f, err := os.Open(ctx.Sh.Abs(specPath))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	spec := MultiImageRunSpec{}
+	if err := json.NewDecoder(f).Decode(&spec); err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", specPath)
+	}
+	if flaws := spec.Validate(); len(flaws) > 0 {
+		msg := fmt.Sprintf("%s invalid:", specPath)
+		for _, f := range flaws {
+			msg += "\n\t" + f.Repair().Error()
+		}
+		return nil, errors.New(msg)
+	}
+
+	spec = spec.Normalized()
+	froms := []string{}
+	for _, image := range spec.Images {
+		for _, inst := range image.Files {
+			src := filepath.Join(ctx.Source.OffsetDir, inst.Source.Dir)
+			if !ctx.Sh.Exists(src) {
+				return nil, errors.Errorf("%s does not exist: build it before building the image", src)
+			}
+		}
+		froms = append(froms, image.Image.From)
+	}
+
+	messages.ReportLogFieldsMessage("Daemonless build detected", logging.DebugLevel, ob.log, specPath)
+	ob.detected = &sous.DetectResult{
+		Compatible:  true,
+		Description: fmt.Sprintf("daemonless build on %s", strings.Join(froms, ", ")),
+		Data:        spec,
+	}
+	return ob.detected, nil
+}
+
+// Build implements Buildpack on OCIBuildpack. It assembles each image's
+// layer and config; they are labelled by ApplyMetadata and pushed by
+// Register.
+func (ob *OCIBuildpack) Build(ctx *sous.BuildContext) (*sous.BuildResult, error) {
+	start := time.Now()
+	if ob.detected == nil {
+		return nil, errors.New("daemonless build: nothing detected")
+	}
+	spec := ob.detected.Data.(MultiImageRunSpec)
+	root := ctx.Sh.Abs(ctx.Source.OffsetDir)
+
+	br := &sous.BuildResult{}
+	for _, rs := range spec.Images {
+		img, err := ob.buildImage(root, ctx, rs)
+		if err != nil {
+			return nil, err
+		}
+		prod := ob.product(ctx, rs)
+		prod.BaseImages = []string{img.base.CanonicalName}
+		ob.images[prod] = img
+		br.Products = append(br.Products, prod)
+	}
+	br.Elapsed = time.Since(start)
+	return br, nil
+}
+
+func (ob *OCIBuildpack) buildImage(root string, ctx *sous.BuildContext, rs SplitImageRunSpec) (*ociImage, error) {
+	messages.ReportLogFieldsMessage("Fetching base image", logging.DebugLevel, ob.log, rs.Image.From)
+	base, err := ob.registry.GetImage(rs.Image.From)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetching base image %s", rs.Image.From)
+	}
+
+	layer, err := buildLayer(root, rs.Files)
+	if err != nil {
+		return nil, err
+	}
+
+	version := ctx.Version().Version
+	version.Meta = ""
+	now := time.Now().UTC()
+
+	config := base.Config
+	config.Created = &now
+	config.Config.Env = setEnv(config.Config.Env,
+		fmt.Sprintf("%s=%s", AppVersionBuildArg, version),
+		fmt.Sprintf("%s=%s", AppRevisionBuildArg, ctx.RevID()),
+	)
+	config.Config.Cmd = rs.Exec
+	config.RootFS.DiffIDs = append(append([]digest.Digest{}, config.RootFS.DiffIDs...), layer.diffID)
+	config.History = append(append([]docker_registry.ImageHistory{}, config.History...),
+		docker_registry.ImageHistory{Created: &now, CreatedBy: "sous build (daemonless)"})
+
+	return &ociImage{base: base, layer: layer, config: config}, nil
+}
+
+func (ob *OCIBuildpack) product(ctx *sous.BuildContext, rs SplitImageRunSpec) *sous.BuildProduct {
+	advisories := ctx.Advisories
+	if rs.Kind != "" {
+		advisories = append(advisories, sous.NotService)
+	}
+	sid := ctx.Version()
+	if rs.Offset != "" {
+		sid.Location.Dir = rs.Offset
+	}
+	return &sous.BuildProduct{
+		Source:     sid,
+		Kind:       rs.Kind,
+		Advisories: advisories,
+	}
+}
+
+// ApplyMetadata implements sous.Labeller on OCIBuildpack. It names and labels
+// the images built by Build.
+func (ob *OCIBuildpack) ApplyMetadata(br *sous.BuildResult) error {
+	for _, prod := range br.Products {
+		img, ok := ob.images[prod]
+		if !ok {
+			return errors.Errorf("%s was not built by the daemonless builder", prod)
+		}
+		prod.VersionName = versionTag(ob.registryHost, prod.Source, prod.Kind, stripRE, ob.log)
+		prod.RevisionName = revisionTag(ob.registryHost, prod.Source, prod.RevID, prod.Kind, time.Now(), stripRE, ob.log)
+
+		labels := map[string]string{}
+		for k, v := range img.config.Config.Labels {
+			labels[k] = v
+		}
+		for k, v := range Labels(prod.Source, prod.RevID) {
+			labels[k] = v
+		}
+		if len(prod.Advisories) > 0 {
+			labels[DockerAdvisoriesLabel] = strings.Join(prod.Advisories.Strings(), ",")
+		}
+		img.config.Config.Labels = labels
+
+		cj, err := json.Marshal(img.config)
+		if err != nil {
+			return err
+		}
+		img.configJSON = cj
+		prod.ID = digest.FromBytes(cj).String()
+	}
+	return nil
+}
+
+// Register implements sous.Registrar on OCIBuildpack. It pushes the images
+// built by Build to the registry, and records their names.
+func (ob *OCIBuildpack) Register(br *sous.BuildResult) error {
+	for _, prod := range br.Products {
+		img, ok := ob.images[prod]
+		if !ok || img.configJSON == nil {
+			return errors.Errorf("%s was not built and labelled by the daemonless builder", prod)
+		}
+		if err := ob.push(prod, img); err != nil {
+			return errors.Wrapf(err, "pushing %s", prod.VersionName)
+		}
+		img.layer.Remove()
+		delete(ob.images, prod)
+
+		logging.DebugConsole(ob.log, fmt.Sprintf("[recording \"%s\" as the docker name for \"%s\"]", prod.DigestName, prod.Source.String()))
+		if err := ob.inserter.Insert(prod.Source, prod.BuildArtifact()); err != nil {
+			messages.ReportLogFieldsMessage(fmt.Sprintf("Failed to record docker image %s:%s in sous local name cache: %s", prod.Source.String(), prod.DigestName, err.Error()), logging.WarningLevel, ob.log, prod, err)
+		}
+	}
+	return nil
+}
+
+func (ob *OCIBuildpack) push(prod *sous.BuildProduct, img *ociImage) error {
+	repo := fullRepoName(ob.registryHost, prod.Source.Location, prod.Kind, stripRE, ob.log)
+
+	for _, l := range img.base.Manifest.Layers {
+		if err := ob.registry.CopyBlob(repo, img.base.CanonicalName, l); err != nil {
+			return errors.Wrapf(err, "copying base layer %s", l.Digest)
+		}
+	}
+
+	content, err := img.layer.Open()
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	if err := ob.registry.PushBlob(repo, img.layer.desc, content); err != nil {
+		return errors.Wrap(err, "pushing layer")
+	}
+
+	configDesc := distribution.Descriptor{
+		MediaType: schema2.MediaTypeConfig,
+		Size:      int64(len(img.configJSON)),
+		Digest:    digest.FromBytes(img.configJSON),
+	}
+	if err := ob.registry.PushBlob(repo, configDesc, bytes.NewReader(img.configJSON)); err != nil {
+		return errors.Wrap(err, "pushing config")
+	}
+
+	m := schema2.Manifest{
+		Versioned: schema2.SchemaVersion,
+		Config:    configDesc,
+		Layers:    append(append([]distribution.Descriptor{}, img.base.Manifest.Layers...), img.layer.desc),
+	}
+	digestName, err := ob.registry.PushManifest(prod.VersionName, m)
+	if err != nil {
+		return err
+	}
+	if _, err := ob.registry.PushManifest(prod.RevisionName, m); err != nil {
+		return err
+	}
+	prod.DigestName = digestName
+
+	logging.DebugConsole(ob.log, fmt.Sprintf("push to registry versionName: %s, revisionName: %s, digest: %s", prod.VersionName,
+		prod.RevisionName, prod.DigestName), prod)
+	return nil
+}
+
+// setEnv returns env with vars set, replacing any existing values.
+func setEnv(env []string, vars ...string) []string {
+	set := []string{}
+	for _, e := range env {
+		replaced := false
+		for _, v := range vars {
+			if strings.SplitN(e, "=", 2)[0] == strings.SplitN(v, "=", 2)[0] {
+				replaced = true
+			}
+		}
+		if !replaced {
+			set = append(set, e)
+		}
+	}
+	return append(set, vars...)
+}
@@ -0,0 +1,177 @@
+package docker
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/nyarly/spies"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/docker_registry"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/shell"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ociTestRunSpec = `{
+  "image": {"type": "docker", "from": "docker.example.com/base:1"},
+  "files": [{"source": {"dir": "target"}, "dest": {"dir": "/srv/app"}}],
+  "exec": ["/srv/app/run"]
+}`
+
+// ociTestProject makes a project with a runspec in offset "svc", and the
+// files it installs if built is true.
+func ociTestProject(t *testing.T, built bool) (*sous.BuildContext, func()) {
+	dir, err := ioutil.TempDir("", "sous-oci")
+	require.NoError(t, err)
+	svc := filepath.Join(dir, "svc")
+	require.NoError(t, os.MkdirAll(filepath.Join(svc, "target", "lib"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(svc, OCIRunSpecFile), []byte(ociTestRunSpec), 0644))
+	if !built {
+		require.NoError(t, os.RemoveAll(filepath.Join(svc, "target")))
+	} else {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(svc, "target", "run"), []byte("#!/bin/sh\n"), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(svc, "target", "lib", "app.jar"), []byte("jar"), 0644))
+	}
+
+	sh, err := shell.DefaultInDir(dir)
+	require.NoError(t, err)
+	ctx := &sous.BuildContext{
+		Sh: sh,
+		Source: sous.SourceContext{
+			RemoteURL:  "github.com/opentable/app",
+			OffsetDir:  "svc",
+			NearestTag: sous.Tag{Name: "1.2.3"},
+			Revision:   "cabbage",
+		},
+	}
+	return ctx, func() { os.RemoveAll(dir) }
+}
+
+func TestOCIBuildpack_Detect(t *testing.T) {
+	ob := NewOCIBuildpack(docker_registry.NewDummyClient(), "docker.example.com", nil, logging.SilentLogSet())
+
+	ctx, cleanup := ociTestProject(t, true)
+	defer cleanup()
+	dr, err := ob.Detect(ctx)
+	require.NoError(t, err)
+	assert.True(t, dr.Compatible)
+	assert.Contains(t, dr.Description, "docker.example.com/base:1")
+
+	unbuilt, cleanup := ociTestProject(t, false)
+	defer cleanup()
+	_, err = ob.Detect(unbuilt)
+	assert.Error(t, err, "the files to install must be built")
+
+	ctx.Source.OffsetDir = ""
+	_, err = ob.Detect(ctx)
+	assert.Error(t, err, "no runspec")
+}
+
+func TestOCIBuildpack_Build(t *testing.T) {
+	ctx, cleanup := ociTestProject(t, true)
+	defer cleanup()
+
+	baseLayer := distribution.Descriptor{MediaType: schema2.MediaTypeLayer, Size: 10, Digest: digest.FromBytes([]byte("base"))}
+	rc := docker_registry.NewDummyClient()
+	rc.MatchMethod("GetImage", spies.AnyArgs, docker_registry.Image{
+		CanonicalName: "docker.example.com/base@sha256:0000",
+		Manifest:      schema2.Manifest{Versioned: schema2.SchemaVersion, Layers: []distribution.Descriptor{baseLayer}},
+		Config: docker_registry.ImageConfig{
+			Config: docker_registry.ImageRunConfig{
+				Env:    []string{"PATH=/bin", "APP_VERSION=0.0.1"},
+				Labels: map[string]string{"base": "yes"},
+			},
+			RootFS: docker_registry.ImageRootFS{Type: "layers", DiffIDs: []digest.Digest{digest.FromBytes([]byte("base-diff"))}},
+		},
+	}, nil)
+	rc.MatchMethod("PushManifest", spies.AnyArgs, "docker.example.com/app/svc@sha256:1111", nil)
+	inserter, ictrl := sous.NewInserterSpy()
+
+	ob := NewOCIBuildpack(rc, "docker.example.com", inserter, logging.SilentLogSet())
+	_, err := ob.Detect(ctx)
+	require.NoError(t, err)
+	br, err := ob.Build(ctx)
+	require.NoError(t, err)
+	require.Len(t, br.Products, 1)
+	br.Contextualize(ctx)
+	require.NoError(t, ob.ApplyMetadata(br))
+	prod := br.Products[0]
+	assert.Equal(t, "docker.example.com/app/svc:1.2.3", prod.VersionName)
+
+	config := docker_registry.ImageConfig{}
+	require.NoError(t, json.Unmarshal(ob.images[prod].configJSON, &config))
+	assert.Equal(t, []string{"PATH=/bin", "APP_VERSION=1.2.3", "APP_REVISION=cabbage"}, config.Config.Env)
+	assert.Equal(t, []string{"/srv/app/run"}, config.Config.Cmd)
+	assert.Equal(t, "yes", config.Config.Labels["base"])
+	assert.Equal(t, "cabbage", config.Config.Labels[DockerRevisionLabel])
+	assert.Len(t, config.RootFS.DiffIDs, 2)
+	assert.Equal(t, digest.FromBytes(ob.images[prod].configJSON).String(), prod.ID)
+
+	require.NoError(t, ob.Register(br))
+	assert.Equal(t, "docker.example.com/app/svc@sha256:1111", prod.DigestName)
+	assert.Empty(t, ob.images, "pushed images are forgotten")
+
+	copies := rc.CallsTo("CopyBlob")
+	require.Len(t, copies, 1)
+	assert.Equal(t, "docker.example.com/app/svc", copies[0].PassedArgs().String(0))
+	assert.Equal(t, baseLayer, copies[0].PassedArgs().Get(2))
+	assert.Len(t, rc.CallsTo("PushBlob"), 2, "the new layer and the config")
+
+	manifests := rc.CallsTo("PushManifest")
+	require.Len(t, manifests, 2)
+	assert.Equal(t, prod.VersionName, manifests[0].PassedArgs().String(0))
+	assert.Equal(t, prod.RevisionName, manifests[1].PassedArgs().String(0))
+	m := manifests[0].PassedArgs().Get(1).(schema2.Manifest)
+	assert.Len(t, m.Layers, 2)
+	assert.Equal(t, schema2.MediaTypeConfig, m.Config.MediaType)
+
+	assert.Len(t, ictrl.CallsTo("Insert"), 1)
+}
+
+func TestBuildLayer(t *testing.T) {
+	ctx, cleanup := ociTestProject(t, true)
+	defer cleanup()
+	root := ctx.Sh.Abs("svc")
+	files := []sbmInstall{{Source: sbmFile{Dir: "target"}, Destination: sbmFile{Dir: "/srv/app"}}}
+
+	layer, err := buildLayer(root, files)
+	require.NoError(t, err)
+	defer layer.Remove()
+
+	again, err := buildLayer(root, files)
+	require.NoError(t, err)
+	defer again.Remove()
+	assert.Equal(t, layer.desc, again.desc, "layers are reproducible")
+
+	f, err := layer.Open()
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	diff := digest.Canonical.New()
+	tr := tar.NewReader(io.TeeReader(gz, diff.Hash()))
+
+	names := []string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, hdr.Name)
+		assert.Equal(t, layerEpoch, hdr.ModTime.UTC())
+	}
+	io.Copy(ioutil.Discard, gz)
+	assert.Equal(t, []string{"srv/", "srv/app/", "srv/app/lib/", "srv/app/lib/app.jar", "srv/app/run"}, names)
+	assert.Equal(t, layer.diffID, diff.Digest())
+}
@@ -0,0 +1,177 @@
+package docker
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/pkg/errors"
+)
+
+// ociLayer is an image layer assembled in-process, kept as a gzipped tar in
+// a temporary file until it's pushed.
+type ociLayer struct {
+	path string
+	// desc describes the compressed layer, as the registry stores it.
+	desc distribution.Descriptor
+	// diffID is the digest of the uncompressed layer, as the image config
+	// records it.
+	diffID digest.Digest
+}
+
+// layerEpoch is the modification time of every file in an ociLayer, so that
+// the same files always make the same layer.
+var layerEpoch = time.Unix(0, 0).UTC()
+
+// buildLayer makes a layer of the files installed by files, whose source
+// directories are relative to root.
+func buildLayer(root string, files []sbmInstall) (*ociLayer, error) {
+	f, err := ioutil.TempFile("", "sous-layer")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	layer := &ociLayer{path: f.Name()}
+
+	compressed := digest.Canonical.New()
+	uncompressed := digest.Canonical.New()
+	counter := &countingWriter{}
+	gz := gzip.NewWriter(io.MultiWriter(f, compressed.Hash(), counter))
+	tw := tar.NewWriter(io.MultiWriter(gz, uncompressed.Hash()))
+
+	lw := layerWriter{tw: tw, dirs: map[string]bool{}}
+	for _, inst := range files {
+		src := filepath.Join(root, inst.Source.Dir)
+		dest := strings.TrimPrefix(path.Clean("/"+inst.Destination.Dir), "/")
+		if err := lw.addTree(src, dest); err != nil {
+			layer.Remove()
+			return nil, errors.Wrapf(err, "adding %s to layer", inst.Source.Dir)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		layer.Remove()
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		layer.Remove()
+		return nil, err
+	}
+
+	layer.desc = distribution.Descriptor{
+		MediaType: schema2.MediaTypeLayer,
+		Size:      counter.n,
+		Digest:    compressed.Digest(),
+	}
+	layer.diffID = uncompressed.Digest()
+	return layer, nil
+}
+
+// Open returns the compressed layer.
+func (l *ociLayer) Open() (io.ReadCloser, error) {
+	return os.Open(l.path)
+}
+
+// Remove deletes the layer's temporary file.
+func (l *ociLayer) Remove() error {
+	return os.Remove(l.path)
+}
+
+type layerWriter struct {
+	tw   *tar.Writer
+	dirs map[string]bool
+}
+
+// addTree adds src to the layer as dest. A directory's contents are added
+// under dest, as `docker cp` would copy them.
+func (lw layerWriter) addTree(src, dest string) error {
+	if err := lw.addParents(dest); err != nil {
+		return err
+	}
+	return filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		return lw.addFile(p, path.Join(dest, filepath.ToSlash(rel)), info)
+	})
+}
+
+// addParents adds the directories leading to name, which the image's base
+// may not have.
+func (lw layerWriter) addParents(name string) error {
+	dir := path.Dir(name)
+	if dir == "." || dir == "/" || lw.dirs[dir] {
+		return nil
+	}
+	if err := lw.addParents(dir); err != nil {
+		return err
+	}
+	lw.dirs[dir] = true
+	return lw.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     dir + "/",
+		Mode:     0755,
+		ModTime:  layerEpoch,
+	})
+}
+
+func (lw layerWriter) addFile(p, name string, info os.FileInfo) error {
+	link := ""
+	if info.Mode()&os.ModeSymlink != 0 {
+		var err error
+		if link, err = os.Readlink(p); err != nil {
+			return err
+		}
+	}
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if info.IsDir() {
+		if lw.dirs[name] {
+			return nil
+		}
+		lw.dirs[name] = true
+		hdr.Name += "/"
+	}
+	hdr.Uid, hdr.Gid = 0, 0
+	hdr.Uname, hdr.Gname = "", ""
+	hdr.ModTime = layerEpoch
+	hdr.AccessTime, hdr.ChangeTime = time.Time{}, time.Time{}
+
+	if err := lw.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(lw.tw, f)
+	return err
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
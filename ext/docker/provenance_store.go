@@ -0,0 +1,95 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/schema2"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/docker_registry"
+	"github.com/pkg/errors"
+)
+
+// ProvenanceMediaType is the media type of the config blob of the artifacts
+// RegistryProvenanceStore stores provenance as.
+const ProvenanceMediaType = "application/vnd.opentable.sous.provenance.v1+json"
+
+// A RegistryProvenanceStore stores the provenance of images in their
+// registry, next to them: as an artifact in the same repository, tagged
+// after the image's digest, e.g. sha256-abc123.prov.
+type RegistryProvenanceStore struct {
+	registry docker_registry.Client
+}
+
+// NewRegistryProvenanceStore returns a RegistryProvenanceStore that uses rc.
+func NewRegistryProvenanceStore(rc docker_registry.Client) *RegistryProvenanceStore {
+	return &RegistryProvenanceStore{registry: rc}
+}
+
+// ProvenanceName returns the name of the provenance artifact of image, a
+// digest name.
+func ProvenanceName(image string) (string, error) {
+	parts := strings.SplitN(image, "@", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", errors.Errorf("%q is not a digest name", image)
+	}
+	return parts[0] + ":" + strings.Replace(parts[1], ":", "-", 1) + ".prov", nil
+}
+
+// PutProvenance implements sous.ProvenanceStore on RegistryProvenanceStore.
+func (ps *RegistryProvenanceStore) PutProvenance(image string, sp *sous.SignedProvenance) error {
+	name, err := ProvenanceName(image)
+	if err != nil {
+		return err
+	}
+	blob, err := json.Marshal(sp)
+	if err != nil {
+		return err
+	}
+	desc := distribution.Descriptor{MediaType: ProvenanceMediaType, Size: int64(len(blob)), Digest: digest.FromBytes(blob)}
+	repo := strings.SplitN(image, "@", 2)[0]
+	if err := ps.registry.PushBlob(repo, desc, bytes.NewReader(blob)); err != nil {
+		return err
+	}
+	_, err = ps.registry.PushManifest(name, schema2.Manifest{
+		Versioned: schema2.SchemaVersion,
+		Config:    desc,
+		Layers:    []distribution.Descriptor{},
+	})
+	return err
+}
+
+// GetProvenance implements sous.ProvenanceStore on RegistryProvenanceStore.
+func (ps *RegistryProvenanceStore) GetProvenance(image string) (*sous.SignedProvenance, error) {
+	name, err := ProvenanceName(image)
+	if err != nil {
+		return nil, err
+	}
+	m, blob, err := ps.registry.GetConfigBlob(name)
+	if err != nil {
+		return nil, err
+	}
+	if m.Config.MediaType != ProvenanceMediaType {
+		return nil, errors.Errorf("%s is a %q, not provenance", name, m.Config.MediaType)
+	}
+	sp := &sous.SignedProvenance{}
+	if err := json.Unmarshal(blob, sp); err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", name)
+	}
+	return sp, nil
+}
+
+// PutProvenance implements sous.ProvenanceStore on NameCache, storing
+// provenance in its registry.
+func (nc *NameCache) PutProvenance(image string, sp *sous.SignedProvenance) error {
+	return NewRegistryProvenanceStore(nc.RegistryClient).PutProvenance(image, sp)
+}
+
+// GetProvenance implements sous.ProvenanceStore on NameCache, so that the
+// signatures of the images it resolves can be verified against its registry.
+func (nc *NameCache) GetProvenance(image string) (*sous.SignedProvenance, error) {
+	return NewRegistryProvenanceStore(nc.RegistryClient).GetProvenance(image)
+}
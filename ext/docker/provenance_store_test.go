@@ -0,0 +1,49 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/nyarly/spies"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/docker_registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryProvenanceStore(t *testing.T) {
+	image := "docker.example.com/app@sha256:0123"
+	name, err := ProvenanceName(image)
+	require.NoError(t, err)
+	assert.Equal(t, "docker.example.com/app:sha256-0123.prov", name)
+	_, err = ProvenanceName("docker.example.com/app:1.0.0")
+	assert.Error(t, err)
+
+	rc := docker_registry.NewDummyClient()
+	ps := NewRegistryProvenanceStore(rc)
+	sp := &sous.SignedProvenance{Payload: []byte(`{"Image":"` + image + `"}`), KeyID: "key", Signature: []byte("sig")}
+	require.NoError(t, ps.PutProvenance(image, sp))
+
+	blobs := rc.CallsTo("PushBlob")
+	require.Len(t, blobs, 1)
+	assert.Equal(t, "docker.example.com/app", blobs[0].PassedArgs().String(0))
+	manifests := rc.CallsTo("PushManifest")
+	require.Len(t, manifests, 1)
+	assert.Equal(t, name, manifests[0].PassedArgs().String(0))
+	m := manifests[0].PassedArgs().Get(1).(schema2.Manifest)
+	assert.Equal(t, ProvenanceMediaType, m.Config.MediaType)
+	assert.Empty(t, m.Layers)
+
+	blob := []byte(`{"Payload":"eyJJbWFnZSI6IngifQ==","KeyID":"key","Signature":"c2ln"}`)
+	rc.MatchMethod("GetConfigBlob", spies.AnyArgs, m, blob, nil)
+	got, err := ps.GetProvenance(image)
+	require.NoError(t, err)
+	assert.Equal(t, `{"Image":"x"}`, string(got.Payload))
+	assert.Equal(t, "key", got.KeyID)
+
+	m.Config.MediaType = schema2.MediaTypeConfig
+	rc = docker_registry.NewDummyClient()
+	rc.MatchMethod("GetConfigBlob", spies.AnyArgs, m, blob, nil)
+	_, err = NewRegistryProvenanceStore(rc).GetProvenance(image)
+	assert.Error(t, err, "an image isn't provenance")
+}
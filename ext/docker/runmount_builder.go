@@ -37,10 +37,10 @@ func build(ctx sous.BuildContext) (string, error) {
 	return itag, nil
 }
 
-func run(ctx sous.BuildContext, buildID string) error {
+func run(ctx sous.BuildContext, buildID, cacheVolume string) error {
 	fmt.Println("starting runmount run")
 	// TODO LH may need to house keep /app/product ?? or do that after artifact is fetched, possible to collide on this on the same agent ?
-	cmd := []interface{}{"run", "--mount", "source=cache,target=/cache",
+	cmd := []interface{}{"run", "--mount", "source=" + cacheVolume + ",target=/cache",
 		"--mount", "source=build_output,target=/build_output"}
 	cmd = append(cmd, buildID)
 
@@ -275,6 +275,7 @@ func product(ctx sous.BuildContext, builder *runnableBuilder) *sous.BuildProduct
 		Advisories:   advisories,
 		VersionName:  versionNameLocal(ctx),
 		RevisionName: revisionNameLocal(ctx),
+		BaseImages:   []string{builder.RunSpec.Image.From},
 	}
 
 	return bp
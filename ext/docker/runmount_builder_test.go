@@ -36,7 +36,7 @@ func TestRunmountBuild_run(t *testing.T) {
 		Sh: sh,
 	}
 
-	err := run(ctx, testBuildID)
+	err := run(ctx, testBuildID, "cache")
 	assert.Empty(t, err)
 }
 
@@ -16,6 +16,9 @@ type (
 	// RunmountBuildpack builds a container, runs it seperately to use docker mounts for cache and output,
 	// and builds final deploy container
 	RunmountBuildpack struct {
+		// Caches keys, sizes and shares the cache volumes of builds. If it's
+		// nil, every build shares the same cache volume.
+		Caches   *BuildCaches
 		detected *sous.DetectResult
 		log      logging.LogSink
 	}
@@ -69,7 +72,22 @@ func (rmbp *RunmountBuildpack) Build(ctx *sous.BuildContext) (*sous.BuildResult,
 		return nil, err
 	}
 
-	err = run(*ctx, buildID)
+	volume := "cache"
+	var cache *BuildCache
+	if rmbp.Caches != nil {
+		cache, err = rmbp.Caches.Acquire(ctx.Source)
+		if err != nil {
+			return nil, err
+		}
+		volume = cache.Volume
+	}
+
+	err = run(*ctx, buildID, volume)
+	if cache != nil {
+		if err := rmbp.Caches.Release(cache); err != nil {
+			messages.ReportLogFieldsMessage("Couldn't release build cache", logging.WarningLevel, rmbp.log, cache.Volume, err)
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -11,18 +11,31 @@ import (
 )
 
 type selector struct {
-	regClient docker_registry.Client
-	log       logging.LogSink
+	regClient  docker_registry.Client
+	caches     *BuildCaches
+	daemonless *OCIBuildpack
+	log        logging.LogSink
 }
 
-// NewBuildStrategySelector constructs a sous.Selector that uses docker build images as its strategies
-func NewBuildStrategySelector(ls logging.LogSink, rc docker_registry.Client) sous.Selector {
-	return &selector{regClient: rc, log: ls}
+// NewBuildStrategySelector constructs a sous.Selector that uses docker build images as its strategies.
+// Runmount builds use caches, if it's not nil.
+// If daemonless is not nil, it is the only strategy, and images are built without a Docker daemon.
+func NewBuildStrategySelector(ls logging.LogSink, rc docker_registry.Client, caches *BuildCaches, daemonless *OCIBuildpack) sous.Selector {
+	return &selector{regClient: rc, caches: caches, daemonless: daemonless, log: ls}
 }
 
 // SelectBuildpack tries to select a buildpack for this BuildContext.
 func (s *selector) SelectBuildpack(ctx *sous.BuildContext) (sous.Buildpack, error) {
+	if s.daemonless != nil {
+		if _, err := s.daemonless.Detect(ctx); err != nil {
+			return nil, fmt.Errorf("cannot build without a Docker daemon: %s", err)
+		}
+		reportStrategyChoice("daemonless image builder", s.log)
+		return s.daemonless, nil
+	}
+
 	rmbp := NewRunmountBuildpack(s.log)
+	rmbp.Caches = s.caches
 	dr, err := rmbp.Detect(ctx)
 	if err == nil && dr.Compatible {
 		reportStrategyChoice("runmount container", s.log)
@@ -42,7 +55,15 @@ func (s *selector) SelectBuildpack(ctx *sous.BuildContext) (sous.Buildpack, erro
 		reportStrategyChoice("simple dockerfile", s.log)
 		return dfbp, nil
 	}
-	return nil, errors.New("no Dockerfile present")
+
+	for _, lbp := range LanguageBuildpacks(s.log) {
+		dr, err = lbp.Detect(ctx)
+		if err == nil && dr.Compatible {
+			reportStrategyChoice(dr.Description, s.log)
+			return lbp, nil
+		}
+	}
+	return nil, errors.New("no Dockerfile present, and no known language detected")
 }
 
 type strategyChoiceMessage struct {
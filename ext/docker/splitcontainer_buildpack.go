@@ -96,7 +96,12 @@ func (sbp *SplitBuildpack) Detect(ctx *sous.BuildContext) (*sous.DetectResult, e
 
 // Build implements Buildpack on SplitBuildpack
 func (sbp *SplitBuildpack) Build(ctx *sous.BuildContext) (*sous.BuildResult, error) {
-	drez := sbp.detected
+	return splitBuild(ctx, sbp.detected)
+}
+
+// splitBuild builds the build container detected by drez, and then the
+// deploy containers described by the runspec it produces.
+func splitBuild(ctx *sous.BuildContext, drez *sous.DetectResult) (*sous.BuildResult, error) {
 	script := splitBuilder{context: ctx, detected: drez, subBuilders: []*runnableBuilder{}}
 
 	/*
@@ -120,6 +120,7 @@ func (rb *runnableBuilder) product() *sous.BuildProduct {
 		Advisories:   advisories,
 		VersionName:  rb.versionName(),
 		RevisionName: rb.revisionName(),
+		BaseImages:   []string{rb.RunSpec.Image.From},
 	}
 
 	return bp
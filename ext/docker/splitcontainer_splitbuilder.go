@@ -70,6 +70,10 @@ func (sb *splitBuilder) buildBuild() error {
 		cmd = append(cmd, "--build-arg", sb.revisionConfig())
 	}
 
+	if r.Dockerfile != "" {
+		cmd = append(cmd, "-f", r.Dockerfile)
+	}
+
 	itag := intermediateTag()
 	cmd = append(cmd, "-t", itag)
 
@@ -30,6 +30,28 @@ func TestSplitBuilder_BuildBuild(t *testing.T) {
 	assert.Regexp(t, `^sousintermediate.*`, builder.buildImageID)
 }
 
+func TestSplitBuilder_BuildBuild_generatedDockerfile(t *testing.T) {
+	sh, ctl := shell.NewTestShell()
+
+	builder := splitBuilder{
+		context: &sous.BuildContext{
+			Sh: sh,
+		},
+		detected: &sous.DetectResult{
+			Data: detectData{Dockerfile: "/tmp/Dockerfile"},
+		},
+	}
+
+	_, cctl := ctl.CmdFor("docker", "build")
+	cctl.ResultSuccess("Successfully built cabba9edeadbeef", "")
+
+	assert.NoError(t, builder.buildBuild())
+
+	args := ctl.CmdsLike("docker", "build")[0].PassedArgs().Get(1).([]interface{})
+	assert.Contains(t, args, "-f")
+	assert.Contains(t, args, "/tmp/Dockerfile")
+}
+
 func TestSplitBuilder_SetupTempdir(t *testing.T) {
 	builder := splitBuilder{}
 	assert.NoError(t, builder.setupTempdir())
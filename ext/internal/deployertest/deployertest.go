@@ -0,0 +1,73 @@
+// Package deployertest holds test support shared by the Deployers of the
+// cluster kinds Sous drives itself. It is only imported by their tests.
+package deployertest
+
+import (
+	"github.com/nyarly/spies"
+	"github.com/opentable/sous/ext/docker"
+	sous "github.com/opentable/sous/lib"
+	"github.com/samsalisbury/semv"
+)
+
+type (
+	// A Scenario is a single cluster, and a registry that labels the images
+	// deployed to it.
+	Scenario struct {
+		Registry sous.Registry
+		Clusters sous.Clusters
+		cluster  string
+	}
+
+	// MapSecretStore is a sous.SecretStore in memory.
+	MapSecretStore map[string]string
+)
+
+// NewScenario returns a Scenario with one cluster of kind, called name and
+// served at baseURL.
+func NewScenario(name, kind, baseURL string) *Scenario {
+	sid := sous.SourceID{
+		Location: sous.SourceLocation{Repo: "github.com/opentable/example"},
+		Version:  semv.MustParse("1.2.3"),
+	}
+	reg, ctrl := sous.NewRegistrySpy()
+	ctrl.MatchMethod("ImageLabels", spies.AnyArgs, docker.Labels(sid, "cabbage"), nil)
+
+	return &Scenario{
+		Registry: reg,
+		Clusters: sous.Clusters{name: &sous.Cluster{Name: name, Kind: kind, BaseURL: baseURL}},
+		cluster:  name,
+	}
+}
+
+// Deployable returns a Deployable of a manifest of kind to s's cluster.
+func (s *Scenario) Deployable(kind sous.ManifestKind) *sous.Deployable {
+	dep := sous.DeploymentFixture("")
+	dep.ClusterName = s.cluster
+	dep.Cluster = s.Clusters[s.cluster]
+	dep.SourceID.Version = semv.MustParse("1.2.3+cabbage")
+	dep.SingularityRequestID = ""
+	dep.Kind = kind
+	dep.Owners = sous.OwnerSet{}
+	dep.Owners.Add("judson")
+	dep.Env = sous.Env{"GREETING": "hello"}
+	dep.Volumes = sous.Volumes{{Host: "/data", Container: "/srv/data", Mode: sous.ReadOnly}}
+	if kind == sous.ManifestKindScheduled {
+		dep.Schedule = "*/5 * * * *"
+	}
+	return &sous.Deployable{
+		Status:     sous.DeployStatusActive,
+		Deployment: dep,
+		BuildArtifact: &sous.BuildArtifact{
+			Type:            "docker",
+			DigestReference: "docker.example.com/example@sha256:0123456789",
+		},
+	}
+}
+
+// Secret implements sous.SecretStore on MapSecretStore.
+func (ss MapSecretStore) Secret(name string) (string, error) {
+	if v, has := ss[name]; has {
+		return v, nil
+	}
+	return "", &sous.NoSuchSecretError{Name: name}
+}
@@ -0,0 +1,29 @@
+package kubernetes
+
+// Config is the configuration for Kubernetes deployers.
+type Config struct {
+	// Namespace is the Kubernetes namespace Sous deploys into.
+	Namespace string `env:"SOUS_KUBERNETES_NAMESPACE"`
+	// BearerToken authenticates Sous to Kubernetes API servers.
+	BearerToken string `env:"SOUS_KUBERNETES_TOKEN"`
+}
+
+// DefaultConfig builds a default configuration, which can be then overridden by
+// client code.
+func DefaultConfig() Config {
+	return Config{
+		Namespace: DefaultNamespace,
+	}
+}
+
+// Options returns the DeployerOptions described by this Config.
+func (c Config) Options() []DeployerOption {
+	opts := []DeployerOption{}
+	if c.Namespace != "" {
+		opts = append(opts, OptNamespace(c.Namespace))
+	}
+	if c.BearerToken != "" {
+		opts = append(opts, OptBearerToken(c.BearerToken))
+	}
+	return opts
+}
@@ -0,0 +1,254 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+)
+
+// ClusterKind is the sous.Cluster.Kind of clusters managed by this package.
+const ClusterKind = "kubernetes"
+
+// DefaultNamespace is the Kubernetes namespace Sous deploys into unless
+// configured otherwise with OptNamespace.
+const DefaultNamespace = "default"
+
+type deployer struct {
+	kubeFac   func(baseURL string) kubeClient
+	namespace string
+	token     string
+	// secrets resolves the SecretRefs in deployments' Envs.
+	secrets sous.SecretStore
+	log     logging.LogSink
+}
+
+// NewDeployer creates a new Kubernetes-based sous.Deployer.
+func NewDeployer(ls logging.LogSink, options ...DeployerOption) sous.Deployer {
+	d := &deployer{log: ls, namespace: DefaultNamespace}
+	for _, opt := range options {
+		opt(d)
+	}
+	return d
+}
+
+func (r *deployer) buildKubeClient(url string) kubeClient {
+	if r.kubeFac == nil {
+		return newAPIClient(url, r.namespace, r.token)
+	}
+	return r.kubeFac(url)
+}
+
+// Rectify invokes actions to ensure that the real world matches pair.Post,
+// given that it currently matches pair.Prior.
+func (r *deployer) Rectify(pair *sous.DeployablePair) sous.DiffResolution {
+	postID := ""
+	version := ""
+	if pair.Post != nil {
+		postID = pair.Post.ID().String()
+		version = pair.Post.DeploySpec().Version.String()
+	}
+
+	if pair.UUID == uuid.Nil {
+		pair.UUID = uuid.NewV4()
+	}
+
+	switch k := pair.Kind(); k {
+	default:
+		panic(fmt.Sprintf("unrecognised kind %q", k))
+	case sous.SameKind:
+		resolution := pair.SameResolution()
+		if pair.Post.Status == sous.DeployStatusFailed {
+			resolution.Error = sous.WrapResolveError(&sous.FailedStatusError{})
+		}
+		messages.ReportLogFieldsMessage("SameKind", logging.InformationLevel, r.log, postID, version, resolution)
+		return resolution
+	case sous.AddedKind:
+		result := sous.DiffResolution{DeploymentID: pair.ID()}
+		if err := r.RectifySingleCreate(pair); err != nil {
+			result.Desc = "not created"
+			result.Error = sous.WrapResolveError(&sous.CreateError{Deployment: pair.Post.Deployment.Clone(), Err: err})
+		} else {
+			result.Desc = sous.CreateDiff
+		}
+		messages.ReportLogFieldsMessage("Result of create", logging.InformationLevel, r.log, postID, version, result)
+		return result
+	case sous.RemovedKind:
+		result := sous.DiffResolution{DeploymentID: pair.ID()}
+		if err := r.RectifySingleDelete(pair); err != nil {
+			result.Error = sous.WrapResolveError(&sous.DeleteError{Deployment: pair.Prior.Deployment.Clone(), Err: err})
+			result.Desc = "not deleted"
+		} else {
+			result.Desc = sous.DeleteDiff
+		}
+		messages.ReportLogFieldsMessage("Result of delete", logging.InformationLevel, r.log, postID, version, result)
+		return result
+	case sous.ModifiedKind:
+		result := sous.DiffResolution{DeploymentID: pair.ID()}
+		if err := r.RectifySingleModification(pair); err != nil {
+			dp := &sous.DeploymentPair{
+				Prior: pair.Prior.Deployment.Clone(),
+				Post:  pair.Post.Deployment.Clone(),
+			}
+			result.Error = sous.WrapResolveError(&sous.ChangeError{Deployments: dp, Err: err})
+			result.Desc = "not updated"
+		} else if pair.Prior.Status == sous.DeployStatusFailed || pair.Post.Status == sous.DeployStatusFailed {
+			result.Desc = sous.ModifyDiff
+			result.Error = sous.WrapResolveError(&sous.FailedStatusError{})
+		} else {
+			result.Desc = sous.ModifyDiff
+		}
+		messages.ReportLogFieldsMessage("Result of modify", logging.InformationLevel, r.log, postID, version, result)
+		return result
+	}
+}
+
+// RectifySingleCreate creates the Kubernetes object for pair.Post.
+func (r *deployer) RectifySingleCreate(pair *sous.DeployablePair) (err error) {
+	defer sous.RectifyRecover(pair, "RectifySingleCreate", &err, r.log)
+
+	w, err := buildWorkload(*pair.Post, r.namespace, r.secrets)
+	if err != nil {
+		return err
+	}
+	messages.ReportLogFieldsMessage("Creating Kubernetes object", logging.DebugLevel, r.log, w.kind(), w.meta().Name)
+	return r.buildKubeClient(pair.Post.Cluster.BaseURL).Create(w)
+}
+
+// RectifySingleDelete does not delete anything: as with Singularity, Sous
+// leaves objects for removed manifests in place for their owners to clean up.
+func (r *deployer) RectifySingleDelete(pair *sous.DeployablePair) (err error) {
+	defer sous.RectifyRecover(pair, "RectifySingleDelete", &err, r.log)
+	data, ok := pair.ExecutorData.(*kubeTaskData)
+	if !ok {
+		return errors.Errorf("Delete record %#v doesn't contain Kubernetes compatible data: was %T\n\t%#v", pair.ID(), data, pair)
+	}
+	messages.ReportLogFieldsMessage("Rectify not deleting Kubernetes object", logging.WarningLevel, r.log, pair.ID(), data.kind, data.name)
+	return nil
+}
+
+// RectifySingleModification updates the Kubernetes object for pair.Post.
+// Deployments and CronJobs are updated in place; Jobs are immutable, so they
+// are replaced.
+func (r *deployer) RectifySingleModification(pair *sous.DeployablePair) (err error) {
+	defer sous.RectifyRecover(pair, "RectifySingleModification", &err, r.log)
+
+	w, err := buildWorkload(*pair.Post, r.namespace, r.secrets)
+	if err != nil {
+		return err
+	}
+	client := r.buildKubeClient(pair.Post.Cluster.BaseURL)
+
+	if data, ok := pair.ExecutorData.(*kubeTaskData); ok && (data.kind != w.kind() || data.kind == kindJob) {
+		messages.ReportLogFieldsMessage("Replacing Kubernetes object", logging.DebugLevel, r.log, data.kind, data.name, w.kind())
+		if err := client.Delete(data.kind, data.name); err != nil && !isNotFound(err) {
+			return err
+		}
+		return client.Create(w)
+	}
+
+	existing, err := client.Get(w.kind(), w.meta().Name)
+	if isNotFound(err) {
+		return client.Create(w)
+	}
+	if err != nil {
+		return err
+	}
+	w.meta().ResourceVersion = existing.meta().ResourceVersion
+	messages.ReportLogFieldsMessage("Updating Kubernetes object", logging.DebugLevel, r.log, w.kind(), w.meta().Name)
+	return client.Update(w)
+}
+
+// RunningDeployments collects data from the Kubernetes clusters and returns a
+// list of actual deployments.
+func (r *deployer) RunningDeployments(reg sous.Registry, clusters sous.Clusters) (sous.DeployStates, error) {
+	deps := sous.NewDeployStates()
+
+	urls := map[string]struct{}{}
+	for _, c := range clusters {
+		urls[c.BaseURL] = struct{}{}
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	wg.Add(len(urls))
+	for url := range urls {
+		go func(url string) {
+			defer wg.Done()
+			states, err := r.clusterDeployments(reg, clusters, url)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, errors.Wrap(err, url))
+				return
+			}
+			for _, s := range states {
+				deps.Add(s)
+			}
+		}(url)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return deps, errs[0]
+	}
+	return deps, nil
+}
+
+func (r *deployer) clusterDeployments(reg sous.Registry, clusters sous.Clusters, url string) ([]*sous.DeployState, error) {
+	client := r.buildKubeClient(url)
+	states := []*sous.DeployState{}
+	for _, kind := range workloadKinds {
+		ws, err := client.List(kind, ManagedLabel+"=true")
+		if err != nil {
+			return nil, errors.Wrapf(err, "listing %s", kind.resource())
+		}
+		for _, w := range ws {
+			ds, err := buildDeployState(reg, clusters, url, w, r.log)
+			if err != nil {
+				if ignorableObject(err) {
+					messages.ReportLogFieldsMessage("Ignorable object.", logging.DebugLevel, r.log, url, w.meta().Name, err)
+					continue
+				}
+				return nil, err
+			}
+			states = append(states, &ds)
+		}
+	}
+	return states, nil
+}
+
+// Status implements sous.Deployer on deployer.
+func (r *deployer) Status(reg sous.Registry, clusters sous.Clusters, pair *sous.DeployablePair) (*sous.DeployState, error) {
+	clusterName := pair.Post.Deployment.ClusterName
+	cluster, has := clusters[clusterName]
+	if !has {
+		return nil, errors.Errorf("No cluster found for %q. Known are: %q.", clusterName, clusters.Names())
+	}
+
+	if pair.UUID == uuid.Nil {
+		pair.UUID = uuid.NewV4()
+	}
+
+	name, err := MakeObjectName(pair.Post.ID())
+	if err != nil {
+		return nil, err
+	}
+	kind, err := workloadKindFor(pair.Post.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := r.buildKubeClient(cluster.BaseURL).Get(kind, name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting %s %s", kind, name)
+	}
+
+	ds, err := buildDeployState(reg, clusters, cluster.BaseURL, w, r.log)
+	return &ds, errors.Wrapf(err, "getting object state")
+}
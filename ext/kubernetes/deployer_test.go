@@ -0,0 +1,230 @@
+package kubernetes
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/opentable/sous/ext/internal/deployertest"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type deployerScenario struct {
+	*deployertest.Scenario
+	server   *fakeAPIServer
+	deployer sous.Deployer
+}
+
+func setupDeployer(t *testing.T) *deployerScenario {
+	server := newFakeAPIServer()
+	ls, _ := logging.NewLogSinkSpy()
+	return &deployerScenario{
+		Scenario: deployertest.NewScenario("kube-1", ClusterKind, server.URL),
+		server:   server,
+		deployer: NewDeployer(ls, OptNamespace("sous")),
+	}
+}
+
+func TestMakeObjectName(t *testing.T) {
+	valid := regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	ids := []sous.DeploymentID{
+		{ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/user/repo"}}, Cluster: "some-cluster"},
+		{ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/user/repo"}}, Cluster: "some_cluster"},
+		{ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/user/Repo", Dir: "some/dir"}, Flavor: "Tasty.Flavor"}, Cluster: "c"},
+		{ManifestID: sous.ManifestID{
+			Source: sous.SourceLocation{
+				Repo: "github.com/ihaveanincrediblylongname/andilikemyprojectstohaveincrediblylongnamestoo",
+				Dir:  "and/also/i/bury/my/services/super/deep/in/the/build/tree",
+			},
+			Flavor: "wellwehavetohaveaflavorforthisservice",
+		}, Cluster: "foo"},
+	}
+
+	seen := map[string]sous.DeploymentID{}
+	for _, id := range ids {
+		name, err := MakeObjectName(id)
+		require.NoError(t, err)
+		assert.True(t, len(name) <= maxObjectNameLen, "%q is too long", name)
+		assert.Regexp(t, valid, name)
+		if other, has := seen[name]; has {
+			t.Errorf("Collision! %q produced by both %v and %v", name, id, other)
+		}
+		seen[name] = id
+	}
+}
+
+func TestBuildWorkload_Kinds(t *testing.T) {
+	s := setupDeployer(t)
+
+	cases := []struct {
+		kind    sous.ManifestKind
+		expects workloadKind
+	}{
+		{sous.ManifestKindService, kindDeployment},
+		{sous.ManifestKindWorker, kindDeployment},
+		{sous.ManifestKindScheduled, kindCronJob},
+		{sous.ManifestKindOnce, kindJob},
+		{sous.ManifestKindOnDemand, kindJob},
+	}
+	for _, c := range cases {
+		w, err := buildWorkload(*s.Deployable(c.kind), "sous", nil)
+		require.NoError(t, err)
+		assert.Equal(t, c.expects, w.kind(), "for %s", c.kind)
+		assert.Equal(t, "docker.example.com/example@sha256:0123456789", w.podTemplate().Spec.Containers[0].Image)
+	}
+
+	w, err := buildWorkload(*s.Deployable(sous.ManifestKindScheduled), "sous", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "*/5 * * * *", w.schedule())
+
+	w, err = buildWorkload(*s.Deployable(sous.ManifestKindOnDemand), "sous", nil)
+	require.NoError(t, err)
+	assert.True(t, *w.(*kubeJob).Spec.Suspend)
+}
+
+func TestRectify_CreateRoundTrip(t *testing.T) {
+	for _, kind := range []sous.ManifestKind{
+		sous.ManifestKindService,
+		sous.ManifestKindScheduled,
+		sous.ManifestKindOnce,
+	} {
+		s := setupDeployer(t)
+		post := s.Deployable(kind)
+
+		rez := s.deployer.Rectify(&sous.DeployablePair{Post: post})
+		require.Nil(t, rez.Error, "for %s", kind)
+		assert.Equal(t, sous.CreateDiff, rez.Desc)
+
+		ds, err := s.deployer.RunningDeployments(s.Registry, s.Clusters)
+		require.NoError(t, err)
+		require.Equal(t, 1, ds.Len(), "for %s", kind)
+
+		actual, has := ds.Get(post.ID())
+		require.True(t, has, "for %s", kind)
+		different, diffs := post.Deployment.Diff(&actual.Deployment)
+		assert.False(t, different, "for %s: %v", kind, diffs)
+		s.server.Close()
+	}
+}
+
+func TestRectify_Modification(t *testing.T) {
+	s := setupDeployer(t)
+	defer s.server.Close()
+	prior := s.Deployable(sous.ManifestKindService)
+	require.Nil(t, s.deployer.Rectify(&sous.DeployablePair{Post: prior}).Error)
+
+	ds, err := s.deployer.RunningDeployments(s.Registry, s.Clusters)
+	require.NoError(t, err)
+	actual, _ := ds.Get(prior.ID())
+
+	post := s.Deployable(sous.ManifestKindService)
+	post.NumInstances = 4
+	pair := &sous.DeployablePair{
+		Prior:        &sous.Deployable{Status: actual.Status, Deployment: &actual.Deployment, BuildArtifact: prior.BuildArtifact},
+		Post:         post,
+		ExecutorData: actual.ExecutorData,
+	}
+	pair.SetID(post.ID())
+
+	rez := s.deployer.Rectify(pair)
+	require.Nil(t, rez.Error)
+	assert.Equal(t, sous.ModifyDiff, rez.Desc)
+
+	name, _ := MakeObjectName(post.ID())
+	obj := s.server.object("deployments", name)
+	require.NotNil(t, obj)
+	assert.EqualValues(t, 4, obj["spec"].(map[string]interface{})["replicas"])
+}
+
+func TestStatus(t *testing.T) {
+	s := setupDeployer(t)
+	defer s.server.Close()
+	post := s.Deployable(sous.ManifestKindService)
+	post.NumInstances = 2
+	require.Nil(t, s.deployer.Rectify(&sous.DeployablePair{Post: post}).Error)
+
+	name, _ := MakeObjectName(post.ID())
+	pair := &sous.DeployablePair{Post: post}
+
+	state, err := s.deployer.Status(s.Registry, s.Clusters, pair)
+	require.NoError(t, err)
+	assert.Equal(t, sous.DeployStatusPending, state.Status)
+
+	s.server.setStatus("deployments", name, map[string]interface{}{
+		"observedGeneration": 1,
+		"replicas":           2,
+		"updatedReplicas":    2,
+		"availableReplicas":  2,
+	})
+	state, err = s.deployer.Status(s.Registry, s.Clusters, pair)
+	require.NoError(t, err)
+	assert.Equal(t, sous.DeployStatusActive, state.Status)
+
+	s.server.setStatus("deployments", name, map[string]interface{}{
+		"conditions": []interface{}{map[string]interface{}{
+			"type":    "Progressing",
+			"status":  "False",
+			"reason":  "ProgressDeadlineExceeded",
+			"message": "took too long",
+		}},
+	})
+	state, err = s.deployer.Status(s.Registry, s.Clusters, pair)
+	require.NoError(t, err)
+	assert.Equal(t, sous.DeployStatusFailed, state.Status)
+	assert.Contains(t, state.ExecutorMessage, "took too long")
+}
+
+func TestRunningDeployments_IgnoresOtherClusters(t *testing.T) {
+	s := setupDeployer(t)
+	defer s.server.Close()
+	post := s.Deployable(sous.ManifestKindService)
+	require.Nil(t, s.deployer.Rectify(&sous.DeployablePair{Post: post}).Error)
+
+	other := sous.Clusters{"kube-2": &sous.Cluster{Name: "kube-2", Kind: ClusterKind, BaseURL: s.server.URL}}
+	ds, err := s.deployer.RunningDeployments(s.Registry, other)
+	require.NoError(t, err)
+	assert.Equal(t, 0, ds.Len())
+}
+
+func TestRectify_Secrets(t *testing.T) {
+	s := setupDeployer(t)
+	defer s.server.Close()
+	ls, _ := logging.NewLogSinkSpy()
+	s.deployer = NewDeployer(ls, OptNamespace("sous"), OptSecretStore(deployertest.MapSecretStore{"db": "hunter2"}))
+	post := s.Deployable(sous.ManifestKindService)
+	post.Env["DB_PASSWORD"] = "${secret:db}"
+
+	require.Nil(t, s.deployer.Rectify(&sous.DeployablePair{Post: post}).Error)
+
+	name, _ := MakeObjectName(post.ID())
+	obj := s.server.object("deployments", name)
+	require.NotNil(t, obj)
+	tmpl := obj["spec"].(map[string]interface{})["template"].(map[string]interface{})
+	c := tmpl["spec"].(map[string]interface{})["containers"].([]interface{})[0].(map[string]interface{})
+	env := map[string]interface{}{}
+	for _, e := range c["env"].([]interface{}) {
+		env[e.(map[string]interface{})["name"].(string)] = e.(map[string]interface{})["value"]
+	}
+	assert.Equal(t, "hunter2", env["DB_PASSWORD"])
+
+	ds, err := s.deployer.RunningDeployments(s.Registry, s.Clusters)
+	require.NoError(t, err)
+	actual, has := ds.Get(post.ID())
+	require.True(t, has)
+	assert.Equal(t, "${secret:db}", actual.Env["DB_PASSWORD"])
+	different, diffs := post.Deployment.Diff(&actual.Deployment)
+	assert.False(t, different, "%v", diffs)
+}
+
+func TestRectify_MissingSecret(t *testing.T) {
+	s := setupDeployer(t)
+	defer s.server.Close()
+	post := s.Deployable(sous.ManifestKindService)
+	post.Env["DB_PASSWORD"] = "${secret:db}"
+
+	rez := s.deployer.Rectify(&sous.DeployablePair{Post: post})
+	require.NotNil(t, rez.Error)
+	assert.Contains(t, rez.Error.Error(), "db")
+}
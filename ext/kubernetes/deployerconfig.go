@@ -0,0 +1,28 @@
+package kubernetes
+
+import "github.com/opentable/sous/lib"
+
+// DeployerOption is an option for configuring Kubernetes deployers.
+type DeployerOption func(*deployer)
+
+// OptNamespace sets the Kubernetes namespace Sous deploys into.
+func OptNamespace(ns string) DeployerOption {
+	return func(d *deployer) { d.namespace = ns }
+}
+
+// OptBearerToken sets the token used to authenticate to Kubernetes API
+// servers.
+func OptBearerToken(token string) DeployerOption {
+	return func(d *deployer) { d.token = token }
+}
+
+// OptSecretStore sets the store the SecretRefs in deployments' Envs are
+// resolved from.
+func OptSecretStore(secrets sous.SecretStore) DeployerOption {
+	return func(d *deployer) { d.secrets = secrets }
+}
+
+// optClientFactory overrides the construction of API clients, for testing.
+func optClientFactory(fn func(string) kubeClient) DeployerOption {
+	return func(d *deployer) { d.kubeFac = fn }
+}
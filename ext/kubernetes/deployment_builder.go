@@ -0,0 +1,311 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/opentable/sous/ext/docker"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/firsterr"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
+
+type (
+	deploymentBuilder struct {
+		clusters  sous.Clusters
+		baseURL   string
+		obj       workload
+		container *container
+		registry  sous.ImageLabeller
+		Target    sous.DeployState
+		log       logging.LogSink
+	}
+
+	kubeTaskData struct {
+		kind workloadKind
+		name string
+	}
+
+	malformedObject struct {
+		message string
+	}
+
+	notThisClusterError struct {
+		foundClusterName        string
+		responsibleClusterNames []string
+	}
+)
+
+func (mo malformedObject) Error() string {
+	return mo.message
+}
+
+func (ntc notThisClusterError) Error() string {
+	return fmt.Sprintf("%s does not belong to this Sous server %#v.",
+		ntc.foundClusterName, ntc.responsibleClusterNames)
+}
+
+func ignorableObject(err error) bool {
+	switch errors.Cause(err).(type) {
+	case malformedObject, notThisClusterError:
+		return true
+	}
+	return false
+}
+
+// buildDeployState does all the work to collect the data for a DeployState
+// from a Kubernetes workload.
+func buildDeployState(reg sous.ImageLabeller, clusters sous.Clusters, baseURL string, obj workload, log logging.LogSink) (sous.DeployState, error) {
+	messages.ReportLogFieldsMessage("Build Deployment", logging.ExtraDebug1Level, log, baseURL, obj.meta().Name)
+	db := deploymentBuilder{registry: reg, clusters: clusters, baseURL: baseURL, obj: obj, log: log}
+	return db.Target, db.completeConstruction()
+}
+
+func (db *deploymentBuilder) completeConstruction() error {
+	wrapError := func(fn func() error, msgStr string) func() error {
+		return func() error {
+			return errors.Wrap(fn(), msgStr)
+		}
+	}
+	return firsterr.Returned(
+		wrapError(db.basics, "Failed to extract basic information from Kubernetes object."),
+		wrapError(db.sousObjectCheck, "Could not determine if the Kubernetes object is controlled by Sous"),
+		wrapError(db.determineStatus, "Could not determine current status of Kubernetes object"),
+		wrapError(db.retrieveSourceID, "Could not determine SourceID from container image."),
+		wrapError(db.restoreFromAnnotations, "Could not restore deployment from Kubernetes annotations."),
+		wrapError(db.unpackDeployConfig, "Could not convert data from a Kubernetes object to a sous.Deployment."),
+	)
+}
+
+func (db *deploymentBuilder) basics() error {
+	name := db.obj.meta().Name
+	db.Target.Cluster = &sous.Cluster{BaseURL: db.baseURL, Kind: ClusterKind}
+	db.Target.ExecutorData = &kubeTaskData{kind: db.obj.kind(), name: name}
+	db.Target.SchedulerURL = fmt.Sprintf("%s/apis/%s/namespaces/%s/%s/%s",
+		db.baseURL, db.obj.kind().apiVersion(), db.obj.meta().Namespace, db.obj.kind().resource(), name)
+
+	cs := db.obj.podTemplate().Spec.Containers
+	for i := range cs {
+		if cs[i].Name == containerName {
+			db.container = &cs[i]
+		}
+	}
+	if db.container == nil {
+		return malformedObject{fmt.Sprintf("Kubernetes object %q has no %q container", name, containerName)}
+	}
+	return nil
+}
+
+func (db *deploymentBuilder) sousObjectCheck() error {
+	cn, ok := db.obj.meta().Annotations[sous.ClusterNameLabel]
+	if !ok {
+		return malformedObject{"Object annotations did not include a cluster name"}
+	}
+	if _, has := db.clusters[cn]; !has {
+		return notThisClusterError{cn, db.clusters.Names()}
+	}
+	db.Target.ClusterName = cn
+	return nil
+}
+
+func (db *deploymentBuilder) determineStatus() error {
+	switch o := db.obj.(type) {
+	default:
+		return malformedObject{fmt.Sprintf("unknown workload type %T", o)}
+	case *kubeDeployment:
+		db.Target.Status, db.Target.ExecutorMessage = deploymentStatusOf(o)
+	case *kubeJob:
+		db.Target.Status, db.Target.ExecutorMessage = jobStatusOf(o)
+	case *kubeCronJob:
+		// A CronJob is as live as it's going to get once it's accepted.
+		db.Target.Status = sous.DeployStatusActive
+	}
+	return nil
+}
+
+func deploymentStatusOf(d *kubeDeployment) (sous.DeployStatus, string) {
+	for _, c := range d.Status.Conditions {
+		if c.Type == "Progressing" && c.Status == "False" && c.Reason == "ProgressDeadlineExceeded" {
+			return sous.DeployStatusFailed, fmt.Sprintf("Deploy failure: %q", c.Message)
+		}
+	}
+	want := int32(d.replicas())
+	if d.Status.ObservedGeneration < d.Metadata.Generation ||
+		d.Status.UpdatedReplicas < want ||
+		d.Status.AvailableReplicas < want {
+		return sous.DeployStatusPending, ""
+	}
+	return sous.DeployStatusActive, ""
+}
+
+func jobStatusOf(j *kubeJob) (sous.DeployStatus, string) {
+	for _, c := range j.Status.Conditions {
+		if c.Type == "Failed" && c.Status == "True" {
+			return sous.DeployStatusFailed, fmt.Sprintf("Job failure: %q", c.Message)
+		}
+	}
+	if j.Spec.Suspend != nil && *j.Spec.Suspend {
+		return sous.DeployStatusActive, ""
+	}
+	if j.Status.Active > 0 || j.Status.Succeeded > 0 {
+		return sous.DeployStatusActive, ""
+	}
+	return sous.DeployStatusPending, ""
+}
+
+func (db *deploymentBuilder) retrieveSourceID() error {
+	// XXX coupled to Docker registry as ImageMapper, as with Singularity.
+	labels, err := db.registry.ImageLabels(db.container.Image)
+	if err != nil {
+		return malformedObject{err.Error()}
+	}
+	db.Target.SourceID, err = docker.SourceIDFromLabels(labels)
+	if err != nil {
+		return errors.Wrapf(malformedObject{err.Error()}, "For object: %s", db.obj.meta().Name)
+	}
+	return nil
+}
+
+func (db *deploymentBuilder) restoreFromAnnotations() error {
+	an := db.obj.meta().Annotations
+
+	db.Target.Flavor = an[sous.FlavorLabel]
+
+	db.Target.Kind = sous.ManifestKind(an[KindAnnotation])
+	kind, err := workloadKindFor(db.Target.Kind)
+	if err != nil {
+		return malformedObject{err.Error()}
+	}
+	if kind != db.obj.kind() {
+		return malformedObject{fmt.Sprintf("manifest kind %q cannot run as a %s", db.Target.Kind, db.obj.kind())}
+	}
+
+	db.Target.Owners = make(sous.OwnerSet)
+	for _, o := range strings.Split(an[OwnersAnnotation], ",") {
+		if o != "" {
+			db.Target.Owners.Add(o)
+		}
+	}
+
+	if s, has := an[StartupAnnotation]; has {
+		if err := json.Unmarshal([]byte(s), &db.Target.Startup); err != nil {
+			return malformedObject{err.Error()}
+		}
+	}
+	if s, has := an[MetadataAnnotation]; has {
+		if err := json.Unmarshal([]byte(s), &db.Target.Metadata); err != nil {
+			return malformedObject{err.Error()}
+		}
+	}
+	if u, has := an[UserAnnotation]; has {
+		db.Target.User.Email = u
+	}
+	return nil
+}
+
+func (db *deploymentBuilder) unpackDeployConfig() error {
+	an := db.obj.meta().Annotations
+	ports, err := strconv.Atoi(an[PortsAnnotation])
+	if err != nil {
+		ports = len(db.container.Ports)
+	}
+
+	db.Target.Env = make(sous.Env)
+	for _, e := range db.container.Env {
+		if m := portEnvName.FindStringSubmatch(e.Name); m != nil {
+			if n, _ := strconv.Atoi(m[1]); n < ports {
+				continue
+			}
+		}
+		db.Target.Env[e.Name] = e.Value
+	}
+	if s, has := an[sous.SecretsLabel]; has {
+		// Put back the SecretRefs the secrets in Env were resolved from, so
+		// that the secrets themselves are neither logged nor compared.
+		refs := map[string]sous.SecretRef{}
+		if err := json.Unmarshal([]byte(s), &refs); err != nil {
+			return malformedObject{fmt.Sprintf("malformed %s annotation: %s", sous.SecretsLabel, err)}
+		}
+		db.Target.Env = db.Target.Env.RestoreSecretRefs(refs)
+	}
+
+	cpus, err := parseCPU(db.container.Resources.Limits["cpu"])
+	if err != nil {
+		return malformedObject{err.Error()}
+	}
+	memory, err := parseMemoryMB(db.container.Resources.Limits["memory"])
+	if err != nil {
+		return malformedObject{err.Error()}
+	}
+	db.Target.Resources = make(sous.Resources)
+	db.Target.Resources["cpus"] = fmt.Sprintf("%f", cpus)
+	db.Target.Resources["memory"] = fmt.Sprintf("%f", memory)
+	db.Target.Resources["ports"] = fmt.Sprintf("%d", ports)
+
+	db.Target.NumInstances = db.obj.replicas()
+	db.Target.Schedule = db.obj.schedule()
+
+	hostPaths := map[string]string{}
+	for _, v := range db.obj.podTemplate().Spec.Volumes {
+		if v.HostPath != nil {
+			hostPaths[v.Name] = v.HostPath.Path
+		}
+	}
+	for _, vm := range db.container.VolumeMounts {
+		mode := sous.ReadWrite
+		if vm.ReadOnly {
+			mode = sous.ReadOnly
+		}
+		db.Target.DeployConfig.Volumes = append(db.Target.DeployConfig.Volumes, &sous.Volume{
+			Host:      hostPaths[vm.Name],
+			Container: vm.MountPath,
+			Mode:      mode,
+		})
+	}
+	return nil
+}
+
+// parseCPU parses a Kubernetes CPU quantity, e.g. "0.5" or "500m".
+func parseCPU(q string) (float64, error) {
+	if q == "" {
+		return 0, errors.Errorf("missing cpu limit")
+	}
+	if strings.HasSuffix(q, "m") {
+		m, err := strconv.ParseFloat(strings.TrimSuffix(q, "m"), 64)
+		return m / 1000, err
+	}
+	return strconv.ParseFloat(q, 64)
+}
+
+var memoryUnits = []struct {
+	suffix string
+	mb     float64
+}{
+	{"Ki", 1.0 / 1024},
+	{"Mi", 1},
+	{"Gi", 1024},
+	{"Ti", 1024 * 1024},
+	{"k", 1000.0 / (1024 * 1024)},
+	{"M", 1000 * 1000.0 / (1024 * 1024)},
+	{"G", 1000 * 1000 * 1000.0 / (1024 * 1024)},
+}
+
+// parseMemoryMB parses a Kubernetes memory quantity into mebibytes, which is
+// what Sous means by "memory".
+func parseMemoryMB(q string) (float64, error) {
+	if q == "" {
+		return 0, errors.Errorf("missing memory limit")
+	}
+	for _, u := range memoryUnits {
+		if strings.HasSuffix(q, u.suffix) {
+			n, err := strconv.ParseFloat(strings.TrimSuffix(q, u.suffix), 64)
+			return n * u.mb, err
+		}
+	}
+	n, err := strconv.ParseFloat(q, 64)
+	return n / (1024 * 1024), err
+}
@@ -0,0 +1,152 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// fakeAPIServer is an in-process stand-in for the parts of the Kubernetes API
+// server that Sous uses. Objects are stored as decoded JSON so that tests
+// exercise the same encoding as a real server.
+type fakeAPIServer struct {
+	*httptest.Server
+	sync.Mutex
+	objects map[string]map[string]map[string]interface{} // resource -> name -> object
+	version int
+	calls   []string
+}
+
+var fakePathRE = regexp.MustCompile(`^/apis/[^/]+/[^/]+/namespaces/([^/]+)/([^/]+)(?:/([^/]+))?$`)
+
+func newFakeAPIServer() *fakeAPIServer {
+	f := &fakeAPIServer{objects: map[string]map[string]map[string]interface{}{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	return f
+}
+
+func (f *fakeAPIServer) serve(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	f.calls = append(f.calls, r.Method+" "+r.URL.Path)
+
+	m := fakePathRE.FindStringSubmatch(r.URL.Path)
+	if m == nil {
+		http.Error(w, "no such path", http.StatusNotFound)
+		return
+	}
+	resource, name := m[2], m[3]
+	if f.objects[resource] == nil {
+		f.objects[resource] = map[string]map[string]interface{}{}
+	}
+	coll := f.objects[resource]
+
+	switch {
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	case r.Method == "GET" && name == "":
+		items := []interface{}{}
+		names := []string{}
+		for n := range coll {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		sel := r.URL.Query().Get("labelSelector")
+		for _, n := range names {
+			if matchesSelector(coll[n], sel) {
+				items = append(items, coll[n])
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+	case r.Method == "GET":
+		obj, has := coll[name]
+		if !has {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(obj)
+	case r.Method == "POST":
+		obj := decodeObject(r)
+		meta := obj["metadata"].(map[string]interface{})
+		n := meta["name"].(string)
+		if _, has := coll[n]; has {
+			http.Error(w, "already exists", http.StatusConflict)
+			return
+		}
+		f.version++
+		meta["resourceVersion"] = fmt.Sprint(f.version)
+		meta["generation"] = 1
+		coll[n] = obj
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(obj)
+	case r.Method == "PUT":
+		old, has := coll[name]
+		if !has {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		obj := decodeObject(r)
+		meta := obj["metadata"].(map[string]interface{})
+		oldMeta := old["metadata"].(map[string]interface{})
+		if meta["resourceVersion"] != oldMeta["resourceVersion"] {
+			http.Error(w, "conflict", http.StatusConflict)
+			return
+		}
+		f.version++
+		meta["resourceVersion"] = fmt.Sprint(f.version)
+		meta["generation"] = oldMeta["generation"].(int) + 1
+		coll[name] = obj
+		json.NewEncoder(w).Encode(obj)
+	case r.Method == "DELETE":
+		if _, has := coll[name]; !has {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		delete(coll, name)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func decodeObject(r *http.Request) map[string]interface{} {
+	b, _ := ioutil.ReadAll(r.Body)
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		panic(err)
+	}
+	return obj
+}
+
+func matchesSelector(obj map[string]interface{}, sel string) bool {
+	if sel == "" {
+		return true
+	}
+	meta, _ := obj["metadata"].(map[string]interface{})
+	labels, _ := meta["labels"].(map[string]interface{})
+	for _, term := range strings.Split(sel, ",") {
+		kv := strings.SplitN(term, "=", 2)
+		if len(kv) != 2 || labels[kv[0]] != kv[1] {
+			return false
+		}
+	}
+	return true
+}
+
+// setStatus replaces the status of a stored object.
+func (f *fakeAPIServer) setStatus(resource, name string, status map[string]interface{}) {
+	f.Lock()
+	defer f.Unlock()
+	f.objects[resource][name]["status"] = status
+}
+
+// object returns a stored object, or nil.
+func (f *fakeAPIServer) object(resource, name string) map[string]interface{} {
+	f.Lock()
+	defer f.Unlock()
+	return f.objects[resource][name]
+}
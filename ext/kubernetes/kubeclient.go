@@ -0,0 +1,214 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// kubeClient abstracts the queries and commands we send to a Kubernetes
+	// API server.
+	kubeClient interface {
+		// List returns every workload of a kind matching the label selector.
+		List(kind workloadKind, selector string) ([]workload, error)
+		// Get returns a single workload by name.
+		Get(kind workloadKind, name string) (workload, error)
+		// Create creates a new workload.
+		Create(w workload) error
+		// Update replaces an existing workload.
+		Update(w workload) error
+		// Delete removes a workload and its dependent objects.
+		Delete(kind workloadKind, name string) error
+	}
+
+	// apiClient is the HTTP implementation of kubeClient.
+	apiClient struct {
+		baseURL   string
+		namespace string
+		token     string
+		http      *http.Client
+	}
+
+	// apiError is returned when the API server responds with a non-2xx
+	// status.
+	apiError struct {
+		Method, URL string
+		Status      int
+		Body        string
+	}
+)
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.Status, e.Body)
+}
+
+func isNotFound(err error) bool {
+	ae, is := errors.Cause(err).(*apiError)
+	return is && ae.Status == http.StatusNotFound
+}
+
+func newAPIClient(baseURL, namespace, token string) *apiClient {
+	return &apiClient{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		namespace: namespace,
+		token:     token,
+		http:      http.DefaultClient,
+	}
+}
+
+func (c *apiClient) collectionURL(kind workloadKind) string {
+	return fmt.Sprintf("%s/apis/%s/namespaces/%s/%s",
+		c.baseURL, kind.apiVersion(), url.PathEscape(c.namespace), kind.resource())
+}
+
+func (c *apiClient) itemURL(kind workloadKind, name string) string {
+	return c.collectionURL(kind) + "/" + url.PathEscape(name)
+}
+
+func (c *apiClient) do(method, u string, body, into interface{}) error {
+	var rdr *bytes.Buffer
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rdr = bytes.NewBuffer(b)
+	} else {
+		rdr = &bytes.Buffer{}
+	}
+
+	req, err := http.NewRequest(method, u, rdr)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	rz, err := c.http.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "%s %s", method, u)
+	}
+	defer rz.Body.Close()
+
+	b, err := ioutil.ReadAll(rz.Body)
+	if err != nil {
+		return err
+	}
+	if rz.StatusCode < 200 || rz.StatusCode > 299 {
+		return &apiError{Method: method, URL: u, Status: rz.StatusCode, Body: string(b)}
+	}
+	if into == nil {
+		return nil
+	}
+	return errors.Wrapf(json.Unmarshal(b, into), "decoding %s %s", method, u)
+}
+
+// List implements kubeClient on apiClient.
+func (c *apiClient) List(kind workloadKind, selector string) ([]workload, error) {
+	u := c.collectionURL(kind)
+	if selector != "" {
+		u += "?labelSelector=" + url.QueryEscape(selector)
+	}
+
+	ws := []workload{}
+	switch kind {
+	default:
+		return nil, errors.Errorf("unknown workload kind %q", kind)
+	case kindDeployment:
+		list := kubeDeploymentList{}
+		if err := c.do("GET", u, nil, &list); err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			ws = append(ws, &list.Items[i])
+		}
+	case kindJob:
+		list := kubeJobList{}
+		if err := c.do("GET", u, nil, &list); err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			ws = append(ws, &list.Items[i])
+		}
+	case kindCronJob:
+		list := kubeCronJobList{}
+		if err := c.do("GET", u, nil, &list); err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			ws = append(ws, &list.Items[i])
+		}
+	}
+	return ws, nil
+}
+
+// Get implements kubeClient on apiClient.
+func (c *apiClient) Get(kind workloadKind, name string) (workload, error) {
+	w, err := newWorkload(kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.do("GET", c.itemURL(kind, name), nil, w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Create implements kubeClient on apiClient.
+func (c *apiClient) Create(w workload) error {
+	stampTypeMeta(w)
+	return c.do("POST", c.collectionURL(w.kind()), w, nil)
+}
+
+// Update implements kubeClient on apiClient.
+func (c *apiClient) Update(w workload) error {
+	stampTypeMeta(w)
+	return c.do("PUT", c.itemURL(w.kind(), w.meta().Name), w, nil)
+}
+
+// Delete implements kubeClient on apiClient.
+func (c *apiClient) Delete(kind workloadKind, name string) error {
+	body := map[string]interface{}{
+		"kind":              "DeleteOptions",
+		"apiVersion":        "v1",
+		"propagationPolicy": "Background",
+	}
+	return c.do("DELETE", c.itemURL(kind, name), body, nil)
+}
+
+func newWorkload(kind workloadKind) (workload, error) {
+	switch kind {
+	default:
+		return nil, errors.Errorf("unknown workload kind %q", kind)
+	case kindDeployment:
+		return &kubeDeployment{}, nil
+	case kindJob:
+		return &kubeJob{}, nil
+	case kindCronJob:
+		return &kubeCronJob{}, nil
+	}
+}
+
+func stampTypeMeta(w workload) {
+	tm := typeMeta{APIVersion: w.kind().apiVersion(), Kind: string(w.kind())}
+	switch o := w.(type) {
+	case *kubeDeployment:
+		o.typeMeta = tm
+	case *kubeJob:
+		o.typeMeta = tm
+	case *kubeCronJob:
+		o.typeMeta = tm
+	}
+}
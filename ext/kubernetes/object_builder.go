@@ -0,0 +1,282 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ManagedLabel marks Kubernetes objects which are controlled by Sous.
+	ManagedLabel = "com.opentable.sous.managed"
+	// DeploymentLabel is the pod label used to select the pods of a single
+	// Sous deployment.
+	DeploymentLabel = "com.opentable.sous.deployment"
+
+	// KindAnnotation records the sous.ManifestKind of a deployment.
+	KindAnnotation = "com.opentable.sous.kind"
+	// OwnersAnnotation records the comma separated owners of a deployment.
+	OwnersAnnotation = "com.opentable.sous.owners"
+	// PortsAnnotation records the number of ports requested by a deployment.
+	PortsAnnotation = "com.opentable.sous.ports"
+	// StartupAnnotation records the JSON encoded sous.Startup of a deployment.
+	StartupAnnotation = "com.opentable.sous.startup"
+	// MetadataAnnotation records the JSON encoded sous.Metadata of a
+	// deployment.
+	MetadataAnnotation = "com.opentable.sous.deploy_metadata"
+	// UserAnnotation records the user who last deployed.
+	UserAnnotation = "com.opentable.sous.deployed_by"
+
+	// Kubernetes object names must be DNS-1123 labels; CronJob names are
+	// further limited so that the names of their Jobs fit.
+	maxObjectNameLen = 52
+
+	// basePort is the container port assigned to PORT0; PORT1 is basePort+1
+	// and so on.
+	basePort = 8080
+
+	containerName = "app"
+)
+
+var illegalObjectNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+var portEnvName = regexp.MustCompile(`^PORT(\d+)$`)
+
+func sanitizeObjectName(in string) string {
+	return strings.Trim(illegalObjectNameChars.ReplaceAllString(strings.ToLower(in), "-"), "-")
+}
+
+// MakeObjectName creates a Kubernetes object name from a sous.DeploymentID.
+func MakeObjectName(depID sous.DeploymentID) (string, error) {
+	sn, err := depID.ManifestID.Source.ShortName()
+	if err != nil {
+		return "", err
+	}
+	parts := []string{}
+	for _, p := range []string{sn, depID.ManifestID.Source.Dir, depID.ManifestID.Flavor, depID.Cluster} {
+		if s := sanitizeObjectName(p); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	digest := fmt.Sprintf("%x", depID.Digest())[:8]
+
+	base := strings.Join(parts, "-")
+	if max := maxObjectNameLen - len(digest) - 1; len(base) > max {
+		base = strings.TrimRight(base[:max], "-")
+	}
+	return base + "-" + digest, nil
+}
+
+// workloadKindFor returns the kind of Kubernetes object used to run a
+// deployment of the given sous.ManifestKind.
+func workloadKindFor(mk sous.ManifestKind) (workloadKind, error) {
+	switch mk {
+	default:
+		return "", errors.Errorf("Unrecognized Sous manifest kind: %v", mk)
+	case sous.ManifestKindService, sous.ManifestKindWorker:
+		return kindDeployment, nil
+	case sous.ManifestKindScheduled, sous.ScheduledJob:
+		return kindCronJob, nil
+	case sous.ManifestKindOnce, sous.ManifestKindOnDemand:
+		return kindJob, nil
+	}
+}
+
+// buildWorkload maps a sous.Deployable onto the Kubernetes object which will
+// run it. This is where the SecretRefs in d's Env are resolved from secrets:
+// the SecretRefs are recorded in an annotation so that they can be restored
+// when the object is read back.
+func buildWorkload(d sous.Deployable, namespace string, secrets sous.SecretStore) (workload, error) {
+	if d.BuildArtifact == nil {
+		return nil, &sous.MissingImageNameError{Cause: fmt.Errorf("Missing BuildArtifact on Deployable")}
+	}
+	dep := d.Deployment
+
+	name, err := MakeObjectName(dep.ID())
+	if err != nil {
+		return nil, err
+	}
+	kind, err := workloadKindFor(dep.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := buildObjectMeta(dep, name, namespace)
+	if err != nil {
+		return nil, err
+	}
+	env, err := dep.Env.ResolveSecrets(secrets)
+	if err != nil {
+		return nil, err
+	}
+	if refs := dep.Env.SecretRefs(); len(refs) > 0 {
+		b, err := json.Marshal(refs)
+		if err != nil {
+			return nil, err
+		}
+		meta.Annotations[sous.SecretsLabel] = string(b)
+	}
+	tmpl := buildPodTemplate(d, name, env)
+
+	switch kind {
+	default:
+		return nil, errors.Errorf("unknown workload kind %q", kind)
+	case kindDeployment:
+		return &kubeDeployment{
+			Metadata: meta,
+			Spec: deploymentSpec{
+				Replicas: int32Ptr(dep.NumInstances),
+				Selector: &labelSelector{MatchLabels: map[string]string{DeploymentLabel: name}},
+				Template: tmpl,
+			},
+		}, nil
+	case kindCronJob:
+		tmpl.Spec.RestartPolicy = "OnFailure"
+		return &kubeCronJob{
+			Metadata: meta,
+			Spec: cronJobSpec{
+				Schedule: dep.Schedule,
+				JobTemplate: jobTemplateSpec{
+					Metadata: objectMeta{Labels: tmpl.Metadata.Labels},
+					Spec: jobSpec{
+						Parallelism: int32Ptr(dep.NumInstances),
+						Template:    tmpl,
+					},
+				},
+			},
+		}, nil
+	case kindJob:
+		tmpl.Spec.RestartPolicy = "OnFailure"
+		return &kubeJob{
+			Metadata: meta,
+			Spec: jobSpec{
+				Parallelism: int32Ptr(dep.NumInstances),
+				Completions: int32Ptr(dep.NumInstances),
+				// On-demand deployments are created suspended, so that they
+				// only run when someone asks them to.
+				Suspend:  boolPtr(dep.Kind == sous.ManifestKindOnDemand),
+				Template: tmpl,
+			},
+		}, nil
+	}
+}
+
+func buildObjectMeta(dep *sous.Deployment, name, namespace string) (objectMeta, error) {
+	startup, err := json.Marshal(dep.Startup)
+	if err != nil {
+		return objectMeta{}, err
+	}
+	md, err := json.Marshal(dep.Metadata)
+	if err != nil {
+		return objectMeta{}, err
+	}
+
+	user := "unknown_sous_deploy"
+	if len(dep.User.Email) > 1 {
+		user = dep.User.Email
+	}
+
+	return objectMeta{
+		Name:      name,
+		Namespace: namespace,
+		Labels: map[string]string{
+			ManagedLabel:    "true",
+			DeploymentLabel: name,
+		},
+		Annotations: map[string]string{
+			sous.ClusterNameLabel: dep.ClusterName,
+			sous.FlavorLabel:      dep.Flavor,
+			KindAnnotation:        string(dep.Kind),
+			OwnersAnnotation:      strings.Join(dep.Owners.Slice(), ","),
+			PortsAnnotation:       strconv.Itoa(int(dep.Resources.Ports())),
+			StartupAnnotation:     string(startup),
+			MetadataAnnotation:    string(md),
+			UserAnnotation:        user,
+		},
+	}, nil
+}
+
+func buildPodTemplate(d sous.Deployable, name string, env sous.Env) podTemplateSpec {
+	dep := d.Deployment
+	ports := int(dep.Resources.Ports())
+
+	c := container{
+		Name:  containerName,
+		Image: d.BuildArtifact.DigestReference,
+		Resources: resourceRequirements{
+			Limits: map[string]string{
+				"cpu":    strconv.FormatFloat(dep.Resources.Cpus(), 'f', -1, 64),
+				"memory": strconv.FormatFloat(dep.Resources.Memory(), 'f', -1, 64) + "Mi",
+			},
+		},
+	}
+
+	envNames := make([]string, 0, len(env))
+	for n := range env {
+		envNames = append(envNames, n)
+	}
+	sort.Strings(envNames)
+	for _, n := range envNames {
+		c.Env = append(c.Env, envVar{Name: n, Value: env[n]})
+	}
+
+	// Singularity provides PORTn to each task; we do the same with fixed
+	// container ports.
+	for i := 0; i < ports; i++ {
+		c.Ports = append(c.Ports, containerPort{Name: portName(i), ContainerPort: int32(basePort + i)})
+		c.Env = append(c.Env, envVar{Name: fmt.Sprintf("PORT%d", i), Value: strconv.Itoa(basePort + i)})
+	}
+
+	tmpl := podTemplateSpec{
+		Metadata: objectMeta{Labels: map[string]string{DeploymentLabel: name}},
+	}
+
+	for i, v := range dep.DeployConfig.Volumes {
+		if v == nil {
+			continue
+		}
+		vn := fmt.Sprintf("vol%d", i)
+		tmpl.Spec.Volumes = append(tmpl.Spec.Volumes, volume{
+			Name:     vn,
+			HostPath: &hostPathVolumeSource{Path: v.Host},
+		})
+		c.VolumeMounts = append(c.VolumeMounts, volumeMount{
+			Name:      vn,
+			MountPath: v.Container,
+			ReadOnly:  v.Mode == sous.ReadOnly,
+		})
+	}
+
+	c.ReadinessProbe = buildReadinessProbe(dep.Startup)
+
+	tmpl.Spec.Containers = []container{c}
+	return tmpl
+}
+
+func portName(i int) string {
+	return fmt.Sprintf("port%d", i)
+}
+
+// buildReadinessProbe maps a sous.Startup onto the nearest equivalent
+// Kubernetes readiness probe.
+func buildReadinessProbe(s sous.Startup) *probe {
+	if s.SkipCheck {
+		return nil
+	}
+	return &probe{
+		HTTPGet: &httpGetAction{
+			Path:   s.CheckReadyURIPath,
+			Port:   portName(s.CheckReadyPortIndex),
+			Scheme: strings.ToUpper(s.CheckReadyProtocol),
+		},
+		InitialDelaySeconds: int32(s.ConnectDelay),
+		TimeoutSeconds:      int32(s.CheckReadyURITimeout),
+		PeriodSeconds:       int32(s.CheckReadyInterval),
+		FailureThreshold:    int32(s.CheckReadyRetries),
+	}
+}
@@ -0,0 +1,277 @@
+package kubernetes
+
+// The types in this file are the subset of the Kubernetes API objects that
+// Sous reads and writes. Fields Sous doesn't care about are simply dropped
+// when decoding, and omitted when encoding.
+
+type (
+	workloadKind string
+
+	typeMeta struct {
+		APIVersion string `json:"apiVersion,omitempty"`
+		Kind       string `json:"kind,omitempty"`
+	}
+
+	objectMeta struct {
+		Name            string            `json:"name,omitempty"`
+		Namespace       string            `json:"namespace,omitempty"`
+		Labels          map[string]string `json:"labels,omitempty"`
+		Annotations     map[string]string `json:"annotations,omitempty"`
+		ResourceVersion string            `json:"resourceVersion,omitempty"`
+		Generation      int64             `json:"generation,omitempty"`
+	}
+
+	listMeta struct {
+		ResourceVersion string `json:"resourceVersion,omitempty"`
+	}
+
+	labelSelector struct {
+		MatchLabels map[string]string `json:"matchLabels,omitempty"`
+	}
+
+	condition struct {
+		Type    string `json:"type"`
+		Status  string `json:"status"`
+		Reason  string `json:"reason,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+
+	podTemplateSpec struct {
+		Metadata objectMeta `json:"metadata"`
+		Spec     podSpec    `json:"spec"`
+	}
+
+	podSpec struct {
+		Containers    []container `json:"containers"`
+		Volumes       []volume    `json:"volumes,omitempty"`
+		RestartPolicy string      `json:"restartPolicy,omitempty"`
+	}
+
+	container struct {
+		Name           string               `json:"name"`
+		Image          string               `json:"image"`
+		Env            []envVar             `json:"env,omitempty"`
+		Resources      resourceRequirements `json:"resources"`
+		Ports          []containerPort      `json:"ports,omitempty"`
+		ReadinessProbe *probe               `json:"readinessProbe,omitempty"`
+		VolumeMounts   []volumeMount        `json:"volumeMounts,omitempty"`
+	}
+
+	envVar struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	resourceRequirements struct {
+		Limits   map[string]string `json:"limits,omitempty"`
+		Requests map[string]string `json:"requests,omitempty"`
+	}
+
+	containerPort struct {
+		Name          string `json:"name,omitempty"`
+		ContainerPort int32  `json:"containerPort"`
+	}
+
+	probe struct {
+		HTTPGet             *httpGetAction `json:"httpGet,omitempty"`
+		InitialDelaySeconds int32          `json:"initialDelaySeconds,omitempty"`
+		TimeoutSeconds      int32          `json:"timeoutSeconds,omitempty"`
+		PeriodSeconds       int32          `json:"periodSeconds,omitempty"`
+		FailureThreshold    int32          `json:"failureThreshold,omitempty"`
+	}
+
+	httpGetAction struct {
+		Path   string `json:"path,omitempty"`
+		Port   string `json:"port"`
+		Scheme string `json:"scheme,omitempty"`
+	}
+
+	volume struct {
+		Name     string                `json:"name"`
+		HostPath *hostPathVolumeSource `json:"hostPath,omitempty"`
+	}
+
+	hostPathVolumeSource struct {
+		Path string `json:"path"`
+	}
+
+	volumeMount struct {
+		Name      string `json:"name"`
+		MountPath string `json:"mountPath"`
+		ReadOnly  bool   `json:"readOnly,omitempty"`
+	}
+
+	// kubeDeployment is an apps/v1 Deployment.
+	kubeDeployment struct {
+		typeMeta
+		Metadata objectMeta       `json:"metadata"`
+		Spec     deploymentSpec   `json:"spec"`
+		Status   deploymentStatus `json:"status,omitempty"`
+	}
+
+	deploymentSpec struct {
+		Replicas                *int32          `json:"replicas,omitempty"`
+		Selector                *labelSelector  `json:"selector,omitempty"`
+		Template                podTemplateSpec `json:"template"`
+		ProgressDeadlineSeconds *int32          `json:"progressDeadlineSeconds,omitempty"`
+	}
+
+	deploymentStatus struct {
+		ObservedGeneration int64       `json:"observedGeneration,omitempty"`
+		Replicas           int32       `json:"replicas,omitempty"`
+		UpdatedReplicas    int32       `json:"updatedReplicas,omitempty"`
+		ReadyReplicas      int32       `json:"readyReplicas,omitempty"`
+		AvailableReplicas  int32       `json:"availableReplicas,omitempty"`
+		Conditions         []condition `json:"conditions,omitempty"`
+	}
+
+	kubeDeploymentList struct {
+		typeMeta
+		Metadata listMeta         `json:"metadata"`
+		Items    []kubeDeployment `json:"items"`
+	}
+
+	// kubeJob is a batch/v1 Job.
+	kubeJob struct {
+		typeMeta
+		Metadata objectMeta `json:"metadata"`
+		Spec     jobSpec    `json:"spec"`
+		Status   jobStatus  `json:"status,omitempty"`
+	}
+
+	jobSpec struct {
+		Parallelism  *int32          `json:"parallelism,omitempty"`
+		Completions  *int32          `json:"completions,omitempty"`
+		BackoffLimit *int32          `json:"backoffLimit,omitempty"`
+		Suspend      *bool           `json:"suspend,omitempty"`
+		Template     podTemplateSpec `json:"template"`
+	}
+
+	jobStatus struct {
+		Active     int32       `json:"active,omitempty"`
+		Succeeded  int32       `json:"succeeded,omitempty"`
+		Failed     int32       `json:"failed,omitempty"`
+		Conditions []condition `json:"conditions,omitempty"`
+	}
+
+	kubeJobList struct {
+		typeMeta
+		Metadata listMeta  `json:"metadata"`
+		Items    []kubeJob `json:"items"`
+	}
+
+	// kubeCronJob is a batch/v1beta1 CronJob.
+	kubeCronJob struct {
+		typeMeta
+		Metadata objectMeta    `json:"metadata"`
+		Spec     cronJobSpec   `json:"spec"`
+		Status   cronJobStatus `json:"status,omitempty"`
+	}
+
+	cronJobSpec struct {
+		Schedule    string          `json:"schedule"`
+		JobTemplate jobTemplateSpec `json:"jobTemplate"`
+	}
+
+	jobTemplateSpec struct {
+		Metadata objectMeta `json:"metadata"`
+		Spec     jobSpec    `json:"spec"`
+	}
+
+	cronJobStatus struct {
+		LastScheduleTime string `json:"lastScheduleTime,omitempty"`
+	}
+
+	kubeCronJobList struct {
+		typeMeta
+		Metadata listMeta      `json:"metadata"`
+		Items    []kubeCronJob `json:"items"`
+	}
+
+	// A workload is any of the Kubernetes objects Sous uses to run a
+	// Deployment.
+	workload interface {
+		kind() workloadKind
+		meta() *objectMeta
+		podTemplate() *podTemplateSpec
+		replicas() int
+		schedule() string
+	}
+)
+
+const (
+	kindDeployment workloadKind = "Deployment"
+	kindJob        workloadKind = "Job"
+	kindCronJob    workloadKind = "CronJob"
+)
+
+// workloadKinds lists every kind of workload Sous manages, in the order in
+// which they are queried.
+var workloadKinds = []workloadKind{kindDeployment, kindCronJob, kindJob}
+
+// apiVersion returns the Kubernetes API group version for this kind.
+func (k workloadKind) apiVersion() string {
+	switch k {
+	default:
+		return ""
+	case kindDeployment:
+		return "apps/v1"
+	case kindJob:
+		return "batch/v1"
+	case kindCronJob:
+		return "batch/v1beta1"
+	}
+}
+
+// resource returns the plural resource name used in API paths.
+func (k workloadKind) resource() string {
+	switch k {
+	default:
+		return ""
+	case kindDeployment:
+		return "deployments"
+	case kindJob:
+		return "jobs"
+	case kindCronJob:
+		return "cronjobs"
+	}
+}
+
+func (d *kubeDeployment) kind() workloadKind            { return kindDeployment }
+func (d *kubeDeployment) meta() *objectMeta             { return &d.Metadata }
+func (d *kubeDeployment) podTemplate() *podTemplateSpec { return &d.Spec.Template }
+func (d *kubeDeployment) schedule() string              { return "" }
+func (d *kubeDeployment) replicas() int {
+	if d.Spec.Replicas == nil {
+		return 1
+	}
+	return int(*d.Spec.Replicas)
+}
+
+func (j *kubeJob) kind() workloadKind            { return kindJob }
+func (j *kubeJob) meta() *objectMeta             { return &j.Metadata }
+func (j *kubeJob) podTemplate() *podTemplateSpec { return &j.Spec.Template }
+func (j *kubeJob) schedule() string              { return "" }
+func (j *kubeJob) replicas() int                 { return jobReplicas(j.Spec) }
+
+func (c *kubeCronJob) kind() workloadKind            { return kindCronJob }
+func (c *kubeCronJob) meta() *objectMeta             { return &c.Metadata }
+func (c *kubeCronJob) podTemplate() *podTemplateSpec { return &c.Spec.JobTemplate.Spec.Template }
+func (c *kubeCronJob) schedule() string              { return c.Spec.Schedule }
+func (c *kubeCronJob) replicas() int                 { return jobReplicas(c.Spec.JobTemplate.Spec) }
+
+func jobReplicas(spec jobSpec) int {
+	if spec.Parallelism == nil {
+		return 1
+	}
+	return int(*spec.Parallelism)
+}
+
+func int32Ptr(i int) *int32 {
+	n := int32(i)
+	return &n
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
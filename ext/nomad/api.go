@@ -0,0 +1,123 @@
+package nomad
+
+// The types in this file are the subset of the Nomad HTTP API objects that
+// Sous reads and writes. Nomad's JSON API uses Go-style field names, so no
+// struct tags are needed beyond omitempty.
+
+type (
+	// job is a Nomad job specification.
+	job struct {
+		ID               string
+		Name             string
+		Type             string
+		Datacenters      []string
+		Meta             map[string]string `json:",omitempty"`
+		Periodic         *periodicConfig   `json:",omitempty"`
+		ParameterizedJob *parameterizedJob `json:",omitempty"`
+		TaskGroups       []*taskGroup
+		Stop             bool   `json:",omitempty"`
+		Status           string `json:",omitempty"`
+		Version          uint64 `json:",omitempty"`
+	}
+
+	jobRegisterRequest struct {
+		Job *job
+	}
+
+	jobListStub struct {
+		ID       string
+		ParentID string
+		Type     string
+		Status   string
+	}
+
+	periodicConfig struct {
+		Enabled  bool
+		Spec     string
+		SpecType string
+	}
+
+	parameterizedJob struct {
+		Payload string `json:",omitempty"`
+	}
+
+	taskGroup struct {
+		Name  string
+		Count int
+		Tasks []*task
+	}
+
+	task struct {
+		Name      string
+		Driver    string
+		Config    map[string]interface{}
+		Env       map[string]string `json:",omitempty"`
+		Resources *resources
+		Services  []*service `json:",omitempty"`
+	}
+
+	resources struct {
+		CPU      int
+		MemoryMB int
+		Networks []*networkResource `json:",omitempty"`
+	}
+
+	networkResource struct {
+		DynamicPorts []port `json:",omitempty"`
+	}
+
+	port struct {
+		Label string
+	}
+
+	service struct {
+		Name      string
+		PortLabel string
+		Checks    []serviceCheck `json:",omitempty"`
+	}
+
+	// serviceCheck durations are in nanoseconds, as in the Nomad API.
+	serviceCheck struct {
+		Name      string
+		Type      string
+		Protocol  string `json:",omitempty"`
+		Path      string `json:",omitempty"`
+		PortLabel string `json:",omitempty"`
+		Interval  int64
+		Timeout   int64
+	}
+
+	// deployment is a Nomad deployment, the rollout of a version of a
+	// service job.
+	deployment struct {
+		ID                string
+		JobID             string
+		JobVersion        uint64
+		Status            string
+		StatusDescription string
+	}
+
+	jobSummary struct {
+		JobID   string
+		Summary map[string]taskGroupSummary
+	}
+
+	taskGroupSummary struct {
+		Queued, Complete, Failed, Running, Starting, Lost int
+	}
+)
+
+const (
+	jobTypeService = "service"
+	jobTypeBatch   = "batch"
+
+	deploymentStatusRunning    = "running"
+	deploymentStatusPaused     = "paused"
+	deploymentStatusFailed     = "failed"
+	deploymentStatusSuccessful = "successful"
+	deploymentStatusCancelled  = "cancelled"
+
+	jobStatusPending = "pending"
+	jobStatusRunning = "running"
+	jobStatusDead    = "dead"
+)
@@ -0,0 +1,38 @@
+package nomad
+
+import "strings"
+
+// Config is the configuration for Nomad deployers.
+type Config struct {
+	// Datacenters is a comma separated list of the Nomad datacenters Sous
+	// jobs may be placed in.
+	Datacenters string `env:"SOUS_NOMAD_DATACENTERS"`
+	// Token is the ACL token Sous uses to authenticate to Nomad servers.
+	Token string `env:"SOUS_NOMAD_TOKEN"`
+}
+
+// DefaultConfig builds a default configuration, which can be then overridden by
+// client code.
+func DefaultConfig() Config {
+	return Config{
+		Datacenters: DefaultDatacenter,
+	}
+}
+
+// Options returns the DeployerOptions described by this Config.
+func (c Config) Options() []DeployerOption {
+	opts := []DeployerOption{}
+	dcs := []string{}
+	for _, dc := range strings.Split(c.Datacenters, ",") {
+		if dc = strings.TrimSpace(dc); dc != "" {
+			dcs = append(dcs, dc)
+		}
+	}
+	if len(dcs) > 0 {
+		opts = append(opts, OptDatacenters(dcs...))
+	}
+	if c.Token != "" {
+		opts = append(opts, OptToken(c.Token))
+	}
+	return opts
+}
@@ -0,0 +1,236 @@
+package nomad
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+)
+
+// ClusterKind is the sous.Cluster.Kind of clusters managed by this package.
+const ClusterKind = "nomad"
+
+// DefaultDatacenter is the Nomad datacenter Sous deploys into unless
+// configured otherwise with OptDatacenters.
+const DefaultDatacenter = "dc1"
+
+type deployer struct {
+	nomadFac    func(baseURL string) nomadClient
+	datacenters []string
+	token       string
+	// secrets resolves the SecretRefs in deployments' Envs.
+	secrets sous.SecretStore
+	log     logging.LogSink
+}
+
+// NewDeployer creates a new Nomad-based sous.Deployer.
+func NewDeployer(ls logging.LogSink, options ...DeployerOption) sous.Deployer {
+	d := &deployer{log: ls, datacenters: []string{DefaultDatacenter}}
+	for _, opt := range options {
+		opt(d)
+	}
+	return d
+}
+
+func (r *deployer) buildNomadClient(url string) nomadClient {
+	if r.nomadFac == nil {
+		return newAPIClient(url, r.token)
+	}
+	return r.nomadFac(url)
+}
+
+// Rectify invokes actions to ensure that the real world matches pair.Post,
+// given that it currently matches pair.Prior.
+func (r *deployer) Rectify(pair *sous.DeployablePair) sous.DiffResolution {
+	postID := ""
+	version := ""
+	if pair.Post != nil {
+		postID = pair.Post.ID().String()
+		version = pair.Post.DeploySpec().Version.String()
+	}
+
+	if pair.UUID == uuid.Nil {
+		pair.UUID = uuid.NewV4()
+	}
+
+	switch k := pair.Kind(); k {
+	default:
+		panic(fmt.Sprintf("unrecognised kind %q", k))
+	case sous.SameKind:
+		resolution := pair.SameResolution()
+		if pair.Post.Status == sous.DeployStatusFailed {
+			resolution.Error = sous.WrapResolveError(&sous.FailedStatusError{})
+		}
+		messages.ReportLogFieldsMessage("SameKind", logging.InformationLevel, r.log, postID, version, resolution)
+		return resolution
+	case sous.AddedKind:
+		result := sous.DiffResolution{DeploymentID: pair.ID()}
+		if err := r.RectifySingleCreate(pair); err != nil {
+			result.Desc = "not created"
+			result.Error = sous.WrapResolveError(&sous.CreateError{Deployment: pair.Post.Deployment.Clone(), Err: err})
+		} else {
+			result.Desc = sous.CreateDiff
+		}
+		messages.ReportLogFieldsMessage("Result of create", logging.InformationLevel, r.log, postID, version, result)
+		return result
+	case sous.RemovedKind:
+		result := sous.DiffResolution{DeploymentID: pair.ID()}
+		if err := r.RectifySingleDelete(pair); err != nil {
+			result.Error = sous.WrapResolveError(&sous.DeleteError{Deployment: pair.Prior.Deployment.Clone(), Err: err})
+			result.Desc = "not deleted"
+		} else {
+			result.Desc = sous.DeleteDiff
+		}
+		messages.ReportLogFieldsMessage("Result of delete", logging.InformationLevel, r.log, postID, version, result)
+		return result
+	case sous.ModifiedKind:
+		result := sous.DiffResolution{DeploymentID: pair.ID()}
+		if err := r.RectifySingleModification(pair); err != nil {
+			dp := &sous.DeploymentPair{
+				Prior: pair.Prior.Deployment.Clone(),
+				Post:  pair.Post.Deployment.Clone(),
+			}
+			result.Error = sous.WrapResolveError(&sous.ChangeError{Deployments: dp, Err: err})
+			result.Desc = "not updated"
+		} else if pair.Prior.Status == sous.DeployStatusFailed || pair.Post.Status == sous.DeployStatusFailed {
+			result.Desc = sous.ModifyDiff
+			result.Error = sous.WrapResolveError(&sous.FailedStatusError{})
+		} else {
+			result.Desc = sous.ModifyDiff
+		}
+		messages.ReportLogFieldsMessage("Result of modify", logging.InformationLevel, r.log, postID, version, result)
+		return result
+	}
+}
+
+// RectifySingleCreate registers the Nomad job for pair.Post.
+func (r *deployer) RectifySingleCreate(pair *sous.DeployablePair) (err error) {
+	defer sous.RectifyRecover(pair, "RectifySingleCreate", &err, r.log)
+	return r.register(pair.Post)
+}
+
+// RectifySingleDelete does not delete anything: as with Singularity, Sous
+// leaves jobs for removed manifests in place for their owners to clean up.
+func (r *deployer) RectifySingleDelete(pair *sous.DeployablePair) (err error) {
+	defer sous.RectifyRecover(pair, "RectifySingleDelete", &err, r.log)
+	data, ok := pair.ExecutorData.(*nomadTaskData)
+	if !ok {
+		return errors.Errorf("Delete record %#v doesn't contain Nomad compatible data: was %T\n\t%#v", pair.ID(), data, pair)
+	}
+	messages.ReportLogFieldsMessage("Rectify not deleting Nomad job", logging.WarningLevel, r.log, pair.ID(), data.jobID)
+	return nil
+}
+
+// RectifySingleModification re-registers the Nomad job for pair.Post; Nomad
+// itself works out how to roll the change out.
+func (r *deployer) RectifySingleModification(pair *sous.DeployablePair) (err error) {
+	defer sous.RectifyRecover(pair, "RectifySingleModification", &err, r.log)
+	return r.register(pair.Post)
+}
+
+func (r *deployer) register(d *sous.Deployable) error {
+	j, err := buildJob(*d, r.datacenters, r.secrets)
+	if err != nil {
+		return err
+	}
+	messages.ReportLogFieldsMessage("Registering Nomad job", logging.DebugLevel, r.log, j.ID, j.Type)
+	return r.buildNomadClient(d.Cluster.BaseURL).RegisterJob(j)
+}
+
+// RunningDeployments collects data from the Nomad clusters and returns a
+// list of actual deployments.
+func (r *deployer) RunningDeployments(reg sous.Registry, clusters sous.Clusters) (sous.DeployStates, error) {
+	deps := sous.NewDeployStates()
+
+	urls := map[string]struct{}{}
+	for _, c := range clusters {
+		urls[c.BaseURL] = struct{}{}
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	wg.Add(len(urls))
+	for url := range urls {
+		go func(url string) {
+			defer wg.Done()
+			states, err := r.clusterDeployments(reg, clusters, url)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, errors.Wrap(err, url))
+				return
+			}
+			for _, s := range states {
+				deps.Add(s)
+			}
+		}(url)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return deps, errs[0]
+	}
+	return deps, nil
+}
+
+func (r *deployer) clusterDeployments(reg sous.Registry, clusters sous.Clusters, url string) ([]*sous.DeployState, error) {
+	client := r.buildNomadClient(url)
+	stubs, err := client.ListJobs()
+	if err != nil {
+		return nil, errors.Wrap(err, "listing jobs")
+	}
+	states := []*sous.DeployState{}
+	for _, stub := range stubs {
+		// Children of periodic and parameterized jobs are runs, not
+		// deployments.
+		if stub.ParentID != "" {
+			continue
+		}
+		j, err := client.GetJob(stub.ID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting job %s", stub.ID)
+		}
+		ds, err := buildDeployState(reg, clusters, url, client, j, r.log)
+		if err != nil {
+			if ignorableJob(err) {
+				messages.ReportLogFieldsMessage("Ignorable job.", logging.DebugLevel, r.log, url, stub.ID, err)
+				continue
+			}
+			return nil, err
+		}
+		states = append(states, &ds)
+	}
+	return states, nil
+}
+
+// Status implements sous.Deployer on deployer.
+func (r *deployer) Status(reg sous.Registry, clusters sous.Clusters, pair *sous.DeployablePair) (*sous.DeployState, error) {
+	clusterName := pair.Post.Deployment.ClusterName
+	cluster, has := clusters[clusterName]
+	if !has {
+		return nil, errors.Errorf("No cluster found for %q. Known are: %q.", clusterName, clusters.Names())
+	}
+
+	if pair.UUID == uuid.Nil {
+		pair.UUID = uuid.NewV4()
+	}
+
+	id, err := MakeJobID(pair.Post.ID())
+	if err != nil {
+		return nil, err
+	}
+
+	client := r.buildNomadClient(cluster.BaseURL)
+	j, err := client.GetJob(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting job %s", id)
+	}
+
+	ds, err := buildDeployState(reg, clusters, cluster.BaseURL, client, j, r.log)
+	return &ds, errors.Wrapf(err, "getting job state")
+}
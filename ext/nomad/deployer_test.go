@@ -0,0 +1,263 @@
+package nomad
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/opentable/sous/ext/internal/deployertest"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type deployerScenario struct {
+	*deployertest.Scenario
+	server   *fakeAPIServer
+	deployer sous.Deployer
+}
+
+func setupDeployer(t *testing.T) *deployerScenario {
+	server := newFakeAPIServer()
+	ls, _ := logging.NewLogSinkSpy()
+	return &deployerScenario{
+		Scenario: deployertest.NewScenario("nomad-1", ClusterKind, server.URL),
+		server:   server,
+		deployer: NewDeployer(ls, OptDatacenters("dc1", "dc2")),
+	}
+}
+
+func TestMakeJobID(t *testing.T) {
+	valid := regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	ids := []sous.DeploymentID{
+		{ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/user/repo"}}, Cluster: "some-cluster"},
+		{ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/user/repo"}}, Cluster: "some_cluster"},
+		{ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/user/Repo", Dir: "some/dir"}, Flavor: "Tasty.Flavor"}, Cluster: "c"},
+		{ManifestID: sous.ManifestID{
+			Source: sous.SourceLocation{
+				Repo: "github.com/ihaveanincrediblylongname/andilikemyprojectstohaveincrediblylongnamestoo",
+				Dir:  "and/also/i/bury/my/services/super/deep/in/the/build/tree",
+			},
+			Flavor: "wellwehavetohaveaflavorforthisservice",
+		}, Cluster: "foo"},
+	}
+
+	seen := map[string]sous.DeploymentID{}
+	for _, id := range ids {
+		jid, err := MakeJobID(id)
+		require.NoError(t, err)
+		assert.True(t, len(jid) <= maxJobIDLen, "%q is too long", jid)
+		assert.Regexp(t, valid, jid)
+		if other, has := seen[jid]; has {
+			t.Errorf("Collision! %q produced by both %v and %v", jid, id, other)
+		}
+		seen[jid] = id
+	}
+}
+
+func TestBuildJob_Kinds(t *testing.T) {
+	s := setupDeployer(t)
+	defer s.server.Close()
+
+	cases := []struct {
+		kind          sous.ManifestKind
+		jobType       string
+		periodic      bool
+		parameterized bool
+	}{
+		{sous.ManifestKindService, jobTypeService, false, false},
+		{sous.ManifestKindWorker, jobTypeService, false, false},
+		{sous.ManifestKindScheduled, jobTypeBatch, true, false},
+		{sous.ManifestKindOnce, jobTypeBatch, false, false},
+		{sous.ManifestKindOnDemand, jobTypeBatch, false, true},
+	}
+	for _, c := range cases {
+		j, err := buildJob(*s.Deployable(c.kind), []string{"dc1"}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, c.jobType, j.Type, "for %s", c.kind)
+		assert.Equal(t, c.periodic, j.Periodic != nil, "for %s", c.kind)
+		assert.Equal(t, c.parameterized, j.ParameterizedJob != nil, "for %s", c.kind)
+		assert.Equal(t, "docker.example.com/example@sha256:0123456789", j.TaskGroups[0].Tasks[0].Config["image"])
+	}
+
+	j, err := buildJob(*s.Deployable(sous.ManifestKindScheduled), []string{"dc1"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "*/5 * * * *", j.Periodic.Spec)
+
+	j, err = buildJob(*s.Deployable(sous.ManifestKindService), []string{"dc1"}, nil)
+	require.NoError(t, err)
+	require.Len(t, j.TaskGroups[0].Tasks[0].Services, 1)
+}
+
+func TestRectify_CreateRoundTrip(t *testing.T) {
+	for _, kind := range []sous.ManifestKind{
+		sous.ManifestKindService,
+		sous.ManifestKindWorker,
+		sous.ManifestKindScheduled,
+		sous.ManifestKindOnce,
+		sous.ManifestKindOnDemand,
+	} {
+		s := setupDeployer(t)
+		post := s.Deployable(kind)
+
+		rez := s.deployer.Rectify(&sous.DeployablePair{Post: post})
+		require.Nil(t, rez.Error, "for %s", kind)
+		assert.Equal(t, sous.CreateDiff, rez.Desc)
+
+		ds, err := s.deployer.RunningDeployments(s.Registry, s.Clusters)
+		require.NoError(t, err)
+		require.Equal(t, 1, ds.Len(), "for %s", kind)
+
+		actual, has := ds.Get(post.ID())
+		require.True(t, has, "for %s", kind)
+		different, diffs := post.Deployment.Diff(&actual.Deployment)
+		assert.False(t, different, "for %s: %v", kind, diffs)
+
+		id, _ := MakeJobID(post.ID())
+		assert.Equal(t, []string{"dc1", "dc2"}, s.server.getJob(id).Datacenters)
+		s.server.Close()
+	}
+}
+
+func TestRectify_Modification(t *testing.T) {
+	s := setupDeployer(t)
+	defer s.server.Close()
+	prior := s.Deployable(sous.ManifestKindService)
+	require.Nil(t, s.deployer.Rectify(&sous.DeployablePair{Post: prior}).Error)
+
+	ds, err := s.deployer.RunningDeployments(s.Registry, s.Clusters)
+	require.NoError(t, err)
+	actual, _ := ds.Get(prior.ID())
+
+	post := s.Deployable(sous.ManifestKindService)
+	post.NumInstances = 4
+	pair := &sous.DeployablePair{
+		Prior:        &sous.Deployable{Status: actual.Status, Deployment: &actual.Deployment, BuildArtifact: prior.BuildArtifact},
+		Post:         post,
+		ExecutorData: actual.ExecutorData,
+	}
+	pair.SetID(post.ID())
+
+	rez := s.deployer.Rectify(pair)
+	require.Nil(t, rez.Error)
+	assert.Equal(t, sous.ModifyDiff, rez.Desc)
+
+	id, _ := MakeJobID(post.ID())
+	j := s.server.getJob(id)
+	require.NotNil(t, j)
+	assert.Equal(t, 4, j.TaskGroups[0].Count)
+	assert.EqualValues(t, 1, j.Version)
+}
+
+func TestStatus_Service(t *testing.T) {
+	s := setupDeployer(t)
+	defer s.server.Close()
+	post := s.Deployable(sous.ManifestKindService)
+	require.Nil(t, s.deployer.Rectify(&sous.DeployablePair{Post: post}).Error)
+
+	id, _ := MakeJobID(post.ID())
+	pair := &sous.DeployablePair{Post: post}
+
+	state, err := s.deployer.Status(s.Registry, s.Clusters, pair)
+	require.NoError(t, err)
+	assert.Equal(t, sous.DeployStatusPending, state.Status)
+
+	s.server.setJobStatus(id, jobStatusRunning)
+	s.server.setDeployment(id, deploymentStatusRunning, "")
+	state, err = s.deployer.Status(s.Registry, s.Clusters, pair)
+	require.NoError(t, err)
+	assert.Equal(t, sous.DeployStatusPending, state.Status)
+
+	s.server.setDeployment(id, deploymentStatusSuccessful, "")
+	state, err = s.deployer.Status(s.Registry, s.Clusters, pair)
+	require.NoError(t, err)
+	assert.Equal(t, sous.DeployStatusActive, state.Status)
+
+	s.server.setDeployment(id, deploymentStatusFailed, "Failed due to unhealthy allocations")
+	state, err = s.deployer.Status(s.Registry, s.Clusters, pair)
+	require.NoError(t, err)
+	assert.Equal(t, sous.DeployStatusFailed, state.Status)
+	assert.Contains(t, state.ExecutorMessage, "unhealthy allocations")
+}
+
+func TestStatus_Batch(t *testing.T) {
+	s := setupDeployer(t)
+	defer s.server.Close()
+	post := s.Deployable(sous.ManifestKindOnce)
+	require.Nil(t, s.deployer.Rectify(&sous.DeployablePair{Post: post}).Error)
+
+	id, _ := MakeJobID(post.ID())
+	pair := &sous.DeployablePair{Post: post}
+
+	state, err := s.deployer.Status(s.Registry, s.Clusters, pair)
+	require.NoError(t, err)
+	assert.Equal(t, sous.DeployStatusPending, state.Status)
+
+	s.server.setSummary(id, taskGroupSummary{Running: 1})
+	state, err = s.deployer.Status(s.Registry, s.Clusters, pair)
+	require.NoError(t, err)
+	assert.Equal(t, sous.DeployStatusActive, state.Status)
+
+	s.server.setSummary(id, taskGroupSummary{Failed: 3})
+	state, err = s.deployer.Status(s.Registry, s.Clusters, pair)
+	require.NoError(t, err)
+	assert.Equal(t, sous.DeployStatusFailed, state.Status)
+}
+
+func TestRunningDeployments_IgnoresForeignJobs(t *testing.T) {
+	s := setupDeployer(t)
+	defer s.server.Close()
+	post := s.Deployable(sous.ManifestKindService)
+	require.Nil(t, s.deployer.Rectify(&sous.DeployablePair{Post: post}).Error)
+
+	client := newAPIClient(s.server.URL, "")
+	require.NoError(t, client.RegisterJob(&job{
+		ID:   "not-sous",
+		Type: jobTypeService,
+		TaskGroups: []*taskGroup{{Name: "web", Count: 1, Tasks: []*task{{
+			Name: "web", Driver: "docker", Resources: &resources{CPU: 100, MemoryMB: 64},
+		}}}},
+	}))
+
+	ds, err := s.deployer.RunningDeployments(s.Registry, s.Clusters)
+	require.NoError(t, err)
+	assert.Equal(t, 1, ds.Len())
+
+	other := sous.Clusters{"nomad-2": &sous.Cluster{Name: "nomad-2", Kind: ClusterKind, BaseURL: s.server.URL}}
+	ds, err = s.deployer.RunningDeployments(s.Registry, other)
+	require.NoError(t, err)
+	assert.Equal(t, 0, ds.Len())
+}
+
+func TestRectify_Secrets(t *testing.T) {
+	s := setupDeployer(t)
+	defer s.server.Close()
+	ls, _ := logging.NewLogSinkSpy()
+	s.deployer = NewDeployer(ls, OptDatacenters("dc1", "dc2"), OptSecretStore(deployertest.MapSecretStore{"db": "hunter2"}))
+	post := s.Deployable(sous.ManifestKindService)
+	post.Env["DB_PASSWORD"] = "${secret:db}"
+
+	require.Nil(t, s.deployer.Rectify(&sous.DeployablePair{Post: post}).Error)
+
+	id, _ := MakeJobID(post.ID())
+	assert.Equal(t, "hunter2", s.server.getJob(id).TaskGroups[0].Tasks[0].Env["DB_PASSWORD"])
+
+	ds, err := s.deployer.RunningDeployments(s.Registry, s.Clusters)
+	require.NoError(t, err)
+	actual, has := ds.Get(post.ID())
+	require.True(t, has)
+	assert.Equal(t, "${secret:db}", actual.Env["DB_PASSWORD"])
+	different, diffs := post.Deployment.Diff(&actual.Deployment)
+	assert.False(t, different, "%v", diffs)
+}
+
+func TestRectify_MissingSecret(t *testing.T) {
+	s := setupDeployer(t)
+	defer s.server.Close()
+	post := s.Deployable(sous.ManifestKindService)
+	post.Env["DB_PASSWORD"] = "${secret:db}"
+
+	rez := s.deployer.Rectify(&sous.DeployablePair{Post: post})
+	require.NotNil(t, rez.Error)
+	assert.Contains(t, rez.Error.Error(), "db")
+}
@@ -0,0 +1,27 @@
+package nomad
+
+import "github.com/opentable/sous/lib"
+
+// DeployerOption is an option for configuring Nomad deployers.
+type DeployerOption func(*deployer)
+
+// OptDatacenters sets the Nomad datacenters Sous jobs may be placed in.
+func OptDatacenters(dcs ...string) DeployerOption {
+	return func(d *deployer) { d.datacenters = dcs }
+}
+
+// OptToken sets the ACL token used to authenticate to Nomad servers.
+func OptToken(token string) DeployerOption {
+	return func(d *deployer) { d.token = token }
+}
+
+// OptSecretStore sets the store the SecretRefs in deployments' Envs are
+// resolved from.
+func OptSecretStore(secrets sous.SecretStore) DeployerOption {
+	return func(d *deployer) { d.secrets = secrets }
+}
+
+// optClientFactory overrides the construction of API clients, for testing.
+func optClientFactory(fn func(string) nomadClient) DeployerOption {
+	return func(d *deployer) { d.nomadFac = fn }
+}
@@ -0,0 +1,275 @@
+package nomad
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/opentable/sous/ext/docker"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/firsterr"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
+
+type (
+	deploymentBuilder struct {
+		clusters sous.Clusters
+		baseURL  string
+		client   nomadClient
+		job      *job
+		task     *task
+		registry sous.ImageLabeller
+		Target   sous.DeployState
+		log      logging.LogSink
+	}
+
+	nomadTaskData struct {
+		jobID   string
+		version uint64
+	}
+
+	malformedJob struct {
+		message string
+	}
+
+	notThisClusterError struct {
+		foundClusterName        string
+		responsibleClusterNames []string
+	}
+)
+
+func (mj malformedJob) Error() string {
+	return mj.message
+}
+
+func (ntc notThisClusterError) Error() string {
+	return fmt.Sprintf("%s does not belong to this Sous server %#v.",
+		ntc.foundClusterName, ntc.responsibleClusterNames)
+}
+
+func ignorableJob(err error) bool {
+	switch errors.Cause(err).(type) {
+	case malformedJob, notThisClusterError:
+		return true
+	}
+	return false
+}
+
+// buildDeployState does all the work to collect the data for a DeployState
+// from a Nomad job.
+func buildDeployState(reg sous.ImageLabeller, clusters sous.Clusters, baseURL string, client nomadClient, j *job, log logging.LogSink) (sous.DeployState, error) {
+	messages.ReportLogFieldsMessage("Build Deployment", logging.ExtraDebug1Level, log, baseURL, j.ID)
+	db := deploymentBuilder{registry: reg, clusters: clusters, baseURL: baseURL, client: client, job: j, log: log}
+	return db.Target, db.completeConstruction()
+}
+
+func (db *deploymentBuilder) completeConstruction() error {
+	wrapError := func(fn func() error, msgStr string) func() error {
+		return func() error {
+			return errors.Wrap(fn(), msgStr)
+		}
+	}
+	return firsterr.Returned(
+		wrapError(db.basics, "Failed to extract basic information from Nomad job."),
+		wrapError(db.sousJobCheck, "Could not determine if the Nomad job is controlled by Sous"),
+		wrapError(db.determineStatus, "Could not determine current status of Nomad job"),
+		wrapError(db.retrieveSourceID, "Could not determine SourceID from task image."),
+		wrapError(db.restoreFromMeta, "Could not restore deployment from Nomad job meta."),
+		wrapError(db.unpackDeployConfig, "Could not convert data from a Nomad job to a sous.Deployment."),
+	)
+}
+
+func (db *deploymentBuilder) basics() error {
+	db.Target.Cluster = &sous.Cluster{BaseURL: db.baseURL, Kind: ClusterKind}
+	db.Target.ExecutorData = &nomadTaskData{jobID: db.job.ID, version: db.job.Version}
+	db.Target.SchedulerURL = fmt.Sprintf("%s/v1/job/%s", db.baseURL, db.job.ID)
+
+	if len(db.job.TaskGroups) != 1 {
+		return malformedJob{fmt.Sprintf("Nomad job %q has %d task groups, expected 1", db.job.ID, len(db.job.TaskGroups))}
+	}
+	for _, t := range db.job.TaskGroups[0].Tasks {
+		if t.Name == taskName {
+			db.task = t
+		}
+	}
+	if db.task == nil {
+		return malformedJob{fmt.Sprintf("Nomad job %q has no %q task", db.job.ID, taskName)}
+	}
+	if db.task.Resources == nil {
+		return malformedJob{fmt.Sprintf("Nomad job %q has no resources", db.job.ID)}
+	}
+	return nil
+}
+
+func (db *deploymentBuilder) sousJobCheck() error {
+	if db.job.Meta[ManagedMeta] != "true" {
+		return malformedJob{"Job meta did not mark it as managed by Sous"}
+	}
+	cn, ok := db.job.Meta[sous.ClusterNameLabel]
+	if !ok {
+		return malformedJob{"Job meta did not include a cluster name"}
+	}
+	if _, has := db.clusters[cn]; !has {
+		return notThisClusterError{cn, db.clusters.Names()}
+	}
+	db.Target.ClusterName = cn
+	return nil
+}
+
+func (db *deploymentBuilder) determineStatus() error {
+	switch {
+	default:
+		return db.batchStatus()
+	case db.job.Type == jobTypeService:
+		return db.serviceStatus()
+	case db.job.Periodic != nil, db.job.ParameterizedJob != nil:
+		// Periodic and parameterized jobs are as live as they're going to
+		// get once they're registered.
+		db.Target.Status = sous.DeployStatusActive
+		return nil
+	}
+}
+
+func (db *deploymentBuilder) serviceStatus() error {
+	d, err := db.client.LatestDeployment(db.job.ID)
+	if err != nil {
+		return err
+	}
+	if d != nil && d.JobVersion == db.job.Version {
+		switch d.Status {
+		case deploymentStatusFailed:
+			db.Target.Status = sous.DeployStatusFailed
+			db.Target.ExecutorMessage = fmt.Sprintf("Deploy failure: %q", d.StatusDescription)
+			return nil
+		case deploymentStatusRunning, deploymentStatusPaused:
+			db.Target.Status = sous.DeployStatusPending
+			return nil
+		}
+	}
+	switch db.job.Status {
+	default:
+		db.Target.Status = sous.DeployStatusPending
+	case jobStatusRunning:
+		db.Target.Status = sous.DeployStatusActive
+	case jobStatusDead:
+		db.Target.Status = sous.DeployStatusFailed
+		db.Target.ExecutorMessage = "Job is dead"
+	}
+	return nil
+}
+
+func (db *deploymentBuilder) batchStatus() error {
+	s, err := db.client.JobSummary(db.job.ID)
+	if err != nil {
+		return err
+	}
+	tg := s.Summary[taskName]
+	switch {
+	default:
+		db.Target.Status = sous.DeployStatusPending
+	case tg.Failed > 0 && tg.Complete == 0 && tg.Running == 0:
+		db.Target.Status = sous.DeployStatusFailed
+		db.Target.ExecutorMessage = fmt.Sprintf("Job failure: %d failed allocations", tg.Failed)
+	case tg.Complete > 0, tg.Running > 0:
+		db.Target.Status = sous.DeployStatusActive
+	}
+	return nil
+}
+
+func (db *deploymentBuilder) retrieveSourceID() error {
+	image, _ := db.task.Config["image"].(string)
+	if image == "" {
+		return malformedJob{fmt.Sprintf("Nomad job %q has no image", db.job.ID)}
+	}
+	// XXX coupled to Docker registry as ImageMapper, as with Singularity.
+	labels, err := db.registry.ImageLabels(image)
+	if err != nil {
+		return malformedJob{err.Error()}
+	}
+	db.Target.SourceID, err = docker.SourceIDFromLabels(labels)
+	if err != nil {
+		return errors.Wrapf(malformedJob{err.Error()}, "For job: %s", db.job.ID)
+	}
+	return nil
+}
+
+func (db *deploymentBuilder) restoreFromMeta() error {
+	meta := db.job.Meta
+
+	db.Target.Flavor = meta[sous.FlavorLabel]
+	db.Target.Kind = sous.ManifestKind(meta[KindMeta])
+
+	db.Target.Owners = make(sous.OwnerSet)
+	for _, o := range strings.Split(meta[OwnersMeta], ",") {
+		if o != "" {
+			db.Target.Owners.Add(o)
+		}
+	}
+
+	if s, has := meta[StartupMeta]; has {
+		if err := json.Unmarshal([]byte(s), &db.Target.Startup); err != nil {
+			return malformedJob{err.Error()}
+		}
+	}
+	if s, has := meta[MetadataMeta]; has {
+		if err := json.Unmarshal([]byte(s), &db.Target.Metadata); err != nil {
+			return malformedJob{err.Error()}
+		}
+	}
+	if u, has := meta[UserMeta]; has {
+		db.Target.User.Email = u
+	}
+	return nil
+}
+
+func (db *deploymentBuilder) unpackDeployConfig() error {
+	db.Target.Env = make(sous.Env)
+	for k, v := range db.task.Env {
+		db.Target.Env[k] = v
+	}
+	if s, has := db.job.Meta[sous.SecretsLabel]; has {
+		// Put back the SecretRefs the secrets in Env were resolved from, so
+		// that the secrets themselves are neither logged nor compared.
+		refs := map[string]sous.SecretRef{}
+		if err := json.Unmarshal([]byte(s), &refs); err != nil {
+			return malformedJob{fmt.Sprintf("Nomad job %q has a malformed %s: %s", db.job.ID, sous.SecretsLabel, err)}
+		}
+		db.Target.Env = db.Target.Env.RestoreSecretRefs(refs)
+	}
+
+	ports := 0
+	for _, n := range db.task.Resources.Networks {
+		ports += len(n.DynamicPorts)
+	}
+	db.Target.Resources = make(sous.Resources)
+	db.Target.Resources["cpus"] = cpusFromMHz(db.task.Resources.CPU)
+	db.Target.Resources["memory"] = strconv.Itoa(db.task.Resources.MemoryMB)
+	db.Target.Resources["ports"] = strconv.Itoa(ports)
+
+	db.Target.NumInstances = db.job.TaskGroups[0].Count
+	if db.job.Periodic != nil {
+		db.Target.Schedule = db.job.Periodic.Spec
+	}
+
+	vols, _ := db.task.Config["volumes"].([]interface{})
+	for _, v := range vols {
+		s, _ := v.(string)
+		parts := strings.Split(s, ":")
+		if len(parts) < 2 {
+			return malformedJob{fmt.Sprintf("malformed volume %q", s)}
+		}
+		mode := sous.ReadWrite
+		if len(parts) > 2 && parts[2] == "ro" {
+			mode = sous.ReadOnly
+		}
+		db.Target.DeployConfig.Volumes = append(db.Target.DeployConfig.Volumes, &sous.Volume{
+			Host:      parts[0],
+			Container: parts[1],
+			Mode:      mode,
+		})
+	}
+	return nil
+}
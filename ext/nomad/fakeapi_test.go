@@ -0,0 +1,147 @@
+package nomad
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// fakeAPIServer is an in-process stand-in for the parts of the Nomad HTTP API
+// that Sous uses. Jobs are round-tripped through JSON so that tests exercise
+// the same encoding as a real server.
+type fakeAPIServer struct {
+	*httptest.Server
+	sync.Mutex
+	jobs        map[string][]byte
+	deployments map[string]*deployment
+	summaries   map[string]*jobSummary
+	calls       []string
+}
+
+var fakeJobPathRE = regexp.MustCompile(`^/v1/job/([^/]+)(?:/([^/]+))?$`)
+
+func newFakeAPIServer() *fakeAPIServer {
+	f := &fakeAPIServer{
+		jobs:        map[string][]byte{},
+		deployments: map[string]*deployment{},
+		summaries:   map[string]*jobSummary{},
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	return f
+}
+
+func (f *fakeAPIServer) serve(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	f.calls = append(f.calls, r.Method+" "+r.URL.Path)
+
+	if r.Method == "GET" && r.URL.Path == "/v1/jobs" {
+		ids := []string{}
+		for id := range f.jobs {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		stubs := []jobListStub{}
+		for _, id := range ids {
+			j := f.job(id)
+			stubs = append(stubs, jobListStub{ID: j.ID, Type: j.Type, Status: j.Status})
+		}
+		json.NewEncoder(w).Encode(stubs)
+		return
+	}
+
+	m := fakeJobPathRE.FindStringSubmatch(r.URL.Path)
+	if m == nil {
+		http.Error(w, "no such path", http.StatusNotFound)
+		return
+	}
+	id, sub := m[1], m[2]
+
+	switch {
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	case r.Method == "POST" && sub == "":
+		req := jobRegisterRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Job == nil || req.Job.ID != id {
+			http.Error(w, "job ID mismatch", http.StatusBadRequest)
+			return
+		}
+		if old := f.job(id); old != nil {
+			req.Job.Version = old.Version + 1
+		}
+		req.Job.Status = jobStatusPending
+		f.jobs[id], _ = json.Marshal(req.Job)
+		json.NewEncoder(w).Encode(map[string]interface{}{"EvalID": "fake-eval"})
+	case r.Method == "GET" && sub == "":
+		if _, has := f.jobs[id]; !has {
+			http.Error(w, "job not found", http.StatusNotFound)
+			return
+		}
+		w.Write(f.jobs[id])
+	case r.Method == "GET" && sub == "deployment":
+		json.NewEncoder(w).Encode(f.deployments[id])
+	case r.Method == "GET" && sub == "summary":
+		s := f.summaries[id]
+		if s == nil {
+			s = &jobSummary{JobID: id, Summary: map[string]taskGroupSummary{}}
+		}
+		json.NewEncoder(w).Encode(s)
+	}
+}
+
+// job decodes a stored job; callers must hold the lock.
+func (f *fakeAPIServer) job(id string) *job {
+	b, has := f.jobs[id]
+	if !has {
+		return nil
+	}
+	j := &job{}
+	if err := json.Unmarshal(b, j); err != nil {
+		panic(err)
+	}
+	return j
+}
+
+// getJob returns a stored job, or nil.
+func (f *fakeAPIServer) getJob(id string) *job {
+	f.Lock()
+	defer f.Unlock()
+	return f.job(id)
+}
+
+// setJobStatus replaces the status of a stored job.
+func (f *fakeAPIServer) setJobStatus(id, status string) {
+	f.Lock()
+	defer f.Unlock()
+	j := f.job(id)
+	j.Status = status
+	f.jobs[id], _ = json.Marshal(j)
+}
+
+// setDeployment records the latest deployment of a job, for its current
+// version.
+func (f *fakeAPIServer) setDeployment(id, status, desc string) {
+	f.Lock()
+	defer f.Unlock()
+	f.deployments[id] = &deployment{
+		ID:                "fake-deployment",
+		JobID:             id,
+		JobVersion:        f.job(id).Version,
+		Status:            status,
+		StatusDescription: desc,
+	}
+}
+
+// setSummary records the allocation summary of a job's task group.
+func (f *fakeAPIServer) setSummary(id string, s taskGroupSummary) {
+	f.Lock()
+	defer f.Unlock()
+	f.summaries[id] = &jobSummary{JobID: id, Summary: map[string]taskGroupSummary{taskName: s}}
+}
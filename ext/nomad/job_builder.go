@@ -0,0 +1,222 @@
+package nomad
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ManagedMeta marks Nomad jobs which are controlled by Sous.
+	ManagedMeta = "com.opentable.sous.managed"
+	// KindMeta records the sous.ManifestKind of a deployment.
+	KindMeta = "com.opentable.sous.kind"
+	// OwnersMeta records the comma separated owners of a deployment.
+	OwnersMeta = "com.opentable.sous.owners"
+	// StartupMeta records the JSON encoded sous.Startup of a deployment.
+	StartupMeta = "com.opentable.sous.startup"
+	// MetadataMeta records the JSON encoded sous.Metadata of a deployment.
+	MetadataMeta = "com.opentable.sous.deploy_metadata"
+	// UserMeta records the user who last deployed.
+	UserMeta = "com.opentable.sous.deployed_by"
+
+	// Nomad allows longer IDs, but they also name Consul services, which are
+	// best kept to a DNS label.
+	maxJobIDLen = 63
+
+	taskName = "app"
+
+	// Nomad measures CPU in MHz; Sous in CPUs.
+	mhzPerCPU = 1000
+)
+
+var illegalJobIDChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// MakeJobID creates a Nomad job ID from a sous.DeploymentID.
+func MakeJobID(depID sous.DeploymentID) (string, error) {
+	sn, err := depID.ManifestID.Source.ShortName()
+	if err != nil {
+		return "", err
+	}
+	parts := []string{}
+	for _, p := range []string{sn, depID.ManifestID.Source.Dir, depID.ManifestID.Flavor, depID.Cluster} {
+		if s := strings.Trim(illegalJobIDChars.ReplaceAllString(p, "_"), "_"); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	digest := fmt.Sprintf("%x", depID.Digest())[:8]
+
+	base := strings.Join(parts, "-")
+	if max := maxJobIDLen - len(digest) - 1; len(base) > max {
+		base = base[:max]
+	}
+	return base + "-" + digest, nil
+}
+
+func portLabel(i int) string {
+	return fmt.Sprintf("PORT%d", i)
+}
+
+// buildJob maps a sous.Deployable onto a Nomad job specification. This is
+// where the SecretRefs in d's Env are resolved from secrets: the SecretRefs
+// are recorded in the job's meta so that they can be restored when the job is
+// read back.
+func buildJob(d sous.Deployable, datacenters []string, secrets sous.SecretStore) (*job, error) {
+	if d.BuildArtifact == nil {
+		return nil, &sous.MissingImageNameError{Cause: fmt.Errorf("Missing BuildArtifact on Deployable")}
+	}
+	dep := d.Deployment
+
+	id, err := MakeJobID(dep.ID())
+	if err != nil {
+		return nil, err
+	}
+	meta, err := buildMeta(dep)
+	if err != nil {
+		return nil, err
+	}
+	env, err := dep.Env.ResolveSecrets(secrets)
+	if err != nil {
+		return nil, err
+	}
+	if refs := dep.Env.SecretRefs(); len(refs) > 0 {
+		b, err := json.Marshal(refs)
+		if err != nil {
+			return nil, err
+		}
+		meta[sous.SecretsLabel] = string(b)
+	}
+
+	j := &job{
+		ID:          id,
+		Name:        id,
+		Datacenters: datacenters,
+		Meta:        meta,
+		TaskGroups: []*taskGroup{{
+			Name:  taskName,
+			Count: dep.NumInstances,
+			Tasks: []*task{buildTask(d, id, env)},
+		}},
+	}
+
+	switch dep.Kind {
+	default:
+		return nil, errors.Errorf("Unrecognized Sous manifest kind: %v", dep.Kind)
+	case sous.ManifestKindService, sous.ManifestKindWorker:
+		j.Type = jobTypeService
+	case sous.ManifestKindScheduled, sous.ScheduledJob:
+		j.Type = jobTypeBatch
+		j.Periodic = &periodicConfig{Enabled: true, Spec: dep.Schedule, SpecType: "cron"}
+	case sous.ManifestKindOnce:
+		j.Type = jobTypeBatch
+	case sous.ManifestKindOnDemand:
+		j.Type = jobTypeBatch
+		j.ParameterizedJob = &parameterizedJob{Payload: "forbidden"}
+	}
+
+	return j, nil
+}
+
+func buildMeta(dep *sous.Deployment) (map[string]string, error) {
+	startup, err := json.Marshal(dep.Startup)
+	if err != nil {
+		return nil, err
+	}
+	md, err := json.Marshal(dep.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	user := "unknown_sous_deploy"
+	if len(dep.User.Email) > 1 {
+		user = dep.User.Email
+	}
+
+	return map[string]string{
+		ManagedMeta:           "true",
+		sous.ClusterNameLabel: dep.ClusterName,
+		sous.FlavorLabel:      dep.Flavor,
+		KindMeta:              string(dep.Kind),
+		OwnersMeta:            strings.Join(dep.Owners.Slice(), ","),
+		StartupMeta:           string(startup),
+		MetadataMeta:          string(md),
+		UserMeta:              user,
+	}, nil
+}
+
+func buildTask(d sous.Deployable, id string, env sous.Env) *task {
+	dep := d.Deployment
+
+	vols := []string{}
+	for _, v := range dep.DeployConfig.Volumes {
+		if v == nil {
+			continue
+		}
+		vol := v.Host + ":" + v.Container
+		if v.Mode == sous.ReadOnly {
+			vol += ":ro"
+		}
+		vols = append(vols, vol)
+	}
+
+	ports := []port{}
+	for i := 0; i < int(dep.Resources.Ports()); i++ {
+		ports = append(ports, port{Label: portLabel(i)})
+	}
+
+	t := &task{
+		Name:   taskName,
+		Driver: "docker",
+		Config: map[string]interface{}{
+			"image": d.BuildArtifact.DigestReference,
+		},
+		Env: map[string]string(env),
+		Resources: &resources{
+			CPU:      int(dep.Resources.Cpus() * mhzPerCPU),
+			MemoryMB: int(dep.Resources.Memory()),
+			Networks: []*networkResource{{DynamicPorts: ports}},
+		},
+	}
+	if len(vols) > 0 {
+		t.Config["volumes"] = vols
+	}
+
+	if dep.Kind == sous.ManifestKindService && len(ports) > 0 {
+		t.Services = []*service{buildService(dep.Startup, id)}
+	}
+	return t
+}
+
+// buildService registers http-service deployments in service discovery, with
+// a health check equivalent to the Startup configuration.
+func buildService(s sous.Startup, id string) *service {
+	svc := &service{
+		Name:      id,
+		PortLabel: portLabel(0),
+	}
+	if s.SkipCheck {
+		return svc
+	}
+	svc.PortLabel = portLabel(s.CheckReadyPortIndex)
+	svc.Checks = []serviceCheck{{
+		Name:      "sous-startup",
+		Type:      "http",
+		Protocol:  strings.ToLower(s.CheckReadyProtocol),
+		Path:      s.CheckReadyURIPath,
+		PortLabel: portLabel(s.CheckReadyPortIndex),
+		Interval:  int64(time.Duration(s.CheckReadyInterval) * time.Second),
+		Timeout:   int64(time.Duration(s.CheckReadyURITimeout) * time.Second),
+	}}
+	return svc
+}
+
+// cpusFromMHz converts Nomad CPU shares back into a Sous cpus value.
+func cpusFromMHz(mhz int) string {
+	return strconv.FormatFloat(float64(mhz)/mhzPerCPU, 'f', 3, 64)
+}
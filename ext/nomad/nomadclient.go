@@ -0,0 +1,142 @@
+package nomad
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// nomadClient abstracts the queries and commands we send to a Nomad
+	// cluster.
+	nomadClient interface {
+		// ListJobs returns a stub for each job in the cluster.
+		ListJobs() ([]jobListStub, error)
+		// GetJob returns the full specification of a job.
+		GetJob(id string) (*job, error)
+		// RegisterJob creates or updates a job.
+		RegisterJob(j *job) error
+		// LatestDeployment returns the most recent deployment of a job, or
+		// nil if it has never been deployed.
+		LatestDeployment(id string) (*deployment, error)
+		// JobSummary returns the allocation counts of a job.
+		JobSummary(id string) (*jobSummary, error)
+	}
+
+	// apiClient is the HTTP implementation of nomadClient.
+	apiClient struct {
+		baseURL string
+		token   string
+		http    *http.Client
+	}
+
+	// apiError is returned when Nomad responds with a non-2xx status.
+	apiError struct {
+		Method, URL string
+		Status      int
+		Body        string
+	}
+)
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.Status, e.Body)
+}
+
+func isNotFound(err error) bool {
+	ae, is := errors.Cause(err).(*apiError)
+	return is && ae.Status == http.StatusNotFound
+}
+
+func newAPIClient(baseURL, token string) *apiClient {
+	return &apiClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    http.DefaultClient,
+	}
+}
+
+func (c *apiClient) jobURL(id string, rest ...string) string {
+	return strings.Join(append([]string{c.baseURL, "v1", "job", url.PathEscape(id)}, rest...), "/")
+}
+
+func (c *apiClient) do(method, u string, body, into interface{}) error {
+	rdr := &bytes.Buffer{}
+	if body != nil {
+		if err := json.NewEncoder(rdr).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, u, rdr)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Nomad-Token", c.token)
+	}
+
+	rz, err := c.http.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "%s %s", method, u)
+	}
+	defer rz.Body.Close()
+
+	b, err := ioutil.ReadAll(rz.Body)
+	if err != nil {
+		return err
+	}
+	if rz.StatusCode < 200 || rz.StatusCode > 299 {
+		return &apiError{Method: method, URL: u, Status: rz.StatusCode, Body: string(b)}
+	}
+	if into == nil {
+		return nil
+	}
+	return errors.Wrapf(json.Unmarshal(b, into), "decoding %s %s", method, u)
+}
+
+// ListJobs implements nomadClient on apiClient.
+func (c *apiClient) ListJobs() ([]jobListStub, error) {
+	stubs := []jobListStub{}
+	if err := c.do("GET", c.baseURL+"/v1/jobs", nil, &stubs); err != nil {
+		return nil, err
+	}
+	return stubs, nil
+}
+
+// GetJob implements nomadClient on apiClient.
+func (c *apiClient) GetJob(id string) (*job, error) {
+	j := &job{}
+	if err := c.do("GET", c.jobURL(id), nil, j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// RegisterJob implements nomadClient on apiClient.
+func (c *apiClient) RegisterJob(j *job) error {
+	return c.do("POST", c.jobURL(j.ID), jobRegisterRequest{Job: j}, nil)
+}
+
+// LatestDeployment implements nomadClient on apiClient.
+func (c *apiClient) LatestDeployment(id string) (*deployment, error) {
+	var d *deployment
+	if err := c.do("GET", c.jobURL(id, "deployment"), nil, &d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// JobSummary implements nomadClient on apiClient.
+func (c *apiClient) JobSummary(id string) (*jobSummary, error) {
+	s := &jobSummary{}
+	if err := c.do("GET", c.jobURL(id, "summary"), nil, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -14,6 +14,9 @@ import (
 	"github.com/satori/go.uuid"
 )
 
+// ClusterKind is the sous.Cluster.Kind of clusters managed by this package.
+const ClusterKind = sous.DefaultClusterKind
+
 // Both of these values are (for reasons only known to the spirits)
 // _configurable_ in singularity. If you've done something silly like configure
 // them differently than their defaults, at the moment we wish you the best of
@@ -62,6 +65,13 @@ type (
 
 		// DeleteRequest instructs Singularity to delete a particular request
 		DeleteRequest(cluster, reqID, message string) error
+
+		// UpdatePendingDeploy sets the number of instances an incremental
+		// deploy should bring up in its current step.
+		UpdatePendingDeploy(cluster, reqID, depID string, targetInstances int) error
+
+		// CancelDeploy instructs Singularity to cancel a pending deploy.
+		CancelDeploy(cluster, reqID, depID string) error
 	}
 
 	// DTOMap is shorthand for map[string]interface{}
@@ -312,7 +322,8 @@ func changesDep(pair *sous.DeployablePair) bool {
 			pair.Prior.Resources.Equal(pair.Post.Resources) &&
 			pair.Prior.Env.Equal(pair.Post.Env) &&
 			pair.Prior.DeployConfig.Volumes.Equal(pair.Post.DeployConfig.Volumes) &&
-			pair.Prior.Startup.Equal(pair.Post.Startup))
+			pair.Prior.Startup.Equal(pair.Post.Startup) &&
+			pair.Prior.Rollout.Equal(pair.Post.Rollout))
 }
 
 func computeRequestID(d *sous.Deployable) (string, error) {
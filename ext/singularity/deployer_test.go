@@ -283,7 +283,7 @@ func matchedPair(t *testing.T, startDep *sous.Deployment) *sous.DeployablePair {
 	req := &dtos.SingularityRequest{}
 	jsonRoundtrip(t, aReq, req)
 
-	aDepReq, err := buildDeployRequest(deployable, reqID, depID, map[string]string{}, ls)
+	aDepReq, err := NewRectiAgent(nil, nil, ls).buildDeployRequest(deployable, reqID, depID, map[string]string{})
 	assert.NoError(t, err)
 	assert.NotNil(t, aDepReq)
 
@@ -201,6 +201,11 @@ func (db *deploymentBuilder) determineDeployStatus() error {
 		db.Target.Status = sous.DeployStatusPending
 		db.depMarker = rds.PendingDeploy
 		db.deploy = rp.PendingDeploy
+		db.Target.ExecutorData = &singularityTaskData{
+			requestID: reqID(rp),
+			deployID:  rds.PendingDeploy.DeployId,
+		}
+		db.Target.RolloutProgress = rolloutProgress(rp.PendingDeployState)
 		/*
 			XXX(jdl) This doesn't work, because as of 0.19, S9y Request responses
 			don't include enough information to distinguish successfully deployed
@@ -216,6 +221,20 @@ func (db *deploymentBuilder) determineDeployStatus() error {
 	return nil
 }
 
+// rolloutProgress reports the progress of a pending incremental deploy.
+func rolloutProgress(pds *dtos.SingularityPendingDeploy) *sous.RolloutProgress {
+	if pds == nil || pds.DeployProgress == nil {
+		return nil
+	}
+	dp := pds.DeployProgress
+	return &sous.RolloutProgress{
+		TargetInstances: int(dp.TargetActiveInstances),
+		ActiveInstances: int(dp.CurrentActiveInstances),
+		FailedInstances: len(dp.FailedDeployTasks),
+		StepComplete:    dp.StepComplete,
+	}
+}
+
 func (db *deploymentBuilder) retrieveDeployHistory() error {
 	if db.depMarker == nil {
 		return db.retrieveHistoricDeploy()
@@ -395,7 +414,16 @@ func (db *deploymentBuilder) restoreFromMetadata() error {
 
 func (db *deploymentBuilder) unpackDeployConfig() error {
 	db.Target.Env = db.deploy.Env
-	messages.ReportLogFieldsMessage("UnpackDeployConfig", logging.ExtraDebug1Level, db.log, db.reqID, db.deploy.Env)
+	if s, has := db.deploy.Metadata[sous.SecretsLabel]; has {
+		// Put back the SecretRefs the secrets in Env were resolved from, so
+		// that the secrets themselves are neither logged nor compared.
+		refs := map[string]sous.SecretRef{}
+		if err := json.Unmarshal([]byte(s), &refs); err != nil {
+			return malformedResponse{fmt.Sprintf("Deploy Metadata included a malformed %s: %s", sous.SecretsLabel, err)}
+		}
+		db.Target.Env = sous.Env(db.deploy.Env).RestoreSecretRefs(refs)
+	}
+	messages.ReportLogFieldsMessage("UnpackDeployConfig", logging.ExtraDebug1Level, db.log, db.reqID, db.Target.Env)
 	if db.Target.Env == nil {
 		db.Target.Env = make(map[string]string)
 	}
@@ -447,6 +475,12 @@ func (db *deploymentBuilder) unpackDeployConfig() error {
 		db.Target.Startup.SkipCheck = true
 	}
 
+	if r, has := db.deploy.Metadata[sous.RolloutLabel]; has {
+		if err := json.Unmarshal([]byte(r), &db.Target.Rollout); err != nil {
+			return malformedResponse{fmt.Sprintf("Deploy Metadata included a malformed %s: %s", sous.RolloutLabel, err)}
+		}
+	}
+
 	return nil
 }
 
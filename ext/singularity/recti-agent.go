@@ -1,6 +1,7 @@
 package singularity
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"strings"
@@ -39,19 +40,25 @@ type (
 		singClients map[string]swaggering.Requester
 		sync.RWMutex
 		labeller sous.ImageLabeller
-		log      logging.LogSink
+		// secrets resolves the SecretRefs in deployments' Envs.
+		secrets sous.SecretStore
+		log     logging.LogSink
 	}
 
 	singularityTaskData struct {
 		requestID string
+		// deployID is the ID of the pending deploy, if there is one.
+		deployID string
 	}
 )
 
-// NewRectiAgent returns a set-up RectiAgent
-func NewRectiAgent(l sous.ImageLabeller, ls logging.LogSink) *RectiAgent {
+// NewRectiAgent returns a set-up RectiAgent. SecretRefs in deployments' Envs
+// are resolved from secrets, which may be nil if there aren't any.
+func NewRectiAgent(l sous.ImageLabeller, secrets sous.SecretStore, ls logging.LogSink) *RectiAgent {
 	return &RectiAgent{
 		singClients: make(map[string]swaggering.Requester),
 		labeller:    l,
+		secrets:     secrets,
 		log:         ls,
 	}
 }
@@ -79,12 +86,14 @@ func (ra *RectiAgent) Deploy(d sous.Deployable, reqID, depID string) error {
 	}
 	messages.ReportLogFieldsMessage("Build deploying instance", logging.DebugLevel, ra.log, d, reqID)
 
-	depReq, err := buildDeployRequest(d, reqID, depID, labels, ra.log)
+	depReq, err := ra.buildDeployRequest(d, reqID, depID, labels)
 	if err != nil {
 		return err
 	}
+	// depReq has the plaintext of any secrets, so only log it redacted.
+	loggedReq := redactDeployRequest(depReq, d.Deployment.Env.SecretRefs())
 
-	messages.ReportLogFieldsMessage("Sending Deploy req to singularity Client", logging.DebugLevel, ra.log, depReq)
+	messages.ReportLogFieldsMessage("Sending Deploy req to singularity Client", logging.DebugLevel, ra.log, loggedReq)
 
 	pathParamMap := map[string]interface{}{}
 
@@ -102,21 +111,37 @@ func (ra *RectiAgent) Deploy(d sous.Deployable, reqID, depID string) error {
 	err = ra.getSingularityRequester(clusterURI).DTORequest("singularity-deploy", response, "POST", "/api/deploys", pathParamMap, queryParamMap, depReq)
 
 	if err != nil {
-		messages.ReportLogFieldsMessage("Singularity client returned following error", logging.WarningLevel, ra.log, depReq, reqID, err, response)
+		messages.ReportLogFieldsMessage("Singularity client returned following error", logging.WarningLevel, ra.log, loggedReq, reqID, err, response)
 	}
 	return err
 }
 
-func buildDeployRequest(d sous.Deployable, reqID, depID string, metadata map[string]string, log logging.LogSink) (*dtos.SingularityDeployRequest, error) {
+// buildDeployRequest builds the request to deploy d. This is where the
+// SecretRefs in d's Env are resolved: the SecretRefs are recorded in the
+// deploy's metadata so that they can be restored when the deploy is read back.
+func (ra *RectiAgent) buildDeployRequest(d sous.Deployable, reqID, depID string, metadata map[string]string) (*dtos.SingularityDeployRequest, error) {
 	var depReq swaggering.Fielder
+	log := ra.log
 	dockerImage := d.BuildArtifact.DigestReference
 	r := d.Deployment.DeployConfig.Resources
-	e := d.Deployment.DeployConfig.Env
 	vols := d.Deployment.DeployConfig.Volumes
 
 	metadata[sous.ClusterNameLabel] = d.Deployment.ClusterName
 	metadata[sous.FlavorLabel] = d.Deployment.Flavor
 
+	refs := d.Deployment.DeployConfig.Env.SecretRefs()
+	e, err := d.Deployment.DeployConfig.Env.ResolveSecrets(ra.secrets)
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) > 0 {
+		b, err := json.Marshal(refs)
+		if err != nil {
+			return nil, err
+		}
+		metadata[sous.SecretsLabel] = string(b)
+	}
+
 	dockerInfo, err := swaggering.LoadMap(&dtos.SingularityDockerInfo{}, dtoMap{
 		"Image":   dockerImage,
 		"Network": dtos.SingularityDockerInfoSingularityDockerNetworkTypeBRIDGE, //defaulting to all bridge
@@ -174,11 +199,15 @@ func buildDeployRequest(d sous.Deployable, reqID, depID string, metadata map[str
 		return nil, err
 	}
 
+	if err := mapRolloutIntoDeploy(depMap, d.Deployment.DeployConfig.Rollout, d.Deployment.NumInstances); err != nil {
+		return nil, err
+	}
+
 	dep, err := swaggering.LoadMap(&dtos.SingularityDeploy{}, depMap)
 	if err != nil {
 		return nil, err
 	}
-	messages.ReportLogFieldsMessage("Deploy", logging.DebugLevel, log, dep, ci, dockerInfo)
+	messages.ReportLogFieldsMessage("Deploy", logging.DebugLevel, log, redactDeploy(dep.(*dtos.SingularityDeploy), refs), ci, dockerInfo)
 
 	depReq, err = swaggering.LoadMap(&dtos.SingularityDeployRequest{}, dtoMap{"Deploy": dep, "Message": message})
 
@@ -188,6 +217,28 @@ func buildDeployRequest(d sous.Deployable, reqID, depID string, metadata map[str
 	return depReq.(*dtos.SingularityDeployRequest), nil
 }
 
+// redactDeploy returns a copy of dep with the secrets in its Env replaced by
+// the SecretRefs in refs.
+func redactDeploy(dep *dtos.SingularityDeploy, refs map[string]sous.SecretRef) *dtos.SingularityDeploy {
+	if dep == nil || len(refs) == 0 {
+		return dep
+	}
+	redacted := *dep
+	redacted.Env = sous.Env(dep.Env).RestoreSecretRefs(refs)
+	return &redacted
+}
+
+// redactDeployRequest returns a copy of req with the secrets in its deploy's
+// Env replaced by the SecretRefs in refs.
+func redactDeployRequest(req *dtos.SingularityDeployRequest, refs map[string]sous.SecretRef) *dtos.SingularityDeployRequest {
+	if req == nil || len(refs) == 0 {
+		return req
+	}
+	redacted := *req
+	redacted.Deploy = redactDeploy(req.Deploy, refs)
+	return &redacted
+}
+
 // MapStartupIntoHealthcheckOptions updates the given dtoMap with fields for a
 // HealthcheckOptions struct if appropriate.
 // map[string]interface{} is used so that the function can be exported
@@ -283,6 +334,51 @@ func determineRequestType(kind sous.ManifestKind) (dtos.SingularityRequestReques
 	}
 }
 
+// mapRolloutIntoDeploy updates the given dtoMap with the fields for an
+// incremental deploy, if the Rollout calls for one. Steps are not advanced
+// automatically: Sous advances them with UpdatePendingDeploy, so that they
+// needn't be of equal size. The Rollout itself is recorded in the deploy
+// metadata so that it can be recovered.
+func mapRolloutIntoDeploy(depMap dtoMap, rollout sous.Rollout, numInstances int) error {
+	if !rollout.Enabled() {
+		return nil
+	}
+	steps := rollout.Steps(numInstances)
+	depMap["DeployInstanceCountPerStep"] = int32(steps[0])
+	depMap["DeployStepWaitTimeMs"] = int32(rollout.PauseSeconds * 1000)
+	depMap["AutoAdvanceDeploySteps"] = false
+
+	b, err := json.Marshal(rollout)
+	if err != nil {
+		return err
+	}
+	depMap["Metadata"].(map[string]string)[sous.RolloutLabel] = string(b)
+	return nil
+}
+
+// UpdatePendingDeploy sends a request to Singularity to set the target
+// number of instances of an incremental deploy.
+func (ra *RectiAgent) UpdatePendingDeploy(cluster, reqID, depID string, targetInstances int) error {
+	messages.ReportLogFieldsMessage("Updating pending deploy", logging.DebugLevel, ra.log, cluster, reqID, depID, targetInstances)
+	req, err := swaggering.LoadMap(&dtos.SingularityUpdatePendingDeployRequest{}, dtoMap{
+		"RequestId":             reqID,
+		"DeployId":              depID,
+		"TargetActiveInstances": int32(targetInstances),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = ra.singularityClient(cluster).UpdatePendingDeploy(req.(*dtos.SingularityUpdatePendingDeployRequest))
+	return err
+}
+
+// CancelDeploy sends a request to Singularity to cancel a pending deploy.
+func (ra *RectiAgent) CancelDeploy(cluster, reqID, depID string) error {
+	messages.ReportLogFieldsMessage("Canceling deploy", logging.DebugLevel, ra.log, cluster, reqID, depID)
+	_, err := ra.singularityClient(cluster).CancelDeploy(reqID, depID)
+	return err
+}
+
 // DeleteRequest sends a request to Singularity to delete a request
 func (ra *RectiAgent) DeleteRequest(cluster, reqID, message string) error {
 	messages.ReportLogFieldsMessage("Deleting application", logging.DebugLevel, ra.log, cluster, reqID, message)
@@ -99,7 +99,7 @@ func TestFailOnNilBuildArtifact(t *testing.T) {
 	r := sous.NewDummyRegistry()
 	d := sous.Deployable{}
 	ls, _ := logging.NewLogSinkSpy()
-	ra := NewRectiAgent(r, ls)
+	ra := NewRectiAgent(r, nil, ls)
 	err := ra.Deploy(d, "testReq", "testDep")
 	if err != nil {
 		t.Logf("Correctly returned an error upon encountering: %#v", err)
@@ -173,7 +173,7 @@ func TestContainerStartupOptions(t *testing.T) {
 
 	ls, _ := logging.NewLogSinkSpy()
 
-	dr, err := buildDeployRequest(d, "fake-request-id", "fake-deploy-id", map[string]string{}, ls)
+	dr, err := NewRectiAgent(nil, nil, ls).buildDeployRequest(d, "fake-request-id", "fake-deploy-id", map[string]string{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -214,7 +214,7 @@ func TestDeploy_MockedSingularity(t *testing.T) {
 	depID := "fake-deploy-id"
 
 	r := sous.NewDummyRegistry()
-	ra := NewRectiAgent(r, ls)
+	ra := NewRectiAgent(r, nil, ls)
 
 	dummyClient, ctrl := singularity.NewDummyClient(d.Deployment.Cluster.BaseURL)
 	ra.singClients[d.Deployment.Cluster.BaseURL] = dummyClient
@@ -255,7 +255,7 @@ func TestDeploy_User(t *testing.T) {
 	depID := "fake-deploy-id"
 
 	r := sous.NewDummyRegistry()
-	ra := NewRectiAgent(r, ls)
+	ra := NewRectiAgent(r, nil, ls)
 
 	myClient := new(MySingularityClient)
 
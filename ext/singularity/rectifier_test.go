@@ -4,6 +4,8 @@ import (
 	"log"
 	"testing"
 
+	"github.com/opentable/go-singularity/dtos"
+	"github.com/opentable/sous/ext/internal/deployertest"
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/logging"
 	"github.com/samsalisbury/semv"
@@ -20,7 +22,7 @@ func TestBuildDeployRequest(t *testing.T) {
 	rID := "expectedRID"
 	dID := "expectedDID"
 	ls, _ := logging.NewLogSinkSpy()
-	dr, err := buildDeployRequest(sous.Deployable{
+	dr, err := NewRectiAgent(nil, nil, ls).buildDeployRequest(sous.Deployable{
 		BuildArtifact: &sous.BuildArtifact{
 			DigestReference: "an-image",
 			Type:            "docker",
@@ -40,7 +42,7 @@ func TestBuildDeployRequest(t *testing.T) {
 				BaseURL: "http://cluster",
 			},
 		},
-	}, rID, dID, map[string]string{}, ls)
+	}, rID, dID, map[string]string{})
 	require.NoError(err)
 	assert.NotNil(dr)
 	assert.Equal(dr.Deploy.RequestId, rID)
@@ -58,7 +60,7 @@ func TestDockerMetadataSet(t *testing.T) {
 	rID := "expectedRID"
 	dID := "expectedDID"
 	ls, _ := logging.NewLogSinkSpy()
-	dr, err := buildDeployRequest(sous.Deployable{
+	dr, err := NewRectiAgent(nil, nil, ls).buildDeployRequest(sous.Deployable{
 		BuildArtifact: &sous.BuildArtifact{
 			DigestReference: "an-image",
 			Type:            "docker",
@@ -78,7 +80,7 @@ func TestDockerMetadataSet(t *testing.T) {
 				BaseURL: "http://cluster",
 			},
 		},
-	}, rID, dID, md, ls)
+	}, rID, dID, md)
 
 	if err != nil {
 		t.Fatal(err)
@@ -90,6 +92,35 @@ func TestDockerMetadataSet(t *testing.T) {
 	}
 }
 
+func TestBuildDeployRequest_Rollout(t *testing.T) {
+	ls, _ := logging.NewLogSinkSpy()
+	dr, err := NewRectiAgent(nil, nil, ls).buildDeployRequest(sous.Deployable{
+		BuildArtifact: &sous.BuildArtifact{
+			DigestReference: "an-image",
+			Type:            "docker",
+		},
+		Deployment: &sous.Deployment{
+			DeployConfig: sous.DeployConfig{
+				NumInstances: 10,
+				Rollout: sous.Rollout{
+					CanaryInstances: 2,
+					StepPercentages: []int{50},
+					PauseSeconds:    30,
+				},
+			},
+			ClusterName: "cluster",
+			Cluster: &sous.Cluster{
+				BaseURL: "http://cluster",
+			},
+		},
+	}, "rid", "did", map[string]string{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, dr.Deploy.DeployInstanceCountPerStep)
+	assert.EqualValues(t, 30000, dr.Deploy.DeployStepWaitTimeMs)
+	assert.False(t, dr.Deploy.AutoAdvanceDeploySteps)
+	assert.Contains(t, dr.Deploy.Metadata, sous.RolloutLabel)
+}
+
 func baseDeployablePair() *sous.DeployablePair {
 	return &sous.DeployablePair{
 		ExecutorData: &singularityTaskData{requestID: "reqid"},
@@ -425,3 +456,64 @@ func TestCreates(t *testing.T) {
 		assert.Equal(12, req.Deployment.DeployConfig.NumInstances)
 	}
 }
+
+func TestAdvanceRollout(t *testing.T) {
+	pair := baseDeployablePair()
+	client := sous.NewDummyRectificationClient()
+	deployer := NewDeployer(client, logging.SilentLogSet()).(sous.RolloutDeployer)
+
+	state := &sous.DeployState{ExecutorData: &singularityTaskData{requestID: "reqid", deployID: "depid"}}
+	require.NoError(t, deployer.AdvanceRollout(pair, state, 5))
+	if assert.Len(t, client.Advanced, 1) {
+		assert.Equal(t, "depid", client.Advanced[0].Depid)
+		assert.Equal(t, 5, client.Advanced[0].Target)
+	}
+
+	require.NoError(t, deployer.AbortRollout(pair, state))
+	assert.Len(t, client.Canceled, 1)
+
+	state.ExecutorData = &singularityTaskData{requestID: "reqid"}
+	assert.Error(t, deployer.AdvanceRollout(pair, state, 5))
+}
+
+func TestBuildDeployRequest_Secrets(t *testing.T) {
+	ls, _ := logging.NewLogSinkSpy()
+	d := sous.Deployable{
+		BuildArtifact: &sous.BuildArtifact{DigestReference: "an-image", Type: "docker"},
+		Deployment: &sous.Deployment{
+			SourceID: sous.SourceID{Location: sous.SourceLocation{Repo: "repo"}},
+			DeployConfig: sous.DeployConfig{
+				NumInstances: 1,
+				Resources:    sous.Resources{},
+				Env: sous.Env{
+					"DB_PASSWORD": "${secret:db/password}",
+					"DB_USER":     "app",
+				},
+			},
+			ClusterName: "cluster",
+			Cluster:     &sous.Cluster{BaseURL: "http://cluster"},
+		},
+	}
+
+	if _, err := NewRectiAgent(nil, nil, ls).buildDeployRequest(d, "rid", "did", map[string]string{}); err == nil {
+		t.Errorf("resolved a secret without a secret store")
+	}
+
+	ra := NewRectiAgent(nil, deployertest.MapSecretStore{"db/password": "hunter2"}, ls)
+	dr, err := ra.buildDeployRequest(d, "rid", "did", map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "hunter2", dr.Deploy.Env["DB_PASSWORD"])
+	assert.Equal(t, "app", dr.Deploy.Env["DB_USER"])
+	assert.Equal(t, `{"DB_PASSWORD":"db/password"}`, dr.Deploy.Metadata[sous.SecretsLabel])
+	assert.Equal(t, "${secret:db/password}", d.Deployment.Env["DB_PASSWORD"], "resolving changed the deployment")
+
+	db := &deploymentBuilder{
+		deploy:  dr.Deploy,
+		request: &dtos.SingularityRequest{Instances: 1},
+		log:     ls,
+	}
+	require.NoError(t, db.unpackDeployConfig())
+	assert.Equal(t, "${secret:db/password}", db.Target.Env["DB_PASSWORD"], "secret read back from Singularity")
+}
@@ -0,0 +1,39 @@
+package singularity
+
+import (
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+)
+
+// pendingDeploy extracts the Singularity IDs of the deploy underway in state.
+func pendingDeploy(pair *sous.DeployablePair, state *sous.DeployState) (cluster, reqID, depID string, err error) {
+	data, ok := state.ExecutorData.(*singularityTaskData)
+	if !ok {
+		return "", "", "", errors.Errorf("Deploy state for %#v doesn't contain Singularity compatible data: was %T", pair.ID(), state.ExecutorData)
+	}
+	if data.deployID == "" {
+		return "", "", "", errors.Errorf("No pending deploy for %#v on request %q", pair.ID(), data.requestID)
+	}
+	return pair.Post.Cluster.BaseURL, data.requestID, data.deployID, nil
+}
+
+// AdvanceRollout implements sous.RolloutDeployer on deployer.
+func (r *deployer) AdvanceRollout(pair *sous.DeployablePair, state *sous.DeployState, targetInstances int) error {
+	cluster, reqID, depID, err := pendingDeploy(pair, state)
+	if err != nil {
+		return err
+	}
+	reportDeployerMessage("Advancing rollout", pair, nil, state.ExecutorData.(*singularityTaskData), nil, logging.InformationLevel, r.log)
+	return r.Client.UpdatePendingDeploy(cluster, reqID, depID, targetInstances)
+}
+
+// AbortRollout implements sous.RolloutDeployer on deployer.
+func (r *deployer) AbortRollout(pair *sous.DeployablePair, state *sous.DeployState) error {
+	cluster, reqID, depID, err := pendingDeploy(pair, state)
+	if err != nil {
+		return err
+	}
+	reportDeployerMessage("Aborting rollout", pair, nil, state.ExecutorData.(*singularityTaskData), nil, logging.WarningLevel, r.log)
+	return r.Client.CancelDeploy(cluster, reqID, depID)
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+)
+
+// SecretsFileName is the name of the FileSecretStore's file in the state
+// directory.
+const SecretsFileName = "secrets.json"
+
+// FileSecretStore is a sous.SecretStore kept in a JSON file, usually in the
+// state repo. The file maps the name of each secret to its value, encrypted
+// with AES-GCM, so only the names are readable without the key.
+type FileSecretStore struct {
+	path string
+	aead cipher.AEAD
+	sync.Mutex
+}
+
+// NewFileSecretStore returns a FileSecretStore keeping secrets in the file at
+// path, encrypted with key, which must be 32 bytes long.
+func NewFileSecretStore(path string, key []byte) (*FileSecretStore, error) {
+	if len(key) != 32 {
+		return nil, errors.Errorf("secrets key must be 32 bytes long, not %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSecretStore{path: path, aead: aead}, nil
+}
+
+// Secret implements sous.SecretStore on FileSecretStore.
+func (fs *FileSecretStore) Secret(name string) (string, error) {
+	fs.Lock()
+	defer fs.Unlock()
+	secrets, err := fs.read()
+	if err != nil {
+		return "", err
+	}
+	sealed, has := secrets[name]
+	if !has {
+		return "", &sous.NoSuchSecretError{Name: name}
+	}
+	b, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(b) < fs.aead.NonceSize() {
+		return "", errors.Errorf("secret %s is malformed", name)
+	}
+	nonce, ciphertext := b[:fs.aead.NonceSize()], b[fs.aead.NonceSize():]
+	// The name is authenticated along with the value, so that values can't
+	// be swapped between names.
+	value, err := fs.aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return "", errors.Wrapf(err, "decrypting secret %s", name)
+	}
+	return string(value), nil
+}
+
+// SetSecret encrypts value and stores it as the secret called name.
+func (fs *FileSecretStore) SetSecret(name, value string) error {
+	fs.Lock()
+	defer fs.Unlock()
+	secrets, err := fs.read()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, fs.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	sealed := fs.aead.Seal(nonce, nonce, []byte(value), []byte(name))
+	secrets[name] = base64.StdEncoding.EncodeToString(sealed)
+	return fs.write(secrets)
+}
+
+func (fs *FileSecretStore) read() (map[string]string, error) {
+	secrets := map[string]string{}
+	b, err := ioutil.ReadFile(fs.path)
+	if os.IsNotExist(err) {
+		return secrets, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading secrets")
+	}
+	if err := json.Unmarshal(b, &secrets); err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", fs.path)
+	}
+	return secrets, nil
+}
+
+func (fs *FileSecretStore) write(secrets map[string]string) error {
+	b, err := json.MarshalIndent(secrets, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(fs.path), ".secrets")
+	if err != nil {
+		return errors.Wrapf(err, "writing secrets")
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "writing secrets")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrapf(err, "writing secrets")
+	}
+	return errors.Wrapf(os.Rename(tmp.Name(), fs.path), "writing secrets")
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/sqlgen"
+	"github.com/pkg/errors"
+)
+
+// PostgresHistory is a sous.HistoryStore that keeps deployment history in
+// the deployment_history table.
+type PostgresHistory struct {
+	db  *sql.DB
+	log logging.LogSink
+}
+
+// NewPostgresHistory creates a new PostgresHistory.
+func NewPostgresHistory(db *sql.DB, log logging.LogSink) *PostgresHistory {
+	return &PostgresHistory{db: db, log: log}
+}
+
+// RecordHistory implements sous.HistoryStore on PostgresHistory.
+func (h *PostgresHistory) RecordHistory(events []sous.HistoryEvent) error {
+	ctx := context.TODO()
+	tx, err := h.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrapf(err, "opening transaction")
+	}
+	defer func(tx *sql.Tx) {
+		// ignoring error - since if the Tx is committed, we would expect an error on rollback
+		tx.Rollback()
+	}(tx)
+
+	const insert = `insert into deployment_history
+		("recorded_at", "user_name", "user_email", "repo", "dir", "flavor", "cluster_name",
+		 "change", "before_deployment", "after_deployment", "diffs")
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);`
+
+	for _, e := range events {
+		start := time.Now()
+		before, err := marshalDeploymentJSON(e.Before)
+		if err != nil {
+			return err
+		}
+		after, err := marshalDeploymentJSON(e.After)
+		if err != nil {
+			return err
+		}
+		mid := e.DeploymentID.ManifestID
+		res, err := tx.ExecContext(ctx, insert,
+			e.When, e.User.Name, e.User.Email, mid.Source.Repo, mid.Source.Dir, mid.Flavor, e.DeploymentID.Cluster,
+			string(e.Change), before, after, pq.Array([]string(e.Diffs)),
+		)
+		rows := 0
+		if res != nil {
+			n, _ := res.RowsAffected()
+			rows = int(n)
+		}
+		sqlgen.ReportInsert(h.log, start, "deployment_history", insert, rows, err)
+		if err != nil {
+			return errors.Wrapf(err, "recording history for %s", e.DeploymentID)
+		}
+	}
+
+	return errors.Wrapf(tx.Commit(), "committing transaction")
+}
+
+// ReadHistory implements sous.HistoryStore on PostgresHistory.
+func (h *PostgresHistory) ReadHistory(filter *sous.ResolveFilter, page sous.HistoryPage) ([]sous.HistoryEvent, error) {
+	ctx := context.TODO()
+	tx, err := h.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening transaction")
+	}
+	defer func(tx *sql.Tx) {
+		tx.Rollback()
+	}(tx)
+
+	where, args := historyWhere(filter)
+	// The newest rows are selected, so that the page is of the most recent
+	// events; they're put back in order below.
+	var limit interface{} // null means no limit
+	if page.Limit > 0 {
+		limit = page.Limit
+	}
+	args = append(args, limit, page.Skip)
+
+	events := []sous.HistoryEvent{}
+	err = loadTable(ctx, h.log, tx, "deployment_history",
+		`select
+			"recorded_at", "user_name", "user_email", "repo", "dir", "flavor", "cluster_name",
+			"change", "before_deployment", "after_deployment", "diffs"
+		from deployment_history
+		`+where+`
+		order by history_id desc
+		`+fmt.Sprintf("limit $%d offset $%d;", len(args)-1, len(args)),
+		func(rows *sql.Rows) error {
+			e := sous.HistoryEvent{}
+			var change string
+			var before, after []byte
+			diffs := pq.StringArray{}
+			mid := &e.DeploymentID.ManifestID
+			if err := rows.Scan(
+				&e.When, &e.User.Name, &e.User.Email, &mid.Source.Repo, &mid.Source.Dir, &mid.Flavor, &e.DeploymentID.Cluster,
+				&change, &before, &after, &diffs,
+			); err != nil {
+				return errors.Wrapf(err, "ReadHistory")
+			}
+			e.Change = sous.HistoryChange(change)
+			if len(diffs) > 0 {
+				e.Diffs = sous.Differences(diffs)
+			}
+			var err error
+			if e.Before, err = unmarshalDeploymentJSON(before); err != nil {
+				return err
+			}
+			if e.After, err = unmarshalDeploymentJSON(after); err != nil {
+				return err
+			}
+			events = append(events, e)
+			return nil
+		}, args...)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	return events, nil
+}
+
+// historyWhere returns the where clause selecting the rows of
+// deployment_history filter matches, and its arguments.
+func historyWhere(filter *sous.ResolveFilter) (string, []interface{}) {
+	if filter == nil {
+		return "", nil
+	}
+	conds := []string{}
+	args := []interface{}{}
+	for _, f := range []struct {
+		column string
+		match  sous.ResolveFieldMatcher
+	}{
+		{"repo", filter.Repo},
+		{"dir", filter.Offset},
+		{"flavor", filter.Flavor},
+	} {
+		if !f.match.All() {
+			args = append(args, *f.match.Match)
+			conds = append(conds, fmt.Sprintf("%q = $%d", f.column, len(args)))
+		}
+	}
+	if names, all := filter.MatchedClusterNames(); !all {
+		args = append(args, pq.Array(names))
+		conds = append(conds, fmt.Sprintf(`"cluster_name" = any($%d)`, len(args)))
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return "where " + strings.Join(conds, " and "), args
+}
+
+func marshalDeploymentJSON(d *sous.Deployment) (interface{}, error) {
+	if d == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, errors.Wrapf(err, "marshalling %s", d.ID())
+	}
+	return string(b), nil
+}
+
+func unmarshalDeploymentJSON(b []byte) (*sous.Deployment, error) {
+	if b == nil {
+		return nil, nil
+	}
+	d := &sous.Deployment{}
+	if err := json.Unmarshal(b, d); err != nil {
+		return nil, errors.Wrapf(err, "unmarshalling deployment")
+	}
+	return d, nil
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"testing"
+
+	sous "github.com/opentable/sous/lib"
+)
+
+func TestHistoryWhere(t *testing.T) {
+	tc := func(filter *sous.ResolveFilter, where string, nargs int) {
+		t.Helper()
+		actual, args := historyWhere(filter)
+		if actual != where {
+			t.Errorf("Expected %+v => \n %q, got\n %q", filter, where, actual)
+		}
+		if len(args) != nargs {
+			t.Errorf("Expected %d args for %+v, got %d", nargs, filter, len(args))
+		}
+	}
+
+	tc(nil, "", 0)
+	tc(&sous.ResolveFilter{}, "", 0)
+	tc(&sous.ResolveFilter{Repo: sous.NewResolveFieldMatcher("github.com/user/repo"), Offset: sous.NewResolveFieldMatcher("")},
+		`where "repo" = $1 and "dir" = $2`, 2)
+	tc(&sous.ResolveFilter{Flavor: sous.NewResolveFieldMatcher("vanilla"), Cluster: sous.NewResolveFieldMatcher("cluster1")},
+		`where "flavor" = $1 and "cluster_name" = any($2)`, 2)
+
+	clusters := sous.Clusters{
+		"cluster1": &sous.Cluster{Name: "cluster1", Labels: map[string]string{"env": "prod"}},
+		"cluster2": &sous.Cluster{Name: "cluster2"},
+	}
+	selected := (&sous.ResolveFilter{Cluster: sous.NewResolveFieldMatcher("env=prod")}).SelectClusters(clusters)
+	tc(selected, `where "cluster_name" = any($1)`, 1)
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/sqlgen"
+	"github.com/pkg/errors"
+)
+
+// PostgresR11nStore is a sous.R11nStore that keeps queued rectifications in
+// the r11n_queue table.
+type PostgresR11nStore struct {
+	db  *sql.DB
+	log logging.LogSink
+}
+
+// NewPostgresR11nStore creates a new PostgresR11nStore.
+func NewPostgresR11nStore(db *sql.DB, log logging.LogSink) *PostgresR11nStore {
+	return &PostgresR11nStore{db: db, log: log}
+}
+
+// StoreQueued implements sous.R11nStore on PostgresR11nStore.
+func (s *PostgresR11nStore) StoreQueued(qr *sous.QueuedR11n, at time.Time) error {
+	qr.Rectification.RLock()
+	pair := qr.Rectification.Pair
+	qr.Rectification.RUnlock()
+
+	var priorDep, postDep *sous.Deployment
+	if pair.Prior != nil {
+		priorDep = pair.Prior.Deployment
+	}
+	if pair.Post != nil {
+		postDep = pair.Post.Deployment
+	}
+	prior, err := marshalDeploymentJSON(priorDep)
+	if err != nil {
+		return err
+	}
+	post, err := marshalDeploymentJSON(postDep)
+	if err != nil {
+		return err
+	}
+
+	const insert = `insert into r11n_queue
+		("r11n_id", "repo", "dir", "flavor", "cluster_name",
+		 "prior_deployment", "post_deployment", "queued_at")
+		values ($1, $2, $3, $4, $5, $6, $7, $8)
+		on conflict ("r11n_id") do nothing;`
+
+	did := pair.ID()
+	mid := did.ManifestID
+	start := time.Now()
+	res, err := s.db.ExecContext(context.TODO(), insert,
+		string(qr.ID), mid.Source.Repo, mid.Source.Dir, mid.Flavor, did.Cluster,
+		prior, post, at,
+	)
+	sqlgen.ReportInsert(s.log, start, "r11n_queue", insert, rowsAffected(res), err)
+	return errors.Wrapf(err, "storing queued rectification %s", qr.ID)
+}
+
+// StoreResolved implements sous.R11nStore on PostgresR11nStore.
+func (s *PostgresR11nStore) StoreResolved(qr *sous.QueuedR11n, at time.Time) error {
+	qr.Rectification.RLock()
+	rez := qr.Rectification.Resolution
+	qr.Rectification.RUnlock()
+
+	b, err := json.Marshal(rez)
+	if err != nil {
+		return errors.Wrapf(err, "marshalling resolution of %s", qr.ID)
+	}
+
+	const update = `update r11n_queue
+		set "finished_at" = $2, "resolution" = $3
+		where "r11n_id" = $1;`
+
+	start := time.Now()
+	res, err := s.db.ExecContext(context.TODO(), update, string(qr.ID), at, string(b))
+	sqlgen.ReportUpdate(s.log, start, "r11n_queue", update, rowsAffected(res), err)
+	return errors.Wrapf(err, "storing resolution of %s", qr.ID)
+}
+
+// LoadR11ns implements sous.R11nStore on PostgresR11nStore.
+func (s *PostgresR11nStore) LoadR11ns(since time.Time) ([]sous.StoredR11n, error) {
+	ctx := context.TODO()
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening transaction")
+	}
+	defer func(tx *sql.Tx) {
+		tx.Rollback()
+	}(tx)
+
+	stored := []sous.StoredR11n{}
+	err = loadTable(ctx, s.log, tx, "r11n_queue",
+		`select
+			"r11n_id", "repo", "dir", "flavor", "cluster_name",
+			"prior_deployment", "post_deployment", "queued_at", "finished_at", "resolution"
+		from r11n_queue
+		order by "queued_at";`,
+		func(rows *sql.Rows) error {
+			sr := sous.StoredR11n{}
+			var id string
+			var prior, post, rez []byte
+			var finished pq.NullTime
+			mid := &sr.DeploymentID.ManifestID
+			if err := rows.Scan(
+				&id, &mid.Source.Repo, &mid.Source.Dir, &mid.Flavor, &sr.DeploymentID.Cluster,
+				&prior, &post, &sr.Queued, &finished, &rez,
+			); err != nil {
+				return errors.Wrapf(err, "LoadR11ns")
+			}
+			if finished.Valid {
+				if finished.Time.Before(since) {
+					return nil
+				}
+				t := finished.Time
+				sr.Finished = &t
+			}
+			sr.ID = sous.R11nID(id)
+			var err error
+			if sr.Prior, err = unmarshalDeploymentJSON(prior); err != nil {
+				return err
+			}
+			if sr.Post, err = unmarshalDeploymentJSON(post); err != nil {
+				return err
+			}
+			if rez != nil {
+				sr.Resolution = &sous.DiffResolution{}
+				if err := json.Unmarshal(rez, sr.Resolution); err != nil {
+					return errors.Wrapf(err, "unmarshalling resolution of %s", id)
+				}
+			}
+			stored = append(stored, sr)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// PurgeR11ns implements sous.R11nStore on PostgresR11nStore.
+func (s *PostgresR11nStore) PurgeR11ns(before time.Time) error {
+	const purge = `delete from r11n_queue where "finished_at" < $1;`
+	start := time.Now()
+	res, err := s.db.ExecContext(context.TODO(), purge, before)
+	sqlgen.ReportUpdate(s.log, start, "r11n_queue", purge, rowsAffected(res), err)
+	return errors.Wrapf(err, "purging rectifications finished before %s", before)
+}
+
+func rowsAffected(res sql.Result) int {
+	if res == nil {
+		return 0
+	}
+	n, _ := res.RowsAffected()
+	return int(n)
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/sqlgen"
+	"github.com/pkg/errors"
+)
+
+// PostgresSchedule is a sous.ScheduleStore that keeps scheduled deploys in
+// the scheduled_deploys table.
+type PostgresSchedule struct {
+	db  *sql.DB
+	log logging.LogSink
+}
+
+// NewPostgresSchedule creates a new PostgresSchedule.
+func NewPostgresSchedule(db *sql.DB, log logging.LogSink) *PostgresSchedule {
+	return &PostgresSchedule{db: db, log: log}
+}
+
+// AddScheduledDeploy implements sous.ScheduleStore on PostgresSchedule.
+func (s *PostgresSchedule) AddScheduledDeploy(sd sous.ScheduledDeploy) error {
+	spec, err := json.Marshal(sd.Deployment)
+	if err != nil {
+		return errors.Wrapf(err, "marshalling scheduled deploy of %s", sd.DeploymentID)
+	}
+	prior, err := json.Marshal(sd.Prior)
+	if err != nil {
+		return errors.Wrapf(err, "marshalling scheduled deploy of %s", sd.DeploymentID)
+	}
+
+	const insert = `insert into scheduled_deploys
+		("scheduled_id", "repo", "dir", "flavor", "cluster_name",
+		 "deploy_spec", "prior_spec", "force", "deploy_at", "user_name", "user_email", "requested_at", "status")
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13);`
+
+	mid := sd.DeploymentID.ManifestID
+	start := time.Now()
+	res, err := s.db.ExecContext(context.TODO(), insert,
+		string(sd.ID), mid.Source.Repo, mid.Source.Dir, mid.Flavor, sd.DeploymentID.Cluster,
+		string(spec), string(prior), sd.Force, sd.At, sd.User.Name, sd.User.Email, sd.Requested, string(sd.Status),
+	)
+	sqlgen.ReportInsert(s.log, start, "scheduled_deploys", insert, rowsAffected(res), err)
+	return errors.Wrapf(err, "storing scheduled deploy of %s", sd.DeploymentID)
+}
+
+// ScheduledDeploys implements sous.ScheduleStore on PostgresSchedule.
+func (s *PostgresSchedule) ScheduledDeploys(filter *sous.ResolveFilter) ([]sous.ScheduledDeploy, error) {
+	ctx := context.TODO()
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening transaction")
+	}
+	defer func(tx *sql.Tx) {
+		tx.Rollback()
+	}(tx)
+
+	deploys := []sous.ScheduledDeploy{}
+	err = loadTable(ctx, s.log, tx, "scheduled_deploys",
+		`select
+			"scheduled_id", "repo", "dir", "flavor", "cluster_name",
+			"deploy_spec", "prior_spec", "force", "deploy_at", "user_name", "user_email", "requested_at",
+			"status", "error", "finished_at"
+		from scheduled_deploys
+		order by "deploy_at";`,
+		func(rows *sql.Rows) error {
+			sd := sous.ScheduledDeploy{}
+			var id, status string
+			var spec, prior []byte
+			var errMsg sql.NullString
+			var finished pq.NullTime
+			mid := &sd.DeploymentID.ManifestID
+			if err := rows.Scan(
+				&id, &mid.Source.Repo, &mid.Source.Dir, &mid.Flavor, &sd.DeploymentID.Cluster,
+				&spec, &prior, &sd.Force, &sd.At, &sd.User.Name, &sd.User.Email, &sd.Requested,
+				&status, &errMsg, &finished,
+			); err != nil {
+				return errors.Wrapf(err, "ScheduledDeploys")
+			}
+			if !sd.Matches(filter) {
+				return nil
+			}
+			sd.ID = sous.ScheduledDeployID(id)
+			sd.Status = sous.ScheduledDeployStatus(status)
+			sd.Error = errMsg.String
+			if finished.Valid {
+				t := finished.Time
+				sd.Finished = &t
+			}
+			if err := json.Unmarshal(spec, &sd.Deployment); err != nil {
+				return errors.Wrapf(err, "unmarshalling scheduled deploy %s", id)
+			}
+			if len(prior) > 0 {
+				if err := json.Unmarshal(prior, &sd.Prior); err != nil {
+					return errors.Wrapf(err, "unmarshalling scheduled deploy %s", id)
+				}
+			}
+			deploys = append(deploys, sd)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return deploys, nil
+}
+
+// SetScheduledDeployStatus implements sous.ScheduleStore on PostgresSchedule.
+func (s *PostgresSchedule) SetScheduledDeployStatus(id sous.ScheduledDeployID, from, to sous.ScheduledDeployStatus, errMsg string, at time.Time) (bool, error) {
+	const update = `update scheduled_deploys
+		set "status" = $3, "error" = $4, "finished_at" = $5
+		where "scheduled_id" = $1 and "status" = $2;`
+
+	start := time.Now()
+	res, err := s.db.ExecContext(context.TODO(), update, string(id), string(from), string(to), errMsg, at)
+	rows := rowsAffected(res)
+	sqlgen.ReportUpdate(s.log, start, "scheduled_deploys", update, rows, err)
+	if err != nil {
+		return false, errors.Wrapf(err, "updating scheduled deploy %s", id)
+	}
+	return rows == 1, nil
+}
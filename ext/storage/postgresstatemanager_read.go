@@ -291,26 +291,26 @@ func loadManifests(context context.Context, log logging.LogSink, tx *sql.Tx, sta
 		})
 }
 
-func loadTable(ctx context.Context, log logging.LogSink, tx *sql.Tx, mainTable string, sql string, pack func(*sql.Rows) error) error {
+func loadTable(ctx context.Context, log logging.LogSink, tx *sql.Tx, mainTable string, sql string, pack func(*sql.Rows) error, args ...interface{}) error {
 	rowcount := 0
 	start := time.Now()
-	rows, err := tx.QueryContext(ctx, sql)
+	rows, err := tx.QueryContext(ctx, sql, args...)
 	if err != nil {
-		sqlgen.ReportSelect(log, start, mainTable, sql, rowcount, err)
+		sqlgen.ReportSelect(log, start, mainTable, sql, rowcount, err, args...)
 		return errors.Wrapf(err, "loadTable %q", sql)
 	}
 	defer rows.Close()
 	for rows.Next() {
 		rowcount++
 		if err := pack(rows); err != nil {
-			sqlgen.ReportSelect(log, start, mainTable, sql, rowcount, err)
+			sqlgen.ReportSelect(log, start, mainTable, sql, rowcount, err, args...)
 			return errors.Wrapf(err, "sql %q", sql)
 		}
 	}
 	if err := rows.Err(); err != nil {
-		sqlgen.ReportSelect(log, start, mainTable, sql, rowcount, err)
+		sqlgen.ReportSelect(log, start, mainTable, sql, rowcount, err, args...)
 		return errors.Wrapf(err, "loadTable query error %q", sql)
 	}
-	sqlgen.ReportSelect(log, start, mainTable, sql, rowcount, nil)
+	sqlgen.ReportSelect(log, start, mainTable, sql, rowcount, nil, args...)
 	return nil
 }
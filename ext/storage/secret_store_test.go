@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opentable/sous/lib"
+)
+
+func TestFileSecretStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sous-secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, SecretsFileName)
+	key := bytes.Repeat([]byte{7}, 32)
+
+	fs, err := NewFileSecretStore(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Secret("db/password"); err == nil {
+		t.Errorf("got a secret from an empty store")
+	}
+	if err := fs.SetSecret("db/password", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(b, []byte("hunter2")) {
+		t.Errorf("secret stored in plaintext:\n%s", b)
+	}
+
+	reopened, err := NewFileSecretStore(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := reopened.Secret("db/password"); err != nil || v != "hunter2" {
+		t.Errorf("got %q, %v; want hunter2", v, err)
+	}
+
+	wrongKey, err := NewFileSecretStore(path, bytes.Repeat([]byte{8}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wrongKey.Secret("db/password"); err == nil {
+		t.Errorf("decrypted a secret with the wrong key")
+	}
+
+	if _, err := NewFileSecretStore(path, []byte("short")); err == nil {
+		t.Errorf("accepted a short key")
+	}
+}
+
+func TestVaultSecretStore(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Vault-Token") != "s.token" {
+			rw.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch req.URL.Path {
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		case "/v1/kv/data/myapp/db":
+			fmt.Fprint(rw, `{"data":{"data":{"password":"hunter2","value":"default"},"metadata":{"version":3}}}`)
+		}
+	}))
+	defer vault.Close()
+
+	vs := NewVaultSecretStore(vault.URL, "s.token", "kv")
+	for name, want := range map[string]string{
+		"myapp/db#password": "hunter2",
+		"myapp/db":          "default",
+	} {
+		if v, err := vs.Secret(name); err != nil || v != want {
+			t.Errorf("Secret(%q): got %q, %v; want %q", name, v, err, want)
+		}
+	}
+	for _, name := range []string{"myapp/db#user", "otherapp/db"} {
+		if _, err := vs.Secret(name); err == nil {
+			t.Errorf("Secret(%q): got no error", name)
+		} else if _, is := err.(*sous.NoSuchSecretError); !is {
+			t.Errorf("Secret(%q): got %v; want a NoSuchSecretError", name, err)
+		}
+	}
+
+	if _, err := NewVaultSecretStore(vault.URL, "wrong", "kv").Secret("myapp/db"); err == nil {
+		t.Errorf("read a secret with the wrong token")
+	}
+}
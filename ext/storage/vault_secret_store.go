@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+)
+
+// VaultSecretStore is a sous.SecretStore backed by the KV version 2 secrets
+// engine of a Vault server, or anything with a compatible HTTP API.
+//
+// Secrets are named path#key, e.g. "myapp/db#password" is the "password" key
+// of the secret at myapp/db. If #key is omitted, the key is "value".
+type VaultSecretStore struct {
+	addr, token, mount string
+	client             *http.Client
+}
+
+// NewVaultSecretStore returns a VaultSecretStore for the server at addr,
+// authenticating with token, and reading secrets from the KV engine mounted
+// at mount ("secret" if empty).
+func NewVaultSecretStore(addr, token, mount string) *VaultSecretStore {
+	if mount == "" {
+		mount = "secret"
+	}
+	return &VaultSecretStore{
+		addr:   strings.TrimSuffix(addr, "/"),
+		token:  token,
+		mount:  strings.Trim(mount, "/"),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Secret implements sous.SecretStore on VaultSecretStore.
+func (vs *VaultSecretStore) Secret(name string) (string, error) {
+	path, key := name, "value"
+	if i := strings.LastIndex(name, "#"); i >= 0 {
+		path, key = name[:i], name[i+1:]
+	}
+	url := fmt.Sprintf("%s/v1/%s/data/%s", vs.addr, vs.mount, strings.TrimPrefix(path, "/"))
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", vs.token)
+
+	rz, err := vs.client.Do(req)
+	if err != nil {
+		return "", errors.Wrapf(err, "reading secret %s from vault", name)
+	}
+	defer rz.Body.Close()
+	if rz.StatusCode == http.StatusNotFound {
+		return "", &sous.NoSuchSecretError{Name: name}
+	}
+	if rz.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(rz.Body)
+		return "", errors.Errorf("reading secret %s from vault: %s: %s", name, rz.Status, b)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rz.Body).Decode(&body); err != nil {
+		return "", errors.Wrapf(err, "parsing secret %s from vault", name)
+	}
+	value, has := body.Data.Data[key]
+	if !has {
+		return "", &sous.NoSuchSecretError{Name: name}
+	}
+	s, is := value.(string)
+	if !is {
+		return "", errors.Errorf("secret %s is a %T, not a string", name, value)
+	}
+	return s, nil
+}
@@ -4,9 +4,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/opentable/sous/cli/actions"
 	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/ext/docker"
 	sous "github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/logging/messages"
@@ -181,10 +185,20 @@ type DeployActionOpts struct {
 	DFF                              config.DeployFilterFlags
 	DryRun, InitSingularityRequestID string
 	Force, WaitStable                bool
+	// Clusters are cluster selectors for the clusters to deploy to, instead of
+	// DFF.Cluster.
+	Clusters []string
+	// AllClusters deploys to every cluster the manifest is deployed to.
+	AllClusters bool
+	// At, if set, schedules the deploy for that time.
+	At time.Time
 }
 
 // GetDeploy constructs a Deploy Actions.
 func (di *SousGraph) GetDeploy(opts DeployActionOpts) (actions.Action, error) {
+	if len(opts.Clusters) > 0 || opts.AllClusters || sous.ClusterSelector(opts.DFF.Cluster).IsLabelSelector() {
+		return di.getDeployClusters(opts)
+	}
 	di.guardedAdd("Dryrun", DryrunOption(opts.DryRun))
 	di.guardedAdd("DeployFilterFlags", &opts.DFF)
 
@@ -224,6 +238,150 @@ func (di *SousGraph) GetDeploy(opts DeployActionOpts) (actions.Action, error) {
 		Config:             scoop.Config.Config,
 		Force:              opts.Force,
 		WaitStable:         opts.WaitStable,
+		At:                 opts.At,
+	}, nil
+}
+
+// getDeployClusters constructs a DeployClusters Action, deploying to each of
+// the clusters selected by opts.
+func (di *SousGraph) getDeployClusters(opts DeployActionOpts) (actions.Action, error) {
+	if opts.DFF.Cluster != "" && len(opts.Clusters) > 0 {
+		return nil, fmt.Errorf("use either -cluster or -clusters, not both")
+	}
+	selectors := opts.Clusters
+	if opts.DFF.Cluster != "" {
+		selectors = []string{opts.DFF.Cluster}
+		opts.DFF.Cluster = ""
+	}
+	di.guardedAdd("Dryrun", DryrunOption(opts.DryRun))
+	di.guardedAdd("DeployFilterFlags", &opts.DFF)
+
+	scoop := struct {
+		ResolveFilter    *RefinedResolveFilter
+		Clients          ClientBundle
+		HTTPStateManager *sous.HTTPStateManager
+		LogSink          LogSink
+		User             sous.User
+		Config           LocalSousConfig
+	}{}
+	if err := di.Inject(&scoop); err != nil {
+		return nil, err
+	}
+	tmid, err := newTargetManifestID(scoop.ResolveFilter)
+	if err != nil {
+		return nil, err
+	}
+	mid := sous.ManifestID(tmid)
+
+	state, err := scoop.HTTPStateManager.ReadState()
+	if err != nil {
+		return nil, err
+	}
+	clusters, err := deployClusterNames(state, mid, selectors, opts.AllClusters)
+	if err != nil {
+		return nil, err
+	}
+
+	dc := &actions.DeployClusters{OutWriter: os.Stdout}
+	for _, cn := range clusters {
+		client, has := scoop.Clients[cn]
+		if !has {
+			return nil, fmt.Errorf("no server for cluster %q", cn)
+		}
+		rf := *(*sous.ResolveFilter)(scoop.ResolveFilter)
+		rf.Cluster = sous.NewResolveFieldMatcher(cn)
+		did := sous.DeploymentID{ManifestID: mid, Cluster: cn}
+		dc.Deploys = append(dc.Deploys, &actions.Deploy{
+			ResolveFilter:      &rf,
+			HTTPClient:         client,
+			TargetDeploymentID: did,
+			StateReader:        scoop.HTTPStateManager,
+			LogSink:            scoop.LogSink.LogSink.Child("deploy", &rf, did),
+			User:               scoop.User,
+			Config:             scoop.Config.Config,
+			Force:              opts.Force,
+			WaitStable:         opts.WaitStable,
+			At:                 opts.At,
+		})
+	}
+	return dc, nil
+}
+
+// deployClusterNames returns the sorted names of the clusters to deploy mid
+// to: all those it's deployed to if all is true, otherwise those selected by
+// selectors. A selector other than a cluster's name only selects clusters mid
+// is deployed to.
+func deployClusterNames(state *sous.State, mid sous.ManifestID, selectors []string, all bool) ([]string, error) {
+	ds, err := state.Deployments()
+	if err != nil {
+		return nil, err
+	}
+	deployed := map[string]bool{}
+	for _, d := range ds.Snapshot() {
+		if d.ManifestID() == mid {
+			deployed[d.ClusterName] = true
+		}
+	}
+
+	selected := map[string]bool{}
+	if all {
+		for cn := range deployed {
+			selected[cn] = true
+		}
+	}
+	for _, sel := range selectors {
+		sel = strings.TrimSpace(sel)
+		if sel == "" {
+			continue
+		}
+		if _, isCluster := state.Defs.Clusters[sel]; isCluster {
+			selected[sel] = true
+			continue
+		}
+		var matched bool
+		for _, cn := range state.Defs.Clusters.Select(sous.ClusterSelector(sel)) {
+			if deployed[cn] {
+				selected[cn], matched = true, true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("%q selects no cluster %s is deployed to", sel, mid)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("%s is not deployed to any cluster", mid)
+	}
+
+	names := make([]string, 0, len(selected))
+	for cn := range selected {
+		names = append(names, cn)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// RollbackActionOpts are options for GetRollback.
+type RollbackActionOpts struct {
+	DFF    config.DeployFilterFlags
+	To     string
+	DryRun bool
+}
+
+// GetRollback constructs a Rollback Action.
+func (di *SousGraph) GetRollback(opts RollbackActionOpts, out io.Writer) (actions.Action, error) {
+	deploy, err := di.GetDeploy(DeployActionOpts{
+		DFF:        opts.DFF,
+		DryRun:     "none",
+		WaitStable: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &actions.Rollback{
+		Deploy:    deploy.(*actions.Deploy),
+		To:        opts.To,
+		DryRun:    opts.DryRun,
+		OutWriter: out,
 	}, nil
 }
 
@@ -272,6 +430,252 @@ func (di *SousGraph) GetPollStatus(dryrun string, dff config.DeployFilterFlags)
 	}, nil
 }
 
+// GetHistory produces an Action that lists the history of deployments: only
+// the limit most recent changes, unless limit is 0.
+func (di *SousGraph) GetHistory(dff config.DeployFilterFlags, limit int, out io.Writer) (actions.Action, error) {
+	di.guardedAdd("Dryrun", DryrunNeither)
+	di.guardedAdd("DeployFilterFlags", &dff)
+
+	scoop := struct {
+		HTTP    HTTPClient
+		LogSink LogSink
+	}{}
+	if err := di.Inject(&scoop); err != nil {
+		return nil, err
+	}
+
+	rf, err := dff.BuildFilter(sous.ParseSourceLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	return &actions.History{
+		ResolveFilter: rf,
+		Limit:         limit,
+		HTTPClient:    scoop.HTTP.HTTPClient,
+		LogSink:       scoop.LogSink.LogSink.Child("history", rf),
+		OutWriter:     out,
+	}, nil
+}
+
+// GetPlan produces an Action that shows what the server would change to
+// bring running deployments in line with the GDM.
+func (di *SousGraph) GetPlan(dff config.DeployFilterFlags, out io.Writer) (actions.Action, error) {
+	di.guardedAdd("Dryrun", DryrunNeither)
+	di.guardedAdd("DeployFilterFlags", &dff)
+
+	scoop := struct {
+		HTTP    HTTPClient
+		LogSink LogSink
+	}{}
+	if err := di.Inject(&scoop); err != nil {
+		return nil, err
+	}
+
+	rf, err := dff.BuildFilter(sous.ParseSourceLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	return &actions.Plan{
+		ResolveFilter: rf,
+		HTTPClient:    scoop.HTTP.HTTPClient,
+		LogSink:       scoop.LogSink.LogSink.Child("plan", rf),
+		OutWriter:     out,
+	}, nil
+}
+
+// GetPause produces an Action that pauses resolution of the cluster, or the
+// deployment to it, selected by dff.
+func (di *SousGraph) GetPause(dff config.DeployFilterFlags, reason string, out io.Writer) (actions.Action, error) {
+	di.guardedAdd("Dryrun", DryrunNeither)
+	di.guardedAdd("DeployFilterFlags", &dff)
+
+	scoop := struct {
+		HTTP    HTTPClient
+		LogSink LogSink
+		User    sous.User
+	}{}
+	if err := di.Inject(&scoop); err != nil {
+		return nil, err
+	}
+
+	target, err := pauseTarget(dff)
+	if err != nil {
+		return nil, err
+	}
+	return &actions.Pause{
+		Target:     target,
+		Reason:     reason,
+		HTTPClient: scoop.HTTP.HTTPClient,
+		User:       scoop.User,
+		LogSink:    scoop.LogSink.LogSink.Child("pause"),
+		OutWriter:  out,
+	}, nil
+}
+
+// GetResume produces an Action that resumes resolution of the cluster, or
+// the deployment to it, selected by dff.
+func (di *SousGraph) GetResume(dff config.DeployFilterFlags, out io.Writer) (actions.Action, error) {
+	di.guardedAdd("Dryrun", DryrunNeither)
+	di.guardedAdd("DeployFilterFlags", &dff)
+
+	scoop := struct {
+		HTTP    HTTPClient
+		LogSink LogSink
+		User    sous.User
+	}{}
+	if err := di.Inject(&scoop); err != nil {
+		return nil, err
+	}
+
+	target, err := pauseTarget(dff)
+	if err != nil {
+		return nil, err
+	}
+	return &actions.Resume{
+		Target:     target,
+		HTTPClient: scoop.HTTP.HTTPClient,
+		User:       scoop.User,
+		LogSink:    scoop.LogSink.LogSink.Child("resume"),
+		OutWriter:  out,
+	}, nil
+}
+
+// pauseTarget returns a Pause of the cluster given by dff, narrowed to the
+// deployment of the manifest it gives, if it gives a repo.
+func pauseTarget(dff config.DeployFilterFlags) (sous.Pause, error) {
+	rf, err := dff.BuildFilter(sous.ParseSourceLocation)
+	if err != nil {
+		return sous.Pause{}, err
+	}
+	cluster, err := rf.Cluster.Value()
+	if err != nil {
+		return sous.Pause{}, fmt.Errorf("-cluster is required")
+	}
+	if sous.ClusterSelector(cluster).IsLabelSelector() {
+		return sous.Pause{}, fmt.Errorf("-cluster must name a single cluster, not %q", cluster)
+	}
+	target := sous.Pause{Cluster: cluster}
+	if rf.Repo.All() {
+		return target, nil
+	}
+	target.Manifest = &sous.ManifestID{
+		Source: sous.SourceLocation{
+			Repo: rf.Repo.ValueOr(""),
+			Dir:  rf.Offset.ValueOr(""),
+		},
+		Flavor: rf.Flavor.ValueOr(""),
+	}
+	return target, nil
+}
+
+// GetScheduled produces an Action that lists the pending scheduled deploys
+// matching dff, or cancels the one with ID cancel. It asks the server of the
+// cluster selected by dff, if there is one, since that's where deploys to it
+// are scheduled.
+func (di *SousGraph) GetScheduled(dff config.DeployFilterFlags, cancel string, out io.Writer) (actions.Action, error) {
+	di.guardedAdd("Dryrun", DryrunNeither)
+	di.guardedAdd("DeployFilterFlags", &dff)
+
+	scoop := struct {
+		HTTP    HTTPClient
+		LogSink LogSink
+		User    sous.User
+	}{}
+	if err := di.Inject(&scoop); err != nil {
+		return nil, err
+	}
+
+	rf, err := dff.BuildFilter(sous.ParseSourceLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	client := scoop.HTTP.HTTPClient
+	if cluster, err := rf.Cluster.Value(); err == nil && !sous.ClusterSelector(cluster).IsLabelSelector() {
+		clients := struct{ Clients ClientBundle }{}
+		if err := di.Inject(&clients); err != nil {
+			return nil, err
+		}
+		cl, has := clients.Clients[cluster]
+		if !has {
+			return nil, fmt.Errorf("no server for cluster %q", cluster)
+		}
+		client = cl
+	}
+
+	return &actions.Scheduled{
+		ResolveFilter: rf,
+		Cancel:        cancel,
+		HTTPClient:    client,
+		User:          scoop.User,
+		LogSink:       scoop.LogSink.LogSink.Child("scheduled", rf),
+		OutWriter:     out,
+	}, nil
+}
+
+// GetBuildCacheList produces an Action that lists the runmount build caches
+// on this machine.
+func (di *SousGraph) GetBuildCacheList(out io.Writer) (actions.Action, error) {
+	scoop := struct{ Caches *docker.BuildCaches }{}
+	if err := di.Inject(&scoop); err != nil {
+		return nil, err
+	}
+	return &actions.BuildCacheList{Caches: scoop.Caches, OutWriter: out}, nil
+}
+
+// GetBuildCachePrune produces an Action that removes the runmount build
+// caches not used since cutoff, and then the least recently used until they
+// total no more than maxSize bytes. If neither limit is given, the caches are
+// pruned to the configured Docker.BuildCacheMaxMB.
+func (di *SousGraph) GetBuildCachePrune(maxSize int64, cutoff time.Time, out io.Writer) (actions.Action, error) {
+	scoop := struct{ Caches *docker.BuildCaches }{}
+	if err := di.Inject(&scoop); err != nil {
+		return nil, err
+	}
+	if maxSize < 0 && cutoff.IsZero() {
+		if scoop.Caches.MaxSize <= 0 {
+			return nil, fmt.Errorf("Docker.BuildCacheMaxMB is not set, so there is no size to prune build caches to")
+		}
+		maxSize = scoop.Caches.MaxSize
+	}
+	return &actions.BuildCachePrune{Caches: scoop.Caches, MaxSize: maxSize, Cutoff: cutoff, OutWriter: out}, nil
+}
+
+// GetPromote constructs a Promote Action.
+func (di *SousGraph) GetPromote(dff config.DeployFilterFlags, out io.Writer) (actions.Action, error) {
+	di.guardedAdd("DeployFilterFlags", &dff)
+	di.guardedAdd("Dryrun", DryrunNeither)
+
+	scoop := struct {
+		RF      *RefinedResolveFilter
+		Tmid    TargetManifestID
+		HTTP    HTTPClient
+		LogSink LogSink
+		User    sous.User
+	}{}
+	if err := di.Inject(&scoop); err != nil {
+		return nil, err
+	}
+
+	rf := (*sous.ResolveFilter)(scoop.RF)
+	version, err := rf.TagVersion()
+	if err != nil {
+		return nil, err
+	}
+	mid := sous.ManifestID(scoop.Tmid)
+	return &actions.Promote{
+		ManifestID:   mid,
+		Version:      version,
+		HTTPClient:   scoop.HTTP.HTTPClient,
+		User:         scoop.User,
+		LogSink:      scoop.LogSink.LogSink.Child("promote", rf, mid),
+		OutWriter:    out,
+		PollInterval: 5 * time.Second,
+	}, nil
+}
+
 // GetServer returns the server action.
 func (di *SousGraph) GetServer(
 	dff config.DeployFilterFlags,
@@ -295,6 +699,7 @@ func (di *SousGraph) GetServer(
 		Config        *config.Config
 		ServerHandler ServerHandler
 		AutoResolver  *sous.AutoResolver
+		Scheduler     *sous.DeployScheduler
 	}{}
 
 	if err := di.Inject(&scoop); err != nil {
@@ -315,5 +720,6 @@ func (di *SousGraph) GetServer(
 		Config:            scoop.Config,
 		ServerHandler:     scoop.ServerHandler.Handler,
 		AutoResolver:      ar,
+		DeployScheduler:   scoop.Scheduler,
 	}, nil
 }
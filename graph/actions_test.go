@@ -149,3 +149,35 @@ func TestActionRectifyDryruns(t *testing.T) {
 	//testDryRun("none", "not empty", &sous.DummyRegistry{})
 	//testDryRun("scheduler", "not empty", &sous.DummyRegistry{})
 }
+
+func TestDeployClusterNames(t *testing.T) {
+	mid := sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/example/project"}}
+	clusters := sous.Clusters{
+		"east": {Name: "east", Labels: map[string]string{"env": "prod"}, Groups: []string{"prod"}},
+		"west": {Name: "west", Labels: map[string]string{"env": "prod"}, Groups: []string{"prod"}},
+		"ci":   {Name: "ci", Labels: map[string]string{"env": "ci"}},
+		"qa":   {Name: "qa", Labels: map[string]string{"env": "ci"}},
+	}
+	m := &sous.Manifest{Deployments: sous.DeploySpecs{
+		"east": {DeployConfig: sous.DeployConfig{NumInstances: 1}},
+		"west": {DeployConfig: sous.DeployConfig{NumInstances: 1}},
+		"ci":   {DeployConfig: sous.DeployConfig{NumInstances: 1}},
+	}}
+	m.SetID(mid)
+	state := &sous.State{Defs: sous.Defs{Clusters: clusters}, Manifests: sous.NewManifests(m)}
+
+	names, err := deployClusterNames(state, mid, nil, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ci", "east", "west"}, names)
+
+	names, err = deployClusterNames(state, mid, []string{"prod", "ci"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ci", "east", "west"}, names)
+
+	names, err = deployClusterNames(state, mid, []string{"env=ci"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ci"}, names)
+
+	_, err = deployClusterNames(state, mid, []string{"env=staging"}, false)
+	assert.Error(t, err)
+}
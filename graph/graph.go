@@ -1,18 +1,24 @@
 package graph
 
 import (
+	"crypto/tls"
 	"database/sql"
+	"encoding/base64"
 	"fmt"
 	"io"
 	"io/ioutil" //ok
 	"net/http"
 	"os"
 	"os/user"
+	"path/filepath"
+	"time"
 
 	"github.com/opentable/sous/config"
 	"github.com/opentable/sous/ext/docker"
 	"github.com/opentable/sous/ext/git"
 	"github.com/opentable/sous/ext/github"
+	"github.com/opentable/sous/ext/kubernetes"
+	"github.com/opentable/sous/ext/nomad"
 	"github.com/opentable/sous/ext/singularity"
 	"github.com/opentable/sous/ext/storage"
 	"github.com/opentable/sous/lib"
@@ -90,6 +96,33 @@ type (
 	ServerStateManager struct{ sous.StateManager }
 	// ServerClusterManager wraps the sous.ClusterManager interface and is used by `sous server`
 	ServerClusterManager struct{ sous.ClusterManager }
+	// ServerHistoryStore wraps the sous.HistoryStore interface and is used by `sous server`
+	ServerHistoryStore struct{ sous.HistoryStore }
+	// ServerScheduleStore wraps the sous.ScheduleStore interface and is used
+	// by `sous server`
+	ServerScheduleStore struct{ sous.ScheduleStore }
+	// ServerR11nStore wraps the sous.R11nStore interface and is used by `sous
+	// server`. R11nStore is nil if there's nowhere to persist rectifications.
+	ServerR11nStore struct {
+		sous.R11nStore
+		// Retention is how long finished rectifications are kept.
+		Retention time.Duration
+	}
+	// ServerAuthorizer wraps the server.Authorizer used by `sous server`. It
+	// is nil unless authentication is configured.
+	ServerAuthorizer struct{ *server.Authorizer }
+	// DeploySecretStore wraps the sous.SecretStore SecretRefs are resolved
+	// from as deployments are sent to their clusters. SecretStore is nil unless
+	// a store is configured.
+	DeploySecretStore struct{ sous.SecretStore }
+
+	// ProvenanceSigner wraps the sous.ProvenanceSigner builds are attested
+	// to with. It's nil unless a signing key is configured.
+	ProvenanceSigner struct{ *sous.ProvenanceSigner }
+	// ProvenanceCheck wraps the sous.ProvenanceCheck a server verifies
+	// images with before rectifying deployments of them. It's nil unless
+	// trusted keys are configured.
+	ProvenanceCheck struct{ *sous.ProvenanceCheck }
 
 	distStateManager struct {
 		sous.StateManager
@@ -236,6 +269,9 @@ func AddFilesystem(graph adder) {
 		newMaybeDatabase, // we need to be able to progress in the absence of a DB.
 		newServerStateManager,
 		newServerClusterManager,
+		newServerHistoryStore,
+		newServerScheduleStore,
+		newServerR11nStore,
 		newDistributedStateManager,
 		newGitStateManager,
 		newDiskStateManager,
@@ -271,6 +307,8 @@ func AddDocker(graph adder) {
 		newLazyNameCache,
 		newNameCache,
 		newDockerBuilder,
+		newOCIBuildpack,
+		newBuildCaches,
 		newSelector,
 	)
 }
@@ -314,6 +352,15 @@ func AddInternals(graph adder) {
 		newHTTPClientBundle,
 		newClusterSpecificHTTPClient,
 		NewR11nQueueSet,
+		newPromotionEngine,
+		newDeployScheduler,
+		sous.NewDeployEvents,
+		newDriftDetector,
+		newServerAuthorizer,
+		newDeploySecretStore,
+		newProvenanceSigner,
+		newProvenanceStore,
+		newProvenanceCheck,
 	)
 }
 
@@ -321,8 +368,15 @@ func newResolveFilter(sf *config.DeployFilterFlags, shc sous.SourceHostChooser)
 	return sf.BuildFilter(shc.ParseSourceLocation)
 }
 
-func newResolver(filter *sous.ResolveFilter, d sous.Deployer, r sous.Registry, ls LogSink, qs *sous.R11nQueueSet) *sous.Resolver {
-	return sous.NewResolver(d, r, filter, ls.Child("resolver"), qs)
+func newResolver(filter *sous.ResolveFilter, d sous.Deployer, r sous.Registry, ls LogSink, qs *sous.R11nQueueSet, events *sous.DeployEvents, drift *sous.DriftDetector) *sous.Resolver {
+	rez := sous.NewResolver(d, r, filter, ls.Child("resolver"), qs)
+	rez.Events = events
+	rez.Drift = drift
+	return rez
+}
+
+func newDriftDetector(ls LogSink) *sous.DriftDetector {
+	return sous.NewDriftDetector(ls.Child("drift"))
 }
 
 func newAutoResolver(rez *sous.Resolver, sr *ServerStateManager, ls LogSink) *sous.AutoResolver {
@@ -474,13 +528,15 @@ func newBuildConfig(ls LogSink, f *config.DeployFilterFlags, p *config.PolicyFla
 	return &cfg
 }
 
-func newBuildManager(ls LogSink, bc *sous.BuildConfig, sl sous.Selector, lb sous.Labeller, rg sous.Registrar) *sous.BuildManager {
+func newBuildManager(ls LogSink, bc *sous.BuildConfig, sl sous.Selector, lb sous.Labeller, rg sous.Registrar, ps ProvenanceSigner, store sous.ProvenanceStore) *sous.BuildManager {
 	return &sous.BuildManager{
-		BuildConfig: bc,
-		Selector:    sl,
-		Labeller:    lb,
-		Registrar:   rg,
-		LogSink:     ls,
+		BuildConfig:     bc,
+		Selector:        sl,
+		Labeller:        lb,
+		Registrar:       rg,
+		Signer:          ps.ProvenanceSigner,
+		ProvenanceStore: store,
+		LogSink:         ls,
 	}
 }
 
@@ -515,8 +571,25 @@ func newLocalWorkDirShell(verbosity *config.Verbosity, l LocalWorkDir) (v LocalW
 	return v, initErr(err, "getting current working directory")
 }
 
-func newSelector(regClient LocalDockerClient, log LogSink) sous.Selector {
-	return docker.NewBuildStrategySelector(log.Child("docker-build-strategy"), regClient)
+func newSelector(cfg LocalSousConfig, regClient LocalDockerClient, caches *docker.BuildCaches, ob *docker.OCIBuildpack, log LogSink) sous.Selector {
+	var daemonless *docker.OCIBuildpack
+	if cfg.Docker.Daemonless {
+		daemonless = ob
+	}
+	return docker.NewBuildStrategySelector(log.Child("docker-build-strategy"), regClient, caches, daemonless)
+}
+
+func newBuildCaches(cfg LocalSousConfig, u config.LocalUser, sh LocalWorkDirShell, log LogSink) *docker.BuildCaches {
+	dir := cfg.BuildStateDir
+	if dir == "" {
+		dir = u.ConfigDir()
+	}
+	return docker.NewBuildCaches(sh.Sh, filepath.Join(dir, "build-caches.json"),
+		cfg.Docker.BuildCacheMaxMB*1024*1024, cfg.Docker.BuildCacheRegistry, log.Child("build-caches"))
+}
+
+func newOCIBuildpack(cfg LocalSousConfig, regClient LocalDockerClient, nc sous.ClientInserter, log LogSink) *docker.OCIBuildpack {
+	return docker.NewOCIBuildpack(regClient, cfg.Docker.RegistryHost, nc.Inserter, log.Child("daemonless-builder"))
 }
 
 func newDockerBuilder(cfg LocalSousConfig, nc sous.ClientInserter, ctx *sous.SourceContext, source LocalWorkDirShell, scratch ScratchDirShell, log LogSink) (*docker.Builder, error) {
@@ -526,11 +599,17 @@ func newDockerBuilder(cfg LocalSousConfig, nc sous.ClientInserter, ctx *sous.Sou
 	return docker.NewBuilder(nc.Inserter, drh, source.Sh, scratch.Sh, log.Child("docker-builder"))
 }
 
-func newLabeller(db *docker.Builder) sous.Labeller {
+func newLabeller(cfg LocalSousConfig, db *docker.Builder, ob *docker.OCIBuildpack) sous.Labeller {
+	if cfg.Docker.Daemonless {
+		return ob
+	}
 	return db
 }
 
-func newRegistrar(db *docker.Builder) sous.Registrar {
+func newRegistrar(cfg LocalSousConfig, db *docker.Builder, ob *docker.OCIBuildpack) sous.Registrar {
+	if cfg.Docker.Daemonless {
+		return ob
+	}
 	return db
 }
 
@@ -542,27 +621,43 @@ func newRegistry(graph *SousGraph, nc lazyNameCache, dryrun DryrunOption, c Loca
 	return sous.NewDummyRegistry(), nil
 }
 
-func newDeployer(dryrun DryrunOption, nc lazyNameCache, ls LogSink, c LocalSousConfig) (sous.Deployer, error) {
+func newDeployer(dryrun DryrunOption, nc lazyNameCache, secrets *DeploySecretStore, pc ProvenanceCheck, ls LogSink, c LocalSousConfig) (sous.Deployer, error) {
 	// Eventually, based on configuration, we may make different decisions here.
 	if dryrun == DryrunBoth || dryrun == DryrunScheduler || c.Server != "" {
 		drc := sous.NewDummyRectificationClient()
 		drc.SetLogger(ls.Child("rectify"))
-		return singularity.NewDeployer(
-			drc,
-			ls.Child("singularity-deployer"),
-			singularity.OptMaxHTTPReqsPerServer(c.MaxHTTPConcurrencySingularity),
-		), nil
+		return sous.NewDispatchDeployer(map[string]sous.Deployer{
+			singularity.ClusterKind: singularity.NewDeployer(
+				drc,
+				ls.Child("singularity-deployer"),
+				singularity.OptMaxHTTPReqsPerServer(c.MaxHTTPConcurrencySingularity),
+			),
+			kubernetes.ClusterKind: sous.NewDummyDeployer(),
+			nomad.ClusterKind:      sous.NewDummyDeployer(),
+		}, ls.Child("dispatch-deployer")), nil
 	}
 	// We need the real name cache.
 	labeller, err := nc()
 	if err != nil {
 		return nil, err
 	}
-	return singularity.NewDeployer(
-		singularity.NewRectiAgent(labeller, ls),
-		ls,
-		singularity.OptMaxHTTPReqsPerServer(c.MaxHTTPConcurrencySingularity),
-	), nil
+	dd := sous.NewDispatchDeployer(map[string]sous.Deployer{
+		singularity.ClusterKind: singularity.NewDeployer(
+			singularity.NewRectiAgent(labeller, secrets.SecretStore, ls),
+			ls,
+			singularity.OptMaxHTTPReqsPerServer(c.MaxHTTPConcurrencySingularity),
+		),
+		kubernetes.ClusterKind: kubernetes.NewDeployer(
+			ls.Child("kubernetes-deployer"),
+			append(c.Kubernetes.Options(), kubernetes.OptSecretStore(secrets.SecretStore))...,
+		),
+		nomad.ClusterKind: nomad.NewDeployer(
+			ls.Child("nomad-deployer"),
+			append(c.Nomad.Options(), nomad.OptSecretStore(secrets.SecretStore))...,
+		),
+	}, ls.Child("dispatch-deployer"))
+	dd.Provenance = pc.ProvenanceCheck
+	return dd, nil
 }
 
 func newServerHandler(g *SousGraph, Registry sous.Registry, ComponentLocator server.ComponentLocator, metrics MetricsHandler, log LogSink) ServerHandler {
@@ -587,13 +682,16 @@ func newServerListData(c HTTPClient) (ServerListData, error) {
 	return serverList, err
 }
 
-func newHTTPClientBundle(serverList ServerListData, tid sous.TraceID, log LogSink) (ClientBundle, error) {
+func newHTTPClientBundle(c LocalSousConfig, serverList ServerListData, user sous.User, tid sous.TraceID, log LogSink) (ClientBundle, error) {
 	bundle := ClientBundle{}
 	for _, s := range serverList.Servers {
-		client, err := restful.NewClient(s.URL, log.Child(s.ClusterName+".http-client"), map[string]string{"OT-RequestId": string(tid)})
+		client, err := restful.NewClient(s.URL, log.Child(s.ClusterName+".http-client"), clientHeaders(user, tid))
 		if err != nil {
 			return nil, err
 		}
+		if err := configureClientTLS(client, c.Auth); err != nil {
+			return nil, err
+		}
 
 		bundle[s.ClusterName] = client
 	}
@@ -625,8 +723,57 @@ func newHTTPClient(c LocalSousConfig, user sous.User, tid sous.TraceID, log LogS
 		return HTTPClient{}, errors.New("no server configured")
 	}
 	messages.ReportLogFieldsMessageToConsole(fmt.Sprintf("Using server %s", c.Server), logging.ExtraDebug1Level, log)
-	cl, err := restful.NewClient(c.Server, log.Child("http-client"), map[string]string{"OT-RequestId": string(tid)})
-	return HTTPClient{HTTPClient: cl}, err
+	cl, err := restful.NewClient(c.Server, log.Child("http-client"), clientHeaders(user, tid))
+	if err != nil {
+		return HTTPClient{HTTPClient: cl}, err
+	}
+	return HTTPClient{HTTPClient: cl}, configureClientTLS(cl, c.Auth)
+}
+
+// clientHeaders returns the headers sent with every request to a server,
+// including the user's token, if they have one.
+func clientHeaders(user sous.User, tid sous.TraceID) map[string]string {
+	hs := map[string]string{"OT-RequestId": string(tid)}
+	if user.Token != "" {
+		hs["Authorization"] = "Bearer " + user.Token
+	}
+	return hs
+}
+
+// configureClientTLS makes cl present the client certificate configured in
+// ac, if any.
+func configureClientTLS(cl *restful.LiveHTTPClient, ac config.AuthConfig) error {
+	if ac.ClientCertFile == "" {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(ac.ClientCertFile, ac.ClientKeyFile)
+	if err != nil {
+		return errors.Wrap(err, "loading client certificate")
+	}
+	cl.SetTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}})
+	return nil
+}
+
+// newSiblingClient returns a client of the server for cluster at url, which
+// presents this server's credentials: its client certificate, and an admin
+// token signed with the token secret servers share.
+func newSiblingClient(cluster, url string, ac config.AuthConfig, log LogSink) (*restful.LiveHTTPClient, error) {
+	headers := map[string]string{}
+	if ac.TokenSecret != "" && ac.AdminGroup != "" {
+		token, err := sous.SignUserToken([]byte(ac.TokenSecret), sous.UserClaims{
+			Name:   "sous-server",
+			Groups: []string{ac.AdminGroup},
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "signing server token")
+		}
+		headers["Authorization"] = "Bearer " + token
+	}
+	cl, err := restful.NewClient(url, log.Child(cluster+".http-client"), headers)
+	if err != nil {
+		return nil, err
+	}
+	return cl, configureClientTLS(cl, ac)
 }
 
 func newInMemoryClient(srvr ServerHandler, log LogSink) (HTTPClient, error) {
@@ -634,7 +781,7 @@ func newInMemoryClient(srvr ServerHandler, log LogSink) (HTTPClient, error) {
 	return HTTPClient{HTTPClient: cl}, err
 }
 
-func newServerStateManager(c LocalSousConfig, log LogSink, gm gitStateManager, dm distStateManager) (*ServerStateManager, error) {
+func newServerStateManager(c LocalSousConfig, log LogSink, gm gitStateManager, dm distStateManager, hs *ServerHistoryStore) (*ServerStateManager, error) {
 	var primary, secondary sous.StateManager
 	var perr error
 	primary = gm.StateManager
@@ -648,10 +795,11 @@ func newServerStateManager(c LocalSousConfig, log LogSink, gm gitStateManager, d
 	secondary = storage.NewLogOnlyStateManager(log.Child("secondary"))
 
 	duplex := storage.NewDuplexStateManager(primary, secondary, log.Child("duplex-state"))
-	return &ServerStateManager{StateManager: duplex}, nil
+	history := sous.NewHistoryStateManager(duplex, hs.HistoryStore, log.Child("history"))
+	return &ServerStateManager{StateManager: history}, nil
 }
 
-func newServerClusterManager(c LocalSousConfig, log LogSink, gm gitStateManager, dm distStateManager) (*ServerClusterManager, error) {
+func newServerClusterManager(c LocalSousConfig, log LogSink, gm gitStateManager, dm distStateManager, hs *ServerHistoryStore) (*ServerClusterManager, error) {
 	var cmgr sous.StateManager
 	var err error
 
@@ -667,7 +815,67 @@ func newServerClusterManager(c LocalSousConfig, log LogSink, gm gitStateManager,
 		return nil, err
 	}
 
-	return &ServerClusterManager{ClusterManager: sous.MakeClusterManager(cmgr, log)}, nil
+	cm := sous.MakeClusterManager(cmgr, log)
+	return &ServerClusterManager{ClusterManager: sous.NewHistoryClusterManager(cm, hs.HistoryStore, log.Child("history"))}, nil
+}
+
+// newServerHistoryStore keeps deployment history in the database if there is
+// one, and in memory otherwise.
+func newServerHistoryStore(mdb MaybeDatabase, log LogSink) *ServerHistoryStore {
+	if mdb.Err != nil {
+		messages.ReportLogFieldsMessage("No database: keeping deployment history in memory", logging.WarningLevel, log, mdb.Err)
+		return &ServerHistoryStore{HistoryStore: sous.NewInMemoryHistory()}
+	}
+	return &ServerHistoryStore{HistoryStore: storage.NewPostgresHistory(mdb.Db, log.Child("history"))}
+}
+
+// newServerScheduleStore keeps scheduled deploys in the database if there is
+// one, and in memory otherwise.
+func newServerScheduleStore(mdb MaybeDatabase, log LogSink) *ServerScheduleStore {
+	if mdb.Err != nil {
+		messages.ReportLogFieldsMessage("No database: scheduled deploys won't survive a restart", logging.WarningLevel, log, mdb.Err)
+		return &ServerScheduleStore{ScheduleStore: sous.NewInMemorySchedule()}
+	}
+	return &ServerScheduleStore{ScheduleStore: storage.NewPostgresSchedule(mdb.Db, log.Child("schedule"))}
+}
+
+// newServerR11nStore persists rectification queues in the database if there
+// is one. Otherwise they are lost when the server restarts.
+func newServerR11nStore(c LocalSousConfig, mdb MaybeDatabase, log LogSink) *ServerR11nStore {
+	retention := sous.R11nRetentionDefault
+	if c.R11nRetentionMinutes > 0 {
+		retention = time.Duration(c.R11nRetentionMinutes) * time.Minute
+	}
+	if mdb.Err != nil {
+		messages.ReportLogFieldsMessage("No database: rectification queues won't survive a restart", logging.WarningLevel, log, mdb.Err)
+		return &ServerR11nStore{Retention: retention}
+	}
+	return &ServerR11nStore{
+		R11nStore: storage.NewPostgresR11nStore(mdb.Db, log.Child("r11n-store")),
+		Retention: retention,
+	}
+}
+
+func newDeploySecretStore(c LocalSousConfig) (*DeploySecretStore, error) {
+	sc := c.Secrets
+	switch sc.Store {
+	default:
+		return &DeploySecretStore{}, nil
+	case "file":
+		key, err := base64.StdEncoding.DecodeString(sc.FileKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decoding secrets file key")
+		}
+		store, err := storage.NewFileSecretStore(filepath.Join(c.StateLocation, storage.SecretsFileName), key)
+		if err != nil {
+			return nil, err
+		}
+		return &DeploySecretStore{SecretStore: store}, nil
+	case "vault":
+		return &DeploySecretStore{
+			SecretStore: storage.NewVaultSecretStore(sc.VaultAddr, sc.VaultToken, sc.VaultMount),
+		}, nil
+	}
 }
 
 func newDistributedStateManager(c LocalSousConfig, mdb MaybeDatabase, tid sous.TraceID, rf *sous.ResolveFilter, log LogSink) distStateManager {
@@ -702,7 +910,7 @@ func newDistributedStorage(db *sql.DB, c LocalSousConfig, tid sous.TraceID, rf *
 	clusterNames := []string{}
 	for n, u := range c.SiblingURLs {
 		// XXX not immediately clear how to conserve the request id through the distributed storage.
-		cl, err := restful.NewClient(u, log.Child(n+".http-client"))
+		cl, err := newSiblingClient(n, u, c.Auth, log)
 		if err != nil {
 			return nil, err
 		}
@@ -711,6 +919,7 @@ func newDistributedStorage(db *sql.DB, c LocalSousConfig, tid sous.TraceID, rf *
 	}
 	// XXX the first arg is used to get e.g. defs. Should be at least an in memory client for these purposes.
 	hsm := sous.NewHTTPStateManager(list[localName], tid, log.Child("http-state-manager"))
+	hsm.UseClusterClients(list)
 	return sous.NewDispatchStateManager(localName, clusterNames, local, hsm, log.Child("state-manager")), nil
 }
 
@@ -735,7 +944,9 @@ func newStatusPoller(cl HTTPClient, rf *RefinedResolveFilter, user sous.User, lo
 		return nil
 	}
 	messages.ReportLogFieldsMessageToConsole("...looks good...", logging.ExtraDebug1Level, logs)
-	return sous.NewStatusPoller(cl, (*sous.ResolveFilter)(rf), user, logs.Child("status-poller"))
+	sp := sous.NewStatusPoller(cl, (*sous.ResolveFilter)(rf), user, logs.Child("status-poller"))
+	sp.Streaming = true
+	return sp
 }
 
 /*
@@ -802,3 +1013,34 @@ func initErr(err error, what string) error {
 	}
 	return fmt.Errorf(message)
 }
+
+func newProvenanceSigner(c LocalSousConfig) (ProvenanceSigner, error) {
+	if c.Provenance.SigningKeyFile == "" {
+		return ProvenanceSigner{}, nil
+	}
+	key, err := ioutil.ReadFile(c.Provenance.SigningKeyFile)
+	if err != nil {
+		return ProvenanceSigner{}, errors.Wrap(err, "reading provenance signing key")
+	}
+	signer, err := sous.NewProvenanceSigner(key)
+	return ProvenanceSigner{signer}, errors.Wrapf(err, "parsing provenance signing key %s", c.Provenance.SigningKeyFile)
+}
+
+func newProvenanceStore(cl LocalDockerClient) sous.ProvenanceStore {
+	return docker.NewRegistryProvenanceStore(cl.Client)
+}
+
+func newProvenanceCheck(c LocalSousConfig, store sous.ProvenanceStore) (ProvenanceCheck, error) {
+	if c.Provenance.TrustedKeysFile == "" {
+		return ProvenanceCheck{}, nil
+	}
+	keys, err := ioutil.ReadFile(c.Provenance.TrustedKeysFile)
+	if err != nil {
+		return ProvenanceCheck{}, errors.Wrap(err, "reading trusted provenance keys")
+	}
+	verifier, err := sous.NewProvenanceVerifier(keys)
+	if err != nil {
+		return ProvenanceCheck{}, errors.Wrapf(err, "parsing trusted provenance keys %s", c.Provenance.TrustedKeysFile)
+	}
+	return ProvenanceCheck{&sous.ProvenanceCheck{Store: store, Verifier: verifier}}, nil
+}
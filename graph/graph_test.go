@@ -4,8 +4,12 @@ import (
 	"bytes"
 	"io/ioutil"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/opentable/sous/config"
 	"github.com/opentable/sous/lib"
@@ -203,3 +207,24 @@ func TestNewBuildConfig(t *testing.T) {
 	}
 
 }
+
+func TestNewSiblingClient(t *testing.T) {
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	ac := config.AuthConfig{TokenSecret: "sekrit", AdminGroup: "admins"}
+	cl, err := newSiblingClient("other", srv.URL, ac, LogSink{logging.SilentLogSet()})
+	require.NoError(t, err)
+	_, err = cl.Retrieve("./servers", nil, &struct{}{}, nil)
+	require.NoError(t, err)
+
+	require.True(t, strings.HasPrefix(auth, "Bearer "), "no token presented: %q", auth)
+	claims, err := sous.VerifyUserToken([]byte(ac.TokenSecret), strings.TrimPrefix(auth, "Bearer "), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admins"}, claims.Groups)
+}
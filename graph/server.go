@@ -6,6 +6,8 @@ import (
 	sous "github.com/opentable/sous/lib"
 	"github.com/opentable/sous/server"
 	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
 	"github.com/samsalisbury/semv"
 )
 
@@ -15,23 +17,22 @@ func newServerComponentLocator(
 	ins serverInserter,
 	sm *ServerStateManager,
 	cm *ServerClusterManager,
+	hs *ServerHistoryStore,
 	rf *sous.ResolveFilter,
 	ar *sous.AutoResolver,
 	v semv.Version,
 	qs *sous.R11nQueueSet,
+	pe *sous.PromotionEngine,
+	scheduler *sous.DeployScheduler,
+	events *sous.DeployEvents,
+	auth *ServerAuthorizer,
 ) server.ComponentLocator {
 
 	logging.Deliver(ls, logging.SousGenericV1, logging.DebugLevel, logging.GetCallerInfo(),
 		logging.MessageField(fmt.Sprintf("Building CL: State manager: %T %[1]p", sm.StateManager)))
 
-	var dm sous.DeploymentManager
+	dm := newDeploymentManager(sm, ls)
 
-	switch ldm := sm.StateManager.(type) {
-	default:
-		dm = sous.MakeDeploymentManager(sm.StateManager, ls)
-	case sous.DeploymentManager:
-		dm = ldm
-	}
 	return server.ComponentLocator{
 
 		LogSink:           ls.LogSink,
@@ -40,21 +41,112 @@ func newServerComponentLocator(
 		StateManager:      sm.StateManager,
 		ClusterManager:    cm.ClusterManager,
 		DeploymentManager: dm,
+		HistoryStore:      hs.HistoryStore,
 		ResolveFilter:     rf,
 		AutoResolver:      ar,
 		Version:           v,
 		QueueSet:          qs,
+		PromotionEngine:   pe,
+		DeployScheduler:   scheduler,
+		DeployEvents:      events,
+		Authorizer:        auth.Authorizer,
+	}
+
+}
+
+// newServerAuthorizer returns the authorizer configured by cfg.Auth. If
+// authentication isn't configured, the server trusts the user named in each
+// request's headers.
+func newServerAuthorizer(cfg LocalSousConfig, ls LogSink) (*ServerAuthorizer, error) {
+	auth, err := server.NewAuthorizer(cfg.Auth, ls.Child("auth"))
+	if err != nil {
+		return nil, errors.Wrap(err, "configuring authentication")
 	}
+	if auth == nil {
+		messages.ReportLogFieldsMessage("Authentication not configured: anyone may change any manifest", logging.InformationLevel, ls)
+	}
+	return &ServerAuthorizer{Authorizer: auth}, nil
+}
 
+func newDeploymentManager(sm *ServerStateManager, ls LogSink) sous.DeploymentManager {
+	if dm, is := sm.StateManager.(sous.DeploymentManager); is {
+		return dm
+	}
+	return sous.MakeDeploymentManager(sm.StateManager, ls)
 }
 
 // NewR11nQueueSet returns a new queue set configured to start processing r11ns
-// immediately.
-func NewR11nQueueSet(d sous.Deployer, r sous.Registry, rf *sous.ResolveFilter, sm *ServerStateManager) *sous.R11nQueueSet {
+// immediately. Failed deployments of manifests which opt in are rolled back.
+// If rs has a store, queues are persisted there, and whatever it held from
+// before a restart is queued again. The progress of each rectification is
+// published to events.
+func NewR11nQueueSet(d sous.Deployer, r sous.Registry, rf *sous.ResolveFilter, sm *ServerStateManager, rs *ServerR11nStore, events *sous.DeployEvents, drift *sous.DriftDetector, ls LogSink) *sous.R11nQueueSet {
 	sr := sm.StateManager
-	return sous.NewR11nQueueSet(sous.R11nQueueStartWithHandler(
+	dm := newDeploymentManager(sm, ls)
+	opts := []sous.R11nQueueOpt{}
+	if rs.R11nStore != nil {
+		opts = append(opts, sous.R11nQueueStore(rs.R11nStore, ls.Child("r11n-store")))
+	}
+	opts = append(opts, sous.R11nQueueStartWithHandler(
 		func(qr *sous.QueuedR11n) sous.DiffResolution {
+			qr.Rectification.AutoRollbackWith(dm)
+			qr.Rectification.PublishTo(events, qr.ID)
 			qr.Rectification.Begin(d, r, rf, sr)
-			return qr.Rectification.Wait()
+			rez := qr.Rectification.Wait()
+			drift.RecordRectified(&qr.Rectification.Pair, rez)
+			return rez
 		}))
+	qs := sous.NewR11nQueueSet(opts...)
+	if rs.R11nStore != nil {
+		if err := restoreR11ns(qs, rs, rf, sr, ls); err != nil {
+			messages.ReportLogFieldsMessage("Failed to restore rectification queues", logging.WarningLevel, ls, err)
+		}
+		sous.PurgeR11nsEvery(rs.R11nStore, rs.Retention, sous.R11nPurgeInterval, ls.Child("r11n-store"))
+	}
+	return qs
+}
+
+// restoreR11ns restores the rectifications in rs of the clusters rf selects.
+func restoreR11ns(qs *sous.R11nQueueSet, rs *ServerR11nStore, rf *sous.ResolveFilter, sr sous.StateReader, ls LogSink) error {
+	state, err := sr.ReadState()
+	if err != nil {
+		return err
+	}
+	return qs.Restore(rs.R11nStore, rs.Retention, rf.SelectClusters(state.Defs.Clusters), ls)
+}
+
+// newPromotionEngine returns a PromotionEngine that rectifies the clusters rf
+// selects through qs, and deploys to other clusters through their sibling
+// servers, presenting this server's credentials. It polls the sibling server of each cluster to confirm a stage is
+// stable.
+func newPromotionEngine(cfg LocalSousConfig, sm *ServerStateManager, qs *sous.R11nQueueSet, rf *sous.ResolveFilter, ls LogSink) (*sous.PromotionEngine, error) {
+	siblings := ClientBundle{}
+	for cluster, url := range cfg.SiblingURLs {
+		cl, err := newSiblingClient(cluster, url, cfg.Auth, ls)
+		if err != nil {
+			return nil, err
+		}
+		siblings[cluster] = cl
+	}
+	newPoller := func(rf *sous.ResolveFilter) (sous.StatusWaiter, error) {
+		cluster, err := rf.Cluster.Value()
+		if err != nil {
+			return nil, err
+		}
+		cl, has := siblings[cluster]
+		if !has {
+			return nil, errors.Errorf("no server for cluster %q", cluster)
+		}
+		return sous.NewStatusPoller(cl, rf, sous.User{}, ls.Child("status-poller")), nil
+	}
+	pe := sous.NewPromotionEngine(sm.StateManager, newDeploymentManager(sm, ls), qs, newPoller, ls.Child("promotion"))
+	pe.Filter = rf
+	pe.Remote = newSiblingDeployer(siblings)
+	return pe, nil
+}
+
+// newDeployScheduler returns a DeployScheduler that makes the scheduled
+// deploys in ss to the clusters this server resolves, through qs.
+func newDeployScheduler(sm *ServerStateManager, qs *sous.R11nQueueSet, ss *ServerScheduleStore, rf *sous.ResolveFilter, ls LogSink) *sous.DeployScheduler {
+	return sous.NewDeployScheduler(sm.StateManager, qs, ss.ScheduleStore, rf, ls.Child("scheduler"))
 }
@@ -0,0 +1,77 @@
+package graph
+
+import (
+	"time"
+
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/server"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+	"github.com/samsalisbury/semv"
+)
+
+// siblingDeployer is a sous.RemoteDeployer that deploys through the
+// /single-deployment resource of the sibling server of each cluster, as
+// `sous deploy` does.
+type siblingDeployer struct {
+	clients  ClientBundle
+	interval time.Duration
+	timeout  time.Duration
+}
+
+func newSiblingDeployer(clients ClientBundle) *siblingDeployer {
+	return &siblingDeployer{clients: clients, interval: time.Second, timeout: sous.PromotionPollTimeout}
+}
+
+// Deploy implements sous.RemoteDeployer on siblingDeployer.
+func (sd *siblingDeployer) Deploy(did sous.DeploymentID, version semv.Version, user sous.User) error {
+	cl, has := sd.clients[did.Cluster]
+	if !has {
+		return errors.Errorf("no server for cluster %q", did.Cluster)
+	}
+	body := server.SingleDeploymentBody{}
+	updater, err := cl.Retrieve("./single-deployment", did.QueryMap(), &body, user.HTTPHeaders())
+	if err != nil {
+		return errors.Wrapf(err, "getting deployment from the server for %q", did.Cluster)
+	}
+	if body.Deployment == nil {
+		return errors.Errorf("the server for %q returned no deployment of %s", did.Cluster, did)
+	}
+	body.Deployment.Version = version
+	updated, err := updater.Update(body, user.HTTPHeaders())
+	if err != nil {
+		return errors.Wrapf(err, "updating deployment on the server for %q", did.Cluster)
+	}
+	if updated.Location() == "" {
+		// The deployment was already at version.
+		return nil
+	}
+	return sd.await(cl, restful.AbsoluteLocation(updated.Location()))
+}
+
+// await polls the deploy queued at location until it is rectified, or the
+// timeout passes.
+func (sd *siblingDeployer) await(cl restful.HTTPClient, location string) error {
+	deadline := time.Now().Add(sd.timeout)
+	for time.Now().Before(deadline) {
+		response := dto.R11nResponse{}
+		if _, err := cl.Retrieve(location, nil, &response, nil); err != nil {
+			return errors.Wrapf(err, "polling %s", location)
+		}
+		if rez := response.Resolution; rez != nil {
+			if rez.Error != nil {
+				return rez.Error
+			}
+			if response.QueuePosition < 0 && rez.DeployState != nil &&
+				(rez.Desc == sous.CreateDiff || rez.Desc == sous.ModifyDiff) {
+				if rez.DeployState.Status != sous.DeployStatusActive {
+					return errors.Errorf("deploy finished %s", rez.DeployState.Status)
+				}
+				return nil
+			}
+		}
+		time.Sleep(sd.interval)
+	}
+	return errors.Errorf("deploy at %s not finished after %s", location, sd.timeout)
+}
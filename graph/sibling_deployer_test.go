@@ -0,0 +1,35 @@
+package graph
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opentable/sous/config"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSiblingDeployer_Deploy_noDeployment(t *testing.T) {
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	ac := config.AuthConfig{TokenSecret: "sekrit", AdminGroup: "admins"}
+	cl, err := newSiblingClient("other", srv.URL, ac, LogSink{logging.SilentLogSet()})
+	require.NoError(t, err)
+	sd := newSiblingDeployer(ClientBundle{"other": cl})
+
+	did := sous.DeploymentID{ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/opentable/example"}}, Cluster: "other"}
+	err = sd.Deploy(did, semv.MustParse("1.2.3"), sous.User{Name: "judson"})
+	assert.Error(t, err)
+	assert.True(t, strings.HasPrefix(auth, "Bearer "), "server credentials not sent: %q", auth)
+}
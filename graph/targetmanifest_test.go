@@ -176,7 +176,7 @@ func TestNewTargetManifest_Existing(t *testing.T) {
 	require.NoError(t, err)
 
 	if tm.Source != sl {
-		t.Errorf("unexpected manifest %q", m)
+		t.Errorf("unexpected manifest %v", m)
 	}
 	flaws := tm.Manifest.Validate()
 	if len(flaws) > 0 {
@@ -200,7 +200,7 @@ func TestNewTargetManifest_Existing_withOffset(t *testing.T) {
 	tm, err := newTargetManifest(detected, tmid, &ClientStateManager{StateManager: sm})
 	require.NoError(t, err)
 	if tm.Source != sl {
-		t.Errorf("unexpected manifest %q", m)
+		t.Errorf("unexpected manifest %v", m)
 	}
 	flaws := tm.Manifest.Validate()
 	if len(flaws) > 0 {
@@ -57,7 +57,7 @@ func setupTest(t *testing.T) *integrationSuite {
 
 	suite.T().Logf("New name cache for %q", t.Name())
 	suite.nameCache = suite.newNameCache(suite.ls)
-	suite.client = singularity.NewRectiAgent(suite.nameCache, suite.ls)
+	suite.client = singularity.NewRectiAgent(suite.nameCache, nil, suite.ls)
 	suite.deployer = singularity.NewDeployer(suite.client, suite.ls)
 	return suite
 }
@@ -466,7 +466,7 @@ func TestMissingImage(t *testing.T) {
 	rf := &sous.ResolveFilter{}
 	sr := sous.NewDummyStateManager()
 	sr.State = &stateOne
-	qs := graph.NewR11nQueueSet(suite.deployer, suite.nameCache, rf, &graph.ServerStateManager{sr})
+	qs := graph.NewR11nQueueSet(suite.deployer, suite.nameCache, rf, &graph.ServerStateManager{sr}, &graph.ServerR11nStore{}, nil, nil, graph.LogSink{LogSink: logging.SilentLogSet()})
 	r := sous.NewResolver(suite.deployer, suite.nameCache, rf, suite.ls, qs)
 
 	deploymentsOne, err := stateOne.Deployments()
@@ -528,7 +528,7 @@ func TestResolve(t *testing.T) {
 	rf := &sous.ResolveFilter{}
 	sr := sous.NewDummyStateManager()
 	sr.State = &stateOneTwo
-	qs := graph.NewR11nQueueSet(suite.deployer, suite.nameCache, rf, &graph.ServerStateManager{sr})
+	qs := graph.NewR11nQueueSet(suite.deployer, suite.nameCache, rf, &graph.ServerStateManager{sr}, &graph.ServerR11nStore{}, nil, nil, graph.LogSink{LogSink: logging.SilentLogSet()})
 	r := sous.NewResolver(suite.deployer, suite.nameCache, rf, logsink, qs)
 
 	suite.T().Log("Begining OneTwo")
@@ -595,13 +595,13 @@ func TestResolve(t *testing.T) {
 	// XXX Let's hope this is a temporary solution to a testing issue
 	// The problem is laid out in DCOPS-7625
 	for tries := 100; tries > 0; tries-- {
-		client := singularity.NewRectiAgent(suite.nameCache, logsink)
+		client := singularity.NewRectiAgent(suite.nameCache, nil, logsink)
 		deployer := singularity.NewDeployer(client, logging.SilentLogSet())
 
 		rf := &sous.ResolveFilter{}
 		sr := sous.NewDummyStateManager()
 		sr.State = &stateOneTwo
-		qs := graph.NewR11nQueueSet(suite.deployer, suite.nameCache, rf, &graph.ServerStateManager{sr})
+		qs := graph.NewR11nQueueSet(suite.deployer, suite.nameCache, rf, &graph.ServerStateManager{sr}, &graph.ServerR11nStore{}, nil, nil, graph.LogSink{LogSink: logging.SilentLogSet()})
 		r := sous.NewResolver(deployer, suite.nameCache, rf, logging.SilentLogSet(), qs)
 
 		err := r.Begin(deploymentsTwoThree, clusterDefs.Clusters).Wait()
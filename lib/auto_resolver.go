@@ -178,7 +178,7 @@ func (ar *AutoResolver) resolveOnce(ac announceChannel) {
 	}
 
 	ar.write(func() {
-		ar.currentRecorder = ar.Resolver.Begin(ar.GDM, state.Defs.Clusters)
+		ar.currentRecorder = ar.Resolver.BeginWithDefs(ar.GDM, state.Defs)
 	})
 	defer ar.write(func() {
 		ar.currentRecorder = nil
@@ -0,0 +1,144 @@
+package sous
+
+import (
+	"fmt"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+)
+
+// AutoRollbackUser is the User recorded against changes Sous makes to the GDM
+// when it rolls back a failed deployment.
+var AutoRollbackUser = User{Name: "sous-autorollback", Email: "sous-autorollback@localhost"}
+
+// A RollbackRecord describes an automatic rollback of a failed deployment.
+type RollbackRecord struct {
+	// From is the version which failed to deploy.
+	From SourceID
+	// To is the previously running version written back to the GDM.
+	To SourceID
+	// Reason explains why the deployment was rolled back.
+	Reason string
+	// User is the user recorded against the rollback.
+	User User
+}
+
+func (rr RollbackRecord) String() string {
+	return fmt.Sprintf("rolled back from %s to %s: %s", rr.From, rr.To, rr.Reason)
+}
+
+// AutoRollbackWith enables automatic rollback of this rectification, using dm
+// to update the GDM. Only deployments whose manifest sets AutoRollback are
+// affected. It must be called before Begin.
+func (r *Rectification) AutoRollbackWith(dm DeploymentManager) {
+	r.rollbackManager = dm
+}
+
+func (r *Rectification) autoRollbackEnabled() bool {
+	return r.rollbackManager != nil &&
+		r.Pair.Post != nil && r.Pair.Post.Deployment != nil &&
+		r.Pair.Post.AutoRollback
+}
+
+// recordKnownGood records the version running before this rectification, if
+// it was running successfully.
+func (r *Rectification) recordKnownGood(d Deployer, reg Registry) {
+	if !r.autoRollbackEnabled() {
+		return
+	}
+	if prior := r.Pair.Prior; prior != nil && prior.Deployment != nil {
+		if prior.Status == DeployStatusActive {
+			sid := prior.SourceID
+			r.knownGood = &sid
+		}
+		return
+	}
+
+	post := r.Pair.Post
+	if post.Cluster == nil {
+		return
+	}
+	state, err := d.Status(reg, Clusters{post.ClusterName: post.Cluster}, &r.Pair)
+	if err != nil || state == nil || state.Status != DeployStatusActive {
+		return
+	}
+	sid := state.SourceID
+	r.knownGood = &sid
+}
+
+// autoRollback writes the known good version back to the GDM if this
+// rectification ended in DeployStatusFailed or an aborted rollout.
+func (r *Rectification) autoRollback() {
+	if !r.autoRollbackEnabled() {
+		return
+	}
+	r.RLock()
+	state := r.Resolution.DeployState
+	rezErr := r.Resolution.Error
+	r.RUnlock()
+	if state == nil {
+		return
+	}
+	aborted := false
+	if rezErr != nil {
+		_, aborted = rezErr.error.(*RolloutAbortedError)
+	}
+	if state.Status != DeployStatusFailed && !aborted {
+		return
+	}
+
+	failed := r.Pair.Post.SourceID
+	if r.knownGood == nil || r.knownGood.Equal(failed) {
+		r.reportRollback(logging.WarningLevel,
+			fmt.Sprintf("Not rolling back %s: no previous version known to be good", r.Pair.ID()))
+		return
+	}
+
+	reason := "deploy failed"
+	if rezErr != nil {
+		reason = rezErr.Error()
+	} else if state.ExecutorMessage != "" {
+		reason = state.ExecutorMessage
+	}
+	record := &RollbackRecord{
+		From:   failed,
+		To:     *r.knownGood,
+		Reason: reason,
+		User:   AutoRollbackUser,
+	}
+
+	if err := r.writeRollback(record); err != nil {
+		r.reportRollback(logging.WarningLevel,
+			fmt.Sprintf("Automatic rollback of %s failed: %s", r.Pair.ID(), err), record)
+		return
+	}
+
+	r.Lock()
+	r.Resolution.AutoRollback = record
+	r.Unlock()
+	r.reportRollback(logging.WarningLevel,
+		fmt.Sprintf("Automatically %s %s", r.Pair.ID(), record), record)
+}
+
+func (r *Rectification) writeRollback(record *RollbackRecord) error {
+	dep, err := r.rollbackManager.ReadDeployment(r.Pair.ID())
+	if err != nil {
+		return err
+	}
+	// Someone may have deployed again since; don't undo their change.
+	if !dep.SourceID.Equal(record.From) {
+		return errors.Errorf("GDM now intends %s, not %s", dep.SourceID, record.From)
+	}
+	dep.SourceID.Version = record.To.Version
+	return r.rollbackManager.WriteDeployment(dep, AutoRollbackUser)
+}
+
+func (r *Rectification) reportRollback(level logging.Level, msg string, fields ...interface{}) {
+	logging.Deliver(r.log, append([]interface{}{
+		logging.SousGenericV1,
+		logging.GetCallerInfo(logging.NotHere()),
+		level,
+		logging.ConsoleAndMessage(msg),
+		r.Pair,
+	}, fields...)...)
+}
@@ -0,0 +1,133 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/nyarly/spies"
+	"github.com/opentable/sous/util/logging"
+	"github.com/samsalisbury/semv"
+)
+
+type autoRollbackScenario struct {
+	r          *Rectification
+	deployer   Deployer
+	deployCtrl *spies.Spy
+	dmCtrl     *spies.Spy
+	good, bad  SourceID
+}
+
+func setupAutoRollback(t *testing.T, optIn bool) *autoRollbackScenario {
+	log, _ := logging.NewLogSinkSpy()
+	loc := SourceLocation{Repo: "github.com/opentable/example"}
+	s := &autoRollbackScenario{
+		good: loc.SourceID(semv.MustParse("1.0.0")),
+		bad:  loc.SourceID(semv.MustParse("2.0.0")),
+	}
+
+	post := &Deployment{
+		ClusterName:  "cluster",
+		Cluster:      &Cluster{Name: "cluster"},
+		SourceID:     s.bad,
+		AutoRollback: optIn,
+		DeployConfig: DeployConfig{NumInstances: 1},
+	}
+	s.r = NewRectification(DeployablePair{
+		Post: &Deployable{Deployment: post, BuildArtifact: &BuildArtifact{}},
+	}, log)
+
+	s.deployer, s.deployCtrl = NewDeployerSpy()
+	s.deployCtrl.MatchMethod("Rectify", spies.AnyArgs, DiffResolution{Desc: ModifyDiff})
+
+	dm, dmCtrl := NewDeploymentManagerSpy()
+	dmCtrl.MatchMethod("WriteDeployment", spies.AnyArgs, nil)
+	s.dmCtrl = dmCtrl
+	s.r.AutoRollbackWith(dm)
+	return s
+}
+
+func (s *autoRollbackScenario) intended(version string) {
+	dep := s.r.Pair.Post.Deployment.Clone()
+	dep.SourceID.Version = semv.MustParse(version)
+	s.dmCtrl.MatchMethod("ReadDeployment", spies.AnyArgs, dep, nil)
+}
+
+func (s *autoRollbackScenario) statuses(before, after DeployStatus) {
+	s.deployCtrl.MatchMethod("Status", spies.Once(), &DeployState{
+		Deployment: Deployment{SourceID: s.good},
+		Status:     before,
+	}, nil)
+	s.deployCtrl.MatchMethod("Status", spies.AnyArgs, &DeployState{
+		Deployment:      Deployment{SourceID: s.bad},
+		Status:          after,
+		ExecutorMessage: "health check failed",
+	}, nil)
+}
+
+func (s *autoRollbackScenario) enact() {
+	s.r.enact(s.deployer, &DummyRegistry{}, &ResolveFilter{}, NewDummyStateManager())
+}
+
+func TestAutoRollback_rollsBackFailedDeploy(t *testing.T) {
+	s := setupAutoRollback(t, true)
+	s.statuses(DeployStatusActive, DeployStatusFailed)
+	s.intended("2.0.0")
+	s.enact()
+
+	writes := s.dmCtrl.CallsTo("WriteDeployment")
+	if len(writes) != 1 {
+		t.Fatalf("got %d calls to WriteDeployment, want 1", len(writes))
+	}
+	written := writes[0].PassedArgs().Get(0).(*Deployment)
+	if !written.SourceID.Equal(s.good) {
+		t.Errorf("wrote %s, want %s", written.SourceID, s.good)
+	}
+	if user := writes[0].PassedArgs().Get(1).(User); user != AutoRollbackUser {
+		t.Errorf("wrote as %v, want %v", user, AutoRollbackUser)
+	}
+
+	rb := s.r.Resolution.AutoRollback
+	if rb == nil {
+		t.Fatalf("no rollback recorded")
+	}
+	if rb.Reason != "health check failed" {
+		t.Errorf("got reason %q", rb.Reason)
+	}
+}
+
+func TestAutoRollback_notOptedIn(t *testing.T) {
+	s := setupAutoRollback(t, false)
+	s.deployCtrl.MatchMethod("Status", spies.AnyArgs, &DeployState{
+		Deployment: Deployment{SourceID: s.bad},
+		Status:     DeployStatusFailed,
+	}, nil)
+	s.enact()
+
+	if calls := s.dmCtrl.Calls(); len(calls) != 0 {
+		t.Errorf("unexpected calls to DeploymentManager: %v", calls)
+	}
+}
+
+func TestAutoRollback_noKnownGood(t *testing.T) {
+	s := setupAutoRollback(t, true)
+	s.statuses(DeployStatusFailed, DeployStatusFailed)
+	s.intended("2.0.0")
+	s.enact()
+
+	if writes := s.dmCtrl.CallsTo("WriteDeployment"); len(writes) != 0 {
+		t.Errorf("rolled back to a version that wasn't running")
+	}
+}
+
+func TestAutoRollback_gdmMovedOn(t *testing.T) {
+	s := setupAutoRollback(t, true)
+	s.statuses(DeployStatusActive, DeployStatusFailed)
+	s.intended("3.0.0")
+	s.enact()
+
+	if writes := s.dmCtrl.CallsTo("WriteDeployment"); len(writes) != 0 {
+		t.Errorf("rolled back over a newer deployment")
+	}
+	if s.r.Resolution.AutoRollback != nil {
+		t.Errorf("recorded a rollback that didn't happen")
+	}
+}
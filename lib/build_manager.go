@@ -6,6 +6,7 @@ import (
 
 	"github.com/opentable/sous/util/firsterr"
 	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
 	"github.com/pkg/errors"
 )
 
@@ -17,7 +18,11 @@ type (
 		Selector
 		Labeller
 		Registrar
-		LogSink logging.LogSink
+		// Signer and ProvenanceStore, if both are set, attest to the
+		// provenance of each registered build product.
+		Signer          *ProvenanceSigner
+		ProvenanceStore ProvenanceStore
+		LogSink         logging.LogSink
 	}
 )
 
@@ -38,6 +43,7 @@ func (m *BuildManager) Build() (*BuildResult, error) {
 		func(e *error) { br.Contextualize(bc) },
 		func(e *error) { *e = m.ApplyMetadata(br) },
 		func(e *error) { *e = m.RegisterAndWarnAdvisories(br) },
+		func(e *error) { *e = m.Attest(bc, bp, br) },
 	)
 	return br, errors.Wrap(err, "unable to build")
 }
@@ -51,6 +57,29 @@ func (m *BuildManager) RegisterAndWarnAdvisories(br *BuildResult) error {
 	return m.Register(br)
 }
 
+// Attest signs the Provenance of each product of br that was registered, and
+// stores it alongside the product's image. It does nothing unless both Signer
+// and ProvenanceStore are set.
+func (m *BuildManager) Attest(bc *BuildContext, bp Buildpack, br *BuildResult) error {
+	if m.Signer == nil || m.ProvenanceStore == nil {
+		return nil
+	}
+	for _, prod := range br.Products {
+		if prod.DigestName == "" || prod.Advisories.Contains(IsBuilder) {
+			continue
+		}
+		sp, err := m.Signer.Sign(NewProvenance(bc, bp, prod))
+		if err != nil {
+			return errors.Wrapf(err, "signing provenance of %s", prod.DigestName)
+		}
+		if err := m.ProvenanceStore.PutProvenance(prod.DigestName, sp); err != nil {
+			return errors.Wrapf(err, "storing provenance of %s", prod.DigestName)
+		}
+		messages.ReportLogFieldsMessage("Attested to provenance", logging.InformationLevel, m.LogSink, prod.DigestName, sp.KeyID)
+	}
+	return nil
+}
+
 // OffsetFromWorkdir sets the offset for the BuildManager to be the indicated directory.
 // It's a convenience for command line users who can `sous build <dir>` (and therefore get tab-completion etc)
 func (m *BuildManager) OffsetFromWorkdir(offset string) error {
@@ -98,6 +98,10 @@ type (
 		VersionName  string
 		RevisionName string
 		DigestName   string
+
+		// BaseImages are the images this product was built on, by digest where
+		// the buildpack knows it.
+		BaseImages []string
 	}
 )
 
@@ -0,0 +1,110 @@
+package sous
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// A ClusterSelector selects clusters. It is either a name, which selects the
+// cluster of that name and the clusters in the group of that name, or a
+// comma-separated list of label terms, all of which a cluster's Labels must
+// satisfy: key=value or key!=value. For example, "env=prod,provider!=aws"
+// selects the production clusters not hosted by AWS.
+type ClusterSelector string
+
+// IsLabelSelector returns true if cs selects clusters by their labels.
+func (cs ClusterSelector) IsLabelSelector() bool {
+	return strings.Contains(string(cs), "=")
+}
+
+// Validate returns an error if cs is malformed.
+func (cs ClusterSelector) Validate() error {
+	if strings.TrimSpace(string(cs)) == "" {
+		return errors.New("empty cluster selector")
+	}
+	if !cs.IsLabelSelector() {
+		return nil
+	}
+	for _, term := range strings.Split(string(cs), ",") {
+		key, _, _ := parseLabelTerm(term)
+		if key == "" {
+			return errors.Errorf("cluster selector %q: %q is not key=value or key!=value", cs, term)
+		}
+	}
+	return nil
+}
+
+func parseLabelTerm(term string) (key, value string, negated bool) {
+	i := strings.Index(term, "=")
+	if i < 0 {
+		return "", "", false
+	}
+	key, value = term[:i], term[i+1:]
+	if strings.HasSuffix(key, "!") {
+		key, negated = strings.TrimSuffix(key, "!"), true
+	}
+	return strings.TrimSpace(key), strings.TrimSpace(value), negated
+}
+
+// Matches returns true if cs selects the cluster called name, whose
+// definition is c. c may be nil if it isn't known, in which case only a
+// selector naming the cluster matches.
+func (cs ClusterSelector) Matches(name string, c *Cluster) bool {
+	if !cs.IsLabelSelector() {
+		return string(cs) == name || (c != nil && c.InGroup(string(cs)))
+	}
+	if c == nil {
+		return false
+	}
+	for _, term := range strings.Split(string(cs), ",") {
+		key, value, negated := parseLabelTerm(term)
+		if key == "" {
+			return false
+		}
+		actual, has := c.Labels[key]
+		if negated == (has && actual == value) {
+			return false
+		}
+	}
+	return true
+}
+
+// InGroup returns true if c is in the named group.
+func (c *Cluster) InGroup(group string) bool {
+	for _, g := range c.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns the sorted names of the clusters selected by cs.
+func (cs Clusters) Select(selector ClusterSelector) []string {
+	var names []string
+	for n, c := range cs {
+		if selector.Matches(n, c) {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Groups returns the names of the groups of clusters, sorted.
+func (cs Clusters) Groups() []string {
+	seen := map[string]struct{}{}
+	var groups []string
+	for _, c := range cs {
+		for _, g := range c.Groups {
+			if _, has := seen[g]; !has {
+				seen[g] = struct{}{}
+				groups = append(groups, g)
+			}
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
@@ -0,0 +1,196 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeLabelledClusters() Clusters {
+	return Clusters{
+		"prod-east": {Name: "prod-east", BaseURL: "http://east", Groups: []string{"prod"},
+			Labels: map[string]string{"env": "prod", "region": "us-east", "provider": "aws"}},
+		"prod-west": {Name: "prod-west", BaseURL: "http://west", Groups: []string{"prod"},
+			Labels: map[string]string{"env": "prod", "region": "us-west", "provider": "gcp"}},
+		"ci": {Name: "ci", BaseURL: "http://ci",
+			Labels: map[string]string{"env": "ci", "region": "us-west", "provider": "aws"}},
+	}
+}
+
+func TestClusters_Select(t *testing.T) {
+	cs := makeLabelledClusters()
+	cases := map[ClusterSelector][]string{
+		"ci":                         {"ci"},
+		"prod":                       {"prod-east", "prod-west"},
+		"nope":                       nil,
+		"env=prod":                   {"prod-east", "prod-west"},
+		"region=us-west":             {"ci", "prod-west"},
+		"env=prod,provider!=aws":     {"prod-west"},
+		"env=prod, region = us-east": {"prod-east"},
+		"team=x":                     nil,
+		"team!=x":                    {"ci", "prod-east", "prod-west"},
+	}
+	for sel, expected := range cases {
+		assert.Equal(t, expected, cs.Select(sel), "selector %q", sel)
+	}
+	assert.Equal(t, []string{"prod"}, cs.Groups())
+}
+
+func TestClusterSelector_Validate(t *testing.T) {
+	for _, sel := range []ClusterSelector{"ci", "env=prod", "env=prod,region!=us-east"} {
+		assert.NoError(t, sel.Validate(), "selector %q", sel)
+	}
+	for _, sel := range []ClusterSelector{"", "env=prod,ci", "=prod"} {
+		assert.Error(t, sel.Validate(), "selector %q", sel)
+	}
+}
+
+func TestResolveFilter_SelectClusters(t *testing.T) {
+	cs := makeLabelledClusters()
+	rf := &ResolveFilter{Cluster: NewResolveFieldMatcher("env=prod")}
+	assert.Equal(t, []string{"prod-east", "prod-west"}, rf.ClusterNames(cs))
+	assert.False(t, rf.SelectsSingleCluster())
+
+	// Deployments read from a cluster may not carry its labels.
+	selected := rf.SelectClusters(cs)
+	assert.True(t, selected.FilterDeployment(&Deployment{ClusterName: "prod-west"}))
+	assert.False(t, selected.FilterDeployment(&Deployment{ClusterName: "ci"}))
+	assert.False(t, rf.FilterDeployment(&Deployment{ClusterName: "prod-west"}))
+
+	_, err := rf.DeploymentID(ManifestID{Source: project1})
+	assert.Error(t, err)
+}
+
+func TestDefs_Validate_groupNamesCluster(t *testing.T) {
+	cs := makeLabelledClusters()
+	cs["ci"].Groups = []string{"prod-east"}
+	flaws := Defs{Clusters: cs}.Validate()
+	require.Len(t, flaws, 1)
+	assert.Contains(t, flaws[0].(GenericFlaw).Desc, `"prod-east" is the name of both a cluster and a group`)
+}
+
+func makeSelectorManifest() *Manifest {
+	return &Manifest{
+		Source: project1,
+		Owners: []string{"owner1"},
+		Kind:   ManifestKindService,
+		Deployments: DeploySpecs{
+			"env=prod": {
+				Version:      semv.MustParse("1.0.0"),
+				DeployConfig: DeployConfig{NumInstances: 4},
+			},
+			"ci": {
+				Version:      semv.MustParse("1.1.0"),
+				DeployConfig: DeployConfig{NumInstances: 1},
+			},
+		},
+	}
+}
+
+func TestManifest_SelectorDeployments(t *testing.T) {
+	defs := Defs{Clusters: makeLabelledClusters()}
+	original := NewManifests(makeSelectorManifest())
+	ds, err := original.Deployments(defs)
+	require.NoError(t, err)
+	require.Equal(t, 3, ds.Len())
+	for _, cn := range []string{"prod-east", "prod-west"} {
+		d, ok := ds.Get(DeploymentID{ManifestID: ManifestID{Source: project1}, Cluster: cn})
+		require.True(t, ok, cn)
+		assert.Equal(t, 4, d.NumInstances)
+		assert.Equal(t, "1.0.0", d.SourceID.Version.String())
+	}
+
+	ms, err := ds.PutbackManifests(defs, original, logging.SilentLogSet())
+	require.NoError(t, err)
+	m, _ := ms.Get(ManifestID{Source: project1})
+	if different, diffs := makeSelectorManifest().Diff(m); different {
+		t.Errorf("round trip changed the manifest: %v", diffs)
+	}
+
+	// Changing one of the selected clusters gives it its own key.
+	did := DeploymentID{ManifestID: ManifestID{Source: project1}, Cluster: "prod-west"}
+	d, _ := ds.Get(did)
+	d.SourceID.Version = semv.MustParse("1.1.0")
+	ds.Set(did, d)
+	ms, err = ds.PutbackManifests(defs, original, logging.SilentLogSet())
+	require.NoError(t, err)
+	m, _ = ms.Get(ManifestID{Source: project1})
+	expected := makeSelectorManifest()
+	expected.Deployments["prod-west"] = DeploySpec{
+		Version:      semv.MustParse("1.1.0"),
+		DeployConfig: DeployConfig{NumInstances: 4},
+	}
+	if different, diffs := expected.Diff(m); different {
+		t.Errorf("putback of a changed deployment: %v", diffs)
+	}
+
+	// Changing all of them changes the selector's spec.
+	did = DeploymentID{ManifestID: ManifestID{Source: project1}, Cluster: "prod-east"}
+	d, _ = ds.Get(did)
+	d.SourceID.Version = semv.MustParse("1.1.0")
+	ds.Set(did, d)
+	ms, err = ds.PutbackManifests(defs, original, logging.SilentLogSet())
+	require.NoError(t, err)
+	m, _ = ms.Get(ManifestID{Source: project1})
+	expected = makeSelectorManifest()
+	expected.Deployments["env=prod"] = DeploySpec{
+		Version:      semv.MustParse("1.1.0"),
+		DeployConfig: DeployConfig{NumInstances: 4},
+	}
+	if different, diffs := expected.Diff(m); different {
+		t.Errorf("putback of all selected deployments: %v", diffs)
+	}
+}
+
+func TestManifest_SelectorDeployments_overlap(t *testing.T) {
+	defs := Defs{Clusters: makeLabelledClusters()}
+	m := makeSelectorManifest()
+	m.Deployments["region=us-west"] = DeploySpec{Version: semv.MustParse("1.0.0")}
+	_, err := DeploymentsFromManifest(defs, m)
+	assert.Error(t, err)
+
+	// An explicit cluster key takes precedence over a selector.
+	m = makeSelectorManifest()
+	m.Deployments["prod-east"] = DeploySpec{Version: semv.MustParse("2.0.0")}
+	ds, err := DeploymentsFromManifest(defs, m)
+	require.NoError(t, err)
+	d, _ := ds.Get(DeploymentID{ManifestID: ManifestID{Source: project1}, Cluster: "prod-east"})
+	assert.Equal(t, "2.0.0", d.SourceID.Version.String())
+}
+
+func TestManifest_SetDeploySpec(t *testing.T) {
+	defs := Defs{Clusters: makeLabelledClusters()}
+	m := makeSelectorManifest()
+	spec, key, ok := m.DeploySpecFor(defs, "prod-west")
+	require.True(t, ok)
+	assert.Equal(t, "env=prod", key)
+	assert.Equal(t, 4, spec.NumInstances)
+
+	// Setting the spec of one of the selected clusters gives it its own key.
+	spec.Version = semv.MustParse("1.1.0")
+	m.SetDeploySpec(defs, "prod-west", spec)
+	assert.Equal(t, "1.0.0", m.Deployments["env=prod"].Version.String())
+	assert.Equal(t, "1.1.0", m.Deployments["prod-west"].Version.String())
+	ms, err := NewManifests(m).Deployments(defs)
+	require.NoError(t, err)
+	putback, err := ms.PutbackManifests(defs, NewManifests(m), logging.SilentLogSet())
+	require.NoError(t, err)
+	pm, _ := putback.Get(m.ID())
+	if different, diffs := m.Diff(pm); different {
+		t.Errorf("putback changed the manifest: %v", diffs)
+	}
+
+	// A selector of a single cluster keeps its key.
+	defs.Clusters["prod-east"].Labels["canary"] = "true"
+	m = makeSelectorManifest()
+	m.Deployments["canary=true"] = m.Deployments["env=prod"]
+	delete(m.Deployments, "env=prod")
+	m.Deployments["prod-west"] = m.Deployments["canary=true"]
+	m.SetDeploySpec(defs, "prod-east", spec)
+	assert.Equal(t, "1.1.0", m.Deployments["canary=true"].Version.String())
+	_, has := m.Deployments["prod-east"]
+	assert.False(t, has)
+}
@@ -10,6 +10,14 @@ const ClusterNameLabel = "com.opentable.sous.clustername"
 // SingularityDeployMetadataFlavor defines the namespace for storing a Sous Flavor in SingularityDeploy metadata.
 const FlavorLabel = "com.opentable.sous.flavor"
 
+// RolloutLabel defines the namespace for storing a Sous Rollout in SingularityDeploy metadata.
+const RolloutLabel = "com.opentable.sous.rollout"
+
+// SecretsLabel defines the namespace for storing the SecretRefs resolved into
+// a deploy's Env in its metadata (a SingularityDeploy's metadata, a Kubernetes
+// object's annotations or a Nomad job's meta), so that they can be restored.
+const SecretsLabel = "com.opentable.sous.secrets"
+
 // RepoLabel is the metadata fieldname that records the version control repository URL of a Sous-controlled service.
 const RepoLabel = "com.opentable.sous.repo_url"
 
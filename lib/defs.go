@@ -2,6 +2,8 @@ package sous
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/opentable/sous/util/restful"
 )
@@ -45,6 +47,9 @@ func (ds *Defs) Diff(o *Defs) []string {
 	vs = append(vs, prefixed("resource ", ds.Resources.Diff(o.Resources))...)
 	vs = append(vs, prefixed("metadata ", ds.Metadata.Diff(o.Metadata))...)
 	vs = append(vs, prefixed("envdefs ", ds.EnvVars.Diff(o.EnvVars))...)
+	vs = append(vs, prefixed("freezes ", ds.Freezes.Diff(o.Freezes))...)
+	vs = append(vs, prefixed("pauses ", ds.Pauses.Diff(o.Pauses))...)
+	vs = append(vs, prefixed("trusted keys ", ds.TrustedKeys.Diff(o.TrustedKeys))...)
 
 	return vs
 }
@@ -137,6 +142,69 @@ func (fd FieldDefinition) Diff(o FieldDefinition) []string {
 	return ds
 }
 
+// Diff reports the differences.
+func (fws FreezeWindows) Diff(os FreezeWindows) []string {
+	if len(fws) != len(os) {
+		return []string{"lengths differ"}
+	}
+	vs := []string{}
+	for i, fw := range fws {
+		o := os[i]
+		if fw.Reason != o.Reason {
+			vs = append(vs, fmt.Sprintf("window %d reasons differ", i))
+		}
+		if !fw.Start.Equal(o.Start) || !fw.End.Equal(o.End) {
+			vs = append(vs, fmt.Sprintf("window %d times differ", i))
+		}
+		if strings.Join(fw.Clusters, ",") != strings.Join(o.Clusters, ",") {
+			vs = append(vs, fmt.Sprintf("window %d clusters differ", i))
+		}
+		if strings.Join(fw.ExemptOwners, ",") != strings.Join(o.ExemptOwners, ",") {
+			vs = append(vs, fmt.Sprintf("window %d exempt owners differ", i))
+		}
+	}
+	return vs
+}
+
+// Diff reports the differences.
+func (ps Pauses) Diff(os Pauses) []string {
+	if len(ps) != len(os) {
+		return []string{"lengths differ"}
+	}
+	vs := []string{}
+	for i, p := range ps {
+		o := os[i]
+		if !p.SameTarget(o) {
+			vs = append(vs, fmt.Sprintf("pause %d targets differ", i))
+		}
+		if p.Reason != o.Reason || p.User != o.User {
+			vs = append(vs, fmt.Sprintf("pause %d reasons differ", i))
+		}
+		if !p.Since.Equal(o.Since) {
+			vs = append(vs, fmt.Sprintf("pause %d times differ", i))
+		}
+	}
+	return vs
+}
+
+// Diff reports the differences between two TrustedKeys.
+func (tk TrustedKeys) Diff(o TrustedKeys) []string {
+	vs := []string{}
+	for _, n := range tk.Names() {
+		if ok, has := o[n]; !has {
+			vs = append(vs, "missing "+n)
+		} else if ok != tk[n] {
+			vs = append(vs, "key differs: "+n)
+		}
+	}
+	for _, n := range o.Names() {
+		if _, has := tk[n]; !has {
+			vs = append(vs, "extra "+n)
+		}
+	}
+	return vs
+}
+
 func prefixed(prefix string, in []string) []string {
 	out := []string{}
 	for _, v := range in {
@@ -172,6 +240,22 @@ func (c *Cluster) Diff(oc *Cluster) []string {
 
 	vs = append(vs, prefixed("startup ", c.Startup.diff(oc.Startup))...)
 
+	if len(c.Labels) != len(oc.Labels) {
+		vs = append(vs, "Labels map different sizes")
+	} else {
+		for k, v := range c.Labels {
+			if ov, has := oc.Labels[k]; !has || v != ov {
+				vs = append(vs, "label differs: "+k)
+			}
+		}
+	}
+	if strings.Join(c.Groups, ",") != strings.Join(oc.Groups, ",") {
+		vs = append(vs, "groups differ")
+	}
+	if c.RequireSignedImages != oc.RequireSignedImages {
+		vs = append(vs, "signed image requirement differs")
+	}
+
 	if len(c.AllowedAdvisories) != len(oc.AllowedAdvisories) {
 		vs = append(vs, "advisories whitelist length differs")
 	} else {
@@ -188,3 +272,135 @@ func (c *Cluster) Diff(oc *Cluster) []string {
 
 	return vs
 }
+
+// Validate checks that the types in ds are known, that the defaults of its
+// field definitions are of their types, and that the Env defaults of its
+// clusters are of the types in EnvVars.
+func (ds Defs) Validate() []Flaw {
+	var flaws []Flaw
+	for _, ed := range ds.EnvVars {
+		if _, _, err := ed.Type.Kind(); err != nil {
+			flaws = append(flaws, FatalFlaw("Defs EnvVars %q: %v", ed.Name, err))
+		}
+	}
+	flaws = append(flaws, ds.Resources.validate("Resources")...)
+	flaws = append(flaws, ds.Metadata.validate("Metadata")...)
+	for _, g := range ds.Clusters.Groups() {
+		if _, has := ds.Clusters[g]; has {
+			flaws = append(flaws, FatalFlaw("Defs Clusters: %q is the name of both a cluster and a group", g))
+		}
+	}
+	for _, p := range ds.Pauses {
+		if _, has := ds.Clusters[p.Cluster]; !has {
+			flaws = append(flaws, FatalFlaw("Defs Pauses: cluster %q is not defined", p.Cluster))
+		}
+	}
+	flaws = append(flaws, ds.TrustedKeys.validate()...)
+	for _, cn := range ds.Clusters.Names() {
+		if ds.Clusters[cn].RequireSignedImages && len(ds.TrustedKeys) == 0 {
+			flaws = append(flaws, FatalFlaw("Defs Clusters: %q requires signed images, but there are no TrustedKeys", cn))
+		}
+		env := Env{}
+		for n, v := range ds.Clusters[cn].Env {
+			env[n] = string(v)
+		}
+		for _, f := range ds.EnvVars.check(env) {
+			f.ClusterName = cn
+			flaws = append(flaws, f)
+		}
+	}
+	return flaws
+}
+
+// ValidateManifest checks the Env, Resources and Metadata of each of m's
+// deployments against the types defined for them in ds. Values without a
+// definition aren't checked.
+func (ds Defs) ValidateManifest(m *Manifest) []Flaw {
+	var flaws []Flaw
+	clusters := make([]string, 0, len(m.Deployments))
+	for cn := range m.Deployments {
+		clusters = append(clusters, cn)
+	}
+	sort.Strings(clusters)
+	for _, cn := range clusters {
+		spec := m.Deployments[cn]
+		var fs []*InvalidValueFlaw
+		fs = append(fs, ds.EnvVars.check(spec.Env)...)
+		fs = append(fs, ds.Resources.check("Resources", spec.Resources)...)
+		fs = append(fs, ds.Metadata.check("Metadata", spec.Metadata)...)
+		for _, f := range fs {
+			f.ManifestID = m.ID().String()
+			f.ClusterName = cn
+			flaws = append(flaws, f)
+		}
+	}
+	flaws = append(flaws, ds.validatePromotion(m)...)
+	return flaws
+}
+
+// validatePromotion checks that each stage of the Promotion of m only names
+// clusters m deploys to.
+func (ds Defs) validatePromotion(m *Manifest) []Flaw {
+	var flaws []Flaw
+	if m.Promotion == nil {
+		return flaws
+	}
+	keys, err := m.DeploymentKeys(ds)
+	if err != nil {
+		return append(flaws, FatalFlaw("manifest %q: %v", m.ID(), err))
+	}
+	for i, s := range m.Promotion.Stages {
+		for _, c := range s.Clusters {
+			if _, has := keys[c]; !has {
+				flaws = append(flaws, FatalFlaw("manifest %q promotion stage %d names cluster %q, which it isn't deployed to", m.ID(), i+1, c))
+			}
+		}
+	}
+	return flaws
+}
+
+func (fds FieldDefinitions) validate(field string) []Flaw {
+	var flaws []Flaw
+	for _, fd := range fds {
+		if _, _, err := fd.Type.Kind(); err != nil {
+			flaws = append(flaws, FatalFlaw("Defs %s %q: %v", field, fd.Name, err))
+		} else if fd.Default != "" {
+			if err := fd.Type.Check(fd.Default); err != nil {
+				flaws = append(flaws, FatalFlaw("Defs %s %q default: %v", field, fd.Name, err))
+			}
+		}
+	}
+	return flaws
+}
+
+func (evs EnvDefs) check(env Env) []*InvalidValueFlaw {
+	var flaws []*InvalidValueFlaw
+	for _, ed := range evs {
+		v, has := env[ed.Name]
+		if !has {
+			continue
+		}
+		// Secrets are only known once they're resolved.
+		if _, is := ParseSecretRef(v); is {
+			continue
+		}
+		if err := ed.Type.Check(v); err != nil {
+			flaws = append(flaws, &InvalidValueFlaw{Field: "Env", Key: ed.Name, Err: err})
+		}
+	}
+	return flaws
+}
+
+func (fds FieldDefinitions) check(field string, values map[string]string) []*InvalidValueFlaw {
+	var flaws []*InvalidValueFlaw
+	for _, fd := range fds {
+		v, has := values[fd.Name]
+		if !has {
+			continue
+		}
+		if err := fd.Type.Check(v); err != nil {
+			flaws = append(flaws, &InvalidValueFlaw{Field: field, Key: fd.Name, Err: err})
+		}
+	}
+	return flaws
+}
@@ -31,6 +31,8 @@ type (
 		Volumes Volumes
 		// Startup containts healthcheck options for this deploy.
 		Startup Startup `yaml:",omitempty"`
+		// Rollout configures how new versions replace old ones.
+		Rollout Rollout `yaml:",omitempty"`
 		// Schedule is a cronjob-format schedule for jobs.
 		Schedule string
 
@@ -78,6 +80,8 @@ func (dc *DeployConfig) Validate() []Flaw {
 
 	flaws = append(flaws, dc.Startup.Validate()...)
 
+	flaws = append(flaws, dc.Rollout.Validate()...)
+
 	for _, f := range flaws {
 		f.AddContext("deploy config", dc)
 	}
@@ -153,6 +157,7 @@ func (dc *DeployConfig) Diff(o DeployConfig) (bool, []string) {
 			dc.SingularityRequestID, o.SingularityRequestID))
 	}
 	diffs = append(diffs, dc.Startup.diff(o.Startup)...)
+	diffs = append(diffs, dc.Rollout.diff(o.Rollout)...)
 	return len(diffs) != 0, diffs
 }
 
@@ -183,6 +188,7 @@ func (dc DeployConfig) Clone() DeployConfig {
 	dc.Resources = dc.Resources.Clone()
 	dc.Metadata = dc.Metadata.Clone()
 	dc.Volumes = dc.Volumes.Clone()
+	dc.Rollout = dc.Rollout.Clone()
 	return dc
 }
 
@@ -250,6 +256,12 @@ func flattenDeployConfigs(dcs []DeployConfig) DeployConfig {
 			break
 		}
 	}
+	for _, c := range dcs {
+		if c.Rollout.Enabled() {
+			dc.Rollout = c.Rollout
+			break
+		}
+	}
 	for _, c := range dcs {
 		for n, v := range c.Resources {
 			if _, set := dc.Resources[n]; !set {
@@ -267,8 +279,10 @@ func flattenDeployConfigs(dcs []DeployConfig) DeployConfig {
 			}
 		}
 
-		dc.Startup = c.Startup
-		dc.SingularityRequestID = c.SingularityRequestID
+		dc.Startup = c.Startup.MergeDefaults(dc.Startup)
+		if dc.SingularityRequestID == "" {
+			dc.SingularityRequestID = c.SingularityRequestID
+		}
 	}
 	return dc
 }
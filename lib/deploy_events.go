@@ -0,0 +1,156 @@
+package sous
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// A DeployEvent reports progress of deployments on a server, as it happens.
+	DeployEvent struct {
+		Kind DeployEventKind
+		At   time.Time
+		// DeploymentID and R11nID identify the rectification a phase or
+		// resolution event is about.
+		DeploymentID *DeploymentID `json:",omitempty"`
+		R11nID       R11nID        `json:",omitempty"`
+		// Phase is set on phase events, and is R11nFinished on resolution
+		// events.
+		Phase R11nPhase `json:",omitempty"`
+		// Rollout is the progress of a progressive rollout, if any.
+		Rollout *RolloutProgress `json:",omitempty"`
+		// Resolution is set on resolution events.
+		Resolution *DiffResolution `json:",omitempty"`
+		// Status is set on status events. It is finished once Status.Finished
+		// is set.
+		Status *ResolveStatus `json:",omitempty"`
+	}
+
+	// DeployEventKind is the kind of a DeployEvent.
+	DeployEventKind string
+
+	// R11nPhase is how far a rectification has got.
+	R11nPhase string
+
+	// DeployEvents publishes DeployEvents to any number of subscribers. The
+	// nil *DeployEvents is valid, and publishes nothing.
+	DeployEvents struct {
+		sync.Mutex
+		subs map[chan DeployEvent]struct{}
+	}
+)
+
+const (
+	// DeployEventPhase reports a rectification moving to a new phase.
+	DeployEventPhase = DeployEventKind("phase")
+	// DeployEventResolution reports the resolution of a rectification.
+	DeployEventResolution = DeployEventKind("resolution")
+	// DeployEventStatus reports an update to the ResolveStatus of the
+	// server's current resolve cycle.
+	DeployEventStatus = DeployEventKind("status")
+
+	// R11nStarted means a rectification has been taken off its queue.
+	R11nStarted = R11nPhase("started")
+	// R11nDeploying means changes have been sent to the cluster, and the
+	// rectification is waiting for the deployment to become active.
+	R11nDeploying = R11nPhase("deploying")
+	// R11nRollingOut means a progressive rollout has made progress.
+	R11nRollingOut = R11nPhase("rolling-out")
+	// R11nFinished means a rectification has been resolved.
+	R11nFinished = R11nPhase("finished")
+)
+
+// deployEventBuffer is the number of events a subscriber can fall behind by
+// before it misses events.
+const deployEventBuffer = 64
+
+// NewDeployEvents returns a DeployEvents with no subscribers.
+func NewDeployEvents() *DeployEvents {
+	return &DeployEvents{subs: map[chan DeployEvent]struct{}{}}
+}
+
+// Subscribe returns a channel of the events published from now on, and a
+// function to call to unsubscribe. Events are dropped, rather than delaying
+// publication, for subscribers that fall behind.
+func (de *DeployEvents) Subscribe() (<-chan DeployEvent, func()) {
+	ch := make(chan DeployEvent, deployEventBuffer)
+	de.Lock()
+	defer de.Unlock()
+	de.subs[ch] = struct{}{}
+	return ch, func() {
+		de.Lock()
+		defer de.Unlock()
+		if _, ok := de.subs[ch]; ok {
+			delete(de.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish sends ev to every subscriber. If ev.At isn't set, it is set to now.
+func (de *DeployEvents) Publish(ev DeployEvent) {
+	if de == nil {
+		return
+	}
+	if ev.At.IsZero() {
+		ev.At = time.Now()
+	}
+	de.Lock()
+	defer de.Unlock()
+	for ch := range de.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// publishing returns true if anyone might be listening to de, so that
+// expensive events needn't be built otherwise.
+func (de *DeployEvents) publishing() bool {
+	if de == nil {
+		return false
+	}
+	de.Lock()
+	defer de.Unlock()
+	return len(de.subs) > 0
+}
+
+// PublishTo makes this rectification publish its progress to events, as the
+// rectification with ID id. It must be called before Begin.
+func (r *Rectification) PublishTo(events *DeployEvents, id R11nID) {
+	r.events = events
+	r.r11nID = id
+}
+
+func (r *Rectification) publishPhase(phase R11nPhase, rollout *RolloutProgress) {
+	if r.events == nil {
+		return
+	}
+	did := r.Pair.ID()
+	r.events.Publish(DeployEvent{
+		Kind:         DeployEventPhase,
+		DeploymentID: &did,
+		R11nID:       r.r11nID,
+		Phase:        phase,
+		Rollout:      rollout,
+	})
+}
+
+func (r *Rectification) publishResolution() {
+	if r.events == nil {
+		return
+	}
+	did := r.Pair.ID()
+	r.RLock()
+	rez := r.Resolution
+	r.RUnlock()
+	r.events.Publish(DeployEvent{
+		Kind:         DeployEventResolution,
+		DeploymentID: &did,
+		R11nID:       r.r11nID,
+		Phase:        R11nFinished,
+		Rollout:      r.RolloutProgress(),
+		Resolution:   &rez,
+	})
+}
@@ -0,0 +1,166 @@
+package sous
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+)
+
+func TestDeployEvents(t *testing.T) {
+	de := NewDeployEvents()
+	if de.publishing() {
+		t.Errorf("publishing with no subscribers")
+	}
+	events, cancel := de.Subscribe()
+	if !de.publishing() {
+		t.Errorf("not publishing with a subscriber")
+	}
+
+	de.Publish(DeployEvent{Kind: DeployEventPhase, Phase: R11nStarted})
+	select {
+	case ev := <-events:
+		if ev.Phase != R11nStarted || ev.At.IsZero() {
+			t.Errorf("got %#v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event not received")
+	}
+
+	// A subscriber that falls behind misses events, rather than blocking.
+	for i := 0; i < deployEventBuffer+1; i++ {
+		de.Publish(DeployEvent{Kind: DeployEventPhase})
+	}
+
+	cancel()
+	n := 0
+	for range events {
+		n++
+	}
+	if n != deployEventBuffer {
+		t.Errorf("got %d buffered events; want %d", n, deployEventBuffer)
+	}
+	if de.publishing() {
+		t.Errorf("publishing after unsubscribing")
+	}
+
+	var nilEvents *DeployEvents
+	nilEvents.Publish(DeployEvent{})
+}
+
+func TestRectification_PublishTo(t *testing.T) {
+	de := NewDeployEvents()
+	events, cancel := de.Subscribe()
+	defer cancel()
+
+	r := makeTestR11nWithRepo("github.com/opentable/example")
+	r.PublishTo(de, "r11n")
+	r.publishPhase(R11nStarted, nil)
+	r.Resolution.Desc = ModifyDiff
+	r.publishResolution()
+
+	ev := <-events
+	if ev.Kind != DeployEventPhase || ev.Phase != R11nStarted || ev.R11nID != "r11n" || ev.DeploymentID == nil {
+		t.Errorf("got first event %#v", ev)
+	}
+	ev = <-events
+	if ev.Kind != DeployEventResolution || ev.Phase != R11nFinished || ev.Resolution == nil || ev.Resolution.Desc != ModifyDiff {
+		t.Errorf("got second event %#v", ev)
+	}
+}
+
+func streamingPollerServer(t *testing.T, stream bool) (*httptest.Server, *[]string) {
+	repoName := "github.com/opentable/example"
+	dep := `{
+		"clustername": "main",
+		"sourceid": {"location": "` + repoName + `", "version": "1.0.1+1234"},
+		"flavor": "canhaz"
+	}`
+	status := `{
+		"started": "2018-10-11T14:27:05.975369893Z",
+		"finished": "2018-10-11T14:28:05.975369893Z",
+		"intended": [` + dep + `],
+		"log": [{"manifestid": "` + repoName + `~canhaz", "desc": "unchanged"}]
+	}`
+
+	var mu sync.Mutex
+	requested := &[]string{}
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		*requested = append(*requested, r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		case "/servers":
+			rw.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(rw, `{"servers": [{"clustername": "main", "url": %q}]}`, srv.URL)
+		case "/gdm":
+			rw.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(rw, `{"deployments": [%s]}`, dep)
+		case "/status":
+			rw.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(rw, `{"completed": %s, "inprogress": %[1]s}`, status)
+		case "/deploy-events":
+			if !stream {
+				rw.WriteHeader(http.StatusNotFound)
+				return
+			}
+			rw.Header().Set("Content-Type", "text/event-stream")
+			ev, _ := json.Marshal(map[string]json.RawMessage{"Status": json.RawMessage(status)})
+			fmt.Fprintf(rw, "event: status\ndata: %s\n\n", ev)
+			rw.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	return srv, requested
+}
+
+func waitStreamingPoller(t *testing.T, srv *httptest.Server) {
+	t.Helper()
+	cl, err := restful.NewClient(srv.URL, logging.SilentLogSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rf := &ResolveFilter{Repo: NewResolveFieldMatcher("github.com/opentable/example")}
+	rf.SetTag("")
+	poller := NewStatusPoller(cl, rf, User{Name: "Test User"}, logging.SilentLogSet())
+	poller.Streaming = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*PollTimeout)
+	defer cancel()
+	state, err := poller.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != ResolveComplete {
+		t.Errorf("got %s; want %s", state, ResolveComplete)
+	}
+}
+
+func TestStatusPoller_streaming(t *testing.T) {
+	srv, requested := streamingPollerServer(t, true)
+	defer srv.Close()
+	waitStreamingPoller(t, srv)
+	if paths := strings.Join(*requested, " "); strings.Contains(paths, "/status") {
+		t.Errorf("polled /status while streaming: %s", paths)
+	}
+}
+
+func TestStatusPoller_streamingFallsBack(t *testing.T) {
+	srv, requested := streamingPollerServer(t, false)
+	defer srv.Close()
+	waitStreamingPoller(t, srv)
+	if paths := strings.Join(*requested, " "); !strings.Contains(paths, "/deploy-events /status") {
+		t.Errorf("expected fallback to /status, got requests: %s", paths)
+	}
+}
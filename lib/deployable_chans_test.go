@@ -77,7 +77,7 @@ func (nrs *NameResolveTestSuite) TearDownTest() {
 
 func (nrs *NameResolveTestSuite) TestResolveNameGood() {
 	ls, _ := logging.NewLogSinkSpy()
-	da, err := resolveName(nrs.reg, nrs.makeTestDep(), ls)
+	da, err := resolveName(nrs.reg, nrs.makeTestDep(), nil, ls)
 	nrs.NotNil(da)
 	nrs.Nil(err)
 }
@@ -86,7 +86,7 @@ func (nrs *NameResolveTestSuite) TestResolveNameBad() {
 	nrs.reg.FeedArtifact(nil, fmt.Errorf("badness"))
 
 	ls, _ := logging.NewLogSinkSpy()
-	da, err := resolveName(nrs.reg, nrs.makeTestDep(), ls)
+	da, err := resolveName(nrs.reg, nrs.makeTestDep(), nil, ls)
 	nrs.Nil(da.BuildArtifact)
 	nrs.Error(err.Error)
 }
@@ -96,14 +96,14 @@ func (nrs *NameResolveTestSuite) TestResolveNameSkipped() {
 	noInstances.DeployConfig.NumInstances = 0
 
 	ls, _ := logging.NewLogSinkSpy()
-	da, err := resolveName(nrs.reg, noInstances, ls)
+	da, err := resolveName(nrs.reg, noInstances, nil, ls)
 	nrs.Nil(da.BuildArtifact)
 	nrs.Nil(err)
 }
 
 func (nrs *NameResolveTestSuite) TestResolveNameStartChannel() {
 	ls, _ := logging.NewLogSinkSpy()
-	nrs.depChans = nrs.diffChans.ResolveNames(context.Background(), nrs.reg, ls)
+	nrs.depChans = nrs.diffChans.ResolveNames(context.Background(), nrs.reg, nil, ls)
 	nrs.diffChans.Pairs <- nrs.makeTestDepPair(nil, nrs.makeTestDep())
 
 	select {
@@ -120,7 +120,7 @@ func (nrs *NameResolveTestSuite) TestResolveNameStartChannel() {
 
 func (nrs *NameResolveTestSuite) TestResolveNameUpdateChannel() {
 	ls, _ := logging.NewLogSinkSpy()
-	nrs.depChans = nrs.diffChans.ResolveNames(context.Background(), nrs.reg, ls)
+	nrs.depChans = nrs.diffChans.ResolveNames(context.Background(), nrs.reg, nil, ls)
 
 	pair := &DeployablePair{
 		Prior: nrs.makeTestDep(),
@@ -145,7 +145,7 @@ func (nrs *NameResolveTestSuite) TestResolveNameUpdateChannel() {
 func (nrs *NameResolveTestSuite) TestResolveNameStartChannelUnresolved() {
 	nrs.reg.FeedArtifact(nil, fmt.Errorf("not found"))
 	ls, _ := logging.NewLogSinkSpy()
-	nrs.depChans = nrs.diffChans.ResolveNames(context.Background(), nrs.reg, ls)
+	nrs.depChans = nrs.diffChans.ResolveNames(context.Background(), nrs.reg, nil, ls)
 	nrs.diffChans.Pairs <- nrs.makeTestDepPair(nil, nrs.makeTestDep())
 
 	select {
@@ -162,7 +162,7 @@ func (nrs *NameResolveTestSuite) TestResolveNameStopChannelUnresolved() {
 	nrs.reg.FeedArtifact(nil, fmt.Errorf("not found"))
 
 	ls, _ := logging.NewLogSinkSpy()
-	nrs.depChans = nrs.diffChans.ResolveNames(context.Background(), nrs.reg, ls)
+	nrs.depChans = nrs.diffChans.ResolveNames(context.Background(), nrs.reg, nil, ls)
 	nrs.diffChans.Pairs <- nrs.makeTestDepPair(nrs.makeTestDep(), nil)
 
 	select {
@@ -1,6 +1,13 @@
 package sous
 
-import "github.com/nyarly/spies"
+import (
+	"runtime/debug"
+
+	"github.com/nyarly/spies"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
 
 type (
 	// Deployer describes a complete deployment system, which is able to create,
@@ -11,12 +18,33 @@ type (
 		Status(Registry, Clusters, *DeployablePair) (*DeployState, error)
 	}
 
+	// A RolloutDeployer is a Deployer which lets Sous drive progressive
+	// rollouts step by step, rather than leaving them to the scheduler.
+	RolloutDeployer interface {
+		// AdvanceRollout instructs the scheduler to bring the rollout in state
+		// up to targetInstances new instances.
+		AdvanceRollout(pair *DeployablePair, state *DeployState, targetInstances int) error
+		// AbortRollout cancels the rollout in state, leaving the prior version
+		// in place.
+		AbortRollout(pair *DeployablePair, state *DeployState) error
+	}
+
 	// DeployerSpy is a noop deployer.
 	DeployerSpy struct {
 		*spies.Spy
 	}
 )
 
+// RectifyRecover recovers a panic in f, a Deployer method rectifying d,
+// reporting it to log and returning it in err. Deployers defer it.
+func RectifyRecover(d interface{}, f string, err *error, log logging.LogSink) {
+	if r := recover(); r != nil {
+		stack := string(debug.Stack())
+		messages.ReportLogFieldsMessage("Panic", logging.WarningLevel, log, d, f, err, r, stack)
+		*err = errors.Errorf("Panicked: %s; stack trace:\n%s", r, stack)
+	}
+}
+
 // NewDummyDeployer creates a DummyDeployer
 func NewDummyDeployer() Deployer {
 	d, c := NewDeployerSpy()
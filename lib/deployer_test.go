@@ -0,0 +1,25 @@
+package sous
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/opentable/sous/util/logging"
+)
+
+func TestRectifyRecover(t *testing.T) {
+	var err error
+	expectedPrefix := "Panicked: What's that coming over the hill?!; stack trace:\n"
+	ls, _ := logging.NewLogSinkSpy()
+	func() {
+		defer RectifyRecover("something", "TestRectifyRecover", &err, ls)
+		panic("What's that coming over the hill?!")
+	}()
+	if err == nil {
+		t.Fatalf("got nil, want error beginning %q", expectedPrefix)
+	}
+	actual := err.Error()
+	if !strings.HasPrefix(actual, expectedPrefix) {
+		t.Errorf("got error %q; want error with prefix %q", actual, expectedPrefix)
+	}
+}
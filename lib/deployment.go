@@ -36,6 +36,15 @@ type (
 		Owners OwnerSet
 		// Kind is the kind of software that SourceRepo represents.
 		Kind ManifestKind
+		// AutoRollback is copied from the Manifest. It is Sous policy rather
+		// than part of what is deployed, so Diff ignores it.
+		AutoRollback bool
+		// Drift is copied from the Manifest. Like AutoRollback, Diff ignores
+		// it.
+		Drift DriftPolicy
+		// Promotion is copied from the Manifest. Like AutoRollback, Diff
+		// ignores it.
+		Promotion *Promotion
 		// User
 		User User
 	}
@@ -50,6 +59,7 @@ func (d Deployment) Clone() *Deployment {
 	if d.Owners != nil {
 		d.Owners = d.Owners.Clone()
 	}
+	d.Promotion = d.Promotion.Clone()
 	return &d
 }
 
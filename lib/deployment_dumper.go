@@ -19,15 +19,23 @@ func DumpDeployments(writer io.Writer, ds Deployments) {
 	w.Flush()
 }
 
-// DumpDeployStatuses prints a bunch of DeployStates to writer.
-func DumpDeployStatuses(writer io.Writer, ds DeployStates) {
+// DumpDeployStatuses prints a bunch of DeployStates to writer, noting those
+// paused by pauses.
+func DumpDeployStatuses(writer io.Writer, ds DeployStates, pauses Pauses) {
 	w := &tabwriter.Writer{}
 	w.Init(writer, 2, 4, 2, ' ', 0)
 
-	fmt.Fprintln(w, TabbedDeploymentHeaders())
+	fmt.Fprintln(w, TabbedDeploymentHeaders()+"\tPaused")
 
-	for _, d := range ds.Snapshot() {
-		fmt.Fprintln(w, d.Tabbed())
+	for id, d := range ds.Snapshot() {
+		paused := ""
+		if p, is := pauses.Paused(id); is {
+			paused = "by " + p.User
+			if p.Reason != "" {
+				paused += ": " + p.Reason
+			}
+		}
+		fmt.Fprintln(w, d.Tabbed()+"\t"+paused)
 	}
 	w.Flush()
 }
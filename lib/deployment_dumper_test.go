@@ -17,3 +17,18 @@ func TestDeploymentDumper(t *testing.T) {
 	DumpDeployments(io, ds)
 	assert.Regexp(`andromeda`, io.String())
 }
+
+func TestDeployStatusDumper(t *testing.T) {
+	assert := assert.New(t)
+
+	io := &bytes.Buffer{}
+	ds := NewDeployStates()
+	ds.Add(&DeployState{Deployment: Deployment{ClusterName: "andromeda"}})
+	ds.Add(&DeployState{Deployment: Deployment{ClusterName: "pegasus"}})
+	pauses := Pauses{{Cluster: "pegasus", User: "oncall", Reason: "incident"}}
+
+	DumpDeployStatuses(io, ds, pauses)
+	assert.Regexp(`Paused\n`, io.String())
+	assert.Regexp(`andromeda.*\s\n`, io.String())
+	assert.Regexp(`pegasus.*by oncall: incident\n`, io.String())
+}
@@ -81,5 +81,10 @@ func (dm *deploymentManagerDecorator) WriteDeployment(dep *Deployment, user User
 		return err
 	}
 	deps.Set(dep.ID(), dep)
+
+	state.Manifests, err = deps.PutbackManifests(state.Defs, state.Manifests, dm.log)
+	if err != nil {
+		return err
+	}
 	return dm.WriteState(state, user)
 }
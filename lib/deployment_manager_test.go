@@ -44,3 +44,43 @@ func TestDeploymentManager_ReadDeployment(t *testing.T) {
 		t.Errorf("ReadDeployment returned different deployment (diffs: %#v)", diffs)
 	}
 }
+
+func TestDeploymentManager_WriteDeployment(t *testing.T) {
+	innerState := DefaultStateFixture()
+	dummy := &DummyStateManager{
+		State: innerState,
+	}
+	ls, _ := logging.NewLogSinkSpy()
+	dm := MakeDeploymentManager(dummy, ls)
+
+	did := DeploymentID{
+		ManifestID: ManifestID{
+			Source: SourceLocation{
+				Repo: "github.com/user1/repo1",
+				Dir:  "dir1",
+			},
+			Flavor: "flavor1",
+		},
+		Cluster: "cluster1",
+	}
+	deployment, err := dm.ReadDeployment(did)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployment.NumInstances = 17
+
+	if err := dm.WriteDeployment(deployment, User{}); err != nil {
+		t.Fatal(err)
+	}
+	if dummy.WriteCount != 1 {
+		t.Errorf("got %d writes, want 1", dummy.WriteCount)
+	}
+
+	written, err := dm.ReadDeployment(did)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written.NumInstances != 17 {
+		t.Errorf("got NumInstances %d after write, want 17", written.NumInstances)
+	}
+}
@@ -19,6 +19,9 @@ func TestDeploymentDiffAnalysis(t *testing.T) {
 		"Deployment.Cluster.BaseURL",
 		"Deployment.Cluster.Env",
 		"Deployment.Cluster.AllowedAdvisories",
+		"Deployment.Cluster.Labels",
+		"Deployment.Cluster.Groups",
+		"Deployment.Cluster.RequireSignedImages",
 		"Deployment.Cluster.Startup",
 		"Deployment.Cluster.Startup.SkipCheck",
 		"Deployment.Cluster.Startup.CheckReadyURIPath",
@@ -38,6 +41,12 @@ func TestDeploymentDiffAnalysis(t *testing.T) {
 		"Deployment.User",
 		"Deployment.User.Name",
 		"Deployment.User.Email",
+		"Deployment.User.Token",
+		// AutoRollback is Sous policy, not deployed state.
+		"Deployment.AutoRollback",
+		"Deployment.Drift",
+		"Deployment.Promotion",
+		"Deployment.Promotion.Stages",
 		/*
 			"Deployment.Owners",
 			"Deployment.DeployConfig.Args",
@@ -13,6 +13,9 @@ type DeployState struct {
 	ExecutorMessage string
 	ExecutorData    interface{}
 	SchedulerURL    string
+	// RolloutProgress is set by Deployers while a progressive rollout is
+	// underway.
+	RolloutProgress *RolloutProgress
 }
 
 func (ds DeployState) String() string {
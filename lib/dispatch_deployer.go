@@ -0,0 +1,189 @@
+package sous
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+)
+
+// DefaultClusterKind is the Cluster.Kind assumed when a cluster doesn't
+// specify one.
+const DefaultClusterKind = "singularity"
+
+// A DispatchDeployer handles dispatching Deployer calls to the Deployer
+// responsible for each Cluster.Kind, so that a single GDM can drive clusters
+// run by different schedulers.
+type DispatchDeployer struct {
+	// Provenance, if set, refuses to rectify deployments of images whose
+	// provenance it can't verify.
+	Provenance *ProvenanceCheck
+	deployers  map[string]Deployer
+	log        logging.LogSink
+}
+
+// NewDispatchDeployer builds a DispatchDeployer from a map of Cluster.Kind to
+// the Deployer responsible for clusters of that kind.
+func NewDispatchDeployer(deployers map[string]Deployer, ls logging.LogSink) *DispatchDeployer {
+	dd := &DispatchDeployer{
+		deployers: map[string]Deployer{},
+		log:       ls,
+	}
+	for kind, d := range deployers {
+		dd.deployers[kind] = d
+	}
+	return dd
+}
+
+// ClusterKind returns the kind of c, defaulting to DefaultClusterKind.
+func ClusterKind(c *Cluster) string {
+	if c == nil || c.Kind == "" {
+		return DefaultClusterKind
+	}
+	return c.Kind
+}
+
+func (dd *DispatchDeployer) deployerFor(kind string) (Deployer, error) {
+	d, ok := dd.deployers[kind]
+	if !ok {
+		return nil, errors.Errorf("No deployer for cluster kind %q", kind)
+	}
+	return d, nil
+}
+
+func (dd *DispatchDeployer) pairDeployer(pair *DeployablePair) (Deployer, error) {
+	var dep *Deployable
+	switch {
+	case pair.Post != nil:
+		dep = pair.Post
+	case pair.Prior != nil:
+		dep = pair.Prior
+	default:
+		return nil, errors.Errorf("Deployable pair has neither Prior nor Post")
+	}
+	if dep.Deployment == nil {
+		return nil, errors.Errorf("Deployable for %q has no Deployment", pair.ID())
+	}
+	return dd.deployerFor(ClusterKind(dep.Deployment.Cluster))
+}
+
+// RunningDeployments implements Deployer on DispatchDeployer. Clusters whose
+// Kind has no Deployer are logged and skipped rather than failing the call.
+func (dd *DispatchDeployer) RunningDeployments(reg Registry, from Clusters) (DeployStates, error) {
+	byKind := map[string]Clusters{}
+	for name, c := range from {
+		kind := ClusterKind(c)
+		if _, ok := byKind[kind]; !ok {
+			byKind[kind] = Clusters{}
+		}
+		byKind[kind][name] = c
+	}
+
+	kinds := make([]string, 0, len(byKind))
+	for kind := range byKind {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	deps := NewDeployStates()
+	for _, kind := range kinds {
+		d, err := dd.deployerFor(kind)
+		if err != nil {
+			// A misconfigured cluster shouldn't stop us reading the others:
+			// its deployments will fail to rectify on their own.
+			logging.ReportMsg(dd.log, logging.WarningLevel,
+				fmt.Sprintf("Skipping clusters %v: %v", byKind[kind].Names(), err))
+			continue
+		}
+		logging.DebugMsg(dd.log, fmt.Sprintf("DispatchDeployer RunningDeployments %q %T", kind, d))
+		ds, err := d.RunningDeployments(reg, byKind[kind])
+		if err != nil {
+			return deps, errors.Wrap(err, kind)
+		}
+		for _, s := range ds.Snapshot() {
+			deps.Add(s)
+		}
+	}
+	return deps, nil
+}
+
+// Rectify implements Deployer on DispatchDeployer.
+func (dd *DispatchDeployer) Rectify(pair *DeployablePair) DiffResolution {
+	d, err := dd.pairDeployer(pair)
+	if err != nil {
+		return DiffResolution{
+			DeploymentID: pair.ID(),
+			Desc:         "not rectified",
+			Error:        WrapResolveError(err),
+		}
+	}
+	if err := dd.checkProvenance(pair); err != nil {
+		return DiffResolution{
+			DeploymentID: pair.ID(),
+			Desc:         "not rectified",
+			Error:        WrapResolveError(err),
+		}
+	}
+	return d.Rectify(pair)
+}
+
+// checkProvenance verifies the provenance of the image pair deploys, if it
+// deploys a new one.
+func (dd *DispatchDeployer) checkProvenance(pair *DeployablePair) error {
+	if dd.Provenance == nil || pair.Post == nil || pair.Post.NumInstances == 0 {
+		return nil
+	}
+	if k := pair.Kind(); k != AddedKind && k != ModifiedKind {
+		return nil
+	}
+	p, err := dd.Provenance.Check(pair.Post.BuildArtifact)
+	if err != nil {
+		ise := &UnsignedImageError{SourceID: pair.Post.SourceID, Cause: err}
+		if art := pair.Post.BuildArtifact; art != nil {
+			ise.Image = art.DigestReference
+		}
+		return ise
+	}
+	logging.DebugMsg(dd.log, fmt.Sprintf("Verified provenance of %s: built from %s@%s by %s", p.Image, p.Repo, p.Revision, p.Buildpack))
+	return nil
+}
+
+// Status implements Deployer on DispatchDeployer.
+func (dd *DispatchDeployer) Status(reg Registry, from Clusters, pair *DeployablePair) (*DeployState, error) {
+	d, err := dd.pairDeployer(pair)
+	if err != nil {
+		return nil, err
+	}
+	return d.Status(reg, from, pair)
+}
+
+func (dd *DispatchDeployer) rolloutDeployer(pair *DeployablePair) (RolloutDeployer, error) {
+	d, err := dd.pairDeployer(pair)
+	if err != nil {
+		return nil, err
+	}
+	rd, ok := d.(RolloutDeployer)
+	if !ok {
+		return nil, errors.Errorf("%T cannot drive progressive rollouts", d)
+	}
+	return rd, nil
+}
+
+// AdvanceRollout implements RolloutDeployer on DispatchDeployer.
+func (dd *DispatchDeployer) AdvanceRollout(pair *DeployablePair, state *DeployState, targetInstances int) error {
+	rd, err := dd.rolloutDeployer(pair)
+	if err != nil {
+		return err
+	}
+	return rd.AdvanceRollout(pair, state, targetInstances)
+}
+
+// AbortRollout implements RolloutDeployer on DispatchDeployer.
+func (dd *DispatchDeployer) AbortRollout(pair *DeployablePair, state *DeployState) error {
+	rd, err := dd.rolloutDeployer(pair)
+	if err != nil {
+		return err
+	}
+	return rd.AbortRollout(pair, state)
+}
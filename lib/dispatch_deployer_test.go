@@ -0,0 +1,98 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/nyarly/spies"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupDispatchDeployer() (*DispatchDeployer, *spies.Spy, *spies.Spy) {
+	sing, singCtrl := NewDeployerSpy()
+	kube, kubeCtrl := NewDeployerSpy()
+
+	ls, _ := logging.NewLogSinkSpy()
+	dd := NewDispatchDeployer(map[string]Deployer{
+		"singularity": sing,
+		"kubernetes":  kube,
+	}, ls)
+
+	return dd, singCtrl, kubeCtrl
+}
+
+func TestDispatchDeployer_RunningDeployments(t *testing.T) {
+	dd, singCtrl, kubeCtrl := setupDispatchDeployer()
+
+	singDep := &DeployState{Deployment: *DeploymentFixture("sequenced-repo")}
+	kubeDep := &DeployState{Deployment: *DeploymentFixture("sequenced-repo")}
+	singCtrl.MatchMethod("RunningDeployments", spies.AnyArgs, NewDeployStates(singDep), nil)
+	kubeCtrl.MatchMethod("RunningDeployments", spies.AnyArgs, NewDeployStates(kubeDep), nil)
+
+	kc := ClusterFixture("kube")
+	kc.Kind = "kubernetes"
+	unkinded := ClusterFixture("unkinded")
+	unkinded.Kind = ""
+	clusters := Clusters{
+		"sing":     ClusterFixture("sing"),
+		"unkinded": unkinded,
+		"kube":     kc,
+	}
+
+	ds, err := dd.RunningDeployments(NewDummyRegistry(), clusters)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, ds.Len())
+
+	singCalls := singCtrl.CallsTo("RunningDeployments")
+	if assert.Len(t, singCalls, 1) {
+		cs := singCalls[0].PassedArgs().Get(1).(Clusters)
+		assert.ElementsMatch(t, []string{"sing", "unkinded"}, cs.Names())
+	}
+	kubeCalls := kubeCtrl.CallsTo("RunningDeployments")
+	if assert.Len(t, kubeCalls, 1) {
+		cs := kubeCalls[0].PassedArgs().Get(1).(Clusters)
+		assert.Equal(t, []string{"kube"}, cs.Names())
+	}
+}
+
+func TestDispatchDeployer_RunningDeployments_unknownKind(t *testing.T) {
+	dd, singCtrl, _ := setupDispatchDeployer()
+
+	singDep := &DeployState{Deployment: *DeploymentFixture("sequenced-repo")}
+	singCtrl.MatchMethod("RunningDeployments", spies.AnyArgs, NewDeployStates(singDep), nil)
+
+	bad := ClusterFixture("bad")
+	bad.Kind = "kubernetse"
+	clusters := Clusters{
+		"sing": ClusterFixture("sing"),
+		"bad":  bad,
+	}
+
+	ds, err := dd.RunningDeployments(NewDummyRegistry(), clusters)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ds.Len())
+
+	singCalls := singCtrl.CallsTo("RunningDeployments")
+	if assert.Len(t, singCalls, 1) {
+		cs := singCalls[0].PassedArgs().Get(1).(Clusters)
+		assert.Equal(t, []string{"sing"}, cs.Names())
+	}
+}
+
+func TestDispatchDeployer_Rectify(t *testing.T) {
+	dd, singCtrl, kubeCtrl := setupDispatchDeployer()
+	singCtrl.MatchMethod("Rectify", spies.AnyArgs, DiffResolution{Desc: "singularity"})
+	kubeCtrl.MatchMethod("Rectify", spies.AnyArgs, DiffResolution{Desc: "kubernetes"})
+
+	post := DeployableFixture("")
+	post.Deployment.Cluster = ClusterFixture("kube")
+	post.Deployment.Cluster.Kind = "kubernetes"
+
+	rez := dd.Rectify(&DeployablePair{Post: post})
+	assert.Equal(t, ResolutionType("kubernetes"), rez.Desc)
+	assert.Len(t, singCtrl.CallsTo("Rectify"), 0)
+
+	post.Deployment.Cluster.Kind = "nonesuch"
+	rez = dd.Rectify(&DeployablePair{Post: post})
+	assert.NotNil(t, rez.Error)
+}
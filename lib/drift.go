@@ -0,0 +1,247 @@
+package sous
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+)
+
+type (
+	// DriftPolicy is what Sous does about a deployment which has drifted:
+	// one that was changed in its cluster by something other than Sous.
+	DriftPolicy string
+
+	// A DriftRecord describes a deployment whose running state differs from
+	// what Sous last applied to it.
+	DriftRecord struct {
+		DeploymentID DeploymentID
+		// Applied is the deployment Sous last applied.
+		Applied *Deployment
+		// Running is the deployment as it is running, or nil if it was
+		// removed from the cluster.
+		Running *Deployment `json:",omitempty"`
+		// Differences are the differences between Applied and Running.
+		Differences Differences
+		// Detected is when the drift was first seen, and LastSeen when it was
+		// most recently seen.
+		Detected, LastSeen time.Time
+		// Policy is the drift policy of the deployment's manifest.
+		Policy DriftPolicy
+	}
+
+	// DriftDetector remembers the deployment Sous last applied for each
+	// DeploymentID, and records drift when a running deployment differs from
+	// it. The nil *DriftDetector is valid, and detects nothing.
+	DriftDetector struct {
+		sync.RWMutex
+		applied map[DeploymentID]*Deployment
+		drifts  map[DeploymentID]*DriftRecord
+		ls      logging.LogSink
+	}
+)
+
+const (
+	// DriftRevert is the default DriftPolicy: drifted deployments are put
+	// back the way the GDM describes them.
+	DriftRevert = DriftPolicy("")
+	// DriftReport means drift is reported, but left in place until the GDM
+	// changes.
+	DriftReport = DriftPolicy("report")
+)
+
+// Validate returns a flaw if p isn't a known DriftPolicy.
+func (p DriftPolicy) Validate() []Flaw {
+	switch p {
+	default:
+		return []Flaw{FatalFlaw("DriftPolicy %q not valid; want %q or empty", p, DriftReport)}
+	case DriftRevert, DriftReport:
+		return nil
+	}
+}
+
+// NewDriftDetector returns a DriftDetector which hasn't seen any deployments.
+func NewDriftDetector(ls logging.LogSink) *DriftDetector {
+	return &DriftDetector{
+		applied: map[DeploymentID]*Deployment{},
+		drifts:  map[DeploymentID]*DriftRecord{},
+		ls:      ls,
+	}
+}
+
+// RecordRectified records the outcome of rectifying pair. If it succeeded,
+// what it deployed becomes what Sous last applied, and any drift is cleared.
+func (dd *DriftDetector) RecordRectified(pair *DeployablePair, rez DiffResolution) {
+	if dd == nil || rez.Error != nil {
+		return
+	}
+	id := pair.ID()
+	dd.Lock()
+	defer dd.Unlock()
+	switch rez.Desc {
+	default:
+		return
+	case CreateDiff, ModifyDiff:
+		if pair.Post == nil || pair.Post.Deployment == nil {
+			return
+		}
+		dd.applied[id] = pair.Post.Deployment.Clone()
+	case DeleteDiff:
+		delete(dd.applied, id)
+	}
+	dd.clear(id)
+}
+
+// Detect compares actual with what Sous last applied to each of intended,
+// and records which have drifted. Deployments which match intended haven't
+// drifted. Those Sous hasn't applied since it started are otherwise left
+// alone, since there's no telling who changed them.
+func (dd *DriftDetector) Detect(actual DeployStates, intended Deployments) {
+	if dd == nil {
+		return
+	}
+	now := time.Now()
+	dd.Lock()
+	defer dd.Unlock()
+	for id, intent := range intended.Snapshot() {
+		running, isRunning := actual.Get(id)
+		if isRunning && running.Status == DeployStatusPending {
+			// Deployments in progress are compared once they settle.
+			continue
+		}
+		if isRunning && intent.Equal(&running.Deployment) {
+			// Whoever made it so, the deployment is as the GDM intends.
+			dd.applied[id] = intent.Clone()
+			dd.clear(id)
+			continue
+		}
+		applied, known := dd.applied[id]
+		if !known {
+			continue
+		}
+
+		var diffs Differences
+		var runningDep *Deployment
+		if isRunning {
+			runningDep = running.Deployment.Clone()
+			if _, diffs = applied.Diff(runningDep); len(diffs) == 0 {
+				dd.clear(id)
+				continue
+			}
+		} else {
+			diffs = Differences{"deployment is not running"}
+		}
+
+		record, drifted := dd.drifts[id]
+		if !drifted {
+			record = &DriftRecord{DeploymentID: id, Applied: applied, Detected: now}
+			dd.drifts[id] = record
+		}
+		record.Running = runningDep
+		record.Differences = diffs
+		record.LastSeen = now
+		record.Policy = intent.Drift
+		reportDrift(dd.ls, *record, false)
+	}
+}
+
+// clear forgets any drift recorded for id. It must be called with the lock
+// held.
+func (dd *DriftDetector) clear(id DeploymentID) {
+	record, drifted := dd.drifts[id]
+	if !drifted {
+		return
+	}
+	delete(dd.drifts, id)
+	reportDrift(dd.ls, *record, true)
+}
+
+// Drifts returns the drift currently recorded, sorted by DeploymentID.
+func (dd *DriftDetector) Drifts() []DriftRecord {
+	drifts := []DriftRecord{}
+	if dd == nil {
+		return drifts
+	}
+	dd.RLock()
+	defer dd.RUnlock()
+	for _, record := range dd.drifts {
+		drifts = append(drifts, *record)
+	}
+	sort.Slice(drifts, func(i, j int) bool {
+		return drifts[i].DeploymentID.String() < drifts[j].DeploymentID.String()
+	})
+	return drifts
+}
+
+// Drifted returns the drift recorded for id, if any.
+func (dd *DriftDetector) Drifted(id DeploymentID) (DriftRecord, bool) {
+	if dd == nil {
+		return DriftRecord{}, false
+	}
+	dd.RLock()
+	defer dd.RUnlock()
+	record, drifted := dd.drifts[id]
+	if !drifted {
+		return DriftRecord{}, false
+	}
+	return *record, true
+}
+
+// ReportOnly returns true if p would only revert drift which its manifest asks
+// to be reported rather than reverted: the deployment has drifted, and what
+// the GDM intends is still what Sous last applied.
+func (dd *DriftDetector) ReportOnly(p *DeployablePair) bool {
+	if p.Post == nil || p.Post.Deployment == nil || p.Post.Drift != DriftReport {
+		return false
+	}
+	record, drifted := dd.Drifted(p.ID())
+	return drifted && record.Applied.Equal(p.Post.Deployment)
+}
+
+type driftMessage struct {
+	logging.CallerInfo
+	record  DriftRecord
+	cleared bool
+}
+
+func reportDrift(ls logging.LogSink, record DriftRecord, cleared bool) {
+	if ls == nil {
+		return
+	}
+	logging.Deliver(ls, driftMessage{
+		CallerInfo: logging.GetCallerInfo(logging.NotHere()),
+		record:     record,
+		cleared:    cleared,
+	})
+}
+
+func (msg driftMessage) MetricsTo(m logging.MetricsSink) {
+	var drifted int64
+	if !msg.cleared {
+		drifted = 1
+	}
+	m.UpdateSample("drift."+msg.record.DeploymentID.String(), drifted)
+}
+
+func (msg driftMessage) DefaultLevel() logging.Level {
+	if msg.cleared {
+		return logging.InformationLevel
+	}
+	return logging.WarningLevel
+}
+
+func (msg driftMessage) Message() string {
+	if msg.cleared {
+		return "Deployment no longer drifted"
+	}
+	return fmt.Sprintf("Deployment drifted from what Sous last applied: %s", msg.record.Differences)
+}
+
+func (msg driftMessage) EachField(f logging.FieldReportFn) {
+	f("@loglov3-otl", logging.SousGenericV1)
+	f(logging.SousDeploymentId, msg.record.DeploymentID.String())
+	f(logging.SousManifestId, msg.record.DeploymentID.ManifestID.String())
+	msg.CallerInfo.EachField(f)
+}
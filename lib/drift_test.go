@@ -0,0 +1,138 @@
+package sous
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nyarly/spies"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func driftFixture(version string, instances int) *Deployment {
+	return &Deployment{
+		ClusterName:  "x",
+		Cluster:      &Cluster{Name: "x"},
+		SourceID:     MustParseSourceID(fmt.Sprintf("github.com/ot/drifty,%s", version)),
+		DeployConfig: DeployConfig{NumInstances: instances},
+		Drift:        DriftReport,
+	}
+}
+
+func driftRunning(d *Deployment, status DeployStatus) DeployStates {
+	return NewDeployStates(&DeployState{Deployment: *d, Status: status})
+}
+
+func TestDriftDetector(t *testing.T) {
+	ls, _ := logging.NewLogSinkSpy()
+	dd := NewDriftDetector(ls)
+	intended := driftFixture("1.0.0", 2)
+	id := intended.ID()
+	intents := NewDeployments(intended)
+
+	// Running differs from intended, but Sous hasn't applied anything yet.
+	dd.Detect(driftRunning(driftFixture("1.0.0", 5), DeployStatusActive), intents)
+	assert.Empty(t, dd.Drifts(), "drift without a known baseline")
+
+	// Running matches intended, which becomes the baseline.
+	dd.Detect(driftRunning(intended, DeployStatusActive), intents)
+	assert.Empty(t, dd.Drifts())
+
+	// Someone scales the deployment directly.
+	scaled := driftFixture("1.0.0", 5)
+	dd.Detect(driftRunning(scaled, DeployStatusActive), intents)
+	drifts := dd.Drifts()
+	require.Len(t, drifts, 1)
+	assert.Equal(t, id, drifts[0].DeploymentID)
+	assert.Equal(t, 2, drifts[0].Applied.NumInstances)
+	assert.Equal(t, 5, drifts[0].Running.NumInstances)
+	assert.NotEmpty(t, drifts[0].Differences)
+	assert.Equal(t, DriftReport, drifts[0].Policy)
+	detected := drifts[0].Detected
+
+	// Pending deployments are left until they settle.
+	dd.Detect(driftRunning(intended, DeployStatusPending), intents)
+	record, drifted := dd.Drifted(id)
+	require.True(t, drifted)
+	assert.Equal(t, detected, record.Detected)
+
+	// Deleted out of band.
+	dd.Detect(NewDeployStates(), intents)
+	record, drifted = dd.Drifted(id)
+	require.True(t, drifted)
+	assert.Nil(t, record.Running)
+	assert.Equal(t, detected, record.Detected)
+
+	// Sous rectifies it, clearing the drift.
+	pair := &DeployablePair{Post: &Deployable{Deployment: intended}, name: id}
+	dd.RecordRectified(pair, DiffResolution{DeploymentID: id, Desc: CreateDiff})
+	assert.Empty(t, dd.Drifts())
+}
+
+func TestDriftDetector_RecordRectified(t *testing.T) {
+	dd := NewDriftDetector(logging.SilentLogSet())
+	v1, v2 := driftFixture("1.0.0", 2), driftFixture("2.0.0", 2)
+	id := v1.ID()
+	pair := &DeployablePair{Post: &Deployable{Deployment: v2}, name: id}
+
+	// A failed rectification isn't what Sous applied.
+	dd.RecordRectified(pair, DiffResolution{DeploymentID: id, Desc: ModifyDiff, Error: WrapResolveError(fmt.Errorf("boom"))})
+	dd.Detect(driftRunning(v1, DeployStatusActive), NewDeployments(v2))
+	assert.Empty(t, dd.Drifts())
+
+	dd.RecordRectified(pair, DiffResolution{DeploymentID: id, Desc: ModifyDiff})
+	dd.Detect(driftRunning(v1, DeployStatusActive), NewDeployments(v2))
+	assert.Len(t, dd.Drifts(), 1)
+}
+
+func TestDriftDetector_ReportOnly(t *testing.T) {
+	dd := NewDriftDetector(logging.SilentLogSet())
+	intended := driftFixture("1.0.0", 2)
+	scaled := driftFixture("1.0.0", 5)
+	dd.Detect(driftRunning(intended, DeployStatusActive), NewDeployments(intended))
+	dd.Detect(driftRunning(scaled, DeployStatusActive), NewDeployments(intended))
+
+	pair := func(post *Deployment) *DeployablePair {
+		return &DeployablePair{
+			Prior: &Deployable{Deployment: scaled},
+			Post:  &Deployable{Deployment: post},
+			name:  post.ID(),
+		}
+	}
+	assert.True(t, dd.ReportOnly(pair(intended)))
+
+	reverting := intended.Clone()
+	reverting.Drift = DriftRevert
+	assert.False(t, dd.ReportOnly(pair(reverting)), "manifest reverts drift")
+
+	assert.False(t, dd.ReportOnly(pair(driftFixture("2.0.0", 2))), "GDM changed since drift")
+
+	var none *DriftDetector
+	assert.False(t, none.ReportOnly(pair(intended)))
+	assert.Empty(t, none.Drifts())
+}
+
+func TestDriftPolicy_Validate(t *testing.T) {
+	assert.Empty(t, DriftRevert.Validate())
+	assert.Empty(t, DriftReport.Validate())
+	assert.Len(t, DriftPolicy("ignore").Validate(), 1)
+}
+
+func TestResolver_Plan_reportedDrift(t *testing.T) {
+	intended := driftFixture("1.0.0", 2)
+	scaled := driftFixture("1.0.0", 5)
+	deployer, dctrl := NewDeployerSpy()
+	dctrl.MatchMethod("RunningDeployments", spies.AnyArgs, driftRunning(scaled, DeployStatusActive), nil)
+
+	r := NewResolver(deployer, NewDummyRegistry(), &ResolveFilter{}, logging.SilentLogSet(), nil)
+	r.Drift = NewDriftDetector(logging.SilentLogSet())
+	r.Drift.Detect(driftRunning(intended, DeployStatusActive), NewDeployments(intended))
+	r.Drift.Detect(driftRunning(scaled, DeployStatusActive), NewDeployments(intended))
+
+	plan, err := r.Plan(NewDeployments(intended), Defs{Clusters: Clusters{"x": intended.Cluster}})
+	require.NoError(t, err)
+	require.Len(t, plan.Changes, 1)
+	assert.Equal(t, "drift is reported, not reverted", plan.Changes[0].Skipped)
+	assert.Equal(t, 0, plan.Count(ModifiedKind))
+}
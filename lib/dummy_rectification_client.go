@@ -13,11 +13,18 @@ type (
 		Created  []Deployable
 		Deployed []Deployable
 		Deleted  []dummyDelete
+		Advanced []dummyAdvance
+		Canceled []dummyDelete
 	}
 
 	dummyDelete struct {
 		Cluster, Reqid, Message string
 	}
+
+	dummyAdvance struct {
+		Cluster, Reqid, Depid string
+		Target                int
+	}
 )
 
 // NewDummyRectificationClient builds a new DummyRectificationClient
@@ -58,3 +65,17 @@ func (drc *DummyRectificationClient) DeleteRequest(
 	drc.Deleted = append(drc.Deleted, dummyDelete{cluster, reqid, message})
 	return nil
 }
+
+// UpdatePendingDeploy (cluster url, request id, deploy id, target instances)
+func (drc *DummyRectificationClient) UpdatePendingDeploy(cluster, reqid, depid string, target int) error {
+	drc.logf("Advancing deploy %s %s %s to %d", cluster, reqid, depid, target)
+	drc.Advanced = append(drc.Advanced, dummyAdvance{cluster, reqid, depid, target})
+	return nil
+}
+
+// CancelDeploy (cluster url, request id, deploy id)
+func (drc *DummyRectificationClient) CancelDeploy(cluster, reqid, depid string) error {
+	drc.logf("Canceling deploy %s %s %s", cluster, reqid, depid)
+	drc.Canceled = append(drc.Canceled, dummyDelete{cluster, reqid, depid})
+	return nil
+}
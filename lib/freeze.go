@@ -0,0 +1,154 @@
+package sous
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"time"
+)
+
+type (
+	// A FreezeWindow is a period during which deployments to some clusters
+	// may not be changed, e.g. over a holiday or during incident response.
+	FreezeWindow struct {
+		// Reason explains the freeze to whoever runs into it.
+		Reason string
+		// Clusters selects the clusters the freeze applies to, by name. Names
+		// may be shell patterns, e.g. "prod-*". If Clusters is empty, the
+		// freeze applies to every cluster.
+		Clusters []string `yaml:",omitempty"`
+		// Start and End bound the freeze.
+		Start, End time.Time
+		// ExemptOwners lists owners whose manifests may still be changed
+		// during the freeze.
+		ExemptOwners []string `yaml:",omitempty"`
+	}
+
+	// FreezeWindows is a list of FreezeWindow.
+	FreezeWindows []FreezeWindow
+
+	// A FreezeError is returned when a change to a deployment is refused
+	// because of a freeze.
+	FreezeError struct {
+		DeploymentID DeploymentID
+		Window       FreezeWindow
+	}
+)
+
+func (e *FreezeError) Error() string {
+	return fmt.Sprintf("deployments of %s are frozen until %s: %s",
+		e.DeploymentID, e.Window.End.Format(time.RFC1123), e.Window.Reason)
+}
+
+// Active returns true if at is within this window.
+func (fw FreezeWindow) Active(at time.Time) bool {
+	return !at.Before(fw.Start) && at.Before(fw.End)
+}
+
+// AppliesTo returns true if this window freezes the named cluster.
+func (fw FreezeWindow) AppliesTo(cluster string) bool {
+	if len(fw.Clusters) == 0 {
+		return true
+	}
+	for _, pattern := range fw.Clusters {
+		if matched, _ := path.Match(pattern, cluster); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Exempts returns true if any of owners is exempt from this window.
+func (fw FreezeWindow) Exempts(owners OwnerSet) bool {
+	for _, o := range fw.ExemptOwners {
+		if _, has := owners[o]; has {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate implements Flawed on FreezeWindow.
+func (fw FreezeWindow) Validate() []Flaw {
+	var flaws []Flaw
+	if fw.Reason == "" {
+		flaws = append(flaws, FatalFlaw("Freeze window has no reason"))
+	}
+	if !fw.End.After(fw.Start) {
+		flaws = append(flaws, FatalFlaw("Freeze window %q ends before it starts", fw.Reason))
+	}
+	for _, pattern := range fw.Clusters {
+		if _, err := path.Match(pattern, ""); err != nil {
+			flaws = append(flaws, FatalFlaw("Freeze window %q has bad cluster pattern %q: %s", fw.Reason, pattern, err))
+		}
+	}
+	return flaws
+}
+
+// Check returns a *FreezeError if a window active at the given time forbids
+// changing the deployment identified by did, whose manifest has owners.
+func (fws FreezeWindows) Check(did DeploymentID, owners OwnerSet, at time.Time) error {
+	for _, fw := range fws {
+		if fw.Active(at) && fw.AppliesTo(did.Cluster) && !fw.Exempts(owners) {
+			return &FreezeError{DeploymentID: did, Window: fw}
+		}
+	}
+	return nil
+}
+
+// CheckManifest returns a *FreezeError if a window active at the given time
+// forbids changing any of the deployments that differ between prior and
+// post. Either may be nil, for a manifest that is being added or removed.
+// Exemptions are judged by the owners of prior if there is one, so that a
+// manifest can't be exempted by the change itself.
+func (fws FreezeWindows) CheckManifest(prior, post *Manifest, at time.Time) error {
+	var mid ManifestID
+	var owners OwnerSet
+	specs := map[string][2]*DeploySpec{}
+	for i, m := range []*Manifest{prior, post} {
+		if m == nil {
+			continue
+		}
+		if owners == nil {
+			mid, owners = m.ID(), NewOwnerSet(m.Owners...)
+		}
+		for cluster, spec := range m.Deployments {
+			spec := spec
+			pair := specs[cluster]
+			pair[i] = &spec
+			specs[cluster] = pair
+		}
+	}
+	clusters := make([]string, 0, len(specs))
+	for cluster := range specs {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+	for _, cluster := range clusters {
+		pair := specs[cluster]
+		if pair[0] != nil && pair[1] != nil {
+			if different, _ := pair[0].Diff(*pair[1]); !different {
+				continue
+			}
+		}
+		did := DeploymentID{ManifestID: mid, Cluster: cluster}
+		if err := fws.Check(did, owners, at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Clone returns a deep copy of this FreezeWindows.
+func (fws FreezeWindows) Clone() FreezeWindows {
+	if fws == nil {
+		return nil
+	}
+	c := make(FreezeWindows, len(fws))
+	for i, fw := range fws {
+		fw.Clusters = append([]string(nil), fw.Clusters...)
+		fw.ExemptOwners = append([]string(nil), fw.ExemptOwners...)
+		c[i] = fw
+	}
+	return c
+}
@@ -0,0 +1,125 @@
+package sous
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samsalisbury/semv"
+)
+
+func TestFreezeWindows_Check(t *testing.T) {
+	now := time.Now()
+	fws := FreezeWindows{{
+		Reason:       "holiday",
+		Clusters:     []string{"prod-*"},
+		Start:        now.Add(-time.Hour),
+		End:          now.Add(time.Hour),
+		ExemptOwners: []string{"sre"},
+	}}
+	did := func(cluster string) DeploymentID {
+		return DeploymentID{ManifestID: MustParseManifestID("github.com/example/app"), Cluster: cluster}
+	}
+
+	testCases := []struct {
+		desc    string
+		cluster string
+		owners  OwnerSet
+		at      time.Time
+		frozen  bool
+	}{
+		{"matching cluster", "prod-east", NewOwnerSet("team"), now, true},
+		{"other cluster", "ci-east", NewOwnerSet("team"), now, false},
+		{"exempt owner", "prod-east", NewOwnerSet("team", "sre"), now, false},
+		{"before window", "prod-east", NewOwnerSet("team"), now.Add(-2 * time.Hour), false},
+		{"at end of window", "prod-east", NewOwnerSet("team"), now.Add(time.Hour), false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := fws.Check(did(tc.cluster), tc.owners, tc.at)
+			if tc.frozen {
+				fe, is := err.(*FreezeError)
+				if !is {
+					t.Fatalf("got %v; want a *FreezeError", err)
+				}
+				if fe.Window.Reason != "holiday" {
+					t.Errorf("got window %q; want %q", fe.Window.Reason, "holiday")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestFreezeWindows_CheckManifest(t *testing.T) {
+	now := time.Now()
+	fws := FreezeWindows{{
+		Reason:       "migration",
+		Clusters:     []string{"frozen"},
+		Start:        now.Add(-time.Hour),
+		End:          now.Add(time.Hour),
+		ExemptOwners: []string{"sre"},
+	}}
+	manifest := func(version string, owners ...string) *Manifest {
+		spec := DeploySpec{
+			Version:      semv.MustParse(version),
+			DeployConfig: DeployConfig{NumInstances: 1},
+		}
+		return &Manifest{
+			Source: SourceLocation{Repo: "github.com/example/app"},
+			Owners: owners,
+			Deployments: DeploySpecs{
+				"frozen": spec,
+				"thawed": spec,
+			},
+		}
+	}
+
+	prior := manifest("1.0.0", "team")
+
+	unchanged := manifest("1.0.0", "team")
+	if err := fws.CheckManifest(prior, unchanged, now); err != nil {
+		t.Errorf("unchanged manifest: unexpected error: %v", err)
+	}
+
+	thawedOnly := manifest("1.0.0", "team")
+	spec := thawedOnly.Deployments["thawed"]
+	spec.NumInstances = 3
+	thawedOnly.Deployments["thawed"] = spec
+	if err := fws.CheckManifest(prior, thawedOnly, now); err != nil {
+		t.Errorf("change to thawed cluster: unexpected error: %v", err)
+	}
+
+	changed := manifest("2.0.0", "team")
+	if err := fws.CheckManifest(prior, changed, now); err == nil {
+		t.Errorf("change to frozen cluster: expected an error")
+	}
+
+	selfExempted := manifest("2.0.0", "team", "sre")
+	if err := fws.CheckManifest(prior, selfExempted, now); err == nil {
+		t.Errorf("change adding exempt owner: expected an error")
+	}
+
+	if err := fws.CheckManifest(manifest("1.0.0", "sre"), manifest("2.0.0", "sre"), now); err != nil {
+		t.Errorf("exempt manifest: unexpected error: %v", err)
+	}
+
+	if err := fws.CheckManifest(nil, manifest("1.0.0", "team"), now); err == nil {
+		t.Errorf("new manifest: expected an error")
+	}
+}
+
+func TestFreezeWindow_Validate(t *testing.T) {
+	now := time.Now()
+	good := FreezeWindow{Reason: "holiday", Start: now, End: now.Add(time.Hour)}
+	if flaws := good.Validate(); len(flaws) != 0 {
+		t.Errorf("unexpected flaws: %v", flaws)
+	}
+
+	bad := FreezeWindow{Clusters: []string{"prod-["}, Start: now, End: now}
+	if flaws := bad.Validate(); len(flaws) != 3 {
+		t.Errorf("got %d flaws; want 3: %v", len(flaws), flaws)
+	}
+}
@@ -0,0 +1,273 @@
+package sous
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/samsalisbury/semv"
+)
+
+type (
+	// A HistoryEvent is an immutable record of a single change to the intended
+	// state of a deployment.
+	HistoryEvent struct {
+		// When is the time the change was written.
+		When time.Time
+		// User is who made the change.
+		User User
+		// DeploymentID identifies the changed deployment.
+		DeploymentID DeploymentID
+		// Change is "added", "modified" or "removed".
+		Change HistoryChange
+		// Before is the deployment before the change, nil if it was added.
+		Before *Deployment
+		// After is the deployment after the change, nil if it was removed.
+		After *Deployment
+		// Diffs are the differences between Before and After, as reported by
+		// Deployment.Diff.
+		Diffs Differences
+	}
+
+	// HistoryChange describes the kind of a HistoryEvent.
+	HistoryChange string
+
+	// A HistoryStore records and retrieves HistoryEvents.
+	HistoryStore interface {
+		// RecordHistory stores events.
+		RecordHistory(events []HistoryEvent) error
+		// ReadHistory returns the page of events for deployments matched by
+		// filter, oldest first.
+		ReadHistory(filter *ResolveFilter, page HistoryPage) ([]HistoryEvent, error)
+	}
+
+	// A HistoryPage selects which of the matching events ReadHistory returns:
+	// the Limit events before the Skip most recent. A zero Limit means all of
+	// them.
+	HistoryPage struct {
+		Limit, Skip int
+	}
+
+	// InMemoryHistory is a HistoryStore that keeps events in memory. It's used
+	// when no database is available.
+	InMemoryHistory struct {
+		sync.RWMutex
+		events []HistoryEvent
+	}
+
+	// HistoryStateManager wraps a StateManager, recording a HistoryEvent for
+	// every deployment changed by WriteState.
+	HistoryStateManager struct {
+		StateManager
+		history HistoryStore
+		log     logging.LogSink
+	}
+
+	// HistoryClusterManager wraps a ClusterManager, recording a HistoryEvent
+	// for every deployment changed by WriteCluster.
+	HistoryClusterManager struct {
+		ClusterManager
+		history HistoryStore
+		log     logging.LogSink
+	}
+)
+
+const (
+	// HistoryAdded means a deployment was added to the GDM.
+	HistoryAdded = HistoryChange("added")
+	// HistoryModified means a deployment in the GDM was changed.
+	HistoryModified = HistoryChange("modified")
+	// HistoryRemoved means a deployment was removed from the GDM.
+	HistoryRemoved = HistoryChange("removed")
+)
+
+func (e HistoryEvent) String() string {
+	return fmt.Sprintf("%s %s %s by %s", e.When.Format(time.RFC3339), e.DeploymentID, e.Change, e.User)
+}
+
+// HistoryEvents returns a HistoryEvent for each deployment that differs
+// between before and after.
+func HistoryEvents(before, after Deployments, user User, when time.Time) []HistoryEvent {
+	events := []HistoryEvent{}
+	for _, p := range before.Diff(after).Collect() {
+		e := HistoryEvent{
+			When:         when,
+			User:         user,
+			DeploymentID: p.ID(),
+		}
+		switch p.Kind() {
+		default:
+			continue
+		case AddedKind:
+			e.Change = HistoryAdded
+			e.After = p.Post.Deployment
+		case RemovedKind:
+			e.Change = HistoryRemoved
+			e.Before = p.Prior.Deployment
+		case ModifiedKind:
+			e.Change = HistoryModified
+			e.Before = p.Prior.Deployment
+			e.After = p.Post.Deployment
+			_, e.Diffs = e.Before.Diff(e.After)
+		}
+		events = append(events, e)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].DeploymentID.String() < events[j].DeploymentID.String()
+	})
+	return events
+}
+
+// Matches returns true if filter matches the deployment this event is about.
+func (e HistoryEvent) Matches(filter *ResolveFilter) bool {
+	if filter == nil {
+		return true
+	}
+	return filter.FilterManifestID(e.DeploymentID.ManifestID) &&
+		filter.FilterClusterName(e.DeploymentID.Cluster)
+}
+
+// PriorVersion returns the version the deployment identified by did had
+// before it was last changed to current, according to events, which are
+// oldest first. It returns false if events don't record such a change.
+func PriorVersion(events []HistoryEvent, did DeploymentID, current semv.Version) (semv.Version, bool) {
+	for i := len(events) - 1; i >= 0; i-- {
+		e := events[i]
+		if e.DeploymentID != did || e.Before == nil || e.After == nil {
+			continue
+		}
+		before, after := e.Before.SourceID.Version, e.After.SourceID.Version
+		if after.String() == current.String() && before.String() != current.String() {
+			return before, true
+		}
+	}
+	return semv.Version{}, false
+}
+
+// NewInMemoryHistory returns an empty InMemoryHistory.
+func NewInMemoryHistory() *InMemoryHistory {
+	return &InMemoryHistory{}
+}
+
+// RecordHistory implements HistoryStore on InMemoryHistory.
+func (h *InMemoryHistory) RecordHistory(events []HistoryEvent) error {
+	h.Lock()
+	defer h.Unlock()
+	h.events = append(h.events, events...)
+	return nil
+}
+
+// Select returns the page p of events, which are oldest first.
+func (p HistoryPage) Select(events []HistoryEvent) []HistoryEvent {
+	end := len(events) - p.Skip
+	if end < 0 {
+		end = 0
+	}
+	start := 0
+	if p.Limit > 0 && end-p.Limit > 0 {
+		start = end - p.Limit
+	}
+	return events[start:end]
+}
+
+// ReadHistory implements HistoryStore on InMemoryHistory.
+func (h *InMemoryHistory) ReadHistory(filter *ResolveFilter, page HistoryPage) ([]HistoryEvent, error) {
+	h.RLock()
+	defer h.RUnlock()
+	events := []HistoryEvent{}
+	for _, e := range h.events {
+		if e.Matches(filter) {
+			events = append(events, e)
+		}
+	}
+	return page.Select(events), nil
+}
+
+// NewHistoryStateManager wraps sm so that its writes are recorded in history.
+func NewHistoryStateManager(sm StateManager, history HistoryStore, ls logging.LogSink) *HistoryStateManager {
+	return &HistoryStateManager{StateManager: sm, history: history, log: ls}
+}
+
+// WriteState implements StateWriter on HistoryStateManager. If the state
+// before the write can't be read, the write isn't recorded in history, rather
+// than recorded as adding every deployment.
+func (hsm *HistoryStateManager) WriteState(state *State, user User) error {
+	before, known := readDeployments(hsm.StateManager, hsm.log)
+	if err := hsm.StateManager.WriteState(state, user); err != nil {
+		return err
+	}
+	if !known {
+		return nil
+	}
+	after, err := state.Deployments()
+	if err != nil {
+		return err
+	}
+	recordHistory(hsm.history, hsm.log, HistoryEvents(before, after, user, time.Now()))
+	return nil
+}
+
+// ReadHistory implements HistoryStore on HistoryStateManager.
+func (hsm *HistoryStateManager) ReadHistory(filter *ResolveFilter, page HistoryPage) ([]HistoryEvent, error) {
+	return hsm.history.ReadHistory(filter, page)
+}
+
+// RecordHistory implements HistoryStore on HistoryStateManager.
+func (hsm *HistoryStateManager) RecordHistory(events []HistoryEvent) error {
+	return hsm.history.RecordHistory(events)
+}
+
+// NewHistoryClusterManager wraps cm so that its writes are recorded in
+// history.
+func NewHistoryClusterManager(cm ClusterManager, history HistoryStore, ls logging.LogSink) *HistoryClusterManager {
+	return &HistoryClusterManager{ClusterManager: cm, history: history, log: ls}
+}
+
+// WriteCluster implements ClusterManager on HistoryClusterManager. Like
+// HistoryStateManager.WriteState, it records nothing if the cluster's
+// deployments before the write can't be read.
+func (hcm *HistoryClusterManager) WriteCluster(clusterName string, deps Deployments, user User) error {
+	before, readErr := hcm.ClusterManager.ReadCluster(clusterName)
+	if readErr != nil {
+		messages.ReportLogFieldsMessage("Reading cluster for deployment history; not recording this write", logging.WarningLevel, hcm.log, readErr)
+	}
+	if err := hcm.ClusterManager.WriteCluster(clusterName, deps, user); err != nil {
+		return err
+	}
+	if readErr != nil {
+		return nil
+	}
+	after := deps.Filter(func(d *Deployment) bool {
+		return d.ClusterName == clusterName
+	})
+	recordHistory(hcm.history, hcm.log, HistoryEvents(before, after, user, time.Now()))
+	return nil
+}
+
+// readDeployments returns the deployments currently in sr, and false if they
+// can't be read.
+func readDeployments(sr StateReader, ls logging.LogSink) (Deployments, bool) {
+	state, err := sr.ReadState()
+	if err == nil {
+		var deps Deployments
+		if deps, err = state.Deployments(); err == nil {
+			return deps, true
+		}
+	}
+	messages.ReportLogFieldsMessage("Reading state for deployment history; not recording this write", logging.WarningLevel, ls, err)
+	return NewDeployments(), false
+}
+
+// recordHistory stores events, logging rather than returning errors: by now
+// the change itself has been written.
+func recordHistory(history HistoryStore, ls logging.LogSink, events []HistoryEvent) {
+	if len(events) == 0 {
+		return
+	}
+	if err := history.RecordHistory(events); err != nil {
+		messages.ReportLogFieldsMessage("Failed to record deployment history", logging.WarningLevel, ls, err)
+	}
+}
@@ -0,0 +1,221 @@
+package sous
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nyarly/spies"
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+	"github.com/samsalisbury/semv"
+)
+
+func TestHistoryEvents(t *testing.T) {
+	user := User{Name: "Judson", Email: "jlester@opentable.com"}
+	when := time.Now()
+
+	kept, changed, removed := makeDepl("kept", 1), makeDepl("changed", 1), makeDepl("removed", 1)
+	before := NewDeployments(kept, changed, removed)
+
+	changedAfter := changed.Clone()
+	changedAfter.NumInstances = 2
+	added := makeDepl("added", 1)
+	after := NewDeployments(kept.Clone(), changedAfter, added)
+
+	events := HistoryEvents(before, after, user, when)
+	if len(events) != 3 {
+		t.Fatalf("got %d events; want 3: %v", len(events), events)
+	}
+
+	want := []struct {
+		repo          string
+		change        HistoryChange
+		before, after bool
+	}{
+		{"added", HistoryAdded, false, true},
+		{"changed", HistoryModified, true, true},
+		{"removed", HistoryRemoved, true, false},
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.DeploymentID.ManifestID.Source.Repo != w.repo {
+			t.Errorf("event %d: got repo %q; want %q", i, e.DeploymentID.ManifestID.Source.Repo, w.repo)
+		}
+		if e.Change != w.change {
+			t.Errorf("event %d: got change %q; want %q", i, e.Change, w.change)
+		}
+		if (e.Before != nil) != w.before || (e.After != nil) != w.after {
+			t.Errorf("event %d: got before %v after %v", i, e.Before, e.After)
+		}
+		if e.User != user || !e.When.Equal(when) {
+			t.Errorf("event %d: got %s", i, e)
+		}
+	}
+	if len(events[1].Diffs) != 1 {
+		t.Errorf("got diffs %v; want one difference", events[1].Diffs)
+	}
+}
+
+func TestInMemoryHistory_ReadHistory(t *testing.T) {
+	h := NewInMemoryHistory()
+	events := []HistoryEvent{}
+	for _, c := range []string{"cluster1", "cluster2", "cluster1"} {
+		d := makeDepl("github.com/opentable/sous", 1)
+		d.ClusterName = c
+		events = append(events, HistoryEvent{DeploymentID: d.ID(), Change: HistoryAdded, After: d})
+	}
+	if err := h.RecordHistory(events); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := h.ReadHistory(nil, HistoryPage{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Errorf("got %d events; want 3", len(all))
+	}
+
+	one, err := h.ReadHistory(&ResolveFilter{Cluster: NewResolveFieldMatcher("cluster1")}, HistoryPage{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(one) != 2 {
+		t.Errorf("got %d events for cluster1; want 2", len(one))
+	}
+
+	none, err := h.ReadHistory(&ResolveFilter{Repo: NewResolveFieldMatcher("github.com/opentable/other")}, HistoryPage{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(none) != 0 {
+		t.Errorf("got %d events for other repo; want 0", len(none))
+	}
+}
+
+func TestHistoryPage_Select(t *testing.T) {
+	events := []HistoryEvent{}
+	for _, c := range []string{"a", "b", "c", "d"} {
+		events = append(events, HistoryEvent{DeploymentID: DeploymentID{Cluster: c}})
+	}
+	clusters := func(es []HistoryEvent) string {
+		cs := ""
+		for _, e := range es {
+			cs += e.DeploymentID.Cluster
+		}
+		return cs
+	}
+	for _, c := range []struct {
+		page HistoryPage
+		want string
+	}{
+		{HistoryPage{}, "abcd"},
+		{HistoryPage{Limit: 2}, "cd"},
+		{HistoryPage{Limit: 2, Skip: 1}, "bc"},
+		{HistoryPage{Limit: 10, Skip: 3}, "a"},
+		{HistoryPage{Skip: 2}, "ab"},
+		{HistoryPage{Limit: 1, Skip: 5}, ""},
+	} {
+		if got := clusters(c.page.Select(events)); got != c.want {
+			t.Errorf("%+v selected %q; want %q", c.page, got, c.want)
+		}
+	}
+}
+
+func TestHistoryStateManager_WriteState(t *testing.T) {
+	sm := NewDummyStateManager()
+	sm.State = DefaultStateFixture()
+	history := NewInMemoryHistory()
+	ls, _ := logging.NewLogSinkSpy()
+	hsm := NewHistoryStateManager(sm, history, ls)
+
+	state := sm.State.Clone()
+	mid := state.Manifests.Keys()[0]
+	m, _ := state.Manifests.Get(mid)
+	spec := m.Deployments["cluster0"]
+	spec.Version = semv.MustParse("1.0.1")
+	m.Deployments["cluster0"] = spec
+
+	user := User{Name: "Judson", Email: "jlester@opentable.com"}
+	if err := hsm.WriteState(state, user); err != nil {
+		t.Fatal(err)
+	}
+	if sm.WriteCount != 1 {
+		t.Errorf("got %d writes; want 1", sm.WriteCount)
+	}
+
+	events, err := hsm.ReadHistory(nil, HistoryPage{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events; want 1: %v", len(events), events)
+	}
+	e := events[0]
+	wantID := DeploymentID{ManifestID: mid, Cluster: "cluster0"}
+	if e.DeploymentID != wantID || e.Change != HistoryModified || e.User != user {
+		t.Errorf("got %s; want modification of %s by %s", e, wantID, user)
+	}
+	if got := e.After.SourceID.Version.String(); got != "1.0.1" {
+		t.Errorf("got version %s after; want 1.0.1", got)
+	}
+}
+
+func TestHistoryStateManager_WriteState_unreadable(t *testing.T) {
+	sm := NewDummyStateManager()
+	sm.State = DefaultStateFixture()
+	sm.ReadErr = errors.New("database unavailable")
+	history := NewInMemoryHistory()
+	hsm := NewHistoryStateManager(sm, history, logging.SilentLogSet())
+
+	if err := hsm.WriteState(sm.State.Clone(), User{Name: "Judson"}); err != nil {
+		t.Fatal(err)
+	}
+	if sm.WriteCount != 1 {
+		t.Errorf("got %d writes; want 1", sm.WriteCount)
+	}
+	if events, _ := hsm.ReadHistory(nil, HistoryPage{}); len(events) != 0 {
+		t.Errorf("got %d events for a write over an unreadable state; want none", len(events))
+	}
+}
+
+func TestHistoryClusterManager_WriteCluster_unreadable(t *testing.T) {
+	cm, ctrl := NewClusterManagerSpy()
+	ctrl.MatchMethod("ReadCluster", spies.AnyArgs, NewDeployments(), errors.New("database unavailable"))
+	ctrl.MatchMethod("WriteCluster", spies.AnyArgs, nil)
+	history := NewInMemoryHistory()
+	hcm := NewHistoryClusterManager(cm, history, logging.SilentLogSet())
+
+	d := makeDepl("github.com/opentable/sous", 1)
+	if err := hcm.WriteCluster(d.ClusterName, NewDeployments(d), User{Name: "Judson"}); err != nil {
+		t.Fatal(err)
+	}
+	if events, _ := history.ReadHistory(nil, HistoryPage{}); len(events) != 0 {
+		t.Errorf("got %d events for a write over unreadable deployments; want none", len(events))
+	}
+}
+
+func TestPriorVersion(t *testing.T) {
+	change := func(cluster, from, to string) HistoryEvent {
+		before := makeDepl("github.com/opentable/sous", 1)
+		before.ClusterName = cluster
+		before.SourceID.Version = semv.MustParse(from)
+		after := before.Clone()
+		after.SourceID.Version = semv.MustParse(to)
+		return HistoryEvent{DeploymentID: before.ID(), Change: HistoryModified, Before: before, After: after}
+	}
+	events := []HistoryEvent{
+		change("cluster1", "1.0.0", "2.0.0"),
+		change("cluster2", "2.0.0", "3.0.0"),
+		change("cluster1", "2.0.0", "2.0.0"),
+	}
+	did := events[0].DeploymentID
+
+	v, ok := PriorVersion(events, did, semv.MustParse("2.0.0"))
+	if !ok || v.String() != "1.0.0" {
+		t.Errorf("got %s, %t; want 1.0.0, true", v, ok)
+	}
+	if v, ok := PriorVersion(events, did, semv.MustParse("3.0.0")); ok {
+		t.Errorf("got %s; want no prior version", v)
+	}
+}
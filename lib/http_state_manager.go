@@ -39,6 +39,13 @@ func NewHTTPStateManager(client restful.HTTPClient, tid TraceID, ls logging.LogS
 	}
 }
 
+// UseClusterClients makes hsm reach the server of each cluster through
+// clients, e.g. to present a server's credentials, rather than through clients
+// it builds from the server list.
+func (hsm *HTTPStateManager) UseClusterClients(clients map[string]restful.HTTPClient) {
+	hsm.clusterClients = clients
+}
+
 // ReadState implements StateReader for HTTPStateManager.
 func (hsm *HTTPStateManager) ReadState() (*State, error) {
 	defs, err := hsm.getDefs()
@@ -29,6 +29,24 @@ type (
 		Owners []string
 		// Kind is the kind of software that SourceRepo represents.
 		Kind ManifestKind `validate:"nonzero"`
+		// AutoRollback opts this manifest in to automatic rollback: if a deploy
+		// fails, Sous writes the previously running version back to the GDM.
+		AutoRollback bool `yaml:",omitempty"`
+		// Drift is what Sous does when this manifest's deployments are changed
+		// by something other than Sous. By default the change is reverted;
+		// "report" leaves it in place until the GDM changes, and reports it.
+		Drift DriftPolicy `yaml:",omitempty"`
+		// Promotion, if set, describes how new versions are promoted through
+		// this manifest's clusters by `sous promote`.
+		Promotion *Promotion `yaml:",omitempty"`
+		// Defaults is merged into the DeployConfig of each of Deployments
+		// when the manifest is expanded: values set by a deployment or an
+		// overlay take precedence.
+		Defaults *DeployConfig `yaml:",omitempty"`
+		// Overlays are merged into the DeployConfigs of the Deployments to
+		// their clusters when the manifest is expanded, taking precedence over
+		// Defaults.
+		Overlays ManifestOverlays `yaml:",omitempty"`
 		// Deployments is a map of cluster names to DeploymentSpecs
 		Deployments DeploySpecs `validate:"keys=nonempty,values=nonzero"`
 	}
@@ -58,6 +76,12 @@ func (m Manifest) Clone() (c *Manifest) {
 		deployments[k] = v.Clone()
 	}
 	c.Owners = owners
+	c.Promotion = m.Promotion.Clone()
+	if m.Defaults != nil {
+		defaults := m.Defaults.Clone()
+		c.Defaults = &defaults
+	}
+	c.Overlays = m.Overlays.Clone()
 	c.Deployments = deployments
 	return
 }
@@ -82,6 +106,26 @@ func (m *Manifest) Diff(o *Manifest) (bool, []string) {
 	if m.Kind != o.Kind {
 		diff("kind; this: %q; other: %q", m.Kind, o.Kind)
 	}
+	if m.AutoRollback != o.AutoRollback {
+		diff("auto rollback; this: %t; other: %t", m.AutoRollback, o.AutoRollback)
+	}
+	if m.Drift != o.Drift {
+		diff("drift; this: %q; other: %q", m.Drift, o.Drift)
+	}
+	_, pds := m.Promotion.Diff(o.Promotion)
+	diffs = append(diffs, pds...)
+	switch {
+	case m.Defaults == nil && o.Defaults != nil:
+		diff("defaults; this: none")
+	case m.Defaults != nil && o.Defaults == nil:
+		diff("defaults; other: none")
+	case m.Defaults != nil:
+		_, dds := m.Defaults.Diff(*o.Defaults)
+		for _, d := range dds {
+			diff("defaults %s", d)
+		}
+	}
+	diffs = append(diffs, m.Overlays.Diff(o.Overlays)...)
 	if len(m.Owners) != len(o.Owners) {
 		diff("number of owners; this: %d; other: %d", len(m.Owners), len(o.Owners))
 	} else {
@@ -131,6 +175,9 @@ func (m *Manifest) Validate() []Flaw {
 	} else {
 		flaws = append(flaws, m.Kind.Validate()...)
 	}
+	flaws = append(flaws, m.Drift.Validate()...)
+	flaws = append(flaws, m.Promotion.Validate(m)...)
+	flaws = append(flaws, m.Overlays.Validate()...)
 
 	/*
 		Cannot validate Deployments without defs...
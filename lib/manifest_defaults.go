@@ -0,0 +1,158 @@
+package sous
+
+import "fmt"
+
+type (
+	// A ManifestOverlay is a DeployConfig shared by the deployments of a
+	// manifest to a group of clusters. It is merged into the DeployConfig of
+	// each deployment to one of its Clusters when the manifest is expanded,
+	// taking precedence over the manifest's Defaults.
+	ManifestOverlay struct {
+		// Clusters select the clusters the overlay applies to. Each is a
+		// cluster name, group or label ClusterSelector.
+		Clusters []string
+		// DeployConfig is the config shared by those clusters.
+		DeployConfig `yaml:",inline"`
+	}
+
+	// ManifestOverlays is a list of ManifestOverlay. Where overlays set the
+	// same value for a cluster, the later one takes precedence.
+	ManifestOverlays []ManifestOverlay
+)
+
+// Clone returns a deep copy of mos.
+func (mos ManifestOverlays) Clone() ManifestOverlays {
+	if mos == nil {
+		return nil
+	}
+	c := make(ManifestOverlays, len(mos))
+	for i, mo := range mos {
+		c[i] = ManifestOverlay{
+			Clusters:     append([]string{}, mo.Clusters...),
+			DeployConfig: mo.DeployConfig.Clone(),
+		}
+	}
+	return c
+}
+
+// Diff returns the differences between mos and o.
+func (mos ManifestOverlays) Diff(o ManifestOverlays) []string {
+	if len(mos) != len(o) {
+		return []string{fmt.Sprintf("number of overlays; this: %d; other: %d", len(mos), len(o))}
+	}
+	var diffs []string
+	for i, mo := range mos {
+		if fmt.Sprint(mo.Clusters) != fmt.Sprint(o[i].Clusters) {
+			diffs = append(diffs, fmt.Sprintf("overlay %d clusters; this: %v; other: %v", i, mo.Clusters, o[i].Clusters))
+		}
+		_, ds := mo.DeployConfig.Diff(o[i].DeployConfig)
+		for _, d := range ds {
+			diffs = append(diffs, fmt.Sprintf("overlay %d %s", i, d))
+		}
+	}
+	return diffs
+}
+
+// Validate returns a flaw for each overlay without clusters.
+func (mos ManifestOverlays) Validate() []Flaw {
+	var flaws []Flaw
+	for i, mo := range mos {
+		if len(mo.Clusters) == 0 {
+			flaws = append(flaws, FatalFlaw("overlay %d lists no clusters", i))
+		}
+	}
+	return flaws
+}
+
+// appliesTo returns true if one of mo's Clusters selects clusterName, as
+// defined in defs.
+func (mo ManifestOverlay) appliesTo(defs Defs, clusterName string) bool {
+	for _, sel := range mo.Clusters {
+		if ClusterSelector(sel).Matches(clusterName, defs.Clusters[clusterName]) {
+			return true
+		}
+	}
+	return false
+}
+
+// inheritedConfig returns the DeployConfig the deployment of m to clusterName
+// inherits from its overlays and Defaults.
+func (m *Manifest) inheritedConfig(defs Defs, clusterName string) DeployConfig {
+	var dcs []DeployConfig
+	for i := len(m.Overlays) - 1; i >= 0; i-- {
+		if m.Overlays[i].appliesTo(defs, clusterName) {
+			dcs = append(dcs, m.Overlays[i].DeployConfig)
+		}
+	}
+	if m.Defaults != nil {
+		dcs = append(dcs, *m.Defaults)
+	}
+	return flattenDeployConfigs(dcs)
+}
+
+// ExpandedSpec returns the DeploySpec for clusterName with m's overlays and
+// Defaults merged into it. defs resolves the selectors in m's overlays.
+func (m *Manifest) ExpandedSpec(defs Defs, clusterName string) DeploySpec {
+	spec := m.Deployments[clusterName]
+	if m.Defaults == nil && len(m.Overlays) == 0 {
+		return spec
+	}
+	expanded := flattenDeploySpecs([]DeploySpec{spec, {DeployConfig: m.inheritedConfig(defs, clusterName)}})
+	expanded.clusterName = spec.clusterName
+	return expanded
+}
+
+// Expanded returns a copy of m with its overlays and Defaults merged into each
+// of its Deployments, and removed. defs resolves the selectors in m's overlays.
+func (m *Manifest) Expanded(defs Defs) *Manifest {
+	e := m.Clone()
+	for cn := range m.Deployments {
+		e.Deployments[cn] = m.ExpandedSpec(defs, cn).Clone()
+	}
+	e.Defaults = nil
+	e.Overlays = nil
+	return e
+}
+
+// collapseConfig is the reverse of ExpandedSpec: it returns a copy of dc,
+// the expanded config of the deployment to clusterName, without the values it
+// would inherit from m's overlays and Defaults. Values set in old, the
+// deployment's config before it was changed, are kept.
+func (m *Manifest) collapseConfig(defs Defs, clusterName string, dc, old DeployConfig) DeployConfig {
+	if m.Defaults == nil && len(m.Overlays) == 0 {
+		return dc
+	}
+	inh := m.inheritedConfig(defs, clusterName)
+	c := dc.Clone()
+
+	collapseMap := func(values, inherited, old map[string]string) {
+		for k, v := range values {
+			if iv, has := inherited[k]; has && iv == v {
+				if _, kept := old[k]; !kept {
+					delete(values, k)
+				}
+			}
+		}
+	}
+	collapseMap(c.Resources, inh.Resources, old.Resources)
+	collapseMap(c.Env, inh.Env, old.Env)
+	collapseMap(c.Metadata, inh.Metadata, old.Metadata)
+
+	if c.NumInstances == inh.NumInstances && old.NumInstances == 0 {
+		c.NumInstances = 0
+	}
+	if len(inh.Volumes) > 0 && c.Volumes.Equal(inh.Volumes) && len(old.Volumes) == 0 {
+		c.Volumes = nil
+	}
+	if c.Schedule == inh.Schedule && old.Schedule == "" {
+		c.Schedule = ""
+	}
+	if inh.Rollout.Enabled() && c.Rollout.Equal(inh.Rollout) && !old.Rollout.Enabled() {
+		c.Rollout = Rollout{}
+	}
+	if c.SingularityRequestID == inh.SingularityRequestID && old.SingularityRequestID == "" {
+		c.SingularityRequestID = ""
+	}
+	c.Startup = inh.Startup.UnmergeDefaults(c.Startup, old.Startup)
+	return c
+}
@@ -0,0 +1,135 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeTemplatedManifest() *Manifest {
+	return &Manifest{
+		Source: project1,
+		Owners: []string{"owner1"},
+		Kind:   ManifestKindService,
+		Defaults: &DeployConfig{
+			Resources:    Resources{"cpus": "1", "mem": "1024"},
+			Env:          Env{"ALL": "IS ONE", "LOG_LEVEL": "info"},
+			NumInstances: 2,
+			Startup:      Startup{CheckReadyURIPath: "/health"},
+		},
+		Overlays: ManifestOverlays{
+			{
+				Clusters:     []string{"cluster-2"},
+				DeployConfig: DeployConfig{NumInstances: 6, Resources: Resources{"mem": "2048"}},
+			},
+		},
+		Deployments: DeploySpecs{
+			"cluster-1": {Version: semv.MustParse("1.0.0")},
+			"cluster-2": {
+				Version:      semv.MustParse("1.0.0"),
+				DeployConfig: DeployConfig{Env: Env{"LOG_LEVEL": "warn"}},
+			},
+		},
+	}
+}
+
+func TestManifest_Defaults_Deployments(t *testing.T) {
+	ds, err := NewManifests(makeTemplatedManifest()).Deployments(makeTestDefs())
+	require.NoError(t, err)
+
+	d1, ok := ds.Get(DeploymentID{ManifestID: ManifestID{Source: project1}, Cluster: "cluster-1"})
+	require.True(t, ok)
+	assert.Equal(t, 2, d1.NumInstances)
+	assert.Equal(t, Resources{"cpus": "1", "mem": "1024"}, d1.Resources)
+	assert.Equal(t, "info", d1.Env["LOG_LEVEL"])
+	assert.Equal(t, "Cluster One", d1.Env["CLUSTER_LONG_NAME"])
+	assert.Equal(t, "/health", d1.Startup.CheckReadyURIPath)
+
+	d2, ok := ds.Get(DeploymentID{ManifestID: ManifestID{Source: project1}, Cluster: "cluster-2"})
+	require.True(t, ok)
+	assert.Equal(t, 6, d2.NumInstances)
+	assert.Equal(t, Resources{"cpus": "1", "mem": "2048"}, d2.Resources)
+	assert.Equal(t, "warn", d2.Env["LOG_LEVEL"])
+	assert.Equal(t, "IS ONE", d2.Env["ALL"])
+}
+
+func TestManifest_Defaults_Putback(t *testing.T) {
+	defs := makeTestDefs()
+	original := NewManifests(makeTemplatedManifest())
+	ds, err := original.Deployments(defs)
+	require.NoError(t, err)
+
+	ms, err := ds.PutbackManifests(defs, original, logging.SilentLogSet())
+	require.NoError(t, err)
+	m, _ := ms.Get(ManifestID{Source: project1})
+	if different, diffs := makeTemplatedManifest().Diff(m); different {
+		t.Errorf("round trip changed the manifest: %v", diffs)
+	}
+
+	did := DeploymentID{ManifestID: ManifestID{Source: project1}, Cluster: "cluster-2"}
+	d2, _ := ds.Get(did)
+	d2.NumInstances = 8
+	d2.SourceID.Version = semv.MustParse("1.1.0")
+	ds.Set(did, d2)
+	ms, err = ds.PutbackManifests(defs, original, logging.SilentLogSet())
+	require.NoError(t, err)
+	m, _ = ms.Get(ManifestID{Source: project1})
+
+	expected := makeTemplatedManifest()
+	expected.Deployments["cluster-2"] = DeploySpec{
+		Version:      semv.MustParse("1.1.0"),
+		DeployConfig: DeployConfig{Env: Env{"LOG_LEVEL": "warn"}, NumInstances: 8},
+	}
+	if different, diffs := expected.Diff(m); different {
+		t.Errorf("putback of a changed deployment: %v", diffs)
+	}
+}
+
+func TestManifest_Expanded(t *testing.T) {
+	defs := makeTestDefs()
+	m := makeTemplatedManifest()
+	e := m.Expanded(defs)
+
+	assert.Nil(t, e.Defaults)
+	assert.Nil(t, e.Overlays)
+	assert.Equal(t, 6, e.Deployments["cluster-2"].NumInstances)
+	assert.Equal(t, Env{"ALL": "IS ONE", "LOG_LEVEL": "warn"}, e.Deployments["cluster-2"].Env)
+	assert.Equal(t, "/health", e.Deployments["cluster-1"].Startup.CheckReadyURIPath)
+	assert.NotNil(t, m.Defaults, "Expanded changed its receiver")
+
+	// Both forms describe the same deployments.
+	compact, err := NewManifests(m).Deployments(defs)
+	require.NoError(t, err)
+	expanded, err := NewManifests(e).Deployments(defs)
+	require.NoError(t, err)
+	compareDeployments(t, compact, expanded)
+}
+
+func TestManifest_Overlays_labelSelector(t *testing.T) {
+	defs := makeTestDefs()
+	prod := cluster2.Clone()
+	prod.Labels = map[string]string{"env": "prod"}
+	defs.Clusters["cluster-2"] = prod
+
+	m := makeTemplatedManifest()
+	m.Overlays[0].Clusters = []string{"env=prod"}
+	original := NewManifests(m)
+	ds, err := original.Deployments(defs)
+	require.NoError(t, err)
+
+	d1, _ := ds.Get(DeploymentID{ManifestID: ManifestID{Source: project1}, Cluster: "cluster-1"})
+	assert.Equal(t, 2, d1.NumInstances)
+	d2, _ := ds.Get(DeploymentID{ManifestID: ManifestID{Source: project1}, Cluster: "cluster-2"})
+	assert.Equal(t, 6, d2.NumInstances)
+	assert.Equal(t, 6, m.Expanded(defs).Deployments["cluster-2"].NumInstances)
+
+	ms, err := ds.PutbackManifests(defs, original, logging.SilentLogSet())
+	require.NoError(t, err)
+	back, _ := ms.Get(ManifestID{Source: project1})
+	if different, diffs := m.Diff(back); different {
+		t.Errorf("round trip changed the manifest: %v", diffs)
+	}
+}
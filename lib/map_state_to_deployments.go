@@ -2,6 +2,7 @@ package sous
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/logging/messages"
@@ -59,12 +60,21 @@ func (ds Deployments) PutbackManifests(defs Defs, olds Manifests, log logging.Lo
 
 		if was {
 			oldSpec, hadSpec = old.Deployments[d.ClusterName]
+			if !hadSpec {
+				if keys, err := old.DeploymentKeys(defs); err == nil {
+					oldSpec, hadSpec = old.Deployments[keys[d.ClusterName]]
+				}
+			}
 		}
 
 		if !ok {
 			m = &Manifest{Deployments: DeploySpecs{}}
 			m.Owners = d.Owners.Slice()
 			m.SetID(mid)
+			if was {
+				m.Defaults = old.Clone().Defaults
+				m.Overlays = old.Overlays.Clone()
+			}
 		}
 		spec := DeploySpec{
 			Version:      d.SourceID.Version,
@@ -94,8 +104,14 @@ func (ds Deployments) PutbackManifests(defs Defs, olds Manifests, log logging.Lo
 				}
 			}
 		}
+		// Leave out what the manifest's Defaults and overlays provide.
+		spec.DeployConfig = m.collapseConfig(defs, d.ClusterName, spec.DeployConfig, oldSpec.DeployConfig)
+
 		m.Deployments[d.ClusterName] = spec
 		m.Kind = d.Kind
+		m.AutoRollback = d.AutoRollback
+		m.Drift = d.Drift
+		m.Promotion = d.Promotion.Clone()
 
 		ms.Set(mid, m)
 	}
@@ -105,6 +121,9 @@ func (ds Deployments) PutbackManifests(defs Defs, olds Manifests, log logging.Lo
 		if !there {
 			continue
 		}
+		if old, was := olds.Get(k); was {
+			m.collapseSelectors(defs, old)
+		}
 		ms.Set(k, m)
 	}
 
@@ -151,6 +170,9 @@ func (ds Deployments) RawManifests(defs Defs, log logging.LogSink) (Manifests, e
 		}
 		m.Deployments[d.ClusterName] = spec
 		m.Kind = d.Kind
+		m.AutoRollback = d.AutoRollback
+		m.Drift = d.Drift
+		m.Promotion = d.Promotion.Clone()
 
 		ms.Set(mid, m)
 	}
@@ -171,14 +193,19 @@ func (ds Deployments) RawManifests(defs Defs, log logging.LogSink) (Manifests, e
 // and configuration).
 func DeploymentsFromManifest(defs Defs, m *Manifest) (Deployments, error) {
 	ds := NewDeployments()
-	var inherit []DeploySpec
 
-	for clusterName, spec := range m.Deployments {
-		cluster, ok := defs.Clusters[clusterName]
-		if !ok {
-			return ds, errors.Errorf("cluster %q doesn't have a definition (but specified in manifest %q)", clusterName, m.ID())
-		}
+	keys, err := m.DeploymentKeys(defs)
+	if err != nil {
+		return ds, err
+	}
+	for clusterName, key := range keys {
+		cluster := defs.Clusters[clusterName]
+		spec := m.Deployments[key]
 		spec.clusterName = cluster.BaseURL
+		var inherit []DeploySpec
+		if m.Defaults != nil || len(m.Overlays) > 0 {
+			inherit = []DeploySpec{{DeployConfig: m.inheritedConfig(defs, clusterName)}}
+		}
 		d, err := BuildDeployment(m, clusterName, cluster, spec, inherit)
 		if err != nil {
 			return ds, err
@@ -188,6 +215,122 @@ func DeploymentsFromManifest(defs Defs, m *Manifest) (Deployments, error) {
 	return ds, nil
 }
 
+// DeploymentKeys maps the name of each cluster m deploys to to the key of its
+// DeploySpec in m.Deployments. A key is either the name of a cluster, or a
+// ClusterSelector standing for each cluster it selects that isn't itself a
+// key.
+func (m *Manifest) DeploymentKeys(defs Defs) (map[string]string, error) {
+	keys := map[string]string{}
+	var selectors []string
+	for key := range m.Deployments {
+		if _, isCluster := defs.Clusters[key]; isCluster {
+			keys[key] = key
+			continue
+		}
+		selectors = append(selectors, key)
+	}
+	sort.Strings(selectors)
+	for _, sel := range selectors {
+		clusterNames := defs.Clusters.Select(ClusterSelector(sel))
+		if len(clusterNames) == 0 {
+			return nil, errors.Errorf("cluster %q doesn't have a definition (but specified in manifest %q)", sel, m.ID())
+		}
+		for _, cn := range clusterNames {
+			other, has := keys[cn]
+			if !has {
+				keys[cn] = sel
+				continue
+			}
+			if other != cn {
+				return nil, errors.Errorf("cluster %q is selected by both %q and %q in manifest %q", cn, other, sel, m.ID())
+			}
+		}
+	}
+	return keys, nil
+}
+
+// DeploySpecFor returns the DeploySpec of the deployment of m to clusterName,
+// and its key in m.Deployments: either the cluster's name, or a
+// ClusterSelector that selects it.
+func (m *Manifest) DeploySpecFor(defs Defs, clusterName string) (DeploySpec, string, bool) {
+	keys, err := m.DeploymentKeys(defs)
+	if err != nil {
+		return DeploySpec{}, "", false
+	}
+	key, has := keys[clusterName]
+	if !has {
+		return DeploySpec{}, "", false
+	}
+	return m.Deployments[key], key, true
+}
+
+// SetDeploySpec sets the DeploySpec of the deployment of m to clusterName. If
+// the deployment's spec is that of a ClusterSelector that selects no other
+// cluster m deploys to, the selector's spec is replaced; otherwise spec is set
+// for clusterName alone, overriding the selector's, as collapseSelectors
+// would leave it.
+func (m *Manifest) SetDeploySpec(defs Defs, clusterName string, spec DeploySpec) {
+	keys, err := m.DeploymentKeys(defs)
+	key, has := keys[clusterName]
+	if err != nil || !has || key == clusterName {
+		m.Deployments[clusterName] = spec
+		return
+	}
+	for cn, k := range keys {
+		if k == key && cn != clusterName {
+			if !spec.Equal(m.Deployments[key]) {
+				m.Deployments[clusterName] = spec
+			}
+			return
+		}
+	}
+	m.Deployments[key] = spec
+}
+
+// collapseSelectors is the reverse of DeploymentKeys: for each ClusterSelector
+// key in old, it replaces the specs in m for the clusters it stood for with a
+// single spec under that key, keeping only those specs that differ from it.
+func (m *Manifest) collapseSelectors(defs Defs, old *Manifest) {
+	oldKeys, err := old.DeploymentKeys(defs)
+	if err != nil {
+		return
+	}
+	covered := map[string][]string{}
+	for cn, key := range oldKeys {
+		if key != cn {
+			covered[key] = append(covered[key], cn)
+		}
+	}
+	for sel, clusterNames := range covered {
+		var present []string
+		for _, cn := range clusterNames {
+			if _, has := m.Deployments[cn]; has {
+				present = append(present, cn)
+			}
+		}
+		if len(present) == 0 {
+			continue
+		}
+		spec := old.Deployments[sel]
+		same := true
+		for _, cn := range present[1:] {
+			if !m.Deployments[cn].Equal(m.Deployments[present[0]]) {
+				same = false
+				break
+			}
+		}
+		if same {
+			spec = m.Deployments[present[0]]
+		}
+		for _, cn := range present {
+			if m.Deployments[cn].Equal(spec) {
+				delete(m.Deployments, cn)
+			}
+		}
+		m.Deployments[sel] = spec
+	}
+}
+
 // BuildDeployment constructs a deployment out of a Manifest.
 func BuildDeployment(m *Manifest, nick string, cluster *Cluster, spec DeploySpec, inherit []DeploySpec) (*Deployment, error) {
 	ownMap := NewOwnerSet(m.Owners...)
@@ -209,6 +352,9 @@ func BuildDeployment(m *Manifest, nick string, cluster *Cluster, spec DeploySpec
 		Flavor:       m.Flavor,
 		Owners:       ownMap,
 		Kind:         m.Kind,
+		AutoRollback: m.AutoRollback,
+		Drift:        m.Drift,
+		Promotion:    m.Promotion.Clone(),
 		SourceID:     m.Source.SourceID(ds.Version),
 	}, nil
 }
@@ -11,18 +11,23 @@ import (
 
 type nameResolver struct {
 	registry Registry
-	log      logging.LogSink
+	// keys are the keys images must be signed by to be deployed to clusters
+	// that RequireSignedImages.
+	keys TrustedKeys
+	log  logging.LogSink
 }
 
-// ResolveNames resolves diffs.
-func (d *DeployableChans) ResolveNames(ctx context.Context, r Registry, ls logging.LogSink) *DeployableChans {
-	names := &nameResolver{registry: r, log: ls}
+// ResolveNames resolves diffs. Images for clusters that RequireSignedImages
+// must be signed by one of keys.
+func (d *DeployableChans) ResolveNames(ctx context.Context, r Registry, keys TrustedKeys, ls logging.LogSink) *DeployableChans {
+	names := &nameResolver{registry: r, keys: keys, log: ls}
 
 	return d.Pipeline(ctx, names)
 }
 
-func HandlePairsByRegistry(r Registry, dp *DeployablePair, ls logging.LogSink) (*DeployablePair, *DiffResolution) {
-	names := &nameResolver{registry: r, log: ls}
+// HandlePairsByRegistry resolves the artifact of dp with r, like ResolveNames.
+func HandlePairsByRegistry(r Registry, dp *DeployablePair, keys TrustedKeys, ls logging.LogSink) (*DeployablePair, *DiffResolution) {
+	names := &nameResolver{registry: r, keys: keys, log: ls}
 	return names.HandlePairs(dp)
 }
 
@@ -39,7 +44,7 @@ func (names *nameResolver) HandlePairs(dp *DeployablePair) (*DeployablePair, *Di
 		// don't care about docker names
 	case AddedKind, ModifiedKind:
 		var newImageNameResolution *DiffResolution
-		newImageName, newImageNameResolution = resolveName(names.registry, intended, names.log)
+		newImageName, newImageNameResolution = resolveName(names.registry, intended, names.keys, names.log)
 		messages.ReportLogFieldsMessage("Deployment processed, needs artifact", logging.ExtraDebug1Level, names.log, dp.Kind(), intended)
 		if err := newImageNameResolution; err != nil {
 			messages.ReportLogFieldsMessage("Unable to perform action", logging.InformationLevel, names.log, action, intended.ID(), err)
@@ -58,13 +63,13 @@ func (names *nameResolver) HandlePairs(dp *DeployablePair) (*DeployablePair, *Di
 	return &DeployablePair{ExecutorData: dp.ExecutorData, name: dp.name, Prior: dp.Prior, Post: newImageName}, nil
 }
 
-func resolveName(r Registry, d *Deployable, log logging.LogSink) (*Deployable, *DiffResolution) {
+func resolveName(r Registry, d *Deployable, keys TrustedKeys, log logging.LogSink) (*Deployable, *DiffResolution) {
 	if d == nil {
 		return nil, &DiffResolution{
 			Error: &ErrorWrapper{error: fmt.Errorf("nil deployable")},
 		}
 	}
-	art, err := guardImage(r, d.Deployment, log)
+	art, err := guardImage(r, d.Deployment, keys, log)
 	if err != nil {
 		return d, &DiffResolution{
 			DeploymentID: d.ID(),
@@ -75,7 +80,7 @@ func resolveName(r Registry, d *Deployable, log logging.LogSink) (*Deployable, *
 	return d, nil
 }
 
-func guardImage(r Registry, d *Deployment, log logging.LogSink) (*BuildArtifact, error) {
+func guardImage(r Registry, d *Deployment, keys TrustedKeys, log logging.LogSink) (*BuildArtifact, error) {
 	if d.NumInstances == 0 {
 		messages.ReportLogFieldsMessage("Deployment has 0 instances, skipping artifact check", logging.InformationLevel, log, d.ID())
 		return nil, nil
@@ -104,5 +109,26 @@ func guardImage(r Registry, d *Deployment, log logging.LogSink) (*BuildArtifact,
 			return nil, &UnacceptableAdvisory{q, &d.SourceID}
 		}
 	}
+	if d.Cluster != nil && d.Cluster.RequireSignedImages {
+		if err := checkSignature(r, art, keys); err != nil {
+			return nil, &UnsignedImageError{SourceID: d.SourceID, Image: art.DigestReference, Cause: err}
+		}
+		messages.ReportLogFieldsMessage("Image signature verified", logging.DebugLevel, log, d.ID(), art.DigestReference)
+	}
 	return art, err
 }
+
+// checkSignature checks that the provenance of art, which r must be able to
+// get from the registry, is signed by one of keys.
+func checkSignature(r Registry, art *BuildArtifact, keys TrustedKeys) error {
+	store, ok := r.(ProvenanceStore)
+	if !ok {
+		return errors.Errorf("can't get image provenance from a %T", r)
+	}
+	verifier, err := keys.Verifier()
+	if err != nil {
+		return err
+	}
+	_, err = (&ProvenanceCheck{Store: store, Verifier: verifier}).Check(art)
+	return err
+}
@@ -0,0 +1,132 @@
+package sous
+
+import (
+	"fmt"
+	"time"
+)
+
+type (
+	// A Pause stops Sous from resolving the deployments to a cluster, or a
+	// single deployment, until it is resumed, e.g. during an incident.
+	Pause struct {
+		// Cluster is the name of the paused cluster.
+		Cluster string
+		// Manifest, if set, narrows the pause to the deployment of this
+		// manifest to Cluster.
+		Manifest *ManifestID `yaml:",omitempty" json:",omitempty"`
+		// Reason explains the pause to whoever runs into it.
+		Reason string `yaml:",omitempty"`
+		// User is who paused it.
+		User string `yaml:",omitempty"`
+		// Since is when it was paused.
+		Since time.Time
+	}
+
+	// Pauses is a list of Pause.
+	Pauses []Pause
+)
+
+// Target describes what p pauses.
+func (p Pause) Target() string {
+	if p.Manifest == nil {
+		return "cluster " + p.Cluster
+	}
+	return DeploymentID{ManifestID: *p.Manifest, Cluster: p.Cluster}.String()
+}
+
+// QueryMap returns the query identifying what p pauses.
+func (p Pause) QueryMap() map[string]string {
+	q := map[string]string{}
+	if p.Manifest != nil {
+		q = p.Manifest.QueryMap()
+	}
+	q["cluster"] = p.Cluster
+	return q
+}
+
+func (p Pause) String() string {
+	msg := fmt.Sprintf("%s paused by %s since %s", p.Target(), p.User, p.Since.Format(time.RFC1123))
+	if p.Reason != "" {
+		msg += ": " + p.Reason
+	}
+	return msg
+}
+
+// Pauses returns true if p pauses the deployment with ID did.
+func (p Pause) Pauses(did DeploymentID) bool {
+	return p.Cluster == did.Cluster && (p.Manifest == nil || *p.Manifest == did.ManifestID)
+}
+
+// SameTarget returns true if p and o pause the same cluster or deployment.
+func (p Pause) SameTarget(o Pause) bool {
+	if p.Cluster != o.Cluster || (p.Manifest == nil) != (o.Manifest == nil) {
+		return false
+	}
+	return p.Manifest == nil || *p.Manifest == *o.Manifest
+}
+
+// Paused returns the pause of the deployment with ID did, if there is one. A
+// pause of the deployment itself is preferred to one of its cluster.
+func (ps Pauses) Paused(did DeploymentID) (Pause, bool) {
+	var found *Pause
+	for i, p := range ps {
+		if !p.Pauses(did) {
+			continue
+		}
+		if p.Manifest != nil {
+			return p, true
+		}
+		found = &ps[i]
+	}
+	if found == nil {
+		return Pause{}, false
+	}
+	return *found, true
+}
+
+// Find returns the pause with the same target as p, if there is one.
+func (ps Pauses) Find(p Pause) (Pause, bool) {
+	for _, o := range ps {
+		if o.SameTarget(p) {
+			return o, true
+		}
+	}
+	return Pause{}, false
+}
+
+// Set returns ps with p added, replacing any pause with the same target.
+func (ps Pauses) Set(p Pause) Pauses {
+	set, _ := ps.Remove(p)
+	return append(set, p)
+}
+
+// Remove returns ps without the pause with the same target as p, and whether
+// there was one.
+func (ps Pauses) Remove(p Pause) (Pauses, bool) {
+	kept := Pauses{}
+	removed := false
+	for _, o := range ps {
+		if o.SameTarget(p) {
+			removed = true
+			continue
+		}
+		kept = append(kept, o)
+	}
+	return kept, removed
+}
+
+// Clone returns a deep copy of this Pauses.
+func (ps Pauses) Clone() Pauses {
+	if ps == nil {
+		return nil
+	}
+	c := make(Pauses, len(ps))
+	for i, p := range ps {
+		if p.Manifest != nil {
+			mid := *p.Manifest
+			p.Manifest = &mid
+		}
+		c[i] = p
+	}
+	return c
+}
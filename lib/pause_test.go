@@ -0,0 +1,107 @@
+package sous
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/nyarly/spies"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPauses_Paused(t *testing.T) {
+	mid := MustParseManifestID("github.com/ot/one")
+	other := MustParseManifestID("github.com/ot/two")
+	pauses := Pauses{
+		{Cluster: "x", Reason: "cluster"},
+		{Cluster: "x", Manifest: &mid, Reason: "deployment"},
+		{Cluster: "y", Manifest: &mid, Reason: "elsewhere"},
+	}
+
+	p, paused := pauses.Paused(DeploymentID{ManifestID: mid, Cluster: "x"})
+	assert.True(t, paused)
+	assert.Equal(t, "deployment", p.Reason, "the deployment's own pause is preferred")
+
+	p, paused = pauses.Paused(DeploymentID{ManifestID: other, Cluster: "x"})
+	assert.True(t, paused)
+	assert.Equal(t, "cluster", p.Reason)
+
+	_, paused = pauses.Paused(DeploymentID{ManifestID: other, Cluster: "y"})
+	assert.False(t, paused)
+	_, paused = pauses.Paused(DeploymentID{ManifestID: mid, Cluster: "z"})
+	assert.False(t, paused)
+}
+
+func TestPauses_SetRemove(t *testing.T) {
+	mid := MustParseManifestID("github.com/ot/one")
+	pauses := Pauses{}.Set(Pause{Cluster: "x", Reason: "first"})
+	pauses = pauses.Set(Pause{Cluster: "x", Manifest: &mid})
+	pauses = pauses.Set(Pause{Cluster: "x", Reason: "second"})
+	require.Len(t, pauses, 2)
+
+	p, found := pauses.Find(Pause{Cluster: "x"})
+	assert.True(t, found)
+	assert.Equal(t, "second", p.Reason)
+
+	pauses, removed := pauses.Remove(Pause{Cluster: "x"})
+	assert.True(t, removed)
+	require.Len(t, pauses, 1)
+	assert.NotNil(t, pauses[0].Manifest)
+
+	_, removed = pauses.Remove(Pause{Cluster: "y"})
+	assert.False(t, removed)
+
+	clone := pauses.Clone()
+	clone[0].Manifest.Flavor = "changed"
+	assert.Equal(t, "", pauses[0].Manifest.Flavor)
+	assert.Empty(t, pauses.Diff(pauses.Clone()))
+	assert.NotEmpty(t, pauses.Diff(clone))
+}
+
+func TestResolver_BeginWithDefs_pauses(t *testing.T) {
+	clusters := Clusters{"x": {Name: "x"}, "y": {Name: "y"}}
+	deployment := func(repo, cluster string) *Deployment {
+		return &Deployment{
+			ClusterName:  cluster,
+			Cluster:      clusters[cluster],
+			SourceID:     MustParseSourceID(fmt.Sprintf("github.com/ot/%s,1.0.0", repo)),
+			DeployConfig: DeployConfig{NumInstances: 1},
+		}
+	}
+
+	deployer, dctrl := NewDeployerSpy()
+	dctrl.MatchMethod("RunningDeployments", spies.AnyArgs, NewDeployStates(
+		&DeployState{Deployment: *deployment("unwanted", "y"), Status: DeployStatusActive},
+	), nil)
+
+	var lock sync.Mutex
+	rectified := []string{}
+	qs := NewR11nQueueSet(R11nQueueStartWithHandler(func(qr *QueuedR11n) DiffResolution {
+		lock.Lock()
+		defer lock.Unlock()
+		rectified = append(rectified, qr.Rectification.Pair.ID().String())
+		return DiffResolution{DeploymentID: qr.Rectification.Pair.ID(), Desc: CreateDiff}
+	}))
+	r := NewResolver(deployer, NewDummyRegistry(), &ResolveFilter{}, logging.SilentLogSet(), qs)
+
+	pausedMID := MustParseManifestID("github.com/ot/paused")
+	defs := Defs{
+		Clusters: clusters,
+		Pauses: Pauses{
+			{Cluster: "y"},
+			{Cluster: "x", Manifest: &pausedMID},
+		},
+	}
+	intended := NewDeployments(
+		deployment("one", "x"),
+		deployment("paused", "x"),
+		deployment("one", "y"),
+	)
+	require.NoError(t, r.BeginWithDefs(intended, defs).Wait())
+
+	sort.Strings(rectified)
+	assert.Equal(t, []string{deployment("one", "x").ID().String()}, rectified)
+}
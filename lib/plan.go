@@ -0,0 +1,144 @@
+package sous
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// A Plan describes what resolving would do to bring running deployments in
+	// line with intended ones, without doing any of it.
+	Plan struct {
+		// Changes has a PlannedChange for every DeployablePair, sorted by
+		// DeploymentID, including those which are the same.
+		Changes []PlannedChange
+		// Errors are the errors from diffing which aren't about a particular
+		// change.
+		Errors []DiffResolution
+	}
+
+	// A PlannedChange is a DeployablePair, and what rectifying it would do.
+	PlannedChange struct {
+		DeploymentID DeploymentID
+		// Kind is "same", "added", "removed" or "modified".
+		Kind        string
+		Differences Differences `json:",omitempty"`
+		Prior, Post *Deployment
+		// Artifact is the artifact Post would be deployed from.
+		Artifact *BuildArtifact `json:",omitempty"`
+		// Error is why the change can't be made, e.g. because no artifact was
+		// found, or a freeze forbids it.
+		Error *ErrorWrapper `json:",omitempty"`
+		// Skipped explains why rectification would not act on the change.
+		Skipped string `json:",omitempty"`
+	}
+)
+
+// Plan runs the phases of resolution up to and including resolving deployment
+// artifacts, and returns what rectification would do, honoring the freezes and
+// pauses in defs. Nothing is deployed.
+func (r *Resolver) Plan(intended Deployments, defs Defs) (*Plan, error) {
+	clusters := defs.Clusters
+	rf := r.ResolveFilter.SelectClusters(clusters)
+	intended = intended.Filter(rf.FilterDeployment)
+	clusters = rf.FilteredClusters(clusters)
+
+	actual, err := r.Deployer.RunningDeployments(r.Registry, clusters)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting running deployments")
+	}
+	actual = actual.Filter(rf.FilterDeployStates)
+
+	diffs := actual.Diff(intended)
+	plan := &Plan{Changes: []PlannedChange{}, Errors: []DiffResolution{}}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for rez := range diffs.Errs {
+			plan.Errors = append(plan.Errors, *rez)
+		}
+	}()
+
+	names := &nameResolver{registry: r.Registry, keys: defs.TrustedKeys, log: r.ls}
+	for p := range diffs.Pairs {
+		plan.Changes = append(plan.Changes, planChange(p, names, defs, r.Drift))
+	}
+	<-done
+
+	sort.Slice(plan.Changes, func(i, j int) bool {
+		return plan.Changes[i].DeploymentID.String() < plan.Changes[j].DeploymentID.String()
+	})
+	return plan, nil
+}
+
+// planChange returns the PlannedChange for p, resolving its artifact with
+// names.
+func planChange(p *DeployablePair, names *nameResolver, defs Defs, drift *DriftDetector) PlannedChange {
+	pc := PlannedChange{
+		DeploymentID: p.ID(),
+		Kind:         p.Kind().String(),
+	}
+	if p.Prior != nil {
+		pc.Prior = p.Prior.Deployment
+	}
+	if p.Post != nil {
+		pc.Post = p.Post.Deployment
+	}
+	if p.Prior != nil && p.Post != nil {
+		pc.Differences = p.Diffs()
+	}
+
+	// Paused deployments are left out of resolution altogether.
+	if pause, paused := defs.Pauses.Paused(p.ID()); paused {
+		pc.Skipped = pause.String()
+		return pc
+	}
+	// The same checks as queueDiffs.
+	if p.Kind() == AddedKind && (p.Post.NumInstances == 0 ||
+		p.Post.DeploySpec().Version.String() == "0.0.0") {
+		pc.Skipped = "new deployment has no instances or version"
+		return pc
+	}
+	if err := checkVersionFreeze(p, defs.Freezes); err != nil {
+		pc.Error = WrapResolveError(err)
+		return pc
+	}
+	if drift.ReportOnly(p) {
+		pc.Skipped = "drift is reported, not reverted"
+		return pc
+	}
+
+	resolved, rez := names.HandlePairs(p)
+	if rez != nil {
+		pc.Error = rez.Error
+		return pc
+	}
+	if resolved.Post != nil {
+		pc.Artifact = resolved.Post.BuildArtifact
+	}
+	return pc
+}
+
+// Count returns the number of changes of kind which can be made.
+func (p *Plan) Count(kind DeployablePairKind) int {
+	n := 0
+	for _, c := range p.Changes {
+		if c.Kind == kind.String() && c.Error == nil && c.Skipped == "" {
+			n++
+		}
+	}
+	return n
+}
+
+// ErrorCount returns the number of changes which can't be made, plus the
+// number of other errors.
+func (p *Plan) ErrorCount() int {
+	n := len(p.Errors)
+	for _, c := range p.Changes {
+		if c.Error != nil {
+			n++
+		}
+	}
+	return n
+}
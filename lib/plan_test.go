@@ -0,0 +1,81 @@
+package sous
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nyarly/spies"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolver_Plan(t *testing.T) {
+	cluster := &Cluster{Name: "x"}
+	deployment := func(repo, version string) *Deployment {
+		return &Deployment{
+			ClusterName:  "x",
+			Cluster:      cluster,
+			SourceID:     MustParseSourceID(fmt.Sprintf("github.com/ot/%s,%s", repo, version)),
+			DeployConfig: DeployConfig{NumInstances: 1},
+		}
+	}
+	running := func(d *Deployment) *DeployState {
+		return &DeployState{Deployment: *d, Status: DeployStatusActive}
+	}
+
+	deployer, dctrl := NewDeployerSpy()
+	dctrl.MatchMethod("RunningDeployments", spies.AnyArgs, NewDeployStates(
+		running(deployment("same", "1.0.0")),
+		running(deployment("modified", "1.0.0")),
+		running(deployment("removed", "1.0.0")),
+	), nil)
+
+	registry, rctrl := NewRegistrySpy()
+	missing := func(args mock.Arguments) bool { return args.Get(0).(SourceID).Location.Repo == "github.com/ot/added" }
+	rctrl.MatchMethod("GetArtifact", missing, (*BuildArtifact)(nil), fmt.Errorf("no such image"))
+	rctrl.MatchMethod("GetArtifact", spies.AnyArgs, &BuildArtifact{DigestReference: "docker.example.com/modified@sha256:abc"}, nil)
+
+	ls, _ := logging.NewLogSinkSpy()
+	r := NewResolver(deployer, registry, &ResolveFilter{}, ls, nil)
+
+	intended := NewDeployments(
+		deployment("same", "1.0.0"),
+		deployment("modified", "2.0.0"),
+		deployment("added", "1.0.0"),
+	)
+	plan, err := r.Plan(intended, Defs{Clusters: Clusters{"x": cluster}})
+	require.NoError(t, err)
+	require.Len(t, plan.Changes, 4)
+
+	changes := map[string]PlannedChange{}
+	for _, c := range plan.Changes {
+		changes[c.DeploymentID.ManifestID.Source.Repo] = c
+	}
+
+	assert.Equal(t, "same", changes["github.com/ot/same"].Kind)
+	assert.Empty(t, changes["github.com/ot/same"].Differences)
+
+	modified := changes["github.com/ot/modified"]
+	assert.Equal(t, "modified", modified.Kind)
+	assert.NotEmpty(t, modified.Differences)
+	require.NotNil(t, modified.Artifact)
+	assert.Equal(t, "docker.example.com/modified@sha256:abc", modified.Artifact.DigestReference)
+	assert.Nil(t, modified.Error)
+
+	assert.Equal(t, "removed", changes["github.com/ot/removed"].Kind)
+	assert.Nil(t, changes["github.com/ot/removed"].Post)
+
+	added := changes["github.com/ot/added"]
+	assert.Equal(t, "added", added.Kind)
+	require.NotNil(t, added.Error)
+	assert.Contains(t, added.Error.Error(), "no such image")
+
+	assert.Equal(t, 1, plan.Count(ModifiedKind))
+	assert.Equal(t, 0, plan.Count(AddedKind))
+	assert.Equal(t, 1, plan.Count(RemovedKind))
+	assert.Equal(t, 1, plan.ErrorCount())
+
+	assert.Empty(t, dctrl.CallsTo("Rectify"))
+}
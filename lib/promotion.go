@@ -0,0 +1,473 @@
+package sous
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+	"github.com/samsalisbury/semv"
+)
+
+type (
+	// A Promotion describes how a new version of a manifest is rolled out
+	// across its clusters: one stage at a time, each stage starting only once
+	// the previous one is stable.
+	Promotion struct {
+		// Stages are deployed in order.
+		Stages []PromotionStage
+	}
+
+	// A PromotionStage is a set of clusters deployed to together.
+	PromotionStage struct {
+		// Clusters are the names of the clusters in this stage.
+		Clusters []string
+		// StableMinutes is how long the new version must have been running in
+		// every cluster of this stage before the next stage starts. Its status
+		// is polled every PromotionStableInterval for that long, and the
+		// promotion fails the first time it isn't complete.
+		StableMinutes int `yaml:",omitempty"`
+	}
+
+	// PromotionStatus describes how far a PromotionRun has got.
+	PromotionStatus string
+
+	// A PromotionRun records the progress of a single version through a
+	// manifest's Promotion.
+	PromotionRun struct {
+		ManifestID ManifestID
+		Version    semv.Version
+		User       User
+		Stages     []PromotionStage
+		// Stage is the index into Stages of the stage being deployed, or of
+		// the last stage reached once the run is over.
+		Stage  int
+		Status PromotionStatus
+		// Error explains why a failed run failed.
+		Error            string `json:",omitempty"`
+		Started, Updated time.Time
+	}
+
+	// A StatusWaiter waits for deployments to resolve. StatusPoller is a
+	// StatusWaiter.
+	StatusWaiter interface {
+		Wait(ctx context.Context) (ResolveState, error)
+	}
+
+	// A RemoteDeployer deploys to clusters through the servers that resolve
+	// them.
+	RemoteDeployer interface {
+		// Deploy deploys version to the deployment did, as user, through the
+		// server of did.Cluster, and waits for it to be rectified.
+		Deploy(did DeploymentID, version semv.Version, user User) error
+	}
+
+	// PromotionEngine advances versions through the stages of their manifests'
+	// Promotions. Deployments to the clusters this server resolves are
+	// written to the GDM and rectified through QueueSet; those to other
+	// clusters are handed to Remote. Stable periods are confirmed with a
+	// StatusWaiter.
+	//
+	// Runs are only kept in memory: a restart of the server drops them,
+	// stopping any in progress after the stage being deployed.
+	PromotionEngine struct {
+		StateReader       StateReader
+		DeploymentManager DeploymentManager
+		QueueSet          QueueSet
+		// Filter selects the clusters this server resolves. Nil selects all
+		// of them.
+		Filter *ResolveFilter
+		// Remote, if set, deploys to the clusters Filter doesn't select.
+		Remote RemoteDeployer
+		// NewPoller returns a StatusWaiter for the deployment matched by rf.
+		NewPoller func(rf *ResolveFilter) (StatusWaiter, error)
+
+		log   logging.LogSink
+		after func(time.Duration) <-chan time.Time
+
+		sync.RWMutex
+		runs map[promotionKey]*PromotionRun
+	}
+
+	promotionKey struct {
+		mid     ManifestID
+		version string
+	}
+)
+
+const (
+	// PromotionRunning means the run is deploying a stage or waiting for it to
+	// be stable.
+	PromotionRunning = PromotionStatus("running")
+	// PromotionSucceeded means every stage was deployed and found stable.
+	PromotionSucceeded = PromotionStatus("succeeded")
+	// PromotionFailed means the run stopped at Stage; see Error.
+	PromotionFailed = PromotionStatus("failed")
+)
+
+// PromotionPollTimeout bounds how long each poll of a stage's status waits
+// for it to resolve.
+const PromotionPollTimeout = 10 * time.Minute
+
+// PromotionStableInterval is how often a stage's status is polled while it
+// must stay stable.
+const PromotionStableInterval = time.Minute
+
+// Clone returns a deep copy of this Promotion.
+func (p *Promotion) Clone() *Promotion {
+	if p == nil {
+		return nil
+	}
+	c := &Promotion{Stages: make([]PromotionStage, len(p.Stages))}
+	for i, s := range p.Stages {
+		s.Clusters = append([]string(nil), s.Clusters...)
+		c.Stages[i] = s
+	}
+	return c
+}
+
+// Diff returns true and a list of differences if p and o are not equal.
+func (p *Promotion) Diff(o *Promotion) (bool, []string) {
+	var diffs []string
+	diff := func(format string, a ...interface{}) { diffs = append(diffs, fmt.Sprintf(format, a...)) }
+	if (p == nil) != (o == nil) {
+		diff("promotion; this: %v; other: %v", p, o)
+		return true, diffs
+	}
+	if p == nil {
+		return false, nil
+	}
+	if len(p.Stages) != len(o.Stages) {
+		diff("number of promotion stages; this: %d; other: %d", len(p.Stages), len(o.Stages))
+		return true, diffs
+	}
+	for i, s := range p.Stages {
+		t := o.Stages[i]
+		if fmt.Sprint(s.Clusters) != fmt.Sprint(t.Clusters) {
+			diff("promotion stage %d clusters; this: %v; other: %v", i+1, s.Clusters, t.Clusters)
+		}
+		if s.StableMinutes != t.StableMinutes {
+			diff("promotion stage %d stable minutes; this: %d; other: %d", i+1, s.StableMinutes, t.StableMinutes)
+		}
+	}
+	return len(diffs) != 0, diffs
+}
+
+// Validate returns flaws in p, the Promotion of m. That its stages only name
+// clusters m deploys to is checked by Defs.ValidateManifest, since its
+// deployments may be keyed by ClusterSelectors.
+func (p *Promotion) Validate(m *Manifest) []Flaw {
+	var flaws []Flaw
+	if p == nil {
+		return flaws
+	}
+	if len(p.Stages) == 0 {
+		flaws = append(flaws, FatalFlaw("manifest %q has a promotion with no stages", m.ID()))
+	}
+	seen := map[string]bool{}
+	for i, s := range p.Stages {
+		if len(s.Clusters) == 0 {
+			flaws = append(flaws, FatalFlaw("manifest %q promotion stage %d has no clusters", m.ID(), i+1))
+		}
+		if s.StableMinutes < 0 {
+			flaws = append(flaws, FatalFlaw("manifest %q promotion stage %d has negative StableMinutes", m.ID(), i+1))
+		}
+		for _, c := range s.Clusters {
+			if seen[c] {
+				flaws = append(flaws, FatalFlaw("manifest %q promotion names cluster %q more than once", m.ID(), c))
+			}
+			seen[c] = true
+		}
+	}
+	return flaws
+}
+
+func (r PromotionRun) String() string {
+	switch r.Status {
+	default:
+		return fmt.Sprintf("%s of %s at %s", r.Status, r.ManifestID, r.Version)
+	case PromotionRunning:
+		var clusters []string
+		if r.Stage < len(r.Stages) {
+			clusters = r.Stages[r.Stage].Clusters
+		}
+		return fmt.Sprintf("promoting %s to %s: stage %d of %d %v",
+			r.ManifestID, r.Version, r.Stage+1, len(r.Stages), clusters)
+	case PromotionFailed:
+		return fmt.Sprintf("promotion of %s to %s failed at stage %d of %d: %s",
+			r.ManifestID, r.Version, r.Stage+1, len(r.Stages), r.Error)
+	case PromotionSucceeded:
+		return fmt.Sprintf("promoted %s to %s in all %d stages", r.ManifestID, r.Version, len(r.Stages))
+	}
+}
+
+// NewPromotionEngine returns a PromotionEngine with no runs.
+func NewPromotionEngine(sr StateReader, dm DeploymentManager, qs QueueSet, newPoller func(*ResolveFilter) (StatusWaiter, error), ls logging.LogSink) *PromotionEngine {
+	return &PromotionEngine{
+		StateReader:       sr,
+		DeploymentManager: dm,
+		QueueSet:          qs,
+		NewPoller:         newPoller,
+		log:               ls,
+		after:             time.After,
+		runs:              map[promotionKey]*PromotionRun{},
+	}
+}
+
+// Start begins promoting version of the manifest identified by mid through
+// its Promotion, in the background. It returns an error if the manifest has
+// no Promotion, or if a promotion of the same version is already running.
+func (pe *PromotionEngine) Start(mid ManifestID, version semv.Version, user User) (PromotionRun, error) {
+	state, err := pe.StateReader.ReadState()
+	if err != nil {
+		return PromotionRun{}, err
+	}
+	m, ok := state.Manifests.Get(mid)
+	if !ok {
+		return PromotionRun{}, errors.Errorf("no manifest with ID %q", mid)
+	}
+	if m.Promotion == nil || len(m.Promotion.Stages) == 0 {
+		return PromotionRun{}, errors.Errorf("manifest %q has no promotion stages", mid)
+	}
+	if pe.Remote == nil {
+		for _, stage := range m.Promotion.Stages {
+			for _, cluster := range stage.Clusters {
+				if !pe.local(state.Defs, cluster) {
+					return PromotionRun{}, errors.Errorf("cluster %q is resolved by another server, which this server can't deploy through", cluster)
+				}
+			}
+		}
+	}
+
+	key := promotionKey{mid: mid, version: version.String()}
+	now := time.Now()
+	run := &PromotionRun{
+		ManifestID: mid,
+		Version:    version,
+		User:       user,
+		Stages:     m.Promotion.Clone().Stages,
+		Status:     PromotionRunning,
+		Started:    now,
+		Updated:    now,
+	}
+
+	pe.Lock()
+	if prior, has := pe.runs[key]; has && prior.Status == PromotionRunning {
+		pe.Unlock()
+		return PromotionRun{}, errors.Errorf("promotion of %s to %s is already running", mid, version)
+	}
+	pe.runs[key] = run
+	c := *run
+	pe.Unlock()
+
+	go pe.promote(key)
+	return c, nil
+}
+
+// Run returns the latest run promoting version of mid.
+func (pe *PromotionEngine) Run(mid ManifestID, version semv.Version) (PromotionRun, bool) {
+	pe.RLock()
+	defer pe.RUnlock()
+	run, has := pe.runs[promotionKey{mid: mid, version: version.String()}]
+	if !has {
+		return PromotionRun{}, false
+	}
+	return *run, true
+}
+
+// Forget discards the record of a finished run, so that the same version
+// can be promoted again.
+func (pe *PromotionEngine) Forget(mid ManifestID, version semv.Version) error {
+	pe.Lock()
+	defer pe.Unlock()
+	key := promotionKey{mid: mid, version: version.String()}
+	if run, has := pe.runs[key]; has && run.Status == PromotionRunning {
+		return errors.Errorf("promotion of %s to %s is still running", mid, version)
+	}
+	delete(pe.runs, key)
+	return nil
+}
+
+// promote deploys each stage of the run identified by key in turn, stopping
+// at the first that fails.
+func (pe *PromotionEngine) promote(key promotionKey) {
+	pe.RLock()
+	run := *pe.runs[key]
+	pe.RUnlock()
+
+	for i, stage := range run.Stages {
+		pe.update(key, func(r *PromotionRun) { r.Stage = i })
+		messages.ReportLogFieldsMessage(fmt.Sprintf("Promotion of %s to %s: starting stage %d %v", run.ManifestID, run.Version, i+1, stage.Clusters),
+			logging.InformationLevel, pe.log)
+		if err := pe.promoteStage(run, stage); err != nil {
+			messages.ReportLogFieldsMessage("Promotion failed", logging.WarningLevel, pe.log, run.ManifestID, err)
+			pe.update(key, func(r *PromotionRun) {
+				r.Status = PromotionFailed
+				r.Error = err.Error()
+			})
+			return
+		}
+	}
+	pe.update(key, func(r *PromotionRun) { r.Status = PromotionSucceeded })
+}
+
+func (pe *PromotionEngine) update(key promotionKey, f func(*PromotionRun)) {
+	pe.Lock()
+	defer pe.Unlock()
+	run := pe.runs[key]
+	f(run)
+	run.Updated = time.Now()
+}
+
+// promoteStage deploys run.Version to each cluster of stage, waits for the
+// rectifications to complete, and then for the stage to prove stable.
+func (pe *PromotionEngine) promoteStage(run PromotionRun, stage PromotionStage) error {
+	state, err := pe.StateReader.ReadState()
+	if err != nil {
+		return err
+	}
+	deps, err := state.Deployments()
+	if err != nil {
+		return err
+	}
+
+	// Check every cluster of the stage before deploying to any of them, so
+	// that a freeze or pause doesn't leave the stage half deployed.
+	stageDeps := make([]*Deployment, 0, len(stage.Clusters))
+	for _, cluster := range stage.Clusters {
+		did := DeploymentID{ManifestID: run.ManifestID, Cluster: cluster}
+		dep, has := deps.Get(did)
+		if !has {
+			return errors.Errorf("no deployment of %s", did)
+		}
+		if err := state.Defs.Freezes.Check(did, dep.Owners, time.Now()); err != nil {
+			return err
+		}
+		if pause, paused := state.Defs.Pauses.Paused(did); paused {
+			return errors.New(pause.String())
+		}
+		stageDeps = append(stageDeps, dep)
+	}
+
+	queued := map[DeploymentID]R11nID{}
+	var remote []DeploymentID
+	for _, dep := range stageDeps {
+		did, cluster := dep.ID(), dep.ClusterName
+		if dep.SourceID.Version.String() == run.Version.String() {
+			continue
+		}
+		if !pe.local(state.Defs, cluster) {
+			remote = append(remote, did)
+			continue
+		}
+		dep = dep.Clone()
+		dep.SourceID.Version = run.Version
+		if err := pe.DeploymentManager.WriteDeployment(dep, run.User); err != nil {
+			return errors.Wrapf(err, "writing deployment of %s", did)
+		}
+		dep.User = run.User
+
+		r := NewRectification(DeployablePair{Post: &Deployable{Deployment: dep}}, pe.log.Child("r11n"))
+		r.Pair.SetID(did)
+		qr, ok := pe.QueueSet.Push(r)
+		if !ok {
+			return errors.Errorf("deploy queue for %s is full", did)
+		}
+		queued[did] = qr.ID
+	}
+
+	if err := pe.deployRemote(run, remote); err != nil {
+		return err
+	}
+
+	for did, id := range queued {
+		rez, ok := pe.QueueSet.Wait(did, id)
+		if !ok {
+			return errors.Errorf("deploy of %s was not found in its queue", did)
+		}
+		if rez.Error != nil {
+			return errors.Wrapf(rez.Error, "deploying %s", did)
+		}
+	}
+
+	return pe.awaitStable(run, stage)
+}
+
+// awaitStable polls the status of stage every PromotionStableInterval for
+// its StableMinutes, failing the first time it isn't stable.
+func (pe *PromotionEngine) awaitStable(run PromotionRun, stage PromotionStage) error {
+	if stage.StableMinutes == 0 {
+		return nil
+	}
+	window := time.Duration(stage.StableMinutes) * time.Minute
+	for waited := time.Duration(0); waited < window; waited += PromotionStableInterval {
+		if err := pe.checkStable(run, stage, waited); err != nil {
+			return err
+		}
+		<-pe.after(PromotionStableInterval)
+	}
+	return pe.checkStable(run, stage, window)
+}
+
+// local returns true if cluster, defined in defs, is resolved by this server.
+func (pe *PromotionEngine) local(defs Defs, cluster string) bool {
+	return pe.Filter == nil || pe.Filter.SelectClusters(defs.Clusters).FilterClusterName(cluster)
+}
+
+// deployRemote deploys run.Version to each of dids through Remote, together.
+func (pe *PromotionEngine) deployRemote(run PromotionRun, dids []DeploymentID) error {
+	if len(dids) == 0 {
+		return nil
+	}
+	if pe.Remote == nil {
+		return errors.Errorf("%s is resolved by another server, which this server can't deploy through", dids[0])
+	}
+	errs := make(chan error, len(dids))
+	for _, did := range dids {
+		go func(did DeploymentID) {
+			errs <- errors.Wrapf(pe.Remote.Deploy(did, run.Version, run.User), "deploying %s", did)
+		}(did)
+	}
+	var err error
+	for range dids {
+		if e := <-errs; e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// checkStable confirms that each cluster of stage has resolved run.Version,
+// which was deployed waited ago.
+func (pe *PromotionEngine) checkStable(run PromotionRun, stage PromotionStage, waited time.Duration) error {
+	if pe.NewPoller == nil {
+		return errors.Errorf("no status poller to confirm stage %v is stable", stage.Clusters)
+	}
+	for _, cluster := range stage.Clusters {
+		rf := &ResolveFilter{
+			Repo:    NewResolveFieldMatcher(run.ManifestID.Source.Repo),
+			Offset:  NewResolveFieldMatcher(run.ManifestID.Source.Dir),
+			Flavor:  NewResolveFieldMatcher(run.ManifestID.Flavor),
+			Cluster: NewResolveFieldMatcher(cluster),
+			Tag:     NewResolveFieldMatcher(run.Version.Format(semv.Complete)),
+		}
+		poller, err := pe.NewPoller(rf)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), PromotionPollTimeout)
+		rs, err := poller.Wait(ctx)
+		cancel()
+		if err != nil {
+			return errors.Wrapf(err, "polling status of %s in %s", run.ManifestID, cluster)
+		}
+		if rs != ResolveComplete {
+			return errors.Errorf("%s is not stable in %s after %s: %s",
+				run.ManifestID, cluster, waited, rs)
+		}
+	}
+	return nil
+}
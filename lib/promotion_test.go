@@ -0,0 +1,307 @@
+package sous
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nyarly/spies"
+	"github.com/opentable/sous/util/logging"
+	"github.com/samsalisbury/semv"
+)
+
+type statusWaiterStub ResolveState
+
+func (s statusWaiterStub) Wait(context.Context) (ResolveState, error) {
+	return ResolveState(s), nil
+}
+
+func promotionFixture(t *testing.T, stable ResolveState, rezErr error) (*PromotionEngine, ManifestID, *spies.Spy, *spies.Spy, *[]*ResolveFilter) {
+	t.Helper()
+	state := DefaultStateFixture()
+	mid := MustParseManifestID("github.com/user1/repo1,dir1~flavor1")
+	m, ok := state.Manifests.Get(mid)
+	if !ok {
+		t.Fatalf("fixture has no manifest %q", mid)
+	}
+	m.Promotion = &Promotion{Stages: []PromotionStage{
+		{Clusters: []string{"cluster0", "cluster1"}},
+		{Clusters: []string{"cluster2"}, StableMinutes: 30},
+	}}
+
+	dm, dmCtrl := NewDeploymentManagerSpy()
+	dmCtrl.MatchMethod("WriteDeployment", spies.AnyArgs, nil)
+	qs, qsCtrl := NewQueueSetSpy()
+	qsCtrl.MatchMethod("Push", spies.AnyArgs, &QueuedR11n{ID: "r11n"}, true)
+	rez := DiffResolution{}
+	if rezErr != nil {
+		rez.Error = WrapResolveError(rezErr)
+	}
+	qsCtrl.MatchMethod("Wait", spies.AnyArgs, rez, true)
+
+	polled := &[]*ResolveFilter{}
+	newPoller := func(rf *ResolveFilter) (StatusWaiter, error) {
+		*polled = append(*polled, rf)
+		return statusWaiterStub(stable), nil
+	}
+
+	pe := NewPromotionEngine(&DummyStateManager{State: state}, dm, qs, newPoller, logging.SilentLogSet())
+	pe.after = func(time.Duration) <-chan time.Time {
+		c := make(chan time.Time, 1)
+		c <- time.Now()
+		return c
+	}
+	return pe, mid, dmCtrl, qsCtrl, polled
+}
+
+func startPromotion(t *testing.T, pe *PromotionEngine, mid ManifestID) PromotionRun {
+	t.Helper()
+	version := semv.MustParse("2.0.0")
+	if _, err := pe.Start(mid, version, User{Name: "Test User"}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		run, _ := pe.Run(mid, version)
+		if run.Status != PromotionRunning {
+			return run
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("promotion didn't finish")
+	return PromotionRun{}
+}
+
+func TestPromotionEngine_succeeds(t *testing.T) {
+	pe, mid, dmCtrl, qsCtrl, polled := promotionFixture(t, ResolveComplete, nil)
+
+	run := startPromotion(t, pe, mid)
+	if run.Status != PromotionSucceeded {
+		t.Fatalf("got %s", run)
+	}
+
+	writes := dmCtrl.CallsTo("WriteDeployment")
+	if len(writes) != 3 {
+		t.Fatalf("got %d deployments written; want 3", len(writes))
+	}
+	clusters := []string{}
+	for _, w := range writes {
+		dep := w.PassedArgs().Get(0).(*Deployment)
+		if dep.SourceID.Version.String() != "2.0.0" {
+			t.Errorf("%s written at %s; want 2.0.0", dep.ClusterName, dep.SourceID.Version)
+		}
+		clusters = append(clusters, dep.ClusterName)
+	}
+	if last := clusters[2]; last != "cluster2" {
+		t.Errorf("got clusters written in order %v; want cluster2 last", clusters)
+	}
+	if n := len(qsCtrl.CallsTo("Push")); n != 3 {
+		t.Errorf("got %d rectifications queued; want 3", n)
+	}
+
+	if len(*polled) != 31 {
+		t.Fatalf("got %d status polls; want 31, every minute of the stage's 30 StableMinutes", len(*polled))
+	}
+	if c, _ := (*polled)[0].Cluster.Value(); c != "cluster2" {
+		t.Errorf("polled cluster %q; want cluster2", c)
+	}
+}
+
+func TestPromotionEngine_deployFails(t *testing.T) {
+	pe, mid, dmCtrl, _, polled := promotionFixture(t, ResolveComplete, errors.New("deploy failed"))
+
+	run := startPromotion(t, pe, mid)
+	if run.Status != PromotionFailed || run.Stage != 0 {
+		t.Fatalf("got %s; want failure at the first stage", run)
+	}
+	if len(dmCtrl.CallsTo("WriteDeployment")) != 2 {
+		t.Errorf("expected only the first stage to be written")
+	}
+	if len(*polled) != 0 {
+		t.Errorf("expected no status polls")
+	}
+}
+
+func TestPromotionEngine_notStable(t *testing.T) {
+	pe, mid, _, _, _ := promotionFixture(t, ResolveFailed, nil)
+
+	run := startPromotion(t, pe, mid)
+	if run.Status != PromotionFailed || run.Stage != 1 {
+		t.Fatalf("got %s; want failure at the second stage", run)
+	}
+	if !strings.Contains(run.Error, "not stable in cluster2") {
+		t.Errorf("got error %q", run.Error)
+	}
+}
+
+type statusWaiterSeq struct {
+	states []ResolveState
+	polls  *int
+}
+
+func (s statusWaiterSeq) Wait(context.Context) (ResolveState, error) {
+	rs := s.states[*s.polls%len(s.states)]
+	*s.polls++
+	return rs, nil
+}
+
+func TestPromotionEngine_unstableDuringWindow(t *testing.T) {
+	pe, mid, _, _, _ := promotionFixture(t, ResolveComplete, nil)
+	polls := 0
+	seq := make([]ResolveState, 40)
+	for i := range seq {
+		seq[i] = ResolveComplete
+	}
+	seq[10] = ResolveFailed
+	pe.NewPoller = func(*ResolveFilter) (StatusWaiter, error) {
+		return statusWaiterSeq{states: seq, polls: &polls}, nil
+	}
+
+	run := startPromotion(t, pe, mid)
+	if run.Status != PromotionFailed || run.Stage != 1 {
+		t.Fatalf("got %s; want failure at the second stage", run)
+	}
+	if polls != 11 {
+		t.Errorf("got %d polls; want the promotion to fail at the 11th", polls)
+	}
+	if !strings.Contains(run.Error, "after 10m0s") {
+		t.Errorf("got error %q", run.Error)
+	}
+}
+
+func TestPromotionEngine_paused(t *testing.T) {
+	pe, mid, dmCtrl, _, _ := promotionFixture(t, ResolveComplete, nil)
+	state := pe.StateReader.(*DummyStateManager).State
+	state.Defs.Pauses = state.Defs.Pauses.Set(Pause{Cluster: "cluster1", Manifest: &mid, User: "oncall", Reason: "incident"})
+
+	run := startPromotion(t, pe, mid)
+	if run.Status != PromotionFailed || run.Stage != 0 {
+		t.Fatalf("got %s; want failure at the first stage", run)
+	}
+	if !strings.Contains(run.Error, "incident") {
+		t.Errorf("got error %q; want the pause", run.Error)
+	}
+	if n := len(dmCtrl.CallsTo("WriteDeployment")); n != 0 {
+		t.Errorf("got %d deployments written; want none", n)
+	}
+}
+
+func TestPromotionEngine_Start_noPromotion(t *testing.T) {
+	pe, _, _, _, _ := promotionFixture(t, ResolveComplete, nil)
+	mid := MustParseManifestID("github.com/user2/repo2,dir2~flavor2")
+	if _, err := pe.Start(mid, semv.MustParse("2.0.0"), User{}); err == nil {
+		t.Errorf("expected an error starting a promotion without stages")
+	}
+}
+
+func TestPromotion_Validate(t *testing.T) {
+	m := &Manifest{
+		Source:      SourceLocation{Repo: "github.com/example/app"},
+		Deployments: DeploySpecs{"ci": {}, "prod": {}},
+	}
+	good := &Promotion{Stages: []PromotionStage{{Clusters: []string{"ci"}}, {Clusters: []string{"prod"}}}}
+	if flaws := good.Validate(m); len(flaws) != 0 {
+		t.Errorf("unexpected flaws: %v", flaws)
+	}
+
+	bad := &Promotion{Stages: []PromotionStage{
+		{Clusters: []string{"ci", "staging"}},
+		{Clusters: []string{"ci"}, StableMinutes: -1},
+	}}
+	if flaws := bad.Validate(m); len(flaws) != 2 {
+		t.Errorf("got %d flaws; want 2: %v", len(flaws), flaws)
+	}
+}
+
+func TestDefs_ValidateManifest_promotion(t *testing.T) {
+	defs := Defs{Clusters: Clusters{
+		"ci":      {Name: "ci"},
+		"staging": {Name: "staging"},
+		"prod-1":  {Name: "prod-1", Labels: map[string]string{"env": "prod"}},
+		"prod-2":  {Name: "prod-2", Labels: map[string]string{"env": "prod"}},
+	}}
+	m := &Manifest{
+		Source:      SourceLocation{Repo: "github.com/example/app"},
+		Deployments: DeploySpecs{"ci": {}, "env=prod": {}},
+		Promotion:   &Promotion{Stages: []PromotionStage{{Clusters: []string{"ci"}}, {Clusters: []string{"prod-1", "prod-2"}}}},
+	}
+	if flaws := defs.ValidateManifest(m); len(flaws) != 0 {
+		t.Errorf("unexpected flaws for clusters covered by a selector: %v", flaws)
+	}
+
+	m.Promotion.Stages[0].Clusters = []string{"ci", "staging"}
+	if flaws := defs.ValidateManifest(m); len(flaws) != 1 {
+		t.Errorf("got %d flaws; want 1 for a cluster not deployed to: %v", len(flaws), flaws)
+	}
+}
+
+type remoteDeployerStub struct {
+	sync.Mutex
+	deployed []DeploymentID
+	err      error
+}
+
+func (r *remoteDeployerStub) Deploy(did DeploymentID, version semv.Version, user User) error {
+	r.Lock()
+	defer r.Unlock()
+	r.deployed = append(r.deployed, did)
+	return r.err
+}
+
+func TestPromotionEngine_remote(t *testing.T) {
+	pe, mid, dmCtrl, qsCtrl, _ := promotionFixture(t, ResolveComplete, nil)
+	remote := &remoteDeployerStub{}
+	pe.Filter = &ResolveFilter{Cluster: NewResolveFieldMatcher("cluster2")}
+	pe.Remote = remote
+
+	run := startPromotion(t, pe, mid)
+	if run.Status != PromotionSucceeded {
+		t.Fatalf("got %s", run)
+	}
+
+	writes := dmCtrl.CallsTo("WriteDeployment")
+	if len(writes) != 1 {
+		t.Fatalf("got %d deployments written; want 1", len(writes))
+	}
+	if c := writes[0].PassedArgs().Get(0).(*Deployment).ClusterName; c != "cluster2" {
+		t.Errorf("wrote %s locally; want cluster2", c)
+	}
+	if n := len(qsCtrl.CallsTo("Push")); n != 1 {
+		t.Errorf("got %d rectifications queued; want 1", n)
+	}
+
+	clusters := map[string]bool{}
+	for _, did := range remote.deployed {
+		clusters[did.Cluster] = true
+	}
+	if len(remote.deployed) != 2 || !clusters["cluster0"] || !clusters["cluster1"] {
+		t.Errorf("deployed %v remotely; want cluster0 and cluster1", remote.deployed)
+	}
+}
+
+func TestPromotionEngine_remoteFails(t *testing.T) {
+	pe, mid, dmCtrl, _, _ := promotionFixture(t, ResolveComplete, nil)
+	pe.Filter = &ResolveFilter{Cluster: NewResolveFieldMatcher("cluster2")}
+	pe.Remote = &remoteDeployerStub{err: errors.New("server down")}
+
+	run := startPromotion(t, pe, mid)
+	if run.Status != PromotionFailed || run.Stage != 0 {
+		t.Fatalf("got %s; want failure at the first stage", run)
+	}
+	if !strings.Contains(run.Error, "server down") {
+		t.Errorf("got error %q", run.Error)
+	}
+	if n := len(dmCtrl.CallsTo("WriteDeployment")); n != 0 {
+		t.Errorf("got %d deployments written; want none", n)
+	}
+}
+
+func TestPromotionEngine_Start_notLocal(t *testing.T) {
+	pe, mid, _, _, _ := promotionFixture(t, ResolveComplete, nil)
+	pe.Filter = &ResolveFilter{Cluster: NewResolveFieldMatcher("cluster2")}
+	if _, err := pe.Start(mid, semv.MustParse("2.0.0"), User{}); err == nil {
+		t.Errorf("expected an error promoting to clusters resolved elsewhere without a RemoteDeployer")
+	}
+}
@@ -0,0 +1,263 @@
+package sous
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Provenance records how an image was built, so that deployers can check
+	// where it came from before deploying it.
+	Provenance struct {
+		// Image is the digest name of the image.
+		Image string
+		// Repo, Offset, Version and Revision identify the source the image
+		// was built from.
+		Repo, Offset, Version, Revision string
+		// Dirty is true if the source had uncommitted changes.
+		Dirty bool
+		// Buildpack names the buildpack the image was built with.
+		Buildpack string
+		// BaseImages are the images the image was built on, by digest where
+		// it's known.
+		BaseImages []string
+		// Advisories are the advisories the image was built with.
+		Advisories Advisories
+		// BuilderHost is the host the image was built on.
+		BuilderHost string
+		// BuiltAt is when the image was built.
+		BuiltAt time.Time
+	}
+
+	// SignedProvenance is a Provenance and its signature.
+	SignedProvenance struct {
+		// Payload is the JSON encoded Provenance, exactly as it was signed.
+		Payload []byte
+		// KeyID identifies the key Payload was signed with.
+		KeyID string
+		// Signature is the ASN.1 encoded ECDSA signature of the SHA-256
+		// digest of Payload.
+		Signature []byte
+	}
+
+	// A ProvenanceStore keeps the SignedProvenance of images.
+	ProvenanceStore interface {
+		// PutProvenance stores sp as the provenance of image, a digest
+		// name.
+		PutProvenance(image string, sp *SignedProvenance) error
+		// GetProvenance returns the provenance of image, a digest name.
+		GetProvenance(image string) (*SignedProvenance, error)
+	}
+
+	// A ProvenanceSigner signs Provenance with a private key.
+	ProvenanceSigner struct {
+		// KeyID identifies the signing key; see ProvenanceKeyID.
+		KeyID string
+		key   *ecdsa.PrivateKey
+	}
+
+	// A ProvenanceVerifier verifies SignedProvenance against a set of
+	// trusted public keys.
+	ProvenanceVerifier struct {
+		keys map[string]*ecdsa.PublicKey
+	}
+
+	// A ProvenanceCheck verifies the provenance of images before they are
+	// deployed.
+	ProvenanceCheck struct {
+		Store    ProvenanceStore
+		Verifier *ProvenanceVerifier
+	}
+
+	ecdsaSignature struct {
+		R, S *big.Int
+	}
+)
+
+// NewProvenance returns the Provenance of prod, built in bc by bp.
+func NewProvenance(bc *BuildContext, bp Buildpack, prod *BuildProduct) Provenance {
+	host, _ := os.Hostname()
+	version := prod.Source.Version
+	version.Meta = ""
+	return Provenance{
+		Image:       prod.DigestName,
+		Repo:        prod.Source.Location.Repo,
+		Offset:      prod.Source.Location.Dir,
+		Version:     version.String(),
+		Revision:    prod.RevID,
+		Dirty:       bc.Source.DirtyWorkingTree,
+		Buildpack:   strings.TrimPrefix(fmt.Sprintf("%T", bp), "*"),
+		BaseImages:  prod.BaseImages,
+		Advisories:  prod.Advisories,
+		BuilderHost: host,
+		BuiltAt:     time.Now().UTC(),
+	}
+}
+
+// ProvenanceKeyID returns the ID of pub: the first 16 hex digits of the
+// SHA-256 digest of its PKIX encoding.
+func ProvenanceKeyID(pub *ecdsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])[:16], nil
+}
+
+// NewProvenanceSigner returns a ProvenanceSigner for the PEM encoded ECDSA
+// private key keyPEM, in either SEC 1 ("EC PRIVATE KEY") or PKCS #8 ("PRIVATE
+// KEY") form.
+func NewProvenanceSigner(keyPEM []byte) (*ProvenanceSigner, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("no PEM encoded key found")
+	}
+	var key *ecdsa.PrivateKey
+	switch block.Type {
+	default:
+		return nil, errors.Errorf("unsupported key type %q", block.Type)
+	case "EC PRIVATE KEY":
+		k, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key = k
+	case "PRIVATE KEY":
+		k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		ec, ok := k.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, errors.Errorf("%T is not an ECDSA key", k)
+		}
+		key = ec
+	}
+	id, err := ProvenanceKeyID(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &ProvenanceSigner{KeyID: id, key: key}, nil
+}
+
+// Sign returns p signed by s.
+func (s *ProvenanceSigner) Sign(p Provenance) (*SignedProvenance, error) {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(payload)
+	r, ss, err := ecdsa.Sign(rand.Reader, s.key, sum[:])
+	if err != nil {
+		return nil, err
+	}
+	sig, err := asn1.Marshal(ecdsaSignature{R: r, S: ss})
+	if err != nil {
+		return nil, err
+	}
+	return &SignedProvenance{Payload: payload, KeyID: s.KeyID, Signature: sig}, nil
+}
+
+// NewProvenanceVerifier returns a ProvenanceVerifier that trusts each of the
+// PEM encoded ECDSA public keys ("PUBLIC KEY") in keysPEM.
+func NewProvenanceVerifier(keysPEM []byte) (*ProvenanceVerifier, error) {
+	v := &ProvenanceVerifier{keys: map[string]*ecdsa.PublicKey{}}
+	for {
+		var block *pem.Block
+		block, keysPEM = pem.Decode(keysPEM)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		k, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		pub, ok := k.(*ecdsa.PublicKey)
+		if !ok {
+			return nil, errors.Errorf("%T is not an ECDSA key", k)
+		}
+		if err := v.Trust(pub); err != nil {
+			return nil, err
+		}
+	}
+	if len(v.keys) == 0 {
+		return nil, errors.New("no PEM encoded public keys found")
+	}
+	return v, nil
+}
+
+// Trust adds pub to the keys v trusts.
+func (v *ProvenanceVerifier) Trust(pub *ecdsa.PublicKey) error {
+	id, err := ProvenanceKeyID(pub)
+	if err != nil {
+		return err
+	}
+	v.keys[id] = pub
+	return nil
+}
+
+// Verify checks that sp is signed by a trusted key, and is the provenance of
+// image, a digest name. It returns the verified Provenance.
+func (v *ProvenanceVerifier) Verify(sp *SignedProvenance, image string) (*Provenance, error) {
+	pub, ok := v.keys[sp.KeyID]
+	if !ok {
+		return nil, errors.Errorf("signed with untrusted key %q", sp.KeyID)
+	}
+	sig := ecdsaSignature{}
+	if _, err := asn1.Unmarshal(sp.Signature, &sig); err != nil {
+		return nil, errors.Wrap(err, "parsing signature")
+	}
+	sum := sha256.Sum256(sp.Payload)
+	if !ecdsa.Verify(pub, sum[:], sig.R, sig.S) {
+		return nil, errors.Errorf("bad signature by key %q", sp.KeyID)
+	}
+
+	p := &Provenance{}
+	if err := json.Unmarshal(sp.Payload, p); err != nil {
+		return nil, errors.Wrap(err, "parsing provenance")
+	}
+	if imageDigest(p.Image) == "" || imageDigest(p.Image) != imageDigest(image) {
+		return nil, errors.Errorf("provenance is of %q, not %q", p.Image, image)
+	}
+	return p, nil
+}
+
+// imageDigest returns the digest part of the digest name image, or "" if it
+// hasn't one.
+func imageDigest(image string) string {
+	i := strings.LastIndex(image, "@")
+	if i < 0 {
+		return ""
+	}
+	return image[i+1:]
+}
+
+// Check returns the verified provenance of the image of art.
+func (pc *ProvenanceCheck) Check(art *BuildArtifact) (*Provenance, error) {
+	if art == nil || art.DigestReference == "" {
+		return nil, errors.New("no image digest to check the provenance of")
+	}
+	sp, err := pc.Store.GetProvenance(art.DigestReference)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting provenance of %s", art.DigestReference)
+	}
+	p, err := pc.Verifier.Verify(sp, art.DigestReference)
+	return p, errors.Wrapf(err, "verifying provenance of %s", art.DigestReference)
+}
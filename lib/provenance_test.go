@@ -0,0 +1,136 @@
+package sous
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/nyarly/spies"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const provenanceTestImage = "docker.example.com/app@sha256:0123456789abcdef"
+
+// provenanceTestKey returns a new key, PEM encoded, and its public key, PEM
+// encoded.
+func provenanceTestKey(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub})
+}
+
+// mapProvenanceStore is a ProvenanceStore in memory.
+type mapProvenanceStore map[string]*SignedProvenance
+
+func (ms mapProvenanceStore) PutProvenance(image string, sp *SignedProvenance) error {
+	ms[image] = sp
+	return nil
+}
+
+func (ms mapProvenanceStore) GetProvenance(image string) (*SignedProvenance, error) {
+	sp, ok := ms[image]
+	if !ok {
+		return nil, errors.Errorf("no provenance for %s", image)
+	}
+	return sp, nil
+}
+
+type provenanceTestBuildpack struct{}
+
+func (provenanceTestBuildpack) Detect(*BuildContext) (*DetectResult, error) { return nil, nil }
+func (provenanceTestBuildpack) Build(*BuildContext) (*BuildResult, error)   { return nil, nil }
+
+func TestProvenance_SignVerify(t *testing.T) {
+	key, pub := provenanceTestKey(t)
+	_, otherPub := provenanceTestKey(t)
+	signer, err := NewProvenanceSigner(key)
+	require.NoError(t, err)
+	verifier, err := NewProvenanceVerifier(append(otherPub, pub...))
+	require.NoError(t, err)
+
+	sp, err := signer.Sign(Provenance{Image: provenanceTestImage, Repo: "github.com/opentable/app", Revision: "cabbage"})
+	require.NoError(t, err)
+	p, err := verifier.Verify(sp, provenanceTestImage)
+	require.NoError(t, err)
+	assert.Equal(t, "cabbage", p.Revision)
+
+	_, err = verifier.Verify(sp, "docker.example.com/app@sha256:fedcba9876543210")
+	assert.Error(t, err, "provenance of another image")
+
+	tampered := *sp
+	tampered.Payload = []byte(`{"Image":"` + provenanceTestImage + `","Revision":"broccoli"}`)
+	_, err = verifier.Verify(&tampered, provenanceTestImage)
+	assert.Error(t, err, "tampered payload")
+
+	untrusted, err := NewProvenanceVerifier(otherPub)
+	require.NoError(t, err)
+	_, err = untrusted.Verify(sp, provenanceTestImage)
+	assert.Error(t, err, "untrusted key")
+
+	_, err = NewProvenanceVerifier([]byte("not a key"))
+	assert.Error(t, err)
+}
+
+func TestBuildManager_Attest(t *testing.T) {
+	key, pub := provenanceTestKey(t)
+	signer, err := NewProvenanceSigner(key)
+	require.NoError(t, err)
+	store := mapProvenanceStore{}
+
+	bm := rootedBuildManager("/somewhere/project", "")
+	bm.Signer = signer
+	bm.ProvenanceStore = store
+	bc := &BuildContext{Source: SourceContext{DirtyWorkingTree: true}}
+	br := &BuildResult{Products: []*BuildProduct{
+		{DigestName: provenanceTestImage, RevID: "cabbage", BaseImages: []string{"docker.example.com/base@sha256:aaaa"}},
+		{DigestName: "docker.example.com/builder@sha256:bbbb", Advisories: Advisories{IsBuilder}},
+		{VersionName: "docker.example.com/unpushed:1.0.0"},
+	}}
+	require.NoError(t, bm.Attest(bc, provenanceTestBuildpack{}, br))
+	require.Len(t, store, 1, "only pushed images that aren't builders are attested")
+
+	verifier, err := NewProvenanceVerifier(pub)
+	require.NoError(t, err)
+	p, err := verifier.Verify(store[provenanceTestImage], provenanceTestImage)
+	require.NoError(t, err)
+	assert.True(t, p.Dirty)
+	assert.Equal(t, "sous.provenanceTestBuildpack", p.Buildpack)
+	assert.Equal(t, []string{"docker.example.com/base@sha256:aaaa"}, p.BaseImages)
+}
+
+func TestDispatchDeployer_Rectify_provenance(t *testing.T) {
+	dd, singCtrl, _ := setupDispatchDeployer()
+	singCtrl.MatchMethod("Rectify", spies.AnyArgs, DiffResolution{Desc: "singularity"})
+	key, pub := provenanceTestKey(t)
+	signer, err := NewProvenanceSigner(key)
+	require.NoError(t, err)
+	verifier, err := NewProvenanceVerifier(pub)
+	require.NoError(t, err)
+	store := mapProvenanceStore{}
+	dd.Provenance = &ProvenanceCheck{Store: store, Verifier: verifier}
+
+	post := DeployableFixture("")
+	pair := &DeployablePair{Post: post}
+	rez := dd.Rectify(pair)
+	if assert.NotNil(t, rez.Error, "no provenance") {
+		assert.IsType(t, &UnsignedImageError{}, rez.Error.error)
+	}
+	assert.Len(t, singCtrl.CallsTo("Rectify"), 0)
+
+	sp, err := signer.Sign(Provenance{Image: post.BuildArtifact.DigestReference})
+	require.NoError(t, err)
+	store[post.BuildArtifact.DigestReference] = sp
+	rez = dd.Rectify(pair)
+	assert.Nil(t, rez.Error)
+	assert.Equal(t, ResolutionType("singularity"), rez.Desc)
+}
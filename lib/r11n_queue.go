@@ -5,6 +5,7 @@ import (
 	"sort"
 	"sync"
 
+	"github.com/opentable/sous/util/logging"
 	"github.com/pborman/uuid"
 )
 
@@ -20,6 +21,8 @@ type (
 		fifoRefs      *ring.Ring
 		handler       func(*QueuedR11n) DiffResolution
 		start         bool
+		store         R11nStore
+		log           logging.LogSink
 		sync.Mutex
 	}
 	// QueuedR11n is a queue item wrapping a Rectification with an ID and position.
@@ -28,6 +31,9 @@ type (
 		Pos           int
 		Rectification *Rectification
 		done          chan struct{}
+		// stored is closed once qr has been recorded as queued, so that its
+		// resolution isn't recorded first.
+		stored chan struct{}
 	}
 
 	// R11nID is a QueuedR11n identifier.
@@ -129,6 +135,8 @@ func (rq *R11nQueue) Start(handler func(*QueuedR11n) DiffResolution) {
 		for {
 			qr := rq.next()
 			handler(qr)
+			<-qr.stored
+			rq.storeResolved(qr)
 			rq.Lock()
 			close(qr.done)
 			delete(rq.refs, qr.ID)
@@ -156,32 +164,48 @@ func (rq *R11nQueue) Wait(id R11nID) (DiffResolution, bool) {
 // returns nil and false.
 func (rq *R11nQueue) Push(r *Rectification) (*QueuedR11n, bool) {
 	rq.Lock()
-	defer rq.Unlock()
 	if len(rq.queue) == rq.cap {
+		rq.Unlock()
 		return nil, false
 	}
-	return rq.internalPush(r), true
+	qr := rq.internalPush(r)
+	rq.Unlock()
+	rq.storeQueued(qr)
+	return qr, true
 }
 
 // internalPush assumes rq is already locked.
 func (rq *R11nQueue) internalPush(r *Rectification) *QueuedR11n {
-	id := NewR11nID()
+	return rq.pushWithID(NewR11nID(), r)
+}
+
+// pushWithID assumes rq is already locked, and that the queue isn't full.
+// Once rq is unlocked, the caller must call storeQueued with the result:
+// storing it is slow, and mustn't hold up the queue.
+func (rq *R11nQueue) pushWithID(id R11nID, r *Rectification) *QueuedR11n {
 	qr := &QueuedR11n{
 		ID:            id,
 		Pos:           len(rq.queue),
 		Rectification: r,
 		done:          make(chan struct{}),
+		stored:        make(chan struct{}),
 	}
 	rq.refs[id] = qr
-	rq.allRefs[id] = qr
+	rq.remember(qr)
+	rq.queue <- qr
+	return qr
+}
+
+// remember makes qr available to ByID until MaxRefsPerR11nQueue newer items
+// have been remembered. It assumes rq is already locked.
+func (rq *R11nQueue) remember(qr *QueuedR11n) {
+	rq.allRefs[qr.ID] = qr
 	rq.fifoRefs = rq.fifoRefs.Next()
 	if rq.fifoRefs.Value != nil {
 		idToDelete := rq.fifoRefs.Value.(R11nID)
 		delete(rq.allRefs, idToDelete)
 	}
-	rq.fifoRefs.Value = id
-	rq.queue <- qr
-	return qr
+	rq.fifoRefs.Value = qr.ID
 }
 
 // PushIfEmpty adds an item to the queue if it is empty, and returns the wrapper
@@ -189,13 +213,16 @@ func (rq *R11nQueue) internalPush(r *Rectification) *QueuedR11n {
 // returns nil, false.
 func (rq *R11nQueue) PushIfEmpty(r *Rectification) (*QueuedR11n, bool) {
 	rq.Lock()
-	defer rq.Unlock()
 	// We look at refs since we only delete the ref after handling has happened.
 	// If we are busy handling a r11n, then we consider the queue non-empty.
 	if len(rq.refs) != 0 {
+		rq.Unlock()
 		return nil, false
 	}
-	return rq.internalPush(r), true
+	qr := rq.internalPush(r)
+	rq.Unlock()
+	rq.storeQueued(qr)
+	return qr, true
 }
 
 // Len returns the current number of items in the queue.
@@ -40,29 +40,25 @@ func NewR11nQueueSet(opts ...R11nQueueOpt) *R11nQueueSet {
 // PushIfEmpty creates a queue for the DeploymentID of r if it does not already
 // exist. It calls PushIfEmpty on that R11nQueue passing r.
 func (rqs *R11nQueueSet) PushIfEmpty(r *Rectification) (*QueuedR11n, bool) {
-	rqs.Lock()
-	defer rqs.Unlock()
-	id := r.Pair.ID()
-	queue, ok := rqs.set[id]
-	if !ok {
-		queue = NewR11nQueue(rqs.opts...)
-		rqs.set[id] = queue
-	}
-	return queue.PushIfEmpty(r)
+	return rqs.queue(r.Pair.ID()).PushIfEmpty(r)
 }
 
 // Push creates a queue for the DeploymentID of r if it does not already
 // exist. It calls Push on that R11nQueue passing r.
 func (rqs *R11nQueueSet) Push(r *Rectification) (*QueuedR11n, bool) {
+	return rqs.queue(r.Pair.ID()).Push(r)
+}
+
+// queue returns the queue for did, creating it if it doesn't already exist.
+func (rqs *R11nQueueSet) queue(did DeploymentID) *R11nQueue {
 	rqs.Lock()
 	defer rqs.Unlock()
-	id := r.Pair.ID()
-	queue, ok := rqs.set[id]
+	queue, ok := rqs.set[did]
 	if !ok {
 		queue = NewR11nQueue(rqs.opts...)
-		rqs.set[id] = queue
+		rqs.set[did] = queue
 	}
-	return queue.Push(r)
+	return queue
 }
 
 // Wait waits for the r11n with id id to complete, if it is found in the
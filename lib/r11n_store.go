@@ -0,0 +1,161 @@
+package sous
+
+import (
+	"sort"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+)
+
+type (
+	// An R11nStore persists queued rectifications and their resolutions, so
+	// that queues survive a server restart.
+	R11nStore interface {
+		// StoreQueued records that qr has been queued.
+		StoreQueued(qr *QueuedR11n, at time.Time) error
+		// StoreResolved records the resolution of qr.
+		StoreResolved(qr *QueuedR11n, at time.Time) error
+		// LoadR11ns returns the rectifications that are unfinished or that
+		// finished after since, oldest first.
+		LoadR11ns(since time.Time) ([]StoredR11n, error)
+		// PurgeR11ns discards rectifications that finished before before.
+		PurgeR11ns(before time.Time) error
+	}
+
+	// A StoredR11n is a rectification as recorded by an R11nStore.
+	StoredR11n struct {
+		ID           R11nID
+		DeploymentID DeploymentID
+		// Prior and Post are the deployments of the rectified pair.
+		Prior, Post *Deployment
+		Queued      time.Time
+		// Finished is nil until the rectification has been resolved.
+		Finished *time.Time
+		// Resolution is the outcome of a finished rectification.
+		Resolution *DiffResolution
+	}
+)
+
+// R11nRetentionDefault is how long finished rectifications are kept in an
+// R11nStore if no other period is configured.
+const R11nRetentionDefault = 24 * time.Hour
+
+// R11nPurgeInterval is how often PurgeR11nsEvery purges an R11nStore by
+// default.
+const R11nPurgeInterval = time.Hour
+
+// R11nQueueStore records every rectification pushed onto the queue, and its
+// resolution, in store. Failures to record are logged to ls.
+func R11nQueueStore(store R11nStore, ls logging.LogSink) R11nQueueOpt {
+	return func(rq *R11nQueue) {
+		rq.store = store
+		rq.log = ls
+	}
+}
+
+// Restore reloads the rectifications in store of deployments matched by rf
+// that are unfinished, or that finished within retention, after discarding
+// older ones. Cluster selectors in rf must already have been resolved with
+// SelectClusters. Unfinished rectifications are queued again, with their
+// original IDs; finished ones can be looked up by ID but aren't repeated.
+func (rqs *R11nQueueSet) Restore(store R11nStore, retention time.Duration, rf *ResolveFilter, ls logging.LogSink) error {
+	since := time.Now().Add(-retention)
+	if err := store.PurgeR11ns(since); err != nil {
+		return err
+	}
+	stored, err := store.LoadR11ns(since)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(stored, func(i, j int) bool {
+		return stored[i].Queued.Before(stored[j].Queued)
+	})
+
+	for _, sr := range stored {
+		if !rf.FilterManifestID(sr.DeploymentID.ManifestID) || !rf.FilterClusterName(sr.DeploymentID.Cluster) {
+			continue
+		}
+		if !rqs.queue(sr.DeploymentID).restore(sr, ls) {
+			messages.ReportLogFieldsMessage("Queue full: not restoring rectification", logging.WarningLevel, ls, sr.DeploymentID, sr.ID)
+		}
+	}
+	return nil
+}
+
+// PurgeR11nsEvery discards the rectifications in store that finished more
+// than retention ago, every interval, in the background.
+func PurgeR11nsEvery(store R11nStore, retention, interval time.Duration, ls logging.LogSink) {
+	go func() {
+		for now := range time.Tick(interval) {
+			if err := store.PurgeR11ns(now.Add(-retention)); err != nil {
+				messages.ReportLogFieldsMessage("Failed to purge rectifications", logging.WarningLevel, ls, err)
+			}
+		}
+	}()
+}
+
+// rectification rebuilds the Rectification sr recorded.
+func (sr StoredR11n) rectification(ls logging.LogSink) *Rectification {
+	pair := DeployablePair{}
+	if sr.Prior != nil {
+		pair.Prior = &Deployable{Deployment: sr.Prior}
+	}
+	if sr.Post != nil {
+		pair.Post = &Deployable{Deployment: sr.Post}
+	}
+	r := NewRectification(pair, ls.Child("r11n"))
+	r.Pair.SetID(sr.DeploymentID)
+	if sr.Resolution != nil {
+		r.Resolution = *sr.Resolution
+	}
+	return r
+}
+
+// restore adds sr to the queue: pending if it's unfinished, otherwise only
+// for lookup by ID. It returns false if the queue is full.
+func (rq *R11nQueue) restore(sr StoredR11n, ls logging.LogSink) bool {
+	r := sr.rectification(ls)
+	rq.Lock()
+	if sr.Finished == nil {
+		if len(rq.queue) == rq.cap {
+			rq.Unlock()
+			return false
+		}
+		qr := rq.pushWithID(sr.ID, r)
+		rq.Unlock()
+		rq.storeQueued(qr)
+		return true
+	}
+	defer rq.Unlock()
+	qr := &QueuedR11n{
+		ID:            sr.ID,
+		Pos:           -1,
+		Rectification: r,
+		done:          make(chan struct{}),
+	}
+	close(qr.done)
+	rq.remember(qr)
+	return true
+}
+
+// storeQueued records qr as queued. It must be called once for each item
+// pushed, without rq locked.
+func (rq *R11nQueue) storeQueued(qr *QueuedR11n) {
+	defer close(qr.stored)
+	if rq.store == nil {
+		return
+	}
+	if err := rq.store.StoreQueued(qr, time.Now()); err != nil {
+		messages.ReportLogFieldsMessage("Failed to store queued rectification", logging.WarningLevel, rq.log, qr.ID, err)
+	}
+}
+
+func (rq *R11nQueue) storeResolved(qr *QueuedR11n) {
+	if rq.store == nil {
+		return
+	}
+	if err := rq.store.StoreResolved(qr, time.Now()); err != nil {
+		messages.ReportLogFieldsMessage("Failed to store rectification resolution", logging.WarningLevel, rq.log, qr.ID, err)
+	}
+}
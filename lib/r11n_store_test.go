@@ -0,0 +1,237 @@
+package sous
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+)
+
+// memR11nStore is an in-memory R11nStore for testing.
+type memR11nStore struct {
+	sync.Mutex
+	stored map[R11nID]StoredR11n
+	order  []R11nID
+}
+
+func newMemR11nStore() *memR11nStore {
+	return &memR11nStore{stored: map[R11nID]StoredR11n{}}
+}
+
+func (s *memR11nStore) StoreQueued(qr *QueuedR11n, at time.Time) error {
+	s.Lock()
+	defer s.Unlock()
+	sr := StoredR11n{ID: qr.ID, DeploymentID: qr.Rectification.Pair.ID(), Queued: at}
+	if qr.Rectification.Pair.Post != nil {
+		sr.Post = qr.Rectification.Pair.Post.Deployment
+	}
+	s.stored[qr.ID] = sr
+	s.order = append(s.order, qr.ID)
+	return nil
+}
+
+func (s *memR11nStore) StoreResolved(qr *QueuedR11n, at time.Time) error {
+	s.Lock()
+	defer s.Unlock()
+	sr := s.stored[qr.ID]
+	rez := qr.Rectification.Resolution
+	sr.Finished, sr.Resolution = &at, &rez
+	s.stored[qr.ID] = sr
+	return nil
+}
+
+func (s *memR11nStore) LoadR11ns(since time.Time) ([]StoredR11n, error) {
+	s.Lock()
+	defer s.Unlock()
+	srs := []StoredR11n{}
+	for _, id := range s.order {
+		sr, ok := s.stored[id]
+		if !ok {
+			continue
+		}
+		if sr.Finished == nil || !sr.Finished.Before(since) {
+			srs = append(srs, sr)
+		}
+	}
+	return srs, nil
+}
+
+func (s *memR11nStore) PurgeR11ns(before time.Time) error {
+	s.Lock()
+	defer s.Unlock()
+	for id, sr := range s.stored {
+		if sr.Finished != nil && sr.Finished.Before(before) {
+			delete(s.stored, id)
+		}
+	}
+	return nil
+}
+
+func TestR11nQueueSet_Restore(t *testing.T) {
+	store := newMemR11nStore()
+	ls := logging.SilentLogSet()
+
+	// Before the "restart": "done" is resolved, "pending" is left queued.
+	proceed := make(chan struct{})
+	before := NewR11nQueueSet(R11nQueueStore(store, ls), R11nQueueStartWithHandler(func(qr *QueuedR11n) DiffResolution {
+		if qr.Rectification.Pair.ID().ManifestID.Source.Repo == "pending" {
+			<-proceed
+		}
+		return DiffResolution{Desc: ModifyDiff}
+	}))
+	defer close(proceed)
+
+	done, ok := before.Push(makeTestR11nWithRepo("done"))
+	if !ok {
+		t.Fatal("push failed")
+	}
+	if _, ok := before.Wait(done.Rectification.Pair.ID(), done.ID); !ok {
+		t.Fatal("wait failed")
+	}
+	pending, ok := before.Push(makeTestR11nWithRepo("pending"))
+	if !ok {
+		t.Fatal("push failed")
+	}
+
+	handled := make(chan R11nID, 2)
+	after := NewR11nQueueSet(R11nQueueStartWithHandler(func(qr *QueuedR11n) DiffResolution {
+		handled <- qr.ID
+		return DiffResolution{}
+	}))
+	if err := after.Restore(store, time.Hour, &ResolveFilter{}, ls); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case id := <-handled:
+		if id != pending.ID {
+			t.Errorf("restored queue handled %q; want %q", id, pending.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pending rectification was not requeued")
+	}
+
+	q, ok := after.Queues()[done.Rectification.Pair.ID()]
+	if !ok {
+		t.Fatalf("no queue restored for %s", done.Rectification.Pair.ID())
+	}
+	qr, ok := q.ByID(done.ID)
+	if !ok {
+		t.Fatalf("finished rectification %q not restored", done.ID)
+	}
+	if qr.Rectification.Resolution.Desc != ModifyDiff {
+		t.Errorf("got resolution %q; want %q", qr.Rectification.Resolution.Desc, ModifyDiff)
+	}
+	select {
+	case id := <-handled:
+		t.Errorf("finished rectification %q was handled again", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestR11nQueueSet_Restore_purgesExpired(t *testing.T) {
+	store := newMemR11nStore()
+	finished := time.Now().Add(-2 * time.Hour)
+	store.stored["old"] = StoredR11n{ID: "old", Finished: &finished, Resolution: &DiffResolution{}}
+	store.order = []R11nID{"old"}
+
+	rqs := NewR11nQueueSet()
+	if err := rqs.Restore(store, time.Hour, &ResolveFilter{}, logging.SilentLogSet()); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.stored) != 0 {
+		t.Errorf("expired rectification not purged")
+	}
+	if len(rqs.Queues()) != 0 {
+		t.Errorf("expired rectification restored")
+	}
+}
+
+func TestR11nQueueSet_Restore_filtered(t *testing.T) {
+	store := newMemR11nStore()
+	for _, cluster := range []string{"cluster1", "cluster2"} {
+		id := R11nID(cluster)
+		store.stored[id] = StoredR11n{ID: id, DeploymentID: DeploymentID{Cluster: cluster}, Queued: time.Now()}
+		store.order = append(store.order, id)
+	}
+
+	rqs := NewR11nQueueSet()
+	if err := rqs.Restore(store, time.Hour, &ResolveFilter{Cluster: NewResolveFieldMatcher("cluster1")}, logging.SilentLogSet()); err != nil {
+		t.Fatal(err)
+	}
+	queues := rqs.Queues()
+	if len(queues) != 1 {
+		t.Fatalf("got %d queues restored; want 1", len(queues))
+	}
+	if _, ok := queues[DeploymentID{Cluster: "cluster1"}]; !ok {
+		t.Errorf("rectification of cluster1 not restored")
+	}
+}
+
+func TestPurgeR11nsEvery(t *testing.T) {
+	store := newMemR11nStore()
+	finished := time.Now().Add(-2 * time.Hour)
+	store.stored["old"] = StoredR11n{ID: "old", Finished: &finished, Resolution: &DiffResolution{}}
+
+	PurgeR11nsEvery(store, time.Hour, time.Millisecond, logging.SilentLogSet())
+	for i := 0; i < 100; i++ {
+		store.Lock()
+		n := len(store.stored)
+		store.Unlock()
+		if n == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("expired rectification not purged")
+}
+
+// blockingR11nStore blocks StoreQueued until proceed is closed.
+type blockingR11nStore struct {
+	*memR11nStore
+	storing, proceed chan struct{}
+}
+
+func (s blockingR11nStore) StoreQueued(qr *QueuedR11n, at time.Time) error {
+	close(s.storing)
+	<-s.proceed
+	return s.memR11nStore.StoreQueued(qr, at)
+}
+
+func TestR11nQueue_Push_storesUnlocked(t *testing.T) {
+	store := blockingR11nStore{memR11nStore: newMemR11nStore(), storing: make(chan struct{}), proceed: make(chan struct{})}
+	ls := logging.SilentLogSet()
+	rq := NewR11nQueue(R11nQueueStore(store, ls), R11nQueueStartWithHandler(func(*QueuedR11n) DiffResolution {
+		return DiffResolution{Desc: ModifyDiff}
+	}))
+
+	pushed := make(chan *QueuedR11n)
+	go func() {
+		qr, _ := rq.Push(makeTestR11nWithRepo("slow"))
+		pushed <- qr
+	}()
+	<-store.storing
+
+	snapshot := make(chan struct{})
+	go func() {
+		rq.Snapshot()
+		close(snapshot)
+	}()
+	select {
+	case <-snapshot:
+	case <-time.After(time.Second):
+		t.Fatal("queue locked while the rectification was stored")
+	}
+
+	close(store.proceed)
+	qr := <-pushed
+	if _, ok := rq.Wait(qr.ID); !ok {
+		t.Fatal("wait failed")
+	}
+	store.Lock()
+	defer store.Unlock()
+	if sr := store.stored[qr.ID]; sr.Finished == nil {
+		t.Errorf("resolution stored before the rectification was queued: %v", sr)
+	}
+}
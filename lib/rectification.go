@@ -17,6 +17,15 @@ type Rectification struct {
 	// Resolution is the final resolution of this single rectification.
 	sync.RWMutex
 	Resolution DiffResolution
+	// rollout is the progress of a progressive rollout, if any.
+	rollout *RolloutProgress
+	// rollbackManager, if set, is used to roll back failed deployments.
+	rollbackManager DeploymentManager
+	// knownGood is the version running before this rectification.
+	knownGood *SourceID
+	// events, if set, is where progress is published, as r11nID.
+	events *DeployEvents
+	r11nID R11nID
 
 	log    logging.LogSink
 	uuid   uuid.UUID
@@ -57,7 +66,10 @@ func (r *Rectification) Begin(d Deployer, reg Registry, rf *ResolveFilter, state
 
 func (r *Rectification) enact(d Deployer, reg Registry, rf *ResolveFilter, stateReader StateReader) {
 	defer r.cancel()
-	r.rectify(d, reg)
+	defer r.publishResolution()
+	r.publishPhase(R11nStarted, nil)
+	r.recordKnownGood(d, reg)
+	r.rectify(d, reg, stateReader)
 	if r.Resolution.Error != nil {
 		logging.Deliver(r.log,
 			logging.SousGenericV1,
@@ -68,12 +80,25 @@ func (r *Rectification) enact(d Deployer, reg Registry, rf *ResolveFilter, state
 		)
 		return
 	}
+	r.publishPhase(R11nDeploying, nil)
 	r.awaitDone(d, reg, rf, stateReader)
+	r.autoRollback()
 }
 
-func (r *Rectification) rectify(d Deployer, reg Registry) {
+func (r *Rectification) rectify(d Deployer, reg Registry, stateReader StateReader) {
 	if r.Pair.Post.BuildArtifact == nil {
-		pair, diff := HandlePairsByRegistry(reg, &r.Pair, r.log)
+		var keys TrustedKeys
+		if c := r.Pair.Post.Cluster; c != nil && c.RequireSignedImages {
+			state, err := stateReader.ReadState()
+			if err != nil {
+				r.Lock()
+				r.Resolution.Error = WrapResolveError(err)
+				r.Unlock()
+				return
+			}
+			keys = state.Defs.TrustedKeys
+		}
+		pair, diff := HandlePairsByRegistry(reg, &r.Pair, keys, r.log)
 		if diff != nil && diff.Error != nil {
 			r.Lock()
 			r.Resolution.Error = WrapResolveError(diff.Error)
@@ -110,7 +135,9 @@ func (r *Rectification) awaitDone(d Deployer, reg Registry, rf *ResolveFilter, s
 	tick := time.NewTicker(250 * time.Millisecond)
 	defer tick.Stop()
 
-	end, ec := context.WithTimeout(r.ctx, 20*time.Minute)
+	rollout := newRolloutDriver(r.Pair.Post)
+
+	end, ec := context.WithTimeout(r.ctx, 20*time.Minute+rollout.totalPause())
 	defer ec()
 
 	logging.Deliver(r.log,
@@ -135,6 +162,13 @@ func (r *Rectification) awaitDone(d Deployer, reg Registry, rf *ResolveFilter, s
 			r.Unlock()
 			return
 		}
+		if err := r.driveRollout(d, s, rollout); err != nil {
+			r.Lock()
+			r.Resolution.DeployState = s
+			r.Resolution.Error = WrapResolveError(err)
+			r.Unlock()
+			return
+		}
 		if s.Final() && s.SourceID.Equal(r.Pair.Post.SourceID) {
 			r.Lock()
 
@@ -175,6 +209,125 @@ func (r *Rectification) pollOnce(d Deployer, reg Registry, clusters Clusters) (*
 	return depState, nil
 }
 
+// RolloutProgress returns the progress of this rectification's rollout, or
+// nil if it isn't a progressive rollout or hasn't started yet.
+func (r *Rectification) RolloutProgress() *RolloutProgress {
+	r.RLock()
+	defer r.RUnlock()
+	if r.rollout == nil {
+		return nil
+	}
+	p := *r.rollout
+	return &p
+}
+
+// rolloutDriver tracks the steps of a progressive rollout between polls.
+type rolloutDriver struct {
+	Rollout
+	steps         []int
+	stepCompleted time.Time
+	requested     int
+}
+
+func newRolloutDriver(d *Deployable) *rolloutDriver {
+	if d == nil || d.Deployment == nil || !d.Rollout.Enabled() {
+		return nil
+	}
+	return &rolloutDriver{
+		Rollout: d.Rollout,
+		steps:   d.Rollout.Steps(d.NumInstances),
+	}
+}
+
+func (rd *rolloutDriver) totalPause() time.Duration {
+	if rd == nil {
+		return 0
+	}
+	return time.Duration(len(rd.steps)) * rd.Pause()
+}
+
+// step returns the 1-based index of the step which brings up target
+// instances.
+func (rd *rolloutDriver) step(target int) int {
+	n := 0
+	for _, s := range rd.steps {
+		if s > target {
+			break
+		}
+		n++
+	}
+	if n == 0 {
+		return 1
+	}
+	return n
+}
+
+// driveRollout records the rollout progress reported in s and, if the
+// Deployer leaves rollouts to Sous, advances or aborts the rollout.
+func (r *Rectification) driveRollout(d Deployer, s *DeployState, rd *rolloutDriver) error {
+	if rd == nil || s.RolloutProgress == nil {
+		return nil
+	}
+	p := *s.RolloutProgress
+	p.TotalSteps = len(rd.steps)
+	p.Step = rd.step(p.TargetInstances)
+
+	record := func() {
+		cp := p
+		r.Lock()
+		changed := r.rollout == nil || *r.rollout != cp
+		r.rollout = &cp
+		r.Unlock()
+		if changed {
+			r.publishPhase(R11nRollingOut, &cp)
+		}
+	}
+	record()
+
+	ctl, ok := d.(RolloutDeployer)
+	if !ok {
+		return nil
+	}
+
+	if rd.AbortOnFailedCheck && p.FailedInstances > 0 {
+		logging.Deliver(r.log,
+			logging.SousGenericV1,
+			logging.GetCallerInfo(logging.NotHere()),
+			logging.WarningLevel,
+			logging.ConsoleAndMessage(fmt.Sprintf("Aborting rollout of %s at %s", r.Pair.ID(), p)),
+			r.Pair,
+		)
+		p.Aborted = true
+		record()
+		if err := ctl.AbortRollout(&r.Pair, s); err != nil {
+			return err
+		}
+		return &RolloutAbortedError{Progress: p}
+	}
+
+	if !p.StepComplete || p.Step >= p.TotalSteps || p.TargetInstances < rd.requested {
+		rd.stepCompleted = time.Time{}
+		return nil
+	}
+	if rd.stepCompleted.IsZero() {
+		rd.stepCompleted = time.Now()
+	}
+	if time.Since(rd.stepCompleted) < rd.Pause() {
+		return nil
+	}
+
+	rd.stepCompleted = time.Time{}
+	rd.requested = rd.steps[p.Step]
+	logging.Deliver(r.log,
+		logging.SousGenericV1,
+		logging.GetCallerInfo(logging.NotHere()),
+		logging.InformationLevel,
+		logging.ConsoleAndMessage(fmt.Sprintf("Advancing rollout of %s to %d instances", r.Pair.ID(), rd.requested)),
+		r.Pair,
+	)
+	return ctl.AdvanceRollout(&r.Pair, s, rd.requested)
+}
+
 // Wait must be called after Begin. It waits for and returns the result.
 func (r *Rectification) Wait() DiffResolution {
 	<-r.ctx.Done()
@@ -94,3 +94,82 @@ func TestRectification_enact_failsImmediatelyWhenRectifyFails(t *testing.T) {
 		t.Errorf("got error %q; want suffix %q", got, wantSuffix)
 	}
 }
+
+type rolloutDeployerSpy struct {
+	*DeployerSpy
+}
+
+func (dd rolloutDeployerSpy) AdvanceRollout(pair *DeployablePair, state *DeployState, targetInstances int) error {
+	return dd.Called(pair, state, targetInstances).Error(0)
+}
+
+func (dd rolloutDeployerSpy) AbortRollout(pair *DeployablePair, state *DeployState) error {
+	return dd.Called(pair, state).Error(0)
+}
+
+func rolloutRectification() *Rectification {
+	log, _ := logging.NewLogSinkSpy()
+	return NewRectification(DeployablePair{
+		Post: &Deployable{
+			Deployment: &Deployment{
+				DeployConfig: DeployConfig{
+					NumInstances: 10,
+					Rollout: Rollout{
+						CanaryInstances:    1,
+						StepPercentages:    []int{50},
+						AbortOnFailedCheck: true,
+					},
+				},
+			},
+		},
+	}, log)
+}
+
+func TestRectification_driveRollout_advances(t *testing.T) {
+	r := rolloutRectification()
+	d, c := NewDeployerSpy()
+	c.MatchMethod("AdvanceRollout", spies.AnyArgs, nil)
+	rd := newRolloutDriver(r.Pair.Post)
+
+	s := &DeployState{RolloutProgress: &RolloutProgress{TargetInstances: 1, ActiveInstances: 0}}
+	if err := r.driveRollout(rolloutDeployerSpy{d.(*DeployerSpy)}, s, rd); err != nil {
+		t.Fatal(err)
+	}
+	if calls := c.CallsTo("AdvanceRollout"); len(calls) != 0 {
+		t.Errorf("advanced rollout before canary was active")
+	}
+	if p := r.RolloutProgress(); p == nil || p.Step != 1 || p.TotalSteps != 3 {
+		t.Errorf("got progress %v, want step 1/3", p)
+	}
+
+	s.RolloutProgress = &RolloutProgress{TargetInstances: 1, ActiveInstances: 1, StepComplete: true}
+	if err := r.driveRollout(rolloutDeployerSpy{d.(*DeployerSpy)}, s, rd); err != nil {
+		t.Fatal(err)
+	}
+	calls := c.CallsTo("AdvanceRollout")
+	if len(calls) != 1 {
+		t.Fatalf("got %d calls to AdvanceRollout, want 1", len(calls))
+	}
+	if target := calls[0].PassedArgs().Int(2); target != 5 {
+		t.Errorf("advanced rollout to %d instances, want 5", target)
+	}
+}
+
+func TestRectification_driveRollout_aborts(t *testing.T) {
+	r := rolloutRectification()
+	d, c := NewDeployerSpy()
+	c.MatchMethod("AbortRollout", spies.AnyArgs, nil)
+	rd := newRolloutDriver(r.Pair.Post)
+
+	s := &DeployState{RolloutProgress: &RolloutProgress{TargetInstances: 1, FailedInstances: 1}}
+	err := r.driveRollout(rolloutDeployerSpy{d.(*DeployerSpy)}, s, rd)
+	if _, is := err.(*RolloutAbortedError); !is {
+		t.Fatalf("got error %v, want *RolloutAbortedError", err)
+	}
+	if len(c.CallsTo("AbortRollout")) != 1 {
+		t.Errorf("AbortRollout not called")
+	}
+	if p := r.RolloutProgress(); p == nil || !p.Aborted {
+		t.Errorf("got progress %v, want aborted", p)
+	}
+}
@@ -37,6 +37,12 @@ type (
 	// singularity
 	FailedStatusError struct{} // XXX maybe handy to have the root Singularity non-SUCCEEDED status?
 
+	// A RolloutAbortedError reports that Sous cancelled a progressive rollout
+	// because new instances failed their health checks.
+	RolloutAbortedError struct {
+		Progress RolloutProgress
+	}
+
 	// An UnacceptableAdvisory reports that there is an advisory on an image
 	// which hasn't been whitelisted on the target cluster
 	UnacceptableAdvisory struct {
@@ -44,6 +50,16 @@ type (
 		*SourceID
 	}
 
+	// An UnsignedImageError reports that an image was refused by a cluster
+	// which requires signed images, or by a server configured with trusted
+	// keys, because it isn't signed, or isn't signed by one of the trusted
+	// keys.
+	UnsignedImageError struct {
+		SourceID SourceID
+		Image    string
+		Cause    error
+	}
+
 	// CreateError is returned when there's an error trying to create a deployment
 	CreateError struct {
 		Deployment *Deployment
@@ -164,11 +180,18 @@ func IsTransientResolveError(err error) bool {
 		// There's no expectation that it will self correct. In the future, we
 		// should do a automatic rollback.
 		return false
+	case *RolloutAbortedError:
+		// The aborted version will fail the same way if we try it again.
+		return false
 	case *UnacceptableAdvisory:
 		// UnacceptableAdvisory is excluded, since this requires operator
 		// intervention: either the image needs to be rebuilt clean, or the cluster
 		// reconfigured to accept the advisory.
 		return false
+	case *UnsignedImageError:
+		// UnsignedImageError requires operator intervention too: either the
+		// image needs to be signed, or the key it was signed with trusted.
+		return false
 	case *MissingImageNameError:
 		// MissingImageNameError isn't transient: it requires that an appropriate
 		// image be built with the desired name and the server needs to be able to
@@ -196,10 +219,18 @@ func (e *MissingImageNameError) Error() string {
 	return fmt.Sprintf("Image name unknown to Sous for source IDs: %s", e.Cause.Error())
 }
 
+func (e *RolloutAbortedError) Error() string {
+	return fmt.Sprintf("Rollout aborted at %s with %d failed instances", e.Progress, e.Progress.FailedInstances)
+}
+
 func (e *UnacceptableAdvisory) Error() string {
 	return fmt.Sprintf("Advisory unacceptable on image: %s for %v", e.Quality.Name, e.SourceID)
 }
 
+func (e *UnsignedImageError) Error() string {
+	return fmt.Sprintf("Image %s for %v not signed by a trusted key: %v", e.Image, e.SourceID, e.Cause)
+}
+
 func (e *FailedStatusError) Error() string {
 	return "Deploy failed on Singularity."
 }
@@ -2,6 +2,7 @@ package sous
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/opentable/sous/util/logging"
 	"github.com/pkg/errors"
@@ -18,8 +19,14 @@ type (
 		Tag      ResolveFieldMatcher
 		Revision ResolveFieldMatcher
 		Flavor   ResolveFieldMatcher
-		Cluster  ResolveFieldMatcher
-		Status   DeployStatus
+		// Cluster matches a ClusterSelector: a cluster or group name, or
+		// cluster labels.
+		Cluster ResolveFieldMatcher
+		Status  DeployStatus
+
+		// clusters, if set by SelectClusters, are the names of the clusters
+		// Cluster selects.
+		clusters map[string]struct{}
 	}
 
 	// A ResolveFieldMatcher matches against any particular string, or All strings.
@@ -83,7 +90,65 @@ func (rf *ResolveFilter) matchFlavor(flavor string) bool {
 }
 
 func (rf *ResolveFilter) matchCluster(cluster string) bool {
-	return rf.Cluster.match(cluster)
+	return rf.matchClusterDef(cluster, nil)
+}
+
+// matchClusterDef matches the cluster called name, whose definition c may be
+// nil if it isn't known.
+func (rf *ResolveFilter) matchClusterDef(name string, c *Cluster) bool {
+	if rf.Cluster.All() {
+		return true
+	}
+	if rf.clusters != nil {
+		_, selected := rf.clusters[name]
+		return selected
+	}
+	return ClusterSelector(*rf.Cluster.Match).Matches(name, c)
+}
+
+// SelectClusters returns a copy of rf which matches exactly the clusters in
+// cs its Cluster selects, even where their definitions aren't known.
+func (rf *ResolveFilter) SelectClusters(cs Clusters) *ResolveFilter {
+	selected := *rf
+	if rf.Cluster.All() {
+		return &selected
+	}
+	selected.clusters = map[string]struct{}{}
+	for _, name := range cs.Select(ClusterSelector(*rf.Cluster.Match)) {
+		selected.clusters[name] = struct{}{}
+	}
+	return &selected
+}
+
+// ClusterNames returns the sorted names of the clusters in cs rf matches.
+func (rf *ResolveFilter) ClusterNames(cs Clusters) []string {
+	return rf.SelectClusters(cs).FilteredClusters(cs).Names()
+}
+
+// MatchedClusterNames returns the sorted names of the clusters rf matches
+// where their definitions aren't known, as FilterClusterName would, and
+// false. If rf matches every cluster, it returns nil and true.
+func (rf *ResolveFilter) MatchedClusterNames() ([]string, bool) {
+	switch {
+	case rf.Cluster.All():
+		return nil, true
+	case rf.clusters != nil:
+		names := make([]string, 0, len(rf.clusters))
+		for name := range rf.clusters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names, false
+	case ClusterSelector(*rf.Cluster.Match).IsLabelSelector():
+		return []string{}, false
+	}
+	return []string{*rf.Cluster.Match}, false
+}
+
+// SelectsSingleCluster returns true if rf's Cluster can only match a single
+// cluster by name.
+func (rf *ResolveFilter) SelectsSingleCluster() bool {
+	return !rf.Cluster.All() && !ClusterSelector(*rf.Cluster.Match).IsLabelSelector()
 }
 
 func (rf *ResolveFilter) matchDeployStatus(status DeployStatus) bool {
@@ -152,6 +217,9 @@ func (rf *ResolveFilter) DeploymentID(mid ManifestID) (DeploymentID, error) {
 	if rf.Cluster.All() {
 		return DeploymentID{}, fmt.Errorf("you must select a cluster using the -cluster flag")
 	}
+	if !rf.SelectsSingleCluster() {
+		return DeploymentID{}, fmt.Errorf("-cluster %s selects clusters by label; you must name a single cluster", *rf.Cluster.Match)
+	}
 	return DeploymentID{ManifestID: mid, Cluster: rf.Cluster.ValueOr("<no-cluster!>")}, nil
 }
 
@@ -173,7 +241,7 @@ func (rf *ResolveFilter) String() string {
 func (rf *ResolveFilter) FilteredClusters(c Clusters) Clusters {
 	newC := make(Clusters)
 	for n, c := range c {
-		if !rf.FilterClusterName(n) {
+		if !rf.matchClusterDef(n, c) {
 			continue
 		}
 		newC[n] = c // c is a *Cluster, so be aware they need to not be changed
@@ -195,7 +263,7 @@ func (rf *ResolveFilter) FilterDeployment(d *Deployment) bool {
 		rf.matchTag(d.SourceID.Version.String()) &&
 		//rf.matchRevision(d.SourceID.RevID()) &&
 		rf.matchFlavor(d.Flavor) &&
-		rf.matchCluster(d.ClusterName)
+		rf.matchClusterDef(d.ClusterName, d.Cluster)
 }
 
 // FilterDeployStates is similar to FilterDeployment, but also filters by
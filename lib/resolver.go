@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/logging/messages"
@@ -18,6 +19,12 @@ type (
 		*ResolveFilter
 		ls       logging.LogSink
 		QueueSet *R11nQueueSet
+		// Events, if set, is where updates to the status of each resolve are
+		// published.
+		Events *DeployEvents
+		// Drift, if set, records running deployments which have drifted from
+		// what Sous last applied.
+		Drift *DriftDetector
 	}
 
 	// DeploymentPredicate takes a *Deployment and returns true if the
@@ -39,8 +46,10 @@ func NewResolver(d Deployer, r Registry, rf *ResolveFilter, ls logging.LogSink,
 
 // queueDiffs adds a rectification for each required change in DeployableChans,
 // as long as there is no planned or currently executing resolution for the
-// DeploymentID relating to that rectification.
-func (r *Resolver) queueDiffs(dcs *DeployableChans, results chan DiffResolution) {
+// DeploymentID relating to that rectification, the change isn't a version
+// change forbidden by freezes, and it wouldn't revert drift that should only be
+// reported.
+func (r *Resolver) queueDiffs(dcs *DeployableChans, results chan DiffResolution, freezes FreezeWindows) {
 	var wg sync.WaitGroup
 	for p := range dcs.Pairs {
 		if p.Post == nil {
@@ -62,6 +71,26 @@ func (r *Resolver) queueDiffs(dcs *DeployableChans, results chan DiffResolution)
 				logging.ExtraDebug1Level, r.ls, p)
 			continue
 		}
+		if err := checkVersionFreeze(p, freezes); err != nil {
+			messages.ReportLogFieldsMessageWithIDs("Not adding frozen version change",
+				logging.InformationLevel, r.ls, p)
+			wg.Add(1)
+			go func(id DeploymentID) {
+				defer wg.Done()
+				results <- DiffResolution{DeploymentID: id, Desc: ModifyDiff, Error: WrapResolveError(err)}
+			}(p.ID())
+			continue
+		}
+		if r.Drift.ReportOnly(p) {
+			messages.ReportLogFieldsMessageWithIDs("Not reverting reported drift",
+				logging.InformationLevel, r.ls, p)
+			wg.Add(1)
+			go func(id DeploymentID) {
+				defer wg.Done()
+				results <- DiffResolution{DeploymentID: id, Desc: DriftDiff}
+			}(p.ID())
+			continue
+		}
 		sr := NewRectification(*p, r.ls)
 		r.reportQSWait("Adding to queue set", logging.NotHere(), sr)
 		queued, ok := r.QueueSet.PushIfEmpty(sr)
@@ -105,17 +134,42 @@ func (r *Resolver) reportQSWait(msg string, notThere logging.Excluder, fields ..
 // the appropriate components to compute the intended deployment set, collect
 // the actual set, compute the diffs and then issue the commands to rectify
 // those differences.
+//
+// Begin ignores freezes and pauses, and can't check signed images; use
+// BeginWithDefs to resolve the GDM.
 func (r *Resolver) Begin(intended Deployments, clusters Clusters) *ResolveRecorder {
-	intended = intended.Filter(r.FilterDeployment)
+	return r.BeginWithFreezes(intended, clusters, nil)
+}
+
+// BeginWithFreezes is like Begin, except that version changes to deployments
+// frozen by freezes are not rectified; they are reported as errors instead.
+func (r *Resolver) BeginWithFreezes(intended Deployments, clusters Clusters, freezes FreezeWindows) *ResolveRecorder {
+	return r.begin(intended, clusters, freezes, nil, nil)
+}
+
+// BeginWithDefs is like BeginWithFreezes, using the clusters and freezes in
+// defs, except that deployments paused by defs are left alone altogether, and
+// images for clusters that RequireSignedImages are checked against the
+// TrustedKeys in defs.
+func (r *Resolver) BeginWithDefs(intended Deployments, defs Defs) *ResolveRecorder {
+	return r.begin(intended, defs.Clusters, defs.Freezes, defs.Pauses, defs.TrustedKeys)
+}
+
+func (r *Resolver) begin(intended Deployments, clusters Clusters, freezes FreezeWindows, pauses Pauses, keys TrustedKeys) *ResolveRecorder {
+	// Running deployments may not carry their clusters' labels, so match them
+	// by the names of the clusters selected.
+	rf := r.ResolveFilter.SelectClusters(clusters)
+	intended = intended.Filter(rf.FilterDeployment)
+	intended = intended.Filter(func(d *Deployment) bool { return !r.paused(pauses, d.ID()) })
 
-	return NewResolveRecorder(intended, r.ls, func(recorder *ResolveRecorder) {
+	return newResolveRecorder(intended, r.ls, r.Events, func(recorder *ResolveRecorder) {
 		var actual DeployStates
 		var diffs *DeployableChans
 		var logger *DeployableChans
 		ctx := context.Background()
 
 		recorder.performPhase("filtering clusters", func() error {
-			clusters = r.FilteredClusters(clusters)
+			clusters = rf.FilteredClusters(clusters)
 			return nil
 		})
 
@@ -126,7 +180,13 @@ func (r *Resolver) Begin(intended Deployments, clusters Clusters) *ResolveRecord
 		})
 
 		recorder.performPhase("filtering running deployments", func() error {
-			actual = actual.Filter(r.FilterDeployStates)
+			actual = actual.Filter(rf.FilterDeployStates)
+			actual = actual.Filter(func(ds *DeployState) bool { return !r.paused(pauses, ds.ID()) })
+			return nil
+		})
+
+		recorder.performPhase("detecting drift", func() error {
+			r.Drift.Detect(actual, intended)
 			return nil
 		})
 
@@ -136,7 +196,7 @@ func (r *Resolver) Begin(intended Deployments, clusters Clusters) *ResolveRecord
 		})
 
 		recorder.performPhase("resolving deployment artifacts", func() error {
-			namer := diffs.ResolveNames(ctx, r.Registry, r.ls)
+			namer := diffs.ResolveNames(ctx, r.Registry, keys, r.ls)
 			logger = namer.Log(ctx, r.ls)
 			logger.Add(1)
 			go func() {
@@ -151,7 +211,7 @@ func (r *Resolver) Begin(intended Deployments, clusters Clusters) *ResolveRecord
 		})
 
 		recorder.performPhase("rectification", func() error {
-			r.queueDiffs(logger, recorder.Log)
+			r.queueDiffs(logger, recorder.Log, freezes)
 			return nil
 		})
 
@@ -163,3 +223,27 @@ func (r *Resolver) Begin(intended Deployments, clusters Clusters) *ResolveRecord
 		}
 	})
 }
+
+// paused returns true if pauses pauses the deployment with ID did, reporting
+// that it is being left alone.
+func (r *Resolver) paused(pauses Pauses, did DeploymentID) bool {
+	p, paused := pauses.Paused(did)
+	if paused {
+		messages.ReportLogFieldsMessageWithIDs("Not resolving paused deployment: "+p.String(),
+			logging.DebugLevel, r.ls, did)
+	}
+	return paused
+}
+
+// checkVersionFreeze returns a *FreezeError if p changes the version of a
+// deployment frozen by freezes.
+func checkVersionFreeze(p *DeployablePair, freezes FreezeWindows) error {
+	if len(freezes) == 0 || p.Kind() != ModifiedKind {
+		return nil
+	}
+	prior, post := p.Prior.Deployment, p.Post.Deployment
+	if prior.SourceID.Version.String() == post.SourceID.Version.String() {
+		return nil
+	}
+	return freezes.Check(p.ID(), post.Owners, time.Now())
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/opentable/sous/util/logging"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGuardImageMissing(t *testing.T) {
@@ -20,7 +21,7 @@ func TestGuardImageMissing(t *testing.T) {
 	dr.FeedArtifact(nil, fmt.Errorf("dummy error"))
 
 	ls, _ := logging.NewLogSinkSpy()
-	_, err := guardImage(dr, &missing, ls)
+	_, err := guardImage(dr, &missing, nil, ls)
 	assert.Error(err)
 }
 
@@ -40,7 +41,7 @@ func TestGuardImageRejected(t *testing.T) {
 	}, nil)
 
 	ls, _ := logging.NewLogSinkSpy()
-	_, err := guardImage(dr, &rejected, ls)
+	_, err := guardImage(dr, &rejected, nil, ls)
 	assert.Error(err)
 
 }
@@ -56,7 +57,7 @@ func TestAllowUndeployedUglies(t *testing.T) {
 	dr.FeedArtifact(nil, fmt.Errorf("dummy error"))
 
 	ls, _ := logging.NewLogSinkSpy()
-	_, err := guardImage(dr, &borken, ls)
+	_, err := guardImage(dr, &borken, nil, ls)
 	assert.NoError(err)
 }
 
@@ -75,7 +76,54 @@ func TestAllowsWhitelistedAdvisories(t *testing.T) {
 	}, nil)
 
 	ls, _ := logging.NewLogSinkSpy()
-	art, err := guardImage(dr, &intoCI, ls)
+	art, err := guardImage(dr, &intoCI, nil, ls)
 	assert.NoError(err)
 	assert.NotNil(art)
 }
+
+// signingRegistry is a DummyRegistry that can also get image provenance.
+type signingRegistry struct {
+	*DummyRegistry
+	mapProvenanceStore
+}
+
+func TestGuardImageSignatures(t *testing.T) {
+	svOne := MustParseSourceID(`github.com/ot/one,1.3.5`)
+	clusterX := &Cluster{Name: "x", RequireSignedImages: true}
+	dep := Deployment{ClusterName: `x`, SourceID: svOne, DeployConfig: DeployConfig{NumInstances: 1}, Cluster: clusterX}
+	key, pub := provenanceTestKey(t)
+	_, otherPub := provenanceTestKey(t)
+	signer, err := NewProvenanceSigner(key)
+	require.NoError(t, err)
+	keys := TrustedKeys{"ci": string(pub)}
+	ls, _ := logging.NewLogSinkSpy()
+
+	guard := func(r interface {
+		Registry
+		FeedArtifact(*BuildArtifact, error)
+	}, keys TrustedKeys) error {
+		r.FeedArtifact(&BuildArtifact{DigestReference: provenanceTestImage, Type: "docker"}, nil)
+		_, err := guardImage(r, &dep, keys, ls)
+		return err
+	}
+
+	err = guard(NewDummyRegistry(), keys)
+	assert.IsType(t, &UnsignedImageError{}, err, "registry without provenance")
+
+	sr := signingRegistry{NewDummyRegistry(), mapProvenanceStore{}}
+	err = guard(sr, keys)
+	assert.IsType(t, &UnsignedImageError{}, err, "unsigned image")
+
+	sp, err := signer.Sign(Provenance{Image: provenanceTestImage})
+	require.NoError(t, err)
+	sr.mapProvenanceStore[provenanceTestImage] = sp
+	assert.NoError(t, guard(sr, keys))
+
+	err = guard(sr, TrustedKeys{"other": string(otherPub)})
+	assert.IsType(t, &UnsignedImageError{}, err, "signed by an untrusted key")
+	err = guard(sr, nil)
+	assert.IsType(t, &UnsignedImageError{}, err, "no trusted keys")
+
+	clusterX.RequireSignedImages = false
+	assert.NoError(t, guard(NewDummyRegistry(), nil), "signatures not required")
+}
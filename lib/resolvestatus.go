@@ -39,6 +39,8 @@ type (
 		err error
 		sync.RWMutex
 		logSink logging.LogSink
+		// events, if set, is where updates to status are published.
+		events *DeployEvents
 	}
 
 	// DiffResolution is the result of applying a single diff.
@@ -55,6 +57,10 @@ type (
 
 		// SchedulerURL is a URL where this deployment can be seen.
 		SchedulerURL string
+
+		// AutoRollback is set if Sous rolled this deployment back after it
+		// failed.
+		AutoRollback *RollbackRecord
 	}
 
 	// ResolutionType marks the kind of a DiffResolution
@@ -73,6 +79,9 @@ const (
 	ModifyDiff = ResolutionType("updated")
 	// DeleteDiff - a deployment was active that wasn't intended at all, and was deleted.
 	DeleteDiff = ResolutionType("deleted")
+	// DriftDiff - a deployment was changed by something other than Sous, and
+	// its manifest asks for that to be reported rather than reverted.
+	DriftDiff = ResolutionType("drifted")
 )
 
 func (rez DiffResolution) String() string {
@@ -95,6 +104,12 @@ func (rez DiffResolution) EachField(f logging.FieldReportFn) {
 // NewResolveRecorder creates a new ResolveRecorder and calls f with it as its
 // argument. It then returns that ResolveRecorder immediately.
 func NewResolveRecorder(intended Deployments, ls logging.LogSink, f func(*ResolveRecorder)) *ResolveRecorder {
+	return newResolveRecorder(intended, ls, nil, f)
+}
+
+// newResolveRecorder is like NewResolveRecorder, but also publishes each
+// update to the status to events.
+func newResolveRecorder(intended Deployments, ls logging.LogSink, events *DeployEvents, f func(*ResolveRecorder)) *ResolveRecorder {
 	rr := &ResolveRecorder{
 		status: &ResolveStatus{
 			Started:  time.Now(),
@@ -105,6 +120,7 @@ func NewResolveRecorder(intended Deployments, ls logging.LogSink, f func(*Resolv
 		Log:      make(chan DiffResolution, 10),
 		finished: make(chan struct{}),
 		logSink:  ls,
+		events:   events,
 	}
 
 	for _, d := range intended.Snapshot() {
@@ -121,6 +137,7 @@ func NewResolveRecorder(intended Deployments, ls logging.LogSink, f func(*Resolv
 					messages.ReportLogFieldsMessage("resolve error", logging.DebugLevel, ls, rez.Error)
 				}
 			})
+			rr.publishStatus()
 		}
 	}()
 
@@ -135,6 +152,7 @@ func NewResolveRecorder(intended Deployments, ls logging.LogSink, f func(*Resolv
 			}
 			close(rr.finished)
 		})
+		rr.publishStatus()
 	}()
 	return rr
 }
@@ -208,6 +226,16 @@ func (rr *ResolveRecorder) setPhase(phase string) {
 	rr.write(func() {
 		rr.status.Phase = phase
 	})
+	rr.publishStatus()
+}
+
+// publishStatus publishes the current status, if anyone is listening.
+func (rr *ResolveRecorder) publishStatus() {
+	if !rr.events.publishing() {
+		return
+	}
+	rs := rr.CurrentStatus()
+	rr.events.Publish(DeployEvent{Kind: DeployEventStatus, Status: &rs})
 }
 
 // Phase returns the name of the current phase.
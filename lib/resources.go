@@ -113,7 +113,7 @@ func (r Resources) Cpus() float64 {
 // Memory returns memory in MB.
 func (r Resources) Memory() float64 {
 	memStr := r["memory"]
-	memory, err := strconv.ParseFloat(memStr, 64)
+	memory, err := ParseMemorySize(memStr)
 	if err != nil {
 		memory = 100
 	}
@@ -0,0 +1,142 @@
+package sous
+
+import (
+	"fmt"
+	"time"
+)
+
+type (
+	// Rollout configures a progressive rollout of new versions of a
+	// deployment: first to a few canary instances, then in steps to the full
+	// NumInstances. The zero Rollout replaces all instances at once.
+	Rollout struct {
+		// CanaryInstances is the number of instances that run the new version
+		// in the first step of the rollout.
+		CanaryInstances int `yaml:",omitempty"`
+		// StepPercentages are the percentages of NumInstances running the new
+		// version after each subsequent step. They must be strictly increasing;
+		// a final step to 100% is implied.
+		StepPercentages []int `yaml:",omitempty"`
+		// PauseSeconds is how long to wait after each step completes before
+		// beginning the next.
+		PauseSeconds int `yaml:",omitempty"`
+		// AbortOnFailedCheck cancels the rollout as soon as any new instance
+		// fails its health check, rather than letting the scheduler retry.
+		AbortOnFailedCheck bool `yaml:",omitempty"`
+	}
+
+	// RolloutProgress reports how far a progressive rollout has got. Deployers
+	// fill in the instance counts; Rectification works out the steps.
+	RolloutProgress struct {
+		// Step is the current step of the rollout, counting from 1.
+		Step int
+		// TotalSteps is the number of steps in the rollout.
+		TotalSteps int
+		// TargetInstances is the number of new instances the current step is
+		// bringing up.
+		TargetInstances int
+		// ActiveInstances is the number of new instances running.
+		ActiveInstances int
+		// FailedInstances is the number of new instances that have failed.
+		FailedInstances int
+		// StepComplete is true once ActiveInstances has reached
+		// TargetInstances.
+		StepComplete bool
+		// Aborted is true if Sous cancelled the rollout.
+		Aborted bool
+	}
+)
+
+// Enabled returns true if this Rollout describes a progressive rollout.
+func (r Rollout) Enabled() bool {
+	return r.CanaryInstances > 0 || len(r.StepPercentages) > 0
+}
+
+// Pause returns the time to wait between steps.
+func (r Rollout) Pause() time.Duration {
+	return time.Duration(r.PauseSeconds) * time.Second
+}
+
+// Steps returns the number of instances which should be running the new
+// version after each step of the rollout, given the final number of instances.
+// The last step is always numInstances.
+func (r Rollout) Steps(numInstances int) []int {
+	steps := []int{}
+	last := 0
+	add := func(n int) {
+		if n > last && n < numInstances {
+			steps = append(steps, n)
+			last = n
+		}
+	}
+	add(r.CanaryInstances)
+	for _, p := range r.StepPercentages {
+		add((numInstances*p + 99) / 100)
+	}
+	return append(steps, numInstances)
+}
+
+// Validate implements Flawed on Rollout.
+func (r *Rollout) Validate() []Flaw {
+	flaws := []Flaw{}
+	if r.CanaryInstances < 0 {
+		flaws = append(flaws, FatalFlaw("Rollout CanaryInstances less than zero: %d!", r.CanaryInstances))
+	}
+	if r.PauseSeconds < 0 {
+		flaws = append(flaws, FatalFlaw("Rollout PauseSeconds less than zero: %d!", r.PauseSeconds))
+	}
+	last := 0
+	for _, p := range r.StepPercentages {
+		if p <= last || p > 100 {
+			flaws = append(flaws, FatalFlaw("Rollout StepPercentages must increase from 1 to 100, were %v.", r.StepPercentages))
+			break
+		}
+		last = p
+	}
+	return flaws
+}
+
+// Clone returns an independent copy of this Rollout.
+func (r Rollout) Clone() Rollout {
+	if r.StepPercentages != nil {
+		r.StepPercentages = append([]int{}, r.StepPercentages...)
+	}
+	return r
+}
+
+// Equal returns true if r and o describe the same rollout.
+func (r Rollout) Equal(o Rollout) bool {
+	return len(r.diff(o)) == 0
+}
+
+func (r Rollout) diff(o Rollout) []string {
+	diffs := []string{}
+	diff := func(format string, a ...interface{}) {
+		diffs = append(diffs, fmt.Sprintf(format, a...))
+	}
+
+	if r.CanaryInstances != o.CanaryInstances {
+		diff("Rollout CanaryInstances; this %d, other %d", r.CanaryInstances, o.CanaryInstances)
+	}
+	if len(r.StepPercentages) != len(o.StepPercentages) {
+		diff("Rollout StepPercentages; this %v, other %v", r.StepPercentages, o.StepPercentages)
+	} else {
+		for n := range r.StepPercentages {
+			if r.StepPercentages[n] != o.StepPercentages[n] {
+				diff("Rollout StepPercentages; this %v, other %v", r.StepPercentages, o.StepPercentages)
+				break
+			}
+		}
+	}
+	if r.PauseSeconds != o.PauseSeconds {
+		diff("Rollout PauseSeconds; this %d, other %d", r.PauseSeconds, o.PauseSeconds)
+	}
+	if r.AbortOnFailedCheck != o.AbortOnFailedCheck {
+		diff("Rollout AbortOnFailedCheck; this %v, other %v", r.AbortOnFailedCheck, o.AbortOnFailedCheck)
+	}
+	return diffs
+}
+
+func (p RolloutProgress) String() string {
+	return fmt.Sprintf("step %d/%d: %d/%d instances active", p.Step, p.TotalSteps, p.ActiveInstances, p.TargetInstances)
+}
@@ -0,0 +1,60 @@
+package sous
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRollout_Steps(t *testing.T) {
+	cases := []struct {
+		rollout   Rollout
+		instances int
+		want      []int
+	}{
+		{Rollout{}, 4, []int{4}},
+		{Rollout{CanaryInstances: 1}, 4, []int{1, 4}},
+		{Rollout{CanaryInstances: 1, StepPercentages: []int{25, 50}}, 10, []int{1, 3, 5, 10}},
+		{Rollout{CanaryInstances: 2, StepPercentages: []int{10, 50, 100}}, 10, []int{2, 5, 10}},
+		{Rollout{CanaryInstances: 5}, 3, []int{3}},
+	}
+	for _, c := range cases {
+		got := c.rollout.Steps(c.instances)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%#v.Steps(%d): got %v, want %v", c.rollout, c.instances, got, c.want)
+		}
+	}
+}
+
+func TestRollout_Validate(t *testing.T) {
+	good := Rollout{CanaryInstances: 1, StepPercentages: []int{25, 50}, PauseSeconds: 30}
+	if flaws := good.Validate(); len(flaws) != 0 {
+		t.Errorf("%#v: unexpected flaws: %v", good, flaws)
+	}
+
+	for _, bad := range []Rollout{
+		{CanaryInstances: -1},
+		{PauseSeconds: -1},
+		{StepPercentages: []int{50, 25}},
+		{StepPercentages: []int{0}},
+		{StepPercentages: []int{150}},
+	} {
+		if flaws := bad.Validate(); len(flaws) != 1 {
+			t.Errorf("%#v: got %d flaws, want 1", bad, len(flaws))
+		}
+	}
+}
+
+func TestRollout_Equal(t *testing.T) {
+	a := Rollout{CanaryInstances: 1, StepPercentages: []int{25, 50}}
+	b := a.Clone()
+	if !a.Equal(b) {
+		t.Errorf("clone of %#v not equal", a)
+	}
+	b.StepPercentages[1] = 75
+	if a.Equal(b) {
+		t.Errorf("%#v should differ from %#v", a, b)
+	}
+	if a.StepPercentages[1] != 50 {
+		t.Errorf("Clone shares StepPercentages")
+	}
+}
@@ -0,0 +1,324 @@
+package sous
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+)
+
+type (
+	// A ScheduledDeploy is a change to a single deployment which Sous makes at
+	// a later time, e.g. during a maintenance window.
+	ScheduledDeploy struct {
+		ID           ScheduledDeployID
+		DeploymentID DeploymentID
+		// Deployment is what the deployment is changed to, as it would have
+		// been by PUT /single-deployment.
+		Deployment DeploySpec
+		// Prior is the deployment as it was when the deploy was scheduled.
+		// The deploy fails if the deployment has changed since, rather than
+		// undoing the change.
+		Prior *DeploySpec `json:",omitempty"`
+		// Force rectifies the deployment even if Deployment is unchanged.
+		Force bool
+		// At is when the change is made.
+		At time.Time
+		// User is who requested the change. The GDM is changed as them.
+		User User
+		// Requested is when the change was requested.
+		Requested time.Time
+		Status    ScheduledDeployStatus
+		// Error explains why a failed deploy failed.
+		Error string `json:",omitempty"`
+		// Finished is when the deploy was applied, failed or was cancelled.
+		Finished *time.Time `json:",omitempty"`
+	}
+
+	// ScheduledDeployID identifies a ScheduledDeploy.
+	ScheduledDeployID string
+
+	// ScheduledDeployStatus describes how far a ScheduledDeploy has got.
+	ScheduledDeployStatus string
+
+	// A ScheduleStore keeps ScheduledDeploys.
+	ScheduleStore interface {
+		// AddScheduledDeploy stores sd.
+		AddScheduledDeploy(sd ScheduledDeploy) error
+		// ScheduledDeploys returns the scheduled deploys of deployments
+		// matched by filter, ordered by At.
+		ScheduledDeploys(filter *ResolveFilter) ([]ScheduledDeploy, error)
+		// SetScheduledDeployStatus changes the status of the scheduled deploy
+		// id from from to to, recording errMsg and when it finished. It
+		// returns false, and changes nothing, unless the deploy has status
+		// from.
+		SetScheduledDeployStatus(id ScheduledDeployID, from, to ScheduledDeployStatus, errMsg string, at time.Time) (bool, error)
+	}
+
+	// InMemorySchedule is a ScheduleStore that keeps scheduled deploys in
+	// memory. It's used when no database is available.
+	InMemorySchedule struct {
+		sync.RWMutex
+		deploys []ScheduledDeploy
+	}
+
+	// DeployScheduler makes scheduled deploys once they are due. Each is
+	// written to the GDM as the user who scheduled it, and rectified through
+	// QueueSet.
+	DeployScheduler struct {
+		StateManager StateManager
+		QueueSet     QueueSet
+		Store        ScheduleStore
+		// Filter selects the deploys this scheduler makes: those to the
+		// clusters its server resolves.
+		Filter *ResolveFilter
+
+		log logging.LogSink
+	}
+)
+
+const (
+	// ScheduledPending means the deploy hasn't been made yet.
+	ScheduledPending = ScheduledDeployStatus("pending")
+	// ScheduledApplied means the deploy was written to the GDM and queued.
+	ScheduledApplied = ScheduledDeployStatus("applied")
+	// ScheduledFailed means the deploy couldn't be made; see Error.
+	ScheduledFailed = ScheduledDeployStatus("failed")
+	// ScheduledCancelled means the deploy was cancelled before it was due.
+	ScheduledCancelled = ScheduledDeployStatus("cancelled")
+)
+
+// ScheduledDeployInterval is how often a DeployScheduler checks for deploys
+// that are due.
+const ScheduledDeployInterval = 15 * time.Second
+
+func (sd ScheduledDeploy) String() string {
+	return fmt.Sprintf("%s of %s at version %s, %s by %s, %s",
+		sd.ID, sd.DeploymentID, sd.Deployment.Version, sd.At.Format(time.RFC1123), sd.User, sd.Status)
+}
+
+// Matches returns true if filter matches the deployment sd changes.
+func (sd ScheduledDeploy) Matches(filter *ResolveFilter) bool {
+	if filter == nil {
+		return true
+	}
+	return filter.FilterManifestID(sd.DeploymentID.ManifestID) &&
+		filter.FilterClusterName(sd.DeploymentID.Cluster)
+}
+
+// NewInMemorySchedule returns an empty InMemorySchedule.
+func NewInMemorySchedule() *InMemorySchedule {
+	return &InMemorySchedule{}
+}
+
+// AddScheduledDeploy implements ScheduleStore on InMemorySchedule.
+func (s *InMemorySchedule) AddScheduledDeploy(sd ScheduledDeploy) error {
+	s.Lock()
+	defer s.Unlock()
+	s.deploys = append(s.deploys, sd)
+	return nil
+}
+
+// ScheduledDeploys implements ScheduleStore on InMemorySchedule.
+func (s *InMemorySchedule) ScheduledDeploys(filter *ResolveFilter) ([]ScheduledDeploy, error) {
+	s.RLock()
+	defer s.RUnlock()
+	deploys := []ScheduledDeploy{}
+	for _, sd := range s.deploys {
+		if sd.Matches(filter) {
+			deploys = append(deploys, sd)
+		}
+	}
+	sort.SliceStable(deploys, func(i, j int) bool {
+		return deploys[i].At.Before(deploys[j].At)
+	})
+	return deploys, nil
+}
+
+// SetScheduledDeployStatus implements ScheduleStore on InMemorySchedule.
+func (s *InMemorySchedule) SetScheduledDeployStatus(id ScheduledDeployID, from, to ScheduledDeployStatus, errMsg string, at time.Time) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	for i := range s.deploys {
+		sd := &s.deploys[i]
+		if sd.ID != id {
+			continue
+		}
+		if sd.Status != from {
+			return false, nil
+		}
+		sd.Status = to
+		sd.Error = errMsg
+		sd.Finished = &at
+		return true, nil
+	}
+	return false, nil
+}
+
+// NewDeployScheduler returns a DeployScheduler which makes the deploys in
+// store matched by rf.
+func NewDeployScheduler(sm StateManager, qs QueueSet, store ScheduleStore, rf *ResolveFilter, ls logging.LogSink) *DeployScheduler {
+	return &DeployScheduler{
+		StateManager: sm,
+		QueueSet:     qs,
+		Store:        store,
+		Filter:       rf,
+		log:          ls,
+	}
+}
+
+// Schedule stores sd, to be deployed at sd.At, and returns it with its ID
+// and status set.
+func (ds *DeployScheduler) Schedule(sd ScheduledDeploy) (ScheduledDeploy, error) {
+	sd.ID = ScheduledDeployID(uuid.New())
+	sd.Status = ScheduledPending
+	sd.Requested = time.Now()
+	sd.Error = ""
+	sd.Finished = nil
+	if err := ds.Store.AddScheduledDeploy(sd); err != nil {
+		return ScheduledDeploy{}, errors.Wrapf(err, "scheduling deploy of %s", sd.DeploymentID)
+	}
+	messages.ReportLogFieldsMessage("Scheduled deploy", logging.InformationLevel, ds.log, sd.DeploymentID, sd.ID)
+	return sd, nil
+}
+
+// Find returns the scheduled deploy with ID id, if there is one.
+func (ds *DeployScheduler) Find(id ScheduledDeployID) (ScheduledDeploy, bool, error) {
+	deploys, err := ds.Store.ScheduledDeploys(nil)
+	if err != nil {
+		return ScheduledDeploy{}, false, err
+	}
+	for _, sd := range deploys {
+		if sd.ID == id {
+			return sd, true, nil
+		}
+	}
+	return ScheduledDeploy{}, false, nil
+}
+
+// Pending returns the deploys matched by filter that haven't been made yet,
+// soonest first.
+func (ds *DeployScheduler) Pending(filter *ResolveFilter) ([]ScheduledDeploy, error) {
+	deploys, err := ds.Store.ScheduledDeploys(filter)
+	if err != nil {
+		return nil, err
+	}
+	pending := []ScheduledDeploy{}
+	for _, sd := range deploys {
+		if sd.Status == ScheduledPending {
+			pending = append(pending, sd)
+		}
+	}
+	return pending, nil
+}
+
+// Cancel cancels the scheduled deploy id. It returns false if the deploy
+// isn't pending.
+func (ds *DeployScheduler) Cancel(id ScheduledDeployID) (bool, error) {
+	return ds.Store.SetScheduledDeployStatus(id, ScheduledPending, ScheduledCancelled, "", time.Now())
+}
+
+// Kickoff checks for due deploys every ScheduledDeployInterval, in the
+// background.
+func (ds *DeployScheduler) Kickoff() {
+	go func() {
+		for now := range time.Tick(ScheduledDeployInterval) {
+			ds.ApplyDue(now)
+		}
+	}()
+}
+
+// ApplyDue makes the pending deploys matched by Filter which are due at now.
+// Each deploy is claimed before it's made, so that it's made once even if
+// several servers share the store.
+func (ds *DeployScheduler) ApplyDue(now time.Time) {
+	pending, err := ds.Pending(ds.Filter)
+	if err != nil {
+		messages.ReportLogFieldsMessage("Failed to read scheduled deploys", logging.WarningLevel, ds.log, err)
+		return
+	}
+	for _, sd := range pending {
+		if sd.At.After(now) {
+			continue
+		}
+		claimed, err := ds.Store.SetScheduledDeployStatus(sd.ID, ScheduledPending, ScheduledApplied, "", now)
+		if err != nil {
+			messages.ReportLogFieldsMessage("Failed to claim scheduled deploy", logging.WarningLevel, ds.log, sd.DeploymentID, sd.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if err := ds.apply(sd, now); err != nil {
+			messages.ReportLogFieldsMessage("Scheduled deploy failed", logging.WarningLevel, ds.log, sd.DeploymentID, sd.ID, err)
+			if _, err := ds.Store.SetScheduledDeployStatus(sd.ID, ScheduledApplied, ScheduledFailed, err.Error(), now); err != nil {
+				messages.ReportLogFieldsMessage("Failed to record scheduled deploy failure", logging.WarningLevel, ds.log, sd.ID, err)
+			}
+			continue
+		}
+		messages.ReportLogFieldsMessage("Applied scheduled deploy", logging.InformationLevel, ds.log, sd.DeploymentID, sd.ID)
+	}
+}
+
+// apply writes sd to the GDM, as its user, and queues its rectification,
+// unless the deployment is frozen or paused at now, or has changed since sd
+// was scheduled.
+func (ds *DeployScheduler) apply(sd ScheduledDeploy, now time.Time) error {
+	did := sd.DeploymentID
+	state, err := ds.StateManager.ReadState()
+	if err != nil {
+		return err
+	}
+	m, ok := state.Manifests.Get(did.ManifestID)
+	if !ok {
+		return errors.Errorf("no manifest with ID %q", did.ManifestID)
+	}
+	original, _, ok := m.DeploySpecFor(state.Defs, did.Cluster)
+	if !ok {
+		return errors.Errorf("manifest %q has no deployment for cluster %q", did.ManifestID, did.Cluster)
+	}
+	if err := state.Defs.Freezes.Check(did, NewOwnerSet(m.Owners...), now); err != nil {
+		return err
+	}
+	if pause, paused := state.Defs.Pauses.Paused(did); paused {
+		return errors.New(pause.String())
+	}
+	if sd.Prior == nil {
+		return errors.Errorf("deploy of %s was scheduled without recording the deployment it changes; schedule it again", did)
+	}
+	if different, diffs := sd.Prior.Diff(original); different {
+		return errors.Errorf("deployment of %s has changed since the deploy was scheduled (%s); schedule it again",
+			did, strings.Join(diffs, "; "))
+	}
+	if different, _ := sd.Deployment.Diff(original); !different && !sd.Force {
+		return nil
+	}
+
+	m.SetDeploySpec(state.Defs, did.Cluster, sd.Deployment)
+	if err := ds.StateManager.WriteState(state, sd.User); err != nil {
+		return errors.Wrap(err, "writing state")
+	}
+
+	deployments, err := state.Deployments()
+	if err != nil {
+		return err
+	}
+	dep, ok := deployments.Get(did)
+	if !ok {
+		return errors.Errorf("no deployment of %s after writing state", did)
+	}
+	dep.User = sd.User
+
+	r := NewRectification(DeployablePair{Post: &Deployable{Deployment: dep}}, ds.log.Child("r11n"))
+	r.Pair.SetID(did)
+	if _, ok := ds.QueueSet.Push(r); !ok {
+		return errors.Errorf("deploy queue for %s is full", did)
+	}
+	return nil
+}
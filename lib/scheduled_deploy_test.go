@@ -0,0 +1,136 @@
+package sous
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nyarly/spies"
+	"github.com/opentable/sous/util/logging"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scheduleFixture(t *testing.T) (*DeployScheduler, *State, StateManagerController, *spies.Spy, ScheduledDeploy) {
+	t.Helper()
+	state := DefaultStateFixture()
+	sm, smCtrl := NewStateManagerSpyFor(state)
+	smCtrl.MatchMethod("WriteState", spies.AnyArgs, nil)
+	qs, qsCtrl := NewQueueSetSpy()
+	qsCtrl.MatchMethod("Push", spies.AnyArgs, &QueuedR11n{ID: "r11n"}, true)
+
+	mid := MustParseManifestID("github.com/user1/repo1,dir1~flavor1")
+	m, ok := state.Manifests.Get(mid)
+	require.True(t, ok)
+	prior := m.Deployments["cluster0"].Clone()
+	spec := prior.Clone()
+	spec.Version = semv.MustParse("2.0.0")
+
+	ds := NewDeployScheduler(sm, qs, NewInMemorySchedule(), &ResolveFilter{}, logging.SilentLogSet())
+	sd := ScheduledDeploy{
+		DeploymentID: DeploymentID{ManifestID: mid, Cluster: "cluster0"},
+		Deployment:   spec,
+		Prior:        &prior,
+		At:           time.Now().Add(time.Hour),
+		User:         User{Name: "Scheduler", Email: "scheduler@example.com"},
+	}
+	return ds, state, smCtrl, qsCtrl, sd
+}
+
+func TestDeployScheduler_ApplyDue(t *testing.T) {
+	ds, state, smCtrl, qsCtrl, sd := scheduleFixture(t)
+	sd, err := ds.Schedule(sd)
+	require.NoError(t, err)
+	assert.NotEmpty(t, sd.ID)
+	assert.Equal(t, ScheduledPending, sd.Status)
+
+	ds.ApplyDue(time.Now())
+	assert.Empty(t, smCtrl.CallsTo("WriteState"), "deploy made before it was due")
+	pending, err := ds.Pending(nil)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+
+	ds.ApplyDue(sd.At)
+	writes := smCtrl.CallsTo("WriteState")
+	require.Len(t, writes, 1)
+	assert.Equal(t, sd.User, writes[0].PassedArgs().Get(1).(User), "GDM changed as the requesting user")
+	m, _ := state.Manifests.Get(sd.DeploymentID.ManifestID)
+	assert.Equal(t, "2.0.0", m.Deployments["cluster0"].Version.String())
+
+	pushes := qsCtrl.CallsTo("Push")
+	require.Len(t, pushes, 1)
+	r := pushes[0].PassedArgs().Get(0).(*Rectification)
+	assert.Equal(t, sd.User, r.Pair.Post.User)
+
+	found, ok, err := ds.Find(sd.ID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, ScheduledApplied, found.Status)
+
+	ds.ApplyDue(sd.At.Add(time.Hour))
+	assert.Len(t, smCtrl.CallsTo("WriteState"), 1, "deploy made twice")
+}
+
+func TestDeployScheduler_ApplyDue_frozen(t *testing.T) {
+	ds, state, smCtrl, _, sd := scheduleFixture(t)
+	sd, err := ds.Schedule(sd)
+	require.NoError(t, err)
+	state.Defs.Freezes = FreezeWindows{{
+		Reason: "maintenance",
+		Start:  sd.At.Add(-time.Minute),
+		End:    sd.At.Add(time.Hour),
+	}}
+
+	ds.ApplyDue(sd.At)
+	assert.Empty(t, smCtrl.CallsTo("WriteState"))
+	found, _, err := ds.Find(sd.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ScheduledFailed, found.Status)
+	assert.Contains(t, found.Error, "maintenance")
+}
+
+func TestDeployScheduler_ApplyDue_changed(t *testing.T) {
+	ds, state, smCtrl, _, sd := scheduleFixture(t)
+	sd, err := ds.Schedule(sd)
+	require.NoError(t, err)
+	m, _ := state.Manifests.Get(sd.DeploymentID.ManifestID)
+	changed := m.Deployments["cluster0"]
+	changed.NumInstances++
+	m.Deployments["cluster0"] = changed
+
+	ds.ApplyDue(sd.At)
+	assert.Empty(t, smCtrl.CallsTo("WriteState"), "change made since the deploy was scheduled undone")
+	found, _, err := ds.Find(sd.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ScheduledFailed, found.Status)
+	assert.Contains(t, found.Error, "changed since the deploy was scheduled")
+}
+
+func TestDeployScheduler_Cancel(t *testing.T) {
+	ds, _, smCtrl, _, sd := scheduleFixture(t)
+	sd, err := ds.Schedule(sd)
+	require.NoError(t, err)
+
+	cancelled, err := ds.Cancel(sd.ID)
+	require.NoError(t, err)
+	assert.True(t, cancelled)
+	cancelled, err = ds.Cancel(sd.ID)
+	require.NoError(t, err)
+	assert.False(t, cancelled, "cancelled twice")
+
+	ds.ApplyDue(sd.At)
+	assert.Empty(t, smCtrl.CallsTo("WriteState"))
+	pending, err := ds.Pending(nil)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestDeployScheduler_ApplyDue_filter(t *testing.T) {
+	ds, _, smCtrl, _, sd := scheduleFixture(t)
+	ds.Filter = &ResolveFilter{Cluster: NewResolveFieldMatcher("cluster1")}
+	_, err := ds.Schedule(sd)
+	require.NoError(t, err)
+
+	ds.ApplyDue(sd.At)
+	assert.Empty(t, smCtrl.CallsTo("WriteState"), "deploy made by the server of another cluster")
+}
@@ -0,0 +1,95 @@
+package sous
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// A SecretStore looks up secrets by name, so that they needn't be kept
+	// in plaintext in the GDM.
+	SecretStore interface {
+		// Secret returns the value of the named secret.
+		Secret(name string) (string, error)
+	}
+
+	// A SecretRef names a secret in a SecretStore. An Env value of the form
+	// ${secret:name} is a SecretRef, which is resolved only as the deployment
+	// is sent to its cluster.
+	SecretRef string
+
+	// NoSuchSecretError is returned by SecretStores asked for a secret they
+	// don't have.
+	NoSuchSecretError struct {
+		Name string
+	}
+)
+
+const (
+	secretRefPrefix = "${secret:"
+	secretRefSuffix = "}"
+)
+
+// ParseSecretRef returns the SecretRef value is, if it is one.
+func ParseSecretRef(value string) (SecretRef, bool) {
+	if !strings.HasPrefix(value, secretRefPrefix) || !strings.HasSuffix(value, secretRefSuffix) {
+		return "", false
+	}
+	name := strings.TrimSuffix(strings.TrimPrefix(value, secretRefPrefix), secretRefSuffix)
+	if name == "" {
+		return "", false
+	}
+	return SecretRef(name), true
+}
+
+// String returns ref as it is written in an Env.
+func (ref SecretRef) String() string {
+	return secretRefPrefix + string(ref) + secretRefSuffix
+}
+
+func (e *NoSuchSecretError) Error() string {
+	return "no secret named " + e.Name
+}
+
+// SecretRefs returns the SecretRefs in e, by variable name.
+func (e Env) SecretRefs() map[string]SecretRef {
+	refs := map[string]SecretRef{}
+	for n, v := range e {
+		if ref, is := ParseSecretRef(v); is {
+			refs[n] = ref
+		}
+	}
+	return refs
+}
+
+// ResolveSecrets returns a copy of e with each SecretRef replaced by the
+// value of the secret from store. The result must never be stored or logged:
+// see RestoreSecretRefs.
+func (e Env) ResolveSecrets(store SecretStore) (Env, error) {
+	resolved := e.Clone()
+	for n, ref := range e.SecretRefs() {
+		if store == nil {
+			return nil, errors.Errorf("env %s refers to secret %q, but no secret store is configured", n, ref)
+		}
+		v, err := store.Secret(string(ref))
+		if err != nil {
+			return nil, errors.Wrapf(err, "resolving env %s", n)
+		}
+		resolved[n] = v
+	}
+	return resolved, nil
+}
+
+// RestoreSecretRefs returns a copy of e with the variables named in refs set
+// back to their SecretRefs. Use it to redact an Env resolved by
+// ResolveSecrets before it's logged or compared.
+func (e Env) RestoreSecretRefs(refs map[string]SecretRef) Env {
+	restored := e.Clone()
+	for n, ref := range refs {
+		if _, has := restored[n]; has {
+			restored[n] = ref.String()
+		}
+	}
+	return restored
+}
@@ -0,0 +1,67 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+type testSecretStore map[string]string
+
+func (ss testSecretStore) Secret(name string) (string, error) {
+	if v, has := ss[name]; has {
+		return v, nil
+	}
+	return "", &NoSuchSecretError{Name: name}
+}
+
+func TestParseSecretRef(t *testing.T) {
+	for value, want := range map[string]SecretRef{
+		"${secret:db/password}": "db/password",
+		"${secret:}":            "",
+		"secret:db/password":    "",
+		"${secret:db":           "",
+		"hunter2":               "",
+	} {
+		ref, is := ParseSecretRef(value)
+		if ref != want || is != (want != "") {
+			t.Errorf("ParseSecretRef(%q): got %q, %t; want %q", value, ref, is, want)
+		}
+		if is && ref.String() != value {
+			t.Errorf("%q.String(): got %q", ref, ref.String())
+		}
+	}
+}
+
+func TestEnv_ResolveSecrets(t *testing.T) {
+	env := Env{"PASSWORD": "${secret:pw}", "USER": "app"}
+	store := testSecretStore{"pw": "hunter2"}
+
+	resolved, err := env.ResolveSecrets(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved["PASSWORD"] != "hunter2" || resolved["USER"] != "app" {
+		t.Errorf("got %v", resolved)
+	}
+	if env["PASSWORD"] != "${secret:pw}" {
+		t.Errorf("ResolveSecrets changed its receiver: %v", env)
+	}
+
+	restored := resolved.RestoreSecretRefs(env.SecretRefs())
+	if !restored.Equal(env) {
+		t.Errorf("RestoreSecretRefs: got %v; want %v", restored, env)
+	}
+
+	if _, err := env.ResolveSecrets(nil); err == nil {
+		t.Errorf("resolved secrets without a store")
+	}
+	if _, err := (Env{"X": "${secret:missing}"}).ResolveSecrets(store); err == nil {
+		t.Errorf("resolved a missing secret")
+	} else if _, is := errors.Cause(err).(*NoSuchSecretError); !is {
+		t.Errorf("got %T; want a NoSuchSecretError", errors.Cause(err))
+	}
+	if _, err := (Env{"X": "plain"}).ResolveSecrets(nil); err != nil {
+		t.Errorf("Env without secrets needed a store: %v", err)
+	}
+}
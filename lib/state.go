@@ -35,6 +35,14 @@ type (
 		Resources FieldDefinitions
 		// Metadata contains the definitions for metadata fields
 		Metadata FieldDefinitions
+		// Freezes are the periods during which deployments may not be changed.
+		Freezes FreezeWindows `yaml:",omitempty"`
+		// Pauses are the clusters and deployments Sous doesn't resolve until
+		// they are resumed.
+		Pauses Pauses `yaml:",omitempty"`
+		// TrustedKeys are the keys images must be signed by to be deployed to
+		// clusters that RequireSignedImages.
+		TrustedKeys TrustedKeys `yaml:",omitempty"`
 	}
 
 	// EnvDefs is a collection of EnvDef
@@ -52,7 +60,7 @@ type (
 	FieldDefinition struct {
 		Name string
 		// Type is the type of value used to represent quantities or instances
-		// of this resource, e.g. MemorySize, Float, or Int (not yet implemented).
+		// of this resource, e.g. MemorySize, Float, or Int.
 		Type VarType
 
 		// Default adds a GDM wide default for a key.
@@ -71,8 +79,9 @@ type (
 	Cluster struct {
 		// Name is the unique name of this cluster.
 		Name string
-		// Kind is the kind of cluster. Currently the only legal value is
-		// "singularity"
+		// Kind is the kind of cluster, which selects the Deployer used to
+		// manage it. Legal values are "singularity" (the default, if Kind is
+		// empty), "kubernetes" and "nomad".
 		Kind string
 		// BaseURL is the main entrypoint URL for interacting with this cluster.
 		BaseURL string
@@ -83,6 +92,16 @@ type (
 		// AllowedAdvisories lists the artifact advisories which are permissible in
 		// this cluster
 		AllowedAdvisories []string
+		// Labels describe the cluster, e.g. its region, env (tier) or
+		// provider, for ClusterSelectors to select it by.
+		Labels map[string]string `yaml:",omitempty"`
+		// Groups are the names of the cluster groups this cluster belongs to.
+		// A ClusterSelector naming a group selects all its clusters.
+		Groups []string `yaml:",omitempty"`
+		// RequireSignedImages, if true, refuses images to this cluster unless
+		// their provenance, stored in the registry next to them, is signed by
+		// one of the TrustedKeys in Defs.
+		RequireSignedImages bool `yaml:",omitempty"`
 	}
 
 	// EnvDefaults is a list of named environment variables along with their values.
@@ -91,7 +110,9 @@ type (
 	// files. It will implement sane YAML marshalling and unmarshalling. (Not
 	// yet implemented.)
 	Var string
-	// VarType represents the type of a Var (not yet implemented).
+	// VarType represents the type of a Var, or of the values of an Env,
+	// Resources or Metadata field. See VarTypeString and the other VarType
+	// constants.
 	VarType string
 )
 
@@ -138,6 +159,9 @@ func (d Defs) Clone() Defs {
 	d.EnvVars = d.EnvVars.Clone()
 	d.Resources = d.Resources.Clone()
 	d.Metadata = d.Metadata.Clone()
+	d.Freezes = d.Freezes.Clone()
+	d.Pauses = d.Pauses.Clone()
+	d.TrustedKeys = d.TrustedKeys.Clone()
 	return d
 }
 
@@ -167,6 +191,16 @@ func (c Cluster) Clone() *Cluster {
 	allowedAdvisories := make([]string, len(c.AllowedAdvisories))
 	copy(allowedAdvisories, c.AllowedAdvisories)
 	c.AllowedAdvisories = allowedAdvisories
+	if c.Labels != nil {
+		labels := make(map[string]string, len(c.Labels))
+		for k, v := range c.Labels {
+			labels[k] = v
+		}
+		c.Labels = labels
+	}
+	if c.Groups != nil {
+		c.Groups = append([]string{}, c.Groups...)
+	}
 	return &c
 }
 
@@ -208,8 +242,11 @@ func (s *State) BaseURLs() []string {
 func (s *State) Validate() []Flaw {
 	var flaws []Flaw
 
+	flaws = append(flaws, s.Defs.Validate()...)
+
 	for _, m := range s.Manifests.Snapshot() {
 		flaws = append(flaws, m.Validate()...)
+		flaws = append(flaws, s.Defs.ValidateManifest(m)...)
 	}
 
 	ds, err := s.Deployments()
@@ -220,6 +257,10 @@ func (s *State) Validate() []Flaw {
 		flaws = append(flaws, depl.Validate()...)
 	}
 
+	for _, fw := range s.Defs.Freezes {
+		flaws = append(flaws, fw.Validate()...)
+	}
+
 	for _, f := range flaws {
 		f.AddContext("state", s)
 	}
@@ -1,6 +1,7 @@
 package sous
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/samsalisbury/semv"
@@ -29,11 +30,6 @@ func TestState_Validate(t *testing.T) {
 
 	mid := MustParseManifestID("github.com/user/repo")
 
-	// TODO: Expand the definition of "valid". At the time of initial writing,
-	// only the individual manifests are validated without reference to
-	// definitions in State.Defs; they should additionally be validated against
-	// these definitions.
-
 	validState := &State{
 		Manifests: NewManifestsFromMap(map[ManifestID]*Manifest{
 			mid: &Manifest{
@@ -74,3 +70,66 @@ func TestState_Validate(t *testing.T) {
 	}
 
 }
+
+func TestState_Validate_typedValues(t *testing.T) {
+	mid := MustParseManifestID("github.com/user/repo")
+	state := &State{
+		Manifests: NewManifestsFromMap(map[ManifestID]*Manifest{
+			mid: &Manifest{
+				Source: mid.Source,
+				Kind:   ManifestKindService,
+				Deployments: DeploySpecs{
+					"some-cluster": DeploySpec{
+						DeployConfig: DeployConfig{
+							Resources: Resources{
+								"cpus":   "1",
+								"memory": "1O24",
+								"ports":  "1",
+							},
+							Env: Env{
+								"TIMEOUT":  "30s",
+								"LOG_MODE": "loud",
+								"RETRIES":  "${secret:retries}",
+							},
+							NumInstances: 3,
+						},
+						Version: semv.MustParse("1"),
+					},
+				},
+			},
+		}),
+		Defs: Defs{
+			Clusters: Clusters{
+				"some-cluster": {
+					Startup: Startup{SkipCheck: true},
+					Env:     EnvDefaults{"RETRIES": "many"},
+				},
+			},
+			EnvVars: EnvDefs{
+				{Name: "TIMEOUT", Type: "Duration"},
+				{Name: "LOG_MODE", Type: "Enum(quiet|verbose)"},
+				{Name: "RETRIES", Type: "Int"},
+			},
+			Resources: FieldDefinitions{
+				{Name: "cpus", Type: "Float"},
+				{Name: "memory", Type: "MemorySize"},
+				{Name: "ports", Type: "Integer", Default: "one"},
+			},
+			Metadata: FieldDefinitions{
+				{Name: "team", Type: "Strnig"},
+			},
+		},
+	}
+
+	var got []string
+	for _, f := range state.Validate() {
+		got = append(got, fmt.Sprint(f))
+	}
+	assert.Equal(t, []string{
+		`Defs Resources "ports" default: "one" is not a valid Int: invalid syntax`,
+		`Defs Metadata "team": unknown type "Strnig"`,
+		`cluster some-cluster: Env "RETRIES": "many" is not a valid Int: invalid syntax`,
+		`manifest github.com/user/repo cluster some-cluster: Env "LOG_MODE": "loud" is not a valid Enum: not one of quiet, verbose`,
+		`manifest github.com/user/repo cluster some-cluster: Resources "memory": "1O24" is not a valid MemorySize: not a number of megabytes, e.g. 512, 512MB or 1GiB`,
+	}, got)
+}
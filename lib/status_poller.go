@@ -14,7 +14,11 @@ type (
 	StatusPoller struct {
 		restful.HTTPClient
 		*ResolveFilter
-		User            User
+		User User
+		// Streaming makes the poller follow each server's /deploy-events
+		// stream, rather than polling /status. It falls back to polling servers
+		// which don't provide the stream.
+		Streaming       bool
 		statePerCluster map[string]*pollerState
 		status          ResolveState
 		logs            logging.LogSink
@@ -152,6 +156,7 @@ func (sp *StatusPoller) subPollers(clusters *ServerListData, deps Deployments) (
 		if err != nil {
 			return nil, err
 		}
+		sub.streaming = sp.Streaming
 		subs = append(subs, sub)
 	}
 	return subs, nil
@@ -1,6 +1,8 @@
 package sous
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"time"
@@ -18,6 +20,15 @@ type (
 		User                     User
 		httpErrorCount           int
 		logs                     logging.LogSink
+		// streaming makes the subPoller follow /deploy-events where the
+		// server provides it.
+		streaming bool
+	}
+
+	// An eventStreamer can follow a stream of server-sent events;
+	// restful.LiveHTTPClient is one.
+	eventStreamer interface {
+		Stream(ctx context.Context, urlPath string, qParms map[string]string, headers map[string]string, fn func(restful.Event) error) error
 	}
 )
 
@@ -47,9 +58,17 @@ func newSubPoller(clusterName, serverURL string, baseFilter *ResolveFilter, user
 }
 
 // start issues a new /status request every half second, reporting the state as computed.
-// c.f. pollOnce.
+// c.f. pollOnce. If streaming, it follows /deploy-events instead, until that
+// stream is unavailable or ends.
 func (sub *subPoller) start(rs chan pollResult, done chan struct{}) {
 	rs <- pollResult{url: sub.URL, stat: ResolveNotPolled}
+	if sub.streaming {
+		err := sub.stream(rs, done)
+		if err == nil {
+			return
+		}
+		reportDebugSubPollerMessage(fmt.Sprintf("%s: falling back to polling: %s", sub.ClusterName, err), sub.logs)
+	}
 	pollResult := sub.pollOnce()
 	rs <- pollResult
 	ticker := time.NewTicker(PollTimeout)
@@ -65,6 +84,55 @@ func (sub *subPoller) start(rs chan pollResult, done chan struct{}) {
 	}
 }
 
+// stream reports the state computed from each status event on
+// /deploy-events, until done is closed, when it returns nil.
+func (sub *subPoller) stream(rs chan pollResult, done chan struct{}) error {
+	es, ok := sub.HTTPClient.(eventStreamer)
+	if !ok {
+		return errors.Errorf("%T can't stream events", sub.HTTPClient)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	data := &statusData{}
+	err := es.Stream(ctx, "./deploy-events", nil, sub.User.HTTPHeaders(), func(ev restful.Event) error {
+		if ev.Name != string(DeployEventStatus) {
+			return nil
+		}
+		de := DeployEvent{}
+		if err := json.Unmarshal(ev.Data, &de); err != nil {
+			return errors.Wrapf(err, "decoding status event")
+		}
+		if de.Status == nil {
+			return nil
+		}
+		// Like /status, InProgress is the latest resolve, whether it's
+		// finished or not, and Completed the latest finished one.
+		data.InProgress = de.Status
+		if !de.Status.Finished.IsZero() {
+			data.Completed = de.Status
+		}
+		select {
+		case rs <- sub.computeResult(data):
+		case <-done:
+		}
+		return nil
+	})
+	select {
+	case <-done:
+		return nil
+	default:
+		return err
+	}
+}
+
 func (sub *subPoller) result(rs ResolveState, data *statusData, err error) pollResult {
 	resolveID := "<none in progress>"
 	if data.InProgress != nil {
@@ -89,7 +157,11 @@ func (sub *subPoller) pollOnce() pollResult {
 		return sub.result(ResolveErredHTTP, data, err)
 	}
 	sub.httpErrorCount = 0
+	return sub.computeResult(data)
+}
 
+// computeResult computes the state of resolution from a status report.
+func (sub *subPoller) computeResult(data *statusData) pollResult {
 	// This serves to maintain backwards compatibility.
 	// XXX One day, remove it.
 	if data.Completed != nil && len(data.Completed.Intended) == 0 {
@@ -0,0 +1,59 @@
+package sous
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// TrustedKeys are PEM encoded ECDSA public keys ("PUBLIC KEY"), by name, that
+// images may be signed by; see ProvenanceSigner.
+type TrustedKeys map[string]string
+
+// Names returns the names of the keys in tk, sorted.
+func (tk TrustedKeys) Names() []string {
+	names := make([]string, 0, len(tk))
+	for n := range tk {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Clone returns a copy of tk.
+func (tk TrustedKeys) Clone() TrustedKeys {
+	if tk == nil {
+		return nil
+	}
+	c := make(TrustedKeys, len(tk))
+	for n, k := range tk {
+		c[n] = k
+	}
+	return c
+}
+
+// Verifier returns a ProvenanceVerifier that trusts each of the keys in tk.
+func (tk TrustedKeys) Verifier() (*ProvenanceVerifier, error) {
+	if len(tk) == 0 {
+		return nil, errors.New("no trusted keys are defined")
+	}
+	pems := []byte{}
+	for _, n := range tk.Names() {
+		if _, err := NewProvenanceVerifier([]byte(tk[n])); err != nil {
+			return nil, errors.Wrapf(err, "trusted key %q", n)
+		}
+		pems = append(pems, tk[n]...)
+		pems = append(pems, '\n')
+	}
+	return NewProvenanceVerifier(pems)
+}
+
+func (tk TrustedKeys) validate() []Flaw {
+	var flaws []Flaw
+	for _, n := range tk.Names() {
+		if _, err := NewProvenanceVerifier([]byte(tk[n])); err != nil {
+			flaws = append(flaws, FatalFlaw("Defs TrustedKeys %q: %v", n, err))
+		}
+	}
+	return flaws
+}
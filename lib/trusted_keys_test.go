@@ -0,0 +1,49 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustedKeys_Verifier(t *testing.T) {
+	key, pub := provenanceTestKey(t)
+	_, otherPub := provenanceTestKey(t)
+	signer, err := NewProvenanceSigner(key)
+	require.NoError(t, err)
+	sp, err := signer.Sign(Provenance{Image: provenanceTestImage})
+	require.NoError(t, err)
+
+	verifier, err := TrustedKeys{"ci": string(pub), "release": string(otherPub)}.Verifier()
+	require.NoError(t, err)
+	_, err = verifier.Verify(sp, provenanceTestImage)
+	assert.NoError(t, err)
+
+	_, err = TrustedKeys{}.Verifier()
+	assert.Error(t, err)
+	_, err = TrustedKeys{"ci": string(pub), "broken": "not a key"}.Verifier()
+	assert.Error(t, err)
+}
+
+func TestDefs_Validate_trustedKeys(t *testing.T) {
+	_, pub := provenanceTestKey(t)
+	defs := Defs{Clusters: Clusters{"x": {Name: "x", RequireSignedImages: true}}}
+	assert.Len(t, defs.Validate(), 1, "cluster requires signatures, but no keys")
+
+	defs.TrustedKeys = TrustedKeys{"ci": string(pub), "broken": "not a key"}
+	assert.Len(t, defs.Validate(), 1, "broken key")
+
+	delete(defs.TrustedKeys, "broken")
+	assert.Len(t, defs.Validate(), 0)
+}
+
+func TestDefs_Diff_trustedKeys(t *testing.T) {
+	_, pub := provenanceTestKey(t)
+	defs := Defs{TrustedKeys: TrustedKeys{"ci": string(pub)}}
+	other := defs.Clone()
+	assert.Empty(t, defs.Diff(&other))
+
+	other.TrustedKeys["release"] = string(pub)
+	assert.Equal(t, []string{"trusted keys extra release"}, defs.Diff(&other))
+}
@@ -8,6 +8,9 @@ type User struct {
 	Name string `env:"SOUS_USER_NAME"`
 	// Email is the email address of this user.
 	Email string `env:"SOUS_USER_EMAIL"`
+	// Token is a signed token identifying this user to a Sous server which
+	// requires authentication. See SignUserToken.
+	Token string `env:"SOUS_USER_TOKEN" json:"-" yaml:",omitempty"`
 }
 
 // String returns the name and email in standard email address format, i.e.:
@@ -33,9 +36,14 @@ func (u User) Complete() bool {
 }
 
 // HTTPHeaders returns a map suitable to use as HTTP headers to be consumed by the server.
+// If u has a Token, it is sent as a bearer token in the Authorization header.
 func (u User) HTTPHeaders() map[string]string {
-	return map[string]string{
+	hs := map[string]string{
 		"Sous-User-Name":  u.Name,
 		"Sous-User-Email": u.Email,
 	}
+	if u.Token != "" {
+		hs["Authorization"] = "Bearer " + u.Token
+	}
+	return hs
 }
@@ -2,6 +2,7 @@ package sous
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -22,3 +23,39 @@ func TestUser_Complete(t *testing.T) {
 	assert.False(t, User{Email: "y"}.Complete())
 	assert.True(t, User{Name: "x", Email: "y"}.Complete())
 }
+
+func TestUser_HTTPHeaders(t *testing.T) {
+	hs := User{Name: "x", Email: "y"}.HTTPHeaders()
+	if _, has := hs["Authorization"]; has {
+		t.Errorf("got an Authorization header without a token")
+	}
+	hs = User{Name: "x", Email: "y", Token: "t"}.HTTPHeaders()
+	assert.Equal(t, "Bearer t", hs["Authorization"])
+}
+
+func TestUserToken(t *testing.T) {
+	secret := []byte("sekrit")
+	now := time.Now()
+	claims := UserClaims{Name: "x", Email: "y", Groups: []string{"admins"}, Expires: now.Add(time.Hour)}
+	token, err := SignUserToken(secret, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := VerifyUserToken(secret, token, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "y", got.Email)
+	assert.Equal(t, []string{"admins"}, got.Groups)
+
+	if _, err := VerifyUserToken([]byte("other"), token, now); err == nil {
+		t.Errorf("verified a token signed with another secret")
+	}
+	if _, err := VerifyUserToken(secret, token, now.Add(2*time.Hour)); err == nil {
+		t.Errorf("verified an expired token")
+	}
+	if _, err := VerifyUserToken(secret, "x"+token, now); err == nil {
+		t.Errorf("verified a tampered token")
+	}
+}
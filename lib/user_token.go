@@ -0,0 +1,74 @@
+package sous
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// UserClaims are what a user token says about its bearer.
+type UserClaims struct {
+	Name   string
+	Email  string
+	Groups []string `json:",omitempty"`
+	// Expires is when the token stops being valid. The zero time means never.
+	Expires time.Time `json:",omitempty"`
+}
+
+var tokenEncoding = base64.RawURLEncoding
+
+// SignUserToken returns a token carrying claims, signed with secret. Tokens
+// are the base64 encoded JSON claims and their HMAC-SHA256, joined by a dot.
+func SignUserToken(secret []byte, claims UserClaims) (string, error) {
+	if len(secret) == 0 {
+		return "", errors.New("no token secret configured")
+	}
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return "", errors.Wrap(err, "encoding token claims")
+	}
+	payload := tokenEncoding.EncodeToString(b)
+	return payload + "." + tokenEncoding.EncodeToString(tokenMAC(secret, payload)), nil
+}
+
+// VerifyUserToken returns the claims of token, if it was signed with secret
+// and hasn't expired by now.
+func VerifyUserToken(secret []byte, token string, now time.Time) (UserClaims, error) {
+	claims := UserClaims{}
+	if len(secret) == 0 {
+		return claims, errors.New("no token secret configured")
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return claims, errors.New("malformed token")
+	}
+	sig, err := tokenEncoding.DecodeString(parts[1])
+	if err != nil {
+		return claims, errors.Wrap(err, "malformed token signature")
+	}
+	if !hmac.Equal(sig, tokenMAC(secret, parts[0])) {
+		return claims, errors.New("bad token signature")
+	}
+	b, err := tokenEncoding.DecodeString(parts[0])
+	if err != nil {
+		return claims, errors.Wrap(err, "malformed token claims")
+	}
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return claims, errors.Wrap(err, "malformed token claims")
+	}
+	if !claims.Expires.IsZero() && now.After(claims.Expires) {
+		return claims, errors.Errorf("token expired at %s", claims.Expires.Format(time.RFC3339))
+	}
+	return claims, nil
+}
+
+func tokenMAC(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,174 @@
+package sous
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The VarTypes values can be checked against. Type names are not case
+// sensitive, and ignore underscores and dashes, so e.g. "memory_size" is a
+// MemorySize. An empty VarType is a String.
+const (
+	// VarTypeString is any string.
+	VarTypeString = VarType("String")
+	// VarTypeInt is a decimal integer, e.g. 3 or -1. Also "Integer".
+	VarTypeInt = VarType("Int")
+	// VarTypeFloat is a decimal number, e.g. 0.5. Also "Number".
+	VarTypeFloat = VarType("Float")
+	// VarTypeMemorySize is a number of megabytes, optionally with a unit:
+	// K, M, G or T, with or without a trailing B or iB, e.g. 512, 512MB or
+	// 1GiB. Units are powers of 1024.
+	VarTypeMemorySize = VarType("MemorySize")
+	// VarTypeDuration is a Go duration, e.g. 1m30s.
+	VarTypeDuration = VarType("Duration")
+	// VarTypeBool is true or false, as accepted by strconv.ParseBool.
+	VarTypeBool = VarType("Bool")
+	// VarTypeURL is an absolute URL, e.g. https://example.com/path.
+	VarTypeURL = VarType("URL")
+	// VarTypeEnum is one of a list of values, written Enum(a|b|c).
+	VarTypeEnum = VarType("Enum")
+)
+
+var (
+	enumVarType = regexp.MustCompile(`(?i)^enum\((.*)\)$`)
+
+	memorySizeUnits = map[string]float64{
+		"":  1,
+		"k": 1.0 / 1024,
+		"m": 1,
+		"g": 1024,
+		"t": 1024 * 1024,
+	}
+	memorySize = regexp.MustCompile(`^\s*([0-9]*\.?[0-9]+)\s*(?i:([kmgt])(?:i?b)?)?\s*$`)
+)
+
+// Kind returns the VarType constant vt is one of, and the values of an Enum.
+func (vt VarType) Kind() (VarType, []string, error) {
+	if m := enumVarType.FindStringSubmatch(string(vt)); m != nil {
+		values := strings.Split(m[1], "|")
+		for i, v := range values {
+			values[i] = strings.TrimSpace(v)
+		}
+		return VarTypeEnum, values, nil
+	}
+	switch strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(string(vt))) {
+	default:
+		return "", nil, errors.Errorf("unknown type %q", string(vt))
+	case "", "string":
+		return VarTypeString, nil, nil
+	case "int", "integer":
+		return VarTypeInt, nil, nil
+	case "float", "number":
+		return VarTypeFloat, nil, nil
+	case "memorysize":
+		return VarTypeMemorySize, nil, nil
+	case "duration":
+		return VarTypeDuration, nil, nil
+	case "bool", "boolean":
+		return VarTypeBool, nil, nil
+	case "url":
+		return VarTypeURL, nil, nil
+	}
+}
+
+// Check returns an error unless value is a valid vt.
+func (vt VarType) Check(value string) error {
+	kind, values, err := vt.Kind()
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case VarTypeInt:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case VarTypeFloat:
+		_, err = strconv.ParseFloat(value, 64)
+	case VarTypeMemorySize:
+		_, err = ParseMemorySize(value)
+	case VarTypeDuration:
+		_, err = time.ParseDuration(value)
+	case VarTypeBool:
+		_, err = strconv.ParseBool(value)
+	case VarTypeURL:
+		var u *url.URL
+		u, err = url.Parse(value)
+		if err == nil && (u.Scheme == "" || u.Host == "") {
+			err = errors.New("not an absolute URL")
+		}
+	case VarTypeEnum:
+		for _, v := range values {
+			if v == value {
+				return nil
+			}
+		}
+		sorted := append([]string{}, values...)
+		sort.Strings(sorted)
+		err = errors.Errorf("not one of %s", strings.Join(sorted, ", "))
+	}
+	if err != nil {
+		if ne, is := err.(*strconv.NumError); is {
+			err = ne.Err
+		}
+		return errors.Errorf("%q is not a valid %s: %v", value, kind, err)
+	}
+	return nil
+}
+
+// ParseMemorySize parses a MemorySize value, and returns its size in
+// megabytes.
+func ParseMemorySize(value string) (float64, error) {
+	m := memorySize.FindStringSubmatch(value)
+	if m == nil {
+		return 0, errors.New("not a number of megabytes, e.g. 512, 512MB or 1GiB")
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, err
+	}
+	return n * memorySizeUnits[strings.ToLower(m[2])], nil
+}
+
+// An InvalidValueFlaw is a value in a manifest or cluster that isn't of the
+// type defined for it in Defs.
+type InvalidValueFlaw struct {
+	// ManifestID and ClusterName locate the value. ManifestID is empty for
+	// the Env defaults of a cluster.
+	ManifestID  string
+	ClusterName string
+	// Field is "Env", "Resources" or "Metadata", and Key names the value.
+	Field, Key string
+	Err        error
+}
+
+// AddContext implements Flaw.AddContext.
+func (f *InvalidValueFlaw) AddContext(name string, i interface{}) {
+	switch name {
+	case "manifest":
+		if m, is := i.(*Manifest); is && f.ManifestID == "" {
+			f.ManifestID = m.ID().String()
+		}
+	case "cluster":
+		if cn, is := i.(string); is && f.ClusterName == "" {
+			f.ClusterName = cn
+		}
+	}
+}
+
+// Repair implements Flaw.Repair; an InvalidValueFlaw can't be repaired.
+func (f *InvalidValueFlaw) Repair() error {
+	return errors.Errorf("%s: cannot be repaired.", f)
+}
+
+func (f *InvalidValueFlaw) String() string {
+	where := "cluster " + f.ClusterName
+	if f.ManifestID != "" {
+		where = fmt.Sprintf("manifest %s %s", f.ManifestID, where)
+	}
+	return fmt.Sprintf("%s: %s %q: %v", where, f.Field, f.Key, f.Err)
+}
@@ -0,0 +1,58 @@
+package sous
+
+import "testing"
+
+func TestVarType_Check(t *testing.T) {
+	for _, c := range []struct {
+		Type  VarType
+		Value string
+		Valid bool
+	}{
+		{"", "anything", true},
+		{"string", "", true},
+		{"Int", "-3", true},
+		{"integer", "3.5", false},
+		{"Float", "0.5", true},
+		{"float", "O.5", false},
+		{"MemorySize", "512", true},
+		{"memory_size", "512MB", true},
+		{"MemorySize", "1GiB", true},
+		{"MemorySize", "1O24", false},
+		{"MemorySize", "1B", false},
+		{"Duration", "1m30s", true},
+		{"Duration", "90", false},
+		{"Bool", "true", true},
+		{"boolean", "yes", false},
+		{"URL", "https://example.com/path", true},
+		{"URL", "example.com/path", false},
+		{"Enum(a|b|c)", "b", true},
+		{"enum(a | b)", "b", true},
+		{"Enum(a|b|c)", "d", false},
+		{"Strnig", "anything", false},
+	} {
+		err := c.Type.Check(c.Value)
+		if (err == nil) != c.Valid {
+			t.Errorf("%s.Check(%q): got %v; want valid: %t", c.Type, c.Value, err, c.Valid)
+		}
+	}
+}
+
+func TestParseMemorySize(t *testing.T) {
+	for value, want := range map[string]float64{
+		"100":    100,
+		"512MB":  512,
+		"1GiB":   1024,
+		"1.5g":   1536,
+		"2048K":  2,
+		"1 TB":   1024 * 1024,
+		"0.25 M": 0.25,
+	} {
+		got, err := ParseMemorySize(value)
+		if err != nil || got != want {
+			t.Errorf("ParseMemorySize(%q): got %v, %v; want %v", value, got, err, want)
+		}
+	}
+	if r := (Resources{"memory": "1GiB"}); r.Memory() != 1024 {
+		t.Errorf("Resources.Memory(): got %v; want 1024", r.Memory())
+	}
+}
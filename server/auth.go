@@ -0,0 +1,312 @@
+package server
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/opentable/sous/config"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+)
+
+type (
+	// An Authorizer authenticates the callers of the server's write endpoints,
+	// and decides whether they may change the manifests they are writing.
+	// Callers are authenticated by a user token signed with the server's
+	// secret, or a client certificate issued by one of its client CAs.
+	//
+	// The nil *Authorizer trusts the Sous-User-* headers of each request, and
+	// lets anyone change anything.
+	Authorizer struct {
+		secret     []byte
+		clientCAs  *x509.CertPool
+		adminGroup string
+		log        logging.LogSink
+	}
+
+	// A Caller is a user of the server, and the groups they belong to.
+	Caller struct {
+		sous.User
+		Groups []string
+		// Method is how the caller was authenticated: "token",
+		// "certificate", or "header" if the server doesn't authenticate.
+		Method string
+	}
+
+	// An AuthError explains why a request was refused. Status is 401 if the
+	// caller couldn't be authenticated, and 403 if they aren't allowed to
+	// make the change they asked for.
+	AuthError struct {
+		Status int
+		Reason string
+	}
+
+	// authorization is the result of authenticating a request, which
+	// handlers use to authorize the changes it makes.
+	authorization struct {
+		authorizer *Authorizer
+		Caller     Caller
+		err        *AuthError
+	}
+
+	authDenialMessage struct {
+		logging.CallerInfo
+		caller     Caller
+		manifestID string
+		*AuthError
+	}
+)
+
+// NewAuthorizer returns an Authorizer configured by ac, or nil if ac doesn't
+// enable authentication.
+func NewAuthorizer(ac config.AuthConfig, ls logging.LogSink) (*Authorizer, error) {
+	if !ac.Enabled() {
+		return nil, nil
+	}
+	a := &Authorizer{
+		secret:     []byte(ac.TokenSecret),
+		adminGroup: ac.AdminGroup,
+		log:        ls,
+	}
+	if ac.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(ac.ClientCAFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading client CAs")
+		}
+		a.clientCAs = x509.NewCertPool()
+		if !a.clientCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificates found in %s", ac.ClientCAFile)
+		}
+	}
+	return a, nil
+}
+
+func (e *AuthError) Error() string {
+	return e.Reason
+}
+
+// Authenticate returns the Caller making req. A client certificate takes
+// precedence over a bearer token.
+func (a *Authorizer) Authenticate(req *http.Request) (Caller, error) {
+	if a == nil {
+		return Caller{User: sous.User(userExtractor{}.GetUser(req)), Method: "header"}, nil
+	}
+	if req.TLS != nil && len(req.TLS.PeerCertificates) > 0 && a.clientCAs != nil {
+		return a.authenticateCertificate(req.TLS.PeerCertificates)
+	}
+	auth := req.Header.Get("Authorization")
+	if strings.HasPrefix(auth, "Bearer ") && len(a.secret) > 0 {
+		claims, err := sous.VerifyUserToken(a.secret, strings.TrimPrefix(auth, "Bearer "), time.Now())
+		if err != nil {
+			return Caller{}, errors.Wrap(err, "invalid user token")
+		}
+		return Caller{
+			User:   sous.User{Name: claims.Name, Email: claims.Email},
+			Groups: claims.Groups,
+			Method: "token",
+		}, nil
+	}
+	return Caller{}, errors.New("no user token or client certificate presented")
+}
+
+func (a *Authorizer) authenticateCertificate(chain []*x509.Certificate) (Caller, error) {
+	leaf := chain[0]
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         a.clientCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return Caller{}, errors.Wrap(err, "invalid client certificate")
+	}
+	caller := Caller{
+		User:   sous.User{Name: leaf.Subject.CommonName},
+		Groups: leaf.Subject.OrganizationalUnit,
+		Method: "certificate",
+	}
+	if len(leaf.EmailAddresses) > 0 {
+		caller.Email = leaf.EmailAddresses[0]
+	}
+	return caller, nil
+}
+
+// Admin returns true if caller is in the admin group.
+func (a *Authorizer) Admin(caller Caller) bool {
+	if a == nil || a.adminGroup == "" {
+		return false
+	}
+	for _, g := range caller.Groups {
+		if g == a.adminGroup {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthorizeManifest returns an AuthError unless caller may change the
+// manifest with ID mid, given prior, the manifest as it is now. Admins may
+// change any manifest, and anyone may change a manifest that doesn't exist
+// yet or has no owners; otherwise the caller must be one of its owners.
+func (a *Authorizer) AuthorizeManifest(caller Caller, mid sous.ManifestID, prior *sous.Manifest) *AuthError {
+	if a == nil || a.Admin(caller) || prior == nil || len(prior.Owners) == 0 {
+		return nil
+	}
+	for _, owner := range prior.Owners {
+		if owner == "" {
+			continue
+		}
+		if strings.EqualFold(owner, caller.Email) || strings.EqualFold(owner, caller.Name) {
+			return nil
+		}
+	}
+	return a.deny(caller, mid.String(), &AuthError{
+		Status: http.StatusForbidden,
+		Reason: fmt.Sprintf("%s is not an owner of %s (owners: %s)", caller.User, mid, strings.Join(prior.Owners, ", ")),
+	})
+}
+
+// AuthorizeAdmin returns an AuthError unless caller is in the admin group.
+func (a *Authorizer) AuthorizeAdmin(caller Caller) *AuthError {
+	if a == nil || a.Admin(caller) {
+		return nil
+	}
+	return a.deny(caller, "", &AuthError{
+		Status: http.StatusForbidden,
+		Reason: fmt.Sprintf("%s is not a Sous admin", caller.User),
+	})
+}
+
+func (a *Authorizer) deny(caller Caller, mid string, ae *AuthError) *AuthError {
+	msg := authDenialMessage{
+		CallerInfo: logging.GetCallerInfo(logging.NotHere()),
+		caller:     caller,
+		manifestID: mid,
+		AuthError:  ae,
+	}
+	// Report the handler that refused the request.
+	msg.CallerInfo.ExcludePathPattern("sous/server/auth.go")
+	logging.Deliver(a.log, msg)
+	return ae
+}
+
+// authenticate authenticates the caller of req.
+func (ctx ComponentLocator) authenticate(req *http.Request) authorization {
+	az := authorization{authorizer: ctx.Authorizer}
+	caller, err := ctx.Authorizer.Authenticate(req)
+	if err != nil {
+		az.err = ctx.Authorizer.deny(caller, "", &AuthError{
+			Status: http.StatusUnauthorized,
+			Reason: "authentication required: " + err.Error(),
+		})
+	}
+	az.Caller = caller
+	return az
+}
+
+// User returns the authenticated user, to record changes against.
+func (az authorization) User() ClientUser {
+	return ClientUser(az.Caller.User)
+}
+
+// Manifest authorizes changes to the manifest with ID mid, given prior, the
+// manifest as it is now, or nil if it doesn't exist.
+func (az authorization) Manifest(mid sous.ManifestID, prior *sous.Manifest) *AuthError {
+	if az.err != nil {
+		return az.err
+	}
+	return az.authorizer.AuthorizeManifest(az.Caller, mid, prior)
+}
+
+// Manifests authorizes every change from prior to after.
+func (az authorization) Manifests(prior, after sous.Manifests) *AuthError {
+	if az.err != nil {
+		return az.err
+	}
+	for mid, m := range after.Snapshot() {
+		old, existed := prior.Get(mid)
+		if existed && old.Equal(m) {
+			continue
+		}
+		if err := az.Manifest(mid, old); err != nil {
+			return err
+		}
+	}
+	for mid, old := range prior.Snapshot() {
+		if _, kept := after.Get(mid); !kept {
+			if err := az.Manifest(mid, old); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Deployments authorizes every change from prior to after, the deployments
+// to a cluster, against the owners of each deployment changed.
+func (az authorization) Deployments(prior, after sous.Deployments) *AuthError {
+	if az.err != nil {
+		return az.err
+	}
+	owners := func(d *sous.Deployment) *sous.Manifest {
+		return &sous.Manifest{Owners: d.Owners.Slice()}
+	}
+	for did, d := range after.Snapshot() {
+		old, existed := prior.Get(did)
+		if !existed {
+			if err := az.Manifest(did.ManifestID, nil); err != nil {
+				return err
+			}
+			continue
+		}
+		if old.Equal(d) {
+			continue
+		}
+		if err := az.Manifest(did.ManifestID, owners(old)); err != nil {
+			return err
+		}
+	}
+	for did, old := range prior.Snapshot() {
+		if _, kept := after.Get(did); !kept {
+			if err := az.Manifest(did.ManifestID, owners(old)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Admin authorizes changes only admins may make.
+func (az authorization) Admin() *AuthError {
+	if az.err != nil {
+		return az.err
+	}
+	return az.authorizer.AuthorizeAdmin(az.Caller)
+}
+
+func (msg authDenialMessage) DefaultLevel() logging.Level {
+	return logging.WarningLevel
+}
+
+func (msg authDenialMessage) Message() string {
+	return "Request refused: " + msg.Reason
+}
+
+func (msg authDenialMessage) EachField(f logging.FieldReportFn) {
+	f("@loglov3-otl", logging.SousGenericV1)
+	f("sous-auth-user", msg.caller.User.String())
+	f("sous-auth-method", msg.caller.Method)
+	f("sous-auth-status", msg.Status)
+	if msg.manifestID != "" {
+		f(logging.SousManifestId, msg.manifestID)
+	}
+	msg.CallerInfo.EachField(f)
+}
@@ -0,0 +1,278 @@
+package server
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/opentable/sous/config"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+)
+
+func testAuthorizer(t *testing.T, ac config.AuthConfig) *Authorizer {
+	t.Helper()
+	if ac.TokenSecret == "" {
+		ac.TokenSecret = "sekrit"
+	}
+	if ac.AdminGroup == "" {
+		ac.AdminGroup = "admins"
+	}
+	a, err := NewAuthorizer(ac, logging.SilentLogSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func ownedManifest(owners ...string) *sous.Manifest {
+	return &sous.Manifest{
+		Source: sous.SourceLocation{Repo: "gh"},
+		Kind:   sous.ManifestKindService,
+		Owners: owners,
+	}
+}
+
+func TestNewAuthorizer_disabled(t *testing.T) {
+	a, err := NewAuthorizer(config.AuthConfig{AdminGroup: "admins"}, logging.SilentLogSet())
+	if err != nil || a != nil {
+		t.Fatalf("got %v, %v; want no authorizer", a, err)
+	}
+
+	req, _ := http.NewRequest("PUT", "/manifest", nil)
+	req.Header.Set("Sous-User-Email", "someone@example.com")
+	caller, err := a.Authenticate(req)
+	if err != nil || caller.Email != "someone@example.com" || caller.Method != "header" {
+		t.Errorf("got %#v, %v; want the user from the headers", caller, err)
+	}
+	if err := a.AuthorizeManifest(caller, ownedManifest().ID(), ownedManifest("else@example.com")); err != nil {
+		t.Errorf("nil authorizer refused: %v", err)
+	}
+}
+
+func TestAuthorizer_Authenticate_token(t *testing.T) {
+	a := testAuthorizer(t, config.AuthConfig{})
+	token, err := sous.SignUserToken([]byte("sekrit"), sous.UserClaims{Email: "sam@example.com", Groups: []string{"admins"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("PUT", "/manifest", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Sous-User-Email", "judson@example.com")
+	caller, err := a.Authenticate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caller.Email != "sam@example.com" || caller.Method != "token" || !a.Admin(caller) {
+		t.Errorf("got %#v; want the admin named by the token", caller)
+	}
+
+	req.Header.Set("Authorization", "Bearer x"+token)
+	if _, err := a.Authenticate(req); err == nil {
+		t.Errorf("authenticated a tampered token")
+	}
+	req.Header.Del("Authorization")
+	if _, err := a.Authenticate(req); err == nil {
+		t.Errorf("authenticated from headers alone")
+	}
+}
+
+func testCertificate(t *testing.T, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestAuthorizer_Authenticate_certificate(t *testing.T) {
+	ca, caKey := testCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Sous CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	client, _ := testCertificate(t, &x509.Certificate{
+		SerialNumber:   big.NewInt(2),
+		Subject:        pkix.Name{CommonName: "Sam", OrganizationalUnit: []string{"admins"}},
+		EmailAddresses: []string{"sam@example.com"},
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+	stranger, _ := testCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "Stranger"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, nil, nil)
+
+	caFile, err := ioutil.TempFile("", "sous-client-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(caFile.Name())
+	pem.Encode(caFile, &pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})
+	caFile.Close()
+
+	a := testAuthorizer(t, config.AuthConfig{ClientCAFile: caFile.Name()})
+
+	req, _ := http.NewRequest("PUT", "/manifest", nil)
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{client}}
+	caller, err := a.Authenticate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caller.Name != "Sam" || caller.Email != "sam@example.com" || caller.Method != "certificate" || !a.Admin(caller) {
+		t.Errorf("got %#v; want the admin named by the certificate", caller)
+	}
+
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{stranger}}
+	if _, err := a.Authenticate(req); err == nil {
+		t.Errorf("authenticated a certificate from an unknown CA")
+	}
+}
+
+func TestAuthorizer_AuthorizeManifest(t *testing.T) {
+	a := testAuthorizer(t, config.AuthConfig{})
+	mid := ownedManifest().ID()
+	owner := Caller{User: sous.User{Email: "Sam@Example.com"}}
+	other := Caller{User: sous.User{Email: "judson@example.com"}}
+	admin := Caller{User: sous.User{Email: "judson@example.com"}, Groups: []string{"admins"}}
+	prior := ownedManifest("sam@example.com")
+
+	if err := a.AuthorizeManifest(owner, mid, prior); err != nil {
+		t.Errorf("owner refused: %v", err)
+	}
+	if err := a.AuthorizeManifest(admin, mid, prior); err != nil {
+		t.Errorf("admin refused: %v", err)
+	}
+	if err := a.AuthorizeManifest(other, mid, nil); err != nil {
+		t.Errorf("refused creating a manifest: %v", err)
+	}
+	if err := a.AuthorizeManifest(other, mid, ownedManifest()); err != nil {
+		t.Errorf("refused changing an unowned manifest: %v", err)
+	}
+	err := a.AuthorizeManifest(other, mid, prior)
+	if err == nil || err.Status != http.StatusForbidden {
+		t.Fatalf("got %v; want non-owner refused", err)
+	}
+	if err := a.AuthorizeAdmin(other); err == nil {
+		t.Errorf("non-admin authorized as admin")
+	}
+}
+
+func TestAuthorizer_deniedMessage(t *testing.T) {
+	logging.AssertReportFields(t,
+		func(ls logging.LogSink) {
+			a := testAuthorizer(t, config.AuthConfig{})
+			a.log = ls
+			a.AuthorizeManifest(
+				Caller{User: sous.User{Email: "judson@example.com"}, Method: "token"},
+				ownedManifest().ID(),
+				ownedManifest("sam@example.com"))
+		},
+		logging.StandardVariableFields,
+		map[string]interface{}{
+			"@loglov3-otl":       logging.SousGenericV1,
+			"severity":           logging.WarningLevel,
+			"call-stack-message": "Request refused: <judson@example.com> is not an owner of gh (owners: sam@example.com)",
+			"sous-auth-user":     "<judson@example.com>",
+			"sous-auth-method":   "token",
+			"sous-auth-status":   http.StatusForbidden,
+			"sous-manifest-id":   "gh",
+		})
+}
+
+func TestHandlesManifestPut_denied(t *testing.T) {
+	q, _ := url.ParseQuery("repo=gh")
+	state := sous.NewState()
+	state.Manifests.Add(ownedManifest("sam@example.com"))
+	writer := &sous.DummyStateManager{State: state}
+
+	buf := &bytes.Buffer{}
+	json.NewEncoder(buf).Encode(ownedManifest("judson@example.com"))
+	req, _ := http.NewRequest("PUT", "", buf)
+
+	th := &PUTManifestHandler{
+		Request:     req,
+		StateWriter: writer,
+		State:       state,
+		QueryValues: restful.QueryValues{Values: q},
+		LogSink:     logging.SilentLogSet(),
+		auth: authorization{
+			authorizer: testAuthorizer(t, config.AuthConfig{}),
+			Caller:     Caller{User: sous.User{Email: "judson@example.com"}},
+		},
+	}
+
+	_, status := th.Exchange()
+	if status != http.StatusForbidden {
+		t.Errorf("got status %d; want 403", status)
+	}
+	if m, _ := state.Manifests.Get(ownedManifest().ID()); m.Owners[0] != "sam@example.com" {
+		t.Errorf("manifest changed to %#v", m)
+	}
+}
+
+func TestStateDefPut_unauthenticated(t *testing.T) {
+	a := testAuthorizer(t, config.AuthConfig{})
+	req, _ := http.NewRequest("PUT", "/defs", bytes.NewBufferString("{}"))
+	ctx := ComponentLocator{Authorizer: a}
+
+	th := &StateDefPutHandler{req: req, auth: ctx.authenticate(req)}
+	_, status := th.Exchange()
+	if status != http.StatusUnauthorized {
+		t.Errorf("got status %d; want 401", status)
+	}
+}
+
+func TestAuthorization_Manifests(t *testing.T) {
+	prior := sous.NewManifests(ownedManifest("sam@example.com"))
+	az := authorization{
+		authorizer: testAuthorizer(t, config.AuthConfig{}),
+		Caller:     Caller{User: sous.User{Email: "judson@example.com"}},
+	}
+
+	if err := az.Manifests(prior, prior.Clone()); err != nil {
+		t.Errorf("refused leaving others' manifests alone: %v", err)
+	}
+
+	changed := prior.Clone()
+	m, _ := changed.Get(ownedManifest().ID())
+	m.Owners = append(m.Owners, "judson@example.com")
+	if err := az.Manifests(prior, changed); err == nil {
+		t.Errorf("authorized changing another's manifest")
+	}
+
+	if err := az.Manifests(prior, sous.NewManifests()); err == nil {
+		t.Errorf("authorized removing another's manifest")
+	}
+}
@@ -92,6 +92,9 @@ func (b SingleDeploymentBody) AddHeaders(headers http.Header) {
 	if ok {
 		headers.Add("Location", queuedURL)
 	}
+	if scheduledURL, ok := b.Meta.Links["scheduledDeploy"]; ok {
+		headers.Add("Location", scheduledURL)
+	}
 }
 
 // EmptyReceiver implements Comparable on SingleDeploymentBody
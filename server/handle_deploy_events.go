@@ -0,0 +1,109 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+)
+
+type (
+	// DeployEventsResource provides for the /deploy-events resource.
+	DeployEventsResource struct {
+		context ComponentLocator
+	}
+
+	// GETDeployEventsHandler handles GET exchanges for /deploy-events.
+	GETDeployEventsHandler struct {
+		Events       *sous.DeployEvents
+		AutoResolver *sous.AutoResolver
+		Filter       *sous.ResolveFilter
+	}
+
+	// deployEventStream streams DeployEvents to a client.
+	deployEventStream struct {
+		*GETDeployEventsHandler
+		events <-chan sous.DeployEvent
+		cancel func()
+	}
+)
+
+// deployEventsKeepalive is how often an idle stream is sent a comment, to
+// stop proxies timing it out.
+var deployEventsKeepalive = 15 * time.Second
+
+func newDeployEventsResource(ctx ComponentLocator) *DeployEventsResource {
+	return &DeployEventsResource{context: ctx}
+}
+
+// Get returns a configured GETDeployEventsHandler.
+func (r *DeployEventsResource) Get(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &GETDeployEventsHandler{
+		Events:       r.context.DeployEvents,
+		AutoResolver: r.context.AutoResolver,
+		Filter:       r.context.selectClusters(historyFilterFromValues(req.URL.Query())),
+	}
+}
+
+// Exchange returns a stream of server-sent events: status events for each
+// update to the server's resolve cycle, and phase and resolution events for
+// the rectifications matching the repo, offset, flavor and cluster requested.
+func (h *GETDeployEventsHandler) Exchange() (interface{}, int) {
+	if h.Events == nil {
+		return "This server doesn't publish deploy events.", http.StatusNotFound
+	}
+	events, cancel := h.Events.Subscribe()
+	return &deployEventStream{GETDeployEventsHandler: h, events: events, cancel: cancel}, http.StatusOK
+}
+
+// Stream implements restful.Streamer on deployEventStream. The stream starts
+// with the latest completed and in-progress statuses, as served by /status.
+func (s *deployEventStream) Stream(ew *restful.EventWriter, done <-chan struct{}) {
+	defer s.cancel()
+
+	if s.AutoResolver != nil {
+		stable, live := s.AutoResolver.Statuses()
+		for _, st := range []*sous.ResolveStatus{stable, live} {
+			if st == nil {
+				continue
+			}
+			if err := ew.WriteEvent(string(sous.DeployEventStatus), sous.DeployEvent{Kind: sous.DeployEventStatus, At: time.Now(), Status: st}); err != nil {
+				return
+			}
+		}
+	}
+
+	keepalive := time.NewTicker(deployEventsKeepalive)
+	defer keepalive.Stop()
+	for {
+		var err error
+		select {
+		case <-done:
+			return
+		case <-keepalive.C:
+			err = ew.WriteComment("keepalive")
+		case ev, ok := <-s.events:
+			if !ok {
+				return
+			}
+			if !s.matches(ev) {
+				continue
+			}
+			err = ew.WriteEvent(string(ev.Kind), ev)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *deployEventStream) matches(ev sous.DeployEvent) bool {
+	if ev.DeploymentID == nil || s.Filter == nil {
+		return true
+	}
+	return s.Filter.FilterManifestID(ev.DeploymentID.ManifestID) &&
+		s.Filter.FilterClusterName(ev.DeploymentID.Cluster)
+}
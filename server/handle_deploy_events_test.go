@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+)
+
+func TestGETDeployEventsHandler_notPublished(t *testing.T) {
+	_, status := (&GETDeployEventsHandler{}).Exchange()
+	if status != http.StatusNotFound {
+		t.Errorf("got status %d; want 404", status)
+	}
+}
+
+func TestDeployEventsResource_stream(t *testing.T) {
+	de := sous.NewDeployEvents()
+	rm := restful.BuildRouteMap(func(re restful.RouteEntryBuilder) {
+		re("deploy-events", "/deploy-events", newDeployEventsResource(ComponentLocator{DeployEvents: de}))
+	})
+	srv := httptest.NewServer(rm.BuildRouter(logging.SilentLogSet()))
+	defer srv.Close()
+
+	cl, err := restful.NewClient(srv.URL, logging.SilentLogSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wanted := sous.MustParseManifestID("github.com/user1/repo1,dir1~flavor1")
+	other := sous.MustParseManifestID("github.com/user2/repo2")
+	go func() {
+		// Publish until the client has subscribed and received the event.
+		for i := 0; i < 100; i++ {
+			for _, mid := range []sous.ManifestID{other, wanted} {
+				did := sous.DeploymentID{ManifestID: mid, Cluster: "cluster1"}
+				de.Publish(sous.DeployEvent{Kind: sous.DeployEventPhase, DeploymentID: &did, Phase: sous.R11nStarted})
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var got sous.DeployEvent
+	err = cl.Stream(ctx, "./deploy-events", map[string]string{"repo": "github.com/user1/repo1"}, nil, func(ev restful.Event) error {
+		if err := json.Unmarshal(ev.Data, &got); err != nil {
+			t.Fatal(err)
+		}
+		cancel()
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("got error %v", err)
+	}
+	if got.DeploymentID == nil || got.DeploymentID.ManifestID != wanted || got.Phase != sous.R11nStarted {
+		t.Errorf("got %#v; want a phase event for %s", got, wanted)
+	}
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+)
+
+type (
+	// A DriftResource provides for the /drift resource.
+	DriftResource struct {
+		context ComponentLocator
+	}
+
+	// GETDriftHandler handles GET exchanges for /drift.
+	GETDriftHandler struct {
+		Drift  *sous.DriftDetector
+		Filter *sous.ResolveFilter
+	}
+)
+
+func newDriftResource(ctx ComponentLocator) *DriftResource {
+	return &DriftResource{context: ctx}
+}
+
+// Get returns a configured GETDriftHandler.
+func (r *DriftResource) Get(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	var drift *sous.DriftDetector
+	if r.context.AutoResolver != nil && r.context.AutoResolver.Resolver != nil {
+		drift = r.context.AutoResolver.Resolver.Drift
+	}
+	return &GETDriftHandler{
+		Drift:  drift,
+		Filter: r.context.selectClusters(historyFilterFromValues(req.URL.Query())),
+	}
+}
+
+// Exchange returns a dto.DriftResponse with the drift of the deployments
+// matching the request.
+func (h *GETDriftHandler) Exchange() (interface{}, int) {
+	drifts := []sous.DriftRecord{}
+	for _, d := range h.Drift.Drifts() {
+		if h.Filter.FilterManifestID(d.DeploymentID.ManifestID) && h.Filter.FilterClusterName(d.DeploymentID.Cluster) {
+			drifts = append(drifts, d)
+		}
+	}
+	return dto.DriftResponse{Drifts: drifts}, http.StatusOK
+}
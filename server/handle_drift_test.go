@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+)
+
+func TestDriftResource_Get(t *testing.T) {
+	drift := sous.NewDriftDetector(logging.SilentLogSet())
+	rez := &sous.Resolver{Drift: drift}
+	c := ComponentLocator{AutoResolver: &sous.AutoResolver{Resolver: rez}}
+	ls, _ := logging.NewLogSinkSpy()
+
+	req := makeRequestWithQuery(t, "repo=github.com%2Fuser1%2Frepo1")
+	got := newDriftResource(c).Get(routemap(c), ls, nil, req, nil).(*GETDriftHandler)
+
+	if got.Drift != drift {
+		t.Errorf("got different drift detector")
+	}
+	if repo := got.Filter.Repo.ValueOr("*"); repo != "github.com/user1/repo1" {
+		t.Errorf("got repo %q; want %q", repo, "github.com/user1/repo1")
+	}
+}
+
+func TestGETDriftHandler_Exchange(t *testing.T) {
+	drift := sous.NewDriftDetector(logging.SilentLogSet())
+	deployment := func(repo string, instances int) *sous.Deployment {
+		return &sous.Deployment{
+			ClusterName:  "cluster-1",
+			SourceID:     sous.MustParseSourceID(repo + ",1.0.0"),
+			DeployConfig: sous.DeployConfig{NumInstances: instances},
+		}
+	}
+	running := func(ds ...*sous.Deployment) sous.DeployStates {
+		states := sous.NewDeployStates()
+		for _, d := range ds {
+			states.Add(&sous.DeployState{Deployment: *d, Status: sous.DeployStatusActive})
+		}
+		return states
+	}
+	intended := sous.NewDeployments(deployment("one", 1), deployment("two", 1))
+	drift.Detect(running(deployment("one", 1), deployment("two", 1)), intended)
+	drift.Detect(running(deployment("one", 3), deployment("two", 3)), intended)
+
+	dh := &GETDriftHandler{
+		Drift:  drift,
+		Filter: &sous.ResolveFilter{Repo: sous.NewResolveFieldMatcher("two")},
+	}
+	body, status := dh.Exchange()
+	if status != http.StatusOK {
+		t.Fatalf("got status %d; want %d", status, http.StatusOK)
+	}
+	drifts := body.(dto.DriftResponse).Drifts
+	if len(drifts) != 1 {
+		t.Fatalf("got %d drifts; want 1", len(drifts))
+	}
+	if repo := drifts[0].DeploymentID.ManifestID.Source.Repo; repo != "two" {
+		t.Errorf("got drift of %q; want %q", repo, "two")
+	}
+	if n := drifts[0].Running.NumInstances; n != 3 {
+		t.Errorf("got %d running instances; want 3", n)
+	}
+}
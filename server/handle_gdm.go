@@ -16,7 +16,6 @@ import (
 type (
 	// GDMResource is the resource for the GDM
 	GDMResource struct {
-		userExtractor
 		context ComponentLocator
 	}
 
@@ -33,6 +32,7 @@ type (
 		//GDM          *sous.State
 		StateManager sous.StateManager
 		User         ClientUser
+		auth         authorization
 	}
 )
 
@@ -74,12 +74,14 @@ func (h *GETGDMHandler) Exchange() (interface{}, int) {
 
 // Put implements Putable on GDMResource
 func (gr *GDMResource) Put(_ *restful.RouteMap, ls logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	auth := gr.context.authenticate(req)
 	return &PUTGDMHandler{
 		Request: req,
 		LogSink: ls,
 		//GDM:          gr.context.liveState(),
 		StateManager: gr.context.StateManager,
-		User:         gr.GetUser(req),
+		User:         auth.User(),
+		auth:         auth,
 	}
 }
 
@@ -101,6 +103,7 @@ func (h *PUTGDMHandler) Exchange() (interface{}, int) {
 
 	reportDebugHandleGDMMessage(fmt.Sprintf("Put GDM Handler Exchange with Server State: %v", state), nil, nil, h.LogSink)
 
+	prior := state.Manifests.Clone()
 	state.Manifests, err = deps.PutbackManifests(state.Defs, state.Manifests, h.LogSink)
 	if err != nil {
 		msg := "Error getting state"
@@ -108,6 +111,10 @@ func (h *PUTGDMHandler) Exchange() (interface{}, int) {
 		return msg, http.StatusConflict
 	}
 
+	if err := h.auth.Manifests(prior, state.Manifests); err != nil {
+		return err.Reason, err.Status
+	}
+
 	flaws := state.Validate()
 	if len(flaws) > 0 {
 		msg := "Invalid GDM"
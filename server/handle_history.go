@@ -0,0 +1,97 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+)
+
+type (
+	// A HistoryResource provides for the /history resource.
+	HistoryResource struct {
+		context ComponentLocator
+	}
+
+	// GETHistoryHandler handles GET exchanges for /history.
+	GETHistoryHandler struct {
+		HistoryStore sous.HistoryStore
+		Filter       *sous.ResolveFilter
+		Page         sous.HistoryPage
+		// PageErr is why the limit or skip parameters couldn't be parsed.
+		PageErr error
+	}
+)
+
+func newHistoryResource(ctx ComponentLocator) *HistoryResource {
+	return &HistoryResource{context: ctx}
+}
+
+// Get returns a configured GETHistoryHandler.
+func (r *HistoryResource) Get(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	page, err := historyPageFromValues(req.URL.Query())
+	return &GETHistoryHandler{
+		HistoryStore: r.context.HistoryStore,
+		Filter:       r.context.selectClusters(historyFilterFromValues(req.URL.Query())),
+		Page:         page,
+		PageErr:      err,
+	}
+}
+
+// Exchange returns a dto.HistoryResponse with the history of the deployments
+// matching the request: the limit events before the skip most recent, if
+// they're given.
+func (h *GETHistoryHandler) Exchange() (interface{}, int) {
+	if h.PageErr != nil {
+		return h.PageErr.Error(), http.StatusBadRequest
+	}
+	if h.HistoryStore == nil {
+		return dto.HistoryResponse{Events: []sous.HistoryEvent{}}, http.StatusOK
+	}
+	events, err := h.HistoryStore.ReadHistory(h.Filter, h.Page)
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	return dto.HistoryResponse{Events: events}, http.StatusOK
+}
+
+// historyFilterFromValues returns a filter matching the repo, offset, flavor
+// and cluster given in values. Those not given match all values.
+func historyFilterFromValues(values map[string][]string) *sous.ResolveFilter {
+	matcher := func(name string) sous.ResolveFieldMatcher {
+		if v, ok := values[name]; ok && len(v) > 0 {
+			return sous.NewResolveFieldMatcher(v[0])
+		}
+		return sous.ResolveFieldMatcher{}
+	}
+	return &sous.ResolveFilter{
+		Repo:    matcher("repo"),
+		Offset:  matcher("offset"),
+		Flavor:  matcher("flavor"),
+		Cluster: matcher("cluster"),
+	}
+}
+
+// historyPageFromValues returns the page of history selected by the limit and
+// skip given in values.
+func historyPageFromValues(values url.Values) (sous.HistoryPage, error) {
+	page := sous.HistoryPage{}
+	for name, n := range map[string]*int{"limit": &page.Limit, "skip": &page.Skip} {
+		v := values.Get(name)
+		if v == "" {
+			continue
+		}
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 0 {
+			return sous.HistoryPage{}, errors.Errorf("%s must be a whole number, not %q", name, v)
+		}
+		*n = i
+	}
+	return page, nil
+}
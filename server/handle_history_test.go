@@ -0,0 +1,103 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+)
+
+func TestHistoryResource_Get(t *testing.T) {
+	history := sous.NewInMemoryHistory()
+	c := ComponentLocator{HistoryStore: history}
+	rm := routemap(c)
+	ls, _ := logging.NewLogSinkSpy()
+
+	req := makeRequestWithQuery(t, "repo=github.com%2Fuser1%2Frepo1&offset=")
+	got := newHistoryResource(c).Get(rm, ls, nil, req, nil).(*GETHistoryHandler)
+
+	if got.HistoryStore != history {
+		t.Errorf("got different history store")
+	}
+	if repo := got.Filter.Repo.ValueOr("*"); repo != "github.com/user1/repo1" {
+		t.Errorf("got repo %q; want %q", repo, "github.com/user1/repo1")
+	}
+	if offset := got.Filter.Offset.ValueOr("*"); offset != "" {
+		t.Errorf("got offset %q; want %q", offset, "")
+	}
+	if !got.Filter.Flavor.All() {
+		t.Errorf("flavor should match all, got %q", got.Filter.Flavor.ValueOr("*"))
+	}
+	if !got.Filter.Cluster.All() {
+		t.Errorf("cluster should match all, got %q", got.Filter.Cluster.ValueOr("*"))
+	}
+}
+
+func TestHistoryResource_Get_page(t *testing.T) {
+	c := ComponentLocator{HistoryStore: sous.NewInMemoryHistory()}
+	rm := routemap(c)
+	ls, _ := logging.NewLogSinkSpy()
+
+	req := makeRequestWithQuery(t, "limit=10&skip=20")
+	got := newHistoryResource(c).Get(rm, ls, nil, req, nil).(*GETHistoryHandler)
+	if got.PageErr != nil || got.Page != (sous.HistoryPage{Limit: 10, Skip: 20}) {
+		t.Errorf("got page %+v, error %v", got.Page, got.PageErr)
+	}
+
+	req = makeRequestWithQuery(t, "limit=lots")
+	got = newHistoryResource(c).Get(rm, ls, nil, req, nil).(*GETHistoryHandler)
+	if _, status := got.Exchange(); status != http.StatusBadRequest {
+		t.Errorf("got status %d for a bad limit; want %d", status, http.StatusBadRequest)
+	}
+}
+
+func TestGETHistoryHandler_Exchange(t *testing.T) {
+	history := sous.NewInMemoryHistory()
+	user := sous.User{Name: "Judson", Email: "jlester@opentable.com"}
+	if err := history.RecordHistory([]sous.HistoryEvent{
+		{When: time.Now(), User: user, DeploymentID: newDid("one"), Change: sous.HistoryAdded},
+		{When: time.Now(), User: user, DeploymentID: newDid("two"), Change: sous.HistoryAdded},
+	}); err != nil {
+		t.Fatalf("setup failed: %s", err)
+	}
+
+	gh := &GETHistoryHandler{
+		HistoryStore: history,
+		Filter:       &sous.ResolveFilter{Repo: sous.NewResolveFieldMatcher("two")},
+	}
+	body, status := gh.Exchange()
+	if status != http.StatusOK {
+		t.Fatalf("got status %d; want %d", status, http.StatusOK)
+	}
+	events := body.(dto.HistoryResponse).Events
+	if len(events) != 1 {
+		t.Fatalf("got %d events; want 1", len(events))
+	}
+	if events[0].DeploymentID != newDid("two") {
+		t.Errorf("got event for %q; want %q", events[0].DeploymentID, newDid("two"))
+	}
+}
+
+func TestGETHistoryHandler_Exchange_page(t *testing.T) {
+	history := sous.NewInMemoryHistory()
+	for _, repo := range []string{"one", "two", "three"} {
+		if err := history.RecordHistory([]sous.HistoryEvent{
+			{When: time.Now(), DeploymentID: newDid(repo), Change: sous.HistoryAdded},
+		}); err != nil {
+			t.Fatalf("setup failed: %s", err)
+		}
+	}
+
+	gh := &GETHistoryHandler{HistoryStore: history, Page: sous.HistoryPage{Limit: 1, Skip: 1}}
+	body, status := gh.Exchange()
+	if status != http.StatusOK {
+		t.Fatalf("got status %d; want %d", status, http.StatusOK)
+	}
+	events := body.(dto.HistoryResponse).Events
+	if len(events) != 1 || events[0].DeploymentID != newDid("two") {
+		t.Errorf("got %v; want only the event for %q", events, newDid("two"))
+	}
+}
@@ -2,7 +2,10 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/opentable/sous/lib"
@@ -15,7 +18,6 @@ import (
 type (
 	// ManifestResource describes resources for manifests
 	ManifestResource struct {
-		userExtractor
 		restful.QueryParser
 		context ComponentLocator
 	}
@@ -34,6 +36,7 @@ type (
 		restful.QueryValues
 		User        ClientUser
 		StateWriter sous.StateWriter
+		auth        authorization
 	}
 
 	// DELETEManifestHandler handles DELETE exchanges for manifests
@@ -41,6 +44,7 @@ type (
 		*sous.State
 		restful.QueryValues
 		StateWriter sous.StateWriter
+		auth        authorization
 	}
 )
 
@@ -58,13 +62,15 @@ func (mr *ManifestResource) Get(_ *restful.RouteMap, _ logging.LogSink, _ http.R
 
 // Put implements Putable for ManifestResource
 func (mr *ManifestResource) Put(_ *restful.RouteMap, ls logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	auth := mr.context.authenticate(req)
 	return &PUTManifestHandler{
 		State:       mr.context.liveState(),
 		LogSink:     ls,
 		Request:     req,
 		QueryValues: mr.ParseQuery(req),
-		User:        mr.GetUser(req),
+		User:        auth.User(),
 		StateWriter: sous.StateWriter(mr.context.StateManager),
+		auth:        auth,
 	}
 }
 
@@ -74,6 +80,7 @@ func (mr *ManifestResource) Delete(_ *restful.RouteMap, _ logging.LogSink, _ htt
 		State:       mr.context.liveState(),
 		QueryValues: mr.ParseQuery(req),
 		StateWriter: sous.StateWriter(mr.context.StateManager),
+		auth:        mr.context.authenticate(req),
 	}
 }
 
@@ -96,10 +103,13 @@ func (dmh *DELETEManifestHandler) Exchange() (interface{}, int) {
 	if err != nil {
 		return err, http.StatusNotFound
 	}
-	_, there := dmh.State.Manifests.Get(mid)
+	prior, there := dmh.State.Manifests.Get(mid)
 	if !there {
 		return nil, http.StatusNotFound
 	}
+	if err := dmh.auth.Manifest(mid, prior); err != nil {
+		return err.Reason, err.Status
+	}
 	dmh.State.Manifests.Remove(mid)
 
 	return nil, http.StatusNoContent
@@ -116,10 +126,21 @@ func (pmh *PUTManifestHandler) Exchange() (interface{}, int) {
 	dec := json.NewDecoder(pmh.Request.Body)
 	dec.Decode(m)
 
-	flaws := m.Validate()
+	flaws := append(m.Validate(), pmh.State.Defs.ValidateManifest(m)...)
 	if len(flaws) > 0 {
 		messages.ReportLogFieldsMessageToConsole("Exchange contains flaws", logging.ExtraDebug1Level, pmh.LogSink, flaws)
-		return "Invalid manifest", http.StatusBadRequest
+		descs := make([]string, len(flaws))
+		for i, f := range flaws {
+			descs[i] = fmt.Sprint(f)
+		}
+		return "Invalid manifest: " + strings.Join(descs, "; "), http.StatusBadRequest
+	}
+	prior, _ := pmh.State.Manifests.Get(mid)
+	if err := pmh.auth.Manifest(mid, prior); err != nil {
+		return err.Reason, err.Status
+	}
+	if err := pmh.State.Defs.Freezes.CheckManifest(prior, m, time.Now()); err != nil {
+		return err.Error(), http.StatusConflict
 	}
 	pmh.State.Manifests.Set(mid, m)
 	if err := pmh.StateWriter.WriteState(pmh.State, sous.User(pmh.User)); err != nil {
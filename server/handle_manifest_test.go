@@ -179,3 +179,39 @@ func TestHandlesManifestPut(t *testing.T) {
 	assert.Equal(changed.Deployments["ci"].SingularityRequestID, "custom-sing-req-id")
 
 }
+
+func TestHandlesManifestPut_invalidValues(t *testing.T) {
+	q, _ := url.ParseQuery("repo=gh")
+	state := sous.NewState()
+	state.Defs.Resources = sous.FieldDefinitions{{Name: "memory", Type: "MemorySize"}}
+	writer := &sous.DummyStateManager{State: state}
+
+	buf := &bytes.Buffer{}
+	json.NewEncoder(buf).Encode(&sous.Manifest{
+		Source: sous.SourceLocation{Repo: "gh"},
+		Kind:   sous.ManifestKindService,
+		Deployments: sous.DeploySpecs{
+			"ci": sous.DeploySpec{
+				DeployConfig: sous.DeployConfig{
+					Resources: sous.Resources{"cpus": "0.1", "memory": "1O24", "ports": "1"},
+				},
+			},
+		},
+	})
+	req, _ := http.NewRequest("PUT", "", buf)
+
+	th := &PUTManifestHandler{
+		Request:     req,
+		StateWriter: writer,
+		State:       state,
+		QueryValues: restful.QueryValues{Values: q},
+		LogSink:     logging.SilentLogSet(),
+	}
+
+	data, status := th.Exchange()
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, data, `manifest gh cluster ci: Resources "memory": "1O24" is not a valid MemorySize`)
+	if _, found := state.Manifests.Get(sous.ManifestID{Source: sous.SourceLocation{Repo: "gh"}}); found {
+		t.Errorf("invalid manifest was stored")
+	}
+}
@@ -0,0 +1,229 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+)
+
+type (
+	// A PausesResource provides for the /pauses resource: the clusters and
+	// deployments Sous doesn't resolve until they are resumed.
+	PausesResource struct {
+		context ComponentLocator
+	}
+
+	// GETPausesHandler handles GET exchanges for /pauses.
+	GETPausesHandler struct {
+		State  *sous.State
+		Filter *sous.ResolveFilter
+	}
+
+	// A PauseResource provides for the /pause resource: the pause of the
+	// cluster, or the deployment to it, given in the query.
+	PauseResource struct {
+		restful.QueryParser
+		context ComponentLocator
+	}
+
+	// GETPauseHandler handles GET exchanges for /pause.
+	GETPauseHandler struct {
+		State *sous.State
+		restful.QueryValues
+	}
+
+	// PUTPauseHandler handles PUT exchanges for /pause, which pause the
+	// cluster, or the deployment to it, given in the query, for the Reason in
+	// the body.
+	PUTPauseHandler struct {
+		StateManager sous.StateManager
+		restful.QueryValues
+		req  *http.Request
+		auth authorization
+	}
+
+	// DELETEPauseHandler handles DELETE exchanges for /pause, which resume
+	// the cluster, or the deployment to it, given in the query.
+	DELETEPauseHandler struct {
+		StateManager sous.StateManager
+		restful.QueryValues
+		auth authorization
+	}
+)
+
+func newPausesResource(ctx ComponentLocator) *PausesResource {
+	return &PausesResource{context: ctx}
+}
+
+// Get implements Getable for PausesResource.
+func (r *PausesResource) Get(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &GETPausesHandler{
+		State:  r.context.liveState(),
+		Filter: historyFilterFromValues(req.URL.Query()),
+	}
+}
+
+func newPauseResource(ctx ComponentLocator) *PauseResource {
+	return &PauseResource{context: ctx}
+}
+
+// Get implements Getable for PauseResource.
+func (r *PauseResource) Get(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &GETPauseHandler{
+		State:       r.context.liveState(),
+		QueryValues: r.ParseQuery(req),
+	}
+}
+
+// Put implements Putable for PauseResource.
+func (r *PauseResource) Put(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &PUTPauseHandler{
+		StateManager: r.context.StateManager,
+		QueryValues:  r.ParseQuery(req),
+		req:          req,
+		auth:         r.context.authenticate(req),
+	}
+}
+
+// Delete implements Deleteable for PauseResource.
+func (r *PauseResource) Delete(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &DELETEPauseHandler{
+		StateManager: r.context.StateManager,
+		QueryValues:  r.ParseQuery(req),
+		auth:         r.context.authenticate(req),
+	}
+}
+
+// Exchange returns a dto.PausesResponse with the pauses of the clusters and
+// deployments matching the request. A pause of a whole cluster matches any
+// repo, offset and flavor.
+func (h *GETPausesHandler) Exchange() (interface{}, int) {
+	if h.State == nil {
+		return "Error loading state from storage", http.StatusInternalServerError
+	}
+	pauses := sous.Pauses{}
+	for _, p := range h.State.Defs.Pauses {
+		if !h.Filter.FilterClusterName(p.Cluster) {
+			continue
+		}
+		if p.Manifest != nil && !h.Filter.FilterManifestID(*p.Manifest) {
+			continue
+		}
+		pauses = append(pauses, p)
+	}
+	return dto.PausesResponse{Pauses: pauses}, http.StatusOK
+}
+
+// Exchange returns the pause of the requested cluster or deployment.
+func (h *GETPauseHandler) Exchange() (interface{}, int) {
+	pause, err := pauseFromValues(h.QueryValues)
+	if err != nil {
+		return err.Error(), http.StatusBadRequest
+	}
+	if h.State == nil {
+		return "Error loading state from storage", http.StatusInternalServerError
+	}
+	existing, paused := h.State.Defs.Pauses.Find(pause)
+	if !paused {
+		return nil, http.StatusNotFound
+	}
+	return existing, http.StatusOK
+}
+
+// Exchange pauses the requested cluster or deployment.
+func (h *PUTPauseHandler) Exchange() (interface{}, int) {
+	pause, err := pauseFromValues(h.QueryValues)
+	if err != nil {
+		return err.Error(), http.StatusBadRequest
+	}
+	body := sous.Pause{}
+	if h.req.Body != nil {
+		if err := json.NewDecoder(h.req.Body).Decode(&body); err != nil && err != io.EOF {
+			return "Error decoding pause: " + err.Error(), http.StatusBadRequest
+		}
+	}
+
+	state, err := h.StateManager.ReadState()
+	if err != nil {
+		return "Error loading state from storage", http.StatusInternalServerError
+	}
+	if _, has := state.Defs.Clusters[pause.Cluster]; !has {
+		return "No cluster named " + pause.Cluster, http.StatusNotFound
+	}
+	if err := authorizePause(h.auth, state, pause); err != nil {
+		return err.Reason, err.Status
+	}
+
+	user := sous.User(h.auth.User())
+	pause.Reason = body.Reason
+	pause.User = user.String()
+	pause.Since = time.Now()
+	state.Defs.Pauses = state.Defs.Pauses.Set(pause)
+	if err := h.StateManager.WriteState(state, user); err != nil {
+		return "Error recording state to storage", http.StatusInternalServerError
+	}
+	return pause, http.StatusCreated
+}
+
+// Exchange resumes the requested cluster or deployment.
+func (h *DELETEPauseHandler) Exchange() (interface{}, int) {
+	pause, err := pauseFromValues(h.QueryValues)
+	if err != nil {
+		return err.Error(), http.StatusBadRequest
+	}
+
+	state, err := h.StateManager.ReadState()
+	if err != nil {
+		return "Error loading state from storage", http.StatusInternalServerError
+	}
+	if err := authorizePause(h.auth, state, pause); err != nil {
+		return err.Reason, err.Status
+	}
+
+	pauses, removed := state.Defs.Pauses.Remove(pause)
+	if !removed {
+		return nil, http.StatusNotFound
+	}
+	state.Defs.Pauses = pauses
+	if err := h.StateManager.WriteState(state, sous.User(h.auth.User())); err != nil {
+		return "Error recording state to storage", http.StatusInternalServerError
+	}
+	return nil, http.StatusNoContent
+}
+
+// authorizePause returns an AuthError unless the caller may pause or resume
+// the target of pause: only admins may pause whole clusters, and the owners
+// of a manifest may pause its deployments.
+func authorizePause(az authorization, state *sous.State, pause sous.Pause) *AuthError {
+	if pause.Manifest == nil {
+		return az.Admin()
+	}
+	prior, _ := state.Manifests.Get(*pause.Manifest)
+	return az.Manifest(*pause.Manifest, prior)
+}
+
+// pauseFromValues returns a Pause of the cluster given in qv, narrowed to the
+// deployment of the manifest given, if a repo is given.
+func pauseFromValues(qv restful.QueryValues) (sous.Pause, error) {
+	cluster, err := qv.Single("cluster")
+	if err != nil {
+		return sous.Pause{}, err
+	}
+	pause := sous.Pause{Cluster: cluster}
+	if _, has := qv.Values["repo"]; !has {
+		return pause, nil
+	}
+	mid, err := manifestIDFromValues(qv)
+	if err != nil {
+		return sous.Pause{}, err
+	}
+	pause.Manifest = &mid
+	return pause, nil
+}
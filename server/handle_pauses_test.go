@@ -0,0 +1,135 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/restful"
+)
+
+func pausesFixture() *sous.DummyStateManager {
+	state := sous.NewState()
+	state.Defs.Clusters = sous.Clusters{"cluster-1": {Name: "cluster-1"}, "cluster-2": {Name: "cluster-2"}}
+	state.Manifests.Add(ownedManifest("sam@example.com"))
+	mid := ownedManifest().ID()
+	state.Defs.Pauses = sous.Pauses{
+		{Cluster: "cluster-1", Reason: "incident"},
+		{Cluster: "cluster-2", Manifest: &mid, Reason: "investigating"},
+	}
+	return &sous.DummyStateManager{State: state}
+}
+
+func pauseQuery(t *testing.T, query string) restful.QueryValues {
+	t.Helper()
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return restful.QueryValues{Values: q}
+}
+
+func TestGETPausesHandler_Exchange(t *testing.T) {
+	sm := pausesFixture()
+	for query, want := range map[string]int{
+		"":                    2,
+		"cluster=cluster-2":   1,
+		"repo=gh":             2,
+		"repo=other":          1,
+		"cluster=no-such-one": 0,
+	} {
+		q, _ := url.ParseQuery(query)
+		h := &GETPausesHandler{State: sm.State, Filter: historyFilterFromValues(q)}
+		body, status := h.Exchange()
+		if status != http.StatusOK {
+			t.Fatalf("%q: got status %d; want 200", query, status)
+		}
+		if got := len(body.(dto.PausesResponse).Pauses); got != want {
+			t.Errorf("%q: got %d pauses; want %d", query, got, want)
+		}
+	}
+}
+
+func TestGETPauseHandler_Exchange(t *testing.T) {
+	sm := pausesFixture()
+	h := &GETPauseHandler{State: sm.State, QueryValues: pauseQuery(t, "cluster=cluster-2&repo=gh")}
+	body, status := h.Exchange()
+	if status != http.StatusOK {
+		t.Fatalf("got status %d; want 200", status)
+	}
+	if reason := body.(sous.Pause).Reason; reason != "investigating" {
+		t.Errorf("got pause %v", body)
+	}
+
+	h.QueryValues = pauseQuery(t, "cluster=cluster-1&repo=gh")
+	if _, status := h.Exchange(); status != http.StatusNotFound {
+		t.Errorf("got status %d for a deployment in a paused cluster; want 404", status)
+	}
+}
+
+func TestPUTPauseHandler_Exchange(t *testing.T) {
+	sm := pausesFixture()
+	req, _ := http.NewRequest("PUT", "/pause", bytes.NewBufferString(`{"Reason": "flaky"}`))
+	h := &PUTPauseHandler{
+		StateManager: sm,
+		QueryValues:  pauseQuery(t, "cluster=cluster-1&repo=gh"),
+		req:          req,
+		auth: authorization{
+			authorizer: testAuthorizer(t, config.AuthConfig{}),
+			Caller:     Caller{User: sous.User{Name: "Sam", Email: "sam@example.com"}},
+		},
+	}
+	body, status := h.Exchange()
+	if status != http.StatusCreated {
+		t.Fatalf("got status %d; want 201: %v", status, body)
+	}
+	mid := ownedManifest().ID()
+	p, paused := sm.State.Defs.Pauses.Find(sous.Pause{Cluster: "cluster-1", Manifest: &mid})
+	if !paused {
+		t.Fatalf("pause not written: %v", sm.State.Defs.Pauses)
+	}
+	if p.Reason != "flaky" || p.User != "Sam <sam@example.com>" || p.Since.IsZero() {
+		t.Errorf("got pause %#v", p)
+	}
+
+	// Only admins may pause a whole cluster.
+	req, _ = http.NewRequest("PUT", "/pause", nil)
+	h.req = req
+	h.QueryValues = pauseQuery(t, "cluster=cluster-2")
+	if _, status := h.Exchange(); status != http.StatusForbidden {
+		t.Errorf("got status %d pausing a cluster; want 403", status)
+	}
+
+	h.QueryValues = pauseQuery(t, "cluster=no-such-cluster&repo=gh")
+	if _, status := h.Exchange(); status != http.StatusNotFound {
+		t.Errorf("got status %d pausing an unknown cluster; want 404", status)
+	}
+	if sm.WriteCount != 1 {
+		t.Errorf("state written %d times; want 1", sm.WriteCount)
+	}
+}
+
+func TestDELETEPauseHandler_Exchange(t *testing.T) {
+	sm := pausesFixture()
+	h := &DELETEPauseHandler{
+		StateManager: sm,
+		QueryValues:  pauseQuery(t, "cluster=cluster-2&repo=gh"),
+		auth: authorization{
+			authorizer: testAuthorizer(t, config.AuthConfig{}),
+			Caller:     Caller{User: sous.User{Email: "sam@example.com"}},
+		},
+	}
+	if _, status := h.Exchange(); status != http.StatusNoContent {
+		t.Fatalf("got status %d; want 204", status)
+	}
+	if len(sm.State.Defs.Pauses) != 1 {
+		t.Errorf("got pauses %v; want only the cluster pause", sm.State.Defs.Pauses)
+	}
+	if _, status := h.Exchange(); status != http.StatusNotFound {
+		t.Errorf("got status %d resuming again; want 404", status)
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+)
+
+type (
+	// A PlanResource provides for the /plan resource.
+	PlanResource struct {
+		context ComponentLocator
+	}
+
+	// GETPlanHandler handles GET exchanges for /plan.
+	GETPlanHandler struct {
+		StateManager sous.StateManager
+		// Resolver supplies the Deployer and Registry used to plan.
+		Resolver *sous.Resolver
+		Filter   *sous.ResolveFilter
+		LogSink  logging.LogSink
+	}
+)
+
+func newPlanResource(ctx ComponentLocator) *PlanResource {
+	return &PlanResource{context: ctx}
+}
+
+// Get returns a configured GETPlanHandler.
+func (r *PlanResource) Get(_ *restful.RouteMap, ls logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	var rez *sous.Resolver
+	if r.context.AutoResolver != nil {
+		rez = r.context.AutoResolver.Resolver
+	}
+	return &GETPlanHandler{
+		StateManager: r.context.StateManager,
+		Resolver:     rez,
+		Filter:       historyFilterFromValues(req.URL.Query()),
+		LogSink:      ls,
+	}
+}
+
+// Exchange returns a dto.PlanResponse describing what resolving the
+// deployments matching the request would do. Nothing is deployed.
+func (h *GETPlanHandler) Exchange() (interface{}, int) {
+	if h.Resolver == nil {
+		return errors.New("this server cannot plan: it has no resolver"), http.StatusServiceUnavailable
+	}
+	state, err := h.StateManager.ReadState()
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	intended, err := state.Deployments()
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+
+	rez := sous.NewResolver(h.Resolver.Deployer, h.Resolver.Registry, h.Filter, h.LogSink, nil)
+	rez.Drift = h.Resolver.Drift
+	plan, err := rez.Plan(intended, state.Defs)
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	return dto.PlanResponse{Changes: plan.Changes, Errors: plan.Errors}, http.StatusOK
+}
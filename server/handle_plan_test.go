@@ -0,0 +1,72 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nyarly/spies"
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+)
+
+func TestPlanResource_Get(t *testing.T) {
+	rez := &sous.Resolver{}
+	c := ComponentLocator{AutoResolver: &sous.AutoResolver{Resolver: rez}}
+	ls, _ := logging.NewLogSinkSpy()
+
+	req := makeRequestWithQuery(t, "cluster=cluster-1")
+	got := newPlanResource(c).Get(routemap(c), ls, nil, req, nil).(*GETPlanHandler)
+
+	if got.Resolver != rez {
+		t.Errorf("got different resolver")
+	}
+	if cluster := got.Filter.Cluster.ValueOr("*"); cluster != "cluster-1" {
+		t.Errorf("got cluster %q; want %q", cluster, "cluster-1")
+	}
+	if !got.Filter.Repo.All() {
+		t.Errorf("repo should match all, got %q", got.Filter.Repo.ValueOr("*"))
+	}
+}
+
+func TestGETPlanHandler_Exchange(t *testing.T) {
+	state := sous.DefaultStateFixture()
+	sm, _ := sous.NewStateManagerSpyFor(state)
+	deployer, dctrl := sous.NewDeployerSpy()
+	dctrl.MatchMethod("RunningDeployments", spies.AnyArgs, sous.NewDeployStates(), nil)
+	ls, _ := logging.NewLogSinkSpy()
+
+	ph := &GETPlanHandler{
+		StateManager: sm,
+		Resolver:     sous.NewResolver(deployer, sous.NewDummyRegistry(), &sous.ResolveFilter{}, ls, nil),
+		Filter:       &sous.ResolveFilter{},
+		LogSink:      ls,
+	}
+	body, status := ph.Exchange()
+	if status != http.StatusOK {
+		t.Fatalf("got status %d; want %d: %v", status, http.StatusOK, body)
+	}
+	intended, err := state.Deployments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := body.(dto.PlanResponse).Changes
+	if len(changes) != intended.Len() {
+		t.Fatalf("got %d changes; want %d", len(changes), intended.Len())
+	}
+	for _, c := range changes {
+		if c.Kind != "added" {
+			t.Errorf("%s: got kind %q; want %q", c.DeploymentID, c.Kind, "added")
+		}
+	}
+	if len(dctrl.CallsTo("Rectify")) != 0 {
+		t.Errorf("planning should not rectify")
+	}
+}
+
+func TestGETPlanHandler_Exchange_noResolver(t *testing.T) {
+	_, status := (&GETPlanHandler{}).Exchange()
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("got status %d; want %d", status, http.StatusServiceUnavailable)
+	}
+}
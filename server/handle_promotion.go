@@ -0,0 +1,160 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+	"github.com/samsalisbury/semv"
+)
+
+type (
+	// A PromotionResource provides for the /promotion resource: the progress
+	// of a version of a manifest through its Promotion.
+	PromotionResource struct {
+		restful.QueryParser
+		context ComponentLocator
+	}
+
+	// GETPromotionHandler handles GET exchanges for /promotion.
+	GETPromotionHandler struct {
+		Engine *sous.PromotionEngine
+		restful.QueryValues
+	}
+
+	// PUTPromotionHandler handles PUT exchanges for /promotion, which start a
+	// promotion.
+	PUTPromotionHandler struct {
+		Engine *sous.PromotionEngine
+		State  *sous.State
+		restful.QueryValues
+		auth authorization
+	}
+
+	// DELETEPromotionHandler handles DELETE exchanges for /promotion, which
+	// discard a finished promotion.
+	DELETEPromotionHandler struct {
+		Engine *sous.PromotionEngine
+		State  *sous.State
+		restful.QueryValues
+		auth authorization
+	}
+)
+
+func newPromotionResource(ctx ComponentLocator) *PromotionResource {
+	return &PromotionResource{context: ctx}
+}
+
+// Get implements Getable for PromotionResource.
+func (r *PromotionResource) Get(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &GETPromotionHandler{
+		Engine:      r.context.PromotionEngine,
+		QueryValues: r.ParseQuery(req),
+	}
+}
+
+// Put implements Putable for PromotionResource.
+func (r *PromotionResource) Put(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &PUTPromotionHandler{
+		Engine:      r.context.PromotionEngine,
+		State:       r.context.liveState(),
+		QueryValues: r.ParseQuery(req),
+		auth:        r.context.authenticate(req),
+	}
+}
+
+// Delete implements Deleteable for PromotionResource.
+func (r *PromotionResource) Delete(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &DELETEPromotionHandler{
+		Engine:      r.context.PromotionEngine,
+		State:       r.context.liveState(),
+		QueryValues: r.ParseQuery(req),
+		auth:        r.context.authenticate(req),
+	}
+}
+
+// Exchange returns the sous.PromotionRun for the requested manifest and
+// version.
+func (h *GETPromotionHandler) Exchange() (interface{}, int) {
+	if h.Engine == nil {
+		return nil, http.StatusNotFound
+	}
+	mid, version, err := promotionFromValues(h.QueryValues)
+	if err != nil {
+		return err, http.StatusBadRequest
+	}
+	run, has := h.Engine.Run(mid, version)
+	if !has {
+		return nil, http.StatusNotFound
+	}
+	return run, http.StatusOK
+}
+
+// Exchange starts promoting the requested version of a manifest.
+func (h *PUTPromotionHandler) Exchange() (interface{}, int) {
+	if h.Engine == nil {
+		return "This server does not run promotions.", http.StatusNotImplemented
+	}
+	mid, version, err := promotionFromValues(h.QueryValues)
+	if err != nil {
+		return err, http.StatusBadRequest
+	}
+	if err := authorizePromotion(h.auth, h.State, mid); err != nil {
+		return err.Reason, err.Status
+	}
+	if run, has := h.Engine.Run(mid, version); has && run.Status == sous.PromotionRunning {
+		return run.String(), http.StatusConflict
+	}
+	run, err := h.Engine.Start(mid, version, sous.User(h.auth.User()))
+	if err != nil {
+		return err.Error(), http.StatusBadRequest
+	}
+	return run, http.StatusCreated
+}
+
+// Exchange discards the record of a finished promotion.
+func (h *DELETEPromotionHandler) Exchange() (interface{}, int) {
+	if h.Engine == nil {
+		return nil, http.StatusNotFound
+	}
+	mid, version, err := promotionFromValues(h.QueryValues)
+	if err != nil {
+		return err, http.StatusBadRequest
+	}
+	if _, has := h.Engine.Run(mid, version); !has {
+		return nil, http.StatusNotFound
+	}
+	if err := authorizePromotion(h.auth, h.State, mid); err != nil {
+		return err.Reason, err.Status
+	}
+	if err := h.Engine.Forget(mid, version); err != nil {
+		return err.Error(), http.StatusConflict
+	}
+	return nil, http.StatusNoContent
+}
+
+// authorizePromotion authorizes promoting the manifest with ID mid, which
+// writes new versions of it into the GDM, as a change to that manifest.
+func authorizePromotion(auth authorization, state *sous.State, mid sous.ManifestID) *AuthError {
+	if state == nil {
+		return &AuthError{Status: http.StatusInternalServerError, Reason: "Couldn't read the current state."}
+	}
+	prior, _ := state.Manifests.Get(mid)
+	return auth.Manifest(mid, prior)
+}
+
+func promotionFromValues(qv restful.QueryValues) (sous.ManifestID, semv.Version, error) {
+	mid, err := manifestIDFromValues(qv)
+	if err != nil {
+		return mid, semv.Version{}, err
+	}
+	v, err := qv.Single("version")
+	if err != nil {
+		return mid, semv.Version{}, err
+	}
+	version, err := semv.Parse(v)
+	return mid, version, errors.Wrapf(err, "parsing version %q", v)
+}
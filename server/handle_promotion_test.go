@@ -0,0 +1,98 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/nyarly/spies"
+	"github.com/opentable/sous/config"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+	"github.com/samsalisbury/semv"
+)
+
+func promotionEngineFixture(t *testing.T) (*sous.PromotionEngine, *sous.State) {
+	t.Helper()
+	state := sous.DefaultStateFixture()
+	m, ok := state.Manifests.Get(sous.MustParseManifestID("github.com/user1/repo1,dir1~flavor1"))
+	if !ok {
+		t.Fatal("fixture has no manifest")
+	}
+	m.Promotion = &sous.Promotion{Stages: []sous.PromotionStage{{Clusters: []string{"cluster1"}}}}
+	m.Owners = []string{"sam@example.com"}
+	state.Manifests.Set(m.ID(), m)
+
+	dm, dmCtrl := sous.NewDeploymentManagerSpy()
+	dmCtrl.MatchMethod("WriteDeployment", spies.AnyArgs, nil)
+	qs, qsCtrl := sous.NewQueueSetSpy()
+	qsCtrl.MatchMethod("Push", spies.AnyArgs, &sous.QueuedR11n{ID: "r11n"}, true)
+	qsCtrl.MatchMethod("Wait", spies.AnyArgs, sous.DiffResolution{}, true)
+
+	return sous.NewPromotionEngine(&sous.DummyStateManager{State: state}, dm, qs, nil, logging.SilentLogSet()), state
+}
+
+// promotionAuth returns the authorization of a caller with email.
+func promotionAuth(t *testing.T, email string) authorization {
+	return authorization{
+		authorizer: testAuthorizer(t, config.AuthConfig{}),
+		Caller:     Caller{User: sous.User{Name: "Someone", Email: email}},
+	}
+}
+
+func promotionQuery(repo, version string) restful.QueryValues {
+	return restful.QueryValues{Values: url.Values{
+		"repo":    {repo},
+		"offset":  {"dir1"},
+		"flavor":  {"flavor1"},
+		"version": {version},
+	}}
+}
+
+func TestPromotionHandlers(t *testing.T) {
+	pe, state := promotionEngineFixture(t)
+	owner := promotionAuth(t, "sam@example.com")
+
+	_, status := (&GETPromotionHandler{Engine: pe, QueryValues: promotionQuery("github.com/user1/repo1", "2.0.0")}).Exchange()
+	if status != http.StatusNotFound {
+		t.Errorf("GET before PUT: got status %d; want 404", status)
+	}
+
+	_, status = (&PUTPromotionHandler{Engine: pe, State: state, auth: owner, QueryValues: promotionQuery("github.com/user1/repo1", "bogus")}).Exchange()
+	if status != http.StatusBadRequest {
+		t.Errorf("PUT with bad version: got status %d; want 400", status)
+	}
+
+	_, status = (&PUTPromotionHandler{Engine: pe, State: state, auth: owner, QueryValues: promotionQuery("github.com/user2/repo2", "2.0.0")}).Exchange()
+	if status != http.StatusBadRequest {
+		t.Errorf("PUT for manifest without promotion: got status %d; want 400", status)
+	}
+
+	_, status = (&PUTPromotionHandler{Engine: pe, State: state, auth: promotionAuth(t, "else@example.com"), QueryValues: promotionQuery("github.com/user1/repo1", "2.0.0")}).Exchange()
+	if status != http.StatusForbidden {
+		t.Errorf("PUT by a non-owner: got status %d; want 403", status)
+	}
+
+	body, status := (&PUTPromotionHandler{Engine: pe, State: state, auth: owner, QueryValues: promotionQuery("github.com/user1/repo1", "2.0.0")}).Exchange()
+	if status != http.StatusCreated {
+		t.Fatalf("PUT: got status %d; want 201: %v", status, body)
+	}
+	run, is := body.(sous.PromotionRun)
+	if !is || run.Version.String() != semv.MustParse("2.0.0").String() {
+		t.Errorf("PUT: got %#v; want the started run", body)
+	}
+	if run.User.Email != "sam@example.com" {
+		t.Errorf("PUT: run by %v; want the authenticated user", run.User)
+	}
+
+	_, status = (&GETPromotionHandler{Engine: pe, QueryValues: promotionQuery("github.com/user1/repo1", "2.0.0")}).Exchange()
+	if status != http.StatusOK {
+		t.Errorf("GET after PUT: got status %d; want 200", status)
+	}
+
+	_, status = (&DELETEPromotionHandler{Engine: pe, State: state, auth: promotionAuth(t, "else@example.com"), QueryValues: promotionQuery("github.com/user1/repo1", "2.0.0")}).Exchange()
+	if status != http.StatusForbidden {
+		t.Errorf("DELETE by a non-owner: got status %d; want 403", status)
+	}
+}
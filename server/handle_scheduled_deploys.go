@@ -0,0 +1,164 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+)
+
+type (
+	// A ScheduledDeploysResource provides for the /scheduled-deploys
+	// resource: the deploys waiting to be made at a later time.
+	ScheduledDeploysResource struct {
+		context ComponentLocator
+	}
+
+	// GETScheduledDeploysHandler handles GET exchanges for
+	// /scheduled-deploys.
+	GETScheduledDeploysHandler struct {
+		Scheduler *sous.DeployScheduler
+		Filter    *sous.ResolveFilter
+		// All includes deploys which have been made, failed or were
+		// cancelled.
+		All bool
+	}
+
+	// A ScheduledDeployResource provides for the /scheduled-deploy resource:
+	// the scheduled deploy with the id given in the query.
+	ScheduledDeployResource struct {
+		restful.QueryParser
+		context ComponentLocator
+	}
+
+	// GETScheduledDeployHandler handles GET exchanges for /scheduled-deploy.
+	GETScheduledDeployHandler struct {
+		Scheduler *sous.DeployScheduler
+		restful.QueryValues
+	}
+
+	// DELETEScheduledDeployHandler handles DELETE exchanges for
+	// /scheduled-deploy, which cancel the scheduled deploy.
+	DELETEScheduledDeployHandler struct {
+		Scheduler   *sous.DeployScheduler
+		StateReader sous.StateReader
+		restful.QueryValues
+		auth authorization
+	}
+)
+
+func newScheduledDeploysResource(ctx ComponentLocator) *ScheduledDeploysResource {
+	return &ScheduledDeploysResource{context: ctx}
+}
+
+// Get implements Getable for ScheduledDeploysResource.
+func (r *ScheduledDeploysResource) Get(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	all, _ := strconv.ParseBool(req.URL.Query().Get("all"))
+	return &GETScheduledDeploysHandler{
+		Scheduler: r.context.DeployScheduler,
+		Filter:    r.context.selectClusters(historyFilterFromValues(req.URL.Query())),
+		All:       all,
+	}
+}
+
+func newScheduledDeployResource(ctx ComponentLocator) *ScheduledDeployResource {
+	return &ScheduledDeployResource{context: ctx}
+}
+
+// Get implements Getable for ScheduledDeployResource.
+func (r *ScheduledDeployResource) Get(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &GETScheduledDeployHandler{
+		Scheduler:   r.context.DeployScheduler,
+		QueryValues: r.ParseQuery(req),
+	}
+}
+
+// Delete implements Deleteable for ScheduledDeployResource.
+func (r *ScheduledDeployResource) Delete(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &DELETEScheduledDeployHandler{
+		Scheduler:   r.context.DeployScheduler,
+		StateReader: r.context.StateManager,
+		QueryValues: r.ParseQuery(req),
+		auth:        r.context.authenticate(req),
+	}
+}
+
+// Exchange returns a dto.ScheduledDeploysResponse with the pending scheduled
+// deploys of the deployments matching the request.
+func (h *GETScheduledDeploysHandler) Exchange() (interface{}, int) {
+	if h.Scheduler == nil {
+		return dto.ScheduledDeploysResponse{Deploys: []sous.ScheduledDeploy{}}, http.StatusOK
+	}
+	var deploys []sous.ScheduledDeploy
+	var err error
+	if h.All {
+		deploys, err = h.Scheduler.Store.ScheduledDeploys(h.Filter)
+	} else {
+		deploys, err = h.Scheduler.Pending(h.Filter)
+	}
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	return dto.ScheduledDeploysResponse{Deploys: deploys}, http.StatusOK
+}
+
+// Exchange returns the requested scheduled deploy.
+func (h *GETScheduledDeployHandler) Exchange() (interface{}, int) {
+	sd, status, msg := findScheduledDeploy(h.Scheduler, h.QueryValues)
+	if status != http.StatusOK {
+		return msg, status
+	}
+	return sd, http.StatusOK
+}
+
+// Exchange cancels the requested scheduled deploy, if it hasn't been made
+// yet.
+func (h *DELETEScheduledDeployHandler) Exchange() (interface{}, int) {
+	sd, status, msg := findScheduledDeploy(h.Scheduler, h.QueryValues)
+	if status != http.StatusOK {
+		return msg, status
+	}
+
+	state, err := h.StateReader.ReadState()
+	if err != nil {
+		return "Error loading state from storage", http.StatusInternalServerError
+	}
+	mid := sd.DeploymentID.ManifestID
+	prior, _ := state.Manifests.Get(mid)
+	if err := h.auth.Manifest(mid, prior); err != nil {
+		return err.Reason, err.Status
+	}
+
+	cancelled, err := h.Scheduler.Cancel(sd.ID)
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	if !cancelled {
+		return "Scheduled deploy " + string(sd.ID) + " is no longer pending", http.StatusConflict
+	}
+	return nil, http.StatusNoContent
+}
+
+// findScheduledDeploy returns the scheduled deploy with the id given in qv,
+// or the status and message to respond with if there isn't one.
+func findScheduledDeploy(scheduler *sous.DeployScheduler, qv restful.QueryValues) (sous.ScheduledDeploy, int, interface{}) {
+	id, err := qv.Single("id")
+	if err != nil {
+		return sous.ScheduledDeploy{}, http.StatusBadRequest, err.Error()
+	}
+	if scheduler == nil {
+		return sous.ScheduledDeploy{}, http.StatusNotFound, nil
+	}
+	sd, found, err := scheduler.Find(sous.ScheduledDeployID(id))
+	if err != nil {
+		return sous.ScheduledDeploy{}, http.StatusInternalServerError, err
+	}
+	if !found {
+		return sous.ScheduledDeploy{}, http.StatusNotFound, nil
+	}
+	return sd, http.StatusOK, nil
+}
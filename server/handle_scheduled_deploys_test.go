@@ -0,0 +1,109 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+)
+
+func scheduleFixture(t *testing.T) (*sous.DeployScheduler, *sous.DummyStateManager, sous.ScheduledDeploy) {
+	t.Helper()
+	state := sous.NewState()
+	state.Defs.Clusters = sous.Clusters{"cluster-1": {Name: "cluster-1"}}
+	m := ownedManifest("sam@example.com")
+	state.Manifests.Add(m)
+	sm := &sous.DummyStateManager{State: state}
+
+	scheduler := sous.NewDeployScheduler(sm, nil, sous.NewInMemorySchedule(), nil, logging.SilentLogSet())
+	sd, err := scheduler.Schedule(sous.ScheduledDeploy{
+		DeploymentID: sous.DeploymentID{ManifestID: m.ID(), Cluster: "cluster-1"},
+		At:           time.Now().Add(time.Hour),
+		User:         sous.User{Email: "sam@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scheduler, sm, sd
+}
+
+func TestGETScheduledDeploysHandler_Exchange(t *testing.T) {
+	scheduler, _, sd := scheduleFixture(t)
+	for query, want := range map[string]int{
+		"":                  1,
+		"cluster=cluster-1": 1,
+		"cluster=cluster-2": 0,
+		"repo=other":        0,
+	} {
+		q, _ := url.ParseQuery(query)
+		h := &GETScheduledDeploysHandler{Scheduler: scheduler, Filter: historyFilterFromValues(q)}
+		body, status := h.Exchange()
+		if status != http.StatusOK {
+			t.Fatalf("%q: got status %d; want 200", query, status)
+		}
+		if got := len(body.(dto.ScheduledDeploysResponse).Deploys); got != want {
+			t.Errorf("%q: got %d scheduled deploys; want %d", query, got, want)
+		}
+	}
+
+	if _, err := scheduler.Cancel(sd.ID); err != nil {
+		t.Fatal(err)
+	}
+	h := &GETScheduledDeploysHandler{Scheduler: scheduler, Filter: &sous.ResolveFilter{}}
+	body, _ := h.Exchange()
+	if got := len(body.(dto.ScheduledDeploysResponse).Deploys); got != 0 {
+		t.Errorf("got %d scheduled deploys after cancelling; want 0", got)
+	}
+	h.All = true
+	body, _ = h.Exchange()
+	if got := len(body.(dto.ScheduledDeploysResponse).Deploys); got != 1 {
+		t.Errorf("got %d scheduled deploys with All; want 1", got)
+	}
+}
+
+func TestGETScheduledDeployHandler_Exchange(t *testing.T) {
+	scheduler, _, sd := scheduleFixture(t)
+	h := &GETScheduledDeployHandler{Scheduler: scheduler, QueryValues: restful.QueryValues{Values: url.Values{"id": {string(sd.ID)}}}}
+	body, status := h.Exchange()
+	if status != http.StatusOK {
+		t.Fatalf("got status %d; want 200", status)
+	}
+	if got := body.(sous.ScheduledDeploy); got.ID != sd.ID {
+		t.Errorf("got scheduled deploy %v; want %v", got, sd)
+	}
+
+	h.QueryValues = restful.QueryValues{Values: url.Values{"id": {"no-such-id"}}}
+	if _, status := h.Exchange(); status != http.StatusNotFound {
+		t.Errorf("got status %d; want 404", status)
+	}
+}
+
+func TestDELETEScheduledDeployHandler_Exchange(t *testing.T) {
+	scheduler, sm, sd := scheduleFixture(t)
+	h := &DELETEScheduledDeployHandler{
+		Scheduler:   scheduler,
+		StateReader: sm,
+		QueryValues: restful.QueryValues{Values: url.Values{"id": {string(sd.ID)}}},
+		auth: authorization{
+			authorizer: testAuthorizer(t, config.AuthConfig{}),
+			Caller:     Caller{User: sous.User{Email: "someone@example.com"}},
+		},
+	}
+	if _, status := h.Exchange(); status != http.StatusForbidden {
+		t.Errorf("got status %d cancelling someone else's deploy; want 403", status)
+	}
+
+	h.auth.Caller.User.Email = "sam@example.com"
+	if _, status := h.Exchange(); status != http.StatusNoContent {
+		t.Fatalf("got status %d; want 204", status)
+	}
+	if _, status := h.Exchange(); status != http.StatusConflict {
+		t.Errorf("got status %d cancelling again; want 409", status)
+	}
+}
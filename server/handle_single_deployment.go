@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/opentable/sous/ext/singularity"
@@ -26,8 +27,10 @@ type (
 	PUTSingleDeploymentHandler struct {
 		SingleDeploymentHandler
 		QueueSet    sous.QueueSet
+		Scheduler   *sous.DeployScheduler
 		routeMap    *restful.RouteMap
 		StateWriter sous.StateWriter
+		auth        authorization
 	}
 
 	// GETSingleDeploymentHandler retrieves manifests containing single deployment
@@ -39,7 +42,6 @@ type (
 	// SingleDeploymentHandler contains common data and methods to both
 	// the GET and PUT handlers.
 	SingleDeploymentHandler struct {
-		userExtractor
 		Body           SingleDeploymentBody
 		req            *http.Request
 		responseWriter http.ResponseWriter
@@ -68,6 +70,11 @@ func (sdh *SingleDeploymentHandler) force() (force bool, err error) {
 	return forceFromValues(qv)
 }
 
+func (sdh *SingleDeploymentHandler) at() (time.Time, error) {
+	qv := restful.QueryValues{Values: sdh.req.URL.Query()}
+	return atFromValues(qv)
+}
+
 func (sdh *SingleDeploymentHandler) depID() (sous.DeploymentID, error) {
 	qv := restful.QueryValues{Values: sdh.req.URL.Query()}
 	return deploymentIDFromValues(qv)
@@ -80,8 +87,10 @@ func (sdr *SingleDeploymentResource) Put(rm *restful.RouteMap, ls logging.LogSin
 	return &PUTSingleDeploymentHandler{
 		SingleDeploymentHandler: sdh,
 		QueueSet:                sdr.context.QueueSet,
+		Scheduler:               sdr.context.DeployScheduler,
 		routeMap:                rm,
 		StateWriter:             sdr.context.StateManager,
+		auth:                    sdr.context.authenticate(req),
 	}
 }
 
@@ -104,7 +113,7 @@ func (h *GETSingleDeploymentHandler) Exchange() (interface{}, int) {
 		return h.err(404, "No manifest with ID %q", did.ManifestID)
 	}
 
-	dep, ok := m.Deployments[did.Cluster]
+	dep, _, ok := m.DeploySpecFor(h.GDM.Defs, did.Cluster)
 	if !ok {
 		return h.err(404, "Manifest %q has no deployment for cluster %q.", m.ID(), did.Cluster)
 	}
@@ -159,7 +168,8 @@ func (sdh *SingleDeploymentHandler) ok(code int, links map[string]string) (Singl
 // Exchange triggers a deployment action when receiving
 // a Manifest containing a deployment matching DeploymentID that differs
 // from the current actual deployment set. It first writes the new
-// deployment spec to the GDM.
+// deployment spec to the GDM. If the query gives a future time "at", the
+// deployment is instead scheduled to be made then.
 func (psd *PUTSingleDeploymentHandler) Exchange() (interface{}, int) {
 	did, err := psd.depID()
 	if err != nil {
@@ -171,6 +181,14 @@ func (psd *PUTSingleDeploymentHandler) Exchange() (interface{}, int) {
 		return psd.err(400, "Cannot parse force from client: %s", err)
 	}
 
+	at, err := psd.at()
+	if err != nil {
+		return psd.err(400, "Cannot parse at from client: %s", err)
+	}
+	if !at.IsZero() && !at.After(time.Now()) {
+		return psd.err(400, "Cannot schedule a deploy at %s, which is in the past.", at.Format(time.RFC3339))
+	}
+
 	if err := json.NewDecoder(psd.req.Body).Decode(&psd.Body); err != nil {
 		return psd.err(400, "Error parsing body: %s.", err)
 	}
@@ -189,7 +207,7 @@ func (psd *PUTSingleDeploymentHandler) Exchange() (interface{}, int) {
 	if !ok {
 		return psd.err(404, "No manifest with ID %q.", did.ManifestID)
 	}
-	original, ok := m.Deployments[did.Cluster]
+	original, _, ok := m.DeploySpecFor(psd.GDM.Defs, did.Cluster)
 	if !ok {
 		return psd.err(404, "Manifest %q has no deployment for cluster %q.",
 			did.ManifestID, did.Cluster)
@@ -204,9 +222,25 @@ func (psd *PUTSingleDeploymentHandler) Exchange() (interface{}, int) {
 		return psd.ok(200, nil)
 	}
 
-	m.Deployments[did.Cluster] = *psd.Body.Deployment
+	if err := psd.auth.Manifest(did.ManifestID, m); err != nil {
+		return psd.err(err.Status, "%s", err.Reason)
+	}
+
+	if !at.IsZero() {
+		return psd.schedule(did, original.Clone(), at, force)
+	}
+
+	if err := psd.GDM.Defs.Freezes.Check(did, sous.NewOwnerSet(m.Owners...), time.Now()); err != nil {
+		return psd.err(409, "%s", err)
+	}
+
+	if pause, paused := psd.GDM.Defs.Pauses.Paused(did); paused {
+		return psd.err(409, "%s; resume it with sous resume", pause)
+	}
 
-	user := sous.User(psd.GetUser(psd.req))
+	m.SetDeploySpec(psd.GDM.Defs, did.Cluster, *psd.Body.Deployment)
+
+	user := sous.User(psd.auth.User())
 
 	if err := psd.StateWriter.WriteState(psd.GDM, user); err != nil {
 		return psd.err(500, "Failed to write state: %s.", err)
@@ -253,8 +287,7 @@ func (psd *PUTSingleDeploymentHandler) Exchange() (interface{}, int) {
 	repoKV := restful.KV{"repo", did.ManifestID.Source.Repo}
 	offsetKV := restful.KV{"offset", did.ManifestID.Source.Dir}
 	flavorKV := restful.KV{"flavor", did.ManifestID.Flavor}
-	hostName := psd.req.Host
-	queueURI, err := psd.routeMap.FullURIFor(hostName, "deploy-queue-item", nil,
+	queueURI, err := psd.routeMap.FullURIFor(baseURL(psd.req), "deploy-queue-item", nil,
 		actionKV, clusterKV, repoKV, offsetKV, flavorKV)
 
 	if err != nil {
@@ -266,3 +299,40 @@ func (psd *PUTSingleDeploymentHandler) Exchange() (interface{}, int) {
 
 	return psd.ok(201, map[string]string{"queuedDeployAction": queueURI})
 }
+
+// schedule stores the deployment in the body, to be deployed at at over
+// prior, and returns the location of the scheduled deploy. Freezes and pauses
+// are checked when it is due, rather than now.
+func (psd *PUTSingleDeploymentHandler) schedule(did sous.DeploymentID, prior sous.DeploySpec, at time.Time, force bool) (interface{}, int) {
+	if psd.Scheduler == nil {
+		return psd.err(501, "This server doesn't schedule deploys.")
+	}
+	sd, err := psd.Scheduler.Schedule(sous.ScheduledDeploy{
+		DeploymentID: did,
+		Deployment:   *psd.Body.Deployment,
+		Prior:        &prior,
+		Force:        force,
+		At:           at,
+		User:         sous.User(psd.auth.User()),
+	})
+	if err != nil {
+		return psd.err(500, "Failed to schedule deploy: %s", err)
+	}
+
+	scheduledURI, err := psd.routeMap.FullURIFor(baseURL(psd.req), "scheduled-deploy", nil,
+		restful.KV{"id", string(sd.ID)})
+	if err != nil {
+		return psd.err(500, "Determining scheduled deploy URL: %s", err)
+	}
+
+	return psd.ok(201, map[string]string{"scheduledDeploy": scheduledURI})
+}
+
+// baseURL returns the scheme and host req was made to, so that the locations
+// the server returns work over HTTPS as well as HTTP.
+func baseURL(req *http.Request) string {
+	if req.TLS != nil {
+		return "https://" + req.Host
+	}
+	return "http://" + req.Host
+}
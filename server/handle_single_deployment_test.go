@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/nyarly/spies"
 	sous "github.com/opentable/sous/lib"
@@ -296,7 +298,51 @@ func TestPUTSingleDeploymentHandler_Exchange(t *testing.T) {
 		scenario.assertDeploymentWritten(t)
 		scenario.assertR11nQueued(t)
 		scenario.assertHeader(t, "Location",
-			"sous.example.com/deploy-queue-item?action=actionid1&cluster=cluster1&flavor=flavor1&offset=dir1&repo=github.com%2Fuser1%2Frepo1")
+			"http://sous.example.com/deploy-queue-item?action=actionid1&cluster=cluster1&flavor=flavor1&offset=dir1&repo=github.com%2Fuser1%2Frepo1")
+	})
+
+	t.Run("change version over HTTPS", func(t *testing.T) {
+		body, query := makeBodyAndQuery(t, false)
+		body.Deployment.Version = semv.MustParse("2.0.0")
+		scenario := setup(body, query)
+		scenario.handler.req.TLS = &tls.ConnectionState{}
+		scenario.queueSet.MatchMethod("Push", spies.AnyArgs, &sous.QueuedR11n{ID: "actionid1"}, true)
+		scenario.exercise()
+
+		scenario.assertStatus(t, 201)
+		scenario.assertHeader(t, "Location",
+			"https://sous.example.com/deploy-queue-item?action=actionid1&cluster=cluster1&flavor=flavor1&offset=dir1&repo=github.com%2Fuser1%2Frepo1")
+	})
+
+	t.Run("cluster covered by a selector", func(t *testing.T) {
+		body, query := makeBodyAndQuery(t, false)
+		body.Deployment.Version = semv.MustParse("2.0.0")
+		scenario := setup(body, query)
+		for _, cn := range []string{"cluster1", "cluster2"} {
+			scenario.gdm.Defs.Clusters[cn].Labels = map[string]string{"env": "prod"}
+		}
+		mid := sous.MustParseManifestID("github.com/user1/repo1,dir1~flavor1")
+		m, ok := scenario.gdm.Manifests.Get(mid)
+		if !ok {
+			t.Fatal("Setup failed to get manifest.")
+		}
+		m.Deployments["env=prod"] = m.Deployments["cluster1"]
+		delete(m.Deployments, "cluster1")
+		delete(m.Deployments, "cluster2")
+		scenario.gdm.Manifests.Set(mid, m)
+		scenario.queueSet.MatchMethod("Push", spies.AnyArgs, &sous.QueuedR11n{ID: "actionid1"}, true)
+		scenario.exercise()
+
+		scenario.assertStatus(t, 201)
+		scenario.assertDeploymentWritten(t)
+		scenario.assertR11nQueued(t)
+		m, _ = scenario.stateManager.State.Manifests.Get(mid)
+		if v := m.Deployments["env=prod"].Version.String(); v != "1.0.0" {
+			t.Errorf("Selector's spec changed to version %s; want 1.0.0 for cluster2.", v)
+		}
+		if v := m.Deployments["cluster1"].Version.String(); v != "2.0.0" {
+			t.Errorf("cluster1 deployed version %s; want 2.0.0.", v)
+		}
 	})
 
 	t.Run("WriteDeployment error", func(t *testing.T) {
@@ -354,7 +400,87 @@ func TestPUTSingleDeploymentHandler_Exchange(t *testing.T) {
 		scenario.assertDeploymentWritten(t)
 		scenario.assertR11nQueued(t)
 		scenario.assertHeader(t, "Location",
-			"sous.example.com/deploy-queue-item?action=actionid1&cluster=cluster1&flavor=flavor1&offset=dir1&repo=github.com%2Fuser1%2Frepo1")
+			"http://sous.example.com/deploy-queue-item?action=actionid1&cluster=cluster1&flavor=flavor1&offset=dir1&repo=github.com%2Fuser1%2Frepo1")
+	})
+
+	t.Run("frozen", func(t *testing.T) {
+		body, query := makeBodyAndQuery(t, false)
+		body.Deployment.Version = semv.MustParse("2.0.0")
+		scenario := setup(body, query)
+		scenario.gdm.Defs.Freezes = sous.FreezeWindows{{
+			Reason:   "holiday",
+			Clusters: []string{"cluster*"},
+			Start:    time.Now().Add(-time.Hour),
+			End:      time.Now().Add(time.Hour),
+		}}
+		scenario.exercise()
+
+		scenario.assertStatus(t, 409)
+		scenario.assertStringBody(t, "holiday")
+		scenario.assertNoR11nQueued(t)
+		if scenario.stateManager.WriteCount != 0 {
+			t.Errorf("Expected no deployment written; written %d times.", scenario.stateManager.WriteCount)
+		}
+	})
+
+	t.Run("paused", func(t *testing.T) {
+		body, query := makeBodyAndQuery(t, false)
+		body.Deployment.Version = semv.MustParse("2.0.0")
+		scenario := setup(body, query)
+		scenario.gdm.Defs.Pauses = sous.Pauses{{
+			Cluster: "cluster1",
+			Reason:  "incident 42",
+			User:    "oncall@example.com",
+			Since:   time.Now(),
+		}}
+		scenario.exercise()
+
+		scenario.assertStatus(t, 409)
+		scenario.assertStringBody(t, "incident 42")
+		scenario.assertNoR11nQueued(t)
+		if scenario.stateManager.WriteCount != 0 {
+			t.Errorf("Expected no deployment written; written %d times.", scenario.stateManager.WriteCount)
+		}
+	})
+
+	t.Run("scheduled", func(t *testing.T) {
+		body, query := makeBodyAndQuery(t, false)
+		body.Deployment.Version = semv.MustParse("2.0.0")
+		at := time.Now().Add(time.Hour).Truncate(time.Second)
+		query["at"] = at.Format(time.RFC3339)
+		scenario := setup(body, query)
+		store := sous.NewInMemorySchedule()
+		scenario.handler.Scheduler = sous.NewDeployScheduler(scenario.stateManager, nil, store, nil, logging.SilentLogSet())
+		scenario.exercise()
+
+		scenario.assertStatus(t, 201)
+		scenario.assertNoR11nQueued(t)
+		if scenario.stateManager.WriteCount != 0 {
+			t.Errorf("Expected no deployment written; written %d times.", scenario.stateManager.WriteCount)
+		}
+		deploys, _ := store.ScheduledDeploys(nil)
+		if len(deploys) != 1 {
+			t.Fatalf("Expected 1 scheduled deploy, got %d.", len(deploys))
+		}
+		sd := deploys[0]
+		if !sd.At.Equal(at) || sd.Deployment.Version.String() != "2.0.0" || sd.User.Email != "testuser@example" {
+			t.Errorf("Scheduled %v", sd)
+		}
+		if sd.Prior == nil || sd.Prior.Version.String() != "1.0.0" {
+			t.Errorf("Expected the deployment at 1.0.0 recorded as the prior; got %v", sd.Prior)
+		}
+		scenario.assertHeader(t, "Location", "http://sous.example.com/scheduled-deploy?id="+string(sd.ID))
+	})
+
+	t.Run("scheduled in the past", func(t *testing.T) {
+		body, query := makeBodyAndQuery(t, false)
+		body.Deployment.Version = semv.MustParse("2.0.0")
+		query["at"] = time.Now().Add(-time.Hour).Format(time.RFC3339)
+		scenario := setup(body, query)
+		scenario.exercise()
+
+		scenario.assertStatus(t, 400)
+		scenario.assertStringBody(t, "in the past")
 	})
 
 }
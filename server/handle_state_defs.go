@@ -13,7 +13,6 @@ import (
 type (
 	// StateDefResource defines the /defs endpoint
 	StateDefResource struct {
-		userExtractor
 		context ComponentLocator
 	}
 
@@ -27,6 +26,7 @@ type (
 		sous.StateManager
 		req  *http.Request
 		user ClientUser
+		auth authorization
 	}
 )
 
@@ -45,10 +45,12 @@ func (sdr *StateDefResource) Get(*restful.RouteMap, logging.LogSink, http.Respon
 // Put implements restful.Putter on StateDefResource (and therefore makes it
 // handle PUT requests.)
 func (sdr *StateDefResource) Put(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	auth := sdr.context.authenticate(req)
 	return &StateDefPutHandler{
 		StateManager: sdr.context.StateManager,
 		req:          req,
-		user:         sdr.GetUser(req),
+		user:         auth.User(),
+		auth:         auth,
 	}
 }
 
@@ -59,6 +61,10 @@ func (sdg *StateDefGetHandler) Exchange() (interface{}, int) {
 
 // Exchange implements restful.Exchanger on StateDefGetHandler.
 func (sdp *StateDefPutHandler) Exchange() (interface{}, int) {
+	if err := sdp.auth.Admin(); err != nil {
+		return err.Reason, err.Status
+	}
+
 	defs := sous.Defs{}
 	dec := json.NewDecoder(sdp.req.Body)
 	dec.Decode(&defs)
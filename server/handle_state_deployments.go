@@ -16,7 +16,6 @@ type (
 	// A StateDeploymentResource provides for the /state/deployments resource family
 	StateDeploymentResource struct {
 		loc ComponentLocator
-		userExtractor
 	}
 
 	// A GETStateDeployments is the exchanger for GET /state/deployments
@@ -32,6 +31,7 @@ type (
 		clusterName string
 		req         *http.Request
 		User        ClientUser
+		auth        authorization
 	}
 )
 
@@ -50,11 +50,13 @@ func (res *StateDeploymentResource) Get(*restful.RouteMap, logging.LogSink, http
 
 // Put implements restful.Putable on StateDeployments
 func (res *StateDeploymentResource) Put(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	auth := res.loc.authenticate(req)
 	return &PUTStateDeployments{
 		cluster:     res.loc.ClusterManager,
 		clusterName: res.loc.ResolveFilter.Cluster.ValueOr("no-cluster"),
 		req:         req,
-		User:        res.GetUser(req),
+		User:        auth.User(),
+		auth:        auth,
 	}
 }
 
@@ -87,6 +89,10 @@ func (psd *PUTStateDeployments) Exchange() (interface{}, int) {
 
 	deps := sous.NewDeployments(data.Deployments...)
 
+	if err := psd.authorize(deps); err != nil {
+		return err.Reason, err.Status
+	}
+
 	err = psd.cluster.WriteCluster(psd.clusterName, deps, sous.User(psd.User))
 	if err != nil {
 		return err, http.StatusInternalServerError
@@ -94,3 +100,22 @@ func (psd *PUTStateDeployments) Exchange() (interface{}, int) {
 
 	return nil, http.StatusAccepted
 }
+
+// authorize lets admins write any deployments, and others only those of the
+// manifests they own.
+func (psd *PUTStateDeployments) authorize(deps sous.Deployments) *AuthError {
+	if psd.auth.err != nil {
+		return psd.auth.err
+	}
+	if psd.auth.authorizer == nil || psd.auth.authorizer.Admin(psd.auth.Caller) {
+		return nil
+	}
+	prior, err := psd.cluster.ReadCluster(psd.clusterName)
+	if err != nil {
+		return &AuthError{
+			Status: http.StatusInternalServerError,
+			Reason: fmt.Sprintf("reading deployments to authorize the change: %v", err),
+		}
+	}
+	return psd.auth.Deployments(prior, deps)
+}
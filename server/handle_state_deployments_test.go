@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/nyarly/spies"
+	"github.com/opentable/sous/config"
 	"github.com/opentable/sous/dto"
 	sous "github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/logging"
@@ -88,3 +89,60 @@ func TestPutStateDeployments(t *testing.T) {
 		t.Errorf("No calls to WriteCluster")
 	}
 }
+
+func TestPutStateDeployments_authorized(t *testing.T) {
+	owned := sous.DeploymentFixture("sequenced-repo")
+	owned.Owners = sous.NewOwnerSet("sam@example.com")
+	prior := sous.NewDeployments(owned)
+
+	changed := owned.Clone()
+	changed.NumInstances++
+
+	put := func(caller Caller) int {
+		cm, ctrl := sous.NewClusterManagerSpy()
+		ctrl.MatchMethod("ReadCluster", spies.AnyArgs, prior, nil)
+		ctrl.MatchMethod("WriteCluster", spies.AnyArgs, nil)
+
+		buf := &bytes.Buffer{}
+		json.NewEncoder(buf).Encode(dto.GDMWrapper{Deployments: []*sous.Deployment{changed}})
+		req, _ := http.NewRequest("PUT", "", buf)
+		ex := &PUTStateDeployments{
+			cluster:     cm,
+			clusterName: "test-cluster",
+			req:         req,
+			auth: authorization{
+				authorizer: testAuthorizer(t, config.AuthConfig{}),
+				Caller:     caller,
+			},
+		}
+		_, status := ex.Exchange()
+		if status >= 400 && len(ctrl.CallsTo("WriteCluster")) > 0 {
+			t.Errorf("wrote deployments despite refusing with %d", status)
+		}
+		return status
+	}
+
+	if status := put(Caller{User: sous.User{Email: "judson@example.com"}}); status != http.StatusForbidden {
+		t.Errorf("non-owner: got status %d; want 403", status)
+	}
+	if status := put(Caller{User: sous.User{Email: "sam@example.com"}}); status != http.StatusAccepted {
+		t.Errorf("owner: got status %d; want 202", status)
+	}
+	if status := put(Caller{User: sous.User{Name: "sous-server"}, Groups: []string{"admins"}}); status != http.StatusAccepted {
+		t.Errorf("admin: got status %d; want 202", status)
+	}
+}
+
+func TestPutStateDeployments_unauthenticated(t *testing.T) {
+	cm, ctrl := sous.NewClusterManagerSpy()
+	req, _ := http.NewRequest("PUT", "", bytes.NewBufferString(`{"Deployments":[]}`))
+	ctx := ComponentLocator{Authorizer: testAuthorizer(t, config.AuthConfig{})}
+
+	ex := &PUTStateDeployments{cluster: cm, clusterName: "test-cluster", req: req, auth: ctx.authenticate(req)}
+	if _, status := ex.Exchange(); status != http.StatusUnauthorized {
+		t.Errorf("got status %d; want 401", status)
+	}
+	if len(ctrl.CallsTo("WriteCluster")) > 0 {
+		t.Errorf("wrote deployments for an unauthenticated caller")
+	}
+}
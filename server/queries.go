@@ -2,6 +2,7 @@ package server
 
 import (
 	"strconv"
+	"time"
 
 	sous "github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/firsterr"
@@ -42,6 +43,16 @@ func forceFromValues(qv restful.QueryValues) (force bool, err error) {
 	return force, nil
 }
 
+// atFromValues returns the time given as "at" in qv, in RFC3339 format, or
+// the zero time if there isn't one.
+func atFromValues(qv restful.QueryValues) (time.Time, error) {
+	a, err := qv.Single("at", "")
+	if err != nil || a == "" {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, a)
+}
+
 func deploymentIDFromValues(qv restful.QueryValues) (sous.DeploymentID, error) {
 	cluster, err := qv.Single("cluster")
 	if err != nil {
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"net/http/pprof"
@@ -32,10 +33,17 @@ type (
 		sous.StateManager
 		sous.ClusterManager    // xxx temporary?
 		sous.DeploymentManager // xxx temporary?
+		HistoryStore           sous.HistoryStore
 		ResolveFilter          *sous.ResolveFilter
 		*sous.AutoResolver
-		Version  semv.Version
-		QueueSet sous.QueueSet
+		Version         semv.Version
+		QueueSet        sous.QueueSet
+		PromotionEngine *sous.PromotionEngine
+		DeployScheduler *sous.DeployScheduler
+		DeployEvents    *sous.DeployEvents
+		// Authorizer authorizes writes to manifests and defs. If it is nil,
+		// anyone may write anything.
+		Authorizer *Authorizer
 	}
 )
 
@@ -51,6 +59,19 @@ func (ctx ComponentLocator) liveState() *sous.State {
 	return state
 }
 
+// selectClusters returns rf, matching exactly the clusters its Cluster
+// selects in the live state.
+func (ctx ComponentLocator) selectClusters(rf *sous.ResolveFilter) *sous.ResolveFilter {
+	if rf.Cluster.All() || ctx.StateManager == nil {
+		return rf
+	}
+	state := ctx.liveState()
+	if state == nil {
+		return rf
+	}
+	return rf.SelectClusters(state.Defs.Clusters)
+}
+
 func (userExtractor) GetUser(req *http.Request) ClientUser {
 	clu := ClientUser{
 		Name:  req.Header.Get("Sous-User-Name"),
@@ -63,14 +84,29 @@ func (userExtractor) GetUser(req *http.Request) ClientUser {
 // Run starts a server up.
 func Run(laddr string, handler http.Handler) error {
 	s := &http.Server{Addr: laddr, Handler: handler}
-	err := s.ListenAndServe()
+	return listen(s.ListenAndServe)
+}
+
+// RunTLS starts a server up, serving HTTPS. Clients may present certificates,
+// which the Authorizer verifies.
+func RunTLS(laddr string, handler http.Handler, certFile, keyFile string) error {
+	s := &http.Server{
+		Addr:      laddr,
+		Handler:   handler,
+		TLSConfig: &tls.Config{ClientAuth: tls.RequestClientCert},
+	}
+	return listen(func() error { return s.ListenAndServeTLS(certFile, keyFile) })
+}
+
+func listen(serve func() error) error {
+	err := serve()
 	if err == nil {
 		return nil
 	}
 	pause := 5 * time.Second
 	fmt.Fprintf(os.Stderr, "Error listening: %s; Trying again in %s", err, pause)
 	time.Sleep(pause)
-	return s.ListenAndServe()
+	return serve()
 }
 
 // Handler builds the http.Handler for the Sous server httprouter.
@@ -109,7 +145,16 @@ func routemap(context ComponentLocator) *restful.RouteMap {
 		re("all-deploy-queues", "/all-deploy-queues", newAllDeployQueuesResource(context))
 		re("deploy-queue", "/deploy-queue", newDeployQueueResource(context))
 		re("deploy-queue-item", "/deploy-queue-item", newR11nResource(context))
+		re("deploy-events", "/deploy-events", newDeployEventsResource(context))
 		re("single-deployment", "/single-deployment", newSingleDeploymentResource(context))
+		re("history", "/history", newHistoryResource(context))
+		re("plan", "/plan", newPlanResource(context))
+		re("drift", "/drift", newDriftResource(context))
+		re("pauses", "/pauses", newPausesResource(context))
+		re("pause", "/pause", newPauseResource(context))
+		re("scheduled-deploys", "/scheduled-deploys", newScheduledDeploysResource(context))
+		re("scheduled-deploy", "/scheduled-deploy", newScheduledDeployResource(context))
+		re("promotion", "/promotion", newPromotionResource(context))
 		re("default", "/", newDefaultResource(context))
 	})
 }
@@ -20,7 +20,6 @@ import (
 	"github.com/docker/distribution/reference"
 	"github.com/docker/distribution/registry/api/v2"
 	"github.com/docker/distribution/registry/client"
-	"github.com/docker/distribution/registry/client/transport"
 	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/logging/messages"
 	"golang.org/x/net/context"
@@ -71,6 +70,7 @@ type (
 		AllTags(repoName string) ([]string, error)
 		Cancel()
 		BecomeFoolishlyTrusting()
+		Pusher
 	}
 
 	// Metadata represents the descriptive data for a docker image
@@ -448,22 +448,10 @@ type tagsResponse struct {
 }
 
 func (r *registry) getBlob(ctx context.Context, name reference.Named, dgst digest.Digest) ([]byte, error) {
-	ref, err := reference.WithDigest(name, dgst)
+	reader, err := r.openBlob(name, dgst)
 	if err != nil {
 		return nil, err
 	}
-	blobURL, err := r.ub.BuildBlobURL(ref)
-	if err != nil {
-		return nil, err
-	}
-
-	reader := transport.NewHTTPReadSeeker(r.client.http, blobURL,
-		func(resp *http.Response) error {
-			if resp.StatusCode == http.StatusNotFound {
-				return distribution.ErrBlobUnknown
-			}
-			return client.HandleErrorResponse(resp)
-		})
 	defer reader.Close()
 
 	return ioutil.ReadAll(reader)
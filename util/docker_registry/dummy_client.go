@@ -2,8 +2,11 @@ package docker_registry
 
 import (
 	"fmt"
+	"io"
 	"regexp"
 
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/schema2"
 	"github.com/nyarly/spies"
 	"github.com/stretchr/testify/mock"
 )
@@ -109,3 +112,38 @@ func (drc *DummyRegistryClient) FeedMetadata(md Metadata) {
 func (drc *DummyRegistryClient) FeedTags(ts []string) {
 	drc.AddTag(`.*`, ts)
 }
+
+// GetImage fulfills part of Client
+func (drc *DummyRegistryClient) GetImage(in string) (Image, error) {
+	res := drc.Called(in)
+	if res.Get(0) == nil {
+		return Image{}, fmt.Errorf("Dummy client: no image for name: %q", in)
+	}
+	return res.Get(0).(Image), res.Error(1)
+}
+
+// PushBlob fulfills part of Client
+func (drc *DummyRegistryClient) PushBlob(rn string, desc distribution.Descriptor, content io.Reader) error {
+	return drc.Called(rn, desc).Error(0)
+}
+
+// CopyBlob fulfills part of Client
+func (drc *DummyRegistryClient) CopyBlob(rn, from string, desc distribution.Descriptor) error {
+	return drc.Called(rn, from, desc).Error(0)
+}
+
+// PushManifest fulfills part of Client
+func (drc *DummyRegistryClient) PushManifest(in string, m schema2.Manifest) (string, error) {
+	res := drc.Called(in, m)
+	return res.String(0), res.Error(1)
+}
+
+// GetConfigBlob fulfills part of Client
+func (drc *DummyRegistryClient) GetConfigBlob(in string) (schema2.Manifest, []byte, error) {
+	res := drc.Called(in)
+	if res.Get(0) == nil {
+		return schema2.Manifest{}, nil, fmt.Errorf("Dummy client: no image for name: %q", in)
+	}
+	blob, _ := res.Get(1).([]byte)
+	return res.Get(0).(schema2.Manifest), blob, res.Error(2)
+}
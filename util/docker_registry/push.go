@@ -0,0 +1,357 @@
+package docker_registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/distribution/registry/client"
+	"github.com/docker/distribution/registry/client/transport"
+)
+
+type (
+	// Pusher reads base images from, and pushes images to, docker registries
+	// without a Docker daemon.
+	Pusher interface {
+		// GetImage returns the manifest and config of the schema 2 image
+		// imageName.
+		GetImage(imageName string) (Image, error)
+		// PushBlob uploads the blob described by desc, read from content, to
+		// the repository repoName, unless it is already there.
+		PushBlob(repoName string, desc distribution.Descriptor, content io.Reader) error
+		// CopyBlob makes the blob described by desc, which belongs to the image
+		// from, available in the repository repoName. The blob is mounted if
+		// the registry allows it, and copied otherwise.
+		CopyBlob(repoName, from string, desc distribution.Descriptor) error
+		// PushManifest stores m as imageName, and returns the canonical name of
+		// the image.
+		PushManifest(imageName string, m schema2.Manifest) (string, error)
+		// GetConfigBlob returns the manifest and the raw config blob of the
+		// schema 2 image imageName. It suits artifacts other than images,
+		// which are stored in their config blob.
+		GetConfigBlob(imageName string) (schema2.Manifest, []byte, error)
+	}
+
+	// Image is a schema 2 image as stored in a registry.
+	Image struct {
+		// CanonicalName is the image's name, with registry host, by digest.
+		CanonicalName string
+		Manifest      schema2.Manifest
+		Config        ImageConfig
+	}
+
+	// ImageConfig is the configuration blob of a schema 2 image.
+	ImageConfig struct {
+		Architecture string         `json:"architecture"`
+		OS           string         `json:"os"`
+		Created      *time.Time     `json:"created,omitempty"`
+		Author       string         `json:"author,omitempty"`
+		Config       ImageRunConfig `json:"config"`
+		RootFS       ImageRootFS    `json:"rootfs"`
+		History      []ImageHistory `json:"history,omitempty"`
+	}
+
+	// ImageRunConfig is the part of an ImageConfig that describes how
+	// containers are run from the image.
+	ImageRunConfig struct {
+		User         string              `json:",omitempty"`
+		ExposedPorts map[string]struct{} `json:",omitempty"`
+		Env          []string            `json:",omitempty"`
+		Entrypoint   []string            `json:",omitempty"`
+		Cmd          []string            `json:",omitempty"`
+		Volumes      map[string]struct{} `json:",omitempty"`
+		WorkingDir   string              `json:",omitempty"`
+		Labels       map[string]string   `json:",omitempty"`
+		StopSignal   string              `json:",omitempty"`
+		OnBuild      []string            `json:",omitempty"`
+		Healthcheck  json.RawMessage     `json:",omitempty"`
+	}
+
+	// ImageRootFS lists the uncompressed digests of an image's layers.
+	ImageRootFS struct {
+		Type    string          `json:"type"`
+		DiffIDs []digest.Digest `json:"diff_ids"`
+	}
+
+	// ImageHistory records how one layer of an image was made.
+	ImageHistory struct {
+		Created    *time.Time `json:"created,omitempty"`
+		CreatedBy  string     `json:"created_by,omitempty"`
+		Comment    string     `json:"comment,omitempty"`
+		EmptyLayer bool       `json:"empty_layer,omitempty"`
+	}
+)
+
+// GetImage implements Pusher on liveClient.
+func (c *liveClient) GetImage(imageName string) (Image, error) {
+	canonical, m, cj, err := c.getSchema2(imageName)
+	if err != nil {
+		return Image{}, err
+	}
+	img := Image{CanonicalName: canonical, Manifest: m}
+	if err := json.Unmarshal(cj, &img.Config); err != nil {
+		return Image{}, fmt.Errorf("parsing config of %s: %s", imageName, err)
+	}
+	return img, nil
+}
+
+// GetConfigBlob implements Pusher on liveClient.
+func (c *liveClient) GetConfigBlob(imageName string) (schema2.Manifest, []byte, error) {
+	_, m, cj, err := c.getSchema2(imageName)
+	return m, cj, err
+}
+
+// getSchema2 returns the canonical name, manifest and config blob of the
+// schema 2 image imageName.
+func (c *liveClient) getSchema2(imageName string) (string, schema2.Manifest, []byte, error) {
+	regHost, ref, err := splitHost(imageName)
+	if err != nil {
+		return "", schema2.Manifest{}, nil, err
+	}
+	rep, err := c.registryForHostname(regHost)
+	if err != nil {
+		return "", schema2.Manifest{}, nil, fmt.Errorf("getting registry for hostname %q: %s", regHost, err)
+	}
+
+	mani, dg, _, err := rep.getManifestWithEtag(c.ctx, ref, "")
+	if err != nil {
+		return "", schema2.Manifest{}, nil, err
+	}
+	s2, ok := mani.(*schema2.DeserializedManifest)
+	if !ok {
+		return "", schema2.Manifest{}, nil, fmt.Errorf("%s is not a schema 2 image", imageName)
+	}
+
+	cj, err := rep.getBlob(c.ctx, ref, s2.Config.Digest)
+	if err != nil {
+		return "", schema2.Manifest{}, nil, err
+	}
+	return regHost + "/" + ref.Name() + "@" + dg.String(), s2.Manifest, cj, nil
+}
+
+// PushBlob implements Pusher on liveClient.
+func (c *liveClient) PushBlob(repoName string, desc distribution.Descriptor, content io.Reader) error {
+	regHost, ref, err := splitHost(repoName)
+	if err != nil {
+		return err
+	}
+	rep, err := c.registryForHostname(regHost)
+	if err != nil {
+		return err
+	}
+
+	if exists, err := rep.blobExists(ref, desc.Digest); err != nil || exists {
+		return err
+	}
+	location, _, err := rep.startUpload(ref, nil)
+	if err != nil {
+		return err
+	}
+	return rep.finishUpload(location, desc, content)
+}
+
+// CopyBlob implements Pusher on liveClient.
+func (c *liveClient) CopyBlob(repoName, from string, desc distribution.Descriptor) error {
+	regHost, ref, err := splitHost(repoName)
+	if err != nil {
+		return err
+	}
+	rep, err := c.registryForHostname(regHost)
+	if err != nil {
+		return err
+	}
+	fromHost, fromRef, err := splitHost(from)
+	if err != nil {
+		return err
+	}
+
+	if exists, err := rep.blobExists(ref, desc.Digest); err != nil || exists {
+		return err
+	}
+
+	var mount url.Values
+	if fromHost == regHost {
+		mount = url.Values{"mount": {desc.Digest.String()}, "from": {fromRef.Name()}}
+	}
+	location, mounted, err := rep.startUpload(ref, mount)
+	if err != nil || mounted {
+		return err
+	}
+
+	fromRep, err := c.registryForHostname(fromHost)
+	if err != nil {
+		return err
+	}
+	content, err := fromRep.openBlob(fromRef, desc.Digest)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	return rep.finishUpload(location, desc, content)
+}
+
+// PushManifest implements Pusher on liveClient.
+func (c *liveClient) PushManifest(imageName string, m schema2.Manifest) (string, error) {
+	regHost, ref, err := splitHost(imageName)
+	if err != nil {
+		return "", err
+	}
+	rep, err := c.registryForHostname(regHost)
+	if err != nil {
+		return "", err
+	}
+
+	dm, err := schema2.FromStruct(m)
+	if err != nil {
+		return "", err
+	}
+	mediaType, payload, err := dm.Payload()
+	if err != nil {
+		return "", err
+	}
+	if err := rep.putManifest(ref, mediaType, payload); err != nil {
+		return "", err
+	}
+	return regHost + "/" + ref.Name() + "@" + digest.FromBytes(payload).String(), nil
+}
+
+func (r *registry) openBlob(name reference.Named, dgst digest.Digest) (distribution.ReadSeekCloser, error) {
+	ref, err := reference.WithDigest(name, dgst)
+	if err != nil {
+		return nil, err
+	}
+	blobURL, err := r.ub.BuildBlobURL(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return transport.NewHTTPReadSeeker(r.client.http, blobURL,
+		func(resp *http.Response) error {
+			if resp.StatusCode == http.StatusNotFound {
+				return distribution.ErrBlobUnknown
+			}
+			return client.HandleErrorResponse(resp)
+		}), nil
+}
+
+func (r *registry) blobExists(name reference.Named, dgst digest.Digest) (bool, error) {
+	ref, err := reference.WithDigest(name, dgst)
+	if err != nil {
+		return false, err
+	}
+	blobURL, err := r.ub.BuildBlobURL(ref)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := r.client.Head("docker-blob", blobURL)
+	if err != nil {
+		return false, err
+	}
+	defer safeCloseBody(resp)
+
+	switch {
+	case client.SuccessStatus(resp.StatusCode):
+		return true, nil
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	default:
+		return false, client.HandleErrorResponse(resp)
+	}
+}
+
+// startUpload begins a blob upload to the repository name, returning where
+// to send the blob. If mount is given and the registry mounts the blob
+// instead, mounted is true and there is nothing to send.
+func (r *registry) startUpload(name reference.Named, mount url.Values) (location string, mounted bool, err error) {
+	values := []url.Values{}
+	if mount != nil {
+		values = append(values, mount)
+	}
+	u, err := r.ub.BuildBlobUploadURL(name, values...)
+	if err != nil {
+		return "", false, err
+	}
+
+	req, err := http.NewRequest("POST", u, nil)
+	if err != nil {
+		return "", false, err
+	}
+	resp, err := r.client.Do("docker-blob-upload", req)
+	if err != nil {
+		return "", false, err
+	}
+	defer safeCloseBody(resp)
+
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return "", true, nil
+	case http.StatusAccepted:
+		location, err = sanitizeLocation(resp.Header.Get("Location"), u)
+		return location, false, err
+	default:
+		return "", false, client.HandleErrorResponse(resp)
+	}
+}
+
+// finishUpload sends the whole of a blob to an upload location returned by
+// startUpload.
+func (r *registry) finishUpload(location string, desc distribution.Descriptor, content io.Reader) error {
+	u, err := url.Parse(location)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	q.Set("digest", desc.Digest.String())
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest("PUT", u.String(), content)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = desc.Size
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := r.client.Do("docker-blob-upload", req)
+	if err != nil {
+		return err
+	}
+	defer safeCloseBody(resp)
+
+	if !client.SuccessStatus(resp.StatusCode) {
+		return client.HandleErrorResponse(resp)
+	}
+	return nil
+}
+
+func (r *registry) putManifest(ref reference.Named, mediaType string, payload []byte) error {
+	u, err := r.ub.BuildManifestURL(ref)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("PUT", u, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mediaType)
+
+	resp, err := r.client.Do("docker-manifest", req)
+	if err != nil {
+		return err
+	}
+	defer safeCloseBody(resp)
+
+	if !client.SuccessStatus(resp.StatusCode) {
+		return client.HandleErrorResponse(resp)
+	}
+	return nil
+}
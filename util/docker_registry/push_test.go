@@ -0,0 +1,131 @@
+package docker_registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRegistry is just enough of a registry to push and pull images.
+type testRegistry struct {
+	sync.Mutex
+	blobs     map[string][]byte // by repo and digest
+	manifests map[string][]byte // by repo and tag
+	uploads   int
+}
+
+func newTestRegistry() *testRegistry {
+	return &testRegistry{blobs: map[string][]byte{}, manifests: map[string][]byte{}}
+}
+
+func (tr *testRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tr.Lock()
+	defer tr.Unlock()
+
+	if strings.HasPrefix(r.URL.Path, "/upload/") && r.Method == "PUT" {
+		body, _ := ioutil.ReadAll(r.Body)
+		tr.blobs[strings.TrimPrefix(r.URL.Path, "/upload/")+"@"+r.URL.Query().Get("digest")] = body
+		tr.uploads++
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v2/")
+	switch {
+	case strings.HasSuffix(path, "/blobs/uploads/"):
+		repo := strings.TrimSuffix(path, "/blobs/uploads/")
+		q := r.URL.Query()
+		if blob, ok := tr.blobs[q.Get("from")+"@"+q.Get("mount")]; ok {
+			tr.blobs[repo+"@"+q.Get("mount")] = blob
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		w.Header().Set("Location", "/upload/"+repo)
+		w.WriteHeader(http.StatusAccepted)
+	case strings.Contains(path, "/blobs/"):
+		parts := strings.SplitN(path, "/blobs/", 2)
+		blob, ok := tr.blobs[parts[0]+"@"+parts[1]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(blob)
+	case strings.Contains(path, "/manifests/"):
+		parts := strings.SplitN(path, "/manifests/", 2)
+		key := parts[0] + ":" + parts[1]
+		if r.Method == "PUT" {
+			tr.manifests[key], _ = ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		m, ok := tr.manifests[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", schema2.MediaTypeManifest)
+		w.Write(m)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestLiveClient_push(t *testing.T) {
+	tr := newTestRegistry()
+	srv := httptest.NewTLSServer(tr)
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	c := NewClient(logging.SilentLogSet())
+	c.BecomeFoolishlyTrusting()
+
+	layer := []byte("layer")
+	layerDesc := distribution.Descriptor{MediaType: schema2.MediaTypeLayer, Size: int64(len(layer)), Digest: digest.FromBytes(layer)}
+	require.NoError(t, c.PushBlob(host+"/base", layerDesc, bytes.NewReader(layer)))
+	require.NoError(t, c.PushBlob(host+"/base", layerDesc, bytes.NewReader(layer)))
+	assert.Equal(t, 1, tr.uploads, "blobs already pushed aren't uploaded again")
+
+	require.NoError(t, c.CopyBlob(host+"/app", host+"/base:1", layerDesc))
+	assert.Equal(t, 1, tr.uploads, "blobs in the same registry are mounted")
+	assert.Equal(t, layer, tr.blobs["app@"+layerDesc.Digest.String()])
+
+	config, err := json.Marshal(ImageConfig{
+		OS:     "linux",
+		Config: ImageRunConfig{Labels: map[string]string{"a": "b"}},
+		RootFS: ImageRootFS{Type: "layers", DiffIDs: []digest.Digest{layerDesc.Digest}},
+	})
+	require.NoError(t, err)
+	configDesc := distribution.Descriptor{MediaType: schema2.MediaTypeConfig, Size: int64(len(config)), Digest: digest.FromBytes(config)}
+	require.NoError(t, c.PushBlob(host+"/app", configDesc, bytes.NewReader(config)))
+
+	name, err := c.PushManifest(host+"/app:1.0.0", schema2.Manifest{
+		Versioned: schema2.SchemaVersion,
+		Config:    configDesc,
+		Layers:    []distribution.Descriptor{layerDesc},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, host+"/app@"+digest.FromBytes(tr.manifests["app:1.0.0"]).String(), name)
+
+	img, err := c.GetImage(host + "/app:1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, name, img.CanonicalName)
+	assert.Equal(t, []distribution.Descriptor{layerDesc}, img.Manifest.Layers)
+	assert.Equal(t, "b", img.Config.Config.Labels["a"])
+
+	m, blob, err := c.GetConfigBlob(host + "/app:1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, configDesc, m.Config)
+	assert.Equal(t, config, blob)
+}
@@ -2,6 +2,7 @@ package restful
 
 import (
 	"bytes"
+	"crypto/tls"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -91,6 +92,25 @@ type (
 	Variances []string
 
 	retryableError string
+
+	// A ConflictError is returned when the server refuses a request with 409
+	// Conflict, e.g. because deployments are frozen. Reason is the
+	// explanation the server gave.
+	ConflictError struct {
+		Status string
+		Reason string
+	}
+
+	// A DeniedError is returned when the server refuses a request with 401
+	// Unauthorized or 403 Forbidden, because the user couldn't be
+	// authenticated or isn't allowed to make the change. Reason is the
+	// explanation the server gave.
+	DeniedError struct {
+		Status string
+		// Unauthenticated is true if the user couldn't be authenticated.
+		Unauthenticated bool
+		Reason          string
+	}
 )
 
 func (rs *resourceState) Update(qBody Comparable, headers map[string]string) (UpdateDeleter, error) {
@@ -109,6 +129,38 @@ func (re retryableError) Error() string {
 	return string(re)
 }
 
+func (ce *ConflictError) Error() string {
+	return fmt.Sprintf("%s: %s", ce.Status, ce.Reason)
+}
+
+func (de *DeniedError) Error() string {
+	return fmt.Sprintf("%s: %s", de.Status, de.Reason)
+}
+
+// newConflictError builds a ConflictError from a response body.
+func newConflictError(status string, b []byte) *ConflictError {
+	return &ConflictError{Status: status, Reason: refusalReason(b)}
+}
+
+// newDeniedError builds a DeniedError from a response and its body.
+func newDeniedError(rz *http.Response, b []byte) *DeniedError {
+	return &DeniedError{
+		Status:          rz.Status,
+		Unauthenticated: rz.StatusCode == http.StatusUnauthorized,
+		Reason:          refusalReason(b),
+	}
+}
+
+// refusalReason returns the reason given in the body of a refused request,
+// which is usually a JSON string.
+func refusalReason(b []byte) string {
+	var reason string
+	if err := json.Unmarshal(b, &reason); err != nil {
+		reason = string(b)
+	}
+	return reason
+}
+
 // Retryable is a predicate on error that returns true if the error indicates
 // that a subsequent attempt at e.g. an Update might succeed.
 func Retryable(err error) bool {
@@ -116,6 +168,16 @@ func Retryable(err error) bool {
 	return is
 }
 
+// AbsoluteLocation returns location, the Location of a resource, as an
+// absolute URL. Older servers return locations without a scheme, which are
+// taken to be HTTP.
+func AbsoluteLocation(location string) string {
+	if location == "" || strings.Contains(location, "://") {
+		return location
+	}
+	return "http://" + location
+}
+
 // NewClient returns a new LiveHTTPClient for a particular serverURL.
 func NewClient(serverURL string, ls logging.LogSink, headers ...map[string]string) (*LiveHTTPClient, error) {
 	u, err := url.Parse(serverURL)
@@ -136,6 +198,14 @@ func NewClient(serverURL string, ls logging.LogSink, headers ...map[string]strin
 	return client, errors.Wrapf(err, "new Sous REST client")
 }
 
+// SetTLSConfig makes client use tc for HTTPS connections, e.g. to present a
+// client certificate.
+func (client *LiveHTTPClient) SetTLSConfig(tc *tls.Config) {
+	if t, is := client.Client.Transport.(*http.Transport); is {
+		t.TLSClientConfig = tc
+	}
+}
+
 // NewInMemoryClient wraps a MemoryListener in a restful.Client
 func NewInMemoryClient(handler http.Handler, ls logging.LogSink, headers ...map[string]string) (HTTPClient, error) {
 	u, err := url.Parse("http://in.memory.server")
@@ -403,10 +473,12 @@ func (client *LiveHTTPClient) extractBody(rz *http.Response, rzBody interface{},
 			headers:      rz.Header,
 			resourceJSON: bytes.NewBuffer(rzJSON),
 		}, errors.Wrapf(err, "processing response body")
+	case rz.StatusCode == http.StatusConflict:
+		return nil, newConflictError(rz.Status, b)
+	case rz.StatusCode == http.StatusUnauthorized || rz.StatusCode == http.StatusForbidden:
+		return nil, newDeniedError(rz, b)
 	case rz.StatusCode < 200 || rz.StatusCode >= 300:
 		return nil, errors.Errorf("%s: %s", rz.Status, string(b))
-	case rz.StatusCode == http.StatusConflict:
-		return nil, errors.Wrap(retryableError(fmt.Sprintf("%s: %#v", rz.Status, string(b))), "getBody")
 	case rz.Header.Get("Content-Type") != "application/json" && len(b) > 0:
 		return nil, errors.Errorf("%s: Not JSON response: %q\n'%s'", rz.Status, rz.Header.Get("Content-Type"), string(b))
 	}
@@ -0,0 +1,150 @@
+package restful
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
+
+type (
+	// A Streamer is a response body which is sent as a stream of server-sent
+	// events, as they happen, rather than rendered as a single JSON document.
+	// A GET Exchanger returns one to stream its response.
+	Streamer interface {
+		// Stream writes events to ew until done is closed, when the client has
+		// gone away.
+		Stream(ew *EventWriter, done <-chan struct{})
+	}
+
+	// An EventWriter writes server-sent events to a client.
+	EventWriter struct {
+		w       io.Writer
+		flusher http.Flusher
+	}
+
+	// An Event is a single server-sent event, as read by LiveHTTPClient.Stream.
+	Event struct {
+		Name string
+		Data []byte
+	}
+)
+
+// maxEventSize is the largest event LiveHTTPClient.Stream will read.
+const maxEventSize = 16 * 1024 * 1024
+
+// WriteEvent sends data, encoded as JSON, as an event named name.
+func (ew *EventWriter) WriteEvent(name string, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return errors.Wrapf(err, "encoding %s event", name)
+	}
+	if _, err := fmt.Fprintf(ew.w, "event: %s\ndata: %s\n\n", name, b); err != nil {
+		return err
+	}
+	ew.flusher.Flush()
+	return nil
+}
+
+// WriteComment sends a comment, which clients ignore. It's useful to keep an
+// idle stream open.
+func (ew *EventWriter) WriteComment(comment string) error {
+	if _, err := fmt.Fprintf(ew.w, ": %s\n\n", comment); err != nil {
+		return err
+	}
+	ew.flusher.Flush()
+	return nil
+}
+
+func (mh *MetaHandler) streamData(status int, w *loggingResponseWriter, r *http.Request, s Streamer) {
+	flusher, ok := w.ResponseWriter.(http.Flusher)
+	if !ok {
+		mh.writeHeaders(http.StatusNotImplemented, w, r, "streaming unsupported")
+		return
+	}
+	w.Header().Set(contentTypeHeader, "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(status)
+	flusher.Flush()
+	s.Stream(&EventWriter{w: w, flusher: flusher}, r.Context().Done())
+	w.sendLog()
+}
+
+// Stream makes a GET request on urlPath for a stream of server-sent events,
+// and calls fn with each event received. It returns when ctx is cancelled,
+// the stream ends, or fn returns an error. A stream that ends without ctx
+// being cancelled returns io.ErrUnexpectedEOF.
+func (client *LiveHTTPClient) Stream(ctx context.Context, urlPath string, qParms map[string]string, headers map[string]string, fn func(Event) error) error {
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	headers["Accept"] = "text/event-stream"
+	rq, err := client.constructRequest(ctx, "GET", urlPath, qParms, nil, headers)
+	if err != nil {
+		return err
+	}
+	messages.ReportClientHTTPRequest(client.LogSink, "<event stream>", rq, "")
+	rz, err := client.Client.Do(rq)
+	if err != nil {
+		return errors.Wrapf(err, "GET %s", rq.URL)
+	}
+	defer rz.Body.Close()
+	if rz.StatusCode != http.StatusOK {
+		return errors.Errorf("GET %s: %s", rq.URL, rz.Status)
+	}
+	if ct := rz.Header.Get("Content-Type"); ct != "text/event-stream" {
+		return errors.Errorf("GET %s: bad content type %q", rq.URL, ct)
+	}
+
+	err = readEvents(rz.Body, fn)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return errors.Wrapf(err, "GET %s", rq.URL)
+}
+
+// readEvents calls fn with each event read from r.
+func readEvents(r io.Reader, fn func(Event) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxEventSize)
+	ev := Event{}
+	data := &bytes.Buffer{}
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			if data.Len() > 0 || ev.Name != "" {
+				ev.Data = append([]byte{}, bytes.TrimSuffix(data.Bytes(), []byte("\n"))...)
+				if err := fn(ev); err != nil {
+					return err
+				}
+			}
+			ev = Event{}
+			data.Reset()
+			continue
+		}
+		if line[0] == ':' {
+			continue
+		}
+		field, value := line, []byte{}
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], bytes.TrimPrefix(line[i+1:], []byte(" "))
+		}
+		switch string(field) {
+		case "event":
+			ev.Name = string(value)
+		case "data":
+			data.Write(value)
+			data.WriteByte('\n')
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
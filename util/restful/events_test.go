@@ -0,0 +1,101 @@
+package restful
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+)
+
+type (
+	testStreamResource struct{}
+	testStreamer       struct{}
+)
+
+func (testStreamResource) Get(*RouteMap, logging.LogSink, http.ResponseWriter, *http.Request, httprouter.Params) Exchanger {
+	return testStreamer{}
+}
+
+func (ts testStreamer) Exchange() (interface{}, int) {
+	return ts, http.StatusOK
+}
+
+func (testStreamer) Stream(ew *EventWriter, done <-chan struct{}) {
+	ew.WriteEvent("greeting", map[string]string{"Hello": "world"})
+	ew.WriteComment("still here")
+	ew.WriteEvent("farewell", "goodbye")
+}
+
+func TestLiveHTTPClient_Stream(t *testing.T) {
+	rm := &RouteMap{{"stream", "/stream", testStreamResource{}}}
+	srv := httptest.NewServer(rm.BuildRouter(logging.SilentLogSet()))
+	defer srv.Close()
+
+	cl, err := NewClient(srv.URL, logging.SilentLogSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := []Event{}
+	err = cl.Stream(context.Background(), "./stream", nil, nil, func(ev Event) error {
+		events = append(events, ev)
+		return nil
+	})
+	if errors.Cause(err) != io.ErrUnexpectedEOF {
+		t.Errorf("got error %v; want the stream to end unexpectedly", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events; want 2: %q", len(events), events)
+	}
+	if events[0].Name != "greeting" || string(events[0].Data) != `{"Hello":"world"}` {
+		t.Errorf("got first event %q %s", events[0].Name, events[0].Data)
+	}
+	if events[1].Name != "farewell" || string(events[1].Data) != `"goodbye"` {
+		t.Errorf("got second event %q %s", events[1].Name, events[1].Data)
+	}
+}
+
+func TestLiveHTTPClient_Stream_notFound(t *testing.T) {
+	rm := &RouteMap{{"stream", "/stream", testStreamResource{}}}
+	srv := httptest.NewServer(rm.BuildRouter(logging.SilentLogSet()))
+	defer srv.Close()
+
+	cl, err := NewClient(srv.URL, logging.SilentLogSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cl.Stream(context.Background(), "./elsewhere", nil, nil, func(Event) error {
+		t.Errorf("unexpected event")
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("got error %v; want 404", err)
+	}
+}
+
+func TestReadEvents(t *testing.T) {
+	stream := "event: one\ndata: a\ndata: b\n\n: comment\n\ndata: c\n\n"
+	events := []Event{}
+	err := readEvents(strings.NewReader(stream), func(ev Event) error {
+		events = append(events, ev)
+		return nil
+	})
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("got error %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events; want 2: %q", len(events), events)
+	}
+	if events[0].Name != "one" || string(events[0].Data) != "a\nb" {
+		t.Errorf("got %q %q; want one, multi-line data", events[0].Name, events[0].Data)
+	}
+	if events[1].Name != "" || string(events[1].Data) != "c" {
+		t.Errorf("got %q %q; want unnamed event", events[1].Name, events[1].Data)
+	}
+}
@@ -149,3 +149,9 @@ func dig(m interface{}, index ...interface{}) interface{} {
 	}
 	return res
 }
+
+func TestAbsoluteLocation(t *testing.T) {
+	assert.Equal(t, "http://sous.example.com/deploy-queue-item", AbsoluteLocation("sous.example.com/deploy-queue-item"))
+	assert.Equal(t, "https://sous.example.com/deploy-queue-item", AbsoluteLocation("https://sous.example.com/deploy-queue-item"))
+	assert.Equal(t, "", AbsoluteLocation(""))
+}